@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/arsham/gitrelease/commit"
+)
+
+// resolveAuth returns the token for the GitHub API. The GITHUB_TOKEN takes
+// precedence. Otherwise the GitHub App credentials in the environment are used
+// as the TokenSource of g, and the returned token is empty.
+func resolveAuth(g *commit.Git) (string, error) {
+	if g.Offline {
+		// Nothing is sent to the API.
+		return "", nil
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token, nil
+	}
+	appID := os.Getenv("GITHUB_APP_ID")
+	if appID == "" {
+		return "", errors.New("please export GITHUB_TOKEN, or GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_PATH")
+	}
+	pemData := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	if path := os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH"); len(pemData) == 0 && path != "" {
+		var err error
+		pemData, err = os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading the private key: %w", err)
+		}
+	}
+	if len(pemData) == 0 {
+		return "", errors.New("please export GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_PATH")
+	}
+	key, err := commit.ParsePrivateKey(pemData)
+	if err != nil {
+		return "", err
+	}
+	g.TokenSource = &commit.AppTokenSource{
+		AppID:          appID,
+		InstallationID: os.Getenv("GITHUB_APP_INSTALLATION_ID"),
+		Key:            key,
+	}
+	return "", nil
+}
+
+// useRepo sets the address of the API to the one of the remote's host, and
+// the repository the GitHub App's installation is looked up for.
+func useRepo(g *commit.Git, r commit.RemoteInfo) {
+	if g.BaseURL == "" {
+		g.BaseURL = r.APIBaseURL()
+	}
+	if src, ok := g.TokenSource.(*commit.AppTokenSource); ok {
+		src.Owner, src.Repo = r.Owner, r.Name
+		src.BaseURL = g.BaseURL
+	}
+}
+
+// checkAuth checks that the token has the permissions the release needs on
+// the repository of the remote: the contents to create the tag and the
+// release, and the issues and the pull requests to comment on and to label.
+// The repositories that are not on GitHub are not checked.
+func checkAuth(ctx context.Context, g *commit.Git, token string) error {
+	r, err := g.RemoteInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if r.IsAzure() || r.IsBitbucket() {
+		return nil
+	}
+	useRepo(g, r)
+	perms := []commit.Permission{commit.PermContentsWrite}
+	if comment {
+		perms = append(perms, commit.PermIssuesWrite)
+	}
+	if labelPulls || pullNotice != "" {
+		perms = append(perms, commit.PermPullsWrite)
+	}
+	_, err = g.CheckPermissions(ctx, token, r.Owner, r.Name, perms...)
+	if errors.Is(err, commit.ErrMissingPermission) {
+		return fmt.Errorf("%w\nuse --skip-auth-check to release anyway", err)
+	}
+	return err
+}
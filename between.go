@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var betweenCmd = &cobra.Command{
+	Use:   "between FROM TO",
+	Short: "Print the combined notes of all releases between two tags",
+	Long: `Print the combined notes of all releases between two tags, with a section
+for each release in between. If the tags are adjacent the notes are the same as
+the notes of the release of TO.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		g, err := newGit()
+		if err != nil {
+			return err
+		}
+		sections, err := g.ReleasesBetween(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return err
+		}
+		opts, err := renderOptions()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(commit.RenderReleases(sections, opts...))
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(betweenCmd)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	draftAge     time.Duration
+	draftPattern string
+
+	cleanupDraftsCmd = &cobra.Command{
+		Use:   "cleanup-drafts",
+		Short: "Delete the stale draft releases of the failed runs",
+		Long: `Delete the draft releases that are older than --older-than and whose tags
+don't exist on the remote, or match --tag-pattern. The published releases and
+the recent drafts are never deleted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			opts := commit.DraftCleanupOptions{MinAge: draftAge}
+			if draftPattern != "" {
+				re, err := regexp.Compile(draftPattern)
+				if err != nil {
+					return fmt.Errorf("parsing the tag pattern: %w", err)
+				}
+				opts.Pattern = re
+			}
+			g, token, user, repo, err := githubRepo(ctx)
+			if err != nil {
+				return err
+			}
+			plan, err := g.PlanDraftCleanup(ctx, token, user, repo, opts)
+			if err != nil {
+				return err
+			}
+			if len(plan.Delete) == 0 {
+				fmt.Printf("no stale drafts, %d draft(s) kept\n", len(plan.Skipped))
+				return nil
+			}
+			fmt.Println("The following drafts will be deleted:")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, d := range plan.Delete {
+				fmt.Fprintf(w, "  %s\t%s\tcreated %s\t%s\n", draftName(d.Release), d.Release.Name,
+					d.Release.CreatedAt.Format("2006-01-02"), d.Reason)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if printMode {
+				return nil
+			}
+			if !assumeYes && !confirm(cmd, "Continue? [y/N] ") {
+				return errors.New("aborted")
+			}
+			summary, err := g.DeleteDrafts(ctx, token, user, repo, plan.Delete)
+			fmt.Printf("drafts: %d deleted, %d skipped, %d failed\n",
+				summary.Deleted, summary.Skipped+len(plan.Skipped), summary.Failed)
+			return err
+		},
+	}
+)
+
+// draftName returns the tag of the draft, or its id if it has none.
+func draftName(r commit.ReleaseDetails) string {
+	if r.TagName == "" {
+		return fmt.Sprintf("(id %d)", r.ID)
+	}
+	return r.TagName
+}
+
+func init() {
+	cleanupDraftsCmd.Flags().DurationVar(&draftAge, "older-than", commit.DefaultDraftAge, "only delete the drafts created longer ago than this")
+	cleanupDraftsCmd.Flags().StringVar(&draftPattern, "tag-pattern", "", "also delete the stale drafts whose tags exist if they match this regular expression, e.g. -ci\\.\\d+$")
+	cleanupDraftsCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "do not ask for confirmation")
+	rootCmd.AddCommand(cleanupDraftsCmd)
+}
@@ -0,0 +1,99 @@
+package commit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Actions writes the job summary and the step outputs of a GitHub Actions
+// step. The files are the ones of the GITHUB_STEP_SUMMARY and the GITHUB_OUTPUT
+// environment variables, and the empty ones are not written.
+type Actions struct {
+	SummaryFile string
+	OutputFile  string
+}
+
+// ActionsFromEnv returns the Actions of the environment variables of the
+// step. Both files are empty outside of GitHub Actions.
+func ActionsFromEnv(getenv func(string) string) Actions {
+	return Actions{
+		SummaryFile: getenv("GITHUB_STEP_SUMMARY"),
+		OutputFile:  getenv("GITHUB_OUTPUT"),
+	}
+}
+
+// Enabled returns true if any of the files is set.
+func (a Actions) Enabled() bool {
+	return a.SummaryFile != "" || a.OutputFile != ""
+}
+
+// ActionsOutput is an output of the step.
+type ActionsOutput struct {
+	Name  string
+	Value string
+}
+
+// AppendSummary appends the markdown to the job summary, separated from what
+// the other steps have written by a blank line.
+func (a Actions) AppendSummary(markdown string) error {
+	if a.SummaryFile == "" {
+		return nil
+	}
+	return appendFile(a.SummaryFile, "\n"+strings.TrimRight(markdown, "\n")+"\n")
+}
+
+// SetOutputs appends the outputs to the file of the step outputs, in order.
+// The values with more than one line are written with a random delimiter
+// that is not in them, as GitHub Actions expects.
+func (a Actions) SetOutputs(outputs ...ActionsOutput) error {
+	if a.OutputFile == "" || len(outputs) == 0 {
+		return nil
+	}
+	buf := &strings.Builder{}
+	for _, o := range outputs {
+		if strings.ContainsAny(o.Name, "=\n<") || o.Name == "" {
+			return fmt.Errorf("invalid output name %q", o.Name)
+		}
+		if !strings.ContainsAny(o.Value, "\r\n") {
+			fmt.Fprintf(buf, "%s=%s\n", o.Name, o.Value)
+			continue
+		}
+		delim, err := outputDelimiter(o.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%s<<%s\n%s\n%s\n", o.Name, delim, o.Value, delim)
+	}
+	return appendFile(a.OutputFile, buf.String())
+}
+
+// outputDelimiter returns a delimiter of a multiline output that doesn't
+// occur in the value.
+func outputDelimiter(value string) (string, error) {
+	b := make([]byte, 16)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("creating the delimiter of the output: %w", err)
+		}
+		delim := "ghadelimiter_" + hex.EncodeToString(b)
+		if !strings.Contains(value, delim) {
+			return delim, nil
+		}
+	}
+}
+
+// appendFile appends the content to the file, which the runner has created.
+func appendFile(name, content string) error {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
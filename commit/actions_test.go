@@ -0,0 +1,64 @@
+package commit_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionsFromEnv(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{
+		"GITHUB_STEP_SUMMARY": "/tmp/summary",
+		"GITHUB_OUTPUT":       "/tmp/output",
+	}
+	a := commit.ActionsFromEnv(func(key string) string { return env[key] })
+	assert.Equal(t, commit.Actions{SummaryFile: "/tmp/summary", OutputFile: "/tmp/output"}, a)
+	assert.True(t, a.Enabled())
+
+	a = commit.ActionsFromEnv(func(string) string { return "" })
+	assert.False(t, a.Enabled())
+	// Outside of Actions nothing is written.
+	assert.NoError(t, a.AppendSummary("### Fix"))
+	assert.NoError(t, a.SetOutputs(commit.ActionsOutput{Name: "version", Value: "v1.0.0"}))
+}
+
+func TestActionsAppendSummary(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "summary")
+	require.NoError(t, os.WriteFile(name, []byte("# Build\n"), 0o644))
+	a := commit.Actions{SummaryFile: name}
+	require.NoError(t, a.AppendSummary("### Fix\n\n- The leak\n\n"))
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "# Build\n\n### Fix\n\n- The leak\n", string(b))
+}
+
+func TestActionsSetOutputs(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, os.WriteFile(name, []byte("other=1\n"), 0o644))
+	a := commit.Actions{OutputFile: name}
+	body := "### Fix\n\n- The leak\n"
+	err := a.SetOutputs(
+		commit.ActionsOutput{Name: "version", Value: "v1.1.0"},
+		commit.ActionsOutput{Name: "previous", Value: ""},
+		commit.ActionsOutput{Name: "body", Value: body},
+	)
+	require.NoError(t, err)
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	re := regexp.MustCompile(`(?s)^other=1\nversion=v1\.1\.0\nprevious=\nbody<<(ghadelimiter_[0-9a-f]+)\n(.*)\n(ghadelimiter_[0-9a-f]+)\n$`)
+	m := re.FindStringSubmatch(string(b))
+	require.NotNil(t, m, string(b))
+	assert.Equal(t, m[1], m[3])
+	assert.Equal(t, body, m[2])
+
+	err = a.SetOutputs(commit.ActionsOutput{Name: "bad=name", Value: "x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package commit
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// AssetMapping selects the files to upload and names them.
+type AssetMapping struct {
+	// Glob selects the files, e.g. "dist/*.tar.gz".
+	Glob string
+	// Name is a text/template for the name of the asset, e.g.
+	// "{{.Name}}_{{.Version}}_{{.OS}}_{{.Arch}}{{.Ext}}". If empty, the file's
+	// base name is used.
+	Name string
+	// Vars are available in the template, and take precedence over the other
+	// variables.
+	Vars map[string]string
+}
+
+// Asset is a file to be uploaded with the Name.
+type Asset struct {
+	Path string
+	Name string
+}
+
+// ParseAssetMapping parses a mapping in the "glob=template" form. The
+// template is optional.
+func ParseAssetMapping(s string) (AssetMapping, error) {
+	glob, name, _ := strings.Cut(s, "=")
+	if glob == "" {
+		return AssetMapping{}, fmt.Errorf("no glob in asset mapping %q", s)
+	}
+	return AssetMapping{Glob: glob, Name: name}, nil
+}
+
+// ResolveAssets returns the assets of the files matching the mappings. The
+// templates can use the variables parsed from the file names, the vars, and
+// the mapping's Vars, each overriding the previous ones. A file matching more
+// than one mapping is only named by the first one. If two files end up with
+// the same name, an error listing all collisions is returned.
+func ResolveAssets(mappings []AssetMapping, vars map[string]string) ([]Asset, error) {
+	var assets []Asset
+	seen := make(map[string]bool)
+	for _, m := range mappings {
+		files, err := filepath.Glob(m.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("matching %q: %w", m.Glob, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no files match %q", m.Glob)
+		}
+		var tmpl *template.Template
+		if m.Name != "" {
+			tmpl, err = template.New(m.Glob).Option("missingkey=error").Parse(m.Name)
+			if err != nil {
+				return nil, fmt.Errorf("parsing template of %q: %w", m.Glob, err)
+			}
+		}
+		for _, f := range files {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			name := filepath.Base(f)
+			if tmpl != nil {
+				if name, err = assetName(tmpl, f, vars, m.Vars); err != nil {
+					return nil, fmt.Errorf("naming %s: %w", f, err)
+				}
+			}
+			assets = append(assets, Asset{Path: f, Name: name})
+		}
+	}
+	return assets, checkCollisions(assets)
+}
+
+func assetName(tmpl *template.Template, path string, vars ...map[string]string) (string, error) {
+	data := ParseAssetName(filepath.Base(path))
+	for _, v := range vars {
+		for k, val := range v {
+			data[k] = val
+		}
+	}
+	buf := &strings.Builder{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid asset name %q", name)
+	}
+	return name, nil
+}
+
+func checkCollisions(assets []Asset) error {
+	byName := make(map[string][]string, len(assets))
+	for _, a := range assets {
+		byName[a.Name] = append(byName[a.Name], a.Path)
+	}
+	var collisions []string
+	for name, paths := range byName {
+		if len(paths) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s: %s", name, strings.Join(paths, ", ")))
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	sort.Strings(collisions)
+	return fmt.Errorf("asset names collide:\n%s", strings.Join(collisions, "\n"))
+}
+
+var (
+	archiveExts = []string{".tar.gz", ".tar.xz", ".tar.bz2", ".tar.zst"}
+	knownOS     = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "illumos": true, "ios": true, "js": true,
+		"linux": true, "netbsd": true, "openbsd": true, "plan9": true,
+		"solaris": true, "wasip1": true, "windows": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true, "armv5": true,
+		"armv6": true, "armv7": true, "loong64": true, "mips": true,
+		"mips64": true, "mips64le": true, "mipsle": true, "ppc64": true,
+		"ppc64le": true, "riscv64": true, "s390x": true, "wasm": true,
+		"x86_64": true, "all": true,
+	}
+)
+
+// ParseAssetName parses the variables of a goreleaser style file name, e.g.
+// "app_1.2.3_linux_amd64.tar.gz". It returns the Name, Version, OS, Arch and
+// Ext variables, leaving out the ones it can't find.
+func ParseAssetName(filename string) map[string]string {
+	vars := make(map[string]string, 5)
+	stem := filename
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(stem, ext) {
+			vars["Ext"] = ext
+			stem = strings.TrimSuffix(stem, ext)
+			break
+		}
+	}
+	if _, ok := vars["Ext"]; !ok {
+		ext := filepath.Ext(stem)
+		// Binaries usually don't have an extension, and the last part of
+		// their version shouldn't be taken as one.
+		if !isExt(ext) {
+			ext = ""
+		}
+		vars["Ext"] = ext
+		stem = strings.TrimSuffix(stem, ext)
+	}
+
+	parts := strings.Split(stem, "_")
+	osIdx := -1
+	for i, p := range parts {
+		if i > 0 && knownOS[strings.ToLower(p)] {
+			osIdx = i
+			break
+		}
+	}
+	if osIdx < 0 {
+		vars["Name"] = stem
+		return vars
+	}
+	vars["Name"] = parts[0]
+	if osIdx > 1 {
+		vars["Version"] = strings.Join(parts[1:osIdx], "_")
+	}
+	vars["OS"] = parts[osIdx]
+	if osIdx+1 < len(parts) && knownArch[strings.ToLower(parts[osIdx+1])] {
+		vars["Arch"] = strings.Join(parts[osIdx+1:], "_")
+	}
+	return vars
+}
+
+// isExt returns true if ext looks like a file extension, e.g. ".zip".
+func isExt(ext string) bool {
+	letter := false
+	for _, r := range strings.TrimPrefix(ext, ".") {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return letter
+}
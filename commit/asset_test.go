@@ -0,0 +1,115 @@
+package commit_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAssetName(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		filename string
+		want     map[string]string
+	}{
+		"goreleaser": {"app_1.2.3_linux_amd64.tar.gz", map[string]string{
+			"Name": "app", "Version": "1.2.3", "OS": "linux", "Arch": "amd64", "Ext": ".tar.gz",
+		}},
+		"windows": {"app_1.2.3_windows_386.zip", map[string]string{
+			"Name": "app", "Version": "1.2.3", "OS": "windows", "Arch": "386", "Ext": ".zip",
+		}},
+		"no version": {"app_darwin_arm64", map[string]string{
+			"Name": "app", "OS": "darwin", "Arch": "arm64", "Ext": "",
+		}},
+		"binary": {"app_1.2.3_linux_armv7", map[string]string{
+			"Name": "app", "Version": "1.2.3", "OS": "linux", "Arch": "armv7", "Ext": "",
+		}},
+		"unknown": {"checksums.txt", map[string]string{
+			"Name": "checksums", "Ext": ".txt",
+		}},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseAssetName(tc.filename))
+		})
+	}
+}
+
+func TestParseAssetMapping(t *testing.T) {
+	t.Parallel()
+	got, err := commit.ParseAssetMapping("dist/*.zip={{.Name}}{{.Ext}}")
+	require.NoError(t, err)
+	assert.Equal(t, commit.AssetMapping{Glob: "dist/*.zip", Name: "{{.Name}}{{.Ext}}"}, got)
+
+	got, err = commit.ParseAssetMapping("dist/*.zip")
+	require.NoError(t, err)
+	assert.Equal(t, commit.AssetMapping{Glob: "dist/*.zip"}, got)
+
+	_, err = commit.ParseAssetMapping("={{.Name}}")
+	assert.Error(t, err)
+}
+
+func TestResolveAssets(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, f := range []string{
+		"app_1.2.3_linux_amd64.tar.gz",
+		"app_1.2.3_darwin_arm64.tar.gz",
+		"checksums.txt",
+	} {
+		createFile(t, dir, f, f)
+	}
+	glob := func(p string) string { return filepath.Join(dir, p) }
+
+	t.Run("Template", func(t *testing.T) {
+		t.Parallel()
+		mappings := []commit.AssetMapping{
+			{Glob: glob("*.tar.gz"), Name: "{{.Project}}_{{.Version}}_{{.OS}}_{{.Arch}}{{.Ext}}", Vars: map[string]string{"Project": "tool"}},
+			{Glob: glob("*")},
+		}
+		got, err := commit.ResolveAssets(mappings, map[string]string{"Version": "v1.2.3"})
+		require.NoError(t, err)
+		want := []commit.Asset{
+			{Path: glob("app_1.2.3_darwin_arm64.tar.gz"), Name: "tool_v1.2.3_darwin_arm64.tar.gz"},
+			{Path: glob("app_1.2.3_linux_amd64.tar.gz"), Name: "tool_v1.2.3_linux_amd64.tar.gz"},
+			{Path: glob("checksums.txt"), Name: "checksums.txt"},
+		}
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		t.Parallel()
+		mappings := []commit.AssetMapping{{Glob: glob("*.tar.gz"), Name: "{{.Name}}{{.Ext}}"}}
+		_, err := commit.ResolveAssets(mappings, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "app.tar.gz")
+		assert.Contains(t, err.Error(), glob("app_1.2.3_linux_amd64.tar.gz"))
+		assert.Contains(t, err.Error(), glob("app_1.2.3_darwin_arm64.tar.gz"))
+	})
+
+	t.Run("MissingVar", func(t *testing.T) {
+		t.Parallel()
+		mappings := []commit.AssetMapping{{Glob: glob("checksums.txt"), Name: "{{.OS}}"}}
+		_, err := commit.ResolveAssets(mappings, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		t.Parallel()
+		mappings := []commit.AssetMapping{{Glob: glob("*.deb")}}
+		_, err := commit.ResolveAssets(mappings, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		t.Parallel()
+		mappings := []commit.AssetMapping{{Glob: glob("checksums.txt"), Name: "a/{{.Name}}"}}
+		_, err := commit.ResolveAssets(mappings, nil)
+		assert.Error(t, err)
+	})
+}
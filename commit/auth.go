@@ -0,0 +1,174 @@
+package commit
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBadPrivateKey is returned when the private key of a GitHub App can't
+	// be parsed, or GitHub rejects the JWT signed with it.
+	ErrBadPrivateKey = errors.New("bad private key")
+	// ErrAppNotInstalled is returned when the GitHub App is not installed on
+	// the repository.
+	ErrAppNotInstalled = errors.New("app not installed on this repo")
+)
+
+// A TokenSource returns the token for the GitHub API. It is used when no
+// token is passed to the API methods of Git.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// tokenRefreshMargin is how long before their expiry the installation tokens
+// are refreshed.
+const tokenRefreshMargin = 5 * time.Minute
+
+// AppTokenSource returns the installation tokens of a GitHub App. The tokens
+// are valid for an hour and are refreshed when they are about to expire.
+type AppTokenSource struct {
+	AppID string
+	// InstallationID is looked up from the Owner and Repo if it's empty.
+	InstallationID string
+	Owner          string
+	Repo           string
+	Key            *rsa.PrivateKey
+	// BaseURL is the address of the GitHub API. It defaults to
+	// https://api.github.com.
+	BaseURL string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	perms   map[string]string
+	now     func() time.Time
+}
+
+// ParsePrivateKey parses the PEM encoded private key of a GitHub App. It
+// returns an error wrapping ErrBadPrivateKey if the key can't be parsed.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found: %w", ErrBadPrivateKey)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v: %w", block.Type, err, ErrBadPrivateKey)
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an RSA key: %w", k, ErrBadPrivateKey)
+	}
+	return key, nil
+}
+
+// Token returns a valid installation token, creating a new one if needed.
+func (a *AppTokenSource) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.clock()
+	if a.token != "" && now.Add(tokenRefreshMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(now)
+	if err != nil {
+		return "", err
+	}
+	g := &Git{BaseURL: a.BaseURL}
+	id := a.InstallationID
+	if id == "" {
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		uri := fmt.Sprintf("/repos/%s/%s/installation", a.Owner, a.Repo)
+		if err := g.apiBearer(ctx, jwt, http.MethodGet, uri, &inst); err != nil {
+			return "", appError(err, fmt.Sprintf("%s/%s", a.Owner, a.Repo))
+		}
+		id = fmt.Sprint(inst.ID)
+	}
+
+	var res struct {
+		Token       string            `json:"token"`
+		ExpiresAt   time.Time         `json:"expires_at"`
+		Permissions map[string]string `json:"permissions"`
+	}
+	uri := fmt.Sprintf("/app/installations/%s/access_tokens", id)
+	if err := g.apiBearer(ctx, jwt, http.MethodPost, uri, &res); err != nil {
+		return "", appError(err, "installation "+id)
+	}
+	a.token, a.expires, a.perms = res.Token, res.ExpiresAt, res.Permissions
+	return a.token, nil
+}
+
+// Permissions returns the permissions the current token is issued with, e.g.
+// "contents" is "write". It's nil before the first token.
+func (a *AppTokenSource) Permissions() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.perms == nil {
+		return nil
+	}
+	perms := make(map[string]string, len(a.perms))
+	for k, v := range a.perms {
+		perms[k] = v
+	}
+	return perms
+}
+
+func appError(err error, target string) error {
+	switch {
+	case hasStatus(err, http.StatusUnauthorized):
+		return fmt.Errorf("GitHub rejected the app's JWT: %v: %w", err, ErrBadPrivateKey)
+	case hasStatus(err, http.StatusNotFound):
+		return fmt.Errorf("%s: %w", target, ErrAppNotInstalled)
+	}
+	return fmt.Errorf("getting installation token: %w", err)
+}
+
+func (a *AppTokenSource) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// jwt returns a JWT signed by the app's key, which is valid for ten minutes.
+func (a *AppTokenSource) jwt(now time.Time) (string, error) {
+	if a.Key == nil {
+		return "", fmt.Errorf("no private key: %w", ErrBadPrivateKey)
+	}
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		// Allow for the clock drift of GitHub.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", err.Error(), ErrBadPrivateKey)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
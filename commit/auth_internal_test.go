@@ -0,0 +1,8 @@
+package commit
+
+import "time"
+
+// SetClock replaces the clock of the AppTokenSource for testing.
+func SetClock(a *AppTokenSource, now func() time.Time) {
+	a.now = now
+}
@@ -0,0 +1,237 @@
+package commit_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	appKeyOnce sync.Once
+	appKey     *rsa.PrivateKey
+)
+
+func testAppKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	appKeyOnce.Do(func() {
+		var err error
+		appKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+	})
+	return appKey
+}
+
+// fakeApp serves the GitHub App endpoints. It verifies the JWTs with the key.
+type fakeApp struct {
+	*httptest.Server
+	mu        sync.Mutex
+	issued    int
+	installed bool
+	status    int
+	// perms are the permissions of the issued tokens.
+	perms map[string]string
+}
+
+func newFakeApp(t *testing.T, key *rsa.PublicKey) *fakeApp {
+	t.Helper()
+	f := &fakeApp{installed: true}
+	verify := func(w http.ResponseWriter, r *http.Request) bool {
+		f.mu.Lock()
+		status := f.status
+		f.mu.Unlock()
+		if status != 0 {
+			w.WriteHeader(status)
+			return false
+		}
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if !assert.Len(t, parts, 3) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		assert.Contains(t, string(claims), `"iss":"123"`)
+		return true
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/user/repo/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !verify(w, r) {
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.installed {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id":42}`)
+	})
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		if !verify(w, r) {
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.issued++
+		w.WriteHeader(http.StatusCreated)
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"token":       fmt.Sprintf("token-%d", f.issued),
+			"expires_at":  time.Now().Add(time.Hour),
+			"permissions": f.perms,
+		}))
+	})
+	mux.HandleFunc("/repos/user/repo", func(w http.ResponseWriter, r *http.Request) {
+		// The installation tokens get no permissions field.
+		fmt.Fprint(w, `{"id":1,"private":true}`)
+	})
+	mux.HandleFunc("/repos/user/repo/releases/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		_, pass, ok := r.BasicAuth()
+		if !ok || !strings.HasPrefix(pass, "token-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	t.Parallel()
+	key := testAppKey(t)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	got, err := commit.ParsePrivateKey(pkcs1)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(got))
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	got, err = commit.ParsePrivateKey(pkcs8)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(got))
+
+	_, err = commit.ParsePrivateKey([]byte("not a key"))
+	assert.ErrorIs(t, err, commit.ErrBadPrivateKey)
+	garbage := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})
+	_, err = commit.ParsePrivateKey(garbage)
+	assert.ErrorIs(t, err, commit.ErrBadPrivateKey)
+}
+
+func TestAppTokenSource(t *testing.T) {
+	t.Parallel()
+	t.Run("Token", testAppTokenSourceToken)
+	t.Run("NotInstalled", testAppTokenSourceNotInstalled)
+	t.Run("WrongKey", testAppTokenSourceWrongKey)
+	t.Run("Git", testAppTokenSourceGit)
+}
+
+func testAppTokenSourceToken(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key := testAppKey(t)
+	app := newFakeApp(t, &key.PublicKey)
+	app.perms = map[string]string{"contents": "write"}
+	src := &commit.AppTokenSource{AppID: "123", Owner: "user", Repo: "repo", Key: key, BaseURL: app.URL}
+	now := time.Now()
+	commit.SetClock(src, func() time.Time { return now })
+	assert.Nil(t, src.Permissions())
+
+	got, err := src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", got)
+	assert.Equal(t, map[string]string{"contents": "write"}, src.Permissions())
+
+	now = now.Add(50 * time.Minute)
+	got, err = src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", got, "the token should be reused")
+
+	now = now.Add(6 * time.Minute)
+	got, err = src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", got, "the token should be refreshed before it expires")
+}
+
+func testAppTokenSourceNotInstalled(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key := testAppKey(t)
+	app := newFakeApp(t, &key.PublicKey)
+	app.installed = false
+	src := &commit.AppTokenSource{AppID: "123", Owner: "user", Repo: "repo", Key: key, BaseURL: app.URL}
+
+	_, err := src.Token(ctx)
+	assert.ErrorIs(t, err, commit.ErrAppNotInstalled)
+	assert.NotErrorIs(t, err, commit.ErrBadPrivateKey)
+
+	src.InstallationID = "42"
+	got, err := src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", got)
+}
+
+func testAppTokenSourceWrongKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key := testAppKey(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	app := newFakeApp(t, &other.PublicKey)
+	src := &commit.AppTokenSource{AppID: "123", Owner: "user", Repo: "repo", Key: key, BaseURL: app.URL}
+
+	_, err = src.Token(ctx)
+	assert.ErrorIs(t, err, commit.ErrBadPrivateKey)
+	assert.NotErrorIs(t, err, commit.ErrAppNotInstalled)
+
+	src.Key = nil
+	_, err = src.Token(ctx)
+	assert.ErrorIs(t, err, commit.ErrBadPrivateKey)
+}
+
+func testAppTokenSourceGit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key := testAppKey(t)
+	app := newFakeApp(t, &key.PublicKey)
+	g := &commit.Git{
+		BaseURL: app.URL,
+		TokenSource: &commit.AppTokenSource{
+			AppID: "123", InstallationID: "42", Key: key, BaseURL: app.URL,
+		},
+	}
+	got, err := g.GetReleaseByTag(ctx, "", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.ID)
+
+	// An explicit token takes precedence.
+	_, err = g.GetReleaseByTag(ctx, "pat", "user", "repo", "v1.0.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package commit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Logins maps the lower case emails of the authors to their GitHub logins.
+type Logins map[string]string
+
+// Add maps the emails to the login. The "@" of the login is optional.
+func (l Logins) Add(login string, emails ...string) {
+	login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+	for _, e := range emails {
+		l[strings.ToLower(strings.TrimSpace(e))] = login
+	}
+}
+
+// mailmapEmailRe matches the emails of a line of a mailmap.
+var mailmapEmailRe = regexp.MustCompile(`<([^<>]*)>`)
+
+// ParseLogins reads the logins in the style of a .mailmap file. Each line is
+// a login followed by the emails of its commits, and the lines starting with
+// "#" are comments:
+//
+//	@jsmith <j.smith@example.com> <john@old.example.com>
+//	octocat <octocat@example.com>
+func ParseLogins(r io.Reader) (Logins, error) {
+	l := make(Logins)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		login, rest, _ := strings.Cut(line, "<")
+		login = strings.TrimSpace(login)
+		matches := mailmapEmailRe.FindAllStringSubmatch("<"+rest, -1)
+		if login == "" || strings.ContainsAny(login, " \t") || len(matches) == 0 {
+			return nil, fmt.Errorf("line %d: want a login and its emails, e.g. jsmith <j.smith@example.com>: %q", n, line)
+		}
+		for _, m := range matches {
+			l.Add(login, m[1])
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading the logins: %w", err)
+	}
+	return l, nil
+}
+
+// AuthorName returns the name of the Author, without the email.
+func (c Commit) AuthorName() string {
+	name, _, _ := strings.Cut(c.Author, "<")
+	return strings.TrimSpace(name)
+}
+
+// AuthorEmail returns the lower case email of the Author, or an empty string
+// if it has none.
+func (c Commit) AuthorEmail() string {
+	_, email, ok := strings.Cut(c.Author, "<")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(email), ">")))
+}
+
+// Attribution returns the mention of the Login of the author, e.g.
+// "@jsmith", or the name of the author if the Login is not known.
+func (c Commit) Attribution() string {
+	if c.Login != "" {
+		return "@" + c.Login
+	}
+	return c.AuthorName()
+}
+
+// noreplyRe matches the noreply emails of GitHub, e.g.
+// "12345+jsmith@users.noreply.github.com".
+var noreplyRe = regexp.MustCompile(`^(?:\d+\+)?([a-z0-9](?:[a-z0-9-]*[a-z0-9])?)@users\.noreply\.github\.com$`)
+
+// noreplyLogin returns the login of a noreply email of GitHub, or an empty
+// string if the email is not one.
+func noreplyLogin(email string) string {
+	m := noreplyRe.FindStringSubmatch(email)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// ResolveLogins sets the Login of the commits. The emails of the authors are
+// looked up in the logins first, and the noreply emails of GitHub have the
+// logins in them. For the rest, the authors of the commits are looked up from
+// the API, once for each email, unless offline is set. The logins the API
+// finds are cached for the Git, and in the Ranges if it is set. A failed
+// lookup doesn't stop the others, and the commits without a login are
+// attributed to their authors' names.
+func (g *Git) ResolveLogins(ctx context.Context, token string, remote RemoteInfo, commits []Commit, logins Logins, offline bool) error {
+	found := g.cachedLogins(ctx, token, remote)
+	pending := make(map[string]string)
+	for i := range commits {
+		email := commits[i].AuthorEmail()
+		login, known := g.knownLogin(email, logins, found)
+		switch {
+		case login != "":
+			commits[i].Login = login
+		case !known && !offline:
+			if _, ok := pending[email]; !ok {
+				pending[email] = commits[i].SHA
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []string
+		looked   = make(map[string]string, len(pending))
+	)
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(4)
+	for email, sha := range pending {
+		email, sha := email, sha
+		eg.Go(func() error {
+			login, err := g.commitLogin(egCtx, token, remote, sha)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", email, err))
+				return nil
+			}
+			looked[email] = login
+			return nil
+		})
+	}
+	// nolint:errcheck // the goroutines don't return errors.
+	eg.Wait()
+
+	g.mu.Lock()
+	if g.logins == nil {
+		g.logins = make(map[string]string)
+	}
+	for email, login := range looked {
+		g.logins[email] = login
+		if login != "" {
+			found[email] = login
+		}
+	}
+	g.mu.Unlock()
+	for i := range commits {
+		if login := looked[commits[i].AuthorEmail()]; login != "" {
+			commits[i].Login = login
+		}
+	}
+	g.storeLogins(ctx, token, remote, found)
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("failed to look up the logins of %d author(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// knownLogin returns the login of the email without asking the API. It
+// returns false if the email is not known, and an empty login if it doesn't
+// belong to any user.
+func (g *Git) knownLogin(email string, logins Logins, found map[string]string) (string, bool) {
+	if email == "" {
+		return "", true
+	}
+	if login := logins[email]; login != "" {
+		return login, true
+	}
+	if login := noreplyLogin(email); login != "" {
+		return login, true
+	}
+	if login := found[email]; login != "" {
+		return login, true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	login, ok := g.logins[email]
+	return login, ok
+}
+
+// commitLogin returns the login of the author of the commit, or an empty
+// string if the email of the commit doesn't belong to any user.
+func (g *Git) commitLogin(ctx context.Context, token string, remote RemoteInfo, sha string) (string, error) {
+	var c struct {
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/commits/%s", remote.Owner, remote.Name, sha)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &c); err != nil {
+		return "", err
+	}
+	if c.Author == nil {
+		return "", nil
+	}
+	return c.Author.Login, nil
+}
+
+// loginsKey returns the key of the logins of the repository in the Ranges,
+// or an empty string if the Ranges is not set.
+func (g *Git) loginsKey(ctx context.Context, token string, remote RemoteInfo) string {
+	if g.Ranges == nil {
+		return ""
+	}
+	token, err := g.token(ctx, token)
+	if err != nil {
+		return ""
+	}
+	return hashKey(token, g.baseURL(), remote.Owner, remote.Name, g.Ranges.Options)
+}
+
+// cachedLogins returns the logins the API has found in the previous runs,
+// keyed by the emails.
+func (g *Git) cachedLogins(ctx context.Context, token string, remote RemoteInfo) map[string]string {
+	found := make(map[string]string)
+	key := g.loginsKey(ctx, token, remote)
+	if key == "" || g.Ranges.Refresh {
+		return found
+	}
+	g.readRangeCache("logins", key, &found)
+	return found
+}
+
+// storeLogins keeps the logins the API has found for the next runs.
+func (g *Git) storeLogins(ctx context.Context, token string, remote RemoteInfo, found map[string]string) {
+	if len(found) > 0 {
+		g.writeRangeCache("logins", g.loginsKey(ctx, token, remote), found)
+	}
+}
+
+// contributorsHeading is the name of the section of the authors.
+const contributorsHeading = "Contributors"
+
+// WithAttribution adds the authors of the commits to their entries, e.g.
+// "- Add the thing by @jsmith". The authors are in the order of the logs of
+// ParseGroups, e.g. the Attribution of each commit, and the logs without an
+// author are not attributed.
+func WithAttribution(authors []string) RenderOption {
+	return func(o *renderOptions) {
+		o.authors = authors
+	}
+}
+
+// WithContributors adds a "Contributors" section with the authors of the
+// commits after the changes, e.g. the Attribution of each commit. Each author
+// is listed once, in the order of their first commits.
+func WithContributors(authors []string) RenderOption {
+	return func(o *renderOptions) {
+		o.contributors = authors
+	}
+}
+
+// contributorsSection returns the section of the contributors, or an empty
+// string if there are none.
+func (o *renderOptions) contributorsSection() string {
+	seen := make(map[string]bool, len(o.contributors))
+	buf := &strings.Builder{}
+	for _, a := range o.contributors {
+		key := strings.ToLower(a)
+		if a == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(buf, "\n- %s", a)
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	return "### " + o.locale.Heading(contributorsHeading) + "\n" + buf.String()
+}
+
+// attribution returns the suffix of the entry of the log at i with its
+// author, or an empty string if it has none.
+func (o *renderOptions) attribution(i int) string {
+	if i >= len(o.authors) || o.authors[i] == "" {
+		return ""
+	}
+	return " " + o.locale.by() + " " + o.authors[i]
+}
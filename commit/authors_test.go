@@ -0,0 +1,190 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogins(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		input   string
+		want    commit.Logins
+		wantErr bool
+	}{
+		"logins": {
+			input: "# the team\n@jsmith <J.Smith@example.com> <john@old.example.com>\n\noctocat <octocat@example.com>\n",
+			want: commit.Logins{
+				"j.smith@example.com":  "jsmith",
+				"john@old.example.com": "jsmith",
+				"octocat@example.com":  "octocat",
+			},
+		},
+		"empty":      {input: "\n# nothing\n", want: commit.Logins{}},
+		"no email":   {input: "jsmith\n", wantErr: true},
+		"no login":   {input: "<j.smith@example.com>\n", wantErr: true},
+		"with space": {input: "John Smith <j.smith@example.com>\n", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseLogins(strings.NewReader(tc.input))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCommitAttribution(t *testing.T) {
+	t.Parallel()
+	c := commit.Commit{Author: "John Smith <J.Smith@Example.com>"}
+	assert.Equal(t, "John Smith", c.AuthorName())
+	assert.Equal(t, "j.smith@example.com", c.AuthorEmail())
+	assert.Equal(t, "John Smith", c.Attribution())
+	c.Login = "jsmith"
+	assert.Equal(t, "@jsmith", c.Attribution())
+	assert.Empty(t, commit.Commit{Author: "nobody"}.AuthorEmail())
+}
+
+// fakeAuthors serves the authors of the commits.
+type fakeAuthors struct {
+	*httptest.Server
+	mu       sync.Mutex
+	logins   map[string]string
+	requests []string
+}
+
+func newFakeAuthors(t *testing.T, logins map[string]string) *fakeAuthors {
+	t.Helper()
+	f := &fakeAuthors{logins: logins}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sha := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/commits/")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, sha)
+		login, ok := f.logins[sha]
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusInternalServerError)
+		case login == "":
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"author": nil}))
+		default:
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"author": map[string]string{"login": login}}))
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAuthors) list() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func TestGitResolveLogins(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	remote := commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"}
+	newCommits := func() []commit.Commit {
+		return []commit.Commit{
+			{SHA: "aaa", Author: "John Smith <j.smith@example.com>"},
+			{SHA: "bbb", Author: "Octo Cat <12345+octocat@users.noreply.github.com>"},
+			{SHA: "ccc", Author: "Jane Doe <jane@example.com>"},
+			{SHA: "ddd", Author: "Jane Doe <JANE@example.com>"},
+			{SHA: "eee", Author: "Someone <someone@example.com>"},
+			{SHA: "fff", Author: "Broken <broken@example.com>"},
+		}
+	}
+	logins := commit.Logins{"j.smith@example.com": "jsmith"}
+	attributions := func(commits []commit.Commit) []string {
+		ret := make([]string, 0, len(commits))
+		for _, c := range commits {
+			ret = append(ret, c.Attribution())
+		}
+		return ret
+	}
+
+	t.Run("Lookup", func(t *testing.T) {
+		t.Parallel()
+		gh := newFakeAuthors(t, map[string]string{"ccc": "janedoe", "eee": ""})
+		g := &commit.Git{BaseURL: gh.URL}
+		commits := newCommits()
+		err := g.ResolveLogins(ctx, "token", remote, commits, logins, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken@example.com")
+		assert.Equal(t, []string{"@jsmith", "@octocat", "@janedoe", "@janedoe", "Someone", "Broken"}, attributions(commits))
+		assert.ElementsMatch(t, []string{"ccc", "eee", "fff"}, gh.list())
+
+		// The found logins, and the emails without any, are not looked up
+		// again.
+		commits = newCommits()
+		err = g.ResolveLogins(ctx, "token", remote, commits, logins, false)
+		require.Error(t, err)
+		assert.Equal(t, []string{"@jsmith", "@octocat", "@janedoe", "@janedoe", "Someone", "Broken"}, attributions(commits))
+		assert.ElementsMatch(t, []string{"ccc", "eee", "fff", "fff"}, gh.list())
+	})
+
+	t.Run("Offline", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Runner: fakeRunner(func(args []string) (string, error) {
+			t.Errorf("unexpected git call: %v", args)
+			return "", nil
+		})}
+		commits := newCommits()
+		err := g.ResolveLogins(ctx, "token", remote, commits, logins, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"@jsmith", "@octocat", "Jane Doe", "Jane Doe", "Someone", "Broken"}, attributions(commits))
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		t.Parallel()
+		gh := newFakeAuthors(t, map[string]string{"ccc": "janedoe", "eee": "", "fff": "broken"})
+		ranges := &commit.RangeCache{Dir: t.TempDir()}
+		g := &commit.Git{BaseURL: gh.URL, Ranges: ranges}
+		require.NoError(t, g.ResolveLogins(ctx, "token", remote, newCommits(), logins, false))
+		assert.Len(t, gh.list(), 3)
+
+		// Another run only looks up the emails without logins.
+		g = &commit.Git{BaseURL: gh.URL, Ranges: ranges}
+		commits := newCommits()
+		require.NoError(t, g.ResolveLogins(ctx, "token", remote, commits, logins, false))
+		assert.Equal(t, []string{"@jsmith", "@octocat", "@janedoe", "@janedoe", "Someone", "@broken"}, attributions(commits))
+		assert.Equal(t, []string{"eee"}, gh.list()[3:])
+	})
+}
+
+func TestParseGroupsAttribution(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing",
+		"fix: the leak (#12)",
+		"fix: the typo",
+	}
+	authors := []string{"@jsmith", "Jane Doe", "@jsmith"}
+	got := commit.ParseGroups(logs, commit.WithAttribution(authors[:2]), commit.WithContributors(authors))
+	want := "### Feature\n\n- The thing by @jsmith\n\n\n" +
+		"### Fix\n\n- The leak (#12) by Jane Doe\n- The typo\n\n" +
+		"### Contributors\n\n- @jsmith\n- Jane Doe"
+	assert.Equal(t, want, got)
+
+	got = commit.ParseGroups(logs[:1], commit.WithAttribution(authors), commit.WithLocale(commit.Locale{
+		By:       "von",
+		Headings: map[string]string{"contributors": "Mitwirkende"},
+	}), commit.WithContributors(authors[:1]))
+	assert.Equal(t, "### Feature\n\n- The thing von @jsmith\n\n### Mitwirkende\n\n- @jsmith", got)
+}
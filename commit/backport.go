@@ -0,0 +1,118 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cherryPickRe matches the trailer "git cherry-pick -x" adds to the messages.
+var cherryPickRe = regexp.MustCompile(`(?m)^\s*\(cherry picked from commit ([0-9a-f]{7,40})\)\s*$`)
+
+// CherryPickOf returns the commit the message is cherry-picked from, as it is
+// in the "(cherry picked from commit <sha>)" trailer, or an empty string if
+// it has none. The first trailer is the original commit if the commit is
+// cherry-picked more than once.
+func CherryPickOf(msg string) string {
+	m := cherryPickRe.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// Backport is the original commit of a cherry-picked one.
+type Backport struct {
+	// SHA is the full hash of the original commit, or the one of the trailer
+	// if it's not in the repository.
+	SHA string
+	// URL is the address of the page of the original commit.
+	URL string
+	// PRNumber is the number of the pull request of the original commit, or
+	// zero if it's not known.
+	PRNumber int
+}
+
+// Backports returns the original commits of the cherry-picked commits, keyed
+// by the hashes of their trailers. The original commits are read with one
+// git process, and the ones that are not in the repository only have the
+// hash of the trailer. The commits are linked to the pages of the r.
+func (g *Git) Backports(ctx context.Context, r RemoteInfo, commits []Commit) (map[string]Backport, error) {
+	ret := make(map[string]Backport)
+	var shas []string
+	for _, c := range commits {
+		sha := CherryPickOf(c.Message)
+		if sha == "" {
+			continue
+		}
+		if _, ok := ret[sha]; !ok {
+			ret[sha] = Backport{SHA: sha}
+			shas = append(shas, sha)
+		}
+	}
+	if len(shas) == 0 {
+		return ret, nil
+	}
+	// The paths are not applied, since they would leave out the originals
+	// that don't touch them.
+	c := newCommand(
+		"log", "--no-walk=unsorted", "--ignore-missing", "--encoding=UTF-8",
+		"--pretty="+commitSeparator+"%H%x00%B",
+	)
+	out, err := g.runRevs(ctx, c.rev(shas...).path())
+	if err != nil {
+		return nil, fmt.Errorf("reading the cherry-picked commits: %w", err)
+	}
+	for _, part := range strings.Split(out, commitSeparator)[1:] {
+		full, msg, ok := strings.Cut(part, "\x00")
+		if !ok {
+			continue
+		}
+		full = strings.TrimSpace(full)
+		for _, sha := range shas {
+			if strings.HasPrefix(full, sha) {
+				ret[sha] = Backport{SHA: full, PRNumber: PullNumber(msg)}
+			}
+		}
+	}
+	for sha, b := range ret {
+		if r.Host != "" {
+			b.URL = r.CommitURL(b.SHA)
+		}
+		ret[sha] = b
+	}
+	return ret, nil
+}
+
+// backportsHeading is the name of the section of the backported commits.
+const backportsHeading = "Backported fixes"
+
+// WithBackports renders the cherry-picked commits in a "Backported fixes"
+// section after the breaking changes, apart from the changes of the branch.
+// Each entry refers to its original commit in the originals, which are keyed
+// by the hashes of the trailers as Git.Backports returns them. The entries
+// whose commits are not in the originals refer to the hashes of the trailers.
+// The section is first unless WithGroupOrder places it.
+func WithBackports(originals map[string]Backport) RenderOption {
+	return func(o *renderOptions) {
+		o.backports = true
+		o.originals = originals
+	}
+}
+
+// backportRef returns the reference to the original commit of the sha.
+func (o *renderOptions) backportRef(sha string) string {
+	b, ok := o.originals[sha]
+	if !ok {
+		b = Backport{SHA: sha}
+	}
+	ref := shortSHA(b.SHA)
+	if b.URL != "" {
+		ref = fmt.Sprintf("[%s](%s)", ref, b.URL)
+	}
+	if b.PRNumber > 0 {
+		ref += fmt.Sprintf(", #%d", b.PRNumber)
+	}
+	return "backport of " + ref
+}
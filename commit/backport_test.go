@@ -0,0 +1,90 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCherryPickOf(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msg  string
+		want string
+	}{
+		"none":    {"fix: the leak\n", ""},
+		"trailer": {"fix: the leak\n\n(cherry picked from commit 0123456789abcdef0123456789abcdef01234567)\n", "0123456789abcdef0123456789abcdef01234567"},
+		"twice": {
+			"fix: the leak\n\n(cherry picked from commit aaaaaaa)\n(cherry picked from commit bbbbbbb)\n",
+			"aaaaaaa",
+		},
+		"in a sentence": {"fix: the leak\n\nas in (cherry picked from commit aaaaaaa) of x\n", ""},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.CherryPickOf(tc.msg))
+		})
+	}
+}
+
+func TestGitBackports(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := repo(t, createGitRepo(t))
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	r.Run("checkout", "--quiet", "-b", "release-1.x")
+	r.Commit("fix: the branch only")
+	r.Run("checkout", "--quiet", "-")
+	fix := r.Commit("fix: the leak (#12)", committest.File{Path: "leak.go", Content: "package leak\n"})
+	r.Run("checkout", "--quiet", "release-1.x")
+	r.Run("cherry-pick", "-x", fix)
+	missing := strings.Repeat("d", 40)
+	r.Commit("fix: the crash\n\n(cherry picked from commit " + missing + ")")
+
+	g := &commit.Git{Dir: r.Dir}
+	commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+	remote := commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"}
+	originals, err := g.Backports(ctx, remote, commits)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]commit.Backport{
+		fix:     {SHA: fix, URL: "https://github.com/user/repo/commit/" + fix, PRNumber: 12},
+		missing: {SHA: missing, URL: "https://github.com/user/repo/commit/" + missing},
+	}, originals)
+
+	notes := commit.ParseGroups(commit.Messages(commits), commit.WithBackports(originals))
+	assert.Equal(t, "### Backported fixes\n\n"+
+		"- The crash (backport of [ddddddd](https://github.com/user/repo/commit/"+missing+"))\n"+
+		"- The leak (#12) (backport of ["+fix[:7]+"](https://github.com/user/repo/commit/"+fix+"), #12)\n\n\n"+
+		"### Fix\n\n- The branch only", notes)
+
+	notes = commit.ParseGroups(commit.Messages(commits))
+	assert.NotContains(t, notes, "Backported")
+}
+
+func TestParseGroupsBackportsOrder(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing",
+		"fix: the leak\n\n(cherry picked from commit aaaaaaa)",
+	}
+	got := commit.ParseGroups(logs, commit.WithBackports(nil), commit.WithGroupOrder("Feature", "Backported fixes"))
+	assert.Equal(t, "### Feature\n\n- The thing\n\n\n### Backported fixes\n\n- The leak (backport of aaaaaaa)", got)
+
+	// The translation keeps the order of the English name.
+	got = commit.ParseGroups(logs,
+		commit.WithBackports(nil),
+		commit.WithGroupOrder("Feature", "Backported fixes"),
+		commit.WithLocale(commit.Locale{Backports: "Zurückportiert"}),
+	)
+	assert.Equal(t, "### Feature\n\n- The thing\n\n\n### Zurückportiert\n\n- The leak (backport of aaaaaaa)", got)
+}
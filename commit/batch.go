@@ -0,0 +1,135 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Publisher publishes a release of the tag with the desc as its notes.
+type Publisher func(ctx context.Context, tag, desc string) error
+
+// BatchStatus is the outcome of releasing a module in a batch.
+type BatchStatus string
+
+// These are the possible outcomes of releasing a module.
+const (
+	BatchCreated BatchStatus = "created"
+	BatchSkipped BatchStatus = "skipped"
+	BatchFailed  BatchStatus = "failed"
+)
+
+// BatchResult is the outcome of releasing a module in a batch.
+type BatchResult struct {
+	Module Module
+	Status BatchStatus
+	// Err is set when the Status is BatchFailed.
+	Err error
+}
+
+// BatchOptions configures ReleaseModules.
+type BatchOptions struct {
+	// Combined publishes a single release with the notes of all modules on
+	// the first created tag, instead of one release per tag.
+	Combined bool
+	// Render is passed to ParseGroups for the notes of each module.
+	Render []RenderOption
+}
+
+// ReleaseModules releases every module that has changed since its latest tag.
+// For each module the next tag is created on HEAD and pushed to the Remote.
+// Only after all tags are pushed the releases are published with the publish
+// function. A failure of a module doesn't stop the other modules from being
+// released; the returned results contain the outcome of every module. The
+// error is only returned if the modules can't be discovered.
+func (g *Git) ReleaseModules(ctx context.Context, publish Publisher, opts BatchOptions) ([]BatchResult, error) {
+	dirs, err := g.moduleDirs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, 0, len(dirs))
+	notes := make(map[int]string, len(dirs))
+	for _, dir := range dirs {
+		m, logs, err := g.module(ctx, dir, dirs)
+		res := BatchResult{Module: m, Status: BatchCreated}
+		switch {
+		case err != nil:
+			res.Status = BatchFailed
+			res.Err = fmt.Errorf("module %s: %w", dir, err)
+		case !m.NeedsRelease():
+			res.Status = BatchSkipped
+		default:
+			if err := g.tagModule(ctx, m.NextTag); err != nil {
+				res.Status = BatchFailed
+				res.Err = err
+				break
+			}
+			notes[len(results)] = ParseGroups(logs, opts.Render...)
+		}
+		results = append(results, res)
+	}
+
+	if opts.Combined {
+		g.publishCombined(ctx, publish, results, notes)
+		return results, nil
+	}
+	for i := range results {
+		if results[i].Status != BatchCreated {
+			continue
+		}
+		tag := results[i].Module.NextTag
+		if err := publish(ctx, tag, notes[i]); err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = fmt.Errorf("publishing %s: %w", tag, err)
+		}
+	}
+	return results, nil
+}
+
+// tagModule creates the tag on HEAD and pushes it. If the tag can't be pushed
+// it is deleted so the module can be released again in the next run.
+func (g *Git) tagModule(ctx context.Context, tag string) error {
+	if err := g.CreateTag(ctx, tag, "HEAD"); err != nil {
+		return err
+	}
+	if err := g.PushTag(ctx, tag); err != nil {
+		if e := g.DeleteTag(ctx, tag); e != nil {
+			g.debugf("%v", e)
+		}
+		return err
+	}
+	return nil
+}
+
+// publishCombined publishes the notes of all created modules in one release
+// on the first created tag. If it fails, all created modules are marked as
+// failed.
+func (g *Git) publishCombined(ctx context.Context, publish Publisher, results []BatchResult, notes map[int]string) {
+	var (
+		tag  string
+		body strings.Builder
+	)
+	for i, res := range results {
+		if res.Status != BatchCreated {
+			continue
+		}
+		if tag == "" {
+			tag = res.Module.NextTag
+		}
+		fmt.Fprintf(&body, "## %s\n\n%s\n\n", res.Module.NextTag, notes[i])
+	}
+	if tag == "" {
+		return
+	}
+	err := publish(ctx, tag, strings.TrimSpace(body.String()))
+	if err == nil {
+		return
+	}
+	for i := range results {
+		if results[i].Status == BatchCreated {
+			results[i].Status = BatchFailed
+			results[i].Err = fmt.Errorf("publishing %s: %w", tag, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package commit_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitReleaseModules(t *testing.T) {
+	t.Parallel()
+	t.Run("PerTag", testGitReleaseModulesPerTag)
+	t.Run("Combined", testGitReleaseModulesCombined)
+	t.Run("PushFailure", testGitReleaseModulesPushFailure)
+}
+
+// publishRecorder records the published releases, and fails the ones in fail.
+type publishRecorder struct {
+	mu       sync.Mutex
+	fail     map[string]bool
+	releases map[string]string
+}
+
+func (p *publishRecorder) Publish(_ context.Context, tag, desc string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[tag] {
+		return errors.New("publishing failed")
+	}
+	if p.releases == nil {
+		p.releases = make(map[string]string)
+	}
+	p.releases[tag] = desc
+	return nil
+}
+
+func statuses(results []commit.BatchResult) map[string]commit.BatchStatus {
+	got := make(map[string]commit.BatchStatus, len(results))
+	for _, res := range results {
+		got[res.Module.Dir] = res.Status
+	}
+	return got
+}
+
+func testGitReleaseModulesPerTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createModulesRepo(t)
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare", "-q")
+	addRemote(t, dir, "origin", remote)
+
+	p := &publishRecorder{fail: map[string]bool{"tools/v0.1.0": true}}
+	g := &commit.Git{Dir: dir}
+	results, err := g.ReleaseModules(ctx, p.Publish, commit.BatchOptions{})
+	require.NoError(t, err)
+
+	want := map[string]commit.BatchStatus{
+		".":       commit.BatchCreated,
+		"mod":     commit.BatchSkipped,
+		"mod/sub": commit.BatchCreated,
+		"tools":   commit.BatchFailed,
+	}
+	assert.Equal(t, want, statuses(results))
+	for _, res := range results {
+		if res.Status == commit.BatchFailed {
+			assert.Error(t, res.Err)
+			continue
+		}
+		assert.NoError(t, res.Err)
+	}
+
+	require.Len(t, p.releases, 2)
+	assert.Contains(t, p.releases["mod/sub/v1.3.0"], "Add sub feature")
+	assert.NotContains(t, p.releases["mod/sub/v1.3.0"], "Fix root")
+	assert.Contains(t, p.releases["v1.0.1"], "Fix root")
+
+	tags := runGit(t, remote, "tag", "--list")
+	for _, tag := range []string{"v1.0.1", "mod/sub/v1.3.0", "tools/v0.1.0"} {
+		assert.Contains(t, tags, tag)
+	}
+}
+
+func testGitReleaseModulesCombined(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createModulesRepo(t)
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare", "-q")
+	addRemote(t, dir, "origin", remote)
+
+	p := &publishRecorder{}
+	g := &commit.Git{Dir: dir}
+	results, err := g.ReleaseModules(ctx, p.Publish, commit.BatchOptions{Combined: true})
+	require.NoError(t, err)
+	assert.Equal(t, commit.BatchSkipped, statuses(results)["mod"])
+
+	require.Len(t, p.releases, 1)
+	body, ok := p.releases["v1.0.1"]
+	require.True(t, ok, p.releases)
+	for _, tag := range []string{"## v1.0.1", "## mod/sub/v1.3.0", "## tools/v0.1.0"} {
+		assert.Contains(t, body, tag)
+	}
+}
+
+func testGitReleaseModulesPushFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createModulesRepo(t)
+	addRemote(t, dir, "origin", t.TempDir())
+
+	p := &publishRecorder{}
+	g := &commit.Git{Dir: dir}
+	results, err := g.ReleaseModules(ctx, p.Publish, commit.BatchOptions{})
+	require.NoError(t, err)
+
+	want := map[string]commit.BatchStatus{
+		".":       commit.BatchFailed,
+		"mod":     commit.BatchSkipped,
+		"mod/sub": commit.BatchFailed,
+		"tools":   commit.BatchFailed,
+	}
+	assert.Equal(t, want, statuses(results))
+	assert.Empty(t, p.releases)
+
+	tags := strings.Fields(runGit(t, dir, "tag", "--list"))
+	assert.ElementsMatch(t, []string{"v1.0.0", "mod/v0.1.0", "mod/sub/v1.2.3"}, tags)
+}
@@ -0,0 +1,131 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseSection is one of the releases between two tags.
+type ReleaseSection struct {
+	Tag string
+	// Previous is the tag of the release before, or the first tag of the span.
+	Previous string
+	// Date is the creation date of the tag, or the committer date if Tag is
+	// not a tag.
+	Date time.Time
+	// CommitDate is the committer date of the commit of the Tag, which is
+	// earlier than the Date if the tag was created later.
+	CommitDate time.Time
+	Logs       []string
+}
+
+// ReleasesBetween returns the releases from the tag "from" up to the tag "to",
+// the newest first. The tags between them that are reachable from "to" but not
+// from "from" and match the TagPrefix, the Scheme and the AnnotatedOnly options
+// split the span into sections. Of the tags of the same commit, only the most
+// recently created one is used. If the tags are adjacent, there is only one
+// section.
+func (g *Git) ReleasesBetween(ctx context.Context, from, to string) ([]ReleaseSection, error) {
+	tags, err := g.Tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	byCommit := make(map[string]string, len(tags))
+	for _, t := range tags {
+		// The most recently created tag of a commit wins.
+		if _, ok := byCommit[t.Commit]; !ok {
+			byCommit[t.Commit] = t.Name
+		}
+	}
+	fromSha, err := g.resolve(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toSha, err := g.resolve(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	out, err := g.run(ctx, newCommand("rev-list", "--topo-order").revFrom(fromSha+".."+toSha, fromSha, toSha))
+	if err != nil {
+		return nil, fmt.Errorf("listing commits of %s..%s: %w", from, to, err)
+	}
+
+	boundaries := []string{to}
+	for _, sha := range splitLines(strings.TrimSpace(out)) {
+		if name, ok := byCommit[sha]; ok && sha != toSha {
+			boundaries = append(boundaries, name)
+		}
+	}
+	boundaries = append(boundaries, from)
+
+	sections := make([]ReleaseSection, 0, len(boundaries)-1)
+	for i := 0; i < len(boundaries)-1; i++ {
+		s := ReleaseSection{Tag: boundaries[i], Previous: boundaries[i+1]}
+		s.Logs, err = g.Commits(ctx, s.Previous, s.Tag)
+		if err != nil {
+			return nil, err
+		}
+		s.Date, s.CommitDate, err = g.refDates(ctx, s.Tag)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+// refDates returns the creation date of the tag, or the committer date of the
+// commit of the rev if it's not a tag, and the committer date.
+func (g *Git) refDates(ctx context.Context, rev string) (date, commitDate time.Time, err error) {
+	out, err := g.run(ctx, newCommand("log", "-1", "--format=%ct").revFrom(rev+"^{commit}", rev))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("getting the date of %s: %w", rev, err)
+	}
+	commitDate, err = parseUnix(out)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing the date of %s: %w", rev, err)
+	}
+	out, err = g.run(ctx, newCommand("for-each-ref", "--format=%(creatordate:unix)").revFrom("refs/tags/"+rev, rev))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("getting the date of %s: %w", rev, err)
+	}
+	if strings.TrimSpace(out) == "" {
+		return commitDate, commitDate, nil
+	}
+	date, err = parseUnix(out)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing the date of %s: %w", rev, err)
+	}
+	return date, commitDate, nil
+}
+
+// parseUnix parses the seconds since the epoch in the output of git.
+func parseUnix(out string) (time.Time, error) {
+	sec, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// RenderReleases renders the sections as one document with a heading for each
+// release. A single section is rendered the same as ParseGroups.
+func RenderReleases(sections []ReleaseSection, opts ...RenderOption) string {
+	if len(sections) == 1 {
+		return ParseGroups(sections[0].Logs, opts...)
+	}
+	o := newRenderOptions(opts)
+	locale := o.locale
+	parts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		body := ParseGroups(s.Logs, opts...)
+		if len(s.Logs) == 0 {
+			body = locale.noChanges(s.Previous)
+		}
+		parts = append(parts, fmt.Sprintf("## %s (%s)\n\n%s", s.Tag, locale.FormatDate(o.dateSource.pick(s.Date, s.CommitDate)), body))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,184 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSpanRepo creates a repository with three releases a day apart:
+//
+//	v1.2.0 -- feat -- v1.3.0 -- fix -- v1.4.0-rc.1 v1.4.0(a) -- docs -- fix
+//
+// The v1.4.0 tag is annotated and created a week after its commit.
+func createSpanRepo(t *testing.T) string {
+	t.Helper()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "1")
+	commitAt(t, dir, "chore: initial", "2024-06-01T10:00:00Z")
+	createGitTag(t, dir, "v1.2.0")
+	createFile(t, dir, "file.txt", "2")
+	commitAt(t, dir, "feat: add thing", "2024-06-02T10:00:00Z")
+	createGitTag(t, dir, "v1.3.0")
+	createFile(t, dir, "file.txt", "3")
+	commitAt(t, dir, "fix: fix thing", "2024-06-03T10:00:00Z")
+	createGitTag(t, dir, "v1.4.0-rc.1")
+	tagAt(t, dir, "v1.4.0", "2024-06-10T10:00:00Z")
+	createFile(t, dir, "file.txt", "4")
+	commitAt(t, dir, "docs: explain thing", "2024-06-11T10:00:00Z")
+	createFile(t, dir, "file.txt", "5")
+	commitAt(t, dir, "fix: fix other", "2024-06-12T10:00:00Z")
+	return dir
+}
+
+// tagAt creates an annotated tag with the date as its creation date.
+func tagAt(t *testing.T, dir, tag, date string) {
+	t.Helper()
+	d, err := time.Parse(time.RFC3339, date)
+	require.NoError(t, err)
+	repo(t, dir).AnnotatedTagAt(tag, tag, d)
+}
+
+func TestGitReleasesBetween(t *testing.T) {
+	t.Parallel()
+	t.Run("Span", testGitReleasesBetweenSpan)
+	t.Run("Adjacent", testGitReleasesBetweenAdjacent)
+	t.Run("Head", testGitReleasesBetweenHead)
+	t.Run("BadTag", testGitReleasesBetweenBadTag)
+	t.Run("DateSource", testGitReleasesBetweenDateSource)
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, 6, d, 10, 0, 0, 0, time.UTC)
+}
+
+func testGitReleasesBetweenSpan(t *testing.T) {
+	t.Parallel()
+	dir := createSpanRepo(t)
+	g := &commit.Git{Dir: dir}
+	got, err := g.ReleasesBetween(context.Background(), "v1.2.0", "v1.4.0")
+	require.NoError(t, err)
+	want := []commit.ReleaseSection{
+		{Tag: "v1.4.0", Previous: "v1.3.0", Date: day(10), CommitDate: day(3), Logs: []string{"fix: fix thing\n"}},
+		{Tag: "v1.3.0", Previous: "v1.2.0", Date: day(2), CommitDate: day(2), Logs: []string{"feat: add thing\n"}},
+	}
+	if diff := cmp.Diff(want, got, commitComparer); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	notes := commit.RenderReleases(got)
+	wantNotes := "## v1.4.0 (2024-06-10)\n\n### Fix\n\n- Fix thing\n\n" +
+		"## v1.3.0 (2024-06-02)\n\n### Feature\n\n- Add thing"
+	if diff := cmp.Diff(wantNotes, notes); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGitReleasesBetweenAdjacent(t *testing.T) {
+	t.Parallel()
+	dir := createSpanRepo(t)
+	g := &commit.Git{Dir: dir}
+	got, err := g.ReleasesBetween(context.Background(), "v1.2.0", "v1.3.0")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "v1.3.0", got[0].Tag)
+	assert.Equal(t, "v1.2.0", got[0].Previous)
+	assert.Equal(t, commit.ParseGroups(got[0].Logs), commit.RenderReleases(got))
+}
+
+func testGitReleasesBetweenHead(t *testing.T) {
+	t.Parallel()
+	dir := createSpanRepo(t)
+	g := &commit.Git{Dir: dir}
+	got, err := g.ReleasesBetween(context.Background(), "v1.3.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "HEAD", got[0].Tag)
+	assert.Equal(t, "v1.4.0", got[0].Previous)
+	assert.Equal(t, day(12), got[0].Date)
+	assert.Equal(t, day(12), got[0].CommitDate)
+	assert.Len(t, got[0].Logs, 2)
+	assert.Equal(t, "v1.4.0", got[1].Tag)
+	assert.Len(t, got[1].Logs, 1)
+}
+
+func testGitReleasesBetweenBadTag(t *testing.T) {
+	t.Parallel()
+	dir := createSpanRepo(t)
+	g := &commit.Git{Dir: dir}
+	_, err := g.ReleasesBetween(context.Background(), "v0.0.1", "v1.4.0")
+	var gitErr *commit.GitError
+	assert.ErrorAs(t, err, &gitErr)
+}
+
+// testGitReleasesBetweenDateSource renders the v1.4.0 tag, which is created a
+// week after its commit, and the unreleased commits of the HEAD.
+func testGitReleasesBetweenDateSource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createSpanRepo(t)
+	g := &commit.Git{Dir: dir}
+	got, err := g.ReleasesBetween(ctx, "v1.3.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	headings := func(notes string) []string {
+		var ret []string
+		for _, line := range strings.Split(notes, "\n") {
+			if strings.HasPrefix(line, "## ") {
+				ret = append(ret, line)
+			}
+		}
+		return ret
+	}
+
+	want := []string{"## HEAD (2024-06-12)", "## v1.4.0 (2024-06-10)"}
+	assert.Equal(t, want, headings(commit.RenderReleases(got)))
+	assert.Equal(t, want, headings(commit.RenderReleases(got, commit.WithDateSource(commit.DateTag))))
+
+	want = []string{"## HEAD (2024-06-12)", "## v1.4.0 (2024-06-03)"}
+	assert.Equal(t, want, headings(commit.RenderReleases(got, commit.WithDateSource(commit.DateCommit))))
+
+	before := time.Now().UTC().Format("2006-01-02")
+	notes := commit.RenderReleases(got, commit.WithDateSource(commit.DateNow))
+	after := time.Now().UTC().Format("2006-01-02")
+	for _, h := range headings(notes) {
+		date := h[strings.Index(h, "(")+1 : len(h)-1]
+		assert.Contains(t, []string{before, after}, date)
+	}
+
+	c, err := g.GenerateForTag(ctx, "v1.4.0")
+	require.NoError(t, err)
+	assert.Equal(t, day(10), c.Date)
+	assert.Equal(t, day(3), c.CommitDate)
+	tags, err := g.Tags(ctx)
+	require.NoError(t, err)
+	for _, tag := range tags {
+		if tag.Name == "v1.4.0" {
+			assert.Equal(t, day(10), tag.Date)
+			assert.Equal(t, day(3), tag.CommitDate)
+		}
+		if tag.Name == "v1.4.0-rc.1" {
+			assert.Equal(t, day(3), tag.Date, "lightweight tags have the commit date")
+		}
+	}
+}
+
+func TestParseDateSource(t *testing.T) {
+	t.Parallel()
+	for _, in := range []string{"tag", "commit", "now"} {
+		got, err := commit.ParseDateSource(in)
+		require.NoError(t, err)
+		assert.Equal(t, in, got.String())
+	}
+	got, err := commit.ParseDateSource("")
+	require.NoError(t, err)
+	assert.Equal(t, commit.DateTag, got)
+	_, err = commit.ParseDateSource("tagger")
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// BodyMode defines how much of the bodies of the commits is rendered under
+// their entries.
+type BodyMode int
+
+const (
+	// BodyNone leaves the bodies out. This is the default.
+	BodyNone BodyMode = iota
+	// BodyFull renders the whole body, indented under the bullet.
+	BodyFull
+	// BodyFirstParagraph renders the first paragraph of the body.
+	BodyFirstParagraph
+	// BodyDetails renders the whole body in a collapsed <details> block.
+	BodyDetails
+)
+
+// ParseBodyMode returns the BodyMode for "none", "full", "first" or
+// "details".
+func ParseBodyMode(s string) (BodyMode, error) {
+	switch s {
+	case "none", "":
+		return BodyNone, nil
+	case "full":
+		return BodyFull, nil
+	case "first":
+		return BodyFirstParagraph, nil
+	case "details":
+		return BodyDetails, nil
+	}
+	return 0, fmt.Errorf("unknown body mode %q: use none, full, first or details", s)
+}
+
+func (m BodyMode) String() string {
+	switch m {
+	case BodyFull:
+		return "full"
+	case BodyFirstParagraph:
+		return "first"
+	case BodyDetails:
+		return "details"
+	}
+	return "none"
+}
+
+// DefaultMaxBody is the number of the characters of a body that are rendered
+// if WithBodies is not given a limit.
+const DefaultMaxBody = 2000
+
+// WithBodies renders the bodies of the commits under their entries. The
+// bodies longer than max characters are truncated, and are linked to their
+// commits if the links are given. Zero max is the DefaultMaxBody, and a
+// negative one is no limit. The links are the addresses of the commits of the
+// logs, in the same order. The bullets of the bodies are not rendered as
+// sub-items, since they are already in the bodies.
+func WithBodies(mode BodyMode, max int, links []string) RenderOption {
+	return func(o *renderOptions) {
+		o.bodies = mode
+		o.maxBody = max
+		if max == 0 {
+			o.maxBody = DefaultMaxBody
+		}
+		o.bodyLinks = links
+	}
+}
+
+var trailerRe = regexp.MustCompile(`^[[:alpha:]][\w-]*: \S`)
+
+// commitBody returns the body of the log without its subject and its
+// trailers, e.g. the Signed-off-by lines. The BREAKING CHANGE footers are
+// kept, since they explain the change.
+func commitBody(log string) string {
+	lines := splitLines(strings.TrimSpace(log))[1:]
+	// The last paragraph is only dropped if all of its lines are trailers.
+	last := len(lines)
+	for last > 0 && strings.TrimSpace(lines[last-1]) != "" {
+		last--
+	}
+	trailers := last < len(lines)
+	for _, line := range lines[last:] {
+		if !trailerRe.MatchString(line) || strings.HasPrefix(line, "BREAKING") {
+			trailers = false
+			break
+		}
+	}
+	if trailers {
+		lines = lines[:last]
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// firstParagraph returns the lines of the body up to the first blank line
+// that is not in a code block.
+func firstParagraph(body string) string {
+	lines := strings.Split(body, "\n")
+	var fence string
+	for i, line := range lines {
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case strings.HasPrefix(m[1], fence) && strings.TrimSpace(line) == m[1]:
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" && strings.TrimSpace(line) == "" {
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return body
+}
+
+// renderBody returns the body of the log, formatted for the mode and
+// indented to be under the bullet of the entry. The link is the address of
+// the commit, and is added if the body is truncated.
+func (o *renderOptions) renderBody(log, link string) string {
+	body := commitBody(log)
+	if o.bodies == BodyFirstParagraph {
+		body = firstParagraph(body)
+	}
+	if body == "" {
+		return ""
+	}
+	lines, truncated := o.bodyLines(body)
+	if truncated {
+		more := "…"
+		if link != "" {
+			more = fmt.Sprintf("… [%s](%s)", o.locale.readMore(), link)
+		}
+		lines = append(lines, "", more)
+	}
+	if o.bodies == BodyDetails {
+		lines = append([]string{"<details><summary>" + o.locale.details() + "</summary>", ""}, lines...)
+		lines = append(lines, "", "</details>")
+	}
+	buf := &strings.Builder{}
+	for _, line := range lines {
+		if line != "" {
+			buf.WriteString(bodyIndent + line)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// bodyIndent puts the lines of the bodies under the text of the bullets.
+var bodyIndent = strings.Repeat(" ", len(ItemPrefix))
+
+// bodyLines returns the lines of the body, sanitised outside of the code
+// blocks, and true if the body is truncated to the maxBody. The headings are
+// escaped, and the tabs of the indentations are replaced with spaces, so the
+// body can't change the structure of the notes. An open code block is closed
+// after the truncation.
+func (o *renderOptions) bodyLines(body string) ([]string, bool) {
+	var (
+		lines     []string
+		fence     string
+		size      int
+		truncated bool
+	)
+	for _, line := range strings.Split(body, "\n") {
+		line = expandIndent(line)
+		n := utf8.RuneCountInString(line) + 1
+		if o.maxBody > 0 && size+n > o.maxBody {
+			if len(lines) == 0 {
+				// A single long line is cut instead of dropping all of it.
+				line = string([]rune(line)[:o.maxBody])
+				lines = append(lines, o.bodyLine(line, fence != ""))
+			}
+			truncated = true
+			break
+		}
+		size += n
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case strings.HasPrefix(m[1], fence) && strings.TrimSpace(line) == m[1]:
+				fence = ""
+			}
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, o.bodyLine(line, fence != ""))
+	}
+	if truncated && fence != "" {
+		lines = append(lines, fence)
+	}
+	return lines, truncated
+}
+
+// bodyLine returns the line of a body sanitised, unless it's in a fenced code
+// block. The indented code blocks are sanitised, since they can't be told
+// apart from the nested lists by their lines.
+func (o *renderOptions) bodyLine(line string, code bool) string {
+	if code {
+		return line
+	}
+	line = o.sanitizeText(line)
+	if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, "#") {
+		line = line[:len(line)-len(trimmed)] + `\` + trimmed
+	}
+	return line
+}
+
+// expandIndent replaces the tabs in the indentation of the line with four
+// spaces.
+func expandIndent(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(trimmed)]
+	return strings.ReplaceAll(indent, "\t", "    ") + trimmed
+}
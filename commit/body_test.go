@@ -0,0 +1,96 @@
+package commit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBodyMode(t *testing.T) {
+	t.Parallel()
+	modes := []commit.BodyMode{commit.BodyNone, commit.BodyFull, commit.BodyFirstParagraph, commit.BodyDetails}
+	for _, want := range modes {
+		got, err := commit.ParseBodyMode(want.String())
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := commit.ParseBodyMode("all")
+	assert.Error(t, err)
+}
+
+func TestParseGroupsBodies(t *testing.T) {
+	t.Parallel()
+	msg := "feat: add the thing\n\n" +
+		"The thing is <needed> by @alice.\nIt was missing.\n\n" +
+		"- first\n  - nested\n\n" +
+		"```go\nx := <-ch\n```\n\n" +
+		"# Heading\n\n" +
+		"Signed-off-by: Bob <bob@example.com>\n"
+	tcs := map[string]struct {
+		mode commit.BodyMode
+		want string
+	}{
+		"none": {
+			mode: commit.BodyNone,
+			want: "### Feature\n\n- Add the thing (# Heading)",
+		},
+		"full": {
+			mode: commit.BodyFull,
+			want: "### Feature\n\n- Add the thing (# Heading)\n\n" +
+				"  The thing is &lt;needed&gt; by `@alice`.\n  It was missing.\n\n" +
+				"  - first\n    - nested\n\n" +
+				"  ```go\n  x := <-ch\n  ```\n\n" +
+				"  \\# Heading",
+		},
+		"first": {
+			mode: commit.BodyFirstParagraph,
+			want: "### Feature\n\n- Add the thing (# Heading)\n\n" +
+				"  The thing is &lt;needed&gt; by `@alice`.\n  It was missing.",
+		},
+		"details": {
+			mode: commit.BodyDetails,
+			want: "### Feature\n\n- Add the thing (# Heading)\n\n" +
+				"  <details><summary>Details</summary>\n\n" +
+				"  The thing is &lt;needed&gt; by `@alice`.\n  It was missing.\n\n" +
+				"  - first\n    - nested\n\n" +
+				"  ```go\n  x := <-ch\n  ```\n\n" +
+				"  \\# Heading\n\n" +
+				"  </details>",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.ParseGroups([]string{msg}, commit.WithBodies(tc.mode, 0, nil))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseGroupsBodiesTruncated(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"fix: the long one\n\n```\n" + strings.Repeat("line\n", 100) + "```\n",
+		"fix: the short one\n\nshort body\n",
+	}
+	links := []string{"https://example.com/commit/aaa", "https://example.com/commit/bbb"}
+	got := commit.ParseGroups(logs, commit.WithBodies(commit.BodyFull, 50, links))
+	assert.Contains(t, got, "  line\n  ```\n\n  … [Read more](https://example.com/commit/aaa)\n")
+	assert.Equal(t, 2, strings.Count(got, "```"), "the code block is closed")
+	assert.Contains(t, got, "- The short one\n\n  short body")
+	assert.NotContains(t, got, "commit/bbb")
+
+	got = commit.ParseGroups(logs[:1], commit.WithBodies(commit.BodyFull, -1, nil))
+	assert.Equal(t, 100, strings.Count(got, "line"))
+}
+
+func TestParseGroupsBodiesSubItems(t *testing.T) {
+	t.Parallel()
+	msg := "feat: squashed (#1)\n\n* feat: one\n* fix: two\n"
+	got := commit.ParseGroups([]string{msg}, commit.WithSubItems(0), commit.WithBodies(commit.BodyFull, 0, nil))
+	assert.Equal(t, "### Feature\n\n- Squashed (#1)\n\n  * feat: one\n  * fix: two", got)
+}
@@ -0,0 +1,97 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/arsham/gitrelease/version"
+)
+
+// ErrBranchExists is returned by CreateReleaseBranch when the branch already
+// exists on another commit.
+var ErrBranchExists = errors.New("branch already exists")
+
+// BranchName returns the name of the release branch of the tag from the
+// template, e.g. "release/{major}.{minor}" is "release/1.4" for v1.4.0. The
+// placeholders are replaced with the parts of the version of the tag, after
+// its TagPrefix:
+//
+//	{tag}        the tag, e.g. v1.4.0-rc.1
+//	{version}    the version without the "v", e.g. 1.4.0-rc.1
+//	{major}      the major part, e.g. 1
+//	{minor}      the minor part, e.g. 4
+//	{patch}      the patch part, e.g. 0
+//	{prerelease} the pre-release part, e.g. rc.1
+func (g *Git) BranchName(template, tag string) (string, error) {
+	v, err := version.Parse(strings.TrimPrefix(tag, g.TagPrefix))
+	if err != nil {
+		return "", fmt.Errorf("the release branch of tag %s: %w", tag, err)
+	}
+	name := strings.NewReplacer(
+		"{tag}", tag,
+		"{version}", strings.TrimPrefix(v.String(), v.Prefix),
+		"{major}", strconv.Itoa(v.Major),
+		"{minor}", strconv.Itoa(v.Minor),
+		"{patch}", strconv.Itoa(v.Patch),
+		"{prerelease}", v.Prerelease,
+	).Replace(template)
+	if name == "" {
+		return "", fmt.Errorf("the release branch of tag %s has no name", tag)
+	}
+	return name, nil
+}
+
+// CreateReleaseBranch creates the branch on the commit of the tag, and pushes
+// it to the Remote. If the branch is already on the Remote at the same
+// commit, it's not created and a WarnBranchExists is added to the Warnings.
+// It returns false in that case. If the branch is at another commit, locally
+// or on the Remote, it returns ErrBranchExists.
+func (g *Git) CreateReleaseBranch(ctx context.Context, branch, tag string) (bool, error) {
+	remote := g.remote()
+	ref := "refs/heads/" + branch
+	list := newCommand("ls-remote", "--heads").rev(remote).revFrom(ref, branch)
+	// The branch is checked before the tag is resolved.
+	if err := list.check(); err != nil {
+		return false, err
+	}
+	sha, err := g.resolve(ctx, tag)
+	if err != nil {
+		return false, err
+	}
+	out, err := g.run(ctx, list)
+	if err != nil {
+		return false, fmt.Errorf("listing branch %s of %s: %w", branch, remote, err)
+	}
+	for _, line := range splitLines(out) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[1] != ref {
+			continue
+		}
+		if fields[0] != sha {
+			return false, fmt.Errorf("%w: %s is at %s on %s, not at %s of tag %s",
+				ErrBranchExists, branch, shortSHA(fields[0]), remote, shortSHA(sha), tag)
+		}
+		g.Warnings.Add(WarnBranchExists, "branch %s is already at tag %s on %s", branch, tag, remote)
+		return false, nil
+	}
+
+	local, err := g.run(ctx, newCommand("rev-parse", "--verify", "--quiet").revFrom(ref+"^{commit}", branch))
+	switch local = strings.TrimSpace(local); {
+	case err != nil && !hasExitCode(err, 1):
+		return false, fmt.Errorf("resolving branch %s: %w", branch, err)
+	case local != "" && local != sha:
+		return false, fmt.Errorf("%w: %s is at %s, not at %s of tag %s",
+			ErrBranchExists, branch, shortSHA(local), shortSHA(sha), tag)
+	case local == "":
+		if _, err := g.run(ctx, newCommand("branch").rev(branch, sha)); err != nil {
+			return false, fmt.Errorf("creating branch %s: %w", branch, err)
+		}
+	}
+	if _, err := g.run(ctx, newCommand("push").rev(remote).revFrom(ref+":"+ref, branch)); err != nil {
+		return false, fmt.Errorf("pushing branch %s to %s: %w", branch, remote, err)
+	}
+	return true, nil
+}
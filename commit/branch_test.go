@@ -0,0 +1,138 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitBranchName(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		prefix   string
+		template string
+		tag      string
+		want     string
+		wantErr  bool
+	}{
+		"major minor":  {template: "release/{major}.{minor}", tag: "v1.4.0", want: "release/1.4"},
+		"all parts":    {template: "{major}-{minor}-{patch}-{prerelease}", tag: "v1.4.2-rc.1", want: "1-4-2-rc.1"},
+		"tag":          {template: "release/{tag}", tag: "v1.4.0", want: "release/v1.4.0"},
+		"version":      {template: "release/{version}", tag: "v1.4.0-rc.1+build.5", want: "release/1.4.0-rc.1+build.5"},
+		"tag prefix":   {prefix: "mod/", template: "mod/release/{major}.{minor}", tag: "mod/v2.1.0", want: "mod/release/2.1"},
+		"not a semver": {template: "release/{major}", tag: "nightly", wantErr: true},
+		"empty":        {tag: "v1.4.0", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := &commit.Git{TagPrefix: tc.prefix}
+			got, err := g.BranchName(tc.template, tc.tag)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGitCreateReleaseBranch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("Create", func(t *testing.T) {
+		t.Parallel()
+		dir, remote := createReleasedRepo(t)
+		commitChanges(t, dir, "fix: after the release")
+		g := &commit.Git{Dir: dir}
+		created, err := g.CreateReleaseBranch(ctx, "release/1.0", "v1.0.0")
+		require.NoError(t, err)
+		assert.True(t, created)
+		tagged := runGit(t, dir, "rev-parse", "v1.0.0^{commit}")
+		assert.Equal(t, tagged, runGit(t, dir, "rev-parse", "release/1.0"))
+		assert.Equal(t, tagged, runGit(t, remote, "rev-parse", "refs/heads/release/1.0"))
+	})
+
+	t.Run("LocalOnly", func(t *testing.T) {
+		t.Parallel()
+		dir, remote := createReleasedRepo(t)
+		runGit(t, dir, "branch", "release/1.0", "v1.0.0")
+		g := &commit.Git{Dir: dir}
+		created, err := g.CreateReleaseBranch(ctx, "release/1.0", "v1.0.0")
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, runGit(t, dir, "rev-parse", "v1.0.0^{commit}"), runGit(t, remote, "rev-parse", "refs/heads/release/1.0"))
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := createReleasedRepo(t)
+		runGit(t, dir, "push", "-q", "origin", "v1.0.0^{commit}:refs/heads/release/1.0")
+		w := &commit.Warnings{}
+		g := &commit.Git{Dir: dir, Warnings: w}
+		created, err := g.CreateReleaseBranch(ctx, "release/1.0", "v1.0.0")
+		require.NoError(t, err)
+		assert.False(t, created)
+		require.Len(t, w.List(), 1)
+		assert.Equal(t, commit.WarnBranchExists, w.List()[0].Code)
+	})
+
+	t.Run("ExistsAnnotated", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := createReleasedRepo(t)
+		commitChanges(t, dir, "fix: after the release")
+		runGit(t, dir, "tag", "-a", "-m", "release", "v1.1.0")
+		runGit(t, dir, "push", "-q", "origin", "HEAD:refs/heads/release/1.1")
+		runGit(t, dir, "branch", "release/1.1", "HEAD")
+		w := &commit.Warnings{}
+		g := &commit.Git{Dir: dir, Warnings: w}
+		created, err := g.CreateReleaseBranch(ctx, "release/1.1", "v1.1.0")
+		require.NoError(t, err, "the branch is compared with the commit, not the tag object")
+		assert.False(t, created)
+		require.Len(t, w.List(), 1)
+		assert.Equal(t, commit.WarnBranchExists, w.List()[0].Code)
+	})
+
+	t.Run("ExistsElsewhere", func(t *testing.T) {
+		t.Parallel()
+		dir, remote := createReleasedRepo(t)
+		commitChanges(t, dir, "fix: after the release")
+		runGit(t, dir, "push", "-q", "origin", "HEAD:refs/heads/release/1.0")
+		head := runGit(t, dir, "rev-parse", "HEAD")
+		g := &commit.Git{Dir: dir}
+		created, err := g.CreateReleaseBranch(ctx, "release/1.0", "v1.0.0")
+		require.ErrorIs(t, err, commit.ErrBranchExists)
+		assert.False(t, created)
+		assert.ErrorContains(t, err, "release/1.0 is at "+strings.TrimSpace(head)[:7]+" on origin")
+		assert.Equal(t, head, runGit(t, remote, "rev-parse", "refs/heads/release/1.0"))
+	})
+
+	t.Run("LocalElsewhere", func(t *testing.T) {
+		t.Parallel()
+		dir, remote := createReleasedRepo(t)
+		commitChanges(t, dir, "fix: after the release")
+		runGit(t, dir, "branch", "release/1.0", "HEAD")
+		g := &commit.Git{Dir: dir}
+		_, err := g.CreateReleaseBranch(ctx, "release/1.0", "v1.0.0")
+		require.ErrorIs(t, err, commit.ErrBranchExists)
+		out := runGit(t, remote, "branch", "--list", "release/1.0")
+		assert.Empty(t, strings.TrimSpace(out))
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Runner: fakeRunner(func(args []string) (string, error) {
+			t.Errorf("unexpected git call: %v", args)
+			return "", nil
+		})}
+		_, err := g.CreateReleaseBranch(ctx, "--force", "v1.0.0")
+		require.ErrorIs(t, err, commit.ErrInvalidRevision)
+	})
+}
@@ -0,0 +1,63 @@
+package commit
+
+import "fmt"
+
+// BreakingPolicy defines where the breaking changes are rendered. Each
+// commit has exactly one full entry in its own section, unless it's
+// duplicated with BreakingDuplicate or moved with BreakingOnly.
+type BreakingPolicy int
+
+const (
+	// BreakingInline only marks the breaking changes in their sections,
+	// without a Breaking Changes section. This is the default.
+	BreakingInline BreakingPolicy = iota
+	// BreakingReference adds a Breaking Changes section that refers to the
+	// entries in their sections with one line each.
+	BreakingReference
+	// BreakingDuplicate renders the full entries of the breaking changes in
+	// both the Breaking Changes section and their sections.
+	BreakingDuplicate
+	// BreakingOnly moves the breaking changes into the Breaking Changes
+	// section.
+	BreakingOnly
+)
+
+// breakingHeading is the heading of the Breaking Changes section, which is
+// translated by the locales like the others.
+const breakingHeading = "Breaking Changes"
+
+// ParseBreakingPolicy returns the BreakingPolicy for "inline", "reference",
+// "duplicate" or "breaking-only".
+func ParseBreakingPolicy(s string) (BreakingPolicy, error) {
+	switch s {
+	case "inline", "":
+		return BreakingInline, nil
+	case "reference":
+		return BreakingReference, nil
+	case "duplicate":
+		return BreakingDuplicate, nil
+	case "breaking-only":
+		return BreakingOnly, nil
+	}
+	return BreakingInline, fmt.Errorf("unknown breaking policy %q", s)
+}
+
+// String returns the name of the policy.
+func (p BreakingPolicy) String() string {
+	switch p {
+	case BreakingReference:
+		return "reference"
+	case BreakingDuplicate:
+		return "duplicate"
+	case BreakingOnly:
+		return "breaking-only"
+	}
+	return "inline"
+}
+
+// WithBreakingPolicy renders the breaking changes with the policy.
+func WithBreakingPolicy(p BreakingPolicy) RenderOption {
+	return func(o *renderOptions) {
+		o.breaking = p
+	}
+}
@@ -0,0 +1,87 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBreakingPolicy(t *testing.T) {
+	t.Parallel()
+	for _, in := range []string{"inline", "reference", "duplicate", "breaking-only"} {
+		got, err := commit.ParseBreakingPolicy(in)
+		require.NoError(t, err)
+		assert.Equal(t, in, got.String())
+	}
+	got, err := commit.ParseBreakingPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, commit.BreakingInline, got)
+	_, err = commit.ParseBreakingPolicy("section")
+	assert.Error(t, err)
+}
+
+func TestParseGroupsBreakingPolicy(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat(api)!: drop the v1 endpoints\n\n- remove /v1/users",
+		"fix!: reject the empty names",
+		"refactor: rename the config\n\nBREAKING CHANGE: the old name is not read",
+		"feat!: both markers\n\nBREAKING CHANGE: counted once",
+		"fix: crash",
+	}
+	tcs := map[commit.BreakingPolicy]string{
+		commit.BreakingInline: "### Feature\n\n" +
+			"- **Api:** Drop the v1 endpoints [**BREAKING CHANGE**]\n" +
+			"- Both markers [**BREAKING CHANGE**]\n\n\n" +
+			"### Fix\n\n- Reject the empty names [**BREAKING CHANGE**]\n- Crash\n\n\n" +
+			"### Refactor\n\n- Rename the config [**BREAKING CHANGE**]",
+		commit.BreakingReference: "### Breaking Changes\n\n" +
+			"- **Api:** Drop the v1 endpoints (Feature)\n" +
+			"- Reject the empty names (Fix)\n" +
+			"- Rename the config (Refactor)\n" +
+			"- Both markers (Feature)\n\n\n" +
+			"### Feature\n\n" +
+			"- **Api:** Drop the v1 endpoints [**BREAKING CHANGE**]\n  - remove /v1/users\n" +
+			"- Both markers [**BREAKING CHANGE**]\n\n\n" +
+			"### Fix\n\n- Reject the empty names [**BREAKING CHANGE**]\n- Crash\n\n\n" +
+			"### Refactor\n\n- Rename the config [**BREAKING CHANGE**]",
+		commit.BreakingDuplicate: "### Breaking Changes\n\n" +
+			"- **Api:** Drop the v1 endpoints\n  - remove /v1/users\n" +
+			"- Reject the empty names\n" +
+			"- Rename the config\n" +
+			"- Both markers\n\n\n" +
+			"### Feature\n\n" +
+			"- **Api:** Drop the v1 endpoints [**BREAKING CHANGE**]\n  - remove /v1/users\n" +
+			"- Both markers [**BREAKING CHANGE**]\n\n\n" +
+			"### Fix\n\n- Reject the empty names [**BREAKING CHANGE**]\n- Crash\n\n\n" +
+			"### Refactor\n\n- Rename the config [**BREAKING CHANGE**]",
+		commit.BreakingOnly: "### Breaking Changes\n\n" +
+			"- **Api:** Drop the v1 endpoints\n  - remove /v1/users\n" +
+			"- Reject the empty names\n" +
+			"- Rename the config\n" +
+			"- Both markers\n\n\n" +
+			"### Fix\n\n- Crash",
+	}
+	for policy, want := range tcs {
+		policy, want := policy, want
+		t.Run(policy.String(), func(t *testing.T) {
+			t.Parallel()
+			opts := []commit.RenderOption{commit.WithBreakingPolicy(policy)}
+			if policy != commit.BreakingInline {
+				opts = append(opts, commit.WithSubItems(0))
+			}
+			got := commit.ParseGroups(logs, opts...)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	got := commit.ParseGroups(logs[:1], commit.WithBreakingPolicy(commit.BreakingReference), commit.WithLocale(germanLocale))
+	want := "### Breaking Changes\n\n- **Api:** Drop the v1 endpoints (Neue Funktionen)\n\n\n" +
+		"### Neue Funktionen\n\n- **Api:** Drop the v1 endpoints [**INKOMPATIBEL**]"
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,114 @@
+package commit
+
+// WithBudget renders at most total entries, and at most perSection entries in
+// each section, e.g. for the announcements. Zero is no limit. The breaking
+// changes are kept first, then the entries of the sections in their order,
+// therefore the features come before the fixes and the chores. The number of
+// the entries that are left out is added after the sections, linked to the
+// page of WithMoreLink, or to the compare page of WithCompareLink.
+func WithBudget(total, perSection int) RenderOption {
+	return func(o *renderOptions) {
+		o.budget = total
+		o.sectionBudget = perSection
+	}
+}
+
+// WithMoreLink links the line of the entries that are left out by WithBudget
+// to the url, e.g. the compare page of the tags, which lists all of them. The
+// notes don't need a WithCompareLink for it.
+func WithMoreLink(url string) RenderOption {
+	return func(o *renderOptions) {
+		o.moreURL = url
+	}
+}
+
+// hasBudget returns true if the entries are limited.
+func (o *renderOptions) hasBudget() bool {
+	return o.budget > 0 || o.sectionBudget > 0
+}
+
+// applyBudget returns the breaking changes and the groups that fit in the
+// budget, and the number of the commits that are left out. The names are the
+// sections in their order. The breaking changes are taken before the others,
+// and one that is also listed in its section is counted once. The entries keep
+// their order in the sections.
+func (o *renderOptions) applyBudget(breaking []Group, groups map[string][]Group, names []string) ([]Group, map[string][]Group, int) {
+	// The breaking section has no name.
+	sections := append([]string{""}, names...)
+	list := func(name string) []Group {
+		if name == "" {
+			return breaking
+		}
+		return groups[name]
+	}
+	var (
+		seen  = make(map[int]bool)
+		kept  = make(map[int]bool)
+		shown = make(map[string]map[int]bool, len(sections))
+	)
+	take := func(name string, g Group) {
+		seen[g.index] = true
+		if shown[name] == nil {
+			shown[name] = make(map[int]bool)
+		}
+		if shown[name][g.index] {
+			return
+		}
+		if o.sectionBudget > 0 && len(shown[name]) >= o.sectionBudget {
+			return
+		}
+		if !kept[g.index] && o.budget > 0 && len(kept) >= o.budget {
+			return
+		}
+		kept[g.index] = true
+		shown[name][g.index] = true
+	}
+	for _, breakingFirst := range []bool{true, false} {
+		for _, name := range sections {
+			for _, g := range list(name) {
+				if g.Breaking == breakingFirst {
+					take(name, g)
+				}
+			}
+		}
+	}
+
+	filter := func(name string) []Group {
+		var ret []Group
+		for _, g := range list(name) {
+			if shown[name][g.index] {
+				ret = append(ret, g)
+			}
+		}
+		return ret
+	}
+	trimmed := make(map[string][]Group, len(groups))
+	for _, name := range names {
+		if l := filter(name); len(l) > 0 {
+			trimmed[name] = l
+		}
+	}
+	return filter(""), trimmed, len(seen) - len(kept)
+}
+
+// moreLine returns the line of the n entries that are left out by the budget.
+func (o *renderOptions) moreLine(n int) string {
+	line := o.locale.moreChanges(n)
+	url := o.moreURL
+	if url == "" {
+		url = o.compareURL
+	}
+	if url == "" {
+		return line
+	}
+	return "[" + line + "](" + url + ")"
+}
+
+// WithLatest starts the notes with a line that says they only have the shown
+// latest commits of the total, e.g. of a CommitPage that is Limited. The
+// line is left out if none of the commits are left out.
+func WithLatest(shown, total int) RenderOption {
+	return func(o *renderOptions) {
+		o.shown, o.total = shown, total
+	}
+}
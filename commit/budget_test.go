@@ -0,0 +1,137 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithBudget(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"chore: tidy up",
+		"fix: the leak",
+		"feat: add the thing",
+		"feat!: drop the old api",
+		"fix: the crash",
+		"feat: add the other thing",
+		"docs: explain the thing",
+	}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"total": {
+			[]commit.RenderOption{commit.WithBudget(3, 0)},
+			"### Feature\n\n" +
+				"- Add the thing\n" +
+				"- Drop the old api [**BREAKING CHANGE**]\n" +
+				"- Add the other thing\n\n" +
+				"…and 4 more changes",
+		},
+		"section": {
+			[]commit.RenderOption{commit.WithBudget(0, 1)},
+			"### Feature\n\n- Drop the old api [**BREAKING CHANGE**]\n\n\n" +
+				"### Fix\n\n- The leak\n\n\n" +
+				"### Docs\n\n- Explain the thing\n\n\n" +
+				"### Chore\n\n- Tidy up\n\n" +
+				"…and 3 more changes",
+		},
+		"both": {
+			[]commit.RenderOption{
+				commit.WithBudget(4, 2),
+				commit.WithCompareLink("https://github.com/user/repo/compare/v0.1.0...v0.2.0"),
+			},
+			"### Feature\n\n" +
+				"- Add the thing\n" +
+				"- Drop the old api [**BREAKING CHANGE**]\n\n\n" +
+				"### Fix\n\n- The leak\n- The crash\n\n" +
+				"[…and 3 more changes](https://github.com/user/repo/compare/v0.1.0...v0.2.0)\n\n" +
+				"**Full Changelog**: https://github.com/user/repo/compare/v0.1.0...v0.2.0",
+		},
+		"more link": {
+			[]commit.RenderOption{
+				commit.WithBudget(0, 1),
+				commit.WithMoreLink("https://github.com/user/repo/compare/v0.1.0...v0.2.0"),
+			},
+			"### Feature\n\n- Drop the old api [**BREAKING CHANGE**]\n\n\n" +
+				"### Fix\n\n- The leak\n\n\n" +
+				"### Docs\n\n- Explain the thing\n\n\n" +
+				"### Chore\n\n- Tidy up\n\n" +
+				"[…and 3 more changes](https://github.com/user/repo/compare/v0.1.0...v0.2.0)",
+		},
+		"duplicate": {
+			[]commit.RenderOption{commit.WithBudget(2, 0), commit.WithBreakingPolicy(commit.BreakingDuplicate)},
+			"### Breaking Changes\n\n- Drop the old api\n\n\n" +
+				"### Feature\n\n" +
+				"- Add the thing\n" +
+				"- Drop the old api [**BREAKING CHANGE**]\n\n" +
+				"…and 5 more changes",
+		},
+		"one more": {
+			[]commit.RenderOption{commit.WithBudget(6, 0)},
+			"### Feature\n\n" +
+				"- Add the thing\n" +
+				"- Drop the old api [**BREAKING CHANGE**]\n" +
+				"- Add the other thing\n\n\n" +
+				"### Fix\n\n- The leak\n- The crash\n\n\n" +
+				"### Docs\n\n- Explain the thing\n\n" +
+				"…and 1 more change",
+		},
+		"fits": {
+			[]commit.RenderOption{commit.WithBudget(7, 3)},
+			commit.ParseGroups(logs),
+		},
+		"locale": {
+			[]commit.RenderOption{
+				commit.WithBudget(1, 0),
+				commit.WithLocale(commit.Locale{MoreChanges: "…und %d weitere Änderungen"}),
+			},
+			"### Feature\n\n- Drop the old api [**BREAKING CHANGE**]\n\n" +
+				"…und 6 weitere Änderungen",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.ParseGroups(logs, tc.opts...)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWithLatest(t *testing.T) {
+	t.Parallel()
+	logs := []string{"feat: add the thing", "fix: the leak"}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"all": {
+			[]commit.RenderOption{commit.WithLatest(2, 2)},
+			"### Feature\n\n- Add the thing\n\n\n### Fix\n\n- The leak",
+		},
+		"limited": {
+			[]commit.RenderOption{commit.WithLatest(2, 5000)},
+			"Showing latest 2 of 5000 changes.\n\n" +
+				"### Feature\n\n- Add the thing\n\n\n### Fix\n\n- The leak",
+		},
+		"locale": {
+			[]commit.RenderOption{
+				commit.WithLatest(2, 3),
+				commit.WithLocale(commit.Locale{ShowingLatest: "Die neuesten %d von %d Änderungen."}),
+			},
+			"Die neuesten 2 von 3 Änderungen.\n\n" +
+				"### Feature\n\n- Add the thing\n\n\n### Fix\n\n- The leak",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseGroups(logs, tc.opts...))
+		})
+	}
+}
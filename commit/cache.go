@@ -0,0 +1,142 @@
+package commit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/kevinburke/rest/restclient"
+)
+
+// cachedResponse is a response of the GitHub API that can be revalidated with
+// its ETag.
+type cachedResponse struct {
+	ETag string `json:"etag"`
+	Body []byte `json:"body"`
+}
+
+// cacheKey returns the key of the GET request of the uri. The token is part
+// of the key, therefore a response is never served to another token that
+// might not have access to it.
+func (g *Git) cacheKey(token, uri, accept string) string {
+	return hashKey(token, g.baseURL(), uri, accept)
+}
+
+// cachedGet sends a GET request to the uri, with the ETag of the cached
+// response if there is one. If the API responds with 304, the cached response
+// is returned.
+func (g *Git) cachedGet(ctx context.Context, rc *restclient.Client, token, uri, accept string) (*http.Response, error) {
+	key := g.cacheKey(token, uri, accept)
+	cached := g.cachedResponse(key)
+	header := http.Header{}
+	if accept != "" {
+		header.Set("Accept", accept)
+	}
+	if cached != nil {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := g.send(ctx, rc, http.MethodGet, uri, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		g.debugf("%s is not modified", uri)
+		// nolint:errcheck // it's empty.
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+	// nolint:errcheck // it's read in full.
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	g.storeResponse(key, &cachedResponse{ETag: etag, Body: body})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// cachedResponse returns the response from the memory, or from the CacheDir.
+func (g *Git) cachedResponse(key string) *cachedResponse {
+	g.mu.Lock()
+	r, ok := g.responses[key]
+	g.mu.Unlock()
+	if ok || g.CacheDir == "" {
+		return r
+	}
+	b, err := os.ReadFile(filepath.Join(g.CacheDir, key+".json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			g.debugf("reading the cache: %v", err)
+		}
+		return nil
+	}
+	r = &cachedResponse{}
+	if err := json.Unmarshal(b, r); err != nil || r.ETag == "" {
+		g.debugf("ignoring the cached response %s: %v", key, err)
+		return nil
+	}
+	g.mu.Lock()
+	g.responses = cacheResponse(g.responses, key, r)
+	g.mu.Unlock()
+	return r
+}
+
+func (g *Git) storeResponse(key string, r *cachedResponse) {
+	g.mu.Lock()
+	g.responses = cacheResponse(g.responses, key, r)
+	g.mu.Unlock()
+	if g.CacheDir == "" {
+		return
+	}
+	if err := writeCache(g.CacheDir, key, r); err != nil {
+		g.debugf("writing the cache: %v", err)
+	}
+}
+
+func cacheResponse(m map[string]*cachedResponse, key string, r *cachedResponse) map[string]*cachedResponse {
+	if m == nil {
+		m = make(map[string]*cachedResponse)
+	}
+	m[key] = r
+	return m
+}
+
+// writeCache writes the v as JSON to a temporary file first, therefore a
+// concurrent run never reads a partially written one.
+func writeCache(dir, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		// nolint:errcheck // the write error is more important.
+		f.Close()
+		// nolint:errcheck // it's only a temporary file.
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		// nolint:errcheck // it's only a temporary file.
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, key+".json"))
+}
@@ -0,0 +1,117 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagServer serves a release with an ETag, and records whether each request
+// was conditional.
+type etagServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	body        string
+	conditional []bool
+}
+
+func newETagServer(t *testing.T) *etagServer {
+	t.Helper()
+	s := &etagServer{body: `{"id":1,"tag_name":"v1.0.0","body":"notes"}`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// The ETag depends on the token, like GitHub's.
+		etag := fmt.Sprintf("%q", fmt.Sprintf("%d-%s", len(s.body), r.Header.Get("Authorization")))
+		match := r.Header.Get("If-None-Match")
+		s.conditional = append(s.conditional, match != "")
+		w.Header().Set("ETag", etag)
+		if match == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *etagServer) requests() []bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.conditional
+	s.conditional = nil
+	return c
+}
+
+func TestGitCache(t *testing.T) {
+	t.Parallel()
+	t.Run("Memory", testGitCacheMemory)
+	t.Run("Token", testGitCacheToken)
+	t.Run("Dir", testGitCacheDir)
+}
+
+func testGitCacheMemory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := newETagServer(t)
+	g := &commit.Git{BaseURL: s.URL}
+
+	for i := 0; i < 3; i++ {
+		r, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, "notes", r.Body)
+	}
+	assert.Equal(t, []bool{false, true, true}, s.requests())
+
+	s.mu.Lock()
+	s.body = `{"id":1,"tag_name":"v1.0.0","body":"new notes"}`
+	s.mu.Unlock()
+	r, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "new notes", r.Body)
+}
+
+func testGitCacheToken(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := newETagServer(t)
+	g := &commit.Git{BaseURL: s.URL}
+
+	_, err := g.GetReleaseByTag(ctx, "token1", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	_, err = g.GetReleaseByTag(ctx, "token2", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	_, err = g.GetReleaseByTag(ctx, "token1", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, s.requests())
+}
+
+func testGitCacheDir(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := newETagServer(t)
+	dir := t.TempDir()
+
+	g := &commit.Git{BaseURL: s.URL, CacheDir: dir}
+	_, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+
+	g = &commit.Git{BaseURL: s.URL, CacheDir: dir}
+	r, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "notes", r.Body)
+	assert.Equal(t, []bool{false, true}, s.requests())
+
+	g = &commit.Git{BaseURL: s.URL, CacheDir: dir}
+	_, err = g.GetReleaseByTag(ctx, "other", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, s.requests())
+}
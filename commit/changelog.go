@@ -0,0 +1,84 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Changelog is the notes of the release of a tag.
+type Changelog struct {
+	Tag string
+	// PreviousTag is empty if the Tag is the first release.
+	PreviousTag string
+	// Date is the creation date of the Tag, which is the tagger date of an
+	// annotated tag.
+	Date time.Time
+	// CommitDate is the committer date of the commit of the Tag.
+	CommitDate time.Time
+	Logs       []string
+	Notes      string
+}
+
+// GenerateForTag renders the notes of an existing tag, as they were generated
+// when the tag was released. The previous tag is found relative to the tag,
+// and the commits are bounded by the tag's commit instead of the HEAD.
+// Therefore the notes can be regenerated long after the tag is cut, e.g. to be
+// published with UpdateReleaseBody after a template is changed. The "@" tag is
+// not accepted.
+func (g *Git) GenerateForTag(ctx context.Context, tag string, opts ...RenderOption) (*Changelog, error) {
+	if err := g.checkTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	c := &Changelog{Tag: tag}
+	var err error
+	c.Date, c.CommitDate, err = g.refDates(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := g.PreviousTag(ctx, tag)
+	if err != nil && !errors.Is(err, ErrNoTag) {
+		return nil, fmt.Errorf("getting previous tag: %w", err)
+	}
+	c.PreviousTag = prev
+	c.Logs, err = g.Commits(ctx, prev, tag)
+	if err != nil {
+		return nil, err
+	}
+	c.Notes = ParseGroups(c.Logs, opts...)
+	if len(c.Logs) == 0 {
+		c.Notes = NoChangesNotes(prev, opts...)
+	}
+	return c, nil
+}
+
+// PrepareTag is Prepare for an existing tag, e.g. for printing its notes. The
+// tag must be in refs/tags, therefore a branch or a revision is rejected
+// instead of being released as the tag of its previous tag, and "@" is not
+// accepted. The remote is optional, without it the notes have no links to the
+// host.
+func (g *Git) PrepareTag(ctx context.Context, tag string) (*ReleaseInfo, error) {
+	if err := g.checkTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return g.prepare(ctx, tag, true)
+}
+
+// checkTag returns ErrNoTag unless the tag exists in refs/tags.
+func (g *Git) checkTag(ctx context.Context, tag string) error {
+	if tag == "" || tag == "@" {
+		return fmt.Errorf("tag %q: %w", tag, ErrNoTag)
+	}
+	// The tag must exist, otherwise the previous tag of a revision would be
+	// used. Unlike rev-parse, show-ref doesn't accept a revision of the tag,
+	// e.g. "v1.0.0~1".
+	_, err := g.run(ctx, newCommand("show-ref", "--verify", "--quiet").revFrom("refs/tags/"+tag, tag))
+	if errors.Is(err, ErrInvalidRevision) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("tag %s: %w", tag, ErrNoTag)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitGenerateForTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "1")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "file.txt", "2")
+	commitChanges(t, dir, "feat: add thing")
+	createGitTag(t, dir, "v1.1.0")
+	createFile(t, dir, "file.txt", "3")
+	commitChanges(t, dir, "fix: fix thing")
+	createGitTag(t, dir, "v1.2.0")
+	createFile(t, dir, "file.txt", "4")
+	commitChanges(t, dir, "fix: unreleased")
+	g := &commit.Git{Dir: dir}
+
+	t.Run("Middle", func(t *testing.T) {
+		t.Parallel()
+		c, err := g.GenerateForTag(ctx, "v1.1.0", commit.WithSanitize(commit.SanitizeStrict))
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.0", c.Tag)
+		assert.Equal(t, "v1.0.0", c.PreviousTag)
+		require.Len(t, c.Logs, 1)
+		assert.Equal(t, "### Feature\n\n- Add thing", c.Notes)
+	})
+
+	t.Run("Latest", func(t *testing.T) {
+		t.Parallel()
+		c, err := g.GenerateForTag(ctx, "v1.2.0")
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.0", c.PreviousTag)
+		assert.Equal(t, "### Fix\n\n- Fix thing", c.Notes)
+	})
+
+	t.Run("First", func(t *testing.T) {
+		t.Parallel()
+		c, err := g.GenerateForTag(ctx, "v1.0.0")
+		require.NoError(t, err)
+		assert.Empty(t, c.PreviousTag)
+		assert.Len(t, c.Logs, 1)
+	})
+
+	t.Run("NoTag", func(t *testing.T) {
+		t.Parallel()
+		for _, tag := range []string{"", "@", "v9.9.9", "HEAD"} {
+			_, err := g.GenerateForTag(ctx, tag)
+			assert.ErrorIs(t, err, commit.ErrNoTag, tag)
+		}
+	})
+}
+
+func TestGitPrepareTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "1")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "file.txt", "2")
+	commitChanges(t, dir, "feat: add thing")
+	createGitTag(t, dir, "v1.1.0")
+	runGit(t, dir, "branch", "feature")
+	createFile(t, dir, "file.txt", "3")
+	commitChanges(t, dir, "fix: unreleased")
+
+	t.Run("NoRemote", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: dir}
+		info, err := g.PrepareTag(ctx, "v1.1.0")
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.0", info.Tag)
+		assert.Equal(t, "v1.0.0", info.PreviousTag)
+		require.Len(t, info.Commits, 1)
+		assert.Equal(t, "feat: add thing", info.Commits[0].Subject())
+		assert.Empty(t, info.CompareURL)
+
+		// Releasing still needs the remote.
+		_, err = g.Prepare(ctx, "v1.1.0")
+		assert.Error(t, err)
+	})
+
+	t.Run("NotTag", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: dir}
+		for _, rev := range []string{"@", "", "HEAD", "feature", "v1.1.0~1", "v9.9.9"} {
+			_, err := g.PrepareTag(ctx, rev)
+			assert.ErrorIs(t, err, commit.ErrNoTag, rev)
+		}
+	})
+}
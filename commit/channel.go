@@ -0,0 +1,105 @@
+package commit
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Channel is a release channel of the repository, e.g. stable, beta or
+// nightly, whose releases have tags of their own. A nil Channel has all tags.
+type Channel struct {
+	Name string
+	// Pattern matches the tags of the channel, after the TagPrefix, e.g.
+	// `^v\d+\.\d+\.\d+-beta\.\d+$`. A nil Pattern matches all tags.
+	Pattern *regexp.Regexp
+	// Prerelease marks the releases of the channel as pre-releases.
+	Prerelease bool
+	// Since is the channel of the previous tags of the releases. For example
+	// the notes of a beta are cumulative since the last stable release if its
+	// Since is the stable channel. If nil, the previous tag is of the channel
+	// itself.
+	Since *Channel
+	// Template is a text/template of the notes of the releases, with the
+	// .Notes, the .Highlights, the .Tag, the .PreviousTag and the .Channel.
+	// If empty, the notes are kept as they are, after the highlights.
+	Template string
+}
+
+// DefaultTemplate is the built-in template of the notes, which keeps them as
+// they are after the highlights. It documents the values a Template has.
+//
+//go:embed templates/notes.tmpl
+var DefaultTemplate string
+
+// NewChannel returns the channel of the tags that match the pattern, which is
+// a regular expression. An empty pattern matches all tags.
+func NewChannel(name, pattern string) (*Channel, error) {
+	c := &Channel{Name: name}
+	if pattern == "" {
+		return c, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the pattern of channel %s: %w", name, err)
+	}
+	c.Pattern = re
+	return c, nil
+}
+
+// Match returns true if the tag, without the TagPrefix, belongs to the
+// channel.
+func (c *Channel) Match(tag string) bool {
+	return c == nil || c.Pattern == nil || c.Pattern.MatchString(tag)
+}
+
+// RenderNotes returns the notes of the release of the info rendered with the
+// Template of the channel, or the notes after the Highlights of the info if it
+// has none. A Template places the Highlights with .Highlights.
+func (c *Channel) RenderNotes(info *ReleaseInfo, notes string) (string, error) {
+	if c == nil || c.Template == "" {
+		if info.Highlights == "" {
+			return notes, nil
+		}
+		return info.Highlights + "\n\n" + notes, nil
+	}
+	tmpl, err := template.New(c.Name).Option("missingkey=error").Parse(c.Template)
+	if err != nil {
+		return "", fmt.Errorf("parsing the template of channel %s: %w", c.Name, err)
+	}
+	buf := &strings.Builder{}
+	err = tmpl.Execute(buf, map[string]string{
+		"Notes":       notes,
+		"Highlights":  info.Highlights,
+		"Tag":         info.Tag,
+		"PreviousTag": info.PreviousTag,
+		"Channel":     c.Name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("rendering the template of channel %s: %w", c.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// key returns what identifies the tags of the channel in the caches.
+func (c *Channel) key() string {
+	if c == nil || c.Pattern == nil {
+		return ""
+	}
+	return c.Pattern.String()
+}
+
+// previousChannel returns the channel of the previous tags.
+func (g *Git) previousChannel() *Channel {
+	if g.Channel != nil && g.Channel.Since != nil {
+		return g.Channel.Since
+	}
+	return g.Channel
+}
+
+// prerelease returns true if the releases of the Channel are pre-releases.
+func (g *Git) prerelease() bool {
+	return g.Channel != nil && g.Channel.Prerelease
+}
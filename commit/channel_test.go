@@ -0,0 +1,162 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitChannel(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := repo(t, createGitRepo(t))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v1.4.0")
+	r.Commit("feat: add the thing")
+	r.Tag("v1.5.0-beta.1")
+	r.Commit("fix: the thing")
+	r.Tag("nightly-20240611")
+	r.Commit("fix: the leak")
+	r.Tag("v1.5.0-beta.2")
+	r.Commit("docs: the readme")
+	r.Tag("nightly-20240612")
+
+	stable, err := commit.NewChannel("stable", `^v\d+\.\d+\.\d+$`)
+	require.NoError(t, err)
+	beta, err := commit.NewChannel("beta", `^v\d+\.\d+\.\d+-beta\.\d+$`)
+	require.NoError(t, err)
+	nightly, err := commit.NewChannel("nightly", `^nightly-\d{8}$`)
+	require.NoError(t, err)
+	cumulative := *beta
+	cumulative.Since = stable
+
+	tcs := map[string]struct {
+		channel  *commit.Channel
+		latest   string
+		previous string
+		logs     int
+	}{
+		"all":        {latest: "nightly-20240612", previous: "v1.5.0-beta.2", logs: 1},
+		"stable":     {channel: stable, latest: "v1.4.0", previous: "", logs: 1},
+		"beta":       {channel: beta, latest: "v1.5.0-beta.2", previous: "v1.5.0-beta.1", logs: 2},
+		"cumulative": {channel: &cumulative, latest: "v1.5.0-beta.2", previous: "v1.4.0", logs: 3},
+		"nightly":    {channel: nightly, latest: "nightly-20240612", previous: "nightly-20240611", logs: 2},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := &commit.Git{Dir: r.Dir, Channel: tc.channel}
+			latest, err := g.LatestTag(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.latest, latest)
+			info, err := g.Prepare(ctx, "@")
+			require.NoError(t, err)
+			assert.Equal(t, tc.latest, info.Tag)
+			assert.Equal(t, tc.previous, info.PreviousTag)
+			assert.Len(t, info.Logs, tc.logs)
+		})
+	}
+
+	t.Run("Tags", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir, Channel: beta}
+		tags, err := g.Tags(ctx)
+		require.NoError(t, err)
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		assert.ElementsMatch(t, []string{"v1.5.0-beta.1", "v1.5.0-beta.2"}, names)
+	})
+}
+
+func TestNewChannel(t *testing.T) {
+	t.Parallel()
+	ch, err := commit.NewChannel("all", "")
+	require.NoError(t, err)
+	assert.True(t, ch.Match("anything"))
+
+	_, err = commit.NewChannel("broken", "v(")
+	assert.ErrorContains(t, err, "channel broken")
+
+	var none *commit.Channel
+	assert.True(t, none.Match("v1.0.0"))
+}
+
+func TestChannelRenderNotes(t *testing.T) {
+	t.Parallel()
+	info := &commit.ReleaseInfo{Tag: "v1.5.0-beta.2", PreviousTag: "v1.4.0"}
+	tcs := map[string]struct {
+		channel    *commit.Channel
+		highlights string
+		want       string
+		err        bool
+	}{
+		"nil":            {want: "- Fix"},
+		"nil highlights": {highlights: "- The dashboard", want: "- The dashboard\n\n- Fix"},
+		"no template":    {channel: &commit.Channel{Name: "beta"}, want: "- Fix"},
+		"template": {
+			channel: &commit.Channel{Name: "beta", Template: "> {{.Channel}} {{.Tag}} since {{.PreviousTag}}\n\n{{.Notes}}"},
+			want:    "> beta v1.5.0-beta.2 since v1.4.0\n\n- Fix",
+		},
+		"highlights": {
+			channel:    &commit.Channel{Name: "beta", Template: "{{.Notes}}\n\n{{.Highlights}}"},
+			highlights: "- The dashboard",
+			want:       "- Fix\n\n- The dashboard",
+		},
+		"missing key": {channel: &commit.Channel{Name: "beta", Template: "{{.Title}}"}, err: true},
+		"broken":      {channel: &commit.Channel{Name: "beta", Template: "{{.Notes"}, err: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			info := *info
+			info.Highlights = tc.highlights
+			got, err := tc.channel.RenderNotes(&info, "- Fix")
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	t.Parallel()
+	info := &commit.ReleaseInfo{Tag: "v1.5.0", PreviousTag: "v1.4.0"}
+	ch := &commit.Channel{Template: strings.TrimSuffix(commit.DefaultTemplate, "\n")}
+	got, err := ch.RenderNotes(info, "### Fix\n\n- The leak")
+	require.NoError(t, err)
+	assert.Equal(t, "### Fix\n\n- The leak", got)
+
+	info.Highlights = "- The dashboard"
+	got, err = ch.RenderNotes(info, "### Fix\n\n- The leak")
+	require.NoError(t, err)
+	assert.Equal(t, "- The dashboard\n\n### Fix\n\n- The leak", got, "it's the same as without a template")
+	want, err := (*commit.Channel)(nil).RenderNotes(info, "### Fix\n\n- The leak")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestGitDraftReleasePrerelease(t *testing.T) {
+	t.Parallel()
+	gh := newFakeGitHub(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL, Channel: &commit.Channel{Name: "beta", Prerelease: true}}
+	r, err := g.DraftRelease(context.Background(), "token", "user", "repo", "v1.1.0-beta.1", "notes")
+	require.NoError(t, err)
+	assert.True(t, r.Prerelease)
+
+	g.Channel = nil
+	r, err = g.DraftRelease(context.Background(), "token", "user", "repo", "v1.1.0", "notes")
+	require.NoError(t, err)
+	assert.False(t, r.Prerelease)
+}
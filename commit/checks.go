@@ -0,0 +1,232 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrChecksFailed is returned by VerifyChecks when the required checks of the
+// commit have failed, are pending or are missing.
+var ErrChecksFailed = errors.New("required checks have not passed")
+
+// DefaultChecksInterval is the time between the polls of the pending checks.
+const DefaultChecksInterval = 15 * time.Second
+
+// ChecksOptions configures VerifyChecks.
+type ChecksOptions struct {
+	// Required are the names of the check runs and the contexts of the
+	// statuses that should pass. If empty, all the checks of the commit
+	// should pass.
+	Required []string
+	// Wait is how long the pending and the missing checks are waited for.
+	// Zero doesn't wait.
+	Wait time.Duration
+	// Interval is the time between the polls of the pending checks. It
+	// defaults to DefaultChecksInterval.
+	Interval time.Duration
+}
+
+// ChecksReport is the state of the checks of a commit. Each check is in one
+// of the lists, sorted by name.
+type ChecksReport struct {
+	SHA     string
+	Passed  []string
+	Failed  []string
+	Pending []string
+	// Missing are the required checks that the commit doesn't have.
+	Missing []string
+}
+
+// OK returns true if none of the checks have failed, are pending or are
+// missing.
+func (r ChecksReport) OK() bool {
+	return len(r.Failed) == 0 && len(r.Pending) == 0 && len(r.Missing) == 0
+}
+
+// String returns the lists of the checks that haven't passed, one per line.
+func (r ChecksReport) String() string {
+	var lines []string
+	for _, l := range []struct {
+		name   string
+		checks []string
+	}{
+		{"failed", r.Failed},
+		{"pending", r.Pending},
+		{"missing", r.Missing},
+	} {
+		if len(l.checks) > 0 {
+			lines = append(lines, fmt.Sprintf("  %s: %s", l.name, strings.Join(l.checks, ", ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ChecksError is returned by VerifyChecks with the report of the checks that
+// haven't passed.
+type ChecksError struct {
+	Report ChecksReport
+}
+
+func (e *ChecksError) Error() string {
+	return fmt.Sprintf("%v on %s:\n%s", ErrChecksFailed, shortSHA(e.Report.SHA), e.Report)
+}
+
+func (e *ChecksError) Unwrap() error { return ErrChecksFailed }
+
+// checkState is the state of a check, in the order of precedence when a
+// check is reported more than once.
+type checkState int
+
+const (
+	checkPassed checkState = iota
+	checkPending
+	checkFailed
+)
+
+// CommitChecks returns the report of the check runs and the commit statuses
+// of the rev, which is resolved to its commit locally. The commit should be
+// pushed to the repository of the user.
+func (g *Git) CommitChecks(ctx context.Context, token, user, repo, rev string, required []string) (ChecksReport, error) {
+	sha, err := g.resolve(ctx, rev)
+	if err != nil {
+		return ChecksReport{}, err
+	}
+	states, err := g.checkStates(ctx, token, user, repo, sha)
+	if err != nil {
+		return ChecksReport{SHA: sha}, err
+	}
+	return newChecksReport(sha, states, required), nil
+}
+
+// VerifyChecks returns a ChecksError if the required checks of the rev
+// haven't passed. The pending and the missing checks, which might not have
+// started yet, are polled until they are done or the Wait of the opts is
+// over, and the report of the last poll is returned. A failed check stops
+// the polls.
+func (g *Git) VerifyChecks(ctx context.Context, token, user, repo, rev string, opts ChecksOptions) (ChecksReport, error) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultChecksInterval
+	}
+	deadline := time.Now().Add(opts.Wait)
+	sha, err := g.resolve(ctx, rev)
+	if err != nil {
+		return ChecksReport{}, err
+	}
+	for {
+		states, err := g.checkStates(ctx, token, user, repo, sha)
+		if err != nil {
+			return ChecksReport{SHA: sha}, fmt.Errorf("reading the checks of %s: %w", rev, err)
+		}
+		r := newChecksReport(sha, states, opts.Required)
+		if r.OK() {
+			return r, nil
+		}
+		wait := time.Until(deadline)
+		if len(r.Failed) > 0 || wait <= 0 {
+			return r, &ChecksError{Report: r}
+		}
+		if wait > interval {
+			wait = interval
+		}
+		g.debugf("waiting for the checks of %s:\n%s", rev, r)
+		select {
+		case <-ctx.Done():
+			return r, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// checkStates returns the states of the check runs and the statuses of the
+// sha, keyed by their names.
+func (g *Git) checkStates(ctx context.Context, token, user, repo, sha string) (map[string]checkState, error) {
+	states := make(map[string]checkState)
+	set := func(name string, s checkState) {
+		if old, ok := states[name]; !ok || s > old {
+			states[name] = s
+		}
+	}
+
+	var status struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			State   string `json:"state"`
+		} `json:"statuses"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/commits/%s/status?per_page=100", user, repo, sha)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &status); err != nil {
+		return nil, fmt.Errorf("reading the statuses: %w", err)
+	}
+	for _, s := range status.Statuses {
+		switch s.State {
+		case "success":
+			set(s.Context, checkPassed)
+		case "pending":
+			set(s.Context, checkPending)
+		default:
+			set(s.Context, checkFailed)
+		}
+	}
+
+	for page := 1; ; page++ {
+		var runs struct {
+			CheckRuns []struct {
+				Name       string `json:"name"`
+				Status     string `json:"status"`
+				Conclusion string `json:"conclusion"`
+			} `json:"check_runs"`
+		}
+		uri := fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?per_page=%d&page=%d", user, repo, sha, listPageSize, page)
+		if err := g.api(ctx, token, http.MethodGet, uri, nil, &runs); err != nil {
+			return nil, fmt.Errorf("reading the check runs: %w", err)
+		}
+		for _, c := range runs.CheckRuns {
+			switch {
+			case c.Status != "completed":
+				set(c.Name, checkPending)
+			case c.Conclusion == "success", c.Conclusion == "neutral", c.Conclusion == "skipped":
+				set(c.Name, checkPassed)
+			default:
+				set(c.Name, checkFailed)
+			}
+		}
+		if len(runs.CheckRuns) < listPageSize {
+			return states, nil
+		}
+	}
+}
+
+// newChecksReport returns the report of the required checks in the states,
+// or all of them if required is empty.
+func newChecksReport(sha string, states map[string]checkState, required []string) ChecksReport {
+	r := ChecksReport{SHA: sha}
+	names := required
+	if len(names) == 0 {
+		for name := range states {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		s, ok := states[name]
+		switch {
+		case !ok:
+			r.Missing = append(r.Missing, name)
+		case s == checkFailed:
+			r.Failed = append(r.Failed, name)
+		case s == checkPending:
+			r.Pending = append(r.Pending, name)
+		default:
+			r.Passed = append(r.Passed, name)
+		}
+	}
+	for _, l := range [][]string{r.Passed, r.Failed, r.Pending, r.Missing} {
+		sort.Strings(l)
+	}
+	return r
+}
@@ -0,0 +1,223 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChecks serves the statuses and the check runs of a commit. Each poll
+// gets the next of the responses, and the last one is repeated.
+type fakeChecks struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []string
+	runs     []string
+	polls    int
+}
+
+func newFakeChecks(t *testing.T, sha string, statuses, runs []string) *fakeChecks {
+	t.Helper()
+	f := &fakeChecks{statuses: statuses, runs: runs}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		prefix := "/repos/user/repo/commits/" + sha
+		var body string
+		switch r.URL.Path {
+		case prefix + "/status":
+			f.polls++
+			body = fmt.Sprintf(`{"statuses": [%s]}`, f.response(f.statuses))
+		case prefix + "/check-runs":
+			body = fmt.Sprintf(`{"check_runs": [%s]}`, f.response(f.runs))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeChecks) response(l []string) string {
+	if len(l) == 0 {
+		return ""
+	}
+	if f.polls > len(l) {
+		return l[len(l)-1]
+	}
+	return l[f.polls-1]
+}
+
+func status(name, state string) string {
+	return fmt.Sprintf(`{"context": %q, "state": %q}`, name, state)
+}
+
+func checkRun(name, status, conclusion string) string {
+	return fmt.Sprintf(`{"name": %q, "status": %q, "conclusion": %q}`, name, status, conclusion)
+}
+
+func TestGitVerifyChecks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := repo(t, createGitRepo(t))
+	sha := r.Commit("feat: add the thing")
+
+	passing := strings.Join([]string{
+		checkRun("build", "completed", "success"),
+		checkRun("lint", "completed", "skipped"),
+	}, ",")
+	tcs := map[string]struct {
+		statuses []string
+		runs     []string
+		opts     commit.ChecksOptions
+		want     commit.ChecksReport
+		polls    int
+		wantErr  bool
+	}{
+		"passed": {
+			statuses: []string{status("ci/docs", "success")},
+			runs:     []string{passing},
+			opts:     commit.ChecksOptions{Required: []string{"build", "ci/docs"}},
+			want:     commit.ChecksReport{SHA: sha, Passed: []string{"build", "ci/docs"}},
+			polls:    1,
+		},
+		"all checks": {
+			runs:  []string{passing},
+			want:  commit.ChecksReport{SHA: sha, Passed: []string{"build", "lint"}},
+			polls: 1,
+		},
+		"no checks": {
+			want:  commit.ChecksReport{SHA: sha},
+			polls: 1,
+		},
+		"failed": {
+			statuses: []string{status("ci/docs", "error")},
+			runs:     []string{passing + "," + checkRun("test", "completed", "timed_out")},
+			opts:     commit.ChecksOptions{Wait: time.Minute, Interval: time.Millisecond},
+			want: commit.ChecksReport{
+				SHA:    sha,
+				Passed: []string{"build", "lint"},
+				Failed: []string{"ci/docs", "test"},
+			},
+			polls:   1,
+			wantErr: true,
+		},
+		"missing": {
+			runs: []string{passing},
+			opts: commit.ChecksOptions{Required: []string{"build", "test"}},
+			want: commit.ChecksReport{
+				SHA:     sha,
+				Passed:  []string{"build"},
+				Missing: []string{"test"},
+			},
+			polls:   1,
+			wantErr: true,
+		},
+		"pending without wait": {
+			statuses: []string{status("ci/docs", "pending")},
+			runs:     []string{checkRun("build", "in_progress", "")},
+			want:     commit.ChecksReport{SHA: sha, Pending: []string{"build", "ci/docs"}},
+			polls:    1,
+			wantErr:  true,
+		},
+		"pending until passed": {
+			runs: []string{
+				checkRun("build", "queued", ""),
+				checkRun("build", "in_progress", "") + "," + checkRun("test", "queued", ""),
+				checkRun("build", "completed", "success") + "," + checkRun("test", "completed", "neutral"),
+			},
+			opts: commit.ChecksOptions{
+				Required: []string{"build", "test"},
+				Wait:     time.Minute,
+				Interval: time.Millisecond,
+			},
+			want:  commit.ChecksReport{SHA: sha, Passed: []string{"build", "test"}},
+			polls: 3,
+		},
+		"pending until failed": {
+			runs: []string{
+				checkRun("build", "queued", ""),
+				checkRun("build", "completed", "failure"),
+			},
+			opts:    commit.ChecksOptions{Wait: time.Minute, Interval: time.Millisecond},
+			want:    commit.ChecksReport{SHA: sha, Failed: []string{"build"}},
+			polls:   2,
+			wantErr: true,
+		},
+		"pending after wait": {
+			runs:    []string{checkRun("build", "queued", "")},
+			opts:    commit.ChecksOptions{Wait: 20 * time.Millisecond, Interval: time.Millisecond},
+			want:    commit.ChecksReport{SHA: sha, Pending: []string{"build"}},
+			wantErr: true,
+		},
+		"worst of both": {
+			statuses: []string{status("build", "failure")},
+			runs:     []string{passing},
+			opts:     commit.ChecksOptions{Required: []string{"build"}},
+			want:     commit.ChecksReport{SHA: sha, Failed: []string{"build"}},
+			polls:    1,
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gh := newFakeChecks(t, sha, tc.statuses, tc.runs)
+			g := &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+			got, err := g.VerifyChecks(ctx, "token", "user", "repo", "HEAD", tc.opts)
+			assert.Equal(t, tc.want, got)
+			if tc.polls > 0 {
+				gh.mu.Lock()
+				assert.Equal(t, tc.polls, gh.polls)
+				gh.mu.Unlock()
+			}
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, commit.ErrChecksFailed)
+			var cerr *commit.ChecksError
+			require.ErrorAs(t, err, &cerr)
+			assert.Equal(t, tc.want, cerr.Report)
+		})
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		t.Parallel()
+		err := &commit.ChecksError{Report: commit.ChecksReport{
+			SHA:     sha,
+			Passed:  []string{"build"},
+			Failed:  []string{"lint", "test"},
+			Missing: []string{"docs"},
+		}}
+		want := fmt.Sprintf("required checks have not passed on %s:\n  failed: lint, test\n  missing: docs", sha[:7])
+		assert.Equal(t, want, err.Error())
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+		gh := newFakeChecks(t, sha, nil, []string{checkRun("build", "queued", "")})
+		g := &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := g.VerifyChecks(ctx, "token", "user", "repo", "HEAD", commit.ChecksOptions{
+			Wait:     time.Minute,
+			Interval: time.Millisecond,
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
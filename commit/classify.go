@@ -0,0 +1,170 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Classifier assigns the commits to the groups, i.e. the sections of the
+// notes. It returns false if it can't classify the commit, therefore the next
+// one of the Classifiers can try.
+type Classifier interface {
+	Classify(c Commit) (group string, ok bool)
+}
+
+// Classifiers tries the classifiers in order, and the first match wins.
+type Classifiers []Classifier
+
+// Classify returns the group of the first classifier that matches the
+// commit.
+func (cs Classifiers) Classify(c Commit) (string, bool) {
+	group, _, ok := classify(cs, c)
+	return group, ok
+}
+
+// describer is implemented by the classifiers that also remove what they
+// match from the description, e.g. the "[FEATURE]" prefix of the subject.
+type describer interface {
+	describe(c Commit) (group, desc string, ok bool)
+}
+
+// classify returns the group of the commit, and the description if the
+// classifier that matches changes it.
+func classify(c Classifier, commit Commit) (group, desc string, ok bool) {
+	switch c := c.(type) {
+	case Classifiers:
+		for _, child := range c {
+			if group, desc, ok := classify(child, commit); ok {
+				return group, desc, true
+			}
+		}
+		return "", "", false
+	case describer:
+		return c.describe(commit)
+	}
+	group, ok = c.Classify(commit)
+	return group, "", ok
+}
+
+// Conventional classifies the commits by their conventional commit types,
+// e.g. "feat: add x" is a Feature. This is how the commits are grouped without
+// a Classifier.
+type Conventional struct{}
+
+// Classify returns the group of the type of the commit. It returns false if
+// the type is not known, or the subject doesn't have one.
+func (Conventional) Classify(c Commit) (string, bool) {
+	matches := descRe.FindStringSubmatch(subjectOf(c.Message))
+	if matches == nil {
+		return "", false
+	}
+	group := conventionalGroup(strings.TrimSuffix(matches[1], "!"))
+	return group, group != ""
+}
+
+// PatternRule assigns the commits whose subjects match the Pattern to the
+// Group.
+type PatternRule struct {
+	Pattern *regexp.Regexp
+	Group   string
+}
+
+// ParsePatternRule compiles the pattern of a PatternRule.
+func ParsePatternRule(pattern, group string) (PatternRule, error) {
+	if group == "" {
+		return PatternRule{}, fmt.Errorf("pattern %q has no group", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return PatternRule{}, fmt.Errorf("compiling pattern %q: %w", pattern, err)
+	}
+	return PatternRule{Pattern: re, Group: group}, nil
+}
+
+// PatternClassifier classifies the commits by their subjects, e.g. the
+// "[FEATURE]" and "[BUGFIX]" prefixes. The first rule that matches wins, and
+// the match is removed from the description if it's at the start.
+type PatternClassifier []PatternRule
+
+// Classify returns the group of the first rule that matches the subject.
+func (p PatternClassifier) Classify(c Commit) (string, bool) {
+	group, _, ok := p.describe(c)
+	return group, ok
+}
+
+func (p PatternClassifier) describe(c Commit) (string, string, bool) {
+	subject := subjectOf(c.Message)
+	for _, r := range p {
+		loc := r.Pattern.FindStringIndex(subject)
+		if loc == nil {
+			continue
+		}
+		if loc[0] == 0 && loc[1] < len(subject) {
+			return r.Group, strings.TrimSpace(subject[loc[1]:]), true
+		}
+		return r.Group, "", true
+	}
+	return "", "", false
+}
+
+// LabelClassifier classifies the commits by the labels of their pull
+// requests, which are looked up by AssociatePulls.
+type LabelClassifier struct {
+	// Groups maps the labels to the groups. The labels are case insensitive.
+	Groups map[string]string
+	// Pulls are the labels of the pull requests by their numbers, for the
+	// commits without Labels, e.g. the ones that are parsed from the notes.
+	Pulls map[int][]string
+}
+
+// NewLabelClassifier returns a LabelClassifier of the groups, with the labels
+// of the pull requests of the commits.
+func NewLabelClassifier(groups map[string]string, commits []Commit) LabelClassifier {
+	l := LabelClassifier{
+		Groups: make(map[string]string, len(groups)),
+		Pulls:  make(map[int][]string),
+	}
+	for label, group := range groups {
+		l.Groups[strings.ToLower(label)] = group
+	}
+	for _, c := range commits {
+		if c.PRNumber > 0 && len(c.Labels) > 0 {
+			l.Pulls[c.PRNumber] = c.Labels
+		}
+	}
+	return l
+}
+
+// Classify returns the group of the first label of the commit that has one.
+func (l LabelClassifier) Classify(c Commit) (string, bool) {
+	labels := c.Labels
+	if len(labels) == 0 {
+		n := c.PRNumber
+		if n == 0 {
+			n = PullNumber(c.Message)
+		}
+		labels = l.Pulls[n]
+	}
+	for _, label := range labels {
+		if group, ok := l.Groups[strings.ToLower(label)]; ok {
+			return group, true
+		}
+	}
+	return "", false
+}
+
+// WithClassifier groups the commits with the classifier instead of their
+// conventional types. The commits that it can't classify are in Misc, unless
+// WithUnmatched or WithHiddenUnmatched is set.
+func WithClassifier(c Classifier) RenderOption {
+	return func(o *renderOptions) {
+		o.classifier = c
+	}
+}
+
+// subjectOf returns the first line of the message.
+func subjectOf(msg string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+	return subject
+}
@@ -0,0 +1,160 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifiers(t *testing.T) {
+	t.Parallel()
+	t.Run("Conventional", testClassifiersConventional)
+	t.Run("Patterns", testClassifiersPatterns)
+	t.Run("Labels", testClassifiersLabels)
+	t.Run("Chain", testClassifiersChain)
+	t.Run("ParseGroups", testClassifiersParseGroups)
+}
+
+func testClassifiersConventional(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msg   string
+		group string
+		ok    bool
+	}{
+		"feature":  {"feat: add the thing", "Feature", true},
+		"scope":    {"fix(api): the leak\n\nbody", "Fix", true},
+		"breaking": {"ref!: drop the old api", "Refactor", true},
+		"unknown":  {"wip: something", "", false},
+		"plain":    {"Update README", "", false},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			group, ok := commit.Conventional{}.Classify(commit.Commit{Message: tc.msg})
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+}
+
+func patternRules(t *testing.T) commit.PatternClassifier {
+	t.Helper()
+	feature, err := commit.ParsePatternRule(`^\[FEATURE\]`, "Feature")
+	require.NoError(t, err)
+	bug, err := commit.ParsePatternRule(`(?i)\bbugfix\b`, "Fix")
+	require.NoError(t, err)
+	return commit.PatternClassifier{feature, bug}
+}
+
+func testClassifiersPatterns(t *testing.T) {
+	t.Parallel()
+	p := patternRules(t)
+	tcs := map[string]struct {
+		msg   string
+		group string
+		ok    bool
+	}{
+		"prefix":   {"[FEATURE] add the thing", "Feature", true},
+		"anywhere": {"the BugFix of the leak", "Fix", true},
+		"subject":  {"add the thing\n\n[FEATURE] in the body", "", false},
+		"none":     {"feat: add the thing", "", false},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			group, ok := p.Classify(commit.Commit{Message: tc.msg})
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+
+	_, err := commit.ParsePatternRule(`[`, "Feature")
+	assert.Error(t, err)
+	_, err = commit.ParsePatternRule(`^x`, "")
+	assert.Error(t, err)
+}
+
+func testClassifiersLabels(t *testing.T) {
+	t.Parallel()
+	commits := []commit.Commit{
+		{Message: "add the thing", PRNumber: 3, Labels: []string{"Enhancement"}},
+	}
+	l := commit.NewLabelClassifier(map[string]string{"enhancement": "Feature", "BUG": "Fix"}, commits)
+	tcs := map[string]struct {
+		c     commit.Commit
+		group string
+		ok    bool
+	}{
+		"labels":    {commit.Commit{Labels: []string{"backend", "bug"}}, "Fix", true},
+		"number":    {commit.Commit{PRNumber: 3}, "Feature", true},
+		"message":   {commit.Commit{Message: "add the thing (#3)"}, "Feature", true},
+		"unknown":   {commit.Commit{Labels: []string{"backend"}}, "", false},
+		"no labels": {commit.Commit{Message: "add the thing (#4)"}, "", false},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			group, ok := l.Classify(tc.c)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+}
+
+func testClassifiersChain(t *testing.T) {
+	t.Parallel()
+	labels := commit.NewLabelClassifier(map[string]string{"bug": "Fix"}, nil)
+	cs := commit.Classifiers{patternRules(t), labels, commit.Conventional{}}
+	tcs := map[string]struct {
+		c     commit.Commit
+		group string
+		ok    bool
+	}{
+		"first":  {commit.Commit{Message: "[FEATURE] x", Labels: []string{"bug"}}, "Feature", true},
+		"second": {commit.Commit{Message: "feat: x", Labels: []string{"bug"}}, "Fix", true},
+		"last":   {commit.Commit{Message: "docs: x"}, "Docs", true},
+		"none":   {commit.Commit{Message: "x"}, "", false},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			group, ok := cs.Classify(tc.c)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+}
+
+func testClassifiersParseGroups(t *testing.T) {
+	t.Parallel()
+	commits := []commit.Commit{
+		{Message: "speed up the thing (#5)", PRNumber: 5, Labels: []string{"enhancement"}},
+	}
+	cs := commit.Classifiers{
+		patternRules(t),
+		commit.NewLabelClassifier(map[string]string{"enhancement": "Feature"}, commits),
+		commit.Conventional{},
+	}
+	logs := []string{
+		"[FEATURE] add the thing",
+		"speed up the thing (#5)",
+		"fix: the leak",
+		"Update README",
+	}
+	got := commit.ParseGroups(logs, commit.WithClassifier(cs))
+	want := "### Feature\n\n" +
+		"- Add the thing\n" +
+		"- Speed up the thing (#5)\n\n\n" +
+		"### Fix\n\n" +
+		"- The leak\n\n\n" +
+		"### Misc\n\n" +
+		"- Update README"
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,150 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultDraftAge is the age from which the drafts are stale by default.
+const DefaultDraftAge = 7 * 24 * time.Hour
+
+// DraftCleanupOptions configures PlanDraftCleanup.
+type DraftCleanupOptions struct {
+	// MinAge is the age from which a draft is stale. The more recent drafts
+	// are kept, since their runs might not be done yet. It defaults to
+	// DefaultDraftAge.
+	MinAge time.Duration
+	// Pattern matches the tags whose stale drafts are deleted even if the
+	// tags exist on the Remote, e.g. `-ci\.\d+$`. The drafts of the missing
+	// tags are always deleted.
+	Pattern *regexp.Regexp
+}
+
+// StaleDraft is a draft release that PlanDraftCleanup has selected, or kept
+// for the Reason.
+type StaleDraft struct {
+	Release ReleaseDetails
+	Reason  string
+}
+
+// DraftCleanup is the plan of PlanDraftCleanup.
+type DraftCleanup struct {
+	// Delete are the stale drafts, whose Reason is why they are deleted.
+	Delete []StaleDraft
+	// Skipped are the other drafts, whose Reason is why they are kept.
+	Skipped []StaleDraft
+}
+
+// PlanDraftCleanup returns the draft releases of the repository that are
+// older than the MinAge and whose tags don't exist on the Remote or match
+// the Pattern, e.g. the orphans of the failed runs. The published releases
+// are never returned.
+func (g *Git) PlanDraftCleanup(ctx context.Context, token, user, repo string, opts DraftCleanupOptions) (*DraftCleanup, error) {
+	minAge := opts.MinAge
+	if minAge <= 0 {
+		minAge = DefaultDraftAge
+	}
+	releases, err := g.ListReleases(ctx, token, user, repo, ListOptions{IncludeDrafts: true})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := g.remoteTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := &DraftCleanup{}
+	now := time.Now()
+	for _, r := range releases {
+		if !r.Draft {
+			continue
+		}
+		d := StaleDraft{Release: r}
+		var stale bool
+		switch age := now.Sub(r.CreatedAt); {
+		case r.CreatedAt.IsZero():
+			d.Reason = "unknown age"
+		case age < minAge:
+			d.Reason = fmt.Sprintf("created %s ago", age.Round(time.Minute))
+		case r.TagName == "" || !tags[r.TagName]:
+			d.Reason, stale = "the tag doesn't exist", true
+		case opts.Pattern != nil && opts.Pattern.MatchString(r.TagName):
+			d.Reason, stale = "the tag matches "+opts.Pattern.String(), true
+		default:
+			d.Reason = "the tag exists"
+		}
+		if stale {
+			plan.Delete = append(plan.Delete, d)
+		} else {
+			plan.Skipped = append(plan.Skipped, d)
+		}
+	}
+	return plan, nil
+}
+
+// DraftSummary is the outcome of deleting the drafts.
+type DraftSummary struct {
+	Deleted int
+	// Skipped is the number of the drafts that are published or deleted
+	// since they are planned.
+	Skipped int
+	Failed  int
+}
+
+// DeleteDrafts deletes the drafts. Each release is read again before it's
+// deleted, and it's skipped if it's published or deleted since. A failed
+// draft doesn't stop the others.
+func (g *Git) DeleteDrafts(ctx context.Context, token, user, repo string, drafts []StaleDraft) (DraftSummary, error) {
+	var (
+		summary  DraftSummary
+		failures []string
+	)
+	for _, d := range drafts {
+		r, err := g.GetRelease(ctx, token, user, repo, d.Release.ID)
+		switch {
+		case hasStatus(err, http.StatusNotFound):
+			g.debugf("skipped the draft of %s, it's already deleted", d.Release.TagName)
+			summary.Skipped++
+			continue
+		case err != nil:
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("%s (id %d): %v", d.Release.TagName, d.Release.ID, err))
+			continue
+		case !r.Draft:
+			g.debugf("skipped the release of %s, it's published", d.Release.TagName)
+			summary.Skipped++
+			continue
+		}
+		uri := fmt.Sprintf("/repos/%s/%s/releases/%d", user, repo, d.Release.ID)
+		if err := g.api(ctx, token, http.MethodDelete, uri, nil, nil); err != nil {
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("%s (id %d): %v", d.Release.TagName, d.Release.ID, err))
+			continue
+		}
+		g.debugf("deleted the draft of %s", d.Release.TagName)
+		summary.Deleted++
+	}
+	if len(failures) > 0 {
+		return summary, fmt.Errorf("failed to delete %d draft(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return summary, nil
+}
+
+// remoteTags returns the tags of the Remote.
+func (g *Git) remoteTags(ctx context.Context) (map[string]bool, error) {
+	out, err := g.run(ctx, newCommand("ls-remote", "--tags", "--refs").rev(g.remote()))
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", g.remote(), err)
+	}
+	tags := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		_, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok {
+			tags[strings.TrimPrefix(ref, "refs/tags/")] = true
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,162 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDrafts serves the releases of user/repo, and records the deleted ones.
+type fakeDrafts struct {
+	*httptest.Server
+	mu       sync.Mutex
+	releases []commit.ReleaseDetails
+	deleted  []int64
+}
+
+func newFakeDrafts(t *testing.T, releases ...commit.ReleaseDetails) *fakeDrafts {
+	t.Helper()
+	f := &fakeDrafts{releases: append([]commit.ReleaseDetails(nil), releases...)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.URL.Path == "/repos/user/repo/releases" {
+			assert.Equal(t, "1", r.URL.Query().Get("page"))
+			assert.NoError(t, json.NewEncoder(w).Encode(f.releases))
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/repos/user/repo/releases/"), 10, 64)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for i, rel := range f.releases {
+			if rel.ID != id {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				f.deleted = append(f.deleted, id)
+				f.releases = append(f.releases[:i], f.releases[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(rel))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// lsRemote is a Runner that lists the tags for ls-remote.
+func lsRemote(tags ...string) commit.Runner {
+	return fakeRunner(func(args []string) (string, error) {
+		if args[0] != "ls-remote" {
+			return "", nil
+		}
+		lines := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			lines = append(lines, "0123456789abcdef\trefs/tags/"+tag)
+		}
+		return strings.Join(lines, "\n") + "\n", nil
+	})
+}
+
+func TestGitPlanDraftCleanup(t *testing.T) {
+	t.Parallel()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	releases := []commit.ReleaseDetails{
+		{ID: 1, TagName: "v1.0.0", CreatedAt: old, PublishedAt: &old},
+		{ID: 2, TagName: "v1.1.0", Draft: true, CreatedAt: old},
+		{ID: 3, TagName: "v1.1.0-ci.4", Draft: true, CreatedAt: old},
+		{ID: 4, TagName: "v1.2.0", Draft: true, CreatedAt: time.Now().Add(-time.Hour)},
+		{ID: 5, TagName: "v1.0.1", Draft: true, CreatedAt: old},
+		{ID: 6, Draft: true, CreatedAt: old},
+		{ID: 7, TagName: "v0.9.0", Draft: true},
+		{ID: 8, TagName: "v0.1.0", CreatedAt: old, PublishedAt: &old},
+	}
+	tcs := map[string]struct {
+		opts        commit.DraftCleanupOptions
+		wantDelete  map[int64]string
+		wantSkipped []int64
+	}{
+		"missing tags": {
+			wantDelete:  map[int64]string{5: "the tag doesn't exist", 6: "the tag doesn't exist"},
+			wantSkipped: []int64{2, 3, 4, 7},
+		},
+		"pattern": {
+			opts: commit.DraftCleanupOptions{Pattern: regexp.MustCompile(`-ci\.\d+$`)},
+			wantDelete: map[int64]string{
+				3: `the tag matches -ci\.\d+$`,
+				5: "the tag doesn't exist",
+				6: "the tag doesn't exist",
+			},
+			wantSkipped: []int64{2, 4, 7},
+		},
+		"age": {
+			opts:        commit.DraftCleanupOptions{MinAge: time.Minute},
+			wantDelete:  map[int64]string{4: "the tag doesn't exist", 5: "the tag doesn't exist", 6: "the tag doesn't exist"},
+			wantSkipped: []int64{2, 3, 7},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gh := newFakeDrafts(t, releases...)
+			g := &commit.Git{BaseURL: gh.URL, Runner: lsRemote("v1.0.0", "v1.1.0", "v1.1.0-ci.4", "v0.1.0")}
+			plan, err := g.PlanDraftCleanup(context.Background(), "token", "user", "repo", tc.opts)
+			require.NoError(t, err)
+			got := make(map[int64]string, len(plan.Delete))
+			for _, d := range plan.Delete {
+				assert.True(t, d.Release.Draft)
+				got[d.Release.ID] = d.Reason
+			}
+			assert.Equal(t, tc.wantDelete, got)
+			skipped := make([]int64, 0, len(plan.Skipped))
+			for _, d := range plan.Skipped {
+				assert.NotEmpty(t, d.Reason)
+				skipped = append(skipped, d.Release.ID)
+			}
+			assert.Equal(t, tc.wantSkipped, skipped, "the published releases are not listed")
+		})
+	}
+}
+
+func TestGitDeleteDrafts(t *testing.T) {
+	t.Parallel()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	gh := newFakeDrafts(t,
+		commit.ReleaseDetails{ID: 1, TagName: "v1.0.0", Draft: true, CreatedAt: old},
+		commit.ReleaseDetails{ID: 2, TagName: "v1.1.0", Draft: true, CreatedAt: old},
+		commit.ReleaseDetails{ID: 3, TagName: "v1.2.0", Draft: true, CreatedAt: old},
+	)
+	g := &commit.Git{BaseURL: gh.URL, Runner: lsRemote()}
+	ctx := context.Background()
+	plan, err := g.PlanDraftCleanup(ctx, "token", "user", "repo", commit.DraftCleanupOptions{})
+	require.NoError(t, err)
+	require.Len(t, plan.Delete, 3)
+
+	// One is published, and one is deleted after the plan.
+	gh.mu.Lock()
+	gh.releases[1].Draft = false
+	gh.releases = gh.releases[:2]
+	gh.mu.Unlock()
+
+	summary, err := g.DeleteDrafts(ctx, "token", "user", "repo", plan.Delete)
+	require.NoError(t, err)
+	assert.Equal(t, commit.DraftSummary{Deleted: 1, Skipped: 2}, summary)
+	assert.Equal(t, []int64{1}, gh.deleted)
+}
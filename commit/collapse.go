@@ -0,0 +1,81 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WithCollapsed renders the sections of the names, e.g. "Chore" or
+// "Dependencies", folded in a details block whose summary is the heading with
+// the number of the entries, e.g. "Dependencies (31)". The names are case
+// insensitive. The folded sections have no heading, therefore they are not in
+// the table of contents.
+func WithCollapsed(names ...string) RenderOption {
+	return func(o *renderOptions) {
+		if o.collapsed == nil {
+			o.collapsed = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			o.collapsed[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// collapse returns the section with its "### heading" line replaced by a
+// details block, or the section as it is if the name is not collapsed. The
+// count is the number of the entries of the section.
+func (o *renderOptions) collapse(name, section string, count int) string {
+	if !o.collapsed[strings.ToLower(name)] {
+		return section
+	}
+	heading, content, _ := strings.Cut(section, "\n")
+	heading = strings.TrimPrefix(heading, "### ")
+	// GitHub only renders the markdown in the block if there is a blank line
+	// after the summary and before the closing tag.
+	return fmt.Sprintf("<details><summary>%s (%d)</summary>\n\n%s\n\n</details>",
+		heading, count, strings.Trim(content, "\n"))
+}
+
+// countItems returns the number of the top level bullets of the section.
+func countItems(section string) int {
+	var n int
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, ItemPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	detailsRe      = regexp.MustCompile(`^<details><summary>(.*)</summary>$`)
+	detailsCloseRe = regexp.MustCompile(`^</details>$`)
+)
+
+// ExpandDetails returns the notes with the sections that are folded by
+// WithCollapsed rendered as plain sections again, for the renderers that
+// can't fold them, e.g. the emails.
+func ExpandDetails(notes string) string {
+	lines := strings.Split(notes, "\n")
+	ret := make([]string, 0, len(lines))
+	var open int
+	for _, line := range lines {
+		if m := detailsRe.FindStringSubmatch(line); m != nil {
+			open++
+			ret = append(ret, "### "+m[1])
+			continue
+		}
+		if open > 0 && detailsCloseRe.MatchString(line) {
+			open--
+			// The blank line before the closing tag separates the sections
+			// already.
+			if n := len(ret); n > 0 && ret[n-1] == "" {
+				ret = ret[:n-1]
+			}
+			continue
+		}
+		ret = append(ret, line)
+	}
+	return strings.Join(ret, "\n")
+}
@@ -0,0 +1,81 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGroupsCollapsed(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing",
+		"chore: the linter",
+		"chore: the formatter",
+	}
+	deps := commit.RenderDependencies([]commit.DepChange{
+		{Path: "github.com/a/b", Old: "v1.0.0", New: "v1.1.0"},
+		{Path: "github.com/c/d", Old: "v2.0.0", New: "v2.0.1"},
+	}, false)
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"none": {
+			want: "### Feature\n\n- The thing\n\n\n" +
+				"### Chore\n\n- The linter\n- The formatter",
+		},
+		"section": {
+			opts: []commit.RenderOption{commit.WithCollapsed("CHORE")},
+			want: "### Feature\n\n- The thing\n\n\n" +
+				"<details><summary>Chore (2)</summary>\n\n" +
+				"- The linter\n- The formatter\n\n</details>",
+		},
+		"dependencies": {
+			opts: []commit.RenderOption{
+				commit.WithCollapsed("dependencies"),
+				commit.WithDependencies(deps, false),
+			},
+			want: "### Feature\n\n- The thing\n\n\n" +
+				"### Chore\n\n- The linter\n- The formatter\n\n" +
+				"<details><summary>Dependencies (2)</summary>\n\n" +
+				"- Upgrade `github.com/a/b` from v1.0.0 to v1.1.0\n" +
+				"- Upgrade `github.com/c/d` from v2.0.0 to v2.0.1\n\n</details>",
+		},
+		"locale": {
+			opts: []commit.RenderOption{
+				commit.WithCollapsed("chore"),
+				commit.WithLocale(commit.Locale{Headings: map[string]string{"chore": "Wartung"}}),
+			},
+			want: "### Feature\n\n- The thing\n\n\n" +
+				"<details><summary>Wartung (2)</summary>\n\n" +
+				"- The linter\n- The formatter\n\n</details>",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.ParseGroups(logs, tc.opts...)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExpandDetails(t *testing.T) {
+	t.Parallel()
+	notes := "### Feature\n\n- The thing\n\n\n" +
+		"<details><summary>Chore (2)</summary>\n\n" +
+		"- The linter\n" +
+		"  <details><summary>Body</summary>\n\n  text\n\n  </details>\n\n" +
+		"</details>\n\n\n" +
+		"### Fix\n\n- The leak"
+	want := "### Feature\n\n- The thing\n\n\n" +
+		"### Chore (2)\n\n" +
+		"- The linter\n" +
+		"  <details><summary>Body</summary>\n\n  text\n\n  </details>\n\n\n" +
+		"### Fix\n\n- The leak"
+	assert.Equal(t, want, commit.ExpandDetails(notes))
+	assert.Equal(t, "### Fix\n\n- The leak", commit.ExpandDetails("### Fix\n\n- The leak"))
+}
@@ -3,7 +3,10 @@ package commit
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -20,12 +23,179 @@ type Group struct {
 	Verb        string
 	Subject     string
 	Description string
-	Breaking    bool
+	// Items are rendered as sub-items of the description.
+	Items    []string
+	Breaking bool
+	// index is the position of the commit in the logs.
+	index int
+	// body is the rendered body of the commit, see WithBodies.
+	body string
+	// backport is the hash of the commit it's cherry-picked from.
+	backport string
+	// author is the attribution of the entry, see WithAttribution.
+	author string
+	// size is the size hint of the entry, see WithSizeHints.
+	size string
+	// paths are the changed files of the commit, see WithPaths.
+	paths []string
+}
+
+// RenderOption configures how ParseGroups renders the logs.
+type RenderOption func(*renderOptions)
+
+type renderOptions struct {
+	subItems    bool
+	maxSubItems int
+	sanitize    Sanitize
+	mentions    map[string]bool
+	compareURL  string
+	moreURL     string
+	extraRanges []CommitRange
+	deps        string
+	replaceDeps bool
+	locale      Locale
+	groupOrder  []string
+	dateSource  DateSource
+	breaking    BreakingPolicy
+	internal    int
+	classifier  Classifier
+	// unmatched and hideUnmatched place the commits that the classifier
+	// can't classify, see WithUnmatched and WithHiddenUnmatched.
+	unmatched     string
+	hideUnmatched bool
+	// budget and sectionBudget limit the entries, see WithBudget.
+	budget        int
+	sectionBudget int
+	// bodies, maxBody and bodyLinks render the bodies, see WithBodies.
+	bodies    BodyMode
+	maxBody   int
+	bodyLinks []string
+	stats     *ReleaseStats
+	// authors and contributors attribute the commits, see WithAttribution
+	// and WithContributors.
+	authors      []string
+	contributors []string
+	// backports and originals render the cherry-picked commits, see
+	// WithBackports.
+	backports bool
+	originals map[string]Backport
+	initial   bool
+	// shown and total note the latest commits of a range, see WithLatest.
+	shown int
+	total int
+	// tocMin is the number of the headings from which the table of contents
+	// is added, see WithTOC. Zero leaves it out.
+	tocMin int
+	// collapsed has the lowercased names of the sections that are folded,
+	// see WithCollapsed.
+	collapsed map[string]bool
+	// sizes and thresholds add the size hints, see WithSizeHints.
+	sizes      []int
+	thresholds SizeThresholds
+	// paths are the changed files of the logs, see WithPaths.
+	paths [][]string
+	// footersHeading and footerKeys add the section of the footers, see
+	// WithFooters.
+	footersHeading string
+	footerKeys     []string
+}
+
+func newRenderOptions(opts []RenderOption) *renderOptions {
+	o := &renderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSubItems renders the bullet lists in the commit bodies as sub-items of
+// the commit's entry instead of dropping them. Squash merges usually contain
+// such a list of the squashed commits. If max is more than zero, only the
+// first max bullets are rendered and the rest are summarised.
+func WithSubItems(max int) RenderOption {
+	return func(o *renderOptions) {
+		o.subItems = true
+		o.maxSubItems = max
+	}
+}
+
+// WithCompareLink adds a link to the compare page of the tags at the end of
+// the notes. See RangeMode.CompareURL.
+func WithCompareLink(url string) RenderOption {
+	return func(o *renderOptions) {
+		o.compareURL = url
+	}
+}
+
+// WithDependencies adds the section rendered by RenderDependencies to the
+// notes. If replace is set and the section is not empty, the commits that bump
+// dependencies, e.g. "chore(deps): bump x from 1 to 2", are left out.
+func WithDependencies(section string, replace bool) RenderOption {
+	return func(o *renderOptions) {
+		o.deps = section
+		o.replaceDeps = replace
+	}
+}
+
+// DefaultGroupOrder is the order of the sections in the notes.
+var DefaultGroupOrder = []string{
+	"Feature", "Fix", "Enhancements", "Refactor", "Upgrades",
+	"Docs", "Style", "CI", "Chore", "Misc",
+}
+
+// WithGroupOrder renders the sections in the order of the names instead of
+// the DefaultGroupOrder. The names are case insensitive, and the sections that
+// are not listed come after the others, sorted by their names.
+func WithGroupOrder(names ...string) RenderOption {
+	return func(o *renderOptions) {
+		o.groupOrder = names
+	}
+}
+
+// sortGroups returns the names of the groups in the order of the sections.
+func (o *renderOptions) sortGroups(groups map[string][]Group) []string {
+	order := o.groupOrder
+	if order == nil {
+		order = DefaultGroupOrder
+	}
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, ok := rank[strings.ToLower(name)]; !ok {
+			rank[strings.ToLower(name)] = i
+		}
+	}
+	if _, ok := rank[strings.ToLower(backportsHeading)]; !ok {
+		rank[strings.ToLower(backportsHeading)] = -1
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, iok := rank[strings.ToLower(names[i])]
+		rj, jok := rank[strings.ToLower(names[j])]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		}
+		return names[i] < names[j]
+	})
+	return names
 }
 
 // GroupFromCommit creates a Group object from the given line.
 func GroupFromCommit(msg string) Group {
 	matches := descRe.FindStringSubmatch(msg)
+	if matches == nil {
+		// The message doesn't start with a word, e.g. it starts with an emoji.
+		return Group{
+			raw:         msg,
+			Verb:        "Misc",
+			Description: strings.TrimSpace(msg),
+		}
+	}
 	verb := matches[1]
 	subject := matches[2]
 	verbBreak := matches[3]
@@ -37,29 +207,7 @@ func GroupFromCommit(msg string) Group {
 		verb = strings.TrimSuffix(verb, "!")
 	}
 
-	switch strings.ToLower(verb) {
-	case "ref", "refactor":
-		verb = "Refactor"
-	case "feat", "feature":
-		verb = "Feature"
-	case "fix", "fixed":
-		verb = "Fix"
-	case "chore":
-		verb = "Chore"
-	case "enhance", "enhancements", "enhancement":
-		verb = "Enhancements"
-	case "upgrade":
-		verb = "Upgrades"
-	case "ci":
-		verb = "CI"
-	case "style":
-		verb = "Style"
-	case "docs":
-		verb = "Docs"
-	default:
-		verb = ""
-	}
-
+	verb = conventionalGroup(verb)
 	if verb == "" {
 		verb = "Misc"
 	}
@@ -76,6 +224,32 @@ func GroupFromCommit(msg string) Group {
 	}
 }
 
+// conventionalGroup returns the group of the conventional type, or an empty
+// string if the type is not known.
+func conventionalGroup(typ string) string {
+	switch strings.ToLower(typ) {
+	case "ref", "refactor":
+		return "Refactor"
+	case "feat", "feature":
+		return "Feature"
+	case "fix", "fixed":
+		return "Fix"
+	case "chore":
+		return "Chore"
+	case "enhance", "enhancements", "enhancement":
+		return "Enhancements"
+	case "upgrade":
+		return "Upgrades"
+	case "ci":
+		return "CI"
+	case "style":
+		return "Style"
+	case "docs":
+		return "Docs"
+	}
+	return ""
+}
+
 // Section returns a printable line for the section.
 func (g Group) Section() string {
 	return "### " + upperFirst(g.Verb)
@@ -119,67 +293,274 @@ func (g Group) DescriptionString() string {
 }
 
 // ParseGroups parses the lines in the logs and returns them as a string.
-func ParseGroups(logs []string) string {
-	logs = cleanup(logs)
-	groups := make(map[string][]Group, len(logs))
-	for _, line := range logs {
-		group := GroupFromCommit(line)
+func ParseGroups(logs []string, opts ...RenderOption) string {
+	o := newRenderOptions(opts)
+	entries := cleanup(logs, o)
+	groups := make(map[string][]Group, len(entries))
+	var breaking []Group
+	for i, e := range entries {
+		if o.replaceDeps && o.deps != "" && isDepCommit(e.title) {
+			continue
+		}
+		group := GroupFromCommit(e.title)
+		group.paths = e.paths
+		if o.classifier != nil && !o.classify(&group) && o.hideUnmatched && !group.Breaking && !e.breaking {
+			continue
+		}
+		if o.backports && e.backport != "" {
+			group.Verb = backportsHeading
+			group.backport = e.backport
+		}
+		group.Items = e.items
+		group.body = e.body
+		group.author = e.author
+		group.size = e.size
+		group.index = i
+		// The "!" of the type and the BREAKING CHANGE footer are the same.
+		group.Breaking = group.Breaking || e.breaking
+		if group.Breaking && o.breaking != BreakingInline {
+			breaking = append(breaking, group)
+			if o.breaking == BreakingOnly {
+				continue
+			}
+		}
 		groups[group.Verb] = append(groups[group.Verb], group)
 	}
 
-	buf := &strings.Builder{}
-	i := 0
-	for _, desc := range groups {
-		fmt.Fprintln(buf, desc[0].Section()+"\n")
-		for _, line := range desc {
-			fmt.Fprint(buf, line.DescriptionString())
-			if line.Breaking {
-				fmt.Fprintf(buf, " [**BREAKING CHANGE**]")
+	names := o.sortGroups(groups)
+	var more int
+	if o.hasBudget() {
+		breaking, groups, more = o.applyBudget(breaking, groups, names)
+	}
+
+	sections := make([]string, 0, len(groups)+2)
+	if len(breaking) > 0 {
+		buf := &strings.Builder{}
+		fmt.Fprintln(buf, "### "+o.locale.Heading(breakingHeading)+"\n")
+		for _, g := range breaking {
+			if o.breaking == BreakingReference {
+				fmt.Fprintf(buf, "%s (%s)\n", g.DescriptionString(), o.locale.Heading(upperFirst(g.Verb)))
+				continue
 			}
-			fmt.Fprintln(buf, "")
+			o.renderGroup(buf, g, false)
 		}
-		i++
-		if i < len(groups) {
-			fmt.Fprintf(buf, "\n\n")
+		sections = append(sections, strings.TrimSuffix(buf.String(), "\n"))
+	}
+	for _, name := range names {
+		desc := groups[name]
+		if len(desc) == 0 {
+			continue
 		}
+		buf := &strings.Builder{}
+		fmt.Fprintln(buf, "### "+o.locale.Heading(upperFirst(desc[0].Verb))+"\n")
+		for _, g := range desc {
+			o.renderGroup(buf, g, g.Breaking)
+		}
+		section := strings.TrimSuffix(buf.String(), "\n")
+		sections = append(sections, o.collapse(name, section, len(desc)))
 	}
 
-	str := buf.String()
-	return strings.TrimSuffix(str, "\n")
+	str := strings.Join(sections, "\n\n\n")
+	if o.initial {
+		str = strings.TrimSuffix(o.locale.firstRelease()+"\n\n"+str, "\n\n")
+	}
+	if o.total > o.shown {
+		str = strings.TrimSuffix(o.locale.showingLatest(o.shown, o.total)+"\n\n"+str, "\n\n")
+	}
+	if more > 0 {
+		str += "\n\n" + o.moreLine(more)
+	}
+	if o.deps != "" {
+		if str != "" {
+			str += "\n\n"
+		}
+		deps := o.deps
+		if h := o.locale.Heading("Dependencies"); h != "Dependencies" {
+			deps = strings.Replace(deps, "### Dependencies", "### "+h, 1)
+		}
+		str += o.collapse("Dependencies", deps, countItems(deps))
+	}
+	if o.internal > 0 {
+		if str != "" {
+			str += "\n\n"
+		}
+		str += o.locale.internalChanges(o.internal)
+	}
+	if section := o.footersSection(logs); section != "" {
+		if str != "" {
+			str += "\n\n"
+		}
+		str += o.collapse(o.footersHeading, section, countItems(section))
+	}
+	if section := o.contributorsSection(); section != "" {
+		if str != "" {
+			str += "\n\n"
+		}
+		str += section
+	}
+	if footer := o.statsFooter(); footer != "" {
+		if str != "" {
+			str += "\n\n"
+		}
+		str += footer
+	}
+	if o.compareURL != "" {
+		if str != "" {
+			str += "\n\n"
+		}
+		str += "**" + o.locale.fullChangelog() + "**: " + o.compareURL + o.extraRangesNote()
+	}
+	if o.tocMin > 0 {
+		str = o.addTOC(str)
+	}
+	return str
 }
 
-// cleanup returns only the title of the logs.
-func cleanup(logs []string) []string {
-	ret := make([]string, 0, len(logs))
-	for _, commit := range logs {
-		items := strings.Split(commit, "\n")
-		item := items[0]
+// classify sets the Verb of the g with the classifier, and returns false if
+// it can't classify the commit. Those commits are in the unmatched group, or
+// in Misc.
+func (o *renderOptions) classify(g *Group) bool {
+	c := Commit{Message: g.raw, PRNumber: PullNumber(g.raw), Paths: g.paths}
+	group, desc, ok := classify(o.classifier, c)
+	if desc == "" {
+		// The first word of the subjects that are not conventional is not
+		// a type, unless the classifier defines it.
+		typ, _ := parseType(subjectOf(g.raw))
+		if _, conventional := (Conventional{}).Classify(c); !conventional && (typ == "" || !definesType(o.classifier, typ)) {
+			desc = strings.TrimSpace(g.raw)
+		}
+	}
+	if desc != "" {
+		g.Subject, g.Description = "", desc
+	}
+	switch {
+	case ok:
+		g.Verb = group
+	case o.unmatched != "":
+		g.Verb = o.unmatched
+	default:
+		g.Verb = "Misc"
+	}
+	return ok
+}
+
+// renderGroup writes the entry of the g, with its sub-items and its body. The
+// breaking marker is added if marked is true.
+func (o *renderOptions) renderGroup(buf *strings.Builder, g Group, marked bool) {
+	fmt.Fprint(buf, g.DescriptionString()+g.author+g.size)
+	if g.backport != "" {
+		fmt.Fprintf(buf, " (%s)", o.backportRef(g.backport))
+	}
+	if marked {
+		fmt.Fprint(buf, " "+o.locale.breakingMarker())
+	}
+	fmt.Fprintln(buf, "")
+	for _, item := range g.Items {
+		fmt.Fprintf(buf, "  %s%s\n", ItemPrefix, item)
+	}
+	if g.body != "" {
+		fmt.Fprint(buf, "\n"+g.body)
+	}
+}
+
+// entry is a cleaned up commit message.
+type entry struct {
+	title string
+	items []string
+	// breaking is true if the body has a BREAKING CHANGE footer.
+	breaking bool
+	// body is the rendered body if the bodies are requested.
+	body string
+	// backport is the hash of the trailer of a cherry-picked commit.
+	backport string
+	// author is the suffix of the entry with its author.
+	author string
+	// size is the suffix of the entry with its size hint.
+	size string
+	// paths are the changed files of the commit.
+	paths []string
+}
+
+// cleanup returns only the title of the logs. If sub-items are requested, the
+// bullets of the bodies are returned separately.
+func cleanup(logs []string, o *renderOptions) []entry {
+	ret := make([]entry, 0, len(logs))
+	for i, commit := range logs {
+		items := splitLines(commit)
+		// A builder is used because bodies with many references are otherwise
+		// copied over and over.
+		item := &strings.Builder{}
+		item.WriteString(o.sanitizeTitle(items[0]))
 		breaking := false
+		var bullets []string
 		for _, line := range items[1:] {
 			if strings.Contains(line, "BREAKING CHANGE") {
 				breaking = true
 			}
+			if o.subItems && o.bodies == BodyNone {
+				if bullet, ok := bulletItem(line); ok {
+					bullets = append(bullets, o.sanitizeText(bullet))
+					continue
+				}
+			}
 			if !strings.Contains(line, "#") {
 				continue
 			}
-			item = fmt.Sprintf("%s (%s)", item, line)
+			fmt.Fprintf(item, " (%s)", o.sanitizeText(line))
 		}
-		if breaking {
-			item += " [**BREAKING CHANGE**]"
-		}
-		if item == "" {
+		if item.Len() == 0 {
 			continue
 		}
-		item = strings.TrimPrefix(item, " ")
-		ret = append(ret, item)
+		if o.maxSubItems > 0 && len(bullets) > o.maxSubItems {
+			more := len(bullets) - o.maxSubItems
+			bullets = append(bullets[:o.maxSubItems], fmt.Sprintf("…and %d more", more))
+		}
+		e := entry{
+			title:    strings.TrimPrefix(item.String(), " "),
+			items:    bullets,
+			breaking: breaking,
+			author:   o.attribution(i),
+			size:     o.sizeHint(i),
+		}
+		if i < len(o.paths) {
+			e.paths = o.paths[i]
+		}
+		if o.backports {
+			e.backport = CherryPickOf(commit)
+		}
+		if o.bodies != BodyNone {
+			var link string
+			if i < len(o.bodyLinks) {
+				link = o.bodyLinks[i]
+			}
+			e.body = o.renderBody(commit, link)
+		}
+		ret = append(ret, e)
 	}
 	return ret
 }
 
+// bulletItem returns the text of a top level markdown bullet. Nested bullets
+// are not considered.
+func bulletItem(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 1 {
+		return "", false
+	}
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(trimmed, marker) {
+			text := strings.TrimSpace(trimmed[len(marker):])
+			return text, text != ""
+		}
+	}
+	return "", false
+}
+
 // upperFirst makes the first letter of the string an uppercase letter.
 func upperFirst(s string) string {
-	if s == "" {
-		return ""
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
 	}
-	return strings.ToUpper(s[:1]) + s[1:]
+	return string(unicode.ToUpper(r)) + s[size:]
 }
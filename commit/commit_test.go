@@ -3,8 +3,10 @@ package commit_test
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/arsham/gitrelease/commit"
 	"github.com/blokur/testament"
@@ -71,6 +73,8 @@ func TestGroupFromCommit(t *testing.T) {
 		"hyphen subj":  {line: "fix(git-commit): something", want: commit.NewGroup("Fix", "git-commit", "something", false)},
 		"underscore":   {line: "fix(git_commit): something", want: commit.NewGroup("Fix", "git_commit", "something", false)},
 		"docs":         {line: "docs: change something", want: commit.NewGroup("Docs", "", "change something", false)},
+		"emoji":        {line: "🎉 initial commit", want: commit.NewGroup("Misc", "", "🎉 initial commit", false)},
+		"empty":        {line: "", want: commit.NewGroup("Misc", "", "", false)},
 	}
 
 	for name, tc := range tcs {
@@ -146,6 +150,10 @@ func testGroupDescriptionString(t *testing.T) {
 			group: commit.NewGroup("Docs", "README", msg, false),
 			want:  fmt.Sprintf("%s**README:** %s", prefix, wantMsg),
 		},
+		"multi-byte letter": {
+			group: commit.NewGroup("Fix", "état", "ça marche", false),
+			want:  fmt.Sprintf("%s**État:** Ça marche", prefix),
+		},
 	}
 
 	for name, tc := range tcs {
@@ -164,6 +172,9 @@ func testGroupParseGroups(t *testing.T) {
 	t.Run("MultipleGroups", testGroupParseGroupsMultipleGroups)
 	t.Run("BreakingSign", testGroupParseGroupsBreakingSign)
 	t.Run("BreakingFooter", testGroupParseGroupsBreakingFooter)
+	t.Run("CRLF", testGroupParseGroupsCRLF)
+	t.Run("SubItems", testGroupParseGroupsSubItems)
+	t.Run("CompareLink", testGroupParseGroupsCompareLink)
 }
 
 func testGroupParseGroupsOneGroup(t *testing.T) {
@@ -275,3 +286,161 @@ func testGroupParseGroupsBreakingFooter(t *testing.T) {
 		t.Errorf("(-want +got):\n%s", diff)
 	}
 }
+
+func testGroupParseGroupsCRLF(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"fix(repo): this is a test\r\n\r\nClose #12\r\n",
+		"fix: this is a new api\r\n\r\nBREAKING CHANGE: this is a changed api\r\n",
+	}
+	got := commit.ParseGroups(logs)
+
+	want := strings.Join([]string{
+		"### Fix\n",
+		"- **Repo:** This is a test (Close #12)",
+		"- This is a new api [**BREAKING CHANGE**]",
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGroupParseGroupsCompareLink(t *testing.T) {
+	t.Parallel()
+	link := "https://github.com/arsham/gitrelease/compare/v1.0.0..v1.1.0"
+	got := commit.ParseGroups([]string{"fix: thing"}, commit.WithCompareLink(link))
+	want := "### Fix\n\n- Thing\n\n**Full Changelog**: " + link
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups(nil, commit.WithCompareLink(link))
+	want = "**Full Changelog**: " + link
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups([]string{"fix: thing"}, commit.WithCompareLink(""))
+	want = "### Fix\n\n- Thing"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGroupParseGroupsSubItems(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		strings.Join([]string{
+			"feat(repo): add the new api (#42)",
+			"",
+			"* feat(api): add the endpoint",
+			"* fix(api): handle errors",
+			"  * this is a nested item",
+			"- docs: document the api",
+			"",
+			"Close #12",
+		}, "\n"),
+	}
+
+	got := commit.ParseGroups(logs)
+	want := "### Feature\n\n- **Repo:** Add the new api (#42) (Close #12)"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups(logs, commit.WithSubItems(0))
+	want = strings.Join([]string{
+		"### Feature\n",
+		"- **Repo:** Add the new api (#42) (Close #12)",
+		"  - feat(api): add the endpoint",
+		"  - fix(api): handle errors",
+		"  - docs: document the api",
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups(logs, commit.WithSubItems(2))
+	want = strings.Join([]string{
+		"### Feature\n",
+		"- **Repo:** Add the new api (#42) (Close #12)",
+		"  - feat(api): add the endpoint",
+		"  - fix(api): handle errors",
+		"  - …and 1 more",
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+// weirdMessages are real world commit messages that have caused troubles in
+// other tools.
+var weirdMessages = []string{
+	"",
+	" ",
+	"\n\n\n",
+	"🎉 initial commit",
+	"feat(🚀): launch",
+	"fix: ✨ sparkles ✨",
+	"إصلاح: خطأ في التحليل",
+	"feat(واجهة): إضافة ميزة جديدة",
+	"‮fix: reversed text‬",
+	"ção: acentuação",
+	"ßtraße: upper first",
+	"feat: " + strings.Repeat("a", 100*1024),
+	"fix: title\n\n" + strings.Repeat("body line #1\n", 8*1024),
+	"\x00\x01\x02\xff\xfe\xfd",
+	"fix(\xff): \xfe\xfd",
+	"feat!(a,b,c)!: (((((",
+	"ref!: breaking\n\nBREAKING CHANGE: yes\nClose #12",
+	"1.2.3: numbers first",
+	"(scope): no verb",
+	"fix(scope: unbalanced",
+	`fix: escaped\n\nClose #1`,
+}
+
+func FuzzGroupFromCommit(f *testing.F) {
+	for _, msg := range weirdMessages {
+		f.Add(msg)
+	}
+	f.Fuzz(func(t *testing.T, msg string) {
+		g := commit.GroupFromCommit(msg)
+		if g.Verb == "" {
+			t.Errorf("empty verb for %q", msg)
+		}
+		desc := g.DescriptionString()
+		if utf8.ValidString(msg) && !utf8.ValidString(desc) {
+			t.Errorf("invalid UTF-8 description %q for %q", desc, msg)
+		}
+	})
+}
+
+func FuzzParseGroups(f *testing.F) {
+	for _, msg := range weirdMessages {
+		f.Add(msg, "fix: second\n\nBREAKING CHANGE: "+msg)
+	}
+	f.Fuzz(func(t *testing.T, msg1, msg2 string) {
+		got := commit.ParseGroups([]string{msg1, msg2})
+		if utf8.ValidString(msg1) && utf8.ValidString(msg2) && !utf8.ValidString(got) {
+			t.Errorf("invalid UTF-8 output %q", got)
+		}
+	})
+}
+
+func BenchmarkParseGroups(b *testing.B) {
+	for _, size := range []int{10000, 100000} {
+		logs := make([]string, size)
+		for i := range logs {
+			logs[i] = fmt.Sprintf("feat(scope%d): commit number %d\n\nsome description\n\nClose #%d", i%20, i, i)
+			if i%7 == 0 {
+				logs[i] = fmt.Sprintf("fix!: commit number %d\n\nBREAKING CHANGE: this is breaking", i)
+			}
+		}
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				commit.ParseGroups(logs)
+			}
+		})
+	}
+}
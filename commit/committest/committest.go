@@ -0,0 +1,318 @@
+// Package committest creates git repositories for the tests of the code that
+// uses the commit package. The repositories are created in temporary folders
+// that are removed when the test ends, and the git commands are run with a
+// fixed identity and without the global and the system configs, so the
+// results don't depend on the machine.
+package committest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Default identity of the authors and the committers.
+const (
+	DefaultName  = "committest"
+	DefaultEmail = "committest@example.com"
+)
+
+// Repo is a git repository in a temporary folder.
+type Repo struct {
+	// Dir is the work tree of the repository.
+	Dir   string
+	t     testing.TB
+	name  string
+	email string
+	// next is the date of the next commit, if the dates are fixed.
+	next time.Time
+	step time.Duration
+}
+
+// Option configures the Repo that is created by NewRepo.
+type Option func(*Repo)
+
+// WithIdentity sets the name and the email of the authors and the committers.
+func WithIdentity(name, email string) Option {
+	return func(r *Repo) {
+		r.name = name
+		r.email = email
+	}
+}
+
+// WithDates backdates the commits and the annotated tags. The first one is
+// dated at start, and each following one step later. With fixed dates and
+// contents, the commits get the same SHAs in every run.
+func WithDates(start time.Time, step time.Duration) Option {
+	return func(r *Repo) {
+		r.next = start
+		r.step = step
+	}
+}
+
+// NewRepo creates an empty repository, which is removed when the test ends.
+func NewRepo(t testing.TB, opts ...Option) *Repo {
+	t.Helper()
+	r := &Repo{
+		Dir:   filepath.Join(t.TempDir(), "project"),
+		t:     t,
+		name:  DefaultName,
+		email: DefaultEmail,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := os.Mkdir(r.Dir, 0o755); err != nil {
+		t.Fatalf("creating the repository: %v", err)
+	}
+	r.Run("init", "--quiet")
+	r.Run("config", "user.name", r.name)
+	r.Run("config", "user.email", r.email)
+	r.Run("config", "commit.gpgSign", "false")
+	r.Run("config", "tag.gpgSign", "false")
+	return r
+}
+
+// Open returns the Repo of an existing repository in the dir. The commands
+// are run with the identity of the options, and the repository is not
+// removed when the test ends.
+func Open(t testing.TB, dir string, opts ...Option) *Repo {
+	r := &Repo{
+		Dir:   dir,
+		t:     t,
+		name:  DefaultName,
+		email: DefaultEmail,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// File is a file that is written before a commit.
+type File struct {
+	Path    string
+	Content string
+}
+
+// WriteFile writes the content into the file of the path, which is relative
+// to the Dir. The parent folders are created if needed.
+func (r *Repo) WriteFile(path, content string) {
+	r.t.Helper()
+	name := filepath.Join(r.Dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		r.t.Fatalf("creating the folder of %s: %v", path, err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		r.t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+// AppendFile appends the content to the file of the path, which is created if
+// it doesn't exist.
+func (r *Repo) AppendFile(path, content string) {
+	r.t.Helper()
+	name := filepath.Join(r.Dir, filepath.FromSlash(path))
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		r.t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		r.t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+// Commit writes the files and commits all the changes of the work tree with
+// the message. The commit is allowed to be empty. It returns the SHA of the
+// commit.
+func (r *Repo) Commit(msg string, files ...File) string {
+	r.t.Helper()
+	var env []string
+	if !r.next.IsZero() {
+		env = r.dateEnv(r.next)
+		r.next = r.next.Add(r.step)
+	}
+	return r.commit(msg, env, files)
+}
+
+// CommitAt is like Commit, but the commit is dated at the date regardless of
+// the dates of the options.
+func (r *Repo) CommitAt(msg string, date time.Time, files ...File) string {
+	r.t.Helper()
+	return r.commit(msg, r.dateEnv(date), files)
+}
+
+func (r *Repo) commit(msg string, env []string, files []File) string {
+	r.t.Helper()
+	for _, f := range files {
+		r.WriteFile(f.Path, f.Content)
+	}
+	r.Run("add", "-A")
+	r.RunEnv(env, "commit", "--quiet", "--allow-empty", "--no-gpg-sign", "-m", msg)
+	return r.Head()
+}
+
+// Generate appends n commits to the current branch, and tags every tagEvery
+// commit with the name of the format and the number of the tag, e.g.
+// "v0.%d.0". The commits have no files, and are imported in one git process,
+// therefore it's fast enough for the benchmarks of the histories with
+// hundreds of thousands of commits. The dates follow the options, or are a
+// minute apart otherwise.
+func (r *Repo) Generate(n, tagEvery int, format string) {
+	r.t.Helper()
+	// The HEAD of a new repository doesn't exist yet.
+	head := exec.CommandContext(context.Background(), "git", "rev-parse", "--verify", "--quiet", "HEAD")
+	head.Dir = r.Dir
+	head.Env = r.env()
+	out, _ := head.Output()
+	parent := strings.TrimSpace(string(out))
+	date := r.next
+	if date.IsZero() {
+		date = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	step := r.step
+	if step == 0 {
+		step = time.Minute
+	}
+	branch := "refs/heads/" + r.CurrentBranch()
+	buf := &strings.Builder{}
+	tags := 0
+	for i := 1; i <= n; i++ {
+		typ := "fix"
+		if i%3 == 0 {
+			typ = "feat"
+		}
+		msg := fmt.Sprintf("%s: change %d\n", typ, i)
+		fmt.Fprintf(buf, "commit %s\nmark :%d\n", branch, i)
+		fmt.Fprintf(buf, "committer %s <%s> %d +0000\n", r.name, r.email, date.Unix())
+		fmt.Fprintf(buf, "data %d\n%s", len(msg), msg)
+		switch {
+		case i > 1:
+			fmt.Fprintf(buf, "from :%d\n", i-1)
+		case parent != "":
+			fmt.Fprintf(buf, "from %s\n", parent)
+		}
+		buf.WriteString("\n")
+		if tagEvery > 0 && i%tagEvery == 0 {
+			tags++
+			fmt.Fprintf(buf, "reset refs/tags/%s\nfrom :%d\n\n", fmt.Sprintf(format, tags), i)
+		}
+		date = date.Add(step)
+	}
+	if !r.next.IsZero() {
+		r.next = date
+	}
+	cmd := exec.CommandContext(context.Background(), "git", "fast-import", "--quiet")
+	cmd.Dir = r.Dir
+	cmd.Env = r.env()
+	cmd.Stdin = strings.NewReader(buf.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git fast-import: %v\n%s", err, out)
+	}
+}
+
+// Head returns the SHA of the HEAD.
+func (r *Repo) Head() string {
+	r.t.Helper()
+	return strings.TrimSpace(r.Run("rev-parse", "HEAD"))
+}
+
+// Tag creates a lightweight tag on the HEAD.
+func (r *Repo) Tag(name string) {
+	r.t.Helper()
+	r.Run("tag", name)
+}
+
+// TagAt creates a lightweight tag on the rev.
+func (r *Repo) TagAt(name, rev string) {
+	r.t.Helper()
+	r.Run("tag", name, rev)
+}
+
+// AnnotatedTag creates an annotated tag with the message on the HEAD.
+func (r *Repo) AnnotatedTag(name, msg string) {
+	r.t.Helper()
+	var env []string
+	if !r.next.IsZero() {
+		env = r.dateEnv(r.next)
+		r.next = r.next.Add(r.step)
+	}
+	r.RunEnv(env, "tag", "--no-sign", "-a", name, "-m", msg)
+}
+
+// AnnotatedTagAt is like AnnotatedTag, but the tag is dated at the date
+// regardless of the dates of the options.
+func (r *Repo) AnnotatedTagAt(name, msg string, date time.Time) {
+	r.t.Helper()
+	r.RunEnv(r.dateEnv(date), "tag", "--no-sign", "-a", name, "-m", msg)
+}
+
+// AddRemote adds a remote with the URL.
+func (r *Repo) AddRemote(name, url string) {
+	r.t.Helper()
+	r.Run("remote", "add", name, url)
+}
+
+// Branch creates a branch on the HEAD and checks it out.
+func (r *Repo) Branch(name string) {
+	r.t.Helper()
+	r.Run("checkout", "--quiet", "-b", name)
+}
+
+// Checkout checks out the rev.
+func (r *Repo) Checkout(rev string) {
+	r.t.Helper()
+	r.Run("checkout", "--quiet", rev)
+}
+
+// CurrentBranch returns the name of the checked out branch, which depends on
+// the version of git for a new repository.
+func (r *Repo) CurrentBranch() string {
+	r.t.Helper()
+	return strings.TrimSpace(r.Run("branch", "--show-current"))
+}
+
+// Run runs git with the args in the Dir and returns its combined output. The
+// test fails if git fails.
+func (r *Repo) Run(args ...string) string {
+	r.t.Helper()
+	return r.RunEnv(nil, args...)
+}
+
+// RunEnv is like Run, with the environment variables added to the ones of
+// the repository.
+func (r *Repo) RunEnv(env []string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.CommandContext(context.Background(), "git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(r.env(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// env returns the environment of the git commands, which doesn't read the
+// configs of the machine.
+func (r *Repo) env() []string {
+	return append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME="+r.name,
+		"GIT_AUTHOR_EMAIL="+r.email,
+		"GIT_COMMITTER_NAME="+r.name,
+		"GIT_COMMITTER_EMAIL="+r.email,
+	)
+}
+
+func (r *Repo) dateEnv(date time.Time) []string {
+	d := fmt.Sprintf("%d +0000", date.Unix())
+	return []string{"GIT_AUTHOR_DATE=" + d, "GIT_COMMITTER_DATE=" + d}
+}
@@ -0,0 +1,124 @@
+package committest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepo(t *testing.T) {
+	t.Parallel()
+	t.Run("Commit", testRepoCommit)
+	t.Run("Dates", testRepoDates)
+	t.Run("Branch", testRepoBranch)
+	t.Run("Git", testRepoGit)
+}
+
+func testRepoCommit(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t)
+	sha := r.Commit("feat: one", committest.File{Path: "dir/file.txt", Content: "one"})
+	assert.Equal(t, sha, r.Head())
+	assert.Equal(t, "dir/file.txt\n", r.Run("ls-files"))
+
+	r.AppendFile("dir/file.txt", " two")
+	r.Commit("fix: two")
+	assert.Equal(t, "one two", r.Run("show", "HEAD:dir/file.txt"))
+	r.Commit("chore: empty")
+
+	author := r.Run("log", "-1", "--format=%an <%ae>|%cn <%ce>")
+	want := committest.DefaultName + " <" + committest.DefaultEmail + ">"
+	assert.Equal(t, want+"|"+want+"\n", author)
+	assert.Equal(t, "3\n", r.Run("rev-list", "--count", "HEAD"))
+}
+
+func testRepoDates(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	create := func() *committest.Repo {
+		r := committest.NewRepo(t,
+			committest.WithIdentity("someone", "someone@example.com"),
+			committest.WithDates(start, time.Hour),
+		)
+		r.Commit("feat: one", committest.File{Path: "file.txt", Content: "one"})
+		r.AnnotatedTag("v0.1.0", "v0.1.0")
+		r.Commit("fix: two", committest.File{Path: "file.txt", Content: "two"})
+		return r
+	}
+	r1, r2 := create(), create()
+	assert.Equal(t, r1.Head(), r2.Head(), "the SHAs should be deterministic")
+	assert.Equal(t, "someone <someone@example.com>\n", r1.Run("log", "-1", "--format=%an <%ae>"))
+
+	dates := strings.Fields(r1.Run("log", "--format=%at %ct"))
+	want := []string{"1717243200", "1717243200", "1717236000", "1717236000"}
+	assert.Equal(t, want, dates)
+	tagDate := r1.Run("for-each-ref", "--format=%(creatordate:unix)", "refs/tags/v0.1.0")
+	assert.Equal(t, "1717239600\n", tagDate)
+
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	r1.CommitAt("docs: old", date)
+	assert.Equal(t, "1577934245\n", r1.Run("log", "-1", "--format=%ct"))
+}
+
+func testRepoBranch(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t)
+	r.Commit("feat: one")
+	main := r.CurrentBranch()
+	r.Branch("feature")
+	assert.Equal(t, "feature", r.CurrentBranch())
+	sha := r.Commit("feat: two")
+	r.Checkout(main)
+	assert.Equal(t, main, r.CurrentBranch())
+	assert.NotEqual(t, sha, r.Head())
+	r.TagAt("v0.2.0", sha)
+	assert.Equal(t, sha+"\n", r.Run("rev-parse", "v0.2.0"))
+}
+
+func testRepoGit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t)
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("feat: one")
+	r.Tag("v0.1.0")
+	r.Commit("fix: two")
+	r.Tag("v0.1.1")
+
+	g := commit.New(commit.WithDir(r.Dir))
+	tag, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.1", tag)
+	info, err := g.Prepare(ctx, "@")
+	require.NoError(t, err)
+	assert.Equal(t, "user", info.User)
+	assert.Equal(t, "v0.1.0", info.PreviousTag)
+	require.Len(t, info.Logs, 1)
+	assert.Equal(t, "fix: two", strings.TrimSpace(info.Logs[0]))
+}
+
+func TestRepoGenerate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t)
+	r.Commit("chore: initial")
+	r.Generate(10, 4, "v0.%d.0")
+	assert.Equal(t, "11\n", r.Run("rev-list", "--count", "HEAD"))
+	assert.Equal(t, "v0.1.0\nv0.2.0\n", r.Run("tag", "--list"))
+	assert.Equal(t, "fix: change 10\n", r.Run("log", "-1", "--format=%s"))
+
+	g := commit.New(commit.WithDir(r.Dir))
+	tag, err := g.PreviousTag(ctx, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0", tag)
+
+	empty := committest.NewRepo(t)
+	empty.Generate(3, 0, "")
+	assert.Equal(t, "3\n", empty.Run("rev-list", "--count", "HEAD"))
+}
@@ -0,0 +1,66 @@
+package commit
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateSource defines which date is rendered in the headings of the releases.
+type DateSource int
+
+const (
+	// DateTag uses the date the tag was created, which is the tagger date of
+	// the annotated tags. The lightweight tags and the revisions that are not
+	// tags, e.g. the HEAD of an unreleased preview, use the committer date.
+	// This is the default.
+	DateTag DateSource = iota
+	// DateCommit uses the committer date of the commit of the tag, even if
+	// the tag was created later.
+	DateCommit
+	// DateNow uses the time of the rendering.
+	DateNow
+)
+
+// ParseDateSource returns the DateSource for "tag", "commit" or "now".
+func ParseDateSource(s string) (DateSource, error) {
+	switch s {
+	case "tag", "":
+		return DateTag, nil
+	case "commit":
+		return DateCommit, nil
+	case "now":
+		return DateNow, nil
+	}
+	return DateTag, fmt.Errorf("unknown date source %q", s)
+}
+
+// String returns the name of the source.
+func (d DateSource) String() string {
+	switch d {
+	case DateCommit:
+		return "commit"
+	case DateNow:
+		return "now"
+	}
+	return "tag"
+}
+
+// WithDateSource renders the dates of the releases from the source.
+func WithDateSource(src DateSource) RenderOption {
+	return func(o *renderOptions) {
+		o.dateSource = src
+	}
+}
+
+// pick returns the date of the source. The date is the creation date of the
+// tag, or the committer date if the revision is not a tag. A zero commitDate
+// falls back to the date.
+func (d DateSource) pick(date, commitDate time.Time) time.Time {
+	switch {
+	case d == DateNow:
+		return time.Now().UTC()
+	case d == DateCommit && !commitDate.IsZero():
+		return commitDate
+	}
+	return date
+}
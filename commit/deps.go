@@ -0,0 +1,177 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arsham/gitrelease/version"
+)
+
+// DepChange is a change of a required module in go.mod between two revisions.
+// Old is empty for the added modules, and New for the removed ones.
+type DepChange struct {
+	Path     string
+	Old      string
+	New      string
+	Indirect bool
+}
+
+// String describes the change, e.g. "Upgrade `golang.org/x/sync` from v0.1.0
+// to v0.2.0".
+func (d DepChange) String() string {
+	switch {
+	case d.Old == "":
+		return fmt.Sprintf("Add `%s` %s", d.Path, d.New)
+	case d.New == "":
+		return fmt.Sprintf("Remove `%s` %s", d.Path, d.Old)
+	case compareSemVer(d.New, d.Old) < 0:
+		return fmt.Sprintf("Downgrade `%s` from %s to %s", d.Path, d.Old, d.New)
+	}
+	return fmt.Sprintf("Upgrade `%s` from %s to %s", d.Path, d.Old, d.New)
+}
+
+// compareSemVer compares the precedence of the semantic versions a and b, and
+// returns -1, 0 or +1. The build metadata is ignored, and the versions that
+// can't be parsed are compared as strings.
+func compareSemVer(a, b string) int {
+	va, erra := version.Parse(a)
+	vb, errb := version.Parse(b)
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return version.Compare(va, vb)
+}
+
+// requirement is a required module of go.mod.
+type requirement struct {
+	version  string
+	indirect bool
+}
+
+// DependencyChanges returns the changes of the required modules in the go.mod
+// of the Dir between the revisions, sorted by their paths. The go.mod lists
+// all the requirements since Go 1.17, therefore go.sum is not consulted. A
+// go.mod that doesn't exist in a revision has no requirements, and nil is
+// returned if it exists in neither.
+func (g *Git) DependencyChanges(ctx context.Context, rev1, rev2 string) ([]DepChange, error) {
+	old, err := g.goModRequirements(ctx, rev1)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := g.goModRequirements(ctx, rev2)
+	if err != nil {
+		return nil, err
+	}
+	var changes []DepChange
+	for path, r := range cur {
+		o, ok := old[path]
+		switch {
+		case !ok:
+			changes = append(changes, DepChange{Path: path, New: r.version, Indirect: r.indirect})
+		case o.version != r.version:
+			changes = append(changes, DepChange{Path: path, Old: o.version, New: r.version, Indirect: r.indirect})
+		}
+	}
+	for path, o := range old {
+		if _, ok := cur[path]; !ok {
+			changes = append(changes, DepChange{Path: path, Old: o.version, Indirect: o.indirect})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// goModRequirements returns the requirements of the go.mod in the rev, or nil
+// if the file doesn't exist in it.
+func (g *Git) goModRequirements(ctx context.Context, rev string) (map[string]requirement, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	// The "./" makes the path relative to the Dir.
+	out, err := g.run(ctx, newCommand("show").revFrom(rev+":./go.mod", rev))
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && (strings.Contains(gitErr.Stderr, "does not exist") ||
+		strings.Contains(gitErr.Stderr, "but not in")) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod of %s: %w", rev, err)
+	}
+	return parseGoMod(out), nil
+}
+
+// parseGoMod returns the requirements in the require directives of the go.mod
+// file.
+func parseGoMod(data string) map[string]requirement {
+	reqs := make(map[string]requirement)
+	inBlock := false
+	for _, line := range splitLines(data) {
+		code, comment, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		switch {
+		case inBlock && len(fields) == 1 && fields[0] == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+			continue
+		case len(fields) > 0 && fields[0] == "require":
+			fields = fields[1:]
+		default:
+			continue
+		}
+		if len(fields) != 2 {
+			continue
+		}
+		path := strings.Trim(fields[0], "\"`")
+		reqs[path] = requirement{
+			version:  fields[1],
+			indirect: strings.TrimSpace(comment) == "indirect",
+		}
+	}
+	return reqs
+}
+
+// RenderDependencies renders the changes as the items of a "Dependencies"
+// section. The indirect dependencies are only included if indirect is true.
+// It returns an empty string if there is nothing to render.
+func RenderDependencies(changes []DepChange, indirect bool) string {
+	buf := &strings.Builder{}
+	for _, c := range changes {
+		if c.Indirect && !indirect {
+			continue
+		}
+		if buf.Len() == 0 {
+			buf.WriteString("### Dependencies\n\n")
+		}
+		line := ItemPrefix + c.String()
+		if c.Indirect {
+			line += " (indirect)"
+		}
+		buf.WriteString(line + "\n")
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// isDepCommit returns true if the title is of a commit that bumps a dependency,
+// e.g. "chore(deps): bump x from 1 to 2" or "deps: upgrade x".
+func isDepCommit(title string) bool {
+	m := descRe.FindStringSubmatch(title)
+	if m == nil {
+		return false
+	}
+	verb := strings.ToLower(strings.TrimSuffix(m[1], "!"))
+	if verb == "deps" || verb == "dep" {
+		return true
+	}
+	for _, scope := range strings.Split(strings.ToLower(m[2]), ",") {
+		if scope == "deps" || scope == "deps-dev" || scope == "dep" {
+			return true
+		}
+	}
+	return false
+}
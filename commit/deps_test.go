@@ -0,0 +1,218 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goModV1 = `module github.com/arsham/app
+
+go 1.18
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/spf13/cobra v1.4.0
+	github.com/stretchr/testify v1.7.1 // a comment
+	golang.org/x/sync v0.1.0
+	github.com/old/gone v1.0.0
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
+
+replace github.com/spf13/cobra => ../cobra
+`
+
+const goModV2 = `module github.com/arsham/app
+
+go 1.18
+
+require (
+	github.com/spf13/cobra v1.5.0
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/sync v0.1.0
+	"github.com/new/thing" v0.0.0-20220101000000-abcdef123456
+	github.com/pmezard/go-difflib v1.0.1-0.20220101000000-abcdef123456 // indirect
+)
+`
+
+func TestGitDependencyChanges(t *testing.T) {
+	t.Parallel()
+	t.Run("Changes", testGitDependencyChangesChanges)
+	t.Run("NoGoMod", testGitDependencyChangesNoGoMod)
+	t.Run("Added", testGitDependencyChangesAdded)
+	t.Run("SubModule", testGitDependencyChangesSubModule)
+}
+
+func testGitDependencyChangesChanges(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "go.mod", goModV1)
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "go.mod", goModV2)
+	commitChanges(t, dir, "chore(deps): bump cobra from 1.4.0 to 1.5.0")
+	createGitTag(t, dir, "v1.1.0")
+
+	g := commit.Git{Dir: dir}
+	got, err := g.DependencyChanges(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	want := []commit.DepChange{
+		{Path: "github.com/new/thing", New: "v0.0.0-20220101000000-abcdef123456"},
+		{Path: "github.com/old/gone", Old: "v1.0.0"},
+		{Path: "github.com/pkg/errors", Old: "v0.9.1"},
+		{Path: "github.com/pmezard/go-difflib", Old: "v1.0.0", New: "v1.0.1-0.20220101000000-abcdef123456", Indirect: true},
+		{Path: "github.com/spf13/cobra", Old: "v1.4.0", New: "v1.5.0"},
+		{Path: "github.com/stretchr/testify", Old: "v1.7.1", New: "v1.7.0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got, err = g.DependencyChanges(ctx, "v1.1.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = g.DependencyChanges(ctx, "v0.0.1", "v1.1.0")
+	var gitErr *commit.GitError
+	assert.ErrorAs(t, err, &gitErr)
+}
+
+func testGitDependencyChangesNoGoMod(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "a")
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "file.txt", "b")
+	commitChanges(t, dir, "fix: thing")
+	createGitTag(t, dir, "v1.1.0")
+
+	g := commit.Git{Dir: dir}
+	got, err := g.DependencyChanges(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func testGitDependencyChangesAdded(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "a")
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "go.mod", "module a\n\nrequire golang.org/x/sync v0.1.0\n")
+	commitChanges(t, dir, "feat: go")
+	createGitTag(t, dir, "v1.1.0")
+
+	g := commit.Git{Dir: dir}
+	got, err := g.DependencyChanges(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []commit.DepChange{{Path: "golang.org/x/sync", New: "v0.1.0"}}, got)
+
+	// There is no previous tag on the first release.
+	got, err = g.DependencyChanges(ctx, "", "v1.1.0")
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func testGitDependencyChangesSubModule(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	createFile(t, dir, "go.mod", "module a\n\nrequire golang.org/x/sync v0.1.0\n")
+	createFile(t, sub, "go.mod", "module a/sub\n\nrequire golang.org/x/exp v0.1.0\n")
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, sub, "go.mod", "module a/sub\n\nrequire golang.org/x/exp v0.2.0\n")
+	commitChanges(t, dir, "chore: bump")
+	createGitTag(t, dir, "v1.1.0")
+
+	g := commit.Git{Dir: sub}
+	got, err := g.DependencyChanges(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []commit.DepChange{{Path: "golang.org/x/exp", Old: "v0.1.0", New: "v0.2.0"}}, got)
+}
+
+func TestDepChangeString(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		change commit.DepChange
+		want   string
+	}{
+		"added":     {commit.DepChange{Path: "a", New: "v1.0.0"}, "Add `a` v1.0.0"},
+		"removed":   {commit.DepChange{Path: "a", Old: "v1.0.0"}, "Remove `a` v1.0.0"},
+		"upgraded":  {commit.DepChange{Path: "a", Old: "v1.9.0", New: "v1.10.0"}, "Upgrade `a` from v1.9.0 to v1.10.0"},
+		"downgrade": {commit.DepChange{Path: "a", Old: "v1.10.0", New: "v1.9.0"}, "Downgrade `a` from v1.10.0 to v1.9.0"},
+		"release":   {commit.DepChange{Path: "a", Old: "v1.0.0-rc.1", New: "v1.0.0"}, "Upgrade `a` from v1.0.0-rc.1 to v1.0.0"},
+		"pre-release": {
+			commit.DepChange{Path: "a", Old: "v1.0.0-rc.10", New: "v1.0.0-rc.9"},
+			"Downgrade `a` from v1.0.0-rc.10 to v1.0.0-rc.9",
+		},
+		"pseudo": {
+			commit.DepChange{Path: "a", Old: "v0.0.0-20220101000000-aaaaaaaaaaaa", New: "v0.0.0-20230101000000-bbbbbbbbbbbb"},
+			"Upgrade `a` from v0.0.0-20220101000000-aaaaaaaaaaaa to v0.0.0-20230101000000-bbbbbbbbbbbb",
+		},
+		"incompatible": {
+			commit.DepChange{Path: "a", Old: "v2.0.0+incompatible", New: "v3.0.0+incompatible"},
+			"Upgrade `a` from v2.0.0+incompatible to v3.0.0+incompatible",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.change.String())
+		})
+	}
+}
+
+func TestRenderDependencies(t *testing.T) {
+	t.Parallel()
+	changes := []commit.DepChange{
+		{Path: "a", Old: "v1.0.0", New: "v1.1.0"},
+		{Path: "b", Old: "v1.0.0", New: "v1.1.0", Indirect: true},
+	}
+	want := "### Dependencies\n\n- Upgrade `a` from v1.0.0 to v1.1.0"
+	assert.Equal(t, want, commit.RenderDependencies(changes, false))
+	want += "\n- Upgrade `b` from v1.0.0 to v1.1.0 (indirect)"
+	assert.Equal(t, want, commit.RenderDependencies(changes, true))
+	assert.Empty(t, commit.RenderDependencies(changes[1:], false))
+	assert.Empty(t, commit.RenderDependencies(nil, true))
+}
+
+func TestParseGroupsWithDependencies(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"chore(deps): bump cobra from 1.4.0 to 1.5.0",
+		"build(deps-dev): bump testify",
+		"deps: upgrade sync",
+		"fix: thing",
+	}
+	section := "### Dependencies\n\n- Upgrade `a` from v1.0.0 to v1.1.0"
+
+	got := commit.ParseGroups(logs, commit.WithDependencies(section, true))
+	want := "### Fix\n\n- Thing\n\n" + section
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups(logs, commit.WithDependencies(section, false))
+	assert.Contains(t, got, "Bump cobra from 1.4.0 to 1.5.0")
+	assert.True(t, strings.HasSuffix(got, section))
+
+	// The commits are kept when there is no section.
+	got = commit.ParseGroups(logs, commit.WithDependencies("", true))
+	assert.Contains(t, got, "Bump cobra from 1.4.0 to 1.5.0")
+}
@@ -0,0 +1,143 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes.
+const diffContext = 3
+
+// NormalizeNotes removes the differences of release notes that don't show up
+// when rendered: CRLF line endings, trailing whitespace, and trailing empty
+// lines.
+func NormalizeNotes(s string) string {
+	lines := splitLines(s)
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// DiffNotes returns the unified diff of the normalised notes, with oldName and
+// newName as the file names in the header. It returns an empty string if they
+// are the same.
+func DiffNotes(oldName, newName, old, new string) string {
+	old, new = NormalizeNotes(old), NormalizeNotes(new)
+	if old == new {
+		return ""
+	}
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+	ops := diffLines(a, b)
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change, and the end of its hunk where there are more
+		// than twice the context of unchanged lines.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end, equal := start, 0
+		for end < len(ops) && equal <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+			end++
+		}
+		end -= equal
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		to := end + diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+		writeHunk(buf, ops[from:to])
+		start = to
+	}
+	return buf.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// aLine and bLine are the line numbers, starting from one, in the old and
+	// new texts before this op.
+	aLine, bLine int
+}
+
+func writeHunk(buf *strings.Builder, ops []diffOp) {
+	var aCount, bCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(ops[0].aLine, aCount), hunkRange(ops[0].bLine, bCount))
+	for _, op := range ops {
+		buf.WriteByte(op.kind)
+		buf.WriteString(op.line)
+		buf.WriteByte('\n')
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffLines returns the operations to turn a into b, using their longest
+// common subsequence. Release notes are short enough for the quadratic cost.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		op := diffOp{aLine: i + 1, bLine: j + 1}
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			op.kind, op.line = ' ', a[i]
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			op.kind, op.line = '-', a[i]
+			i++
+		default:
+			op.kind, op.line = '+', b[j]
+			j++
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
@@ -0,0 +1,55 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeNotes(t *testing.T) {
+	t.Parallel()
+	got := commit.NormalizeNotes("### Fix  \r\n\r\n- Fix thing\t\r\n\r\n\r\n")
+	assert.Equal(t, "### Fix\n\n- Fix thing", got)
+}
+
+func TestDiffNotes(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		old  string
+		new  string
+		want string
+	}{
+		"same": {
+			old: "### Fix\r\n\r\n- Fix thing  \r\n",
+			new: "### Fix\n\n- Fix thing",
+		},
+		"changed": {
+			old: "### Fix\n\n- Fix thing",
+			new: "### Fix\n\n- Fix thing\n- Fix other thing",
+			want: "--- published\n+++ generated\n" +
+				"@@ -1,3 +1,4 @@\n" +
+				" ### Fix\n \n - Fix thing\n+- Fix other thing\n",
+		},
+		"empty": {
+			old:  "",
+			new:  "- Fix thing",
+			want: "--- published\n+++ generated\n@@ -1 +1 @@\n-\n+- Fix thing\n",
+		},
+		"hunks": {
+			old: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj",
+			new: "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ",
+			want: "--- published\n+++ generated\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n" +
+				"@@ -7,4 +7,4 @@\n g\n h\n i\n-j\n+J\n",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.DiffNotes("published", "generated", tc.old, tc.new)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
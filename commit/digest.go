@@ -0,0 +1,95 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoCommitsSince is returned by Clone when the repository has no commits
+// since the date.
+var ErrNoCommitsSince = errors.New("no commits since the date")
+
+// noChangesHeading is the heading of the repositories without changes in a
+// digest, which is translated by the locales like the others.
+const noChangesHeading = "No changes"
+
+// Clone clones the default branch of the repository of the url into the dir,
+// without checking out the files. If since is not zero the clone is shallow,
+// and only has the commits since then and their tags. Otherwise the depth of
+// the latest tag is not known, therefore it has all the commits of the branch
+// and their tags, but none of the files, which are fetched when they are
+// read. Unlike a treeless clone, it has the trees, which would be fetched
+// commit by commit by a log of the paths. The process runs in the Dir of the
+// Git.
+func (g *Git) Clone(ctx context.Context, url, dir string, since time.Time) error {
+	c := newCommand("clone", "--quiet", "--no-checkout", "--single-branch")
+	if since.IsZero() {
+		c = c.opt("--filter=blob:none")
+	} else {
+		c = c.opt("--shallow-since=" + since.UTC().Format(time.RFC3339))
+	}
+	_, err := g.run(ctx, c.path(url, dir))
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "no commits selected for shallow requests") {
+		return fmt.Errorf("cloning %s: %w", url, ErrNoCommitsSince)
+	}
+	if err != nil {
+		return fmt.Errorf("cloning %s: %w", url, err)
+	}
+	return nil
+}
+
+// Unreleased returns the latest tag and the messages of the commits since
+// then, up to the HEAD. If there are no tags, the tag is empty and all the
+// commits are returned.
+func (g *Git) Unreleased(ctx context.Context) (string, []string, error) {
+	tag, err := g.LatestTag(ctx)
+	if err != nil && !errors.Is(err, ErrNoTag) {
+		return "", nil, err
+	}
+	logs, err := g.Commits(ctx, tag, "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+	return tag, logs, nil
+}
+
+// DigestSection is the changes of one of the repositories of a digest.
+type DigestSection struct {
+	// Name is the heading of the section, e.g. the owner/repo of the
+	// repository.
+	Name string
+	// Since is what the changes are since, e.g. the latest tag or a date.
+	// It's empty if the repository has no tags.
+	Since string
+	Logs  []string
+}
+
+// RenderDigest renders the sections as one document with a heading for each
+// repository, in the order of the sections. The repositories without changes
+// are listed together under "No changes" at the end.
+func RenderDigest(sections []DigestSection, opts ...RenderOption) string {
+	o := newRenderOptions(opts)
+	var (
+		parts     = make([]string, 0, len(sections)+1)
+		unchanged []string
+	)
+	for _, s := range sections {
+		if len(s.Logs) == 0 {
+			unchanged = append(unchanged, ItemPrefix+s.Name)
+			continue
+		}
+		body := ParseGroups(s.Logs, opts...)
+		if s.Since != "" {
+			body = fmt.Sprintf("_Since %s_\n\n%s", s.Since, body)
+		}
+		parts = append(parts, fmt.Sprintf("## %s\n\n%s", s.Name, body))
+	}
+	if len(unchanged) > 0 {
+		parts = append(parts, fmt.Sprintf("## %s\n\n%s", o.locale.Heading(noChangesHeading), strings.Join(unchanged, "\n")))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,134 @@
+package commit_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitClone(t *testing.T) {
+	t.Parallel()
+	t.Run("Tags", testGitCloneTags)
+	t.Run("Since", testGitCloneSince)
+	t.Run("NoCommitsSince", testGitCloneNoCommitsSince)
+	t.Run("Failure", testGitCloneFailure)
+}
+
+// digestRepo returns a repository with the commits dated a day apart since
+// 2024-06-01, and v0.1.0 tagged on the second one.
+func digestRepo(t *testing.T) *committest.Repo {
+	t.Helper()
+	r := committest.NewRepo(t, identity, committest.WithDates(day(1), 24*time.Hour))
+	r.Commit("chore: initial")
+	r.Commit("feat: one")
+	r.AnnotatedTag("v0.1.0", "v0.1.0")
+	r.Commit("fix: two")
+	r.Commit("feat: three")
+	return r
+}
+
+func testGitCloneTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := digestRepo(t)
+	r.Run("config", "uploadpack.allowFilter", "true")
+	dir := filepath.Join(t.TempDir(), "clone")
+	require.NoError(t, commit.New().Clone(ctx, "file://"+r.Dir, dir, time.Time{}))
+
+	// The clone has the history without the files.
+	clone := committest.Open(t, dir)
+	assert.Equal(t, "blob:none\n", clone.Run("config", "remote.origin.partialclonefilter"))
+	assert.Equal(t, "false\n", clone.Run("rev-parse", "--is-shallow-repository"))
+
+	tag, logs, err := commit.New(commit.WithDir(dir)).Unreleased(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", tag)
+	assert.Len(t, logs, 2)
+}
+
+func testGitCloneSince(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := digestRepo(t)
+	dir := filepath.Join(t.TempDir(), "clone")
+	require.NoError(t, commit.New().Clone(ctx, "file://"+r.Dir, dir, day(3)))
+
+	g := commit.New(commit.WithDir(dir))
+	assert.Equal(t, "true\n", committest.Open(t, dir).Run("rev-parse", "--is-shallow-repository"))
+	commits, err := g.CommitsSince(ctx, day(3), nil)
+	require.NoError(t, err)
+	assert.Len(t, commits, 2)
+}
+
+func testGitCloneNoCommitsSince(t *testing.T) {
+	t.Parallel()
+	r := digestRepo(t)
+	dir := filepath.Join(t.TempDir(), "clone")
+	err := commit.New().Clone(context.Background(), "file://"+r.Dir, dir, day(20))
+	assert.ErrorIs(t, err, commit.ErrNoCommitsSince)
+}
+
+func testGitCloneFailure(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "clone")
+	err := commit.New().Clone(context.Background(), "file://"+filepath.Join(t.TempDir(), "nope"), dir, time.Time{})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, commit.ErrNoCommitsSince)
+}
+
+func TestGitUnreleasedNoTags(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.Commit("feat: one")
+	r.Commit("fix: two")
+	tag, logs, err := commit.New(commit.WithDir(r.Dir)).Unreleased(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tag)
+	assert.Len(t, logs, 2)
+}
+
+func TestRenderDigest(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		sections []commit.DigestSection
+		opts     []commit.RenderOption
+		want     string
+	}{
+		"changes": {
+			sections: []commit.DigestSection{
+				{Name: "user/api", Since: "v1.2.0", Logs: []string{"feat: add the endpoint"}},
+				{Name: "user/web", Logs: []string{"fix: the button"}},
+			},
+			want: "## user/api\n\n_Since v1.2.0_\n\n### Feature\n\n- Add the endpoint\n\n" +
+				"## user/web\n\n### Fix\n\n- The button",
+		},
+		"no changes": {
+			sections: []commit.DigestSection{
+				{Name: "user/cli", Since: "v0.3.0"},
+				{Name: "user/api", Since: "v1.2.0", Logs: []string{"feat: add the endpoint"}},
+				{Name: "user/web"},
+			},
+			want: "## user/api\n\n_Since v1.2.0_\n\n### Feature\n\n- Add the endpoint\n\n" +
+				"## No changes\n\n- user/cli\n- user/web",
+		},
+		"locale": {
+			sections: []commit.DigestSection{{Name: "user/cli"}},
+			opts:     []commit.RenderOption{commit.WithLocale(commit.Locale{Headings: map[string]string{"no changes": "Keine Änderungen"}})},
+			want:     "## Keine Änderungen\n\n- user/cli",
+		},
+		"empty": {want: ""},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.RenderDigest(tc.sections, tc.opts...))
+		})
+	}
+}
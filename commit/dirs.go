@@ -0,0 +1,124 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMultipleAreas is the group of the commits that change more than one
+// directory, if the Multiple of the DirClassifier is empty.
+const DefaultMultipleAreas = "Multiple areas"
+
+// ChangedPaths sets the Paths of the commits in place. They are read with one
+// git log process, which reads the commits from its standard input and only
+// lists the changes in the Paths of the Git. The merge commits have no paths.
+func (g *Git) ChangedPaths(ctx context.Context, commits []Commit) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	c := newCommand(
+		"log", "--no-walk=unsorted", "--name-only", "--no-renames", "--no-ext-diff",
+		"--pretty="+commitSeparator+"%H",
+	)
+	out, err := g.runRevs(ctx, c.rev(commitSHAs(commits)...).path(g.Paths...))
+	if err != nil {
+		return fmt.Errorf("reading the changed files of the commits: %w", err)
+	}
+	paths := make(map[string][]string, len(commits))
+	for _, part := range strings.Split(out, commitSeparator)[1:] {
+		lines := splitLines(part)
+		if len(lines) == 0 {
+			continue
+		}
+		var files []string
+		for _, line := range lines[1:] {
+			line = strings.TrimSpace(line)
+			// The unusual names are quoted with the escapes of C.
+			if unquoted, err := strconv.Unquote(line); err == nil && strings.HasPrefix(line, `"`) {
+				line = unquoted
+			}
+			if line != "" {
+				files = append(files, line)
+			}
+		}
+		paths[strings.TrimSpace(lines[0])] = files
+	}
+	for i, c := range commits {
+		if files, ok := paths[c.SHA]; ok {
+			commits[i].Paths = files
+		}
+	}
+	return nil
+}
+
+// DirClassifier classifies the commits by the directories of the files they
+// change, e.g. the commits that only change the files in api/ are in the
+// "api/" group. It is a fallback for the repositories without conventional
+// commits or labels, e.g. after the Conventional classifier. The Paths of the
+// commits are set by ChangedPaths, and passed to ParseGroups with WithPaths.
+// The files at the root of the repository are not in any directory, therefore
+// the commits that only change them are not classified.
+type DirClassifier struct {
+	// Depth is the number of the leading directories of the groups, e.g.
+	// "services/auth/" with 2. It defaults to 1.
+	Depth int
+	// Multiple is the group of the commits that change more than one
+	// directory. It defaults to DefaultMultipleAreas.
+	Multiple string
+}
+
+// Classify returns the directory of the files of the commit, or the Multiple
+// group if they are in more than one.
+func (d DirClassifier) Classify(c Commit) (string, bool) {
+	dirs := d.Dirs(c.Paths)
+	switch len(dirs) {
+	case 0:
+		return "", false
+	case 1:
+		return dirs[0], true
+	}
+	if d.Multiple == "" {
+		return DefaultMultipleAreas, true
+	}
+	return d.Multiple, true
+}
+
+// Dirs returns the sorted directories of the paths up to the Depth, with a
+// trailing slash, e.g. "api/".
+func (d DirClassifier) Dirs(paths []string) []string {
+	depth := d.Depth
+	if depth < 1 {
+		depth = 1
+	}
+	seen := make(map[string]bool, len(paths))
+	var dirs []string
+	for _, p := range paths {
+		parts := strings.Split(path.Dir(strings.TrimPrefix(p, "/")), "/")
+		if parts[0] == "." {
+			continue
+		}
+		if len(parts) > depth {
+			parts = parts[:depth]
+		}
+		dir := strings.Join(parts, "/") + "/"
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// WithPaths gives the changed files of the commits to the classifier of
+// WithClassifier, e.g. a DirClassifier. The paths are in the order of the logs
+// of ParseGroups, e.g. the Paths of the commits of the logs.
+func WithPaths(paths [][]string) RenderOption {
+	return func(o *renderOptions) {
+		o.paths = paths
+	}
+}
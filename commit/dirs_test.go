@@ -0,0 +1,116 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirClassifier(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		depth    int
+		multiple string
+		paths    []string
+		group    string
+		ok       bool
+	}{
+		"one dir":         {paths: []string{"api/server.go", "api/v1/routes.go"}, group: "api/", ok: true},
+		"multiple":        {paths: []string{"api/server.go", "web/index.html"}, group: commit.DefaultMultipleAreas, ok: true},
+		"custom multiple": {multiple: "Cross-cutting", paths: []string{"api/a.go", "docs/a.md"}, group: "Cross-cutting", ok: true},
+		"root ignored":    {paths: []string{"go.mod", "docs/guide.md"}, group: "docs/", ok: true},
+		"only root":       {paths: []string{"go.mod", "README.md"}},
+		"no paths":        {},
+		"depth":           {depth: 2, paths: []string{"services/auth/main.go", "services/auth/db/x.go"}, group: "services/auth/", ok: true},
+		"depth multiple":  {depth: 2, paths: []string{"services/auth/main.go", "services/billing/main.go"}, group: commit.DefaultMultipleAreas, ok: true},
+		"shallower file":  {depth: 2, paths: []string{"services/README.md"}, group: "services/", ok: true},
+		"depth one":       {paths: []string{"services/auth/main.go", "services/billing/main.go"}, group: "services/", ok: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			d := commit.DirClassifier{Depth: tc.depth, Multiple: tc.multiple}
+			group, ok := d.Classify(commit.Commit{Message: "update things", Paths: tc.paths})
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+}
+
+func TestGitChangedPaths(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	first := r.Commit("initial", committest.File{Path: "go.mod", Content: "module x\n"})
+	second := r.Commit("the api",
+		committest.File{Path: "api/server.go", Content: "package api\n"},
+		committest.File{Path: "web/ünïcode.html", Content: "<p>\n"},
+	)
+	third := r.Commit("the docs", committest.File{Path: "docs/guide.md", Content: "# Guide\n"})
+
+	commits := []commit.Commit{{SHA: third}, {SHA: first}, {SHA: second}}
+	g := &commit.Git{Dir: r.Dir}
+	require.NoError(t, g.ChangedPaths(ctx, commits))
+	assert.Equal(t, []string{"docs/guide.md"}, commits[0].Paths)
+	assert.Equal(t, []string{"go.mod"}, commits[1].Paths)
+	assert.Equal(t, []string{"api/server.go", "web/ünïcode.html"}, commits[2].Paths)
+
+	commits = []commit.Commit{{SHA: second}, {SHA: third}}
+	g = &commit.Git{Dir: r.Dir, Paths: []string{"api"}}
+	require.NoError(t, g.ChangedPaths(ctx, commits))
+	assert.Equal(t, []string{"api/server.go"}, commits[0].Paths)
+	assert.Empty(t, commits[1].Paths)
+
+	assert.NoError(t, g.ChangedPaths(ctx, nil))
+
+	trace := &commit.Trace{Environ: func() []string { return nil }}
+	g = &commit.Git{Dir: r.Dir, Trace: trace}
+	require.NoError(t, g.ChangedPaths(ctx, []commit.Commit{{SHA: second}}))
+	args := trace.Entries()[0].Args
+	assert.Contains(t, args, "--stdin")
+	assert.NotContains(t, args, second)
+}
+
+func TestParseGroupsWithPaths(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the login page",
+		"Speed up the handlers",
+		"Correct the typos",
+		"Bump everything",
+		"Update the licence",
+	}
+	paths := [][]string{
+		{"web/login.html"},
+		{"api/server.go"},
+		{"docs/guide.md", "docs/api.md"},
+		{"api/go.mod", "web/package.json"},
+		{"LICENSE"},
+	}
+	cls := commit.Classifiers{commit.Conventional{}, commit.DirClassifier{}}
+	got := commit.ParseGroups(logs,
+		commit.WithClassifier(cls),
+		commit.WithPaths(paths),
+	)
+	// The headings have their first letters upper cased like the others.
+	want := "### Feature\n\n" +
+		"- The login page\n\n\n" +
+		"### Misc\n\n" +
+		"- Update the licence\n\n\n" +
+		"### Multiple areas\n\n" +
+		"- Bump everything\n\n\n" +
+		"### Api/\n\n" +
+		"- Speed up the handlers\n\n\n" +
+		"### Docs/\n\n" +
+		"- Correct the typos"
+	assert.Equal(t, want, got)
+
+	// Without the paths, the classifier can't classify them.
+	got = commit.ParseGroups(logs, commit.WithClassifier(cls))
+	assert.NotContains(t, got, "Api/")
+}
@@ -0,0 +1,132 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrNotVerified is returned when a draft release doesn't pass the
+// verification, and therefore is not published.
+var ErrNotVerified = errors.New("release is not verified")
+
+// DraftRelease creates a draft release of the tag with the desc as its notes.
+// If a draft of the tag is left from a previous run, its notes are updated
+// and it is returned instead. The release is a pre-release if the Channel is
+// one. It returns ErrReleaseExists if the tag is already released.
+func (g *Git) DraftRelease(ctx context.Context, token, user, repo, tag, desc string) (*ReleaseDetails, error) {
+	_, err := g.GetReleaseByTag(ctx, token, user, repo, tag)
+	switch {
+	case err == nil:
+		return nil, ErrReleaseExists
+	case !hasStatus(err, http.StatusNotFound):
+		return nil, err
+	}
+
+	// A draft left from a previous run is among the latest releases.
+	releases, err := g.ListReleases(ctx, token, user, repo, ListOptions{
+		Limit:         listPageSize,
+		IncludeDrafts: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		r := &releases[i]
+		if !r.Draft || r.TagName != tag {
+			continue
+		}
+		g.debugf("reusing the draft release %d of %s", r.ID, tag)
+		if err := g.UpdateReleaseBody(ctx, token, user, repo, r.ID, desc); err != nil {
+			return nil, err
+		}
+		r.Body = desc
+		return r, nil
+	}
+
+	params := releaseCreate{
+		TagName:         tag,
+		TargetCommitish: g.releaseTarget(ctx, tag),
+		Body:            desc,
+		Draft:           true,
+		Prerelease:      g.prerelease(),
+	}
+	r := &ReleaseDetails{}
+	uri := fmt.Sprintf("/repos/%s/%s/releases", user, repo)
+	if err := g.api(ctx, token, http.MethodPost, uri, params, r); err != nil {
+		return nil, fmt.Errorf("creating draft release: %w", err)
+	}
+	return r, nil
+}
+
+// GetRelease returns the release with the id. Unlike GetReleaseByTag, it
+// returns the drafts too.
+func (g *Git) GetRelease(ctx context.Context, token, user, repo string, id int64) (*ReleaseDetails, error) {
+	r := &ReleaseDetails{}
+	uri := fmt.Sprintf("/repos/%s/%s/releases/%d", user, repo, id)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, r); err != nil {
+		return nil, fmt.Errorf("getting release %d: %w", id, err)
+	}
+	return r, nil
+}
+
+// VerifyRelease checks the release with the id has notes, and exactly the
+// assets, all completely uploaded. The assets are compared by their sha256
+// sum in the release's checksums.txt manifest if there is one, otherwise by
+// their size. The returned error wraps ErrNotVerified and lists all problems.
+func (g *Git) VerifyRelease(ctx context.Context, token, user, repo string, id int64, assets []Asset) (*ReleaseDetails, error) {
+	r, err := g.GetRelease(ctx, token, user, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	if strings.TrimSpace(r.Body) == "" {
+		problems = append(problems, "the notes are empty")
+	}
+	if len(r.Assets) != len(assets) {
+		problems = append(problems, fmt.Sprintf("has %d asset(s), want %d", len(r.Assets), len(assets)))
+	}
+	uploaded := make(map[string]ReleaseAsset, len(r.Assets))
+	for _, a := range r.Assets {
+		uploaded[a.Name] = a
+	}
+	sums, err := g.remoteChecksums(ctx, token, user, repo, r.Assets)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, a := range assets {
+		old, ok := uploaded[a.Name]
+		switch {
+		case !ok:
+			problems = append(problems, a.Name+" is missing")
+			continue
+		case old.State != "" && old.State != "uploaded":
+			problems = append(problems, fmt.Sprintf("%s is %s", a.Name, old.State))
+			continue
+		}
+		same, err := sameAsset(a, old, sums)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", a.Name, err))
+		case !same:
+			problems = append(problems, fmt.Sprintf("%s doesn't match %s", a.Name, a.Path))
+		}
+	}
+	if len(problems) > 0 {
+		return r, fmt.Errorf("%w:\n%s", ErrNotVerified, strings.Join(problems, "\n"))
+	}
+	return r, nil
+}
+
+// PublishDraft publishes the draft release with the id.
+func (g *Git) PublishDraft(ctx context.Context, token, user, repo string, id int64) (*ReleaseDetails, error) {
+	r := &ReleaseDetails{}
+	uri := fmt.Sprintf("/repos/%s/%s/releases/%d", user, repo, id)
+	payload := map[string]bool{"draft": false}
+	if err := g.api(ctx, token, http.MethodPatch, uri, payload, r); err != nil {
+		return nil, fmt.Errorf("publishing release: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,156 @@
+package commit_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeDraft returns a fakeGitHub with a draft release of the tag.
+func newFakeDraft(t *testing.T, tag string) *fakeGitHub {
+	t.Helper()
+	gh := newFakeGitHub(t, tag)
+	gh.releaseDeleted = true
+	gh.release.Draft = true
+	gh.release.Body = "old notes"
+	gh.releases = []commit.ReleaseDetails{
+		{ID: 5, TagName: "v0.9.0"},
+		gh.release,
+	}
+	return gh
+}
+
+func TestGitDraftRelease(t *testing.T) {
+	t.Parallel()
+	t.Run("Create", testGitDraftReleaseCreate)
+	t.Run("Target", testGitDraftReleaseTarget)
+	t.Run("Exists", testGitDraftReleaseExists)
+	t.Run("Reuse", testGitDraftReleaseReuse)
+}
+
+func testGitDraftReleaseCreate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	r, err := g.DraftRelease(ctx, "token", "user", "repo", "v1.1.0", "notes")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, r.ID)
+	assert.True(t, r.Draft)
+	assert.Equal(t, "notes", r.Body)
+}
+
+func testGitDraftReleaseTarget(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir := createGitRepo(t)
+	r := repo(t, dir)
+	sha := r.Commit("feat: thing")
+	r.AnnotatedTag("v1.1.0", "the release")
+	g := &commit.Git{Dir: dir, BaseURL: gh.URL}
+
+	rel, err := g.DraftRelease(ctx, "token", "user", "repo", "v1.1.0", "notes")
+	require.NoError(t, err)
+	assert.Equal(t, sha, rel.TargetCommitish, "the tag should be peeled to its commit")
+
+	rel, err = g.DraftRelease(ctx, "token", "user", "repo", "v1.2.0", "notes")
+	require.NoError(t, err)
+	assert.Empty(t, rel.TargetCommitish, "the missing tag is left to GitHub")
+}
+
+func testGitDraftReleaseExists(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	_, err := g.DraftRelease(ctx, "token", "user", "repo", "v1.0.0", "notes")
+	assert.ErrorIs(t, err, commit.ErrReleaseExists)
+}
+
+func testGitDraftReleaseReuse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeDraft(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	r, err := g.DraftRelease(ctx, "token", "user", "repo", "v1.0.0", "new notes")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, r.ID)
+	assert.Equal(t, "new notes", r.Body)
+	assert.Equal(t, "new notes", gh.release.Body)
+}
+
+func TestGitVerifyRelease(t *testing.T) {
+	t.Parallel()
+	t.Run("Publish", testGitVerifyReleasePublish)
+	t.Run("Problems", testGitVerifyReleaseProblems)
+}
+
+func testGitVerifyReleasePublish(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeDraft(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+	dir := t.TempDir()
+	createFile(t, dir, "app.tar.gz", "app")
+	sum := sha256.Sum256([]byte("app"))
+	createFile(t, dir, "checksums.txt", hex.EncodeToString(sum[:])+"  app.tar.gz\n")
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "app.tar.gz"), Name: "app.tar.gz"},
+		{Path: filepath.Join(dir, "checksums.txt"), Name: "checksums.txt"},
+	}
+
+	r, err := g.DraftRelease(ctx, "token", "user", "repo", "v1.0.0", "notes")
+	require.NoError(t, err)
+	_, err = g.UploadReleaseAssets(ctx, "token", "user", "repo", r, assets, false)
+	require.NoError(t, err)
+	_, err = g.VerifyRelease(ctx, "token", "user", "repo", r.ID, assets)
+	require.NoError(t, err)
+	assert.True(t, gh.release.Draft)
+
+	r, err = g.PublishDraft(ctx, "token", "user", "repo", r.ID)
+	require.NoError(t, err)
+	assert.False(t, r.Draft)
+	assert.False(t, gh.release.Draft)
+}
+
+func testGitVerifyReleaseProblems(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeDraft(t, "v1.0.0")
+	gh.release.Body = " \n"
+	g := &commit.Git{BaseURL: gh.URL}
+	dir := t.TempDir()
+	createFile(t, dir, "app.tar.gz", "app")
+	createFile(t, dir, "app.zip", "zip")
+	createFile(t, dir, "app.deb", "deb")
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "app.tar.gz"), Name: "app.tar.gz"},
+		{Path: filepath.Join(dir, "app.zip"), Name: "app.zip"},
+		{Path: filepath.Join(dir, "app.deb"), Name: "app.deb"},
+	}
+	gh.addAsset("app.tar.gz", "partial")
+	gh.addAsset("app.deb", "deb")
+	gh.release.Assets[1].State = "starter"
+
+	_, err := g.VerifyRelease(ctx, "token", "user", "repo", 1, assets)
+	require.ErrorIs(t, err, commit.ErrNotVerified)
+	for _, want := range []string{
+		"the notes are empty",
+		"has 2 asset(s), want 3",
+		"app.tar.gz doesn't match",
+		"app.zip is missing",
+		"app.deb is starter",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
@@ -0,0 +1,295 @@
+package commit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrNoStartTLS is returned when the SMTP server doesn't support STARTTLS.
+var ErrNoStartTLS = errors.New("server doesn't support STARTTLS")
+
+// DefaultEmailSubject is the template of the subject of the emails.
+const DefaultEmailSubject = "[Release] {{.Project}} {{.Tag}}"
+
+// SMTPSecurity defines how the connection to the SMTP server is encrypted.
+type SMTPSecurity int
+
+const (
+	// SMTPStartTLS upgrades the connection with STARTTLS, and fails if the
+	// server doesn't support it. This is the default.
+	SMTPStartTLS SMTPSecurity = iota
+	// SMTPImplicitTLS connects over TLS, usually on port 465.
+	SMTPImplicitTLS
+	// SMTPPlain doesn't encrypt the connection. The credentials are only sent
+	// to localhost.
+	SMTPPlain
+)
+
+// ParseSMTPSecurity returns the SMTPSecurity for "starttls", "tls" or "none".
+func ParseSMTPSecurity(s string) (SMTPSecurity, error) {
+	switch strings.ToLower(s) {
+	case "starttls", "":
+		return SMTPStartTLS, nil
+	case "tls", "implicit":
+		return SMTPImplicitTLS, nil
+	case "none", "plain":
+		return SMTPPlain, nil
+	}
+	return SMTPStartTLS, fmt.Errorf("unknown SMTP security %q", s)
+}
+
+// String returns the name of the security.
+func (s SMTPSecurity) String() string {
+	switch s {
+	case SMTPImplicitTLS:
+		return "tls"
+	case SMTPPlain:
+		return "none"
+	}
+	return "starttls"
+}
+
+// port returns the default port of the security.
+func (s SMTPSecurity) port() int {
+	switch s {
+	case SMTPImplicitTLS:
+		return 465
+	case SMTPPlain:
+		return 25
+	}
+	return 587
+}
+
+// EmailNotifier announces the releases by email. The body has the notes both
+// as HTML and as plain text.
+type EmailNotifier struct {
+	Host string
+	// Port defaults to the port of the Security.
+	Port     int
+	Security SMTPSecurity
+	// Username and Password are used for the PLAIN authentication if the
+	// Username is set.
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Subject is a text/template with the fields of the Announcement. It
+	// defaults to DefaultEmailSubject.
+	Subject string
+	// DryRun, if set, is the file the message is written into instead of
+	// sending it.
+	DryRun string
+	// TLSConfig is used for the encrypted connections. The ServerName
+	// defaults to the Host.
+	TLSConfig *tls.Config
+}
+
+// Notify sends the announcement to the recipients.
+func (e *EmailNotifier) Notify(ctx context.Context, a Announcement) error {
+	if len(e.To) == 0 {
+		return errors.New("email: no recipients")
+	}
+	msg, err := e.Message(a, time.Now())
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	if e.DryRun != "" {
+		if err := os.WriteFile(e.DryRun, msg, 0o600); err != nil {
+			return fmt.Errorf("email: writing the message: %w", err)
+		}
+		return nil
+	}
+	if err := e.send(ctx, msg); err != nil {
+		return fmt.Errorf("email: sending to %s: %w", e.Host, err)
+	}
+	return nil
+}
+
+// Message returns the MIME message of the announcement.
+func (e *EmailNotifier) Message(a Announcement, date time.Time) ([]byte, error) {
+	text := e.Subject
+	if text == "" {
+		text = DefaultEmailSubject
+	}
+	tmpl, err := template.New("subject").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the subject template: %w", err)
+	}
+	subject := &strings.Builder{}
+	if err := tmpl.Execute(subject, a); err != nil {
+		return nil, fmt.Errorf("rendering the subject: %w", err)
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the sender %q: %w", e.From, err)
+	}
+	to := make([]string, len(e.To))
+	for i, addr := range e.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the recipient %q: %w", addr, err)
+		}
+		to[i] = a.String()
+	}
+
+	notes := ExpandDetails(StripProvenance(a.Notes))
+	plain := notes
+	html := RenderHTML(notes)
+	if a.URL != "" {
+		plain += "\n\n" + a.URL
+		html += "\n<p><a href=\"" + escapeHTML(a.URL) + "\">" + escapeHTML(a.URL) + "</a></p>"
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, part := range []struct{ kind, content string }{
+		{"text/plain", plain},
+		{"text/html", "<!DOCTYPE html>\n<html><body>\n" + html + "\n</body></html>"},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.kind + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	msg := &bytes.Buffer{}
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject.String())},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from.Address)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + w.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID on the domain of the address.
+func messageID(addr string) string {
+	b := make([]byte, 12)
+	// nolint:errcheck // crypto/rand never fails on supported platforms.
+	rand.Read(b)
+	domain := "localhost"
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		domain = addr[i+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+func (e *EmailNotifier) send(ctx context.Context, msg []byte) error {
+	port := e.Port
+	if port == 0 {
+		port = e.Security.port()
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(port))
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if e.TLSConfig != nil {
+		cfg = e.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = e.Host
+	}
+
+	dialer := &net.Dialer{}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if e.Security == SMTPImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// nolint:errcheck // the connection is new.
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		// nolint:errcheck // the error of the client is more relevant.
+		conn.Close()
+		return err
+	}
+	// nolint:errcheck // the error of Quit is returned.
+	defer c.Close()
+
+	if e.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return ErrNoStartTLS
+		}
+		if err := c.StartTLS(cfg); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return err
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		a, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+		if err := c.Rcpt(a.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", a.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
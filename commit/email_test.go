@@ -0,0 +1,398 @@
+package commit_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate returns the certificate of httptest for 127.0.0.1, and the
+// client config that trusts it.
+func testCertificate(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	server = &tls.Config{Certificates: srv.TLS.Certificates, MinVersion: tls.VersionTLS12}
+	client = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return server, client
+}
+
+// fakeSMTP is an SMTP server that records the messages it receives.
+type fakeSMTP struct {
+	tls      *tls.Config
+	implicit bool
+	startTLS bool
+	user     string
+	password string
+
+	port int
+	mu   sync.Mutex
+	msgs []smtpMessage
+}
+
+type smtpMessage struct {
+	from   string
+	to     []string
+	data   string
+	tls    bool
+	authed bool
+}
+
+func newFakeSMTP(t *testing.T, f *fakeSMTP) *fakeSMTP {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if f.implicit {
+		l = tls.NewListener(l, f.tls)
+	}
+	t.Cleanup(func() { l.Close() })
+	f.port = l.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) messages() []smtpMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]smtpMessage(nil), f.msgs...)
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_, secure := conn.(*tls.Conn)
+	var msg smtpMessage
+	reply := func(format string) {
+		// nolint:errcheck // the client handles broken connections.
+		tp.PrintfLine(format)
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost"}
+			if f.startTLS && !secure {
+				lines = append(lines, "STARTTLS")
+			}
+			lines = append(lines, "AUTH PLAIN", "8BITMIME")
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				reply("250" + sep + l)
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, f.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			_, cred, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(cred)
+			if string(b) != "\x00"+f.user+"\x00"+f.password {
+				reply("535 authentication failed")
+				continue
+			}
+			msg.authed = true
+			reply("235 accepted")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			msg.from, _, _ = strings.Cut(msg.from, ">")
+			reply("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data, msg.tls = string(data), secure
+			f.mu.Lock()
+			f.msgs = append(f.msgs, msg)
+			f.mu.Unlock()
+			msg = smtpMessage{}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+var announcement = commit.Announcement{
+	Project: "myapp",
+	Tag:     "v1.4.0",
+	URL:     "https://github.com/arsham/myapp/releases/tag/v1.4.0",
+	Notes:   "### Feature\n\n- **Api:** Add the `<endpoint>` & docs\n\n<!-- gitrelease-provenance\n{}\n-->",
+}
+
+// readParts returns the decoded parts of the message keyed by their type.
+func readParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	require.NoError(t, err)
+	kind, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", kind)
+	parts := make(map[string]string)
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		kind, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		b, err := io.ReadAll(p)
+		require.NoError(t, err)
+		parts[kind] = strings.ReplaceAll(string(b), "\r\n", "\n")
+	}
+	return m, parts
+}
+
+func TestEmailNotifier(t *testing.T) {
+	t.Parallel()
+	t.Run("StartTLS", testEmailNotifierStartTLS)
+	t.Run("ImplicitTLS", testEmailNotifierImplicitTLS)
+	t.Run("NoStartTLS", testEmailNotifierNoStartTLS)
+	t.Run("BadPassword", testEmailNotifierBadPassword)
+	t.Run("DryRun", testEmailNotifierDryRun)
+	t.Run("Message", testEmailNotifierMessage)
+}
+
+func testEmailNotifierStartTLS(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := testCertificate(t)
+	srv := newFakeSMTP(t, &fakeSMTP{tls: serverTLS, startTLS: true, user: "bot", password: "secret"})
+	e := &commit.EmailNotifier{
+		Host:      "127.0.0.1",
+		Port:      srv.port,
+		Username:  "bot",
+		Password:  "secret",
+		From:      "Releases <releases@example.com>",
+		To:        []string{"a@example.com", "B <b@example.com>"},
+		TLSConfig: clientTLS,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, e.Notify(ctx, announcement))
+
+	msgs := srv.messages()
+	require.Len(t, msgs, 1)
+	assert.True(t, msgs[0].tls)
+	assert.True(t, msgs[0].authed)
+	assert.Equal(t, "releases@example.com", msgs[0].from)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, msgs[0].to)
+	m, parts := readParts(t, msgs[0].data)
+	assert.Equal(t, "[Release] myapp v1.4.0", m.Header.Get("Subject"))
+	assert.Contains(t, parts["text/plain"], "- **Api:** Add the `<endpoint>` & docs")
+	assert.Contains(t, parts["text/plain"], announcement.URL)
+	assert.Contains(t, parts["text/html"], "<code>&lt;endpoint&gt;</code> &amp; docs")
+	for _, p := range parts {
+		assert.NotContains(t, p, "gitrelease-provenance")
+	}
+}
+
+func testEmailNotifierImplicitTLS(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := testCertificate(t)
+	srv := newFakeSMTP(t, &fakeSMTP{tls: serverTLS, implicit: true, user: "bot", password: "secret"})
+	e := &commit.EmailNotifier{
+		Host:      "127.0.0.1",
+		Port:      srv.port,
+		Security:  commit.SMTPImplicitTLS,
+		Username:  "bot",
+		Password:  "secret",
+		From:      "releases@example.com",
+		To:        []string{"a@example.com"},
+		TLSConfig: clientTLS,
+	}
+	require.NoError(t, e.Notify(context.Background(), announcement))
+	msgs := srv.messages()
+	require.Len(t, msgs, 1)
+	assert.True(t, msgs[0].tls)
+	assert.True(t, msgs[0].authed)
+}
+
+func testEmailNotifierNoStartTLS(t *testing.T) {
+	t.Parallel()
+	srv := newFakeSMTP(t, &fakeSMTP{})
+	e := &commit.EmailNotifier{
+		Host: "127.0.0.1",
+		Port: srv.port,
+		From: "releases@example.com",
+		To:   []string{"a@example.com"},
+	}
+	err := e.Notify(context.Background(), announcement)
+	assert.ErrorIs(t, err, commit.ErrNoStartTLS)
+	assert.Empty(t, srv.messages())
+
+	e.Security = commit.SMTPPlain
+	require.NoError(t, e.Notify(context.Background(), announcement))
+	msgs := srv.messages()
+	require.Len(t, msgs, 1)
+	assert.False(t, msgs[0].tls)
+	assert.False(t, msgs[0].authed)
+}
+
+func testEmailNotifierBadPassword(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := testCertificate(t)
+	srv := newFakeSMTP(t, &fakeSMTP{tls: serverTLS, startTLS: true, user: "bot", password: "secret"})
+	e := &commit.EmailNotifier{
+		Host:      "127.0.0.1",
+		Port:      srv.port,
+		Username:  "bot",
+		Password:  "wrong",
+		From:      "releases@example.com",
+		To:        []string{"a@example.com"},
+		TLSConfig: clientTLS,
+	}
+	err := e.Notify(context.Background(), announcement)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authenticating")
+	assert.Empty(t, srv.messages())
+}
+
+func testEmailNotifierDryRun(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "message.eml")
+	e := &commit.EmailNotifier{
+		Host:   "smtp.invalid",
+		From:   "releases@example.com",
+		To:     []string{"a@example.com", "b@example.com"},
+		DryRun: path,
+	}
+	require.NoError(t, e.Notify(context.Background(), announcement))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	m, parts := readParts(t, string(b))
+	assert.Equal(t, "<a@example.com>, <b@example.com>", m.Header.Get("To"))
+	assert.Len(t, parts, 2)
+
+	e.To = nil
+	assert.Error(t, e.Notify(context.Background(), announcement))
+}
+
+func testEmailNotifierMessage(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	e := &commit.EmailNotifier{
+		From:    "releases@example.com",
+		To:      []string{"a@example.com"},
+		Subject: "{{.Project}} {{.Tag}} is out 🎉",
+	}
+	b, err := e.Message(announcement, date)
+	require.NoError(t, err)
+	m, _ := readParts(t, string(b))
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "myapp v1.4.0 is out 🎉", subject)
+	got, err := m.Header.Date()
+	require.NoError(t, err)
+	assert.True(t, date.Equal(got))
+	assert.True(t, strings.HasSuffix(m.Header.Get("Message-ID"), "@example.com>"))
+
+	tcs := map[string]commit.EmailNotifier{
+		"unknown field": {From: "a@example.com", To: []string{"b@example.com"}, Subject: "{{.Nope}}"},
+		"bad template":  {From: "a@example.com", To: []string{"b@example.com"}, Subject: "{{"},
+		"bad sender":    {From: "not an address", To: []string{"b@example.com"}},
+		"bad recipient": {From: "a@example.com", To: []string{"not an address"}},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := tc.Message(announcement, date)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseSMTPSecurity(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]commit.SMTPSecurity{
+		"":         commit.SMTPStartTLS,
+		"starttls": commit.SMTPStartTLS,
+		"TLS":      commit.SMTPImplicitTLS,
+		"none":     commit.SMTPPlain,
+	} {
+		got, err := commit.ParseSMTPSecurity(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+		again, err := commit.ParseSMTPSecurity(got.String())
+		require.NoError(t, err)
+		assert.Equal(t, got, again)
+	}
+	_, err := commit.ParseSMTPSecurity("ssl3")
+	assert.Error(t, err)
+}
+
+type notifierFunc func(ctx context.Context, a commit.Announcement) error
+
+func (f notifierFunc) Notify(ctx context.Context, a commit.Announcement) error { return f(ctx, a) }
+
+func TestNotifyAll(t *testing.T) {
+	t.Parallel()
+	var called []string
+	ok := notifierFunc(func(_ context.Context, a commit.Announcement) error {
+		called = append(called, "ok "+a.Tag)
+		return nil
+	})
+	fail := notifierFunc(func(_ context.Context, a commit.Announcement) error {
+		called = append(called, "fail "+a.Tag)
+		return errors.New("boom " + strconv.Itoa(len(called)))
+	})
+	err := commit.NotifyAll(context.Background(), announcement, fail, ok, fail)
+	require.Error(t, err)
+	assert.Equal(t, []string{"fail v1.4.0", "ok v1.4.0", "fail v1.4.0"}, called)
+	assert.Contains(t, err.Error(), "boom 1")
+	assert.Contains(t, err.Error(), "boom 3")
+
+	assert.NoError(t, commit.NotifyAll(context.Background(), announcement, ok))
+}
@@ -0,0 +1,40 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrEmptyRepository is returned when the repository has no commits yet, e.g.
+// right after git init.
+var ErrEmptyRepository = errors.New("the repository has no commits yet")
+
+// IsEmpty returns true if the repository has no commits, therefore its HEAD
+// doesn't resolve to a commit.
+func (g *Git) IsEmpty(ctx context.Context) (bool, error) {
+	_, err := g.run(ctx, newCommand("rev-parse", "--verify", "--quiet").rev("HEAD^{commit}"))
+	if err == nil {
+		return false, nil
+	}
+	// git exits with 128 outside of a repository, and only exits with 1,
+	// quietly, if the revision is missing.
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && gitErr.ExitCode == 1 && strings.TrimSpace(gitErr.Stderr) == "" {
+		return true, nil
+	}
+	return false, err
+}
+
+// emptyError returns ErrEmptyRepository instead of the err if the repository
+// has no commits, since the errors of git are cryptic then. The repository is
+// only checked after the err.
+func (g *Git) emptyError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrEmptyRepository) || ctx.Err() != nil {
+		return err
+	}
+	if empty, _ := g.IsEmpty(ctx); empty {
+		return ErrEmptyRepository
+	}
+	return err
+}
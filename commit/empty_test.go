@@ -0,0 +1,46 @@
+package commit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitEmptyRepository(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createEmptyGitRepo(t)
+	g := &commit.Git{Dir: dir}
+
+	empty, err := g.IsEmpty(ctx)
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	_, err = g.LatestTag(ctx)
+	assert.True(t, errors.Is(err, commit.ErrEmptyRepository), err)
+	_, err = g.PreviousTag(ctx, "v1.0.0")
+	assert.True(t, errors.Is(err, commit.ErrEmptyRepository), err)
+	_, err = g.Commits(ctx, "", "HEAD")
+	assert.True(t, errors.Is(err, commit.ErrEmptyRepository), err)
+	_, err = g.Prepare(ctx, "@")
+	assert.True(t, errors.Is(err, commit.ErrEmptyRepository), err)
+
+	commitChanges(t, dir, "chore: initial")
+	g.Refresh()
+	empty, err = g.IsEmpty(ctx)
+	require.NoError(t, err)
+	assert.False(t, empty)
+	_, err = g.LatestTag(ctx)
+	assert.True(t, errors.Is(err, commit.ErrNoTag), err)
+}
+
+func TestGitIsEmptyNotARepository(t *testing.T) {
+	t.Parallel()
+	g := &commit.Git{Dir: t.TempDir()}
+	_, err := g.IsEmpty(context.Background())
+	assert.Error(t, err)
+}
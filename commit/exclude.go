@@ -0,0 +1,75 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var shaRe = regexp.MustCompile(`^[[:xdigit:]]{4,64}$`)
+
+// WithExclude leaves the commits of the specs out of the notes. See the
+// Exclude of the Git.
+func WithExclude(specs ...string) Option {
+	return func(g *Git) { g.Exclude = append(g.Exclude, specs...) }
+}
+
+// exclusions resolves the Exclude to the full hashes of the commits. The
+// abbreviated hashes that are unknown or ambiguous, and the ranges without
+// any commits, are errors, therefore a typo doesn't silently exclude nothing.
+func (g *Git) exclusions(ctx context.Context) (map[string]bool, error) {
+	if len(g.Exclude) == 0 {
+		return nil, nil
+	}
+	excluded := make(map[string]bool)
+	for _, spec := range g.Exclude {
+		spec = strings.TrimSpace(spec)
+		if strings.Contains(spec, "..") {
+			out, err := g.run(ctx, newCommand("rev-list").rev(spec).path())
+			if err != nil {
+				return nil, fmt.Errorf("excluding %q: %w", spec, err)
+			}
+			shas := strings.Fields(out)
+			if len(shas) == 0 {
+				return nil, fmt.Errorf("excluding %q: the range has no commits", spec)
+			}
+			for _, sha := range shas {
+				excluded[sha] = true
+			}
+			continue
+		}
+		if !shaRe.MatchString(spec) {
+			return nil, fmt.Errorf("excluding %q: not a commit hash or a range", spec)
+		}
+		out, err := g.run(ctx, newCommand("rev-parse", "--verify").revFrom(spec+"^{commit}", spec))
+		if err != nil {
+			return nil, fmt.Errorf("excluding %q: %w", spec, err)
+		}
+		excluded[strings.TrimSpace(out)] = true
+	}
+	return excluded, nil
+}
+
+// exclude returns the commits that are not excluded, and the number of the
+// ones that are left out.
+func exclude(commits []Commit, excluded map[string]bool) ([]Commit, int) {
+	if len(excluded) == 0 {
+		return commits, 0
+	}
+	kept := make([]Commit, 0, len(commits))
+	for _, c := range commits {
+		if !excluded[c.SHA] {
+			kept = append(kept, c)
+		}
+	}
+	return kept, len(commits) - len(kept)
+}
+
+// WithInternalChanges notes the number of the commits that are left out of
+// the notes, e.g. "2 internal changes.", after the sections.
+func WithInternalChanges(n int) RenderOption {
+	return func(o *renderOptions) {
+		o.internal = n
+	}
+}
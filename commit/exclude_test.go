@@ -0,0 +1,138 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitExclude(t *testing.T) {
+	t.Parallel()
+	t.Run("Specs", testGitExcludeSpecs)
+	t.Run("Invalid", testGitExcludeInvalid)
+	t.Run("Commits", testGitExcludeCommits)
+}
+
+// excludeRepo returns a repository with four commits after v0.1.0, which is
+// tagged v0.2.0, and their hashes.
+func excludeRepo(t *testing.T) (*committest.Repo, []string) {
+	t.Helper()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v0.1.0")
+	shas := []string{
+		r.Commit("feat: one"),
+		r.Commit("fix: leak the secret"),
+		r.Commit("fix: rotate the secret"),
+		r.Commit("feat: four"),
+	}
+	r.Tag("v0.2.0")
+	return r, shas
+}
+
+func testGitExcludeSpecs(t *testing.T) {
+	t.Parallel()
+	r, shas := excludeRepo(t)
+	tcs := map[string]struct {
+		specs []string
+		want  []string
+	}{
+		"none":        {nil, []string{"feat: four", "fix: rotate the secret", "fix: leak the secret", "feat: one"}},
+		"full":        {[]string{shas[1]}, []string{"feat: four", "fix: rotate the secret", "feat: one"}},
+		"abbreviated": {[]string{shas[1][:7]}, []string{"feat: four", "fix: rotate the secret", "feat: one"}},
+		"range":       {[]string{shas[0] + ".." + shas[2]}, []string{"feat: four", "feat: one"}},
+		"several":     {[]string{shas[0], " " + shas[3][:10] + " "}, []string{"fix: rotate the secret", "fix: leak the secret"}},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.New(commit.WithDir(r.Dir), commit.WithExclude(tc.specs...), commit.WithCommitOrder(commit.OrderLog))
+			info, err := g.Prepare(context.Background(), "v0.2.0")
+			require.NoError(t, err)
+			got := make([]string, 0, len(info.Logs))
+			for _, l := range info.Logs {
+				got = append(got, strings.TrimSpace(l))
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Len(t, info.Commits, len(tc.want))
+			assert.Equal(t, 4-len(tc.want), info.Excluded)
+		})
+	}
+}
+
+func testGitExcludeInvalid(t *testing.T) {
+	t.Parallel()
+	r, shas := excludeRepo(t)
+	tcs := map[string]string{
+		"unknown":     "deadbeef",
+		"too short":   shas[0][:3],
+		"not a hash":  "v0.1.0",
+		"empty range": "v0.2.0..v0.1.0",
+		"bad range":   "v0.1.0..nope",
+		"dash":        "--all",
+	}
+	for name, spec := range tcs {
+		spec := spec
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.New(commit.WithDir(r.Dir), commit.WithExclude(spec))
+			_, err := g.Prepare(context.Background(), "v0.2.0")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), spec)
+			_, err = g.Commits(context.Background(), "v0.1.0", "v0.2.0")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func testGitExcludeCommits(t *testing.T) {
+	t.Parallel()
+	r, shas := excludeRepo(t)
+	g := commit.New(commit.WithDir(r.Dir), commit.WithExclude(shas[1][:8]))
+	logs, err := g.Commits(context.Background(), "v0.1.0", "v0.2.0")
+	require.NoError(t, err)
+	assert.Len(t, logs, 3)
+	for _, l := range logs {
+		assert.NotContains(t, l, "leak")
+	}
+}
+
+func TestInternalChanges(t *testing.T) {
+	t.Parallel()
+	logs := []string{"fix: rotate the secret"}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"one": {
+			[]commit.RenderOption{commit.WithInternalChanges(1)},
+			"### Fix\n\n- Rotate the secret\n\n1 internal change.",
+		},
+		"several": {
+			[]commit.RenderOption{commit.WithInternalChanges(3), commit.WithCompareLink("https://example.com/compare")},
+			"### Fix\n\n- Rotate the secret\n\n3 internal changes.\n\n**Full Changelog**: https://example.com/compare",
+		},
+		"none": {
+			[]commit.RenderOption{commit.WithInternalChanges(0)},
+			"### Fix\n\n- Rotate the secret",
+		},
+		"locale": {
+			[]commit.RenderOption{commit.WithInternalChanges(2), commit.WithLocale(commit.Locale{InternalChanges: "%d interne Änderungen."})},
+			"### Fix\n\n- Rotate the secret\n\n2 interne Änderungen.",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseGroups(logs, tc.opts...))
+		})
+	}
+}
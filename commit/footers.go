@@ -0,0 +1,135 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trailer is a "Key: value" footer of a commit message, e.g.
+// "Deploy-To: staging".
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// Trailers returns the footers of the last paragraph of the message, in their
+// order. The paragraph is only taken as the footers if all of its lines are
+// trailers, BREAKING CHANGE footers, or the indented continuations of the
+// previous one, which are joined to its value. A key can be repeated.
+func Trailers(msg string) []Trailer {
+	lines := splitLines(strings.TrimSpace(msg))[1:]
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	var ret []Trailer
+	for _, line := range lines[start:] {
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(ret) == 0 {
+				return nil
+			}
+			ret[len(ret)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		if !trailerRe.MatchString(line) && !strings.HasPrefix(line, "BREAKING CHANGE: ") {
+			return nil
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		ret = append(ret, Trailer{Key: key, Value: strings.TrimSpace(value)})
+	}
+	return ret
+}
+
+// FooterValue is a value of a footer and the number of the commits that have
+// it.
+type FooterValue struct {
+	Value   string
+	Commits int
+}
+
+// Footer is the values of a footer key across the commits.
+type Footer struct {
+	Key    string
+	Values []FooterValue
+}
+
+// CollectFooters returns the values of the footers of the keys in the
+// messages, in the order of the keys. The keys are case insensitive, and the
+// values are split on the commas. The values are counted once for each
+// commit, and are deduplicated case insensitively with the casing of their
+// first commit. The values are in the order they first appear, and the keys
+// without any values are left out.
+func CollectFooters(msgs []string, keys []string) []Footer {
+	ret := make([]Footer, 0, len(keys))
+	for _, key := range keys {
+		f := Footer{Key: key}
+		index := make(map[string]int)
+		for _, msg := range msgs {
+			seen := make(map[string]bool)
+			for _, t := range Trailers(msg) {
+				if !strings.EqualFold(t.Key, key) {
+					continue
+				}
+				for _, v := range strings.Split(t.Value, ",") {
+					v = strings.TrimSpace(v)
+					id := strings.ToLower(v)
+					if v == "" || seen[id] {
+						continue
+					}
+					seen[id] = true
+					i, ok := index[id]
+					if !ok {
+						i = len(f.Values)
+						index[id] = i
+						f.Values = append(f.Values, FooterValue{Value: v})
+					}
+					f.Values[i].Commits++
+				}
+			}
+		}
+		if len(f.Values) > 0 {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// DefaultFootersHeading is the heading of the section of WithFooters.
+const DefaultFootersHeading = "Footers"
+
+// WithFooters adds a section with the values of the footers of the keys
+// across the commits, e.g. "- **Deploy-To**: staging (3), eu-prod (1)", with
+// the number of the commits of each value. See CollectFooters. The heading
+// defaults to DefaultFootersHeading, and can be translated by the Locale.
+// The section is left out if none of the commits has the footers.
+func WithFooters(heading string, keys ...string) RenderOption {
+	return func(o *renderOptions) {
+		if heading == "" {
+			heading = DefaultFootersHeading
+		}
+		o.footersHeading = heading
+		o.footerKeys = keys
+	}
+}
+
+// footersSection returns the section of the footers of the logs, or an empty
+// string if there are none.
+func (o *renderOptions) footersSection(logs []string) string {
+	if len(o.footerKeys) == 0 {
+		return ""
+	}
+	footers := CollectFooters(logs, o.footerKeys)
+	if len(footers) == 0 {
+		return ""
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "### %s\n\n", o.locale.Heading(o.footersHeading))
+	for _, f := range footers {
+		values := make([]string, 0, len(f.Values))
+		for _, v := range f.Values {
+			values = append(values, fmt.Sprintf("%s (%d)", o.sanitizeText(v.Value), v.Commits))
+		}
+		fmt.Fprintf(buf, "%s**%s**: %s\n", ItemPrefix, o.sanitizeText(f.Key), strings.Join(values, ", "))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
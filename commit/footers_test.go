@@ -0,0 +1,134 @@
+package commit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailers(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msg  string
+		want []commit.Trailer
+	}{
+		"subject only": {msg: "fix: the leak"},
+		"body only":    {msg: "fix: the leak\n\nIt was slow."},
+		"trailers": {
+			msg: "fix: the leak\n\nIt was slow.\n\nDeploy-To: staging, eu-prod\nSigned-off-by: A <a@example.com>\n",
+			want: []commit.Trailer{
+				{Key: "Deploy-To", Value: "staging, eu-prod"},
+				{Key: "Signed-off-by", Value: "A <a@example.com>"},
+			},
+		},
+		"repeated and continued": {
+			msg: "fix: the leak\n\nDeploy-To: staging\nDeploy-To: eu-prod,\n  us-prod\nBREAKING CHANGE: the flag is gone",
+			want: []commit.Trailer{
+				{Key: "Deploy-To", Value: "staging"},
+				{Key: "Deploy-To", Value: "eu-prod, us-prod"},
+				{Key: "BREAKING CHANGE", Value: "the flag is gone"},
+			},
+		},
+		"not all trailers": {msg: "fix: the leak\n\nDeploy-To: staging\nand more text"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.Trailers(tc.msg))
+		})
+	}
+}
+
+func TestCollectFooters(t *testing.T) {
+	t.Parallel()
+	msgs := []string{
+		"feat: the thing\n\nDeploy-To: staging, eu-prod\nRisk: low",
+		"fix: the leak\n\ndeploy-to: Staging\nDeploy-To: staging, us-prod",
+		"docs: the readme",
+		"chore: the linter\n\nDeploy-To: staging,,",
+	}
+	got := commit.CollectFooters(msgs, []string{"Deploy-To", "Owner", "risk"})
+	assert.Equal(t, []commit.Footer{
+		{Key: "Deploy-To", Values: []commit.FooterValue{
+			{Value: "staging", Commits: 3},
+			{Value: "eu-prod", Commits: 1},
+			{Value: "us-prod", Commits: 1},
+		}},
+		{Key: "risk", Values: []commit.FooterValue{{Value: "low", Commits: 1}}},
+	}, got)
+}
+
+func TestParseGroupsFooters(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing\n\nDeploy-To: staging, eu-prod",
+		"fix: the leak\n\nDeploy-To: staging",
+	}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"none": {
+			want: "### Feature\n\n- The thing\n\n\n### Fix\n\n- The leak",
+		},
+		"default heading": {
+			opts: []commit.RenderOption{commit.WithFooters("", "Deploy-To")},
+			want: "### Feature\n\n- The thing\n\n\n### Fix\n\n- The leak\n\n" +
+				"### Footers\n\n- **Deploy-To**: staging (2), eu-prod (1)",
+		},
+		"heading": {
+			opts: []commit.RenderOption{commit.WithFooters("Environments", "Deploy-To")},
+			want: "### Feature\n\n- The thing\n\n\n### Fix\n\n- The leak\n\n" +
+				"### Environments\n\n- **Deploy-To**: staging (2), eu-prod (1)",
+		},
+		"no values": {
+			opts: []commit.RenderOption{commit.WithFooters("", "Owner")},
+			want: "### Feature\n\n- The thing\n\n\n### Fix\n\n- The leak",
+		},
+		"collapsed": {
+			opts: []commit.RenderOption{
+				commit.WithFooters("Environments", "Deploy-To"),
+				commit.WithCollapsed("environments"),
+			},
+			want: "### Feature\n\n- The thing\n\n\n### Fix\n\n- The leak\n\n" +
+				"<details><summary>Environments (1)</summary>\n\n" +
+				"- **Deploy-To**: staging (2), eu-prod (1)\n\n</details>",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseGroups(logs, tc.opts...))
+		})
+	}
+}
+
+func FuzzTrailers(f *testing.F) {
+	f.Add("fix: the leak\n\nDeploy-To: staging, eu-prod\nSigned-off-by: A <a@example.com>")
+	f.Add("fix: the leak\n\nDeploy-To: staging\n  eu-prod\nBREAKING CHANGE: gone")
+	f.Add("fix: the leak\r\n\r\nDeploy-To: ,staging,,STAGING\r\n")
+	f.Add("\n\n  \n\tDeploy-To: x")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, msg string) {
+		for _, tr := range commit.Trailers(msg) {
+			if strings.Contains(tr.Key, "\n") || strings.Contains(tr.Value, "\n") {
+				t.Errorf("multiline trailer %q for %q", tr, msg)
+			}
+		}
+		msgs := []string{msg, msg}
+		for _, footer := range commit.CollectFooters(msgs, []string{"Deploy-To", "BREAKING CHANGE"}) {
+			if len(footer.Values) == 0 {
+				t.Errorf("footer %q without values for %q", footer.Key, msg)
+			}
+			for _, v := range footer.Values {
+				if v.Value == "" || v.Commits < 1 || v.Commits > len(msgs) {
+					t.Errorf("invalid value %+v of %q for %q", v, footer.Key, msg)
+				}
+			}
+		}
+	})
+}
@@ -5,154 +5,1208 @@ package commit
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"os/exec"
-	"regexp"
+	"io"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
-	"github.com/github-release/github-release/github"
-	"github.com/pkg/errors"
+	"github.com/arsham/gitrelease/version"
+	"golang.org/x/sync/errgroup"
 )
 
 const baseURL = "https://api.github.com"
 
+var (
+	// ErrNoTag is returned when there is no tag reachable from a revision.
+	ErrNoTag = errors.New("no tag found")
+	// ErrDetachedHead is returned when the HEAD is expected to be on a branch
+	// but it is detached.
+	ErrDetachedHead = errors.New("HEAD is detached")
+	// ErrNotOnBranch is returned when a commit is not reachable from a
+	// branch.
+	ErrNotOnBranch = errors.New("commit is not reachable from the branch")
+	// ErrReleaseExists is returned when the release of a tag is already
+	// published.
+	ErrReleaseExists = errors.New("release already exists")
+)
+
+// RangeMode defines how the commits between two tags are selected.
+type RangeMode int
+
+const (
+	// RangeTwoDot selects the commits reachable from the second tag that are
+	// not reachable from the first one (tag1..tag2). This is the default.
+	RangeTwoDot RangeMode = iota
+	// RangeSymmetric selects the commits of both tags since their merge base
+	// (tag1...tag2). When the tags are on diverged branches, the commits of
+	// the first tag's branch are included as well.
+	RangeSymmetric
+)
+
+// ParseRangeMode returns the RangeMode for "two-dot" or "three-dot".
+func ParseRangeMode(s string) (RangeMode, error) {
+	switch s {
+	case "two-dot", "..", "":
+		return RangeTwoDot, nil
+	case "three-dot", "...":
+		return RangeSymmetric, nil
+	}
+	return RangeTwoDot, fmt.Errorf("unknown range mode %q", s)
+}
+
+// String returns the name of the mode.
+func (r RangeMode) String() string {
+	if r == RangeSymmetric {
+		return "three-dot"
+	}
+	return "two-dot"
+}
+
+// Range returns the git revision range between the two tags.
+func (r RangeMode) Range(tag1, tag2 string) string {
+	if r == RangeSymmetric {
+		return tag1 + "..." + tag2
+	}
+	return tag1 + ".." + tag2
+}
+
+// CompareURL returns the address of the compare page of the two tags on the
+// remote. The compare pages always list the commits of tag2 since the merge
+// base, which are the commits of the two-dot notes, therefore its link is the
+// merge base view (tag1...tag2 on GitHub). The three-dot notes also have the
+// commits of tag1's branch, which no compare page lists; its link compares the
+// two tags directly (tag1..tag2 on GitHub), whose diff has the changes of both
+// branches. See RemoteInfo.CompareURL.
+func (r RangeMode) CompareURL(remote RemoteInfo, tag1, tag2 string) string {
+	return remote.CompareURL(r, tag1, tag2)
+}
+
+// escapeRef escapes the ref for a URL path, keeping the slashes of the
+// hierarchical names, e.g. "mod/v1.0.0".
+func escapeRef(ref string) string {
+	parts := strings.Split(ref, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// Logger is used for printing debug information.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
 // Git executes git processes targeted at a directory. If the Dir property is
 // empty, all calls will be on the current folder.
+//
+// The tags and remotes of the repository are read once and cached on the
+// value for the duration of a run. Therefore a Git value should not be copied
+// after its first use, and Refresh should be called if the repository has
+// changed since.
 type Git struct {
-	Dir    string
-	Remote string
+	Dir string
+	// GitDir is the git directory of the repository, e.g. the one of a clone
+	// with --separate-git-dir, and WorkTree is its working tree. They are
+	// passed to git as --git-dir and --work-tree, therefore the Dir doesn't
+	// have to be in the repository. The relative paths are to the Dir. The
+	// linked worktrees, and the clones whose .git is a file, work without
+	// them.
+	GitDir   string
+	WorkTree string
+	Remote   string
+	// Logger receives debug information if set.
+	Logger Logger
+	// Runner runs the git commands. If it is nil, the git binary is executed.
+	Runner Runner
+	// RangeMode defines which commits are returned by Commits.
+	RangeMode RangeMode
+	// TagPrefix limits the tags to the ones starting with it, e.g. the
+	// "mod/sub/" tags of a Go module in the mod/sub directory.
+	TagPrefix string
+	// Paths limits Commits to the commits touching these pathspecs.
+	Paths []string
+	// Scheme limits the tags to the ones whose version, after the TagPrefix,
+	// belongs to the scheme. When nil, all tags are considered.
+	Scheme VersionScheme
+	// AnnotatedOnly limits the tags to the annotated ones, leaving out the
+	// lightweight tags, e.g. the ones CI systems push for every build.
+	AnnotatedOnly bool
+	// Channel limits the tags to the ones of the release channel, and its
+	// Since channel selects the previous tags. When nil, all tags are
+	// considered.
+	Channel *Channel
+	// SinceLastStable skips the pre-release tags when looking for the
+	// previous tag of a stable release, therefore the notes of v1.5.0 cover
+	// the changes since v1.4.0 rather than since v1.5.0-rc.1. The previous
+	// tags of the pre-releases are not affected.
+	SinceLastStable bool
+	// BaseURL is the address of the GitHub API. It defaults to
+	// https://api.github.com.
+	BaseURL string
+	// TokenSource provides the token for the GitHub API when the methods
+	// are called with an empty token.
+	TokenSource TokenSource
+	// CacheDir keeps the responses of the GitHub API between the runs. The
+	// responses are always cached in memory during a run.
+	CacheDir string
+	// Ranges keeps the commits of Prepare and their pull requests between
+	// the runs if it is set.
+	Ranges *RangeCache
+	// Progress receives the progress of the uploads if it is set.
+	Progress Progress
+	// NoMerges leaves the merge commits out of Commits and Log.
+	NoMerges bool
+	// FirstParent only follows the first parent of the merge commits in
+	// Commits and Log.
+	FirstParent bool
+	// NotesRef is the ref of the git notes that annotate the commits of
+	// Commits and Log, e.g. "release" for refs/notes/release. The notes are
+	// in the Note of the commits. A missing ref is the same as no notes.
+	NotesRef string
+	// Timeout limits the duration of each git process if it is set.
+	Timeout time.Duration
+	// MaxSubject is the number of characters after which Commits truncates
+	// the subjects. It defaults to DefaultMaxSubject, and a negative value
+	// disables the truncation.
+	MaxSubject int
+	// Order is the order of the commits of Commits, Log and CommitsSince.
+	Order CommitOrder
+	// SlowThreshold is the duration after which a git process is reported
+	// to the SlowLogger. Zero disables the reports.
+	SlowThreshold time.Duration
+	// SlowLogger receives a warning with the arguments of the git processes
+	// that take longer than the SlowThreshold.
+	SlowLogger Logger
+	// Warnings collects the issues that don't stop the run, e.g. the slow
+	// git processes and the invalid UTF-8 in the commit messages.
+	Warnings *Warnings
+	// Metrics collects the durations of the git processes and the API calls,
+	// and the bytes of the uploaded assets.
+	Metrics *Metrics
+	// Trace records the git processes with their results if it is set.
+	Trace *Trace
+	// HostURLs overrides the addresses of the pages of the hosts in the
+	// RemoteInfo, keyed by the lower case host names.
+	HostURLs map[string]URLPatterns
+	// Exclude leaves the commits out of Commits and Prepare, e.g. the ones
+	// with a secret in their messages. Each item is a full or an abbreviated
+	// hash, or a range such as "v1.0.0..abc123".
+	Exclude []string
+	// InitialVersion is the suggested tag of the first release when the
+	// repository has no tags. It defaults to DefaultInitialVersion.
+	InitialVersion string
+	// ExtraRanges are the ranges whose commits Prepare adds to the ones since
+	// the previous tag, e.g. of a branch that is merged late. The commits
+	// that are in several ranges are only counted once.
+	ExtraRanges []CommitRange
+	// Offline disables the network. The git commands that reach the remotes
+	// and the requests to the API return ErrOffline instead, and are kept
+	// in the OfflineViolations.
+	Offline bool
+	// ReplaceRefs passes -c core.useReplaceRefs=true to git, therefore the
+	// objects that are replaced with git replace are read as their
+	// replacements, even if the config of the repository turns them off.
+	// It lets the ranges start at the tags of a rewritten history, whose
+	// commits are mapped to the new ones. A replaced commit keeps its id,
+	// therefore a range that starts at it also lists its replacement.
+	ReplaceRefs bool
+
+	mu        sync.Mutex
+	remotes   *remotesResult
+	tags      map[string]*tagsResult
+	walks     map[string]*walkResult
+	responses map[string]*cachedResponse
+	// logins are the logins ResolveLogins has looked up, keyed by the
+	// emails. The emails that don't belong to any user have empty logins.
+	logins map[string]string
+	// offline refuses the network access of an Offline Git.
+	offline *OfflineTransport
+}
+
+type remotesResult struct {
+	urls map[string]string
+	err  error
+	once sync.Once
 }
 
-// LatestTag returns the last tag in the repository.
-func (g Git) LatestTag(ctx context.Context) (string, error) {
-	args := []string{
-		"describe",
-		"--tags",
-		"--abbrev=0",
+type walkResult struct {
+	err    error
+	head   string
+	tagged []taggedCommit
+	once   sync.Once
+}
+
+type taggedCommit struct {
+	sha  string
+	tags []string
+}
+
+// Refresh drops all cached information, therefore the next calls read them
+// from the repository again.
+func (g *Git) Refresh() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.remotes = nil
+	g.tags = nil
+	g.walks = nil
+}
+
+// LatestTag returns the nearest tag reachable from the HEAD. As with git
+// describe, the tags of the branches that are not merged into the HEAD are not
+// considered, even if they are newer or have higher versions. It returns
+// ErrEmptyRepository if the repository has no commits.
+func (g *Git) LatestTag(ctx context.Context) (string, error) {
+	w, err := g.walk(ctx, "HEAD")
+	if err != nil {
+		return "", g.emptyError(ctx, err)
 	}
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = g.Dir
-	out, err := cmd.CombinedOutput()
+	if len(w.tagged) == 0 {
+		if head, err := g.Head(ctx); err == nil {
+			return "", fmt.Errorf("HEAD is %s: %w", head, ErrNoTag)
+		}
+		return "", fmt.Errorf("HEAD: %w", ErrNoTag)
+	}
+	return w.tagged[0].tags[0], nil
+}
+
+// PreviousTag returns the nearest tag reachable from the given tag, other than
+// the ones pointing to the same commit. Like LatestTag, it only considers the
+// reachable tags, and returns ErrEmptyRepository if there are no commits. The
+// tags are of the Since of the Channel if it's set. With SinceLastStable, the
+// previous tag of a stable release is the nearest stable one.
+func (g *Git) PreviousTag(ctx context.Context, tag string) (string, error) {
+	w, err := g.walkChannel(ctx, tag, g.previousChannel())
 	if err != nil {
-		return "", errors.Wrap(err, string(out))
+		return "", g.emptyError(ctx, err)
+	}
+	if _, prev := g.previousTagged(w, tag); prev != "" {
+		return prev, nil
+	}
+	return "", fmt.Errorf("before %s: %w", tag, ErrNoTag)
+}
+
+// previousTagged returns the commit and the tag of the walk of the tag that
+// PreviousTag returns, or empty strings if there is none.
+func (g *Git) previousTagged(w *walkResult, tag string) (sha, prev string) {
+	stable := g.SinceLastStable && !g.isPrerelease(tag)
+	for _, c := range w.tagged {
+		if c.sha == w.head {
+			continue
+		}
+		if !stable {
+			return c.sha, c.tags[0]
+		}
+		for _, t := range c.tags {
+			if !g.isPrerelease(t) {
+				return c.sha, t
+			}
+		}
+	}
+	return "", ""
+}
+
+// prereleases returns true if all the tags are pre-releases.
+func (g *Git) prereleases(tags []string) bool {
+	for _, tag := range tags {
+		if !g.isPrerelease(tag) {
+			return false
+		}
 	}
+	return true
+}
+
+// isPrerelease returns true if the tag, after the TagPrefix, is a semantic
+// version with a pre-release part.
+func (g *Git) isPrerelease(tag string) bool {
+	return version.IsPrerelease(strings.TrimPrefix(tag, g.TagPrefix))
+}
+
+// Head describes the commit the HEAD points to.
+type Head struct {
+	// Branch is empty when the HEAD is detached.
+	Branch string
+	Commit string
+	// Tags contains the tags pointing to the commit.
+	Tags []string
+}
 
-	return strings.Trim(string(out), "\n"), nil
+// Detached returns true if the HEAD is not on a branch, which is the case
+// when CI systems check out a tag.
+func (h Head) Detached() bool {
+	return h.Branch == ""
 }
 
-// PreviousTag returns the previous tag of the given tag.
-func (g Git) PreviousTag(ctx context.Context, tag string) (string, error) {
-	args := []string{
-		"describe",
-		"--tags",
-		"--abbrev=0",
-		tag + "^",
+// String describes the state of the HEAD.
+func (h Head) String() string {
+	switch {
+	case !h.Detached():
+		return "on branch " + h.Branch
+	case len(h.Tags) > 0:
+		return "detached at tag " + h.Tags[0]
+	case len(h.Commit) > 7:
+		return "detached at " + h.Commit[:7]
 	}
-	// nolint:gosec // we don't have any other way to get the previous tag.
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = g.Dir
-	out, err := cmd.CombinedOutput()
+	return "detached at " + h.Commit
+}
+
+// Head returns the state of the HEAD.
+func (g *Git) Head(ctx context.Context) (Head, error) {
+	c := newCommand(
+		"log",
+		"-1",
+		"--format=%H%x00%D",
+		"--decorate-refs=HEAD",
+		"--decorate-refs=refs/heads/",
+		"--decorate-refs="+g.decorateRefs(),
+	)
+	out, err := g.run(ctx, c.rev("HEAD").path())
 	if err != nil {
-		return "", errors.Wrap(err, string(out))
+		return Head{}, err
 	}
+	sha, refs, _ := strings.Cut(strings.TrimSpace(out), "\x00")
+	head := Head{Commit: sha}
+	for _, ref := range strings.Split(refs, ", ") {
+		switch {
+		case strings.HasPrefix(ref, "HEAD -> "):
+			head.Branch = strings.TrimPrefix(ref, "HEAD -> ")
+		case strings.HasPrefix(ref, "tag: "):
+			head.Tags = append(head.Tags, strings.TrimPrefix(ref, "tag: "))
+		}
+	}
+	return head, nil
+}
 
-	return strings.Trim(string(out), "\n"), nil
+// CheckBranch returns an error if the HEAD is not on the branch. A detached
+// HEAD at the tag is accepted, because that's how CI systems check out the
+// tag that is being released.
+func (g *Git) CheckBranch(ctx context.Context, branch, tag string) error {
+	head, err := g.Head(ctx)
+	if err != nil {
+		return fmt.Errorf("getting the HEAD: %w", err)
+	}
+	if head.Branch == branch {
+		return nil
+	}
+	if !head.Detached() {
+		return fmt.Errorf("HEAD is %s, expected to be on branch %q", head, branch)
+	}
+	for _, t := range head.Tags {
+		if t == tag {
+			return nil
+		}
+	}
+	return fmt.Errorf("HEAD is %s, expected to be on branch %q or at tag %q: %w", head, branch, tag, ErrDetachedHead)
 }
 
-// Commits returns the contents of all commits between two tags.
-func (g Git) Commits(ctx context.Context, tag1, tag2 string) ([]string, error) {
-	separator := "00000000000000000000000000000000000"
-	args := []string{
-		"log",
-		"--oneline",
-		fmt.Sprintf("%s..%s", tag1, tag2),
-		fmt.Sprintf("--pretty=%s%%B", separator),
+// IsAncestor returns true if the commit is reachable from the ref. The
+// annotated tags are peeled to their commits.
+func (g *Git) IsAncestor(ctx context.Context, commit, ref string) (bool, error) {
+	_, err := g.run(ctx, newCommand("merge-base", "--is-ancestor").rev(commit, ref))
+	if err == nil {
+		return true, nil
+	}
+	if hasExitCode(err, 1) {
+		return false, nil
+	}
+	return false, err
+}
+
+// CheckReachable returns ErrNotOnBranch if the commit of the tag is not
+// reachable from the branch.
+func (g *Git) CheckReachable(ctx context.Context, tag, branch string) error {
+	sha, err := g.resolve(ctx, tag)
+	if err != nil {
+		return err
+	}
+	ok, err := g.IsAncestor(ctx, sha, branch)
+	if err != nil {
+		return fmt.Errorf("checking %s is on %s: %w", tag, branch, err)
+	}
+	if !ok {
+		return fmt.Errorf("commit %s of tag %s is not on %s: %w", sha, tag, branch, ErrNotOnBranch)
+	}
+	return nil
+}
+
+// CreateTag creates a lightweight tag on the rev.
+func (g *Git) CreateTag(ctx context.Context, tag, rev string) error {
+	if _, err := g.run(ctx, newCommand("tag").rev(tag, rev)); err != nil {
+		return fmt.Errorf("creating tag %s: %w", tag, err)
+	}
+	return nil
+}
+
+// CreateAnnotatedTag creates an annotated tag on the rev with the message,
+// which is kept verbatim, e.g. the notes of the release.
+func (g *Git) CreateAnnotatedTag(ctx context.Context, tag, rev, msg string) error {
+	c := newCommand("tag", "--annotate", "--cleanup=verbatim", "--message="+msg).rev(tag, rev)
+	if _, err := g.run(ctx, c); err != nil {
+		return fmt.Errorf("creating tag %s: %w", tag, err)
+	}
+	return nil
+}
+
+// DeleteTag deletes the local tag.
+func (g *Git) DeleteTag(ctx context.Context, tag string) error {
+	if _, err := g.run(ctx, newCommand("tag", "-d").rev(tag)); err != nil {
+		return fmt.Errorf("deleting tag %s: %w", tag, err)
+	}
+	return nil
+}
+
+// PushTag pushes the tag to the Remote, or origin if Remote is empty.
+func (g *Git) PushTag(ctx context.Context, tag string) error {
+	remote := g.remote()
+	c := newCommand("push").rev(remote).revFrom("refs/tags/"+tag, tag)
+	if _, err := g.run(ctx, c); err != nil {
+		return fmt.Errorf("pushing tag %s to %s: %w", tag, remote, err)
+	}
+	return nil
+}
+
+// resolve returns the commit the rev points to.
+func (g *Git) resolve(ctx context.Context, rev string) (string, error) {
+	out, err := g.run(ctx, newCommand("rev-parse", "--verify", "--quiet").revFrom(rev+"^{commit}", rev))
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", rev, err)
 	}
-	// nolint:gosec // we need these variables.
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = g.Dir
-	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(out), nil
+}
+
+// resolveTag returns the commit of the tag, which is looked up in refs/tags,
+// therefore a branch of the same name is never taken.
+func (g *Git) resolveTag(ctx context.Context, tag string) (string, error) {
+	ref := "refs/tags/" + tag
+	out, err := g.run(ctx, newCommand("rev-parse", "--verify", "--quiet").revFrom(ref+"^{commit}", tag))
 	if err != nil {
-		return nil, errors.Wrap(err, string(out))
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// commitSeparator separates the commits in the output of git log.
+const commitSeparator = "00000000000000000000000000000000000"
+
+// Commits returns the contents of all commits between two tags. If tag1 is
+// empty, all commits reachable from tag2 are returned. Messages are always
+// returned as UTF-8: git transcodes the commits that declare their encoding,
+// and any remaining invalid sequences are replaced with U+FFFD. If there are
+// no commits in the range, for example when both tags point to the same
+// commit, the returned slice is empty. The messages are normalized as
+// Commit.Normalize does with the MaxSubject. The opts take precedence over
+// the NoMerges and the FirstParent of the Git.
+//
+// Each message has only LF line endings, and no blank lines at its start or
+// its end. The subject is the first line, and the body, if there is one, is
+// separated from it by exactly one blank line. Therefore the subject and the
+// body can be split at the first "\n\n". The lines of the first paragraph are
+// joined with spaces into the subject. A message that starts with a blank line
+// has no subject, therefore it renders as "(no subject)" with its body.
+// ErrEmptyRepository is returned if the repository has no commits.
+func (g *Git) Commits(ctx context.Context, tag1, tag2 string, opts ...LogOption) ([]string, error) {
+	excluded, err := g.exclusions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := g.Log(ctx, tag1, tag2, opts...)
+	if err != nil {
+		return nil, g.emptyError(ctx, err)
+	}
+	commits, _ = exclude(commits, excluded)
+	for i := range commits {
+		// The subjects are joined before they are truncated.
+		commits[i].Message = tidyMessage(commits[i].Message)
+	}
+	msgs := Messages(g.normalize(commits))
+	for i := range msgs {
+		msgs[i] = tidyMessage(msgs[i])
+	}
+	return msgs, nil
+}
+
+// maxSubject returns the MaxSubject, or its default.
+func (g *Git) maxSubject() int {
+	if g.MaxSubject == 0 {
+		return DefaultMaxSubject
+	}
+	return g.MaxSubject
+}
+
+// Commit is a commit with its metadata.
+type Commit struct {
+	SHA    string
+	Author string
+	// Date is the committer date in UTC.
+	Date    time.Time
+	Message string
+	// PRNumber is the number of the pull request the commit is merged with,
+	// or zero if it's not known. Log sets it from the message, and
+	// AssociatePulls from the GitHub API.
+	PRNumber int
+	// PRURL is the address of the pull request. It is set by AssociatePulls.
+	PRURL string
+	// Labels are the labels of the pull request. They are set by
+	// AssociatePulls if the PullOptions ask for them.
+	Labels []string
+	// Note is the git note of the commit in the NotesRef of the Git, or
+	// empty if it has none.
+	Note string
+	// Login is the GitHub login of the author. It is set by ResolveLogins.
+	Login string
+	// Additions, Deletions and FilesChanged are the size of the diff of the
+	// commit. They are set by DiffStats.
+	Additions    int
+	Deletions    int
+	FilesChanged int
+	// Paths are the files the commit changes in the Paths of the Git. They
+	// are set by ChangedPaths.
+	Paths []string
+}
+
+// Messages returns the messages of the commits, with their notes preferred
+// over their subjects. See Commit.Annotated.
+func Messages(commits []Commit) []string {
+	msgs := make([]string, 0, len(commits))
+	for _, c := range commits {
+		msgs = append(msgs, c.Annotated())
+	}
+	return msgs
+}
+
+// maxArgRevs is the number of the revisions that are passed as the arguments
+// of one git process, if the runner can't write them into its standard input.
+const maxArgRevs = 1000
+
+// runRevs runs the git command. The revs of the command are written into the
+// standard input of git with --stdin, therefore any number of them can be
+// given, e.g. the commits of a large release. If the runner is not a
+// StdinRunner, they are passed as the arguments of as many processes as
+// needed, and their outputs are joined.
+func (g *Git) runRevs(ctx context.Context, c command) (string, error) {
+	// All revs are checked before the first batch is run.
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	revs := c.revs
+	c.revs = nil
+	if _, ok := g.runner().(StdinRunner); ok {
+		c.stdin = revs
+		return g.run(ctx, c)
+	}
+	out := &strings.Builder{}
+	for len(revs) > 0 {
+		n := len(revs)
+		if n > maxArgRevs {
+			n = maxArgRevs
+		}
+		s, err := g.run(ctx, c.rev(revs[:n]...))
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(s)
+		revs = revs[n:]
+	}
+	return out.String(), nil
+}
+
+// commitSHAs returns the SHAs of the commits.
+func commitSHAs(commits []Commit) []string {
+	shas := make([]string, 0, len(commits))
+	for _, c := range commits {
+		shas = append(shas, c.SHA)
+	}
+	return shas
+}
+
+// normalize normalizes the messages of the commits in place with the
+// MaxSubject, and returns them.
+func (g *Git) normalize(commits []Commit) []Commit {
+	for i := range commits {
+		commits[i] = commits[i].Normalize(g.maxSubject())
 	}
-	logs := strings.Split(string(out), separator)
-	return logs, nil
+	return commits
+}
+
+// Subject returns the first line of the message.
+func (c Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(subject)
 }
 
-var infoRe = regexp.MustCompile(`github\.com[:/](?P<user>[^/]+)/(?P<repo>.+?)(?:.git)?\n?$`)
+// commitFormat is the format of git log that is parsed by parseCommits. The
+// notes are only added to it with the --notes flag of notesArgs.
+const commitFormat = "%H%x00%an <%ae>%x00%ct%x00%B"
+
+// notesFormat is the commitFormat with the notes of the commits.
+const notesFormat = commitFormat + "%x00%N"
 
-// RepoInfo returns some information about the repository.
-func (g Git) RepoInfo(ctx context.Context) (user, repo string, err error) {
-	if g.Remote == "" {
-		g.Remote = "origin"
+// notesArgs returns the format of git log for parseCommits, and the flag that
+// selects the NotesRef if it is set. All notes are read by the same git log.
+func (g *Git) notesArgs() (format string, args []string) {
+	if g.NotesRef == "" {
+		return commitFormat, nil
 	}
-	args := []string{
-		"config",
-		"--get",
-		fmt.Sprintf("remote.%s.url", g.Remote),
+	return notesFormat, []string{"--notes=" + g.NotesRef}
+}
+
+// Log returns the commits between two tags, with the same rules and options
+// as Commits.
+func (g *Git) Log(ctx context.Context, tag1, tag2 string, opts ...LogOption) ([]Commit, error) {
+	format, notes := g.notesArgs()
+	parts, err := g.log(ctx, tag1, tag2, format, g.logOptions(opts), notes...)
+	if err != nil {
+		return nil, err
+	}
+	return g.parseCommits(parts), nil
+}
+
+// CountCommits returns the number of the commits Log would return, without
+// reading their messages. It's cheaper than Log when only the existence of
+// the changes matters. The Limit is ignored, all commits are counted.
+func (g *Git) CountCommits(ctx context.Context, tag1, tag2 string, opts ...LogOption) (int, error) {
+	out, err := g.revList(ctx, tag1, tag2, opts, "--count")
+	if err != nil {
+		return 0, err
 	}
-	// nolint:gosec // it is required.
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = g.Dir
-	out, err := cmd.CombinedOutput()
+	n, err := strconv.Atoi(strings.TrimSpace(out))
 	if err != nil {
-		return "", "", errors.Wrap(err, string(out))
+		return 0, fmt.Errorf("counting the commits of %s..%s: %w", tag1, tag2, err)
 	}
+	return n, nil
+}
 
-	info := infoRe.FindStringSubmatch(string(out))
-	if len(info) != 3 {
-		return "", "", fmt.Errorf("could not parse repository info: %s", string(out))
+// revList runs git rev-list with the flags on the commits Log would return,
+// without the Limit.
+func (g *Git) revList(ctx context.Context, tag1, tag2 string, opts []LogOption, flags ...string) (string, error) {
+	rng := tag2
+	if tag1 != "" {
+		rng = g.RangeMode.Range(tag1, tag2)
 	}
-	user = info[1]
-	repo = info[2]
+	if err := g.checkRange(ctx, tag1, tag2); err != nil {
+		return "", err
+	}
+	o := g.logOptions(opts)
+	o.limit = 0
+	c := newCommand("rev-list").opt(flags...).opt(o.args()...)
+	return g.run(ctx, c.revFrom(rng, tag1, tag2).path(g.Paths...))
+}
 
-	return user, repo, nil
+// CommitPage is the latest commits of a range, and the number of all the
+// commits of the range.
+type CommitPage struct {
+	Commits []Commit
+	Count   int
 }
 
-type releaseCreate struct {
-	TagName         string `json:"tag_name"`
-	TargetCommitish string `json:"target_commitish,omitempty"`
-	Name            string `json:"name"`
-	Body            string `json:"body"`
-	Draft           bool   `json:"draft"`
-	Prerelease      bool   `json:"prerelease"`
+// Limited returns true if the range has more commits than the Commits.
+func (p CommitPage) Limited() bool {
+	return p.Count > len(p.Commits)
 }
 
-// Release publishes the release for the user on the repo.
-func (g Git) Release(ctx context.Context, token, user, repo, tag, desc string) error {
-	params := releaseCreate{
-		TagName: tag,
-		Body:    desc,
+// LogPage is like Log, but with the Count of all the commits of the range if
+// the Limit leaves some of them out. The Count is read with CountCommits,
+// which doesn't read the messages of the commits that are left out,
+// therefore a preview of a range of many commits stays fast.
+func (g *Git) LogPage(ctx context.Context, tag1, tag2 string, opts ...LogOption) (CommitPage, error) {
+	commits, err := g.Log(ctx, tag1, tag2, opts...)
+	if err != nil {
+		return CommitPage{}, err
+	}
+	page := CommitPage{Commits: commits, Count: len(commits)}
+	if limit := g.logOptions(opts).limit; limit > 0 && len(commits) >= limit {
+		if page.Count, err = g.CountCommits(ctx, tag1, tag2, opts...); err != nil {
+			return CommitPage{}, err
+		}
 	}
+	return page, nil
+}
 
-	payload, err := json.Marshal(params)
+// parseCommits parses the entries of git log formatted with commitFormat or
+// notesFormat, and sorts them in the Order.
+func (g *Git) parseCommits(parts []string) []Commit {
+	commits := make([]Commit, 0, len(parts))
+	for _, part := range parts {
+		fields := strings.SplitN(part, "\x00", 5)
+		if len(fields) < 4 {
+			continue
+		}
+		var note string
+		if len(fields) == 5 {
+			note = strings.TrimSpace(g.validUTF8(fields[4]))
+		}
+		var date time.Time
+		if sec, err := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64); err == nil {
+			date = time.Unix(sec, 0).UTC()
+		}
+		msg := g.validUTF8(fields[3])
+		commits = append(commits, Commit{
+			SHA:      strings.TrimSpace(fields[0]),
+			Author:   g.validUTF8(fields[1]),
+			Date:     date,
+			Message:  msg,
+			PRNumber: PullNumber(msg),
+			Note:     note,
+		})
+	}
+	sortCommits(commits, g.Order)
+	return commits
+}
+
+// log returns the entries of git log in the range of two tags, formatted with
+// the format. The flags are added before the range.
+func (g *Git) log(ctx context.Context, tag1, tag2, format string, o logOptions, flags ...string) ([]string, error) {
+	if err := g.checkRange(ctx, tag1, tag2); err != nil {
+		return nil, err
+	}
+	rng := tag2
+	if tag1 != "" {
+		rng = g.RangeMode.Range(tag1, tag2)
+	}
+	return g.logRevs(ctx, g.logCommand(o, format, flags...).revFrom(rng, tag1, tag2))
+}
+
+// logCommand returns the git log command of the entries formatted with the
+// format, with the flags.
+func (g *Git) logCommand(o logOptions, format string, flags ...string) command {
+	c := newCommand("log", "--oneline", "--encoding=UTF-8").opt(o.args()...).opt(flags...)
+	return c.opt(fmt.Sprintf("--pretty=%s%s", commitSeparator, format))
+}
+
+// logRevs returns the entries of the git log command of logCommand, in the
+// Paths.
+func (g *Git) logRevs(ctx context.Context, c command) ([]string, error) {
+	out, err := g.run(ctx, c.path(g.Paths...))
 	if err != nil {
-		return errors.Wrap(err, "marshalling values")
+		return nil, err
+	}
+	// Windows tools might have committed the messages with CRLF line endings.
+	out = strings.ReplaceAll(out, "\r\n", "\n")
+	parts := strings.Split(out, commitSeparator)
+	return parts[1:], nil
+}
+
+// validUTF8 replaces the invalid UTF-8 sequences of s with U+FFFD.
+func (g *Git) validUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
 	}
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	title, _, _ := strings.Cut(s, "\n")
+	g.debugf("replaced invalid UTF-8 sequences in commit: %q", title)
+	g.Warnings.Add(WarnInvalidUTF8, "replaced invalid UTF-8 sequences in commit: %q", title)
+	return s
+}
 
-	client := github.NewClient(repo, token, nil)
-	client.SetBaseURL(baseURL)
-	reader := bytes.NewReader(payload)
-	req, err := client.NewRequest("POST", fmt.Sprintf("/repos/%s/%s/releases", user, repo), reader)
+// RepoInfo returns the owner and the name of the repository of the Remote.
+//
+// Deprecated: use RemoteInfo, which also returns the host and the scheme.
+func (g *Git) RepoInfo(ctx context.Context) (user, repo string, err error) {
+	r, err := g.RemoteInfo(ctx)
 	if err != nil {
-		return errors.Wrapf(err, "creating request to the API: %q", string(payload))
+		return "", "", err
 	}
-	req = req.WithContext(ctx)
+	return r.Owner, r.Name, nil
+}
 
-	resp, err := client.Do(req)
+// parseRemoteURL returns the owner and the repository name of a remote url.
+func parseRemoteURL(url string) (user, repo string, err error) {
+	r, err := parseRemote(url)
 	if err != nil {
-		return errors.Wrapf(err, "submitting to the API: %q", string(payload))
+		return "", "", err
 	}
-	// nolint:errcheck // it's ok.
-	defer resp.Body.Close()
+	return r.Owner, r.Name, nil
+}
+
+// ReleaseInfo contains everything required for publishing a release.
+type ReleaseInfo struct {
+	// User and Repo are the Owner and Name of the Remote.
+	User        string
+	Repo        string
+	Remote      RemoteInfo
+	Tag         string
+	PreviousTag string
+	// CompareURL is the compare page of the PreviousTag and the Tag, with the
+	// same RangeMode as the Logs.
+	CompareURL string
+	// ExtraRanges are the ExtraRanges of the Git whose commits are in the
+	// Commits too, with their To set. The CompareURL doesn't have them.
+	ExtraRanges []CommitRange
+	// Logs are the messages of the Commits.
+	Logs    []string
+	Commits []Commit
+	// Excluded is the number of the commits of the range that are left out
+	// with the Exclude of the Git.
+	Excluded int
+	// Initial is true if the Tag is the first release, which has no
+	// PreviousTag. Its Logs are all the commits up to the Tag, from the root
+	// commit, and its CompareURL is the list of the commits of the Tag.
+	Initial bool
+	// Untagged is true if the repository has no tags yet, and the Tag is the
+	// suggested InitialTag of the Git that is not created.
+	Untagged bool
+	// Target is the commit the Untagged Tag should be created on. It's
+	// only set by PrepareNext.
+	Target string
+	// Highlights are the curated notes of the release, see Highlights. They
+	// are put before the changes by RenderNotes.
+	Highlights string
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		if resp.StatusCode == 422 {
-			return errors.New("release already exists")
+// Prepare collects the information needed for releasing the tag. If the tag
+// is "@", the latest tag is used. Reads that don't depend on each other are
+// done concurrently. The commits are read from the Ranges if they are cached.
+// If there is no tag before the tag, the release is Initial and has all the
+// commits up to the tag. If the tag is "@" and there are no tags at all, the
+// commits are the ones up to the HEAD, and the Tag is the suggested
+// InitialTag. With a Channel, "@" is the latest tag of the channel. The
+// commits of the ExtraRanges are added to the ones since the previous tag. A
+// range that ends after the commit of the tag, e.g. at the HEAD of "@" that
+// has moved on, is reported with a WarnAheadOfTag. It returns
+// ErrEmptyRepository if the repository has no commits.
+func (g *Git) Prepare(ctx context.Context, tag string) (*ReleaseInfo, error) {
+	return g.prepare(ctx, tag, false)
+}
+
+// prepare is Prepare, with the remote optional for only printing the notes.
+// Without a remote the Remote and the CompareURL of the info are empty.
+func (g *Git) prepare(ctx context.Context, tag string, optionalRemote bool) (*ReleaseInfo, error) {
+	if tag == "@" && g.Channel != nil {
+		// The latest tag of the channel is not necessarily at the HEAD,
+		// e.g. when the nightly releases are more frequent.
+		latest, err := g.LatestTag(ctx)
+		if err != nil && !errors.Is(err, ErrNoTag) {
+			return nil, err
+		}
+		if latest != "" {
+			tag = latest
 		}
-		return fmt.Errorf("error publishing release with code: %q", resp.Status)
 	}
-	return nil
+	info := &ReleaseInfo{Tag: tag}
+	parent := ctx
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		var err error
+		info.Remote, err = g.RemoteInfo(ctx)
+		if err != nil && optionalRemote {
+			g.debugf("printing the notes without the remote: %v", err)
+			info.Remote = RemoteInfo{}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't get repo name: %w", err)
+		}
+		info.User, info.Repo = info.Remote.Owner, info.Remote.Name
+		return nil
+	})
+	eg.Go(func() error {
+		prev, err := g.PreviousTag(ctx, tag)
+		switch {
+		case errors.Is(err, ErrNoTag):
+			info.Initial = true
+		case err != nil:
+			return fmt.Errorf("getting previous tag: %w", err)
+		}
+		info.PreviousTag = prev
+		key, cached := g.cachedRange(ctx, tag)
+		if cached != nil {
+			info.Commits, info.Excluded = cached.Commits, cached.Excluded
+			info.Logs = Messages(info.Commits)
+			return nil
+		}
+		excluded, err := g.exclusions(ctx)
+		if err != nil {
+			return err
+		}
+		commits, err := g.Log(ctx, prev, tag)
+		if err != nil {
+			return err
+		}
+		commits, err = g.mergeRanges(ctx, commits, g.extraRanges(tag))
+		if err != nil {
+			return err
+		}
+		commits, info.Excluded = exclude(commits, excluded)
+		info.Commits = g.normalize(commits)
+		info.Logs = Messages(info.Commits)
+		g.storeRange(key, &cachedRange{Commits: info.Commits, Excluded: info.Excluded})
+		return nil
+	})
+	if tag == "@" {
+		eg.Go(func() error {
+			latest, err := g.LatestTag(ctx)
+			if errors.Is(err, ErrNoTag) {
+				info.Tag, info.Untagged = g.InitialTag(), true
+				return nil
+			}
+			info.Tag = latest
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, g.emptyError(parent, err)
+	}
+	info.ExtraRanges = g.extraRanges(info.Tag)
+	if err := g.checkUpperBounds(parent, info, tag); err != nil {
+		return nil, err
+	}
+	if info.Remote.Host == "" {
+		return info, nil
+	}
+	info.CompareURL = g.RangeMode.CompareURL(info.Remote, info.PreviousTag, info.Tag)
+	if info.Initial {
+		info.CompareURL = info.Remote.CommitsURL(info.Tag)
+	}
+	return info, nil
+}
+
+func (g *Git) debugf(format string, v ...any) {
+	if g.Logger != nil {
+		g.Logger.Printf(format, v...)
+	}
+}
+
+// run runs the git command in the repository and returns its output.
+func (g *Git) run(ctx context.Context, c command) (string, error) {
+	buf := &bytes.Buffer{}
+	err := g.exec(ctx, c, buf, nil)
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && gitErr.Stdout == "" {
+		gitErr.Stdout = buf.String()
+	}
+	return buf.String(), err
+}
+
+// exec runs the git command with its output written into the stdout. All git
+// processes are started here, after the revs of the command are checked. The
+// revs of the stdin are written into the standard input of git if the runner
+// is a StdinRunner. If stopped is not nil and returns true, the failure of git
+// is expected, since the stdout has stopped reading its output.
+func (g *Git) exec(ctx context.Context, c command, stdout io.Writer, stopped func() bool) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	args := c.argv()
+	if err := g.checkOffline(args); err != nil {
+		return err
+	}
+	ctx, cancel := g.processContext(ctx)
+	defer cancel()
+	defer g.timed(args)()
+	defer g.Metrics.gitCommand()()
+	out := &traceBuffer{}
+	w := io.MultiWriter(out, stdout)
+	start := time.Now()
+	var err error
+	if r, ok := g.runner().(StdinRunner); ok && c.stdin != nil {
+		stdin := strings.NewReader(strings.Join(c.stdin, "\n") + "\n")
+		err = r.RunStdin(ctx, g.Dir, stdin, w, g.gitArgs(args)...)
+	} else {
+		err = g.runner().Run(ctx, g.Dir, w, g.gitArgs(args)...)
+	}
+	if err != nil && stopped != nil && stopped() {
+		err = nil
+	}
+	err = gitError(err, args, "")
+	var gitErr *GitError
+	if ctx.Err() != nil && errors.As(err, &gitErr) {
+		// The process is killed by the context, whose error tells why.
+		gitErr.Err = ctx.Err()
+	}
+	g.Trace.record(g.Dir, g.gitArgs(args), start, out.String(), err)
+	return err
+}
+
+// gitArgs returns the args of a git process with the options of the Git.
+func (g *Git) gitArgs(args []string) []string {
+	var opts []string
+	if g.GitDir != "" {
+		opts = append(opts, "--git-dir="+g.GitDir)
+	}
+	if g.WorkTree != "" {
+		opts = append(opts, "--work-tree="+g.WorkTree)
+	}
+	if g.ReplaceRefs {
+		opts = append(opts, "-c", "core.useReplaceRefs=true")
+	}
+	if len(opts) == 0 {
+		return args
+	}
+	return append(opts, args...)
+}
+
+// timed returns a function that reports the git process of the args to the
+// SlowLogger if it is called after the SlowThreshold.
+func (g *Git) timed(args []string) func() {
+	if g.SlowThreshold <= 0 || (g.SlowLogger == nil && g.Warnings == nil) {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		if d <= g.SlowThreshold {
+			return
+		}
+		msg := fmt.Sprintf("git %s took %s", strings.Join(args, " "), d.Round(time.Millisecond))
+		if g.SlowLogger != nil {
+			g.SlowLogger.Printf("%s", msg)
+		}
+		g.Warnings.Add(WarnSlowGit, "%s", msg)
+	}
+}
+
+// processContext returns the context of a git process, which is cancelled
+// after the Timeout if it is set.
+func (g *Git) processContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.Timeout > 0 {
+		return context.WithTimeout(ctx, g.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// remote returns the Remote, or origin if it's not set.
+func (g *Git) remote() string {
+	if g.Remote != "" {
+		return g.Remote
+	}
+	return "origin"
+}
+
+func (g *Git) runner() Runner {
+	if g.Runner != nil {
+		return g.Runner
+	}
+	return execRunner{}
+}
+
+// loadRemotes returns the url of all remotes keyed by their names.
+func (g *Git) loadRemotes(ctx context.Context) (map[string]string, error) {
+	g.mu.Lock()
+	if g.remotes == nil {
+		g.remotes = &remotesResult{}
+	}
+	r := g.remotes
+	g.mu.Unlock()
+
+	r.once.Do(func() {
+		r.urls = make(map[string]string)
+		out, err := g.run(ctx, newCommand("config", "--get-regexp").rev(`^remote\..*\.url$`))
+		if err != nil {
+			if hasExitCode(err, 1) {
+				// There are no remotes.
+				return
+			}
+			r.err = err
+			return
+		}
+		for _, line := range splitLines(strings.TrimSpace(out)) {
+			key, url, ok := strings.Cut(strings.TrimSpace(line), " ")
+			if !ok {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+			r.urls[name] = url
+		}
+	})
+	if contextError(r.err) {
+		g.mu.Lock()
+		if g.remotes == r {
+			g.remotes = nil
+		}
+		g.mu.Unlock()
+	}
+	return r.urls, r.err
+}
+
+// contextError returns true if the err is of a cancelled or an expired
+// context. These errors are not cached, therefore the next call tries again,
+// e.g. after a sibling of an errgroup has cancelled the first one.
+func contextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// walk returns the tagged commits reachable from the rev, nearest first. It
+// stops as soon as it finds a tagged commit other than the rev itself, which
+// is all LatestTag and PreviousTag need to know. The tags are read from the
+// decorations of the same log, therefore it only spawns one process.
+func (g *Git) walk(ctx context.Context, rev string) (*walkResult, error) {
+	return g.walkChannel(ctx, rev, g.Channel)
+}
+
+// walkChannel is like walk, but only considers the tags of the ch.
+func (g *Git) walkChannel(ctx context.Context, rev string, ch *Channel) (*walkResult, error) {
+	if rev == "@" {
+		rev = "HEAD"
+	}
+	key := fmt.Sprintf("%s\x00%s\x00%v\x00%t\x00%s", rev, g.TagPrefix, g.Scheme, g.AnnotatedOnly, ch.key())
+	g.mu.Lock()
+	if g.walks == nil {
+		g.walks = make(map[string]*walkResult)
+	}
+	w, ok := g.walks[key]
+	if !ok {
+		w = &walkResult{}
+		g.walks[key] = w
+	}
+	g.mu.Unlock()
+
+	w.once.Do(func() {
+		var annotated map[string]bool
+		if g.AnnotatedOnly {
+			annotated, w.err = g.annotatedTags(ctx)
+			if w.err != nil {
+				return
+			}
+		}
+		w.head, w.tagged, w.err = g.tagWalk(ctx, rev, func(tag string) bool {
+			return g.matchChannelTag(tag, ch) && (annotated == nil || annotated[tag])
+		})
+	})
+	if contextError(w.err) {
+		g.mu.Lock()
+		if g.walks[key] == w {
+			delete(g.walks, key)
+		}
+		g.mu.Unlock()
+	}
+	return w, w.err
+}
+
+// tagWalk only considers the tags that match returns true for.
+func (g *Git) tagWalk(ctx context.Context, rev string, match func(tag string) bool) (head string, tagged []taggedCommit, err error) {
+	cmd := newCommand("log", "--format=%H%x00%D", "--decorate-refs="+g.decorateRefs()).rev(rev).path()
+	w := &lineWriter{fn: func(line string) bool {
+		sha, refs, _ := strings.Cut(line, "\x00")
+		if head == "" {
+			head = sha
+		}
+		if refs == "" {
+			return true
+		}
+		c := taggedCommit{sha: sha}
+		for _, ref := range strings.Split(refs, ", ") {
+			tag := strings.TrimPrefix(ref, "tag: ")
+			if match(tag) {
+				c.tags = append(c.tags, tag)
+			}
+		}
+		if len(c.tags) == 0 {
+			return true
+		}
+		tagged = append(tagged, c)
+		// With SinceLastStable, the walk goes on to the nearest stable tag.
+		return sha == head || (g.SinceLastStable && g.prereleases(c.tags))
+	}}
+	// When the writer stops, git fails on writing into a closed pipe. This is
+	// the only way to stop it when we have found what we need.
+	err = g.exec(ctx, cmd, w, func() bool { return w.stopped })
+	if err != nil {
+		return "", nil, err
+	}
+	w.Flush()
+	return head, tagged, nil
 }
@@ -0,0 +1,7 @@
+package commit
+
+// ParseRemoteURL is exported for testing.
+var ParseRemoteURL = parseRemoteURL
+
+// ParseRemote is exported for testing.
+var ParseRemote = parseRemote
@@ -2,11 +2,18 @@ package commit_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
 	"github.com/blokur/testament"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -17,8 +24,21 @@ func TestGit(t *testing.T) {
 	t.Parallel()
 	t.Run("LatestTag", testGitLatestTag)
 	t.Run("PreviousTag", testGitPreviousTag)
+	t.Run("SinceLastStable", testGitSinceLastStable)
+	t.Run("UnreachableTag", testGitUnreachableTag)
 	t.Run("Commits", testGitCommits)
+	t.Run("CommitsEncoding", testGitCommitsEncoding)
+	t.Run("Log", testGitLog)
 	t.Run("RepoInfo", testGitRepoInfo)
+	t.Run("Prepare", testGitPrepare)
+	t.Run("Cache", testGitCache)
+	t.Run("CancelledCache", testGitCancelledCache)
+	t.Run("CRLF", testGitCRLF)
+	t.Run("DetachedHead", testGitDetachedHead)
+	t.Run("IsAncestor", testGitIsAncestor)
+	t.Run("RangeMode", testGitRangeMode)
+	t.Run("CompareURL", testGitRangeModeCompareURL)
+	t.Run("GitError", testGitGitError)
 }
 
 func testGitLatestTag(t *testing.T) {
@@ -37,6 +57,7 @@ func testGitLatestTag(t *testing.T) {
 	commitChanges(t, dir, testament.RandomString(20))
 	createGitTag(t, dir, "v0.0.1")
 
+	g.Refresh()
 	got, err := g.LatestTag(ctx)
 	require.NoError(t, err)
 	assert.Equal(t, "v0.0.1", got)
@@ -47,11 +68,44 @@ func testGitLatestTag(t *testing.T) {
 
 	createFile(t, dir, "file3.txt", testament.RandomString(20))
 	commitChanges(t, dir, testament.RandomString(20))
+	g.Refresh()
 	got, err = g.LatestTag(ctx)
 	require.NoError(t, err)
 	assert.Equal(t, "v0.0.2", got)
 }
 
+// testGitCancelledCache checks that the errors of a cancelled context are not
+// cached, therefore the next calls with a live context succeed without a
+// Refresh.
+func testGitCancelledCache(t *testing.T) {
+	t.Parallel()
+	dir := createGitRepo(t)
+	addRemote(t, dir, "origin", "git@github.com:arsham/gitrelease.git")
+	commitChanges(t, dir, "feat: thing")
+	createGitTag(t, dir, "v1.0.0")
+	g := &commit.Git{Dir: dir}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := g.LatestTag(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = g.Tags(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = g.RemoteInfo(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	ctx := context.Background()
+	tag, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+	tags, err := g.Tags(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+	r, err := g.RemoteInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "gitrelease", r.Name)
+}
+
 func testGitPreviousTag(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -72,17 +126,125 @@ func testGitPreviousTag(t *testing.T) {
 	commitChanges(t, dir, testament.RandomString(20))
 	createGitTag(t, dir, "v0.0.2")
 
+	g.Refresh()
 	got, err := g.PreviousTag(ctx, "v0.0.2")
 	require.NoError(t, err)
 	assert.Equal(t, "v0.0.1", got)
 
 	createFile(t, dir, "file3.txt", testament.RandomString(20))
 	commitChanges(t, dir, testament.RandomString(20))
+	g.Refresh()
 	got, err = g.PreviousTag(ctx, "@")
 	require.NoError(t, err)
 	assert.Equal(t, "v0.0.2", got)
 }
 
+// testGitSinceLastStable creates the release candidates of v1.5.0 after
+// v1.4.0:
+//
+//	v1.4.0 -- v1.5.0-rc.1 -- v1.5.0-rc.2 -- v1.5.0 -- v1.5.1
+func testGitSinceLastStable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := repo(t, createGitRepo(t))
+	for _, tag := range []string{"v1.4.0", "v1.5.0-rc.1", "v1.5.0-rc.2", "v1.5.0", "v1.5.1"} {
+		r.Commit("feat: " + tag)
+		r.Tag(tag)
+	}
+	r.AddRemote("origin", "https://github.com/arsham/gitrelease.git")
+	tcs := map[string]struct {
+		tag    string
+		stable bool
+		want   string
+	}{
+		"rc to rc":             {tag: "v1.5.0-rc.2", stable: true, want: "v1.5.0-rc.1"},
+		"stable to rc":         {tag: "v1.5.0-rc.1", stable: true, want: "v1.4.0"},
+		"rc to stable":         {tag: "v1.5.0", stable: true, want: "v1.4.0"},
+		"stable to stable":     {tag: "v1.5.1", stable: true, want: "v1.5.0"},
+		"rc to stable off":     {tag: "v1.5.0", want: "v1.5.0-rc.2"},
+		"stable to stable off": {tag: "v1.5.1", want: "v1.5.0"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := &commit.Git{Dir: r.Dir, SinceLastStable: tc.stable}
+			got, err := g.PreviousTag(ctx, tc.tag)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("Prepare", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir, SinceLastStable: true}
+		info, err := g.Prepare(ctx, "v1.5.0")
+		require.NoError(t, err)
+		assert.Equal(t, "v1.4.0", info.PreviousTag)
+		assert.Len(t, info.Logs, 3, "the changes of the release candidates are included")
+	})
+
+	t.Run("Only pre-releases", func(t *testing.T) {
+		t.Parallel()
+		r := repo(t, createGitRepo(t))
+		r.Commit("feat: the thing")
+		r.Tag("v0.1.0-rc.1")
+		r.Commit("fix: the leak")
+		r.Tag("v0.1.0")
+		g := &commit.Git{Dir: r.Dir, SinceLastStable: true}
+		_, err := g.PreviousTag(ctx, "v0.1.0")
+		assert.ErrorIs(t, err, commit.ErrNoTag)
+	})
+}
+
+// testGitUnreachableTag creates a newer tag with a higher version on a branch
+// that is not merged, which must not be chosen:
+//
+//	v1.0.0 -- fix -- v1.1.0 -- feat (master)
+//	      \
+//	       experiment -- v9.0.0 (experiment)
+func testGitUnreachableTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	runGit(t, dir, "checkout", "-q", "-b", "master")
+	createFile(t, dir, "file.txt", "1")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	runGit(t, dir, "checkout", "-q", "-b", "experiment")
+	createFile(t, dir, "other.txt", "1")
+	commitChanges(t, dir, "feat: experiment")
+	runGit(t, dir, "checkout", "-q", "master")
+	createFile(t, dir, "file.txt", "2")
+	commitChanges(t, dir, "fix: thing")
+	createGitTag(t, dir, "v1.1.0")
+	runGit(t, dir, "tag", "-a", "-m", "experiment", "v9.0.0", "experiment")
+	createFile(t, dir, "file.txt", "3")
+	commitChanges(t, dir, "feat: thing")
+
+	g := &commit.Git{Dir: dir}
+	got, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", got)
+	got, err = g.PreviousTag(ctx, "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", got)
+	got, err = g.PreviousTag(ctx, "v9.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", got)
+	info, err := g.ReleasesBetween(ctx, "v1.0.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, info, 2)
+	assert.Equal(t, "v1.1.0", info[1].Tag)
+
+	// The tag is considered once its branch is merged.
+	runGit(t, dir, "merge", "-q", "--no-edit", "--no-ff", "experiment")
+	g.Refresh()
+	got, err = g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v9.0.0", got)
+}
+
 func testGitCommits(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -111,6 +273,131 @@ func testGitCommits(t *testing.T) {
 	if diff := cmp.Diff(msgs, got, commitComparer...); diff != "" {
 		t.Errorf("(-want +got):\n%s", diff)
 	}
+	assert.Len(t, got, len(msgs))
+
+	createGitTag(t, dir, "v0.0.3")
+	got, err = g.Commits(ctx, "v0.0.2", "v0.0.3")
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+
+	got, err = g.Commits(ctx, "v0.0.2", "v0.0.2")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testGitLog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	g := commit.Git{Dir: dir}
+
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+	appendToFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "feat: subject\n\nbody")
+	sha := strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+
+	got, err := g.Log(ctx, "v0.0.1", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, sha, got[0].SHA)
+	assert.Equal(t, "arsham <arsham@github.com>", got[0].Author)
+	assert.Equal(t, "feat: subject", got[0].Subject())
+	assert.Contains(t, got[0].Message, "body")
+
+	got, err = g.Log(ctx, "", "HEAD")
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	got, err = g.Log(ctx, "HEAD", "HEAD")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testGitCommitsEncoding(t *testing.T) {
+	t.Run("Declared", testGitCommitsEncodingDeclared)
+	t.Run("Invalid", testGitCommitsEncodingInvalid)
+}
+
+func testGitCommitsEncodingDeclared(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	logger := &logRecorder{}
+	g := commit.Git{
+		Dir:    dir,
+		Logger: logger,
+	}
+
+	filename := "file.txt"
+	createFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+
+	// With this setting git also prints the logs in ISO-8859-1 by default.
+	gitConfig(t, dir, "i18n.commitEncoding", "ISO-8859-1")
+	appendToFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "fix: caf\xe9 cr\xe8me")
+	createGitTag(t, dir, "v0.0.2")
+
+	got, err := g.Commits(ctx, "v0.0.1", "v0.0.2")
+	require.NoError(t, err)
+	want := []string{"fix: café crème"}
+	if diff := cmp.Diff(want, got, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Empty(t, logger.Lines())
+}
+
+func testGitCommitsEncodingInvalid(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	logger := &logRecorder{}
+	g := commit.Git{
+		Dir:    dir,
+		Logger: logger,
+	}
+
+	// git commit fixes invalid messages, but other tools might not.
+	msg := "fix: caf\xe9 cr\xe8me"
+	stream := strings.Join([]string{
+		"commit refs/heads/main",
+		"mark :1",
+		"committer arsham <arsham@github.com> 1600000000 +0000",
+		"data 4",
+		"msg1",
+		"M 644 inline file.txt",
+		"data 1",
+		"1",
+		"",
+		"reset refs/tags/v0.0.1",
+		"from :1",
+		"",
+		"commit refs/heads/main",
+		"mark :2",
+		"committer arsham <arsham@github.com> 1600000001 +0000",
+		fmt.Sprintf("data %d", len(msg)),
+		msg,
+		"from :1",
+		"M 644 inline file.txt",
+		"data 1",
+		"2",
+		"",
+	}, "\n")
+	fastImport(t, dir, "main", stream)
+
+	got, err := g.Commits(ctx, "v0.0.1", "HEAD")
+	require.NoError(t, err)
+	want := []string{"fix: caf\uFFFD cr\uFFFDme"}
+	if diff := cmp.Diff(want, got, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	lines := logger.Lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "invalid UTF-8")
 }
 
 func testGitRepoInfo(t *testing.T) {
@@ -217,3 +504,759 @@ func testGitRepoInfoRemote(t *testing.T) {
 	assert.Equal(t, "arsham", user)
 	assert.Equal(t, "arshlib.nvim", repo)
 }
+
+func testGitPrepare(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	addRemote(t, dir, "origin", "git@github.com:arsham/gitrelease.git")
+
+	g := commit.Git{
+		Dir: dir,
+	}
+
+	filename := "file.txt"
+	createFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+
+	msgs := []string{"msg2", "msg3"}
+	for _, msg := range msgs {
+		appendToFile(t, dir, filename, testament.RandomString(20))
+		commitChanges(t, dir, msg)
+	}
+	createGitTag(t, dir, "v0.0.2")
+
+	info, err := g.Prepare(ctx, "@")
+	require.NoError(t, err)
+	assert.Equal(t, "arsham", info.User)
+	assert.Equal(t, "gitrelease", info.Repo)
+	assert.Equal(t, "v0.0.2", info.Tag)
+	assert.Equal(t, "v0.0.1", info.PreviousTag)
+	if diff := cmp.Diff(msgs, info.Logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Equal(t, "https://github.com/arsham/gitrelease/compare/v0.0.1...v0.0.2", info.CompareURL)
+
+	info, err = g.Prepare(ctx, "v0.0.1")
+	require.NoError(t, err)
+	assert.True(t, info.Initial)
+	assert.False(t, info.Untagged)
+	assert.Empty(t, info.PreviousTag)
+	if diff := cmp.Diff([]string{"msg1"}, info.Logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Equal(t, "https://github.com/arsham/gitrelease/commits/v0.0.1", info.CompareURL)
+}
+
+func testGitCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	addRemote(t, dir, "origin", "git@github.com:arsham/shark.git")
+
+	g := commit.Git{
+		Dir: dir,
+	}
+
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, testament.RandomString(20))
+	createGitTag(t, dir, "v0.0.1")
+
+	got, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.1", got)
+	user, repo, err := g.RepoInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "arsham", user)
+	assert.Equal(t, "shark", repo)
+
+	createFile(t, dir, "file2.txt", testament.RandomString(20))
+	commitChanges(t, dir, testament.RandomString(20))
+	createGitTag(t, dir, "v0.0.2")
+	addRemote(t, dir, "other", "git@github.com:arsham/arshlib.nvim.git")
+	g.Remote = "other"
+
+	got, err = g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.1", got, "should have been cached")
+	_, _, err = g.RepoInfo(ctx)
+	assert.Error(t, err, "should have been cached")
+
+	g.Refresh()
+	got, err = g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.2", got)
+	user, repo, err = g.RepoInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "arsham", user)
+	assert.Equal(t, "arshlib.nvim", repo)
+}
+
+func testGitCRLF(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	separator := "00000000000000000000000000000000000"
+	g := commit.Git{
+		Runner: fakeRunner(func(args []string) (string, error) {
+			switch {
+			case args[0] == "config":
+				return "remote.origin.url git@github.com:arsham/gitrelease.git\r\n", nil
+			case args[0] == "rev-parse":
+				return "aaa\nccc\n", nil
+			case args[0] == "log" && args[1] == "--oneline":
+				return separator + "aaa\x00arsham <arsham@github.com>\x001600000001\x00fix(repo): something\r\n\r\nClose #12\r\n" +
+					separator + "bbb\x00arsham <arsham@github.com>\x001600000000\x00feat: else\r\n", nil
+			case args[0] == "log":
+				return "aaa\x00tag: v0.0.3\r\nbbb\x00\r\nccc\x00tag: v0.0.2, tag: v0.0.1\r\nddd\x00tag: v0.0.0\r\n", nil
+			}
+			return "", fmt.Errorf("unexpected command: %v", args)
+		}),
+	}
+
+	user, repo, err := g.RepoInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "arsham", user)
+	assert.Equal(t, "gitrelease", repo)
+
+	got, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.3", got)
+
+	got, err = g.PreviousTag(ctx, "@")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.2", got)
+
+	logs, err := g.Commits(ctx, "v0.0.2", "v0.0.3")
+	require.NoError(t, err)
+	want := []string{"fix(repo): something\n\nClose #12", "feat: else"}
+	if diff := cmp.Diff(want, logs, stringSliceCleaner); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGitDetachedHead(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	g := commit.Git{
+		Dir: dir,
+	}
+
+	filename := "file.txt"
+	createFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg0")
+	appendToFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+	appendToFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg2")
+	createGitTag(t, dir, "v0.0.2")
+	appendToFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg3")
+
+	head, err := g.Head(ctx)
+	require.NoError(t, err)
+	require.False(t, head.Detached())
+	branch := head.Branch
+	assert.NoError(t, g.CheckBranch(ctx, branch, "v0.0.2"))
+	err = g.CheckBranch(ctx, "other", "v0.0.2")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, commit.ErrDetachedHead)
+	assert.Contains(t, err.Error(), branch)
+
+	runGit(t, dir, "checkout", "-q", "v0.0.2")
+	g.Refresh()
+
+	head, err = g.Head(ctx)
+	require.NoError(t, err)
+	assert.True(t, head.Detached())
+	assert.Equal(t, []string{"v0.0.2"}, head.Tags)
+	assert.Equal(t, "detached at tag v0.0.2", head.String())
+
+	got, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.2", got)
+	got, err = g.PreviousTag(ctx, "@")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.1", got)
+	logs, err := g.Commits(ctx, "v0.0.1", "@")
+	require.NoError(t, err)
+	if diff := cmp.Diff([]string{"msg2"}, logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	assert.NoError(t, g.CheckBranch(ctx, branch, "v0.0.2"))
+	err = g.CheckBranch(ctx, branch, "v0.0.3")
+	assert.ErrorIs(t, err, commit.ErrDetachedHead)
+	assert.Contains(t, err.Error(), "detached at tag v0.0.2")
+
+	runGit(t, dir, "checkout", "-q", "v0.0.1~1")
+	g.Refresh()
+	err = g.CheckBranch(ctx, branch, "v0.0.1")
+	assert.ErrorIs(t, err, commit.ErrDetachedHead)
+	_, err = g.PreviousTag(ctx, "@")
+	assert.ErrorIs(t, err, commit.ErrNoTag)
+	_, err = g.LatestTag(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "detached at")
+}
+
+func testGitIsAncestor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	g := commit.Git{
+		Dir: dir,
+	}
+
+	filename := "file.txt"
+	createFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+	mainBranch := strings.TrimSpace(runGit(t, dir, "branch", "--show-current"))
+
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	appendToFile(t, dir, filename, testament.RandomString(20))
+	commitChanges(t, dir, "msg2")
+	runGit(t, dir, "tag", "-a", "v0.0.2", "-m", "v0.0.2")
+	sha := strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+
+	ok, err := g.IsAncestor(ctx, "v0.0.1", mainBranch)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = g.IsAncestor(ctx, "v0.0.2", mainBranch)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = g.IsAncestor(ctx, "v0.0.2", "feature")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = g.IsAncestor(ctx, "v0.0.3", mainBranch)
+	var gitErr *commit.GitError
+	require.ErrorAs(t, err, &gitErr)
+	assert.Equal(t, []string{"merge-base", "--is-ancestor", "v0.0.3", mainBranch}, gitErr.Args)
+	assert.NotZero(t, gitErr.ExitCode)
+
+	assert.NoError(t, g.CheckReachable(ctx, "v0.0.1", mainBranch))
+	assert.NoError(t, g.CheckReachable(ctx, "v0.0.2", "feature"))
+	err = g.CheckReachable(ctx, "v0.0.2", mainBranch)
+	require.ErrorIs(t, err, commit.ErrNotOnBranch)
+	assert.Contains(t, err.Error(), sha)
+	assert.Contains(t, err.Error(), mainBranch)
+	assert.Error(t, g.CheckReachable(ctx, "v0.0.3", mainBranch))
+}
+
+func testGitGitError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("Exec", func(t *testing.T) {
+		t.Parallel()
+		dir := createGitRepo(t)
+		g := commit.Git{Dir: dir}
+		createFile(t, dir, "file.txt", testament.RandomString(20))
+		commitChanges(t, dir, "msg")
+		createGitTag(t, dir, "v0.0.1")
+
+		err := g.CreateTag(ctx, "v0.0.1", "HEAD")
+		var gitErr *commit.GitError
+		require.ErrorAs(t, err, &gitErr)
+		assert.Equal(t, []string{"tag", "v0.0.1", "HEAD"}, gitErr.Args)
+		assert.Equal(t, 128, gitErr.ExitCode)
+		assert.Contains(t, gitErr.Stderr, "already exists")
+		var exitErr *exec.ExitError
+		assert.ErrorAs(t, err, &exitErr)
+	})
+
+	t.Run("Runner", func(t *testing.T) {
+		t.Parallel()
+		errBoom := errors.New("boom")
+		g := commit.Git{
+			Runner: fakeRunner(func([]string) (string, error) {
+				return "", errBoom
+			}),
+		}
+		err := g.DeleteTag(ctx, "v0.0.1")
+		var gitErr *commit.GitError
+		require.ErrorAs(t, err, &gitErr)
+		assert.Equal(t, []string{"tag", "-d", "v0.0.1"}, gitErr.Args)
+		assert.Equal(t, -1, gitErr.ExitCode)
+		assert.ErrorIs(t, err, errBoom)
+	})
+
+	t.Run("Colored", func(t *testing.T) {
+		t.Parallel()
+		g := commit.Git{
+			Runner: fakeRunner(func(args []string) (string, error) {
+				return "", &commit.GitError{
+					Args:     args,
+					ExitCode: 128,
+					Stderr:   "\x1b]0;git\x07\x1b[1;31mfatal:\x1b[m tag 'v0.0.1' already\x00 exists\r\n\x1b[33mhint:\x1b[m\tsee git help\n",
+					Err:      errors.New("exit status 128"),
+				}
+			}),
+		}
+		err := g.CreateTag(ctx, "v0.0.1", "HEAD")
+		require.Error(t, err)
+		assert.Equal(t, "creating tag v0.0.1: git tag v0.0.1 HEAD: exit status 128: fatal: tag 'v0.0.1' already exists\nhint:\tsee git help", err.Error())
+		var gitErr *commit.GitError
+		require.ErrorAs(t, err, &gitErr)
+		assert.Contains(t, gitErr.Stderr, "\x1b[1;31m", "the captured output is kept as it is")
+	})
+
+	t.Run("Long", func(t *testing.T) {
+		t.Parallel()
+		long := strings.Repeat("é", 1<<20) + "\nfatal: the end"
+		g := commit.Git{
+			Runner: fakeRunner(func(args []string) (string, error) {
+				return "", &commit.GitError{Args: args, ExitCode: 128, Stderr: long, Err: errors.New("exit status 128")}
+			}),
+		}
+		err := g.DeleteTag(ctx, "v0.0.1")
+		require.Error(t, err)
+		msg := err.Error()
+		assert.Less(t, len(msg), 5<<10)
+		assert.True(t, utf8.ValidString(msg))
+		assert.Contains(t, msg, "bytes truncated] …")
+		assert.True(t, strings.HasSuffix(msg, "fatal: the end"), msg[len(msg)-50:])
+	})
+
+	t.Run("ColorConfig", func(t *testing.T) {
+		t.Parallel()
+		dir := createGitRepo(t)
+		runGit(t, dir, "config", "color.ui", "always")
+		commitChanges(t, dir, "msg")
+		g := commit.Git{Dir: dir}
+		_, err := g.Commits(ctx, "v9.9.9", "HEAD")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "\x1b")
+		head, err := g.Head(ctx)
+		require.NoError(t, err)
+		assert.Len(t, head.Commit, 40)
+	})
+}
+
+func testGitRangeMode(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+
+	// main:        v1.3.0 -- feat1 -- feat2 (v1.4.0)
+	//                    \
+	// release/1.3:        fix1 -- fix2 (v1.3.1)
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.3.0")
+	mainBranch := strings.TrimSpace(runGit(t, dir, "branch", "--show-current"))
+
+	runGit(t, dir, "checkout", "-q", "-b", "release/1.3")
+	fixes := []string{"fix: fix1", "fix: fix2"}
+	for _, msg := range fixes {
+		appendToFile(t, dir, "fix.txt", testament.RandomString(20))
+		commitChanges(t, dir, msg)
+	}
+	createGitTag(t, dir, "v1.3.1")
+
+	runGit(t, dir, "checkout", "-q", mainBranch)
+	feats := []string{"feat: feat1", "feat: feat2"}
+	for _, msg := range feats {
+		appendToFile(t, dir, "feat.txt", testament.RandomString(20))
+		commitChanges(t, dir, msg)
+	}
+	createGitTag(t, dir, "v1.4.0")
+
+	g := commit.Git{
+		Dir: dir,
+	}
+	logs, err := g.Commits(ctx, "v1.4.0", "v1.3.1")
+	require.NoError(t, err)
+	if diff := cmp.Diff(fixes, logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	g.RangeMode = commit.RangeSymmetric
+	logs, err = g.Commits(ctx, "v1.4.0", "v1.3.1")
+	require.NoError(t, err)
+	want := append(append([]string{}, fixes...), feats...)
+	if diff := cmp.Diff(want, logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	// On a linear history both modes are the same.
+	logs, err = g.Commits(ctx, "v1.3.0", "v1.4.0")
+	require.NoError(t, err)
+	if diff := cmp.Diff(feats, logs, commitComparer...); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGitRangeModeCompareURL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	remote := commit.RemoteInfo{Host: "github.com", Owner: "arsham", Name: "gitrelease", Scheme: "https"}
+
+	// main:        v1.3.0 -- feat1 (v1.4.0)
+	//                    \
+	// release/1.3:        fix1 (v1.3.1)
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "initial")
+	createGitTag(t, dir, "v1.3.0")
+	mainBranch := strings.TrimSpace(runGit(t, dir, "branch", "--show-current"))
+	runGit(t, dir, "checkout", "-q", "-b", "release/1.3")
+	appendToFile(t, dir, "fix.txt", testament.RandomString(20))
+	commitChanges(t, dir, "fix: fix1")
+	createGitTag(t, dir, "v1.3.1")
+	runGit(t, dir, "checkout", "-q", mainBranch)
+	appendToFile(t, dir, "feat.txt", testament.RandomString(20))
+	commitChanges(t, dir, "feat: feat1")
+	createGitTag(t, dir, "v1.4.0")
+
+	// GitHub lists the commits of the second tag since the merge base with
+	// both notations.
+	base := strings.TrimSpace(runGit(t, dir, "merge-base", "v1.3.1", "v1.4.0"))
+	listed := strings.Fields(runGit(t, dir, "rev-list", base+"..v1.4.0"))
+
+	tcs := map[string]struct {
+		mode       commit.RangeMode
+		wantURL    string
+		wantLogs   []string
+		wantListed bool
+	}{
+		"two-dot": {
+			mode:       commit.RangeTwoDot,
+			wantURL:    "https://github.com/arsham/gitrelease/compare/v1.3.1...v1.4.0",
+			wantLogs:   []string{"feat: feat1"},
+			wantListed: true,
+		},
+		"three-dot": {
+			mode:     commit.RangeSymmetric,
+			wantURL:  "https://github.com/arsham/gitrelease/compare/v1.3.1..v1.4.0",
+			wantLogs: []string{"feat: feat1", "fix: fix1"},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.Git{Dir: dir, RangeMode: tc.mode}
+			u := tc.mode.CompareURL(remote, "v1.3.1", "v1.4.0")
+			assert.Equal(t, tc.wantURL, u)
+
+			got, err := g.Log(ctx, "v1.3.1", "v1.4.0")
+			require.NoError(t, err)
+			subjects := make([]string, len(got))
+			shas := make([]string, len(got))
+			for i, c := range got {
+				subjects[i] = c.Subject()
+				shas[i] = c.SHA
+			}
+			assert.ElementsMatch(t, tc.wantLogs, subjects)
+
+			// The page lists all the commits of the notes only in the
+			// two-dot mode, and never the ones of the first tag's branch.
+			assert.Subset(t, shas, listed)
+			if tc.wantListed {
+				assert.ElementsMatch(t, listed, shas)
+				return
+			}
+			assert.NotSubset(t, listed, shas)
+		})
+	}
+}
+
+func TestParseRangeMode(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		input   string
+		want    commit.RangeMode
+		wantErr bool
+	}{
+		"empty":     {input: "", want: commit.RangeTwoDot},
+		"two-dot":   {input: "two-dot", want: commit.RangeTwoDot},
+		"two dots":  {input: "..", want: commit.RangeTwoDot},
+		"three-dot": {input: "three-dot", want: commit.RangeSymmetric},
+		"dots":      {input: "...", want: commit.RangeSymmetric},
+		"unknown":   {input: "four-dot", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseRangeMode(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRangeModeRange(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1..v2", commit.RangeTwoDot.Range("v1", "v2"))
+	assert.Equal(t, "v1...v2", commit.RangeSymmetric.Range("v1", "v2"))
+	assert.Equal(t, "two-dot", commit.RangeTwoDot.String())
+	assert.Equal(t, "three-dot", commit.RangeSymmetric.String())
+}
+
+func TestRangeModeCompareURL(t *testing.T) {
+	t.Parallel()
+	remote := commit.RemoteInfo{Host: "ghe.example.com", Owner: "arsham", Name: "gitrelease", Scheme: "ssh"}
+	assert.Equal(t,
+		"https://ghe.example.com/arsham/gitrelease/compare/mod/v1.0.0...mod/v1.1.0",
+		commit.RangeTwoDot.CompareURL(remote, "mod/v1.0.0", "mod/v1.1.0"),
+	)
+	assert.Equal(t,
+		"https://ghe.example.com/arsham/gitrelease/compare/v1.0.0..v1.1.0%23rc",
+		commit.RangeSymmetric.CompareURL(remote, "v1.0.0", "v1.1.0#rc"),
+	)
+
+	remote = commit.RemoteInfo{Host: "ssh.dev.azure.com", Owner: "org", Project: "My Project", Name: "repo", Scheme: "ssh"}
+	want := "https://dev.azure.com/org/My%20Project/_git/repo/branchCompare?baseVersion=GTv1.0.0&targetVersion=GTmod%2Fv1.1.0"
+	assert.Equal(t, want, commit.RangeTwoDot.CompareURL(remote, "v1.0.0", "mod/v1.1.0"))
+	assert.Equal(t, want, commit.RangeSymmetric.CompareURL(remote, "v1.0.0", "mod/v1.1.0"))
+
+	remote = commit.RemoteInfo{Host: "bitbucket.org", Owner: "team", Name: "repo", Scheme: "ssh"}
+	assert.Equal(t,
+		"https://bitbucket.org/team/repo/branches/compare/v1.1.0%0Dv1.0.0",
+		commit.RangeTwoDot.CompareURL(remote, "v1.0.0", "v1.1.0"),
+	)
+}
+
+func TestGitCountCommits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial", committest.File{Path: "a/file.txt", Content: "0"})
+	r.Tag("v0.1.0")
+	r.Commit("feat: a", committest.File{Path: "a/file.txt", Content: "1"})
+	r.Commit("feat: b", committest.File{Path: "b/file.txt", Content: "1"})
+	r.Commit("fix: a", committest.File{Path: "a/file.txt", Content: "2"})
+
+	g := commit.New(commit.WithDir(r.Dir))
+	n, err := g.CountCommits(ctx, "v0.1.0", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = g.CountCommits(ctx, "", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	g = commit.New(commit.WithDir(r.Dir))
+	g.Paths = []string{"a"}
+	n, err = g.CountCommits(ctx, "v0.1.0", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = g.CountCommits(ctx, "--all", "HEAD")
+	assert.ErrorIs(t, err, commit.ErrInvalidRevision)
+}
+
+func TestGitLogPage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial")
+	r.Tag("v0.1.0")
+	r.Commit("feat: a")
+	r.Commit("feat: b")
+	r.Commit("fix: c")
+
+	g := commit.New(commit.WithDir(r.Dir), commit.WithCommitOrder(commit.OrderLog))
+	page, err := g.LogPage(ctx, "v0.1.0", "HEAD", commit.Limit(2))
+	require.NoError(t, err)
+	require.Len(t, page.Commits, 2)
+	assert.Equal(t, "fix: c", page.Commits[0].Subject())
+	assert.Equal(t, "feat: b", page.Commits[1].Subject())
+	assert.Equal(t, 3, page.Count)
+	assert.True(t, page.Limited())
+
+	// The Limit doesn't limit the count.
+	n, err := g.CountCommits(ctx, "v0.1.0", "HEAD", commit.Limit(2))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	page, err = g.LogPage(ctx, "v0.1.0", "HEAD", commit.Limit(5))
+	require.NoError(t, err)
+	assert.Len(t, page.Commits, 3)
+	assert.Equal(t, 3, page.Count)
+	assert.False(t, page.Limited())
+
+	page, err = g.LogPage(ctx, "", "HEAD")
+	require.NoError(t, err)
+	assert.Len(t, page.Commits, 4)
+	assert.Equal(t, 4, page.Count)
+}
+
+func TestGitSlowLogger(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	runner := fakeRunner(func(args []string) (string, error) {
+		if args[len(args)-2] == "v1.0.0" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return "", nil
+	})
+	l := &logRecorder{}
+	g := commit.New(commit.WithRunner(runner), commit.WithSlowLogger(10*time.Millisecond, l))
+	_, err := g.IsAncestor(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	_, err = g.IsAncestor(ctx, "v1.1.0", "v1.2.0")
+	require.NoError(t, err)
+	lines := l.Lines()
+	require.Len(t, lines, 1)
+	assert.True(t, strings.HasPrefix(lines[0], "git merge-base --is-ancestor v1.0.0 v1.1.0 took "), lines[0])
+
+	g = commit.New(commit.WithRunner(runner), commit.WithSlowLogger(0, l))
+	_, err = g.IsAncestor(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Len(t, l.Lines(), 1)
+}
+
+func BenchmarkRelease(b *testing.B) {
+	ctx := context.Background()
+	dir := createLargeGitRepo(b, 3000, 100)
+	addRemote(b, dir, "origin", "git@github.com:arsham/gitrelease.git")
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sequentialRelease(b, dir)
+		}
+	})
+
+	b.Run("Prepare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g := &commit.Git{Dir: dir}
+			_, err := g.Prepare(ctx, "@")
+			require.NoError(b, err)
+		}
+	})
+}
+
+// BenchmarkMonorepo measures the tags of a prefix in a long history, where
+// most tags belong to other prefixes and the tags of the prefix are far from
+// the HEAD. Set GITRELEASE_BENCH_COMMITS to change the size.
+func BenchmarkMonorepo(b *testing.B) {
+	ctx := context.Background()
+	n := 20000
+	if v, err := strconv.Atoi(os.Getenv("GITRELEASE_BENCH_COMMITS")); err == nil && v > 0 {
+		n = v
+	}
+	r := committest.NewRepo(b, identity)
+	r.AddRemote("origin", "git@github.com:arsham/gitrelease.git")
+	r.Generate(n/2, 50, "svc/v0.%d.0")
+	r.Generate(n/2, 10, "v0.%d.0")
+	b.ResetTimer()
+
+	b.Run("Tags", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g := commit.New(commit.WithDir(r.Dir), commit.WithTagPrefix("svc/"))
+			tags, err := g.Tags(ctx)
+			require.NoError(b, err)
+			require.NotEmpty(b, tags)
+		}
+	})
+
+	b.Run("LatestTag", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g := commit.New(commit.WithDir(r.Dir), commit.WithTagPrefix("svc/"))
+			_, err := g.LatestTag(ctx)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("Prepare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			g := commit.New(commit.WithDir(r.Dir))
+			_, err := g.Prepare(ctx, "@")
+			require.NoError(b, err)
+		}
+	})
+}
+
+// sequentialRelease runs the same git processes as the release flow did before
+// Prepare was introduced.
+func sequentialRelease(b *testing.B, dir string) {
+	b.Helper()
+	commands := [][]string{
+		{"config", "--get", "remote.origin.url"},
+		{"describe", "--tags", "--abbrev=0", "@^"},
+		{"log", "--oneline", "HEAD~100..@", "--pretty=%B"},
+		{"describe", "--tags", "--abbrev=0"},
+	}
+	for _, args := range commands {
+		cmd := exec.CommandContext(context.Background(), "git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(b, err, string(out))
+	}
+}
+
+func FuzzParseRemoteURL(f *testing.F) {
+	seeds := []string{
+		"git@github.com:arsham/gitrelease.git",
+		"https://github.com/arsham/arshlib.nvim",
+		"github.com/arsham/gitrelease",
+		"ssh://git@github.com/arsham/gitrelease.git\n",
+		"https://github.com//",
+		"github.com:/.git",
+		"https://example.com/arsham/gitrelease",
+		"https://github.com/🎉/🚀.git",
+		"\x00\xffgithub.com/\xfe/\xfd",
+		"git@ssh.dev.azure.com:v3/org/My%20Project/repo",
+		"https://org.visualstudio.com/DefaultCollection/project/_git/repo",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, url string) {
+		user, repo, err := commit.ParseRemoteURL(url)
+		if err != nil {
+			return
+		}
+		if user == "" || repo == "" {
+			t.Errorf("empty user (%q) or repo (%q) for %q", user, repo, url)
+		}
+		if strings.Contains(user, "/") {
+			t.Errorf("user %q contains a slash for %q", user, url)
+		}
+	})
+}
+
+// BenchmarkCommits measures the parsing of the git log output of large
+// ranges.
+func BenchmarkCommits(b *testing.B) {
+	ctx := context.Background()
+	separator := strings.Repeat("0", 35)
+	for _, size := range []int{10000, 100000} {
+		var sb strings.Builder
+		for i := 0; i < size; i++ {
+			msg := fmt.Sprintf("feat(scope%d): commit number %d\n\nsome description\n\nClose #%d", i%20, i, i)
+			if i%7 == 0 {
+				msg = fmt.Sprintf("fix!: commit number %d\n\nBREAKING CHANGE: this is breaking", i)
+			}
+			fmt.Fprintf(&sb, "%s%s%08x\x00arsham <arsham@github.com>\x00%d\x00%s\n",
+				separator, strings.Repeat("ab", 16), i, 1700000000+i, msg)
+		}
+		out := sb.String()
+		g := &commit.Git{
+			Runner: fakeRunner(func([]string) (string, error) {
+				return out, nil
+			}),
+		}
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				logs, err := g.Commits(ctx, "v0.1.0", "v0.2.0")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(logs) != size {
+					b.Fatalf("got %d commits, want %d", len(logs), size)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package commit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/github-release/github-release/github"
+	"github.com/kevinburke/rest/restclient"
+)
+
+type releaseCreate struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+// Release publishes the release for the user on the repo, as a pre-release if
+// the Channel is one. It returns ErrReleaseExists if the tag is already
+// released.
+func (g *Git) Release(ctx context.Context, token, user, repo, tag, desc string) error {
+	params := releaseCreate{
+		TagName:         tag,
+		TargetCommitish: g.releaseTarget(ctx, tag),
+		Body:            desc,
+		Prerelease:      g.prerelease(),
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/releases", user, repo)
+	err := g.api(ctx, token, http.MethodPost, uri, params, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code("already_exists") {
+		return ErrReleaseExists
+	}
+	if err != nil {
+		return fmt.Errorf("publishing release: %w", err)
+	}
+	return nil
+}
+
+// releaseTarget returns the commit of the tag for the target_commitish of its
+// release, which is the commit and never the object of an annotated tag. It
+// returns an empty string if the tag is not in the repository, in which case
+// GitHub uses the default branch.
+func (g *Git) releaseTarget(ctx context.Context, tag string) string {
+	sha, err := g.resolveTag(ctx, tag)
+	if err != nil {
+		g.debugf("the target of the release is unknown: %v", err)
+		return ""
+	}
+	return sha
+}
+
+// APIError is returned when the GitHub API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Errors     []struct {
+		Resource string `json:"resource"`
+		Field    string `json:"field"`
+		Code     string `json:"code"`
+	} `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitHub API responded with %d", e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub API responded with %d: %s", e.StatusCode, e.Message)
+}
+
+// Code returns true if any of the detailed errors has the code.
+func (e *APIError) Code(code string) bool {
+	for _, d := range e.Errors {
+		if d.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStatus returns true if the err is an APIError with the status code.
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+func parseAPIError(resp *http.Response) error {
+	// nolint:errcheck // it's ok.
+	defer resp.Body.Close()
+	e := &APIError{StatusCode: resp.StatusCode}
+	// The body is only used for a better message.
+	_ = json.NewDecoder(resp.Body).Decode(e)
+	return e
+}
+
+// ReleaseDetails is a release on GitHub.
+type ReleaseDetails struct {
+	ID              int64          `json:"id"`
+	TagName         string         `json:"tag_name"`
+	TargetCommitish string         `json:"target_commitish"`
+	Name            string         `json:"name"`
+	Body            string         `json:"body"`
+	Draft           bool           `json:"draft"`
+	Prerelease      bool           `json:"prerelease"`
+	CreatedAt       time.Time      `json:"created_at"`
+	PublishedAt     *time.Time     `json:"published_at"`
+	HTMLURL         string         `json:"html_url"`
+	UploadURL       string         `json:"upload_url"`
+	Assets          []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a release.
+type ReleaseAsset struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	State              string `json:"state"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// ListOptions configures ListReleases.
+type ListOptions struct {
+	// Limit is the maximum number of releases to return. Zero means no limit.
+	Limit int
+	// IncludeDrafts includes the draft releases. The API only returns them
+	// if the token has push access to the repository.
+	IncludeDrafts bool
+}
+
+// listPageSize is the number of releases requested in each page.
+const listPageSize = 100
+
+// ListReleases returns the releases of the repository, newest first. It
+// follows the pages of the API until there are no more releases or the
+// Limit is reached.
+func (g *Git) ListReleases(ctx context.Context, token, user, repo string, opts ListOptions) ([]ReleaseDetails, error) {
+	var releases []ReleaseDetails
+	for page := 1; ; page++ {
+		var batch []ReleaseDetails
+		uri := fmt.Sprintf("/repos/%s/%s/releases?per_page=%d&page=%d", user, repo, listPageSize, page)
+		if err := g.api(ctx, token, http.MethodGet, uri, nil, &batch); err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		for _, r := range batch {
+			if r.Draft && !opts.IncludeDrafts {
+				continue
+			}
+			releases = append(releases, r)
+			if opts.Limit > 0 && len(releases) == opts.Limit {
+				return releases, nil
+			}
+		}
+		if len(batch) < listPageSize {
+			return releases, nil
+		}
+	}
+}
+
+// GetReleaseByTag returns the release of the tag.
+func (g *Git) GetReleaseByTag(ctx context.Context, token, user, repo, tag string) (*ReleaseDetails, error) {
+	r := &ReleaseDetails{}
+	uri := fmt.Sprintf("/repos/%s/%s/releases/tags/%s", user, repo, url.PathEscape(tag))
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, r); err != nil {
+		return nil, fmt.Errorf("getting release of %s: %w", tag, err)
+	}
+	return r, nil
+}
+
+// UpdateReleaseBody replaces the notes of the release with the id.
+func (g *Git) UpdateReleaseBody(ctx context.Context, token, user, repo string, id int64, body string) error {
+	uri := fmt.Sprintf("/repos/%s/%s/releases/%d", user, repo, id)
+	payload := map[string]string{"body": body}
+	if err := g.api(ctx, token, http.MethodPatch, uri, payload, nil); err != nil {
+		return fmt.Errorf("updating release: %w", err)
+	}
+	return nil
+}
+
+// UploadSummary is the outcome of uploading the assets of a release.
+type UploadSummary struct {
+	Uploaded int
+	Replaced int
+	Skipped  int
+	Failed   int
+}
+
+// UploadAssets uploads the assets to the release of the tag. It is safe to
+// run it again after a failure: the assets already on the release are skipped
+// if their size, or their sha256 sum in the release's checksums.txt manifest,
+// matches the local file, and replaced otherwise. When reupload is true, all
+// existing assets are replaced. A failed asset doesn't stop the others from
+// being uploaded.
+func (g *Git) UploadAssets(ctx context.Context, token, user, repo, tag string, assets []Asset, reupload bool) (UploadSummary, error) {
+	if len(assets) == 0 {
+		return UploadSummary{}, nil
+	}
+	r, err := g.GetReleaseByTag(ctx, token, user, repo, tag)
+	if err != nil {
+		return UploadSummary{}, err
+	}
+	return g.UploadReleaseAssets(ctx, token, user, repo, r, assets, reupload)
+}
+
+// UploadReleaseAssets is like UploadAssets, but uploads to the release r. It
+// should be used for the draft releases, which can't be found by their tag.
+func (g *Git) UploadReleaseAssets(ctx context.Context, token, user, repo string, r *ReleaseDetails, assets []Asset, reupload bool) (UploadSummary, error) {
+	var (
+		summary UploadSummary
+		err     error
+	)
+	existing := make(map[string]ReleaseAsset, len(r.Assets))
+	for _, a := range r.Assets {
+		existing[a.Name] = a
+	}
+	var sums map[string]string
+	if !reupload {
+		sums, err = g.remoteChecksums(ctx, token, user, repo, r.Assets)
+		if err != nil {
+			g.debugf("ignoring the checksums manifest: %v", err)
+		}
+	}
+
+	var failures []string
+	for _, a := range assets {
+		old, ok := existing[a.Name]
+		if ok && !reupload {
+			same, err := sameAsset(a, old, sums)
+			if err != nil {
+				summary.Failed++
+				failures = append(failures, fmt.Sprintf("%s: %v", a.Name, err))
+				continue
+			}
+			if same {
+				g.debugf("skipped %s, it is already uploaded", a.Name)
+				summary.Skipped++
+				continue
+			}
+		}
+		if ok {
+			uri := fmt.Sprintf("/repos/%s/%s/releases/assets/%d", user, repo, old.ID)
+			if err := g.api(ctx, token, http.MethodDelete, uri, nil, nil); err != nil {
+				summary.Failed++
+				failures = append(failures, fmt.Sprintf("%s: deleting the old asset: %v", a.Name, err))
+				continue
+			}
+		}
+		if err := g.uploadAsset(ctx, token, r.UploadURL, a); err != nil {
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("%s: uploading %s: %v", a.Name, a.Path, err))
+			continue
+		}
+		if ok {
+			g.debugf("replaced %s with %s", a.Name, a.Path)
+			summary.Replaced++
+			continue
+		}
+		g.debugf("uploaded %s as %s", a.Path, a.Name)
+		summary.Uploaded++
+	}
+	if len(failures) > 0 {
+		return summary, fmt.Errorf("failed to upload %d asset(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return summary, nil
+}
+
+// sameAsset returns true if the local asset has the same content as the
+// uploaded one, using the sha256 sum in the sums if it exists, or the size.
+func sameAsset(a Asset, old ReleaseAsset, sums map[string]string) (bool, error) {
+	if sum, ok := sums[a.Name]; ok {
+		local, err := fileSHA256(a.Path)
+		if err != nil {
+			return false, err
+		}
+		return strings.EqualFold(local, sum), nil
+	}
+	info, err := os.Stat(a.Path)
+	if err != nil {
+		return false, err
+	}
+	return info.Size() == old.Size, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	// nolint:errcheck // it's only read.
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteChecksums downloads the checksums.txt manifest of the release, if
+// there is one, and returns the sums keyed by the asset names.
+func (g *Git) remoteChecksums(ctx context.Context, token, user, repo string, assets []ReleaseAsset) (map[string]string, error) {
+	for _, a := range assets {
+		if !strings.HasSuffix(a.Name, "checksums.txt") {
+			continue
+		}
+		uri := fmt.Sprintf("/repos/%s/%s/releases/assets/%d", user, repo, a.ID)
+		resp, err := g.apiDo(ctx, token, http.MethodGet, uri, nil, "application/octet-stream")
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %w", a.Name, err)
+		}
+		// nolint:errcheck // it's ok.
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %w", a.Name, err)
+		}
+		return parseChecksums(string(b)), nil
+	}
+	return nil, nil
+}
+
+// parseChecksums parses the output of sha256sum.
+func parseChecksums(s string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range splitLines(s) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	return sums
+}
+
+func (g *Git) uploadAsset(ctx context.Context, token, uploadURL string, a Asset) error {
+	token, err := g.token(ctx, token)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filepath.Clean(a.Path))
+	if err != nil {
+		return err
+	}
+	// nolint:errcheck // it's only read.
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The upload_url is a URI template, e.g. ".../assets{?name,label}".
+	if i := strings.IndexByte(uploadURL, '{'); i >= 0 {
+		uploadURL = uploadURL[:i]
+	}
+	uploadURL += "?name=" + url.QueryEscape(a.Name)
+	body := &countingReader{r: f, name: a.Name, total: info.Size(), progress: g.progress()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := g.httpClient(http.DefaultClient).Do(req)
+	if err != nil {
+		return err
+	}
+	// nolint:errcheck // it's ok.
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("error uploading asset with code: %q", resp.Status)
+	}
+	if info.Size() == 0 {
+		// Nothing is read from the empty files.
+		g.progress().Transfer(a.Name, 0, 0)
+	}
+	g.Metrics.uploaded(info.Size())
+	return nil
+}
+
+// api sends the payload to the uri of the GitHub API, and decodes the response
+// into out if it's not nil.
+func (g *Git) api(ctx context.Context, token, method, uri string, payload, out any) error {
+	resp, err := g.apiDo(ctx, token, method, uri, payload, "")
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+// apiDo sends the payload to the uri of the GitHub API and returns the
+// response, which the caller should close. If accept is not empty, it
+// replaces the default Accept header.
+func (g *Git) apiDo(ctx context.Context, token, method, uri string, payload any, accept string) (*http.Response, error) {
+	token, err := g.token(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	rc := restclient.New("x-access-token", token, g.baseURL())
+	if method == http.MethodGet {
+		return g.cachedGet(ctx, rc, token, uri, accept)
+	}
+	header := http.Header{}
+	if accept != "" {
+		header.Set("Accept", accept)
+	}
+	return g.send(ctx, rc, method, uri, payload, header)
+}
+
+// apiBearer is like api, but authenticates with a bearer token, e.g. the JWT
+// of a GitHub App.
+func (g *Git) apiBearer(ctx context.Context, token, method, uri string, out any) error {
+	rc := restclient.NewBearerClient(token, g.baseURL())
+	resp, err := g.send(ctx, rc, method, uri, nil, nil)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+// send sends the request with the payload, and the header in addition to
+// the default ones.
+func (g *Git) send(ctx context.Context, rc *restclient.Client, method, uri string, payload any, header http.Header) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling values: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	rc.ErrorParser = parseAPIError
+	rc.Client = g.httpClient(rc.Client)
+	client := github.NewClient("", "", rc)
+	req, err := client.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request to the API: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return resp, fmt.Errorf("submitting to the API: %w", err)
+	}
+	return resp, nil
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	// nolint:errcheck // it's ok.
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding the response: %w", err)
+	}
+	return nil
+}
+
+// token returns the token if it's not empty, otherwise a token from the
+// TokenSource if it's set and the Git is not Offline.
+func (g *Git) token(ctx context.Context, token string) (string, error) {
+	if token != "" || g.TokenSource == nil {
+		return token, nil
+	}
+	if g.Offline {
+		// The TokenSource has its own client.
+		return "", g.offlineTransport().refuse("the token of the TokenSource")
+	}
+	return g.TokenSource.Token(ctx)
+}
+
+func (g *Git) baseURL() string {
+	if g.BaseURL != "" {
+		return g.BaseURL
+	}
+	return baseURL
+}
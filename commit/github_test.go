@@ -0,0 +1,472 @@
+package commit_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub serves the parts of the GitHub API the tests need.
+type fakeGitHub struct {
+	*httptest.Server
+	mu      sync.Mutex
+	release commit.ReleaseDetails
+	uploads map[string]string
+	deleted []int64
+	nextID  int64
+
+	releaseDeleted bool
+	releases       []commit.ReleaseDetails
+	listRequests   int
+	issues         map[int]string
+	comments       map[int][]string
+	// targets are the target_commitish of the created releases.
+	targets []string
+}
+
+func newFakeGitHub(t *testing.T, tag string) *fakeGitHub {
+	t.Helper()
+	f := &fakeGitHub{
+		uploads:  make(map[string]string),
+		nextID:   100,
+		issues:   make(map[int]string),
+		comments: make(map[int][]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/user/repo/issues/", func(w http.ResponseWriter, r *http.Request) {
+		f.issue(t, w, r)
+	})
+	mux.HandleFunc("/repos/user/repo/releases/tags/"+tag, func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.releaseDeleted {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(f.release))
+	})
+	mux.HandleFunc("/repos/user/repo/releases/1", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.Method {
+		case http.MethodDelete:
+			f.releaseDeleted = true
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			assert.NoError(t, json.NewEncoder(w).Encode(f.release))
+		case http.MethodPatch:
+			var req struct {
+				Body  *string `json:"body"`
+				Draft *bool   `json:"draft"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.Body != nil {
+				f.release.Body = *req.Body
+			}
+			if req.Draft != nil {
+				f.release.Draft = *req.Draft
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(f.release))
+		default:
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+	})
+	mux.HandleFunc("/repos/user/repo/releases", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			TagName         string `json:"tag_name"`
+			TargetCommitish string `json:"target_commitish"`
+			Body            string `json:"body"`
+			Draft           bool   `json:"draft"`
+			Prerelease      bool   `json:"prerelease"`
+		}
+		if r.Method == http.MethodGet {
+			f.listReleases(t, w, r)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if req.TagName == f.release.TagName {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message":"Validation Failed","errors":[{"resource":"Release","code":"already_exists","field":"tag_name"}]}`)
+			return
+		}
+		f.targets = append(f.targets, req.TargetCommitish)
+		w.WriteHeader(http.StatusCreated)
+		assert.NoError(t, json.NewEncoder(w).Encode(commit.ReleaseDetails{
+			ID:              2,
+			TagName:         req.TagName,
+			TargetCommitish: req.TargetCommitish,
+			Body:            req.Body,
+			Draft:           req.Draft,
+			Prerelease:      req.Prerelease,
+		}))
+	})
+	mux.HandleFunc("/repos/user/repo/releases/assets/", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(path.Base(r.URL.Path), 10, 64)
+		assert.NoError(t, err)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, a := range f.release.Assets {
+			if a.ID != id {
+				continue
+			}
+			switch r.Method {
+			case http.MethodGet:
+				assert.Equal(t, "application/octet-stream", r.Header.Get("Accept"))
+				fmt.Fprint(w, f.uploads[a.Name])
+			case http.MethodDelete:
+				f.deleted = append(f.deleted, id)
+				f.release.Assets = append(f.release.Assets[:i], f.release.Assets[i+1:]...)
+				delete(f.uploads, a.Name)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/uploads/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "token token", r.Header.Get("Authorization"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.addAsset(r.URL.Query().Get("name"), string(body))
+		w.WriteHeader(http.StatusCreated)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	f.release = commit.ReleaseDetails{
+		ID:        1,
+		TagName:   tag,
+		UploadURL: f.URL + "/uploads/1/assets{?name,label}",
+	}
+	return f
+}
+
+func (f *fakeGitHub) listReleases(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	assert.NoError(t, err)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	assert.NoError(t, err)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listRequests++
+	start := (page - 1) * perPage
+	if start > len(f.releases) {
+		start = len(f.releases)
+	}
+	end := start + perPage
+	if end > len(f.releases) {
+		end = len(f.releases)
+	}
+	assert.NoError(t, json.NewEncoder(w).Encode(f.releases[start:end]))
+}
+
+func (f *fakeGitHub) issue(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	num := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/issues/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := strconv.Atoi(strings.TrimSuffix(num, "/comments"))
+	assert.NoError(t, err)
+	state, ok := f.issues[n]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		fmt.Fprintf(w, `{"number":%d,"state":%q}`, n, state)
+		return
+	}
+	var req struct {
+		Body string `json:"body"`
+	}
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+	f.comments[n] = append(f.comments[n], req.Body)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// addAsset should be called with the lock held.
+func (f *fakeGitHub) addAsset(name, content string) {
+	f.nextID++
+	f.uploads[name] = content
+	f.release.Assets = append(f.release.Assets, commit.ReleaseAsset{
+		ID:   f.nextID,
+		Name: name,
+		Size: int64(len(content)),
+	})
+}
+
+func TestGitRelease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	err := g.Release(ctx, "token", "user", "repo", "v1.1.0", "desc")
+	assert.NoError(t, err)
+
+	err = g.Release(ctx, "token", "user", "repo", "v1.0.0", "desc")
+	assert.ErrorIs(t, err, commit.ErrReleaseExists)
+
+	err = g.Release(ctx, "token", "user", "other", "v1.1.0", "desc")
+	var apiErr *commit.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	t.Run("Target", func(t *testing.T) {
+		t.Parallel()
+		gh := newFakeGitHub(t, "v1.0.0")
+		r := repo(t, createGitRepo(t))
+		sha := r.Commit("feat: thing")
+		r.AnnotatedTag("v1.1.0", "the release")
+		require.NotEqual(t, sha, strings.TrimSpace(r.Run("rev-parse", "v1.1.0")))
+		g := &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+
+		require.NoError(t, g.Release(ctx, "token", "user", "repo", "v1.1.0", "desc"))
+		require.NoError(t, g.Release(ctx, "token", "user", "repo", "v1.2.0", "desc"))
+		assert.Equal(t, []string{sha, ""}, gh.targets,
+			"the tag is peeled to its commit, and the missing tag is left to GitHub")
+	})
+}
+
+func TestGitListReleases(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	for i := 250; i > 0; i-- {
+		gh.releases = append(gh.releases, commit.ReleaseDetails{
+			ID:      int64(i),
+			TagName: fmt.Sprintf("v0.%d.0", i),
+			Draft:   i%50 == 0,
+		})
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+
+	got, err := g.ListReleases(ctx, "token", "user", "repo", commit.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, got, 245)
+	assert.Equal(t, "v0.249.0", got[0].TagName)
+	assert.Equal(t, 3, gh.listRequests)
+
+	got, err = g.ListReleases(ctx, "token", "user", "repo", commit.ListOptions{IncludeDrafts: true})
+	require.NoError(t, err)
+	assert.Len(t, got, 250)
+	assert.Equal(t, "v0.250.0", got[0].TagName)
+
+	gh.listRequests = 0
+	got, err = g.ListReleases(ctx, "token", "user", "repo", commit.ListOptions{Limit: 120})
+	require.NoError(t, err)
+	assert.Len(t, got, 120)
+	assert.Equal(t, 2, gh.listRequests)
+
+	_, err = g.ListReleases(ctx, "token", "user", "other", commit.ListOptions{})
+	assert.Error(t, err)
+}
+
+func TestGitGetReleaseByTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	gh.addAsset("a.zip", "content")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	got, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.ID)
+	require.Len(t, got.Assets, 1)
+	assert.Equal(t, "a.zip", got.Assets[0].Name)
+
+	_, err = g.GetReleaseByTag(ctx, "token", "user", "repo", "v2.0.0")
+	var apiErr *commit.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestGitUpdateReleaseBody(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	g := &commit.Git{BaseURL: gh.URL}
+
+	require.NoError(t, g.UpdateReleaseBody(ctx, "token", "user", "repo", 1, "new body"))
+	got, err := g.GetReleaseByTag(ctx, "token", "user", "repo", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "new body", got.Body)
+
+	err = g.UpdateReleaseBody(ctx, "token", "user", "repo", 2, "new body")
+	assert.Error(t, err)
+}
+
+func TestGitUploadAssets(t *testing.T) {
+	t.Parallel()
+	t.Run("Upload", testGitUploadAssetsUpload)
+	t.Run("Resume", testGitUploadAssetsResume)
+	t.Run("Checksums", testGitUploadAssetsChecksums)
+	t.Run("Failure", testGitUploadAssetsFailure)
+	t.Run("Progress", testGitUploadAssetsProgress)
+}
+
+func testGitUploadAssetsUpload(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir := t.TempDir()
+	createFile(t, dir, "a.zip", "content a")
+	createFile(t, dir, "b.zip", "content b")
+
+	g := &commit.Git{BaseURL: gh.URL}
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "a.zip"), Name: "app_linux.zip"},
+		{Path: filepath.Join(dir, "b.zip"), Name: "app_darwin.zip"},
+	}
+	got, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.NoError(t, err)
+	assert.Equal(t, commit.UploadSummary{Uploaded: 2}, got)
+	want := map[string]string{
+		"app_linux.zip":  "content a",
+		"app_darwin.zip": "content b",
+	}
+	assert.Equal(t, want, gh.uploads)
+
+	_, err = g.UploadAssets(ctx, "token", "user", "repo", "v2.0.0", assets, false)
+	assert.Error(t, err)
+}
+
+func testGitUploadAssetsResume(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	gh.addAsset("a.zip", "content a")
+	gh.addAsset("b.zip", "old")
+	dir := t.TempDir()
+	createFile(t, dir, "a.zip", "content a")
+	createFile(t, dir, "b.zip", "content b")
+	createFile(t, dir, "c.zip", "content c")
+
+	g := &commit.Git{BaseURL: gh.URL}
+	var assets []commit.Asset
+	for _, name := range []string{"a.zip", "b.zip", "c.zip"} {
+		assets = append(assets, commit.Asset{Path: filepath.Join(dir, name), Name: name})
+	}
+	got, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.NoError(t, err)
+	assert.Equal(t, commit.UploadSummary{Uploaded: 1, Replaced: 1, Skipped: 1}, got)
+	assert.Equal(t, "content b", gh.uploads["b.zip"])
+	assert.Equal(t, []int64{102}, gh.deleted)
+
+	got, err = g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.NoError(t, err)
+	assert.Equal(t, commit.UploadSummary{Skipped: 3}, got)
+
+	got, err = g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, true)
+	require.NoError(t, err)
+	assert.Equal(t, commit.UploadSummary{Replaced: 3}, got)
+}
+
+func testGitUploadAssetsChecksums(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	// Same size, different content.
+	gh.addAsset("a.zip", "content x")
+	gh.addAsset("b.zip", "content b")
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	gh.addAsset("app_checksums.txt", fmt.Sprintf("%s  a.zip\n%s  b.zip\n", sum("content x"), sum("content b")))
+	dir := t.TempDir()
+	createFile(t, dir, "a.zip", "content a")
+	createFile(t, dir, "b.zip", "content b")
+
+	g := &commit.Git{BaseURL: gh.URL}
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "a.zip"), Name: "a.zip"},
+		{Path: filepath.Join(dir, "b.zip"), Name: "b.zip"},
+	}
+	got, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.NoError(t, err)
+	assert.Equal(t, commit.UploadSummary{Replaced: 1, Skipped: 1}, got)
+	assert.Equal(t, "content a", gh.uploads["a.zip"])
+}
+
+func testGitUploadAssetsFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir := t.TempDir()
+	createFile(t, dir, "b.zip", "content b")
+
+	g := &commit.Git{BaseURL: gh.URL}
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "a.zip"), Name: "a.zip"},
+		{Path: filepath.Join(dir, "b.zip"), Name: "b.zip"},
+	}
+	got, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a.zip")
+	assert.Equal(t, commit.UploadSummary{Uploaded: 1, Failed: 1}, got)
+	assert.Equal(t, map[string]string{"b.zip": "content b"}, gh.uploads)
+}
+
+// progressRecorder is a commit.Progress that records the transfers.
+type progressRecorder struct {
+	mu        sync.Mutex
+	transfers map[string][]int64
+	totals    map[string]int64
+}
+
+func (p *progressRecorder) Start(string) func(error) { return func(error) {} }
+
+func (p *progressRecorder) Transfer(name string, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.transfers == nil {
+		p.transfers = make(map[string][]int64)
+		p.totals = make(map[string]int64)
+	}
+	p.transfers[name] = append(p.transfers[name], done)
+	p.totals[name] = total
+}
+
+func testGitUploadAssetsProgress(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir := t.TempDir()
+	content := strings.Repeat("a", 100000)
+	createFile(t, dir, "a.zip", content)
+	createFile(t, dir, "empty.zip", "")
+
+	p := &progressRecorder{}
+	g := &commit.Git{BaseURL: gh.URL, Progress: p}
+	assets := []commit.Asset{
+		{Path: filepath.Join(dir, "a.zip"), Name: "a.zip"},
+		{Path: filepath.Join(dir, "empty.zip"), Name: "empty.zip"},
+	}
+	_, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+	require.NoError(t, err)
+
+	done := p.transfers["a.zip"]
+	require.NotEmpty(t, done)
+	assert.IsIncreasing(t, done)
+	assert.EqualValues(t, len(content), done[len(done)-1])
+	assert.EqualValues(t, len(content), p.totals["a.zip"])
+	assert.Equal(t, []int64{0}, p.transfers["empty.zip"])
+}
@@ -0,0 +1,198 @@
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// typeRe matches the type and the scopes of the subjects that have one, e.g.
+// "sec(auth,api)!: rotate the keys". Unlike descRe, the colon is required.
+var typeRe = regexp.MustCompile(`^\s*([[:alpha:]]+)(?:\(([^)]*)\))?!?:\s*(.*)`)
+
+// GroupRule assigns the commits that match all of its rules to the section of
+// the Title. The Types and the Scopes are the ones of the conventional commits,
+// e.g. "fix(auth): x" has the "fix" type and the "auth" scope, and are case
+// insensitive. A rule matches if any of its values does, and the empty rules
+// are ignored.
+type GroupRule struct {
+	Title    string
+	Types    []string
+	Scopes   []string
+	Subjects []*regexp.Regexp
+	// Order sorts the sections, the lower first. The sections with the same
+	// order are in the order of their rules.
+	Order int
+}
+
+// NewGroupRule returns the GroupRule of the title with the subjects compiled.
+// It returns an error if the title is empty, or it has no rules, since it
+// would match all the commits.
+func NewGroupRule(title string, types, scopes, subjects []string, order int) (GroupRule, error) {
+	if strings.TrimSpace(title) == "" {
+		return GroupRule{}, errors.New("a group has no title")
+	}
+	if len(types)+len(scopes)+len(subjects) == 0 {
+		return GroupRule{}, fmt.Errorf("group %q has no types, scopes or subjects to match", title)
+	}
+	r := GroupRule{Title: title, Order: order}
+	for _, t := range types {
+		if t = strings.TrimSpace(t); t == "" {
+			return GroupRule{}, fmt.Errorf("group %q has an empty type", title)
+		}
+		r.Types = append(r.Types, strings.ToLower(t))
+	}
+	for _, s := range scopes {
+		if s = strings.TrimSpace(s); s == "" {
+			return GroupRule{}, fmt.Errorf("group %q has an empty scope", title)
+		}
+		r.Scopes = append(r.Scopes, strings.ToLower(s))
+	}
+	for _, s := range subjects {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return GroupRule{}, fmt.Errorf("group %q: compiling subject %q: %w", title, s, err)
+		}
+		r.Subjects = append(r.Subjects, re)
+	}
+	return r, nil
+}
+
+// match returns true if the subject matches all the rules.
+func (r GroupRule) match(subject, typ string, scopes []string) bool {
+	if len(r.Types) > 0 && !contains(r.Types, typ) {
+		return false
+	}
+	if len(r.Scopes) > 0 {
+		var found bool
+		for _, s := range scopes {
+			if contains(r.Scopes, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Subjects) == 0 {
+		return true
+	}
+	for _, re := range r.Subjects {
+		if re.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupRules classifies the commits by the groups of the config file, which
+// replace the conventional ones. The rules are evaluated in order, and the
+// first match wins. The titles can repeat, to put the commits of several
+// rules in one section.
+type GroupRules []GroupRule
+
+// Classify returns the title of the first rule that matches the commit.
+func (g GroupRules) Classify(c Commit) (string, bool) {
+	subject := subjectOf(c.Message)
+	typ, scopes := parseType(subject)
+	for _, r := range g {
+		if r.match(subject, typ, scopes) {
+			return r.Title, true
+		}
+	}
+	return "", false
+}
+
+// Order returns the titles of the sections, sorted by their Order.
+func (g GroupRules) Order() []string {
+	rules := append(GroupRules(nil), g...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Order < rules[j].Order
+	})
+	titles := make([]string, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if key := strings.ToLower(r.Title); !seen[key] {
+			seen[key] = true
+			titles = append(titles, r.Title)
+		}
+	}
+	return titles
+}
+
+// hasType returns true if any of the rules matches the type, therefore the
+// subjects of the type are parsed like the conventional ones.
+func (g GroupRules) hasType(typ string) bool {
+	for _, r := range g {
+		if contains(r.Types, strings.ToLower(typ)) {
+			return true
+		}
+	}
+	return false
+}
+
+// typer is implemented by the classifiers that define their own types.
+type typer interface {
+	hasType(typ string) bool
+}
+
+// definesType returns true if the classifier, or any of the Classifiers,
+// defines the type.
+func definesType(c Classifier, typ string) bool {
+	switch c := c.(type) {
+	case Classifiers:
+		for _, child := range c {
+			if definesType(child, typ) {
+				return true
+			}
+		}
+	case typer:
+		return c.hasType(typ)
+	}
+	return false
+}
+
+// parseType returns the lower case type and scopes of the subject, or an
+// empty type if it doesn't have one.
+func parseType(subject string) (typ string, scopes []string) {
+	m := typeRe.FindStringSubmatch(subject)
+	if m == nil {
+		return "", nil
+	}
+	for _, s := range strings.Split(m[2], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, strings.ToLower(s))
+		}
+	}
+	return strings.ToLower(m[1]), scopes
+}
+
+// contains returns true if the list has the s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// WithUnmatched puts the commits that the classifier of WithClassifier can't
+// classify in the section of the group instead of Misc.
+func WithUnmatched(group string) RenderOption {
+	return func(o *renderOptions) {
+		o.unmatched = group
+	}
+}
+
+// WithHiddenUnmatched leaves the commits that the classifier of
+// WithClassifier can't classify out of the notes. The breaking changes are
+// never hidden, and are listed in the section of WithUnmatched.
+func WithHiddenUnmatched() RenderOption {
+	return func(o *renderOptions) {
+		o.hideUnmatched = true
+	}
+}
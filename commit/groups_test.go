@@ -0,0 +1,161 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGroupRule(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		title    string
+		types    []string
+		scopes   []string
+		subjects []string
+		wantErr  string
+	}{
+		"types":       {title: "Security", types: []string{"SEC"}},
+		"subjects":    {title: "Security", subjects: []string{`(?i)\bCVE-\d+`}},
+		"no title":    {title: " ", types: []string{"sec"}, wantErr: "no title"},
+		"no rules":    {title: "Security", wantErr: "no types, scopes or subjects"},
+		"empty type":  {title: "Security", types: []string{""}, wantErr: "empty type"},
+		"empty scope": {title: "Security", scopes: []string{" "}, wantErr: "empty scope"},
+		"bad subject": {title: "Security", subjects: []string{"["}, wantErr: "compiling subject"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r, err := commit.NewGroupRule(tc.title, tc.types, tc.scopes, tc.subjects, 0)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.title, r.Title)
+		})
+	}
+}
+
+// groupRules returns the rules of the Security, Platform and Developer
+// Experience groups.
+func groupRules(t *testing.T) commit.GroupRules {
+	t.Helper()
+	security, err := commit.NewGroupRule("Security", nil, []string{"security", "Auth"}, nil, 0)
+	require.NoError(t, err)
+	cve, err := commit.NewGroupRule("Security", nil, nil, []string{`(?i)\bCVE-\d+`}, 0)
+	require.NoError(t, err)
+	platform, err := commit.NewGroupRule("Platform", []string{"feat", "fix", "infra"}, []string{"kube", "terraform"}, nil, 1)
+	require.NoError(t, err)
+	infra, err := commit.NewGroupRule("Platform", []string{"infra"}, nil, nil, 1)
+	require.NoError(t, err)
+	dx, err := commit.NewGroupRule("Developer Experience", []string{"ci", "build", "chore"}, nil, []string{`(?i)tooling|lint`}, -1)
+	require.NoError(t, err)
+	return commit.GroupRules{security, cve, platform, infra, dx}
+}
+
+func TestGroupRules(t *testing.T) {
+	t.Parallel()
+	rules := groupRules(t)
+	tcs := map[string]struct {
+		msg   string
+		group string
+		ok    bool
+	}{
+		"scope":              {"fix(auth): the leak", "Security", true},
+		"first match wins":   {"feat(kube,security): the policies", "Security", true},
+		"subject":            {"Bump x for CVE-2024-1234", "Security", true},
+		"type and scope":     {"feat(kube): the autoscaler", "Platform", true},
+		"type of other":      {"docs(kube): the autoscaler", "", false},
+		"custom type":        {"infra!: move to the new region", "Platform", true},
+		"type and subject":   {"ci: faster lint", "Developer Experience", true},
+		"type not subject":   {"ci: cache the modules", "", false},
+		"case insensitive":   {"FIX(Security): the leak", "Security", true},
+		"no type":            {"Update the kube tooling", "", false},
+		"subject first line": {"chore: x\n\nthe tooling", "", false},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			group, ok := rules.Classify(commit.Commit{Message: tc.msg})
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.group, group)
+		})
+	}
+}
+
+func TestGroupRulesOrder(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"Developer Experience", "Security", "Platform"}, groupRules(t).Order())
+	assert.Empty(t, commit.GroupRules{}.Order())
+}
+
+func TestGroupRulesParseGroups(t *testing.T) {
+	t.Parallel()
+	rules := groupRules(t)
+	logs := []string{
+		"feat(kube): the autoscaler",
+		"infra(eu): move to the new region",
+		"fix(auth): the leak",
+		"ci: faster lint",
+		"docs: the guide",
+		"feat!: drop the v1 api",
+	}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"misc": {
+			want: "### Developer Experience\n\n" +
+				"- Faster lint\n\n\n" +
+				"### Security\n\n" +
+				"- **Auth:** The leak\n\n\n" +
+				"### Platform\n\n" +
+				"- **Kube:** The autoscaler\n" +
+				"- **Eu:** Move to the new region\n\n\n" +
+				"### Misc\n\n" +
+				"- The guide\n" +
+				"- Drop the v1 api [**BREAKING CHANGE**]",
+		},
+		"default": {
+			opts: []commit.RenderOption{commit.WithUnmatched("Other")},
+			want: "### Developer Experience\n\n" +
+				"- Faster lint\n\n\n" +
+				"### Security\n\n" +
+				"- **Auth:** The leak\n\n\n" +
+				"### Platform\n\n" +
+				"- **Kube:** The autoscaler\n" +
+				"- **Eu:** Move to the new region\n\n\n" +
+				"### Other\n\n" +
+				"- The guide\n" +
+				"- Drop the v1 api [**BREAKING CHANGE**]",
+		},
+		"hidden": {
+			opts: []commit.RenderOption{commit.WithHiddenUnmatched()},
+			want: "### Developer Experience\n\n" +
+				"- Faster lint\n\n\n" +
+				"### Security\n\n" +
+				"- **Auth:** The leak\n\n\n" +
+				"### Platform\n\n" +
+				"- **Kube:** The autoscaler\n" +
+				"- **Eu:** Move to the new region\n\n\n" +
+				"### Misc\n\n" +
+				"- Drop the v1 api [**BREAKING CHANGE**]",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			opts := append([]commit.RenderOption{
+				commit.WithClassifier(rules),
+				commit.WithGroupOrder(rules.Order()...),
+			}, tc.opts...)
+			assert.Equal(t, tc.want, commit.ParseGroups(logs, opts...))
+		})
+	}
+}
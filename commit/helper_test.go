@@ -2,85 +2,169 @@ package commit_test
 
 import (
 	"context"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/arsham/gitrelease/commit/committest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
 )
 
-func createGitRepo(t *testing.T) string {
+// repo returns the committest.Repo of the dir, with the identity all the
+// tests expect.
+func repo(t testing.TB, dir string) *committest.Repo {
 	t.Helper()
-	dir, err := ioutil.TempDir("", "git-lfs-test")
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		require.NoError(t, os.RemoveAll(dir))
-	})
-
-	newDir := path.Join(dir, "project")
-	os.Mkdir(newDir, 0o755)
+	return committest.Open(t, dir, identity)
+}
 
-	commands := [][]string{
-		{"init"},
-		{"config", "user.email", "arsham@github.com"},
-		{"config", "user.name", "arsham"},
-	}
+var identity = committest.WithIdentity("arsham", "arsham@github.com")
 
-	for _, args := range commands {
-		cmd := exec.CommandContext(context.Background(), "git", args...)
-		cmd.Dir = newDir
-		_, err = cmd.CombinedOutput()
-		require.NoError(t, err)
-	}
+func createGitRepo(t testing.TB) string {
+	t.Helper()
+	return committest.NewRepo(t, identity).Dir
+}
 
-	return newDir
+// createEmptyGitRepo creates a repository without any commits, as a clone of
+// a new repository on GitHub is. It has an origin remote, so only the missing
+// commits fail.
+func createEmptyGitRepo(t testing.TB) string {
+	t.Helper()
+	dir := createGitRepo(t)
+	addRemote(t, dir, "origin", "git@github.com:arsham/empty.git")
+	return dir
 }
 
 func createGitTag(t *testing.T, dir, tag string) {
 	t.Helper()
-	args := []string{"tag", tag}
-	cmd := exec.CommandContext(context.Background(), "git", args...)
-	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
-	require.NoError(t, err, string(out))
+	repo(t, dir).Tag(tag)
 }
 
 func createFile(t *testing.T, dir, filename, content string) {
 	t.Helper()
-	require.NoError(t, os.Chdir(dir))
-	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+	repo(t, dir).WriteFile(filename, content)
 }
 
 func commitChanges(t *testing.T, dir, msg string) {
 	t.Helper()
+	repo(t, dir).Commit(msg)
+}
 
-	args := []string{"add", "-A"}
-	cmd := exec.CommandContext(context.Background(), "git", args...)
+// commitAt commits the changes with the date as both the author and the
+// committer date.
+func commitAt(t *testing.T, dir, msg, date string) {
+	t.Helper()
+	d, err := time.Parse(time.RFC3339, date)
+	require.NoError(t, err)
+	repo(t, dir).CommitAt(msg, d)
+}
+
+func addRemote(t testing.TB, dir, name, addr string) {
+	t.Helper()
+	repo(t, dir).AddRemote(name, addr)
+}
+
+// createLargeGitRepo creates a repository with the given amount of commits,
+// and tags every nth commit. It uses fast-import because creating commits one
+// by one is too slow.
+func createLargeGitRepo(t testing.TB, commits, every int) string {
+	t.Helper()
+	r := committest.NewRepo(t, identity)
+	r.Generate(commits, every, "v0.%d.0")
+	return r.Dir
+}
+
+// fastImport feeds the stream to git fast-import and points the HEAD to the
+// branch. It is useful for creating commits that git commit would refuse to
+// create.
+func fastImport(t testing.TB, dir, branch, stream string) {
+	t.Helper()
+	cmd := exec.CommandContext(context.Background(), "git", "fast-import", "--quiet")
 	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stream)
 	out, err := cmd.CombinedOutput()
 	require.NoError(t, err, string(out))
 
-	args = []string{"commit", "-am", msg, "--no-gpg-sign"}
-	cmd = exec.CommandContext(context.Background(), "git", args...)
+	cmd = exec.CommandContext(context.Background(), "git", "symbolic-ref", "HEAD", "refs/heads/"+branch)
 	cmd.Dir = dir
 	out, err = cmd.CombinedOutput()
 	require.NoError(t, err, string(out))
 }
 
-func appendToFile(t *testing.T, dir, filename, msg string) {
+// runGit runs git with the args in the dir and returns its output.
+func runGit(t testing.TB, dir string, args ...string) string {
 	t.Helper()
-	require.NoError(t, os.Chdir(dir))
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
-	require.NoError(t, err)
-	defer f.Close()
+	return repo(t, dir).Run(args...)
+}
 
-	_, err = f.WriteString(msg)
-	require.NoError(t, err)
+func gitConfig(t testing.TB, dir, key, value string) {
+	t.Helper()
+	cmd := exec.CommandContext(context.Background(), "git", "config", key, value)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// fakeRunner returns the output of the function instead of running git.
+type fakeRunner func(args []string) (string, error)
+
+func (f fakeRunner) Run(_ context.Context, _ string, stdout io.Writer, args ...string) error {
+	out, err := f(args)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, out)
+	return err
+}
+
+// fakeStdinRunner is a fakeRunner that also reads the standard input of git.
+type fakeStdinRunner func(args []string, stdin string) (string, error)
+
+func (f fakeStdinRunner) Run(ctx context.Context, dir string, stdout io.Writer, args ...string) error {
+	return f.RunStdin(ctx, dir, strings.NewReader(""), stdout, args...)
+}
+
+func (f fakeStdinRunner) RunStdin(_ context.Context, _ string, stdin io.Reader, stdout io.Writer, args ...string) error {
+	in, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	out, err := f(args, string(in))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, out)
+	return err
+}
+
+// logRecorder is a commit.Logger that records the lines.
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *logRecorder) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func appendToFile(t *testing.T, dir, filename, msg string) {
+	t.Helper()
+	repo(t, dir).AppendFile(filename, msg)
 }
 
 var cmpIgnoreNewlines = cmp.Transformer("IgnoreNewlines", func(in string) string {
@@ -102,3 +186,31 @@ var commitComparer = cmp.Options{
 	cmpIgnoreNewlines,
 	stringSliceCleaner,
 }
+
+// createModulesRepo creates a repository with the ".", "mod", "mod/sub" and
+// "tools" modules. The "mod" module has no changes since its tag, "mod/sub"
+// has a feature and a fix, "." has a fix and "tools" has never been tagged.
+func createModulesRepo(t *testing.T) string {
+	t.Helper()
+	dir := createGitRepo(t)
+	for _, d := range []string{"mod/sub", "tools"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, d), 0o755))
+	}
+
+	createFile(t, dir, "go.mod", "module example.com/root\n")
+	createFile(t, dir, "mod/go.mod", "module example.com/root/mod\n")
+	createFile(t, dir, "mod/sub/go.mod", "module example.com/root/mod/sub\n")
+	createFile(t, dir, "tools/go.mod", "module example.com/root/tools\n")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	createGitTag(t, dir, "mod/v0.1.0")
+	createGitTag(t, dir, "mod/sub/v1.2.3")
+
+	createFile(t, dir, "mod/sub/file.go", "package sub\n")
+	commitChanges(t, dir, "feat: add sub feature")
+	createFile(t, dir, "mod/sub/other.go", "package sub\n")
+	commitChanges(t, dir, "fix: fix sub")
+	createFile(t, dir, "main.go", "package main\n")
+	commitChanges(t, dir, "fix: fix root")
+	return dir
+}
@@ -0,0 +1,55 @@
+package commit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// highlightsHeadingRe matches the headings of the versions in a highlights
+// file, e.g. "## v1.5.0", "## [1.5.0]" or "# 2024.05.1".
+var highlightsHeadingRe = regexp.MustCompile(`^#{1,6}\s+\[?(v?\d[0-9A-Za-z.+-]*)\]?\s*$`)
+
+// Highlights returns the curated highlights of the version, without the
+// TagPrefix, from the content of a highlights file, e.g. HIGHLIGHTS.md. The
+// file can collect the highlights of several upcoming versions in sections
+// under the headings of their versions:
+//
+//	## v1.5.0
+//
+//	- The new dashboard.
+//
+//	## v1.4.0
+//
+//	- Faster startup.
+//
+// The versions match with or without the "v" prefix, and the text before the
+// first heading is ignored. If the content has no headings of versions, all
+// of it is the highlights of any version. It returns an empty string if the
+// version has no highlights.
+func Highlights(content, version string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	want := strings.TrimPrefix(version, "v")
+	var (
+		sectioned bool
+		inside    bool
+		section   []string
+	)
+	for _, line := range lines {
+		m := highlightsHeadingRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			if inside {
+				section = append(section, line)
+			}
+			continue
+		}
+		sectioned = true
+		if inside {
+			break
+		}
+		inside = strings.TrimPrefix(m[1], "v") == want
+	}
+	if !sectioned {
+		return strings.TrimSpace(content)
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
@@ -0,0 +1,37 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlights(t *testing.T) {
+	t.Parallel()
+	file := "# Highlights\n\nWritten by marketing.\n\n" +
+		"## v1.5.0\n\n- The new dashboard.\n- Dark mode.\n\n### Details\n\nMore to come.\n\n" +
+		"## [1.4.0]\r\n\r\n- Faster startup.\r\n\r\n" +
+		"## v1.3.0\n"
+	tcs := map[string]struct {
+		content string
+		version string
+		want    string
+	}{
+		"section":         {content: file, version: "v1.5.0", want: "- The new dashboard.\n- Dark mode.\n\n### Details\n\nMore to come."},
+		"without v":       {content: file, version: "1.5.0", want: "- The new dashboard.\n- Dark mode.\n\n### Details\n\nMore to come."},
+		"bracketed":       {content: file, version: "v1.4.0", want: "- Faster startup."},
+		"empty section":   {content: file, version: "v1.3.0"},
+		"missing section": {content: file, version: "v1.6.0"},
+		"prefix":          {content: file, version: "v1.5.0-rc.1"},
+		"no sections":     {content: "\n- The new dashboard.\n\n## Thanks\n\nTo all.\n", version: "v2.0.0", want: "- The new dashboard.\n\n## Thanks\n\nTo all."},
+		"empty":           {version: "v1.5.0"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.Highlights(tc.content, tc.version))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package commit
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitlabHost is the host of the public GitLab.
+const gitlabHost = "gitlab.com"
+
+// URLPatterns overrides the addresses of the pages of a host, e.g. for a
+// self-hosted instance with nonstandard paths. The empty patterns use the
+// defaults of the detected host. The patterns can have these placeholders:
+//
+//	{repo}   the HTMLURL of the repository
+//	{sha}    the hash of the commit
+//	{from}   the old ref of the comparison
+//	{to}     the new ref of the comparison, or the ref of the commits list
+//	{number} the number of the issue or the pull request
+//
+// For example, "{repo}/-/commit/{sha}".
+type URLPatterns struct {
+	Commit  string
+	Compare string
+	// Commits is the list of the commits of a ref, which the first release
+	// links to instead of the compare page.
+	Commits string
+	Issue   string
+	Pull    string
+}
+
+// IsGitLab returns true if the repository is on GitLab. The self-hosted
+// instances are detected if their host has "gitlab" in it, e.g.
+// gitlab.example.com, otherwise their URLs should be set with the URLs.
+func (r RemoteInfo) IsGitLab() bool {
+	return strings.Contains(strings.ToLower(r.Host), "gitlab")
+}
+
+// CommitURL returns the address of the page of the commit.
+func (r RemoteInfo) CommitURL(sha string) string {
+	if r.URLs.Commit != "" {
+		return r.expand(r.URLs.Commit, "{sha}", url.PathEscape(sha))
+	}
+	switch {
+	case r.IsGitLab():
+		return r.HTMLURL() + "/-/commit/" + url.PathEscape(sha)
+	case r.IsBitbucket():
+		return r.HTMLURL() + "/commits/" + url.PathEscape(sha)
+	}
+	return r.HTMLURL() + "/commit/" + url.PathEscape(sha)
+}
+
+// CommitsURL returns the address of the list of the commits reachable from the
+// ref, e.g. the commits of the first release.
+func (r RemoteInfo) CommitsURL(ref string) string {
+	if r.URLs.Commits != "" {
+		return r.expand(r.URLs.Commits, "{to}", escapeRef(ref))
+	}
+	switch {
+	case r.IsAzure():
+		q := url.Values{}
+		q.Set("itemVersion", "GT"+ref)
+		return r.HTMLURL() + "/commits?" + q.Encode()
+	case r.IsGitLab():
+		return r.HTMLURL() + "/-/commits/" + escapeRef(ref)
+	case r.IsBitbucket():
+		return r.HTMLURL() + "/commits/tag/" + escapeRef(ref)
+	}
+	return r.HTMLURL() + "/commits/" + escapeRef(ref)
+}
+
+// IssueURL returns the address of the issue with the number. On Azure DevOps
+// it's the work item of the project.
+func (r RemoteInfo) IssueURL(n int) string {
+	num := strconv.Itoa(n)
+	if r.URLs.Issue != "" {
+		return r.expand(r.URLs.Issue, "{number}", num)
+	}
+	switch {
+	case r.IsAzure():
+		return strings.TrimSuffix(r.HTMLURL(), "/_git/"+url.PathEscape(r.Name)) + "/_workitems/edit/" + num
+	case r.IsGitLab():
+		return r.HTMLURL() + "/-/issues/" + num
+	}
+	return r.HTMLURL() + "/issues/" + num
+}
+
+// PullURL returns the address of the pull request, or the merge request on
+// GitLab, with the number.
+func (r RemoteInfo) PullURL(n int) string {
+	num := strconv.Itoa(n)
+	if r.URLs.Pull != "" {
+		return r.expand(r.URLs.Pull, "{number}", num)
+	}
+	switch {
+	case r.IsAzure():
+		return r.HTMLURL() + "/pullrequest/" + num
+	case r.IsGitLab():
+		return r.HTMLURL() + "/-/merge_requests/" + num
+	case r.IsBitbucket():
+		return r.HTMLURL() + "/pull-requests/" + num
+	}
+	return r.HTMLURL() + "/pull/" + num
+}
+
+// CompareURL returns the address of the compare page of the two refs, with
+// the view of the mode where the host supports it, see RangeMode.CompareURL.
+// GitHub and GitLab show the changes since the merge base in the two-dot
+// mode, and the direct diff of the refs in the three-dot mode, which is the
+// "straight" comparison of GitLab. Azure DevOps and Bitbucket only have the
+// view of the changes since the merge base, in both modes. The pages list
+// the commits of to since the merge base, therefore the commits of from's
+// branch in the three-dot notes are never listed.
+func (r RemoteInfo) CompareURL(mode RangeMode, from, to string) string {
+	if r.URLs.Compare != "" {
+		return r.expand(r.URLs.Compare, "{from}", escapeRef(from), "{to}", escapeRef(to))
+	}
+	switch {
+	case r.IsAzure():
+		q := url.Values{}
+		q.Set("baseVersion", "GT"+from)
+		q.Set("targetVersion", "GT"+to)
+		return r.HTMLURL() + "/branchCompare?" + q.Encode()
+	case r.IsBitbucket():
+		return r.HTMLURL() + "/branches/compare/" + url.PathEscape(to) + "%0D" + url.PathEscape(from)
+	case r.IsGitLab():
+		link := r.HTMLURL() + "/-/compare/" + escapeRef(from) + "..." + escapeRef(to)
+		if mode == RangeSymmetric {
+			link += "?straight=true"
+		}
+		return link
+	}
+	if mode == RangeSymmetric {
+		return r.HTMLURL() + "/compare/" + escapeRef(from) + ".." + escapeRef(to)
+	}
+	return r.HTMLURL() + "/compare/" + escapeRef(from) + "..." + escapeRef(to)
+}
+
+// expand replaces the {repo} and the other placeholders of the pattern, which
+// are given as old and new pairs.
+func (r RemoteInfo) expand(pattern string, oldnew ...string) string {
+	return strings.NewReplacer(append([]string{"{repo}", r.HTMLURL()}, oldnew...)...).Replace(pattern)
+}
@@ -0,0 +1,173 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteInfoLinks(t *testing.T) {
+	t.Parallel()
+	var (
+		github    = commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"}
+		gitlab    = commit.RemoteInfo{Host: "gitlab.com", Owner: "group", Name: "repo"}
+		bitbucket = commit.RemoteInfo{Host: "bitbucket.org", Owner: "team", Name: "repo"}
+		azure     = commit.RemoteInfo{Host: "dev.azure.com", Owner: "org", Project: "project", Name: "repo"}
+		custom    = commit.RemoteInfo{Host: "git.example.com", Owner: "group", Name: "repo", URLs: commit.URLPatterns{
+			Commit:  "{repo}/-/commit/{sha}",
+			Compare: "{repo}/-/compare/{from}...{to}",
+			Commits: "{repo}/-/commits/{to}",
+			Issue:   "https://tracker.example.com/browse/{number}",
+			Pull:    "{repo}/-/merge_requests/{number}",
+		}}
+	)
+	tcs := map[string]struct {
+		link func() string
+		want string
+	}{
+		"github commit":    {func() string { return github.CommitURL("abc123") }, "https://github.com/user/repo/commit/abc123"},
+		"github compare":   {func() string { return github.CompareURL(commit.RangeTwoDot, "v1.0.0", "v1.1.0") }, "https://github.com/user/repo/compare/v1.0.0...v1.1.0"},
+		"github commits":   {func() string { return github.CommitsURL("v0.1.0") }, "https://github.com/user/repo/commits/v0.1.0"},
+		"github issue":     {func() string { return github.IssueURL(12) }, "https://github.com/user/repo/issues/12"},
+		"github pull":      {func() string { return github.PullURL(12) }, "https://github.com/user/repo/pull/12"},
+		"gitlab commit":    {func() string { return gitlab.CommitURL("abc123") }, "https://gitlab.com/group/repo/-/commit/abc123"},
+		"gitlab compare":   {func() string { return gitlab.CompareURL(commit.RangeTwoDot, "v1.0.0", "v1.1.0") }, "https://gitlab.com/group/repo/-/compare/v1.0.0...v1.1.0"},
+		"gitlab symmetric": {func() string { return gitlab.CompareURL(commit.RangeSymmetric, "v1.0.0", "v1.1.0") }, "https://gitlab.com/group/repo/-/compare/v1.0.0...v1.1.0?straight=true"},
+		"gitlab commits":   {func() string { return gitlab.CommitsURL("v0.1.0") }, "https://gitlab.com/group/repo/-/commits/v0.1.0"},
+		"gitlab issue":     {func() string { return gitlab.IssueURL(12) }, "https://gitlab.com/group/repo/-/issues/12"},
+		"gitlab pull":      {func() string { return gitlab.PullURL(12) }, "https://gitlab.com/group/repo/-/merge_requests/12"},
+		"bitbucket commit": {func() string { return bitbucket.CommitURL("abc123") }, "https://bitbucket.org/team/repo/commits/abc123"},
+		"bitbucket compare": {
+			func() string { return bitbucket.CompareURL(commit.RangeTwoDot, "v1.0.0", "v1.1.0") },
+			"https://bitbucket.org/team/repo/branches/compare/v1.1.0%0Dv1.0.0",
+		},
+		"bitbucket commits": {func() string { return bitbucket.CommitsURL("v0.1.0") }, "https://bitbucket.org/team/repo/commits/tag/v0.1.0"},
+		"bitbucket issue":   {func() string { return bitbucket.IssueURL(12) }, "https://bitbucket.org/team/repo/issues/12"},
+		"bitbucket pull":    {func() string { return bitbucket.PullURL(12) }, "https://bitbucket.org/team/repo/pull-requests/12"},
+		"azure commit":      {func() string { return azure.CommitURL("abc123") }, "https://dev.azure.com/org/project/_git/repo/commit/abc123"},
+		"azure compare": {
+			func() string { return azure.CompareURL(commit.RangeTwoDot, "v1.0.0", "v1.1.0") },
+			"https://dev.azure.com/org/project/_git/repo/branchCompare?baseVersion=GTv1.0.0&targetVersion=GTv1.1.0",
+		},
+		"azure commits": {
+			func() string { return azure.CommitsURL("v0.1.0") },
+			"https://dev.azure.com/org/project/_git/repo/commits?itemVersion=GTv0.1.0",
+		},
+		"azure issue":    {func() string { return azure.IssueURL(12) }, "https://dev.azure.com/org/project/_workitems/edit/12"},
+		"azure pull":     {func() string { return azure.PullURL(12) }, "https://dev.azure.com/org/project/_git/repo/pullrequest/12"},
+		"custom commit":  {func() string { return custom.CommitURL("abc123") }, "https://git.example.com/group/repo/-/commit/abc123"},
+		"custom compare": {func() string { return custom.CompareURL(commit.RangeTwoDot, "mod/v1.0.0", "mod/v1.1.0") }, "https://git.example.com/group/repo/-/compare/mod/v1.0.0...mod/v1.1.0"},
+		"custom commits": {func() string { return custom.CommitsURL("mod/v0.1.0") }, "https://git.example.com/group/repo/-/commits/mod/v0.1.0"},
+		"custom issue":   {func() string { return custom.IssueURL(12) }, "https://tracker.example.com/browse/12"},
+		"custom pull":    {func() string { return custom.PullURL(12) }, "https://git.example.com/group/repo/-/merge_requests/12"},
+		"custom default": {func() string {
+			return commit.RemoteInfo{Host: "git.example.com", Owner: "group", Name: "repo"}.CommitURL("abc123")
+		}, "https://git.example.com/group/repo/commit/abc123"},
+		"gitlab self-hosted": {
+			func() string {
+				return commit.RemoteInfo{Host: "gitlab.example.com", Owner: "group", Name: "repo"}.CommitURL("abc123")
+			},
+			"https://gitlab.example.com/group/repo/-/commit/abc123",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.link())
+		})
+	}
+}
+
+// TestRemoteInfoCompareURL documents the view of the compare page of each
+// host in each mode. The merge base view has the changes of the commits of
+// the two-dot notes, and the direct view has the changes of both branches,
+// like the three-dot notes.
+func TestRemoteInfoCompareURL(t *testing.T) {
+	t.Parallel()
+	const (
+		mergeBase = "the changes of v1.1.0 since the merge base"
+		direct    = "the diff of v1.0.0 and v1.1.0"
+	)
+	tcs := map[string]struct {
+		remote commit.RemoteInfo
+		mode   commit.RangeMode
+		want   string
+		shows  string
+	}{
+		"github two-dot": {
+			remote: commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"},
+			mode:   commit.RangeTwoDot,
+			want:   "https://github.com/user/repo/compare/v1.0.0...v1.1.0",
+			shows:  mergeBase,
+		},
+		"github three-dot": {
+			remote: commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"},
+			mode:   commit.RangeSymmetric,
+			want:   "https://github.com/user/repo/compare/v1.0.0..v1.1.0",
+			shows:  direct,
+		},
+		"gitlab two-dot": {
+			remote: commit.RemoteInfo{Host: "gitlab.com", Owner: "group", Name: "repo"},
+			mode:   commit.RangeTwoDot,
+			want:   "https://gitlab.com/group/repo/-/compare/v1.0.0...v1.1.0",
+			shows:  mergeBase,
+		},
+		"gitlab three-dot": {
+			remote: commit.RemoteInfo{Host: "gitlab.com", Owner: "group", Name: "repo"},
+			mode:   commit.RangeSymmetric,
+			want:   "https://gitlab.com/group/repo/-/compare/v1.0.0...v1.1.0?straight=true",
+			shows:  direct,
+		},
+		"bitbucket two-dot": {
+			remote: commit.RemoteInfo{Host: "bitbucket.org", Owner: "team", Name: "repo"},
+			mode:   commit.RangeTwoDot,
+			want:   "https://bitbucket.org/team/repo/branches/compare/v1.1.0%0Dv1.0.0",
+			shows:  mergeBase,
+		},
+		"bitbucket three-dot": {
+			remote: commit.RemoteInfo{Host: "bitbucket.org", Owner: "team", Name: "repo"},
+			mode:   commit.RangeSymmetric,
+			want:   "https://bitbucket.org/team/repo/branches/compare/v1.1.0%0Dv1.0.0",
+			shows:  mergeBase,
+		},
+		"azure two-dot": {
+			remote: commit.RemoteInfo{Host: "dev.azure.com", Owner: "org", Project: "project", Name: "repo"},
+			mode:   commit.RangeTwoDot,
+			want:   "https://dev.azure.com/org/project/_git/repo/branchCompare?baseVersion=GTv1.0.0&targetVersion=GTv1.1.0",
+			shows:  mergeBase,
+		},
+		"azure three-dot": {
+			remote: commit.RemoteInfo{Host: "dev.azure.com", Owner: "org", Project: "project", Name: "repo"},
+			mode:   commit.RangeSymmetric,
+			want:   "https://dev.azure.com/org/project/_git/repo/branchCompare?baseVersion=GTv1.0.0&targetVersion=GTv1.1.0",
+			shows:  mergeBase,
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.remote.CompareURL(tc.mode, "v1.0.0", "v1.1.0"), tc.shows)
+		})
+	}
+}
+
+func TestGitHostURL(t *testing.T) {
+	t.Parallel()
+	dir := createGitRepo(t)
+	g := commit.New(
+		commit.WithDir(dir),
+		commit.WithRemote("upstream"),
+		commit.WithHostURL("Git.Example.com", commit.URLPatterns{Commit: "{repo}/-/commit/{sha}"}),
+	)
+	addRemote(t, dir, "upstream", "git@git.example.com:group/repo.git")
+
+	got, err := g.RemoteInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "https://git.example.com/group/repo/-/commit/abc", got.CommitURL("abc"))
+	assert.Equal(t, "https://git.example.com/group/repo/issues/3", got.IssueURL(3))
+}
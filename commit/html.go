@@ -0,0 +1,181 @@
+package commit
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// entityRe matches the HTML entities the notes might already contain,
+	// e.g. the ones added by the sanitiser.
+	entityRe = regexp.MustCompile(`^&(?:[[:alpha:]][[:alnum:]]*|#[0-9]+|#[xX][[:xdigit:]]+);`)
+	boldRe   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	emRe     = regexp.MustCompile(`(^|[^\w\\])_(\S(?:.*?\S)?)_($|\W)`)
+	linkRe   = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	// autolinkRe matches the bare URLs, which GitHub renders as links.
+	autolinkRe = regexp.MustCompile(`(^|\s)(https?://[^\s<]*[^\s<.,:;'")\]])`)
+	// mdUnescape removes the backslashes of the escaped markdown characters.
+	mdUnescapeRe = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|~<>])")
+	commentRe    = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// RenderHTML renders the notes as HTML. It supports the markdown ParseGroups
+// and TruncateNotes produce: headings, nested bullet lists, paragraphs, code
+// spans and blocks, bold and emphasised text and links. HTML comments, e.g.
+// the provenance footer, are removed.
+func RenderHTML(notes string) string {
+	notes = commentRe.ReplaceAllString(notes, "")
+	buf := &strings.Builder{}
+	var (
+		// depth is the number of open lists.
+		depth int
+		para  []string
+		fence string
+	)
+	flushPara := func() {
+		if len(para) > 0 {
+			buf.WriteString("<p>" + strings.Join(para, "\n") + "</p>\n")
+			para = nil
+		}
+	}
+	closeLists := func(to int) {
+		for ; depth > to; depth-- {
+			buf.WriteString("</li>\n</ul>\n")
+		}
+	}
+
+	for _, line := range splitLines(notes) {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if trimmed == fence {
+				buf.WriteString("</code></pre>\n")
+				fence = ""
+				continue
+			}
+			buf.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			flushPara()
+			closeLists(0)
+			fence = m[1]
+			buf.WriteString("<pre><code>")
+			continue
+		}
+		if trimmed == "" {
+			flushPara()
+			continue
+		}
+		if headingRe.MatchString(line) {
+			flushPara()
+			closeLists(0)
+			text := strings.TrimLeft(trimmed, "#")
+			tag := "h" + string(rune('0'+len(trimmed)-len(text)))
+			text = strings.TrimSpace(text)
+			buf.WriteString("<" + tag + ">" + renderInline(text) + "</" + tag + ">\n")
+			continue
+		}
+		if m := listItemRe.FindString(line); m != "" {
+			flushPara()
+			// Every two spaces of indentation is a level of nesting.
+			level := (len(line)-len(strings.TrimLeft(line, " ")))/2 + 1
+			if level > depth+1 {
+				level = depth + 1
+			}
+			switch {
+			case level > depth && depth > 0:
+				buf.WriteString("\n<ul>\n")
+				depth = level
+			case level > depth:
+				buf.WriteString("<ul>\n")
+				depth = level
+			case level < depth:
+				closeLists(level)
+				buf.WriteString("</li>\n")
+			default:
+				buf.WriteString("</li>\n")
+			}
+			buf.WriteString("<li>" + renderInline(strings.TrimSpace(line[len(m):])))
+			continue
+		}
+		if depth > 0 && strings.HasPrefix(line, " ") {
+			// The continuation of a list item.
+			buf.WriteString("\n" + renderInline(trimmed))
+			continue
+		}
+		closeLists(0)
+		para = append(para, renderInline(trimmed))
+	}
+	if fence != "" {
+		buf.WriteString("</code></pre>\n")
+	}
+	flushPara()
+	closeLists(0)
+	return strings.TrimSpace(buf.String())
+}
+
+// renderInline renders the inline markdown of the text. The content of the code
+// spans is rendered literally. They are replaced with placeholders while the
+// rest is rendered, since emphasis can contain code spans.
+func renderInline(s string) string {
+	var (
+		codes []string
+		buf   = &strings.Builder{}
+	)
+	for s != "" {
+		start, end := codeSpan(s)
+		if start < 0 {
+			buf.WriteString(s)
+			break
+		}
+		buf.WriteString(s[:start])
+		n := backticks(s[start:])
+		code := strings.TrimSpace(s[start+n : end-n])
+		fmt.Fprintf(buf, "\x00%d\x00", len(codes))
+		codes = append(codes, "<code>"+html.EscapeString(code)+"</code>")
+		s = s[end:]
+	}
+	out := renderText(buf.String())
+	for i, code := range codes {
+		out = strings.Replace(out, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+	return out
+}
+
+// renderText renders the text outside of the code spans.
+func renderText(s string) string {
+	s = escapeHTML(s)
+	s = linkRe.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = autolinkRe.ReplaceAllString(s, `$1<a href="$2">$2</a>`)
+	s = boldRe.ReplaceAllString(s, "<strong>$1</strong>")
+	s = emRe.ReplaceAllString(s, "$1<em>$2</em>$3")
+	return mdUnescapeRe.ReplaceAllString(s, "$1")
+}
+
+// escapeHTML escapes the HTML special characters of s, keeping the entities
+// that are already escaped.
+func escapeHTML(s string) string {
+	buf := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			if m := entityRe.FindString(s[i:]); m != "" {
+				buf.WriteString(m)
+				i += len(m) - 1
+				continue
+			}
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '"':
+			buf.WriteString("&#34;")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
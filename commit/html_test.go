@@ -0,0 +1,84 @@
+package commit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderHTML(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		notes string
+		want  string
+	}{
+		"empty": {},
+		"sections": {
+			notes: "### Feature\n\n- **Repo:** Add the api (#42)\n- Another\n\n### Fix\n\n- Thing",
+			want: strings.Join([]string{
+				"<h3>Feature</h3>",
+				"<ul>",
+				"<li><strong>Repo:</strong> Add the api (#42)</li>",
+				"<li>Another</li>",
+				"</ul>",
+				"<h3>Fix</h3>",
+				"<ul>",
+				"<li>Thing</li>",
+				"</ul>",
+			}, "\n"),
+		},
+		"sub-items": {
+			notes: "- Parent\n  - child 1\n  - child 2\n- Next",
+			want: strings.Join([]string{
+				"<ul>",
+				"<li>Parent",
+				"<ul>",
+				"<li>child 1</li>",
+				"<li>child 2</li>",
+				"</ul>",
+				"</li>",
+				"<li>Next</li>",
+				"</ul>",
+			}, "\n"),
+		},
+		"escaped": {
+			notes: "- Fix &lt;b&gt; & <i>x</i> \\*not bold\\*",
+			want:  "<ul>\n<li>Fix &lt;b&gt; &amp; &lt;i&gt;x&lt;/i&gt; *not bold*</li>\n</ul>",
+		},
+		"code": {
+			notes: "- Use `a <b> **c**` here",
+			want:  "<ul>\n<li>Use <code>a &lt;b&gt; **c**</code> here</li>\n</ul>",
+		},
+		"links": {
+			notes: "See [the docs](https://example.com/a?b=1&c=2).\n\n**Full Changelog**: https://github.com/a/b/compare/v1..v2",
+			want: strings.Join([]string{
+				`<p>See <a href="https://example.com/a?b=1&amp;c=2">the docs</a>.</p>`,
+				`<p><strong>Full Changelog</strong>: <a href="https://github.com/a/b/compare/v1..v2">https://github.com/a/b/compare/v1..v2</a></p>`,
+			}, "\n"),
+		},
+		"emphasis": {
+			notes: "_The notes are truncated, see `CHANGELOG-v1.md`._",
+			want:  "<p><em>The notes are truncated, see <code>CHANGELOG-v1.md</code>.</em></p>",
+		},
+		"fence": {
+			notes: "Text\n\n```go\nfunc <T>() {}\n```",
+			want:  "<p>Text</p>\n<pre><code>func &lt;T&gt;() {}\n</code></pre>",
+		},
+		"comment": {
+			notes: "- Thing\n\n<!-- gitrelease-provenance\n{\"version\": \"1\"}\n-->",
+			want:  "<ul>\n<li>Thing</li>\n</ul>",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.RenderHTML(tc.notes)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package commit
+
+// DefaultInitialVersion is the suggested version of the first release if the
+// InitialVersion of the Git is not set.
+const DefaultInitialVersion = "v0.1.0"
+
+// InitialTag returns the suggested tag of the first release of a repository
+// without tags, which is the InitialVersion or the DefaultInitialVersion.
+func (g *Git) InitialTag() string {
+	if g.InitialVersion != "" {
+		return g.InitialVersion
+	}
+	return DefaultInitialVersion
+}
+
+// WithInitialRelease starts the notes with a line that says it's the first
+// release, e.g. when the ReleaseInfo is Initial. The link of WithCompareLink
+// should be the list of the commits instead of the compare page, since there
+// is no previous tag to compare with.
+func WithInitialRelease() RenderOption {
+	return func(o *renderOptions) {
+		o.initial = true
+	}
+}
@@ -0,0 +1,67 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitPrepareInitial(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := repo(t, createGitRepo(t))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Commit("feat: add the thing")
+	r.Commit("fix: the leak")
+
+	t.Run("Untagged", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir}
+		info, err := g.Prepare(ctx, "@")
+		require.NoError(t, err)
+		assert.Equal(t, commit.DefaultInitialVersion, info.Tag)
+		assert.True(t, info.Initial)
+		assert.True(t, info.Untagged)
+		assert.Empty(t, info.PreviousTag)
+		assert.Len(t, info.Logs, 3)
+		assert.Equal(t, "https://github.com/user/repo/commits/v0.1.0", info.CompareURL)
+	})
+
+	t.Run("InitialVersion", func(t *testing.T) {
+		t.Parallel()
+		g := commit.New(commit.WithDir(r.Dir), commit.WithInitialVersion("v1.0.0"))
+		info, err := g.Prepare(ctx, "@")
+		require.NoError(t, err)
+		assert.Equal(t, "v1.0.0", info.Tag)
+		assert.Equal(t, "https://github.com/user/repo/commits/v1.0.0", info.CompareURL)
+	})
+
+	t.Run("NoTag", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir}
+		_, err := g.Prepare(ctx, "v9.0.0")
+		assert.Error(t, err)
+	})
+}
+
+func TestParseGroupsInitialRelease(t *testing.T) {
+	t.Parallel()
+	logs := []string{"feat: add the thing", "fix: the leak"}
+	link := "https://github.com/user/repo/commits/v0.1.0"
+	got := commit.ParseGroups(logs, commit.WithInitialRelease(), commit.WithCompareLink(link))
+	assert.Equal(t, "This is the first release.\n\n"+
+		"### Feature\n\n- Add the thing\n\n\n"+
+		"### Fix\n\n- The leak\n\n"+
+		"**Full Changelog**: "+link, got)
+
+	got = commit.ParseGroups(logs[:1], commit.WithInitialRelease(),
+		commit.WithLocale(commit.Locale{FirstRelease: "Erste Version."}))
+	assert.Equal(t, "Erste Version.\n\n### Feature\n\n- Add the thing", got)
+
+	got = commit.ParseGroups(nil, commit.WithInitialRelease(), commit.WithInternalChanges(2))
+	assert.Equal(t, "This is the first release.\n\n2 internal changes.", got)
+}
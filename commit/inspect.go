@@ -0,0 +1,120 @@
+package commit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// InspectCommits is the number of the latest commits Inspect reads.
+const InspectCommits = 100
+
+// ChangelogStyle is the style of the headings of an existing changelog.
+type ChangelogStyle string
+
+const (
+	// ChangelogNone is when the repository has no CHANGELOG.md.
+	ChangelogNone ChangelogStyle = ""
+	// ChangelogKeepAChangelog has the "Added", "Changed" and "Fixed"
+	// sections of keepachangelog.com.
+	ChangelogKeepAChangelog ChangelogStyle = "keep-a-changelog"
+	// ChangelogConventional has the "Features" and "Bug Fixes" sections of
+	// conventional-changelog.
+	ChangelogConventional ChangelogStyle = "conventional-changelog"
+	// ChangelogUnknown is a changelog in none of the known styles.
+	ChangelogUnknown ChangelogStyle = "unknown"
+)
+
+// Profile is what Inspect detects of a repository, e.g. to write its config.
+type Profile struct {
+	// Remote is the Remote of the Git. It's empty if the repository has no
+	// such remote.
+	Remote RemoteInfo
+	// Commits is the number of the inspected commits, and Conventional the
+	// ones that follow the conventional commits, see Conforms.
+	Commits      int
+	Conventional int
+	// Changelog is the style of the CHANGELOG.md of the Dir.
+	Changelog ChangelogStyle
+}
+
+// IsConventional returns true if at least four of five inspected commits
+// follow the conventional commits.
+func (p Profile) IsConventional() bool {
+	return p.Commits > 0 && p.Conventional*5 >= p.Commits*4
+}
+
+// KnownHost returns true if the addresses of the pages of the Remote are
+// known without the hosts of the config file, which is the case for GitHub
+// and GitLab.
+func (p Profile) KnownHost() bool {
+	return p.Remote.Host == "" || strings.EqualFold(p.Remote.Host, "github.com") || p.Remote.IsGitLab()
+}
+
+// Inspect detects how the repository is set up: the host of the Remote,
+// whether the latest InspectCommits commits follow the conventional commits,
+// and the style of the CHANGELOG.md of the Dir. A repository without the
+// Remote or without any commits is not an error.
+func (g *Git) Inspect(ctx context.Context) (*Profile, error) {
+	p := &Profile{}
+	remotes, err := g.loadRemotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := remotes[g.remote()]; ok {
+		if p.Remote, err = g.RemoteInfo(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c := g.logCommand(g.logOptions(nil), commitFormat, fmt.Sprintf("--max-count=%d", InspectCommits))
+	parts, err := g.logRevs(ctx, c.rev("HEAD"))
+	err = g.emptyError(ctx, err)
+	if err != nil && !errors.Is(err, ErrEmptyRepository) {
+		return nil, fmt.Errorf("reading the latest commits: %w", err)
+	}
+	for _, c := range g.parseCommits(parts) {
+		p.Commits++
+		if Conforms(c.Message) {
+			p.Conventional++
+		}
+	}
+
+	p.Changelog, err = changelogStyle(g.path("CHANGELOG.md"))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var (
+	keepAChangelogRe  = regexp.MustCompile(`^###\s+(Added|Changed|Deprecated|Removed|Fixed|Security)\s*$`)
+	conventionalLogRe = regexp.MustCompile(`^###\s+(Features|Bug Fixes|Performance Improvements|BREAKING CHANGES)\s*$`)
+)
+
+// changelogStyle returns the style of the headings of the changelog file.
+func changelogStyle(path string) (ChangelogStyle, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ChangelogNone, nil
+	}
+	if err != nil {
+		return ChangelogNone, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case keepAChangelogRe.MatchString(line):
+			return ChangelogKeepAChangelog, nil
+		case conventionalLogRe.MatchString(line):
+			return ChangelogConventional, nil
+		}
+	}
+	return ChangelogUnknown, s.Err()
+}
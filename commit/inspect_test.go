@@ -0,0 +1,78 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitInspect(t *testing.T) {
+	t.Parallel()
+	t.Run("Conventional", testGitInspectConventional)
+	t.Run("Other", testGitInspectOther)
+	t.Run("Empty", testGitInspectEmpty)
+}
+
+func testGitInspectConventional(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:owner/name.git")
+	r.Commit("feat: the thing", committest.File{
+		Path:    "CHANGELOG.md",
+		Content: "# Changelog\n\n## [1.0.0] - 2020-01-01\n\n### Added\n\n- The thing\n",
+	})
+	r.Commit("fix: the leak")
+	r.Commit("docs: the readme")
+	r.Commit("chore: the linter")
+	r.Commit("Merge the branch")
+
+	p, err := (&commit.Git{Dir: r.Dir}).Inspect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "github.com", p.Remote.Host)
+	assert.True(t, p.KnownHost())
+	assert.Equal(t, 5, p.Commits)
+	assert.Equal(t, 4, p.Conventional)
+	assert.True(t, p.IsConventional())
+	assert.Equal(t, commit.ChangelogKeepAChangelog, p.Changelog)
+}
+
+func testGitInspectOther(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "https://git.example.com/owner/name.git")
+	r.Commit("Add the thing", committest.File{
+		Path:    "CHANGELOG.md",
+		Content: "## 1.0.0 (2020-01-01)\n\n### Features\n\n- The thing\n",
+	})
+	r.Commit("feat: the other thing")
+
+	p, err := (&commit.Git{Dir: r.Dir}).Inspect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "git.example.com", p.Remote.Host)
+	assert.False(t, p.KnownHost())
+	assert.Equal(t, 2, p.Commits)
+	assert.Equal(t, 1, p.Conventional)
+	assert.False(t, p.IsConventional())
+	assert.Equal(t, commit.ChangelogConventional, p.Changelog)
+
+	r.WriteFile("CHANGELOG.md", "# History\n\n- The thing\n")
+	p, err = (&commit.Git{Dir: r.Dir}).Inspect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, commit.ChangelogUnknown, p.Changelog)
+}
+
+func testGitInspectEmpty(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	p, err := (&commit.Git{Dir: r.Dir}).Inspect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, p.Remote.Host)
+	assert.True(t, p.KnownHost())
+	assert.Zero(t, p.Commits)
+	assert.False(t, p.IsConventional())
+	assert.Equal(t, commit.ChangelogNone, p.Changelog)
+}
@@ -0,0 +1,198 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// issueRefRe matches the references in the same form as the ones listed in
+// the notes, e.g. "Fixes #512" or "Closes owner/repo#12".
+var issueRefRe = regexp.MustCompile(`[[:alpha:]]+:?\s+(?:([\w.-]+)/([\w.-]+))?#(\d+)\b`)
+
+// IssueRef is a reference to an issue in a commit message. Owner and Repo are
+// empty if the issue belongs to the same repository.
+type IssueRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (r IssueRef) String() string {
+	if r.Owner == "" {
+		return fmt.Sprintf("#%d", r.Number)
+	}
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// IssueRefs returns the issues referenced in the messages, without
+// duplicates and in the order they first appear.
+func IssueRefs(msgs []string) []IssueRef {
+	var refs []IssueRef
+	seen := make(map[IssueRef]bool)
+	for _, msg := range msgs {
+		for _, m := range issueRefRe.FindAllStringSubmatch(msg, -1) {
+			n, err := strconv.Atoi(m[3])
+			if err != nil || n == 0 {
+				continue
+			}
+			ref := IssueRef{Owner: m[1], Repo: m[2], Number: n}
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// DefaultIssueComment is the template of the comments posted on the issues.
+const DefaultIssueComment = "Shipped in [{{.Tag}}]({{.URL}})."
+
+// IssueCommentOptions configures CommentIssues.
+type IssueCommentOptions struct {
+	// Tag and URL are the released tag and the address of its release. They
+	// are available in the Template along with the Issue number.
+	Tag string
+	URL string
+	// Template is a text/template for the comment. It defaults to
+	// DefaultIssueComment.
+	Template string
+	// Concurrency is the number of requests made at the same time. It
+	// defaults to 4.
+	Concurrency int
+	// Limit is the maximum number of issues to comment on in a run. Zero
+	// means no limit.
+	Limit int
+}
+
+// IssueSummary is the outcome of commenting on the referenced issues.
+type IssueSummary struct {
+	Commented int
+	// Closed is the number of issues that are skipped because they are
+	// already closed.
+	Closed int
+	// CrossRepo is the number of references to issues of other repositories.
+	CrossRepo int
+	// OverLimit is the number of issues skipped after the Limit is reached.
+	OverLimit int
+	Failed    int
+}
+
+// CommentIssues posts a comment on each open issue of the repository that is
+// referenced in the logs. References to other repositories are skipped, and
+// so are the issues over the Limit. A failed issue doesn't stop the others.
+func (g *Git) CommentIssues(ctx context.Context, token, user, repo string, logs []string, opts IssueCommentOptions) (IssueSummary, error) {
+	var summary IssueSummary
+	text := opts.Template
+	if text == "" {
+		text = DefaultIssueComment
+	}
+	tmpl, err := template.New("comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return summary, fmt.Errorf("parsing the comment template: %w", err)
+	}
+	var numbers []int
+	for _, ref := range IssueRefs(logs) {
+		if ref.Owner != "" && !(strings.EqualFold(ref.Owner, user) && strings.EqualFold(ref.Repo, repo)) {
+			g.debugf("skipped %s, it belongs to another repository", ref)
+			summary.CrossRepo++
+			continue
+		}
+		numbers = append(numbers, ref.Number)
+	}
+	numbers = uniqueInts(numbers)
+	if opts.Limit > 0 && len(numbers) > opts.Limit {
+		summary.OverLimit = len(numbers) - opts.Limit
+		numbers = numbers[:opts.Limit]
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []string
+	)
+	eg, ctx := errgroup.WithContext(ctx)
+	if opts.Concurrency > 0 {
+		eg.SetLimit(opts.Concurrency)
+	} else {
+		eg.SetLimit(4)
+	}
+	for _, n := range numbers {
+		n := n
+		eg.Go(func() error {
+			closed, err := g.commentIssue(ctx, token, user, repo, n, tmpl, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				summary.Failed++
+				failures = append(failures, fmt.Sprintf("#%d: %v", n, err))
+			case closed:
+				g.debugf("skipped #%d, it is closed", n)
+				summary.Closed++
+			default:
+				g.debugf("commented on #%d", n)
+				summary.Commented++
+			}
+			// The other issues should still be commented on.
+			return nil
+		})
+	}
+	// nolint:errcheck // the goroutines don't return errors.
+	eg.Wait()
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return summary, fmt.Errorf("failed to comment on %d issue(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return summary, nil
+}
+
+// commentIssue posts the comment on the issue if it is open. It returns true
+// if the issue is closed.
+func (g *Git) commentIssue(ctx context.Context, token, user, repo string, n int, tmpl *template.Template, opts IssueCommentOptions) (bool, error) {
+	var issue struct {
+		State string `json:"state"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/issues/%d", user, repo, n)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &issue); err != nil {
+		return false, fmt.Errorf("getting the issue: %w", err)
+	}
+	if issue.State == "closed" {
+		return true, nil
+	}
+	buf := &strings.Builder{}
+	err := tmpl.Execute(buf, map[string]any{
+		"Tag":   opts.Tag,
+		"URL":   opts.URL,
+		"Issue": n,
+	})
+	if err != nil {
+		return false, fmt.Errorf("rendering the comment: %w", err)
+	}
+	payload := map[string]string{"body": buf.String()}
+	if err := g.api(ctx, token, http.MethodPost, uri+"/comments", payload, nil); err != nil {
+		return false, fmt.Errorf("posting the comment: %w", err)
+	}
+	return false, nil
+}
+
+func uniqueInts(in []int) []int {
+	seen := make(map[int]bool, len(in))
+	out := in[:0]
+	for _, n := range in {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueRefs(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msgs []string
+		want []commit.IssueRef
+	}{
+		"none":      {[]string{"fix: something"}, nil},
+		"no number": {[]string{"fix: see #"}, nil},
+		"trailer":   {[]string{"fix: something\n\nFixes: #512"}, []commit.IssueRef{{Number: 512}}},
+		"several": {
+			[]string{"fix: one\n\nFixes #1, closes #2", "feat: two\n\nResolves #1\nRefs #3"},
+			[]commit.IssueRef{{Number: 1}, {Number: 2}, {Number: 3}},
+		},
+		"cross repo": {
+			[]string{"fix: one\n\nFixes owner/repo.go#12 and fixes #12"},
+			[]commit.IssueRef{{Owner: "owner", Repo: "repo.go", Number: 12}, {Number: 12}},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.IssueRefs(tc.msgs)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGitCommentIssues(t *testing.T) {
+	t.Parallel()
+	t.Run("Comments", testGitCommentIssuesComments)
+	t.Run("Limit", testGitCommentIssuesLimit)
+	t.Run("Failure", testGitCommentIssuesFailure)
+	t.Run("BadTemplate", testGitCommentIssuesBadTemplate)
+}
+
+func testGitCommentIssuesComments(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.4.0")
+	gh.issues[1] = "open"
+	gh.issues[2] = "closed"
+	gh.issues[3] = "open"
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{
+		"fix: one\n\nFixes #1\nFixes #2",
+		"fix: two\n\nFixes other/repo#3, fixes User/Repo#3",
+		"feat: three\n\nCloses #1",
+	}
+
+	summary, err := g.CommentIssues(ctx, "token", "user", "repo", logs, commit.IssueCommentOptions{
+		Tag: "v1.4.0",
+		URL: "https://example.com/v1.4.0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commit.IssueSummary{Commented: 2, Closed: 1, CrossRepo: 1}, summary)
+	want := map[int][]string{
+		1: {"Shipped in [v1.4.0](https://example.com/v1.4.0)."},
+		3: {"Shipped in [v1.4.0](https://example.com/v1.4.0)."},
+	}
+	assert.Equal(t, want, gh.comments)
+}
+
+func testGitCommentIssuesLimit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.4.0")
+	for i := 1; i <= 5; i++ {
+		gh.issues[i] = "open"
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{"fix: one\n\nFixes #1, fixes #2, fixes #3, fixes #4, fixes #5"}
+
+	summary, err := g.CommentIssues(ctx, "token", "user", "repo", logs, commit.IssueCommentOptions{
+		Tag:         "v1.4.0",
+		Template:    "{{.Tag}} fixes #{{.Issue}}",
+		Concurrency: 2,
+		Limit:       3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commit.IssueSummary{Commented: 3, OverLimit: 2}, summary)
+	assert.Equal(t, []string{"v1.4.0 fixes #3"}, gh.comments[3])
+	assert.NotContains(t, gh.comments, 4)
+}
+
+func testGitCommentIssuesFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.4.0")
+	gh.issues[2] = "open"
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{"fix: one\n\nFixes #1\nFixes #2"}
+
+	summary, err := g.CommentIssues(ctx, "token", "user", "repo", logs, commit.IssueCommentOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "#1: getting the issue")
+	assert.Equal(t, commit.IssueSummary{Commented: 1, Failed: 1}, summary)
+	assert.Len(t, gh.comments[2], 1)
+}
+
+func testGitCommentIssuesBadTemplate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.4.0")
+	gh.issues[1] = "open"
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{"fix: one\n\nFixes #1"}
+
+	_, err := g.CommentIssues(ctx, "token", "user", "repo", logs, commit.IssueCommentOptions{
+		Template: "{{.Tag",
+	})
+	assert.Error(t, err)
+	assert.Empty(t, gh.comments)
+}
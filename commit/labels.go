@@ -0,0 +1,234 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// mergePullRe matches the subjects of the merge commits of the pull
+	// requests.
+	mergePullRe = regexp.MustCompile(`^Merge pull request #(\d+)\b`)
+	// squashPullRe matches the number GitHub adds to the end of the subjects
+	// of the squashed and rebased pull requests.
+	squashPullRe = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+)
+
+// DefaultLabelMap maps the commit types to the labels GitHub creates in new
+// repositories.
+var DefaultLabelMap = LabelMap{
+	"feat": {"enhancement"},
+	"fix":  {"bug"},
+	"docs": {"documentation"},
+}
+
+// LabelMap maps the conventional types and scopes of the commits to the
+// labels of their pull requests. The keys are a type, e.g. "feat", a type with
+// a scope, e.g. "fix(api)", or only a scope, e.g. "(api)", which matches any
+// type. The keys are case insensitive.
+type LabelMap map[string][]string
+
+// ParseLabelMap parses the entries in the "key=label[,label]" form into a
+// LabelMap. The labels of the same key are merged.
+func ParseLabelMap(entries []string) (LabelMap, error) {
+	m := make(LabelMap, len(entries))
+	for _, e := range entries {
+		key, labels, ok := strings.Cut(e, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" || key == "()" {
+			return nil, fmt.Errorf("invalid label mapping %q, expected key=label", e)
+		}
+		for _, l := range strings.Split(labels, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				m[key] = append(m[key], l)
+			}
+		}
+		if len(m[key]) == 0 {
+			return nil, fmt.Errorf("invalid label mapping %q, no labels", e)
+		}
+	}
+	return m, nil
+}
+
+// Labels returns the labels of the type and the scopes of the message,
+// without duplicates. The message of a merge commit of a pull request is read
+// from its body, which is where GitHub puts the title of the pull request.
+func (m LabelMap) Labels(msg string) []string {
+	subject, body, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+	if mergePullRe.MatchString(subject) {
+		subject, _, _ = strings.Cut(strings.TrimSpace(body), "\n")
+	}
+	matches := descRe.FindStringSubmatch(subject)
+	if matches == nil || !strings.Contains(subject, ":") {
+		return nil
+	}
+	typ := strings.ToLower(strings.TrimSuffix(matches[1], "!"))
+	keys := []string{typ}
+	for _, scope := range strings.Split(strings.ToLower(matches[2]), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			keys = append(keys, typ+"("+scope+")", "("+scope+")")
+		}
+	}
+	var labels []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, l := range m[key] {
+			if !seen[strings.ToLower(l)] {
+				seen[strings.ToLower(l)] = true
+				labels = append(labels, l)
+			}
+		}
+	}
+	return labels
+}
+
+// PullNumber returns the number of the pull request the commit is merged
+// with, or zero if the message doesn't reference one.
+func PullNumber(msg string) int {
+	subject, _, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+	m := mergePullRe.FindStringSubmatch(subject)
+	if m == nil {
+		m = squashPullRe.FindStringSubmatch(subject)
+	}
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// LabelOptions configures LabelPulls.
+type LabelOptions struct {
+	// Map defaults to DefaultLabelMap.
+	Map LabelMap
+	// Interval is the minimum time between two requests that add labels,
+	// because GitHub limits the mutations more strictly than the reads. It
+	// defaults to one second.
+	Interval time.Duration
+}
+
+// LabelSummary is the outcome of labelling the pull requests.
+type LabelSummary struct {
+	Labelled int
+	// Unchanged is the number of pull requests that already have all their
+	// labels.
+	Unchanged int
+	// NotPull is the number of references that are issues and not pull
+	// requests.
+	NotPull int
+	Failed  int
+}
+
+// LabelPulls adds the labels of the types and scopes of the commits to the
+// pull requests they are merged with, so GitHub can categorize them in the
+// generated release notes. Only the missing labels are added, and the pull
+// requests are updated one at a time. A failed pull request doesn't stop the
+// others.
+func (g *Git) LabelPulls(ctx context.Context, token, user, repo string, logs []string, opts LabelOptions) (LabelSummary, error) {
+	var summary LabelSummary
+	m := opts.Map
+	if len(m) == 0 {
+		m = DefaultLabelMap
+	}
+	interval := opts.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	wanted := make(map[int][]string)
+	for _, log := range logs {
+		n := PullNumber(log)
+		if n == 0 {
+			continue
+		}
+		wanted[n] = append(wanted[n], m.Labels(log)...)
+	}
+	numbers := make([]int, 0, len(wanted))
+	for n, labels := range wanted {
+		if len(labels) > 0 {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	var (
+		failures []string
+		last     time.Time
+	)
+	for _, n := range numbers {
+		missing, pull, err := g.missingLabels(ctx, token, user, repo, n, wanted[n])
+		switch {
+		case err != nil:
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("#%d: %v", n, err))
+			continue
+		case !pull:
+			g.debugf("skipped #%d, it is not a pull request", n)
+			summary.NotPull++
+			continue
+		case len(missing) == 0:
+			summary.Unchanged++
+			continue
+		}
+		if wait := interval - time.Since(last); !last.IsZero() && wait > 0 {
+			select {
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		last = time.Now()
+		payload := map[string][]string{"labels": missing}
+		uri := fmt.Sprintf("/repos/%s/%s/issues/%d/labels", user, repo, n)
+		if err := g.api(ctx, token, http.MethodPost, uri, payload, nil); err != nil {
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("#%d: adding the labels: %v", n, err))
+			continue
+		}
+		g.debugf("labelled #%d with %s", n, strings.Join(missing, ", "))
+		summary.Labelled++
+	}
+	if len(failures) > 0 {
+		return summary, fmt.Errorf("failed to label %d pull request(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return summary, nil
+}
+
+// missingLabels returns the labels the pull request doesn't have. It returns
+// false if n is an issue.
+func (g *Git) missingLabels(ctx context.Context, token, user, repo string, n int, labels []string) ([]string, bool, error) {
+	var issue struct {
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest *struct{} `json:"pull_request"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/issues/%d", user, repo, n)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &issue); err != nil {
+		return nil, false, fmt.Errorf("getting the pull request: %w", err)
+	}
+	if issue.PullRequest == nil {
+		return nil, false, nil
+	}
+	has := make(map[string]bool, len(issue.Labels)+len(labels))
+	for _, l := range issue.Labels {
+		has[strings.ToLower(l.Name)] = true
+	}
+	var missing []string
+	for _, l := range labels {
+		if !has[strings.ToLower(l)] {
+			has[strings.ToLower(l)] = true
+			missing = append(missing, l)
+		}
+	}
+	return missing, true, nil
+}
@@ -0,0 +1,233 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelMap(t *testing.T) {
+	t.Parallel()
+	got, err := commit.ParseLabelMap([]string{"feat=enhancement", "Fix(API)=bug, api", "(docs)=documentation", "feat=new"})
+	require.NoError(t, err)
+	want := commit.LabelMap{
+		"feat":     {"enhancement", "new"},
+		"fix(api)": {"bug", "api"},
+		"(docs)":   {"documentation"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	for _, e := range []string{"feat", "=bug", "feat=", "feat= , ", "()=bug"} {
+		_, err := commit.ParseLabelMap([]string{e})
+		assert.Error(t, err, e)
+	}
+}
+
+func TestLabelMapLabels(t *testing.T) {
+	t.Parallel()
+	m := commit.LabelMap{
+		"feat":      {"enhancement"},
+		"fix":       {"bug"},
+		"fix(api)":  {"api", "Bug"},
+		"(ui)":      {"frontend"},
+		"chore(ci)": {"ci"},
+	}
+	tcs := map[string]struct {
+		msg  string
+		want []string
+	}{
+		"type":          {"feat: add the thing", []string{"enhancement"}},
+		"case":          {"Feat: add the thing", []string{"enhancement"}},
+		"breaking":      {"feat!: drop the thing", []string{"enhancement"}},
+		"type scope":    {"fix(api): handle the error", []string{"bug", "api"}},
+		"any type":      {"refactor(ui): move the buttons", []string{"frontend"}},
+		"scopes":        {"fix(api,ui): handle the error", []string{"bug", "api", "frontend"}},
+		"unmapped":      {"chore(deps): bump x", nil},
+		"not conv":      {"Update the readme", nil},
+		"merge":         {"Merge pull request #12 from user/branch\n\nfix(ui): align the buttons", []string{"bug", "frontend"}},
+		"merge no body": {"Merge pull request #12 from user/branch", nil},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := m.Labels(tc.msg)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullNumber(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msg  string
+		want int
+	}{
+		"merge":      {"Merge pull request #12 from user/branch\n\nfeat: add", 12},
+		"squash":     {"feat: add the thing (#34)\n\n* one\n* two", 34},
+		"none":       {"feat: add the thing", 0},
+		"in body":    {"feat: add the thing\n\nSee (#34)", 0},
+		"issue ref":  {"fix: crash\n\nFixes #3", 0},
+		"mid string": {"fix: crash (#3) on start", 0},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.PullNumber(tc.msg))
+		})
+	}
+}
+
+// fakeLabels serves the issues and the labels of the pull requests.
+type fakeLabels struct {
+	*httptest.Server
+	mu     sync.Mutex
+	pulls  map[int][]string
+	issues map[int]bool
+	added  map[int][]string
+	posts  []time.Time
+}
+
+func newFakeLabels(t *testing.T) *fakeLabels {
+	t.Helper()
+	f := &fakeLabels{
+		pulls:  make(map[int][]string),
+		issues: make(map[int]bool),
+		added:  make(map[int][]string),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/issues/")
+		n, err := strconv.Atoi(strings.TrimSuffix(rest, "/labels"))
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		labels, pull := f.pulls[n]
+		if !pull && !f.issues[n] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			names := make([]map[string]string, 0, len(labels))
+			for _, l := range labels {
+				names = append(names, map[string]string{"name": l})
+			}
+			issue := map[string]any{"number": n, "labels": names}
+			if pull {
+				issue["pull_request"] = map[string]string{"url": "https://example.com"}
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(issue))
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.True(t, strings.HasSuffix(rest, "/labels"))
+		var req struct {
+			Labels []string `json:"labels"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		f.posts = append(f.posts, time.Now())
+		f.added[n] = append(f.added[n], req.Labels...)
+		f.pulls[n] = append(f.pulls[n], req.Labels...)
+		fmt.Fprint(w, "[]")
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestGitLabelPulls(t *testing.T) {
+	t.Parallel()
+	t.Run("Labels", testGitLabelPullsLabels)
+	t.Run("Interval", testGitLabelPullsInterval)
+	t.Run("Failure", testGitLabelPullsFailure)
+}
+
+func testGitLabelPullsLabels(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeLabels(t)
+	gh.pulls[1] = nil
+	gh.pulls[2] = []string{"Bug"}
+	gh.pulls[3] = []string{"bug"}
+	gh.issues[4] = true
+	gh.pulls[5] = nil
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{
+		"feat(api): add the endpoint (#1)",
+		"Merge pull request #2 from user/fix\n\nfix(api): handle the error",
+		"fix: crash on start (#3)",
+		"feat: something (#4)",
+		"chore: tidy up (#5)",
+		"feat: not merged with a pull request",
+	}
+
+	summary, err := g.LabelPulls(ctx, "token", "user", "repo", logs, commit.LabelOptions{
+		Map: commit.LabelMap{
+			"feat":  {"enhancement"},
+			"fix":   {"bug"},
+			"(api)": {"api"},
+		},
+		Interval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commit.LabelSummary{Labelled: 2, Unchanged: 1, NotPull: 1}, summary)
+	want := map[int][]string{
+		1: {"enhancement", "api"},
+		2: {"api"},
+	}
+	assert.Equal(t, want, gh.added)
+}
+
+func testGitLabelPullsInterval(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeLabels(t)
+	logs := make([]string, 0, 3)
+	for i := 1; i <= 3; i++ {
+		gh.pulls[i] = nil
+		logs = append(logs, fmt.Sprintf("fix: crash %d (#%d)", i, i))
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	interval := 50 * time.Millisecond
+
+	summary, err := g.LabelPulls(ctx, "token", "user", "repo", logs, commit.LabelOptions{Interval: interval})
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Labelled)
+	require.Len(t, gh.posts, 3)
+	for i := 1; i < len(gh.posts); i++ {
+		assert.GreaterOrEqual(t, gh.posts[i].Sub(gh.posts[i-1]), interval)
+	}
+}
+
+func testGitLabelPullsFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeLabels(t)
+	gh.pulls[2] = nil
+	g := &commit.Git{BaseURL: gh.URL}
+	logs := []string{"fix: one (#1)", "fix: two (#2)"}
+
+	summary, err := g.LabelPulls(ctx, "token", "user", "repo", logs, commit.LabelOptions{Interval: time.Millisecond})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "#1: getting the pull request")
+	assert.Equal(t, commit.LabelSummary{Labelled: 1, Failed: 1}, summary)
+	assert.Equal(t, map[int][]string{2: {"bug"}}, gh.added)
+}
@@ -0,0 +1,159 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitLayout(t *testing.T) {
+	t.Parallel()
+	t.Run("Args", testGitLayoutArgs)
+	t.Run("SeparateGitDir", testGitLayoutSeparateGitDir)
+	t.Run("LinkedWorktree", testGitLayoutLinkedWorktree)
+}
+
+func testGitLayoutArgs(t *testing.T) {
+	t.Parallel()
+	var got []string
+	g := &commit.Git{
+		Dir:         "/repo",
+		GitDir:      "/meta/project.git",
+		WorkTree:    "/src/project",
+		ReplaceRefs: true,
+		Runner: fakeRunner(func(args []string) (string, error) {
+			got = args
+			return "0a1b2c\n", nil
+		}),
+	}
+	_, err := g.IsAncestor(context.Background(), "HEAD", "HEAD")
+	require.NoError(t, err)
+	require.Greater(t, len(got), 4)
+	assert.Equal(t, []string{
+		"--git-dir=/meta/project.git",
+		"--work-tree=/src/project",
+		"-c", "core.useReplaceRefs=true",
+	}, got[:4])
+}
+
+// layoutRepo returns a repository with the v1.0.0 and v1.1.0 tags, and the
+// VERSION and CHANGELOG.md files.
+func layoutRepo(t *testing.T) *committest.Repo {
+	t.Helper()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial",
+		committest.File{Path: "VERSION", Content: "1.0.0\n"},
+		committest.File{Path: "CHANGELOG.md", Content: "# Changelog\n\n## 1.0.0\n\n### Added\n\n- The thing\n"},
+	)
+	r.Tag("v1.0.0")
+	r.Commit("feat: the other thing")
+	r.Tag("v1.1.0")
+	r.AddRemote("origin", "git@github.com:arsham/layout.git")
+	return r
+}
+
+func testGitLayoutSeparateGitDir(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	src := layoutRepo(t)
+	base := t.TempDir()
+	gitDir := filepath.Join(base, "meta")
+	workTree := filepath.Join(base, "tree")
+	src.Run("clone", "--quiet", "--separate-git-dir="+gitDir, src.Dir, workTree)
+	wt := repo(t, workTree)
+	wt.Run("config", "user.name", "arsham")
+	wt.Run("config", "user.email", "arsham@github.com")
+	wt.Run("remote", "set-url", "origin", "git@github.com:arsham/layout.git")
+	wt.Commit("fix: the bug")
+
+	tcs := map[string]func() *commit.Git{
+		"dot git file": func() *commit.Git { return &commit.Git{Dir: workTree} },
+		"absolute": func() *commit.Git {
+			return &commit.Git{Dir: t.TempDir(), GitDir: gitDir, WorkTree: workTree}
+		},
+		"relative": func() *commit.Git {
+			return &commit.Git{Dir: base, GitDir: "meta", WorkTree: "tree"}
+		},
+	}
+	for name, newGit := range tcs {
+		newGit := newGit
+		t.Run(name, func(t *testing.T) {
+			g := newGit()
+			tag, err := g.LatestTag(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "v1.1.0", tag)
+
+			logs, err := g.Commits(ctx, "v1.0.0", "v1.1.0")
+			require.NoError(t, err)
+			require.Len(t, logs, 1)
+			assert.Contains(t, logs[0], "feat: the other thing")
+
+			p, err := g.Inspect(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, commit.ChangelogKeepAChangelog, p.Changelog)
+		})
+	}
+
+	// The subtests above only read, so the version is bumped once they are
+	// done.
+	g := &commit.Git{Dir: t.TempDir(), GitDir: gitDir, WorkTree: workTree}
+	changes, sha, err := g.BumpVersion(ctx, []commit.VersionFile{{Path: "VERSION"}}, "v1.2.0")
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, strings.TrimSpace(wt.Head()), sha)
+	assert.Equal(t, "1.2.0\n", readFile(t, filepath.Join(workTree, "VERSION")))
+	assert.Equal(t, "chore(release): v1.2.0", strings.TrimSpace(wt.Run("log", "-1", "--format=%s")))
+}
+
+func testGitLayoutLinkedWorktree(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	main := layoutRepo(t)
+	mainHead := strings.TrimSpace(main.Head())
+	workTree := filepath.Join(t.TempDir(), "release")
+	main.Run("worktree", "add", "--quiet", "-b", "release", workTree)
+	fi, err := os.Stat(filepath.Join(workTree, ".git"))
+	require.NoError(t, err)
+	require.False(t, fi.IsDir(), "the .git of a linked worktree is a file")
+
+	wt := repo(t, workTree)
+	wt.Commit("fix: the bug", committest.File{Path: "fix.txt", Content: "fixed"})
+	wt.Tag("v1.1.1")
+
+	g := &commit.Git{Dir: workTree}
+	tag, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.1", tag)
+	logs, err := g.Commits(ctx, "v1.1.0", "v1.1.1")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0], "fix: the bug")
+	p, err := g.Inspect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, commit.ChangelogKeepAChangelog, p.Changelog)
+
+	// The main worktree sees the tag, but not on its HEAD.
+	mg := &commit.Git{Dir: main.Dir}
+	tag, err = mg.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", tag)
+	ok, err := mg.IsAncestor(ctx, "v1.1.1", "HEAD")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	changes, sha, err := g.BumpVersion(ctx, []commit.VersionFile{{Path: "VERSION"}}, "v1.2.0")
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, strings.TrimSpace(wt.Head()), sha)
+	assert.Equal(t, "1.2.0\n", readFile(t, filepath.Join(workTree, "VERSION")))
+	assert.Equal(t, "1.0.0\n", readFile(t, filepath.Join(main.Dir, "VERSION")))
+	assert.Equal(t, mainHead, strings.TrimSpace(main.Head()))
+	assert.Equal(t, "release", strings.TrimSpace(wt.CurrentBranch()))
+}
@@ -0,0 +1,38 @@
+package commit
+
+import (
+	"context"
+	"regexp"
+)
+
+// Conforms returns true if the message follows the conventional commits, in
+// the sense that ParseGroups puts it in a section other than Misc.
+func Conforms(msg string) bool {
+	return GroupFromCommit(msg).Verb != "Misc"
+}
+
+// Lint returns the commits that don't follow the conventional commits. The
+// commits whose subject matches the allow regexp are not reported, e.g. merge
+// commits. The allow regexp can be nil.
+func Lint(commits []Commit, allow *regexp.Regexp) []Commit {
+	var issues []Commit
+	for _, c := range commits {
+		if allow != nil && allow.MatchString(c.Subject()) {
+			continue
+		}
+		if !Conforms(c.Message) {
+			issues = append(issues, c)
+		}
+	}
+	return issues
+}
+
+// Lint returns the commits between two tags that don't follow the
+// conventional commits. See Lint for the allow regexp.
+func (g *Git) Lint(ctx context.Context, tag1, tag2 string, allow *regexp.Regexp) ([]Commit, error) {
+	commits, err := g.Log(ctx, tag1, tag2)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(commits, allow), nil
+}
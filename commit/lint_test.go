@@ -0,0 +1,64 @@
+package commit_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConforms(t *testing.T) {
+	t.Parallel()
+	for _, msg := range []string{"feat: thing", "fix(api)!: thing", "chore: thing\n\nbody"} {
+		assert.True(t, commit.Conforms(msg), msg)
+	}
+	for _, msg := range []string{"", "update thing", "Merge branch 'master'", "🎉 release"} {
+		assert.False(t, commit.Conforms(msg), msg)
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	commits := []commit.Commit{
+		{SHA: "1", Message: "feat: thing"},
+		{SHA: "2", Message: "update thing"},
+		{SHA: "3", Message: "Merge branch 'master'\n\nfeat: thing"},
+		{SHA: "4", Message: "fix: thing"},
+	}
+
+	got := commit.Lint(commits, nil)
+	assert.Equal(t, []commit.Commit{commits[1], commits[2]}, got)
+
+	got = commit.Lint(commits, regexp.MustCompile(`^Merge `))
+	assert.Equal(t, []commit.Commit{commits[1]}, got)
+
+	assert.Empty(t, commit.Lint(commits[:1], nil))
+}
+
+func TestGitLint(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	g := &commit.Git{Dir: dir}
+
+	createFile(t, dir, "file.txt", "content")
+	commitChanges(t, dir, "initial commit")
+	createGitTag(t, dir, "v0.0.1")
+	for _, msg := range []string{"feat: thing", "update thing", "fix: thing"} {
+		appendToFile(t, dir, "file.txt", msg)
+		commitChanges(t, dir, msg)
+	}
+
+	got, err := g.Lint(ctx, "v0.0.1", "HEAD", nil)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "update thing", got[0].Subject())
+	assert.NotEmpty(t, got[0].SHA)
+
+	got, err = g.Lint(ctx, "", "HEAD", regexp.MustCompile(`^initial`))
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
@@ -0,0 +1,257 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Locale translates the fixed texts of the notes. The zero value renders them
+// in English.
+type Locale struct {
+	// Headings maps the names of the sections, e.g. "Feature", "Fix" or
+	// "Dependencies", to their translations. The keys are case insensitive,
+	// and the sections that are not in the map keep their English names.
+	Headings map[string]string
+	// DateFormat is the layout of the dates as in time.Format. It defaults to
+	// "2006-01-02".
+	DateFormat string
+	// Breaking replaces "BREAKING CHANGE" in the marker of the breaking
+	// changes.
+	Breaking string
+	// FullChangelog replaces "Full Changelog" in the compare link.
+	FullChangelog string
+	// ExtraRanges is the format of the note of WithCompareRanges after the
+	// compare link, with the ranges as its argument. It defaults to
+	// "also includes %s".
+	ExtraRanges string
+	// NoChanges is the format of the notes of a release without any commits,
+	// with the previous tag as its argument. It defaults to
+	// "No changes since %s.".
+	NoChanges string
+	// InternalChanges is the format of the note of the commits that are left
+	// out, with their number as its argument. It defaults to
+	// "%d internal changes.", or "1 internal change." for one.
+	InternalChanges string
+	// MoreChanges is the format of the line of the entries that are left out
+	// by WithBudget, with their number as its argument. It defaults to
+	// "…and %d more changes", or "…and 1 more change" for one.
+	MoreChanges string
+	// FirstRelease replaces "This is the first release." at the start of
+	// the notes of WithInitialRelease.
+	FirstRelease string
+	// ShowingLatest is the format of the line of WithLatest, with the
+	// numbers of the shown and all the changes as its arguments. It defaults
+	// to "Showing latest %d of %d changes.".
+	ShowingLatest string
+	// By replaces "by" before the authors of the entries of
+	// WithAttribution.
+	By string
+	// Contents replaces "Contents" in the title of the table of contents of
+	// WithTOC.
+	Contents string
+	// Details replaces "Details" in the summary of the collapsed bodies.
+	Details string
+	// ReadMore replaces "Read more" in the link of the truncated bodies.
+	ReadMore string
+	// LeadTime replaces "Lead time" in the stats of WithStats.
+	LeadTime string
+	// MedianAge replaces "Median commit age" in the stats of WithStats.
+	MedianAge string
+	// ActiveDays replaces "Active days" in the stats of WithStats.
+	ActiveDays string
+	// Backports replaces "Backported fixes" in the heading of
+	// WithBackports, unless the Headings translate it.
+	Backports string
+}
+
+// WithLocale renders the headings, the dates and the other fixed texts of the
+// notes with the locale.
+func WithLocale(l Locale) RenderOption {
+	return func(o *renderOptions) {
+		o.locale = l
+	}
+}
+
+// Validate returns an error if any of the formats of the locale doesn't take
+// its arguments, e.g. a NoChanges with a %d, or a MoreChanges without any
+// verb. The formats come from the config files, and fmt would render their
+// mistakes into the notes, e.g. "%!d(string=v1.0.0)". The arguments can be
+// reordered with the explicit indexes, e.g. "%[2]d".
+func (l Locale) Validate() error {
+	formats := []struct {
+		name   string
+		format string
+		args   []any
+	}{
+		{"extra-ranges", l.ExtraRanges, []any{"v1.0.0..v1.1.0"}},
+		{"no-changes", l.NoChanges, []any{"v1.0.0"}},
+		{"internal-changes", l.InternalChanges, []any{2}},
+		{"more-changes", l.MoreChanges, []any{2}},
+		{"showing-latest", l.ShowingLatest, []any{2, 3}},
+	}
+	for _, f := range formats {
+		if f.format == "" {
+			continue
+		}
+		if out := fmt.Sprintf(f.format, f.args...); strings.Contains(out, "%!") {
+			return fmt.Errorf("%s %q doesn't take its arguments: %s", f.name, f.format, out)
+		}
+	}
+	return nil
+}
+
+// Heading returns the translation of the name of the section, or the name if
+// it has none.
+func (l Locale) Heading(name string) string {
+	for k, v := range l.Headings {
+		if strings.EqualFold(k, name) && v != "" {
+			return v
+		}
+	}
+	if strings.EqualFold(name, backportsHeading) && l.Backports != "" {
+		return l.Backports
+	}
+	return name
+}
+
+// FormatDate formats the date with the DateFormat.
+func (l Locale) FormatDate(t time.Time) string {
+	if l.DateFormat == "" {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(l.DateFormat)
+}
+
+// breakingMarker returns the marker that is added to the breaking changes.
+func (l Locale) breakingMarker() string {
+	if l.Breaking == "" {
+		return "[**BREAKING CHANGE**]"
+	}
+	return "[**" + l.Breaking + "**]"
+}
+
+// fullChangelog returns the label of the compare link.
+func (l Locale) fullChangelog() string {
+	if l.FullChangelog == "" {
+		return "Full Changelog"
+	}
+	return l.FullChangelog
+}
+
+// extraRanges returns the note of the ranges that are not in the compare
+// link.
+func (l Locale) extraRanges(ranges string) string {
+	if l.ExtraRanges == "" {
+		return "also includes " + ranges
+	}
+	return fmt.Sprintf(l.ExtraRanges, ranges)
+}
+
+// noChanges returns the notes of a release without commits since prev.
+func (l Locale) noChanges(prev string) string {
+	if l.NoChanges == "" {
+		return fmt.Sprintf("No changes since %s.", prev)
+	}
+	return fmt.Sprintf(l.NoChanges, prev)
+}
+
+// firstRelease returns the line of the notes of the first release.
+func (l Locale) firstRelease() string {
+	if l.FirstRelease == "" {
+		return "This is the first release."
+	}
+	return l.FirstRelease
+}
+
+// showingLatest returns the line of the shown latest changes of the total.
+func (l Locale) showingLatest(shown, total int) string {
+	if l.ShowingLatest == "" {
+		return fmt.Sprintf("Showing latest %d of %d changes.", shown, total)
+	}
+	return fmt.Sprintf(l.ShowingLatest, shown, total)
+}
+
+// by returns the word before the author of an entry.
+func (l Locale) by() string {
+	if l.By == "" {
+		return "by"
+	}
+	return l.By
+}
+
+// contents returns the title of the table of contents.
+func (l Locale) contents() string {
+	if l.Contents == "" {
+		return "Contents"
+	}
+	return l.Contents
+}
+
+// internalChanges returns the note of the n commits that are left out.
+func (l Locale) internalChanges(n int) string {
+	switch {
+	case l.InternalChanges != "":
+		return fmt.Sprintf(l.InternalChanges, n)
+	case n == 1:
+		return "1 internal change."
+	}
+	return fmt.Sprintf("%d internal changes.", n)
+}
+
+// moreChanges returns the line of the n entries that are left out.
+func (l Locale) moreChanges(n int) string {
+	switch {
+	case l.MoreChanges != "":
+		return fmt.Sprintf(l.MoreChanges, n)
+	case n == 1:
+		return "…and 1 more change"
+	}
+	return fmt.Sprintf("…and %d more changes", n)
+}
+
+// details returns the summary of the collapsed bodies.
+func (l Locale) details() string {
+	if l.Details == "" {
+		return "Details"
+	}
+	return l.Details
+}
+
+// readMore returns the label of the link of the truncated bodies.
+func (l Locale) readMore() string {
+	if l.ReadMore == "" {
+		return "Read more"
+	}
+	return l.ReadMore
+}
+
+// leadTime returns the label of the lead time of the stats.
+func (l Locale) leadTime() string {
+	if l.LeadTime == "" {
+		return "Lead time"
+	}
+	return l.LeadTime
+}
+
+// medianAge returns the label of the median commit age of the stats.
+func (l Locale) medianAge() string {
+	if l.MedianAge == "" {
+		return "Median commit age"
+	}
+	return l.MedianAge
+}
+
+// activeDays returns the label of the active days of the stats.
+func (l Locale) activeDays() string {
+	if l.ActiveDays == "" {
+		return "Active days"
+	}
+	return l.ActiveDays
+}
+
+// NoChangesNotes returns the notes of a release without any commits since the
+// previous tag, translated with the locale of the opts.
+func NoChangesNotes(prev string, opts ...RenderOption) string {
+	return newRenderOptions(opts).locale.noChanges(prev)
+}
@@ -0,0 +1,113 @@
+package commit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+var germanLocale = commit.Locale{
+	Headings: map[string]string{
+		"feature":      "Neue Funktionen",
+		"FIX":          "Fehlerbehebungen",
+		"Dependencies": "Abhängigkeiten",
+	},
+	DateFormat:    "02.01.2006",
+	Breaking:      "INKOMPATIBEL",
+	FullChangelog: "Alle Änderungen",
+	NoChanges:     "Keine Änderungen seit %s.",
+}
+
+func TestLocaleHeading(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Neue Funktionen", germanLocale.Heading("Feature"))
+	assert.Equal(t, "Fehlerbehebungen", germanLocale.Heading("fix"))
+	assert.Equal(t, "Chore", germanLocale.Heading("Chore"))
+	assert.Equal(t, "Feature", commit.Locale{}.Heading("Feature"))
+}
+
+func TestLocaleFormatDate(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, "02.06.2024", germanLocale.FormatDate(date))
+	assert.Equal(t, "2024-06-02", commit.Locale{}.FormatDate(date))
+}
+
+func TestLocaleValidate(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		locale  commit.Locale
+		wantErr string
+	}{
+		"english":   {},
+		"german":    {locale: germanLocale},
+		"reordered": {locale: commit.Locale{ShowingLatest: "Von %[2]d Änderungen die letzten %[1]d."}},
+		"wrong verb": {
+			locale:  commit.Locale{NoChanges: "Keine Änderungen seit %d."},
+			wantErr: "no-changes",
+		},
+		"no verb": {
+			locale:  commit.Locale{MoreChanges: "…und weitere Änderungen"},
+			wantErr: "more-changes",
+		},
+		"missing": {
+			locale:  commit.Locale{ShowingLatest: "Die letzten %d Änderungen."},
+			wantErr: "showing-latest",
+		},
+		"extra": {
+			locale:  commit.Locale{InternalChanges: "%d interne Änderungen seit %s."},
+			wantErr: "internal-changes",
+		},
+		"escaped": {locale: commit.Locale{ExtraRanges: "100%% von %s"}},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.locale.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseGroupsLocale(t *testing.T) {
+	t.Parallel()
+	logs := []string{"fix(api)!: handle the error"}
+	deps := commit.RenderDependencies([]commit.DepChange{{Path: "example.com/mod", Old: "v1.0.0", New: "v1.1.0"}}, false)
+	got := commit.ParseGroups(logs,
+		commit.WithLocale(germanLocale),
+		commit.WithDependencies(deps, false),
+		commit.WithCompareLink("https://example.com/compare"),
+	)
+	want := "### Fehlerbehebungen\n\n- **Api:** Handle the error [**INKOMPATIBEL**]\n\n" +
+		"### Abhängigkeiten\n\n- Upgrade `example.com/mod` from v1.0.0 to v1.1.0\n\n" +
+		"**Alle Änderungen**: https://example.com/compare"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups([]string{"fix: one\n\nBREAKING CHANGE: it's gone"}, commit.WithLocale(germanLocale))
+	assert.Equal(t, "### Fehlerbehebungen\n\n- One [**INKOMPATIBEL**]", got)
+}
+
+func TestRenderReleasesLocale(t *testing.T) {
+	t.Parallel()
+	sections := []commit.ReleaseSection{
+		{Tag: "v1.1.0", Previous: "v1.0.0", Date: time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)},
+		{Tag: "v1.0.0", Previous: "v0.9.0", Date: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), Logs: []string{"feat: add thing"}},
+	}
+	got := commit.RenderReleases(sections, commit.WithLocale(germanLocale))
+	want := "## v1.1.0 (10.06.2024)\n\nKeine Änderungen seit v1.0.0.\n\n" +
+		"## v1.0.0 (02.06.2024)\n\n### Neue Funktionen\n\n- Add thing"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Equal(t, "No changes since v1.0.0.", commit.NoChangesNotes("v1.0.0"))
+}
@@ -0,0 +1,208 @@
+package commit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics collects where the time of a run goes: the durations of the stages,
+// the git processes, the API calls and the uploaded bytes. It is safe for
+// concurrent use, and a nil *Metrics drops them.
+type Metrics struct {
+	mu     sync.Mutex
+	report MetricsReport
+}
+
+// MetricsReport is the snapshot of the Metrics.
+type MetricsReport struct {
+	// Stages are in the order they are first started.
+	Stages      []StageMetrics
+	GitCommands int
+	GitDuration time.Duration
+	// APICalls are the requests to the API of the provider, including the
+	// uploads of the assets.
+	APICalls    int
+	APIDuration time.Duration
+	// UploadedBytes are the bytes of the uploaded assets.
+	UploadedBytes int64
+}
+
+// StageMetrics are the metrics of the runs of a stage.
+type StageMetrics struct {
+	Name     string
+	Count    int
+	Failed   int
+	Duration time.Duration
+}
+
+// Start records the duration of the stage until the returned function is
+// called with its result. A stage can run more than once.
+func (m *Metrics) Start(stage string) func(err error) {
+	if m == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	return func(err error) {
+		d := time.Since(start)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		s := m.stage(stage)
+		s.Count++
+		s.Duration += d
+		if err != nil {
+			s.Failed++
+		}
+	}
+}
+
+// stage returns the metrics of the stage, which are added if it's new. The
+// mu should be held.
+func (m *Metrics) stage(name string) *StageMetrics {
+	for i := range m.report.Stages {
+		if m.report.Stages[i].Name == name {
+			return &m.report.Stages[i]
+		}
+	}
+	m.report.Stages = append(m.report.Stages, StageMetrics{Name: name})
+	return &m.report.Stages[len(m.report.Stages)-1]
+}
+
+// gitCommand returns the function that records a git process when it ends.
+func (m *Metrics) gitCommand() func() {
+	if m == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.report.GitCommands++
+		m.report.GitDuration += d
+	}
+}
+
+// uploaded records the bytes of an uploaded asset.
+func (m *Metrics) uploaded(n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.report.UploadedBytes += n
+}
+
+// Report returns the snapshot of the metrics.
+func (m *Metrics) Report() MetricsReport {
+	if m == nil {
+		return MetricsReport{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.report
+	r.Stages = append([]StageMetrics(nil), r.Stages...)
+	return r
+}
+
+// MarshalJSON returns the report with the durations in seconds.
+func (r MetricsReport) MarshalJSON() ([]byte, error) {
+	type stage struct {
+		Name    string  `json:"name"`
+		Count   int     `json:"count"`
+		Failed  int     `json:"failed"`
+		Seconds float64 `json:"seconds"`
+	}
+	v := struct {
+		Stages        []stage `json:"stages"`
+		GitCommands   int     `json:"git_commands"`
+		GitSeconds    float64 `json:"git_seconds"`
+		APICalls      int     `json:"api_calls"`
+		APISeconds    float64 `json:"api_seconds"`
+		UploadedBytes int64   `json:"uploaded_bytes"`
+	}{
+		Stages:        make([]stage, 0, len(r.Stages)),
+		GitCommands:   r.GitCommands,
+		GitSeconds:    r.GitDuration.Seconds(),
+		APICalls:      r.APICalls,
+		APISeconds:    r.APIDuration.Seconds(),
+		UploadedBytes: r.UploadedBytes,
+	}
+	for _, s := range r.Stages {
+		v.Stages = append(v.Stages, stage{Name: s.Name, Count: s.Count, Failed: s.Failed, Seconds: s.Duration.Seconds()})
+	}
+	return json.Marshal(v)
+}
+
+// OpenMetrics returns the report in the OpenMetrics text format, e.g. for
+// pushing it to a Prometheus Pushgateway. The names have the gitrelease_
+// prefix, and the stages are labelled with their names.
+func (r MetricsReport) OpenMetrics() string {
+	buf := &strings.Builder{}
+	metric := func(name, typ, help string, samples ...string) {
+		fmt.Fprintf(buf, "# TYPE gitrelease_%s %s\n# HELP gitrelease_%s %s\n", name, typ, name, help)
+		for _, s := range samples {
+			fmt.Fprintf(buf, "gitrelease_%s\n", s)
+		}
+	}
+	var durations, runs, failures []string
+	for _, s := range r.Stages {
+		label := fmt.Sprintf("{stage=%q}", s.Name)
+		durations = append(durations, fmt.Sprintf("stage_duration_seconds_total%s %s", label, seconds(s.Duration)))
+		runs = append(runs, fmt.Sprintf("stage_runs_total%s %d", label, s.Count))
+		failures = append(failures, fmt.Sprintf("stage_failures_total%s %d", label, s.Failed))
+	}
+	metric("stage_duration_seconds", "counter", "Time spent in the stages of the release.", durations...)
+	metric("stage_runs", "counter", "Runs of the stages of the release.", runs...)
+	metric("stage_failures", "counter", "Failed runs of the stages of the release.", failures...)
+	metric("git_commands", "counter", "Git processes that are run.", fmt.Sprintf("git_commands_total %d", r.GitCommands))
+	metric("git_duration_seconds", "counter", "Time spent in the git processes.", "git_duration_seconds_total "+seconds(r.GitDuration))
+	metric("api_calls", "counter", "Requests to the API, including the uploads.", fmt.Sprintf("api_calls_total %d", r.APICalls))
+	metric("api_duration_seconds", "counter", "Time spent in the requests to the API.", "api_duration_seconds_total "+seconds(r.APIDuration))
+	metric("uploaded_bytes", "counter", "Bytes of the uploaded assets.", fmt.Sprintf("uploaded_bytes_total %d", r.UploadedBytes))
+	buf.WriteString("# EOF\n")
+	return buf.String()
+}
+
+// seconds formats the duration in seconds for the OpenMetrics text format.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.6f", d.Seconds())
+}
+
+// metricsTransport records the requests that are sent with next.
+type metricsTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	d := time.Since(start)
+	t.metrics.mu.Lock()
+	defer t.metrics.mu.Unlock()
+	t.metrics.report.APICalls++
+	t.metrics.report.APIDuration += d
+	return resp, err
+}
+
+// client returns a copy of the c that records its requests, or the c if the
+// metrics are dropped.
+func (m *Metrics) client(c *http.Client) *http.Client {
+	if m == nil {
+		return c
+	}
+	if c == nil {
+		c = http.DefaultClient
+	}
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	recorded := *c
+	recorded.Transport = &metricsTransport{next: next, metrics: m}
+	return &recorded
+}
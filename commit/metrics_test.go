@@ -0,0 +1,142 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsStart(t *testing.T) {
+	t.Parallel()
+	m := &commit.Metrics{}
+	m.Start("Generating notes")(nil)
+	m.Start("Uploading assets")(errors.New("boom"))
+	m.Start("Generating notes")(nil)
+	got := m.Report()
+	require.Len(t, got.Stages, 2)
+	assert.Equal(t, "Generating notes", got.Stages[0].Name)
+	assert.Equal(t, 2, got.Stages[0].Count)
+	assert.Zero(t, got.Stages[0].Failed)
+	assert.Equal(t, "Uploading assets", got.Stages[1].Name)
+	assert.Equal(t, 1, got.Stages[1].Failed)
+
+	var dropped *commit.Metrics
+	dropped.Start("Generating notes")(nil)
+	assert.Equal(t, commit.MetricsReport{}, dropped.Report())
+}
+
+func TestGitMetrics(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("Git", func(t *testing.T) {
+		t.Parallel()
+		r := committest.NewRepo(t, identity)
+		r.Commit("chore: initial")
+		r.Tag("v1.0.0")
+		m := &commit.Metrics{}
+		g := &commit.Git{Dir: r.Dir, Metrics: m}
+		_, err := g.LatestTag(ctx)
+		require.NoError(t, err)
+		got := m.Report()
+		assert.Positive(t, got.GitCommands)
+		assert.Positive(t, got.GitDuration)
+		assert.Zero(t, got.APICalls)
+	})
+
+	t.Run("API", func(t *testing.T) {
+		t.Parallel()
+		gh := newFakeGitHub(t, "v1.0.0")
+		dir := t.TempDir()
+		createFile(t, dir, "a.zip", "content a")
+		createFile(t, dir, "b.zip", "content b")
+		m := &commit.Metrics{}
+		g := &commit.Git{BaseURL: gh.URL, Metrics: m}
+		assets := []commit.Asset{
+			{Path: filepath.Join(dir, "a.zip"), Name: "app_linux.zip"},
+			{Path: filepath.Join(dir, "b.zip"), Name: "app_darwin.zip"},
+		}
+		_, err := g.UploadAssets(ctx, "token", "user", "repo", "v1.0.0", assets, false)
+		require.NoError(t, err)
+		got := m.Report()
+		// The release is looked up before the uploads.
+		assert.GreaterOrEqual(t, got.APICalls, 3)
+		assert.Positive(t, got.APIDuration)
+		assert.Equal(t, int64(len("content a")+len("content b")), got.UploadedBytes)
+		assert.Zero(t, got.GitCommands)
+	})
+}
+
+func TestMetricsReportFormats(t *testing.T) {
+	t.Parallel()
+	r := commit.MetricsReport{
+		Stages: []commit.StageMetrics{
+			{Name: "Generating notes", Count: 1, Duration: 1500 * time.Millisecond},
+			{Name: "Uploading assets", Count: 2, Failed: 1, Duration: 3 * time.Second},
+		},
+		GitCommands:   12,
+		GitDuration:   250 * time.Millisecond,
+		APICalls:      4,
+		APIDuration:   2 * time.Second,
+		UploadedBytes: 2048,
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+		b, err := json.Marshal(r)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"stages": [
+				{"name": "Generating notes", "count": 1, "failed": 0, "seconds": 1.5},
+				{"name": "Uploading assets", "count": 2, "failed": 1, "seconds": 3}
+			],
+			"git_commands": 12,
+			"git_seconds": 0.25,
+			"api_calls": 4,
+			"api_seconds": 2,
+			"uploaded_bytes": 2048
+		}`, string(b))
+	})
+
+	t.Run("OpenMetrics", func(t *testing.T) {
+		t.Parallel()
+		want := `# TYPE gitrelease_stage_duration_seconds counter
+# HELP gitrelease_stage_duration_seconds Time spent in the stages of the release.
+gitrelease_stage_duration_seconds_total{stage="Generating notes"} 1.500000
+gitrelease_stage_duration_seconds_total{stage="Uploading assets"} 3.000000
+# TYPE gitrelease_stage_runs counter
+# HELP gitrelease_stage_runs Runs of the stages of the release.
+gitrelease_stage_runs_total{stage="Generating notes"} 1
+gitrelease_stage_runs_total{stage="Uploading assets"} 2
+# TYPE gitrelease_stage_failures counter
+# HELP gitrelease_stage_failures Failed runs of the stages of the release.
+gitrelease_stage_failures_total{stage="Generating notes"} 0
+gitrelease_stage_failures_total{stage="Uploading assets"} 1
+# TYPE gitrelease_git_commands counter
+# HELP gitrelease_git_commands Git processes that are run.
+gitrelease_git_commands_total 12
+# TYPE gitrelease_git_duration_seconds counter
+# HELP gitrelease_git_duration_seconds Time spent in the git processes.
+gitrelease_git_duration_seconds_total 0.250000
+# TYPE gitrelease_api_calls counter
+# HELP gitrelease_api_calls Requests to the API, including the uploads.
+gitrelease_api_calls_total 4
+# TYPE gitrelease_api_duration_seconds counter
+# HELP gitrelease_api_duration_seconds Time spent in the requests to the API.
+gitrelease_api_duration_seconds_total 2.000000
+# TYPE gitrelease_uploaded_bytes counter
+# HELP gitrelease_uploaded_bytes Bytes of the uploaded assets.
+gitrelease_uploaded_bytes_total 2048
+# EOF
+`
+		assert.Equal(t, want, r.OpenMetrics())
+	})
+}
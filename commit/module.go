@@ -0,0 +1,142 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Module is a Go module in the repository. Following the Go convention, the
+// tags of a module in a sub-directory are prefixed with the directory, e.g.
+// "mod/sub/v1.2.3" has the "mod/sub/" prefix and the "v1.2.3" version.
+type Module struct {
+	// Dir is the directory of the module relative to the repository's root. It
+	// is "." for the module in the root.
+	Dir string `json:"dir"`
+	// TagPrefix is the prefix of the module's tags.
+	TagPrefix string `json:"tag_prefix"`
+	// LatestTag is the last tag of the module, or empty if it has no tags.
+	LatestTag string `json:"latest_tag"`
+	// Commits is the number of commits touching the module since LatestTag.
+	Commits int `json:"commits"`
+	// NextVersion is the suggested version for the next release, calculated
+	// with the Scheme. It is empty if the module doesn't need a release.
+	NextVersion string `json:"next_version,omitempty"`
+	// NextTag is NextVersion with the TagPrefix.
+	NextTag string `json:"next_tag,omitempty"`
+}
+
+// NeedsRelease returns true if there are any commits touching the module since
+// its latest tag.
+func (m Module) NeedsRelease() bool {
+	return m.Commits > 0
+}
+
+// Modules returns all Go modules in the repository, sorted by their
+// directories. The commits of each module are the ones touching its directory,
+// excluding the nested modules. Only the tags matching the Scheme are
+// considered, which defaults to SemVer.
+func (g *Git) Modules(ctx context.Context) ([]Module, error) {
+	dirs, err := g.moduleDirs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modules := make([]Module, 0, len(dirs))
+	for _, dir := range dirs {
+		m, _, err := g.module(ctx, dir, dirs)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", dir, err)
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+// moduleDirs returns the directories of all tracked go.mod files.
+func (g *Git) moduleDirs(ctx context.Context) ([]string, error) {
+	out, err := g.run(ctx, newCommand("ls-files", "--full-name", "-z").path(":(top,glob)**/go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("listing go.mod files: %w", err)
+	}
+	var dirs []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f == "" {
+			continue
+		}
+		dirs = append(dirs, path.Dir(f))
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// module returns the module in the dir and the logs of its commits since its
+// latest tag. The all slice should contain the directories of all modules.
+func (g *Git) module(ctx context.Context, dir string, all []string) (Module, []string, error) {
+	m := Module{Dir: dir}
+	paths := []string{":(top)"}
+	if dir != "." {
+		m.TagPrefix = dir + "/"
+		paths[0] += dir
+	}
+	for _, other := range all {
+		if other != dir && (dir == "." || strings.HasPrefix(other, dir+"/")) {
+			paths = append(paths, ":(top,exclude)"+other)
+		}
+	}
+
+	mg := &Git{
+		Dir:           g.Dir,
+		GitDir:        g.GitDir,
+		WorkTree:      g.WorkTree,
+		Logger:        g.Logger,
+		Runner:        g.Runner,
+		RangeMode:     g.RangeMode,
+		TagPrefix:     m.TagPrefix,
+		Paths:         paths,
+		Scheme:        g.Scheme,
+		AnnotatedOnly: g.AnnotatedOnly,
+		NoMerges:      g.NoMerges,
+		FirstParent:   g.FirstParent,
+		Timeout:       g.Timeout,
+		Order:         g.Order,
+		SlowThreshold: g.SlowThreshold,
+		SlowLogger:    g.SlowLogger,
+		Warnings:      g.Warnings,
+		MaxSubject:    g.MaxSubject,
+		Exclude:       g.Exclude,
+	}
+	if mg.Scheme == nil {
+		mg.Scheme = SemVer{}
+	}
+	latest, err := mg.LatestTag(ctx)
+	if err != nil && !errors.Is(err, ErrNoTag) {
+		return m, nil, err
+	}
+	m.LatestTag = latest
+
+	// Most modules of a large repository are unchanged, and counting is
+	// cheaper than reading the messages.
+	m.Commits, err = mg.CountCommits(ctx, latest, "HEAD")
+	if err != nil {
+		return m, nil, err
+	}
+	if !m.NeedsRelease() {
+		return m, nil, nil
+	}
+	logs, err := mg.Commits(ctx, latest, "HEAD")
+	if err != nil {
+		return m, nil, err
+	}
+	current := strings.TrimPrefix(latest, m.TagPrefix)
+	next, err := mg.Scheme.Next(current, BumpFromLogs(logs), time.Now())
+	if err != nil {
+		return m, nil, err
+	}
+	m.NextVersion = next
+	m.NextTag = m.TagPrefix + next
+	return m, logs, nil
+}
@@ -0,0 +1,88 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitModules(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createModulesRepo(t)
+
+	g := &commit.Git{Dir: dir}
+	got, err := g.Modules(ctx)
+	require.NoError(t, err)
+	want := []commit.Module{
+		{
+			Dir:         ".",
+			LatestTag:   "v1.0.0",
+			Commits:     1,
+			NextVersion: "v1.0.1",
+			NextTag:     "v1.0.1",
+		},
+		{
+			Dir:       "mod",
+			TagPrefix: "mod/",
+			LatestTag: "mod/v0.1.0",
+		},
+		{
+			Dir:         "mod/sub",
+			TagPrefix:   "mod/sub/",
+			LatestTag:   "mod/sub/v1.2.3",
+			Commits:     2,
+			NextVersion: "v1.3.0",
+			NextTag:     "mod/sub/v1.3.0",
+		},
+		{
+			Dir:         "tools",
+			TagPrefix:   "tools/",
+			Commits:     1,
+			NextVersion: "v0.1.0",
+			NextTag:     "tools/v0.1.0",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestGitModulesCalVer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "go.mod", "module example.com/app\n")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "2020.01.3")
+	createFile(t, dir, "main.go", "package main\n")
+	commitChanges(t, dir, "fix: something")
+	// The semver tag should be ignored.
+	createGitTag(t, dir, "v1.0.0")
+	createFile(t, dir, "other.go", "package main\n")
+	commitChanges(t, dir, "fix: something else")
+
+	scheme, err := commit.NewCalVer("YYYY.0M.MICRO")
+	require.NoError(t, err)
+	g := &commit.Git{Dir: dir, Scheme: scheme}
+	got, err := g.Modules(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	next, err := scheme.Next("2020.01.3", commit.BumpNone, time.Now())
+	require.NoError(t, err)
+	want := commit.Module{
+		Dir:         ".",
+		LatestTag:   "2020.01.3",
+		Commits:     2,
+		NextVersion: next,
+		NextTag:     next,
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
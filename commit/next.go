@@ -0,0 +1,51 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrHeadTagged is returned by PrepareNext when the HEAD already has a tag.
+var ErrHeadTagged = errors.New("HEAD is already tagged")
+
+// PrepareNext is like Prepare, but for the commits since the previous tag up
+// to the HEAD, which is not tagged yet. The Tag is the next version of the
+// previous tag with the Scheme, SemVer by default, bumped by the Logs, or the
+// InitialTag if there is no previous tag. The release is Untagged, and its
+// Target is the commit of the HEAD to tag. It returns an ErrHeadTagged error
+// if the HEAD already has a tag.
+func (g *Git) PrepareNext(ctx context.Context) (*ReleaseInfo, error) {
+	head, err := g.Head(ctx)
+	if err != nil {
+		return nil, g.emptyError(ctx, err)
+	}
+	if len(head.Tags) > 0 {
+		return nil, fmt.Errorf("%w with %s", ErrHeadTagged, strings.Join(head.Tags, ", "))
+	}
+	info, err := g.Prepare(ctx, head.Commit)
+	if err != nil {
+		return nil, err
+	}
+	info.Tag = g.InitialTag()
+	if !info.Initial {
+		scheme := g.Scheme
+		if scheme == nil {
+			scheme = SemVer{}
+		}
+		current := strings.TrimPrefix(info.PreviousTag, g.TagPrefix)
+		next, err := scheme.Next(current, BumpFromLogs(info.Logs), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("the next version of %s: %w", info.PreviousTag, err)
+		}
+		info.Tag = g.TagPrefix + next
+	}
+	info.Untagged, info.Target = true, head.Commit
+	info.CompareURL = g.RangeMode.CompareURL(info.Remote, info.PreviousTag, info.Tag)
+	if info.Initial {
+		info.CompareURL = info.Remote.CommitsURL(info.Tag)
+	}
+	return info, nil
+}
@@ -0,0 +1,77 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitPrepareNext(t *testing.T) {
+	t.Parallel()
+	t.Run("Next", testGitPrepareNextNext)
+	t.Run("Initial", testGitPrepareNextInitial)
+	t.Run("Tagged", testGitPrepareNextTagged)
+}
+
+func testGitPrepareNextNext(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:owner/name.git")
+	r.Commit("chore: initial")
+	r.Tag("mod/v1.2.3")
+	r.Commit("fix: the leak")
+	r.Commit("feat: the thing")
+	head := r.Head()
+
+	g := &commit.Git{Dir: r.Dir, TagPrefix: "mod/"}
+	info, err := g.PrepareNext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "mod/v1.3.0", info.Tag)
+	assert.Equal(t, "mod/v1.2.3", info.PreviousTag)
+	assert.Equal(t, head, info.Target)
+	assert.True(t, info.Untagged)
+	assert.False(t, info.Initial)
+	assert.Len(t, info.Logs, 2)
+	assert.Equal(t, "https://github.com/owner/name/compare/mod/v1.2.3...mod/v1.3.0", info.CompareURL)
+
+	// The tag keeps the notes as they are.
+	notes := "### Feature\n\n- The thing\n\n# not a comment\n"
+	require.NoError(t, g.CreateAnnotatedTag(context.Background(), info.Tag, info.Target, notes))
+	assert.Equal(t, "tag", strings.TrimSpace(r.Run("cat-file", "-t", info.Tag)))
+	assert.Equal(t, head, strings.TrimSpace(r.Run("rev-parse", info.Tag+"^{commit}")))
+	assert.Equal(t, notes+"\n", r.Run("tag", "--list", "--format=%(contents)", info.Tag))
+}
+
+func testGitPrepareNextInitial(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:owner/name.git")
+	r.Commit("feat!: the thing")
+
+	g := &commit.Git{Dir: r.Dir, InitialVersion: "v0.2.0"}
+	info, err := g.PrepareNext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0", info.Tag)
+	assert.True(t, info.Initial)
+	assert.True(t, info.Untagged)
+	assert.Equal(t, "https://github.com/owner/name/commits/v0.2.0", info.CompareURL)
+}
+
+func testGitPrepareNextTagged(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, identity)
+	r.Commit("feat: the thing")
+	r.Tag("v1.0.0")
+
+	_, err := (&commit.Git{Dir: r.Dir}).PrepareNext(context.Background())
+	require.ErrorIs(t, err, commit.ErrHeadTagged)
+	assert.ErrorContains(t, err, "v1.0.0")
+
+	_, err = (&commit.Git{Dir: committest.NewRepo(t).Dir}).PrepareNext(context.Background())
+	assert.ErrorIs(t, err, commit.ErrEmptyRepository)
+}
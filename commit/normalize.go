@@ -0,0 +1,118 @@
+package commit
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxSubject is the number of characters after which the subjects of
+// the commits are truncated.
+const DefaultMaxSubject = 200
+
+// noSubject replaces the subjects that are empty or only whitespace.
+const noSubject = "(no subject)"
+
+// ansiRe matches the terminal escape sequences, e.g. colours and titles.
+var ansiRe = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\))`)
+
+// Normalize returns the commit with a message that renders as one line item.
+// The control characters, other than the new lines and the tabs, are removed.
+// An empty subject becomes "(no subject)" followed by the short SHA. A subject
+// longer than max characters is truncated with an ellipsis, and the full
+// subject is kept as the first paragraph of the body. If max is zero or less,
+// the subject is not truncated. The Note is normalized the same way.
+func (c Commit) Normalize(max int) Commit {
+	c.Message = normalizeMessage(c.Message, c.SHA, max)
+	if c.Note != "" {
+		c.Note = normalizeMessage(strings.TrimSpace(c.Note), "", max)
+	}
+	return c
+}
+
+func normalizeMessage(msg, sha string, max int) string {
+	msg = stripControl(msg)
+	subject, body, hasBody := strings.Cut(msg, "\n")
+	subject = strings.TrimSpace(subject)
+	switch {
+	case subject == "":
+		// The body of a message that starts with a blank line is kept.
+		subject = noSubject
+		if sha != "" {
+			subject += " " + shortSHA(sha)
+		}
+	case max > 0 && utf8.RuneCountInString(subject) > max:
+		full := subject
+		runes := []rune(subject)
+		subject = strings.TrimRightFunc(string(runes[:max-1]), unicode.IsSpace) + "…"
+		body = "\n" + full + "\n" + body
+		hasBody = true
+	default:
+		return msg
+	}
+	if !hasBody {
+		return subject
+	}
+	return subject + "\n" + body
+}
+
+// tidyMessage returns the msg with LF line endings and without the blank
+// lines at its end. The lines of the first paragraph are joined into the
+// subject, and the body is separated from it by one blank line. If the msg
+// starts with a blank line, the subject is empty and the body starts at the
+// first line that is not blank, e.g. "\n\nClose #12".
+func tidyMessage(msg string) string {
+	msg = strings.ReplaceAll(msg, "\r\n", "\n")
+	msg = strings.ReplaceAll(msg, "\r", "\n")
+	lines := strings.Split(msg, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	end := 0
+	for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+		end++
+	}
+	subject := make([]string, 0, end)
+	for _, line := range lines[:end] {
+		subject = append(subject, strings.TrimSpace(line))
+	}
+	body := lines[end:]
+	for len(body) > 0 && strings.TrimSpace(body[0]) == "" {
+		body = body[1:]
+	}
+	if len(body) == 0 {
+		return strings.Join(subject, " ")
+	}
+	return strings.Join(subject, " ") + "\n\n" + strings.Join(body, "\n")
+}
+
+// stripControl removes the terminal escape sequences and the control
+// characters other than the new lines and the tabs.
+func stripControl(s string) string {
+	if strings.IndexFunc(s, isStripped) < 0 {
+		return s
+	}
+	s = ansiRe.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if isStripped(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func isStripped(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t'
+}
+
+// shortSHA returns the abbreviated form of the sha.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
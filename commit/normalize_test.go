@@ -0,0 +1,159 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sha = "0123456789abcdef0123456789abcdef01234567"
+
+func TestCommitNormalize(t *testing.T) {
+	t.Parallel()
+	long := "feat: " + strings.Repeat("generated ", 10)
+	tcs := map[string]struct {
+		msg  string
+		max  int
+		want string
+	}{
+		"normal":           {"fix: thing\n\nbody\n", 20, "fix: thing\n\nbody\n"},
+		"empty":            {"", 20, "(no subject) 0123456"},
+		"only whitespace":  {"   \t ", 20, "(no subject) 0123456"},
+		"whitespace body":  {"  \n\nClose #12\n", 20, "(no subject) 0123456\n\nClose #12\n"},
+		"trailing newline": {"\n", 20, "(no subject) 0123456\n"},
+		"exact length":     {"fix: twenty chars ok", 20, "fix: twenty chars ok"},
+		"too long": {
+			long, 20,
+			"feat: generated gen…\n\n" + strings.TrimSpace(long) + "\n",
+		},
+		"too long with body": {
+			long + "\n\nClose #12\n", 20,
+			"feat: generated gen…\n\n" + strings.TrimSpace(long) + "\n\nClose #12\n",
+		},
+		"trailing space cut": {"feat: generated generated", 17, "feat: generated…\n\nfeat: generated generated\n"},
+		"multibyte": {
+			"feat: ñññññññññññññññ", 10,
+			"feat: ñññ…\n\nfeat: ñññññññññññññññ\n",
+		},
+		"no limit":         {long, 0, long},
+		"negative limit":   {long, -1, long},
+		"control chars":    {"fix: bell\a and\x00 nul\n\nbody\x1b\n", 20, "fix: bell and nul\n\nbody\n"},
+		"ansi colours":     {"fix: \x1b[31mred\x1b[0m thing", 20, "fix: red thing"},
+		"tabs kept":        {"fix:\tthing\n\n\tcode\n", 20, "fix:\tthing\n\n\tcode\n"},
+		"c1 control":       {"fix: thing\u0085", 20, "fix: thing"},
+		"only control":     {"\x1b[0m\x07", 20, "(no subject) 0123456"},
+		"unicode kept":     {"fix: 修复 🐛", 20, "fix: 修复 🐛"},
+		"carriage returns": {"fix: thing\r\n\r\nbody", 20, "fix: thing\n\nbody"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := commit.Commit{SHA: sha, Message: tc.msg}
+			got := c.Normalize(tc.max)
+			assert.Equal(t, tc.want, got.Message)
+			assert.Equal(t, sha, got.SHA)
+			if tc.max > 0 {
+				assert.LessOrEqual(t, utf8.RuneCountInString(got.Subject()), tc.max)
+			}
+		})
+	}
+}
+
+// TestGitCommitsNormalized renders commits that git commit refuses to create.
+func TestGitCommitsNormalized(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	messages := []string{
+		"chore: initial",
+		"",
+		"   \n\nClose #12",
+		"feat: " + strings.Repeat("x", 5000),
+		"fix: \x1b[31mred\x1b[0m\x07 thing",
+	}
+	stream := &strings.Builder{}
+	for i, msg := range messages {
+		fmt.Fprintf(stream, "commit refs/heads/master\nmark :%d\n", i+1)
+		fmt.Fprintf(stream, "committer arsham <arsham@github.com> %d +0000\n", 1600000000+i)
+		fmt.Fprintf(stream, "data %d\n%s\n", len(msg), msg)
+		if i > 0 {
+			fmt.Fprintf(stream, "from :%d\n", i)
+		}
+		fmt.Fprintf(stream, "M 644 inline file.txt\ndata 1\n%d\n\n", i)
+		if i == 0 {
+			fmt.Fprint(stream, "reset refs/tags/v1.0.0\nfrom :1\n\n")
+		}
+	}
+	fastImport(t, dir, "master", stream.String())
+
+	g := &commit.Git{Dir: dir, MaxSubject: 50}
+	logs, err := g.Commits(ctx, "v1.0.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, logs, 4)
+	for _, log := range logs {
+		subject, _, _ := strings.Cut(log, "\n")
+		assert.NotEmpty(t, strings.TrimSpace(subject))
+		assert.LessOrEqual(t, utf8.RuneCountInString(subject), 50)
+		assert.NotContains(t, log, "\x1b")
+		assert.NotContains(t, log, "\x07")
+	}
+
+	notes := commit.ParseGroups(logs)
+	for _, line := range strings.Split(notes, "\n") {
+		assert.NotEqual(t, "- ", line)
+		assert.LessOrEqual(t, utf8.RuneCountInString(line), 100, line)
+	}
+	assert.Contains(t, notes, "- Red thing")
+	assert.Regexp(t, `(?m)^- \(no subject\) [0-9a-f]{7}$`, notes)
+	assert.Contains(t, notes, "Close #12")
+	assert.Regexp(t, `(?m)^- \(no subject\) [0-9a-f]{7} \(Close #12\)$`, notes)
+}
+
+// TestGitCommitsTidy commits messy messages verbatim with git commit -F.
+func TestGitCommitsTidy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	tcs := []struct {
+		msg  string
+		want string
+	}{
+		{"fix: crlf\r\n\r\nthe body\r\nsecond line\r\n", "fix: crlf\n\nthe body\nsecond line"},
+		{"feat: trailing blanks\n\n\n\nthe body\n\n\n", "feat: trailing blanks\n\nthe body"},
+		{"fix: a subject\nthat wraps\n\nthe body\n", "fix: a subject that wraps\n\nthe body"},
+		{"fix: lone cr\rthe body\r", "fix: lone cr the body"},
+		{"docs: no body\n\n\n", "docs: no body"},
+		{"feat: paragraphs\n\nfirst\n\n\nsecond\n", "feat: paragraphs\n\nfirst\n\n\nsecond"},
+	}
+	for i, tc := range tcs {
+		name := filepath.Join(t.TempDir(), fmt.Sprintf("msg%d", i))
+		require.NoError(t, os.WriteFile(name, []byte(tc.msg), 0o600))
+		runGit(t, dir, "commit", "--quiet", "--allow-empty", "--no-gpg-sign", "--cleanup=verbatim", "-F", name)
+	}
+
+	g := &commit.Git{Dir: dir, Order: commit.OrderLog}
+	logs, err := g.Commits(ctx, "v1.0.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, logs, len(tcs))
+	for i, tc := range tcs {
+		// git log lists the newest commit first.
+		got := logs[len(logs)-1-i]
+		assert.Equal(t, tc.want, got, "%q", tc.msg)
+		assert.NotContains(t, got, "\r")
+		if subject, body, ok := strings.Cut(got, "\n"); ok {
+			assert.NotEmpty(t, subject)
+			assert.True(t, strings.HasPrefix(body, "\n") && !strings.HasPrefix(body, "\n\n"), "%q", got)
+		}
+	}
+}
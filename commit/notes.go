@@ -0,0 +1,63 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// typePrefixRe matches the conventional type and scope of a subject, e.g.
+// "feat(cli)!: ".
+var typePrefixRe = regexp.MustCompile(`^\s*[[:alpha:]]+!?(\([^)]*\))?!?:\s*`)
+
+// Annotated returns the message of the commit with its Note preferred over its
+// subject. The first line of the note replaces the subject, and the rest of
+// the note comes before the body. If the note doesn't have a conventional
+// type, the type and the scope of the subject are kept, e.g. the note "Faster
+// startup" of "feat(cli): cache the config" is "feat(cli): Faster startup".
+// The number of the pull request is kept as well. The message is returned as
+// it is if the commit has no note.
+func (c Commit) Annotated() string {
+	note := strings.TrimSpace(c.Note)
+	if note == "" {
+		return c.Message
+	}
+	subject, extra, hasExtra := strings.Cut(note, "\n")
+	subject = strings.TrimSpace(subject)
+	orig, body, _ := strings.Cut(c.Message, "\n")
+	if _, ok := (Conventional{}).Classify(Commit{Message: subject}); !ok {
+		if _, ok := (Conventional{}).Classify(c); ok {
+			subject = typePrefixRe.FindString(orig) + subject
+		}
+	}
+	if c.PRNumber > 0 && !squashPullRe.MatchString(subject) {
+		subject = fmt.Sprintf("%s (#%d)", subject, c.PRNumber)
+	}
+	msg := subject + "\n"
+	if hasExtra {
+		msg += "\n" + strings.Trim(extra, "\n") + "\n"
+	}
+	if body = strings.Trim(body, "\n"); body != "" {
+		msg += "\n" + body + "\n"
+	}
+	return msg
+}
+
+// notesHead returns the commit of the NotesRef, or an empty string if it's
+// not set or doesn't exist. The notes change without changing the commits
+// they annotate, therefore the cached ranges depend on it.
+func (g *Git) notesHead(ctx context.Context) string {
+	if g.NotesRef == "" {
+		return ""
+	}
+	ref := g.NotesRef
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/notes/" + ref
+	}
+	out, err := g.run(ctx, newCommand("rev-parse", "--verify", "--quiet").revFrom(ref, g.NotesRef))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
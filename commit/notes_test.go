@@ -0,0 +1,131 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitAnnotated(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		c    commit.Commit
+		want string
+	}{
+		"no note": {
+			c:    commit.Commit{Message: "feat: add the thing\n\nbody\n"},
+			want: "feat: add the thing\n\nbody\n",
+		},
+		"keeps the type": {
+			c:    commit.Commit{Message: "feat(cli)!: cache the config\n", Note: "Much faster startup\n"},
+			want: "feat(cli)!: Much faster startup\n",
+		},
+		"own type": {
+			c:    commit.Commit{Message: "feat: cache the config\n", Note: "fix: the slow startup"},
+			want: "fix: the slow startup\n",
+		},
+		"not conventional": {
+			c:    commit.Commit{Message: "Cache the config\n", Note: "Much faster startup"},
+			want: "Much faster startup\n",
+		},
+		"supplements the body": {
+			c: commit.Commit{
+				Message: "feat: cache the config\n\nThe config is read once.\n",
+				Note:    "Much faster startup\n\nReviewers: check the cache.\n",
+			},
+			want: "feat: Much faster startup\n\nReviewers: check the cache.\n\nThe config is read once.\n",
+		},
+		"pull request": {
+			c:    commit.Commit{Message: "feat: cache the config (#12)\n", Note: "Much faster startup", PRNumber: 12},
+			want: "feat: Much faster startup (#12)\n",
+		},
+		"blank note": {
+			c:    commit.Commit{Message: "feat: add the thing\n", Note: " \n"},
+			want: "feat: add the thing\n",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.c.Annotated())
+		})
+	}
+}
+
+func TestGitNotesRef(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	commitChanges(t, dir, "feat: cache the config")
+	commitChanges(t, dir, "fix: the typo")
+	runGit(t, dir, "notes", "--ref=release", "add", "-m", "Much faster startup", "HEAD~1")
+
+	t.Run("Log", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: dir, NotesRef: "release"}
+		commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		require.Len(t, commits, 2)
+		notes := map[string]string{}
+		for _, c := range commits {
+			notes[c.Subject()] = c.Note
+		}
+		assert.Equal(t, map[string]string{
+			"feat: cache the config": "Much faster startup",
+			"fix: the typo":          "",
+		}, notes)
+
+		logs, err := g.Commits(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		notesStr := commit.ParseGroups(logs)
+		assert.Contains(t, notesStr, "- Much faster startup")
+		assert.NotContains(t, notesStr, "Cache the config")
+	})
+
+	t.Run("NoRef", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: dir}
+		commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		for _, c := range commits {
+			assert.Empty(t, c.Note)
+		}
+	})
+
+	t.Run("MissingRef", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: dir, NotesRef: "missing"}
+		logs, err := g.Commits(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		assert.Len(t, logs, 2)
+		assert.Contains(t, strings.Join(logs, "\n"), "feat: cache the config")
+	})
+
+	t.Run("SinglePass", func(t *testing.T) {
+		t.Parallel()
+		var calls int32
+		g := &commit.Git{
+			NotesRef: "release",
+			Runner: fakeRunner(func(args []string) (string, error) {
+				// The ends of the range are checked first.
+				if args[0] == "rev-parse" {
+					return "", nil
+				}
+				atomic.AddInt32(&calls, 1)
+				assert.Contains(t, args, "--notes=release")
+				return "", nil
+			}),
+		}
+		_, err := g.Log(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+}
@@ -0,0 +1,38 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Announcement is the release the notifiers announce.
+type Announcement struct {
+	// Project is the name of the repository.
+	Project string
+	Tag     string
+	// URL is the address of the release page.
+	URL string
+	// Notes are the notes of the release in markdown.
+	Notes string
+}
+
+// Notifier announces releases, e.g. by email.
+type Notifier interface {
+	Notify(ctx context.Context, a Announcement) error
+}
+
+// NotifyAll announces the release with all the notifiers. A failed notifier
+// doesn't stop the others, and the returned error contains all failures.
+func NotifyAll(ctx context.Context, a Announcement, notifiers ...Notifier) error {
+	var failures []string
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, a); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to announce %s:\n%s", a.Tag, strings.Join(failures, "\n"))
+	}
+	return nil
+}
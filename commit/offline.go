@@ -0,0 +1,90 @@
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrOffline is returned when the network is accessed while the Git is
+// Offline.
+var ErrOffline = errors.New("the network is not accessible in the offline mode")
+
+// networkCommands are the git commands that reach the remotes.
+var networkCommands = map[string]bool{
+	"fetch":     true,
+	"pull":      true,
+	"push":      true,
+	"ls-remote": true,
+	"clone":     true,
+}
+
+// OfflineTransport is an http.RoundTripper that refuses all requests with
+// ErrOffline, and keeps them as violations. It's the transport of the
+// requests of an Offline Git, and can be installed in the other clients, e.g.
+// the ones of the notifiers, to make sure nothing reaches the network.
+type OfflineTransport struct {
+	mu         sync.Mutex
+	violations []string
+}
+
+// RoundTrip refuses the request.
+func (t *OfflineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		// nolint:errcheck // it's never sent.
+		req.Body.Close()
+	}
+	return nil, t.refuse(req.Method + " " + req.URL.Redacted())
+}
+
+// Violations returns the refused requests and git commands, in order.
+func (t *OfflineTransport) Violations() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.violations...)
+}
+
+// refuse keeps the access as a violation and returns its ErrOffline.
+func (t *OfflineTransport) refuse(access string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.violations = append(t.violations, access)
+	return fmt.Errorf("%w: %s", ErrOffline, access)
+}
+
+// OfflineViolations returns the network accesses that are refused since the
+// Git is Offline. It's empty if the Git has never tried to reach the network.
+func (g *Git) OfflineViolations() []string {
+	return g.offlineTransport().Violations()
+}
+
+// offlineTransport returns the transport that keeps the violations of the
+// Git.
+func (g *Git) offlineTransport() *OfflineTransport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.offline == nil {
+		g.offline = &OfflineTransport{}
+	}
+	return g.offline
+}
+
+// httpClient returns the client c that records its requests in the Metrics,
+// or a client that refuses all requests if the Git is Offline.
+func (g *Git) httpClient(c *http.Client) *http.Client {
+	if !g.Offline {
+		return g.Metrics.client(c)
+	}
+	return &http.Client{Transport: g.offlineTransport()}
+}
+
+// checkOffline returns ErrOffline if the Git is Offline and the git command
+// of the args reaches the remotes.
+func (g *Git) checkOffline(args []string) error {
+	if !g.Offline || len(args) == 0 || !networkCommands[args[0]] {
+		return nil
+	}
+	return g.offlineTransport().refuse("git " + strings.Join(args, " "))
+}
@@ -0,0 +1,191 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Option configures the Git that is created by New. The options set the
+// fields of the Git, therefore a Git value that is created as a struct
+// literal works the same.
+type Option func(*Git)
+
+// New returns a Git configured with the options. Without any options, it
+// works on the current folder and the origin remote.
+func New(opts ...Option) *Git {
+	g := &Git{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// WithDir runs git in the dir.
+func WithDir(dir string) Option {
+	return func(g *Git) { g.Dir = dir }
+}
+
+// WithGitDir uses the git directory of the repository, and the work tree
+// of the working tree, e.g. of a clone with --separate-git-dir. Either can be
+// empty.
+func WithGitDir(gitDir, workTree string) Option {
+	return func(g *Git) { g.GitDir, g.WorkTree = gitDir, workTree }
+}
+
+// WithRemote uses the remote instead of origin.
+func WithRemote(remote string) Option {
+	return func(g *Git) { g.Remote = remote }
+}
+
+// WithTagPrefix limits the tags to the ones starting with the prefix.
+func WithTagPrefix(prefix string) Option {
+	return func(g *Git) { g.TagPrefix = prefix }
+}
+
+// WithTimeout limits the duration of each git process. It doesn't apply to the
+// calls of the GitHub API, which should be limited by the context.
+func WithTimeout(d time.Duration) Option {
+	return func(g *Git) { g.Timeout = d }
+}
+
+// WithRunner runs the git commands with the runner instead of the git binary.
+func WithRunner(r Runner) Option {
+	return func(g *Git) { g.Runner = r }
+}
+
+// WithLogger prints the debug information with the logger.
+func WithLogger(l Logger) Option {
+	return func(g *Git) { g.Logger = l }
+}
+
+// WithRangeMode selects the commits between the tags with the mode.
+func WithRangeMode(mode RangeMode) Option {
+	return func(g *Git) { g.RangeMode = mode }
+}
+
+// WithAnnotatedOnly only considers the annotated tags.
+func WithAnnotatedOnly() Option {
+	return func(g *Git) { g.AnnotatedOnly = true }
+}
+
+// WithNoMerges leaves the merge commits out of Commits and Log by default.
+// The per-call LogOptions take precedence.
+func WithNoMerges() Option {
+	return func(g *Git) { g.NoMerges = true }
+}
+
+// WithFirstParent only follows the first parent of the merge commits in
+// Commits and Log by default. The per-call LogOptions take precedence.
+func WithFirstParent() Option {
+	return func(g *Git) { g.FirstParent = true }
+}
+
+// WithNotesRef reads the git notes of the commits in the ref, e.g. "release"
+// for refs/notes/release.
+func WithNotesRef(ref string) Option {
+	return func(g *Git) { g.NotesRef = ref }
+}
+
+// WithInitialVersion suggests the version for the first release of a
+// repository without tags instead of the DefaultInitialVersion.
+func WithInitialVersion(version string) Option {
+	return func(g *Git) { g.InitialVersion = version }
+}
+
+// WithCommitOrder sorts the commits of Commits, Log and CommitsSince in the
+// order.
+func WithCommitOrder(order CommitOrder) Option {
+	return func(g *Git) { g.Order = order }
+}
+
+// WithSlowLogger reports the git processes that take longer than the
+// threshold to the logger.
+func WithSlowLogger(threshold time.Duration, l Logger) Option {
+	return func(g *Git) {
+		g.SlowThreshold = threshold
+		g.SlowLogger = l
+	}
+}
+
+// WithWarnings collects the warnings of the Git into w.
+func WithWarnings(w *Warnings) Option {
+	return func(g *Git) { g.Warnings = w }
+}
+
+// WithHostURL overrides the addresses of the pages of the host, e.g. of a
+// self-hosted instance with nonstandard paths.
+func WithHostURL(host string, patterns URLPatterns) Option {
+	return func(g *Git) {
+		if g.HostURLs == nil {
+			g.HostURLs = make(map[string]URLPatterns)
+		}
+		g.HostURLs[strings.ToLower(host)] = patterns
+	}
+}
+
+// LogOption changes which commits a single call of Commits or Log returns. It
+// takes precedence over the defaults of the Git.
+type LogOption func(*logOptions)
+
+type logOptions struct {
+	noMerges    bool
+	firstParent bool
+	limit       int
+}
+
+// logOptions returns the options of the call over the defaults of the Git.
+func (g *Git) logOptions(opts []LogOption) logOptions {
+	o := logOptions{
+		noMerges:    g.NoMerges,
+		firstParent: g.FirstParent,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// args returns the arguments of git log for the options.
+func (o logOptions) args() []string {
+	var args []string
+	if o.noMerges {
+		args = append(args, "--no-merges")
+	}
+	if o.firstParent {
+		args = append(args, "--first-parent")
+	}
+	if o.limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", o.limit))
+	}
+	return args
+}
+
+// NoMerges leaves the merge commits out.
+func NoMerges() LogOption {
+	return func(o *logOptions) { o.noMerges = true }
+}
+
+// Merges keeps the merge commits, even if the Git leaves them out by default.
+func Merges() LogOption {
+	return func(o *logOptions) { o.noMerges = false }
+}
+
+// FirstParent only follows the first parent of the merge commits, which
+// leaves out the commits of the merged branches.
+func FirstParent() LogOption {
+	return func(o *logOptions) { o.firstParent = true }
+}
+
+// AllParents follows all parents of the merge commits, even if the Git only
+// follows the first parent by default.
+func AllParents() LogOption {
+	return func(o *logOptions) { o.firstParent = false }
+}
+
+// Limit only returns the n most recent commits of the range, e.g. for a
+// preview of a large range. See LogPage for the number of all of them. Zero
+// is no limit.
+func Limit(n int) LogOption {
+	return func(o *logOptions) { o.limit = n }
+}
@@ -0,0 +1,144 @@
+package commit_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	runner := fakeRunner(func([]string) (string, error) { return "", nil })
+	logger := log.New(os.Stderr, "", 0)
+	g := commit.New(
+		commit.WithDir("/tmp/repo"),
+		commit.WithRemote("upstream"),
+		commit.WithTagPrefix("mod/"),
+		commit.WithTimeout(time.Minute),
+		commit.WithRunner(runner),
+		commit.WithLogger(logger),
+		commit.WithRangeMode(commit.RangeSymmetric),
+		commit.WithAnnotatedOnly(),
+		commit.WithNoMerges(),
+		commit.WithFirstParent(),
+	)
+	assert.Equal(t, "/tmp/repo", g.Dir)
+	assert.Equal(t, "upstream", g.Remote)
+	assert.Equal(t, "mod/", g.TagPrefix)
+	assert.Equal(t, time.Minute, g.Timeout)
+	assert.NotNil(t, g.Runner)
+	assert.Equal(t, logger, g.Logger)
+	assert.Equal(t, commit.RangeSymmetric, g.RangeMode)
+	assert.True(t, g.AnnotatedOnly)
+	assert.True(t, g.NoMerges)
+	assert.True(t, g.FirstParent)
+
+	// The later options win.
+	g = commit.New(commit.WithRemote("a"), commit.WithRemote("b"))
+	assert.Equal(t, "b", g.Remote)
+}
+
+// createMergeRepo creates a repository with a merged branch:
+//
+//	v1.0.0 -- main -- merge v1.1.0
+//	      \          /
+//	       feature --
+func createMergeRepo(t *testing.T) string {
+	t.Helper()
+	dir := createGitRepo(t)
+	runGit(t, dir, "checkout", "-q", "-b", "master")
+	createFile(t, dir, "file.txt", "1")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	createFile(t, dir, "feature.txt", "1")
+	commitChanges(t, dir, "feat: feature")
+	runGit(t, dir, "checkout", "-q", "master")
+	createFile(t, dir, "file.txt", "2")
+	commitChanges(t, dir, "fix: main")
+	runGit(t, dir, "merge", "-q", "--no-ff", "-m", "Merge branch feature", "feature")
+	createGitTag(t, dir, "v1.1.0")
+	return dir
+}
+
+func TestGitLogOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createMergeRepo(t)
+	subjects := func(t *testing.T, logs []string) []string {
+		t.Helper()
+		out := make([]string, len(logs))
+		for i, l := range logs {
+			out[i] = commit.Commit{Message: l}.Subject()
+		}
+		return out
+	}
+	all := []string{"Merge branch feature", "fix: main", "feat: feature"}
+
+	tcs := map[string]struct {
+		opts    []commit.Option
+		logOpts []commit.LogOption
+		want    []string
+	}{
+		"default":                  {want: all},
+		"no merges":                {logOpts: []commit.LogOption{commit.NoMerges()}, want: []string{"fix: main", "feat: feature"}},
+		"first parent":             {logOpts: []commit.LogOption{commit.FirstParent()}, want: []string{"Merge branch feature", "fix: main"}},
+		"both":                     {logOpts: []commit.LogOption{commit.NoMerges(), commit.FirstParent()}, want: []string{"fix: main"}},
+		"constructor no merges":    {opts: []commit.Option{commit.WithNoMerges()}, want: []string{"fix: main", "feat: feature"}},
+		"constructor first":        {opts: []commit.Option{commit.WithFirstParent()}, want: []string{"Merge branch feature", "fix: main"}},
+		"call overrides merges":    {opts: []commit.Option{commit.WithNoMerges()}, logOpts: []commit.LogOption{commit.Merges()}, want: all},
+		"call overrides parents":   {opts: []commit.Option{commit.WithFirstParent()}, logOpts: []commit.LogOption{commit.AllParents()}, want: all},
+		"call adds to constructor": {opts: []commit.Option{commit.WithFirstParent()}, logOpts: []commit.LogOption{commit.NoMerges()}, want: []string{"fix: main"}},
+		"last call option wins":    {logOpts: []commit.LogOption{commit.NoMerges(), commit.Merges()}, want: all},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.New(append([]commit.Option{commit.WithDir(dir)}, tc.opts...)...)
+			logs, err := g.Commits(ctx, "v1.0.0", "v1.1.0", tc.logOpts...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, subjects(t, logs))
+
+			commits, err := g.Log(ctx, "v1.0.0", "v1.1.0", tc.logOpts...)
+			require.NoError(t, err)
+			got := make([]string, len(commits))
+			for i, c := range commits {
+				got[i] = c.Subject()
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	// The plain struct works the same.
+	g := &commit.Git{Dir: dir, NoMerges: true}
+	logs, err := g.Commits(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix: main", "feat: feature"}, subjects(t, logs))
+}
+
+func TestGitTimeout(t *testing.T) {
+	t.Parallel()
+	runner := fakeRunnerCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g := commit.New(commit.WithRunner(runner), commit.WithTimeout(10*time.Millisecond))
+	_, err := g.LatestTag(context.Background())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+// fakeRunnerCtx runs the function with the context of the command.
+type fakeRunnerCtx func(ctx context.Context) error
+
+func (f fakeRunnerCtx) Run(ctx context.Context, _ string, _ io.Writer, _ ...string) error {
+	return f(ctx)
+}
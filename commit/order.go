@@ -0,0 +1,52 @@
+package commit
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CommitOrder defines the order of the commits returned by Commits, Log and
+// CommitsSince, which is also their order in the sections of the notes.
+type CommitOrder int
+
+const (
+	// OrderLog keeps the order of git log, which depends on the history and
+	// the version of git. This is the default.
+	OrderLog CommitOrder = iota
+	// OrderTime sorts the commits by their committer dates, the newest first,
+	// and the commits of the same second by their SHAs. The notes of the
+	// same commits are therefore the same regardless of how they are listed.
+	OrderTime
+)
+
+// ParseCommitOrder returns the CommitOrder for "log" or "time".
+func ParseCommitOrder(s string) (CommitOrder, error) {
+	switch s {
+	case "log", "":
+		return OrderLog, nil
+	case "time":
+		return OrderTime, nil
+	}
+	return OrderLog, fmt.Errorf("unknown commit order %q", s)
+}
+
+// String returns the name of the order.
+func (o CommitOrder) String() string {
+	if o == OrderTime {
+		return "time"
+	}
+	return "log"
+}
+
+// sortCommits sorts the commits in the order in place.
+func sortCommits(commits []Commit, order CommitOrder) {
+	if order != OrderTime {
+		return
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		if !commits[i].Date.Equal(commits[j].Date) {
+			return commits[i].Date.After(commits[j].Date)
+		}
+		return commits[i].SHA < commits[j].SHA
+	})
+}
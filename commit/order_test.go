@@ -0,0 +1,158 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommitOrder(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		want    commit.CommitOrder
+		wantErr bool
+	}{
+		"":     {want: commit.OrderLog},
+		"log":  {want: commit.OrderLog},
+		"time": {want: commit.OrderTime},
+		"date": {wantErr: true},
+	}
+	for in, tc := range tcs {
+		in, tc := in, tc
+		t.Run(in, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseCommitOrder(in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			if in != "" {
+				assert.Equal(t, in, got.String())
+			}
+		})
+	}
+}
+
+func TestParseGroupsOrder(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"chore: tidy up",
+		"unknown: something",
+		"fix: crash",
+		"docs: explain",
+		"feat: add",
+	}
+	got := commit.ParseGroups(logs)
+	want := "### Feature\n\n- Add\n\n\n### Fix\n\n- Crash\n\n\n" +
+		"### Docs\n\n- Explain\n\n\n### Chore\n\n- Tidy up\n\n\n### Misc\n\n- Something"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	got = commit.ParseGroups(logs, commit.WithGroupOrder("fix", "CHORE"))
+	want = "### Fix\n\n- Crash\n\n\n### Chore\n\n- Tidy up\n\n\n" +
+		"### Docs\n\n- Explain\n\n\n### Feature\n\n- Add\n\n\n### Misc\n\n- Something"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestGitCommitOrder(t *testing.T) {
+	t.Parallel()
+	t.Run("SameSecond", testGitCommitOrderSameSecond)
+	t.Run("Golden", testGitCommitOrderGolden)
+}
+
+func testGitCommitOrderSameSecond(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	separator := "00000000000000000000000000000000000"
+	entry := func(sha string, sec int, msg string) string {
+		return fmt.Sprintf("%s%s\x00arsham <arsham@github.com>\x00%d\x00%s\n", separator, sha, sec, msg)
+	}
+	out := entry("ccc", 10, "fix: c") + entry("aaa", 20, "fix: a") + entry("bbb", 10, "fix: b")
+	g := &commit.Git{
+		Order: commit.OrderTime,
+		Runner: fakeRunner(func([]string) (string, error) {
+			return out, nil
+		}),
+	}
+	logs, err := g.Commits(ctx, "v0.1.0", "v0.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix: a", "fix: b", "fix: c"}, logs)
+
+	commits, err := g.Log(ctx, "v0.1.0", "v0.2.0")
+	require.NoError(t, err)
+	shas := make([]string, 0, len(commits))
+	for _, c := range commits {
+		shas = append(shas, c.SHA)
+	}
+	assert.Equal(t, []string{"aaa", "bbb", "ccc"}, shas)
+
+	g.Order = commit.OrderLog
+	logs, err = g.Commits(ctx, "v0.1.0", "v0.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix: c", "fix: a", "fix: b"}, logs)
+}
+
+// testGitCommitOrderGolden creates the same commits in two repositories, in a
+// different order of their parents. Their notes should be the same with the
+// OrderTime.
+func testGitCommitOrderGolden(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	type change struct {
+		msg  string
+		hour int
+	}
+	changes := []change{
+		{"feat(api): add the endpoint", 1},
+		{"fix: handle the error", 2},
+		{"feat: add the button", 3},
+		{"docs: explain the endpoint", 4},
+		{"fix(api): check the input", 5},
+	}
+	create := func(order []int) *committest.Repo {
+		r := committest.NewRepo(t, identity)
+		r.CommitAt("chore: initial", start, committest.File{Path: "file.txt", Content: "0"})
+		r.Tag("v0.1.0")
+		for _, i := range order {
+			c := changes[i]
+			r.CommitAt(c.msg, start.Add(time.Duration(c.hour)*time.Hour),
+				committest.File{Path: fmt.Sprintf("file%d.txt", i), Content: c.msg})
+		}
+		r.Tag("v0.2.0")
+		return r
+	}
+	notes := func(r *committest.Repo, order commit.CommitOrder) string {
+		g := commit.New(commit.WithDir(r.Dir), commit.WithCommitOrder(order))
+		logs, err := g.Commits(ctx, "v0.1.0", "v0.2.0")
+		require.NoError(t, err)
+		return commit.ParseGroups(logs)
+	}
+
+	r1 := create([]int{0, 1, 2, 3, 4})
+	r2 := create([]int{2, 0, 4, 1, 3})
+	want := "### Feature\n\n- Add the button\n- **Api:** Add the endpoint\n\n\n" +
+		"### Fix\n\n- **Api:** Check the input\n- Handle the error\n\n\n" +
+		"### Docs\n\n- Explain the endpoint"
+	for i := 0; i < 2; i++ {
+		if diff := cmp.Diff(want, notes(r1, commit.OrderTime)); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(want, notes(r2, commit.OrderTime)); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	}
+	assert.NotEqual(t, notes(r1, commit.OrderLog), notes(r2, commit.OrderLog))
+}
@@ -0,0 +1,228 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PendingMarker is the hidden marker of the comments of PostPendingNotes. The
+// comment of a previous run is found with it, therefore a pull request only
+// has one comment however many times it is updated.
+const PendingMarker = "<!-- gitrelease:pending-notes -->"
+
+// MaxCommentLength is the maximum number of characters GitHub accepts in the
+// body of a comment.
+const MaxCommentLength = 65536
+
+// ErrNoWriteAccess is returned when the token can't comment on a pull
+// request, e.g. the read-only token of the runs of the pull requests from
+// forks.
+var ErrNoWriteAccess = errors.New("no write access to the pull request")
+
+var (
+	// syntheticMergeRe matches the subject of the merge commits CI systems
+	// check out for the pull requests, e.g. refs/pull/12/merge on GitHub.
+	syntheticMergeRe = regexp.MustCompile(`^Merge [[:xdigit:]]{40} into [[:xdigit:]]{40}$`)
+	pullRefRe        = regexp.MustCompile(`^refs/pull/(\d+)/(?:merge|head)$`)
+	pullURLRe        = regexp.MustCompile(`/pulls?/(\d+)/?$`)
+)
+
+// PendingCommits returns the nearest tag reachable from the head, and the
+// commits since then up to the head, e.g. the head of a pull request. The
+// merge commit CI systems check out for a pull request is left out, because
+// it only merges the pull request into its base. If there are no tags, the
+// tag is empty and all commits are returned.
+func (g *Git) PendingCommits(ctx context.Context, head string) (string, []Commit, error) {
+	tag, page, err := g.PendingPage(ctx, head)
+	return tag, page.Commits, err
+}
+
+// PendingPage is like PendingCommits, but the commits can be limited with the
+// Limit, e.g. for a preview of a large range. The commits that are left out
+// of the latest ones are only counted in the Count of the page. The excluded
+// commits are not counted, wherever they are in the range.
+func (g *Git) PendingPage(ctx context.Context, head string, opts ...LogOption) (string, CommitPage, error) {
+	if head == "" {
+		head = "HEAD"
+	}
+	w, err := g.walk(ctx, head)
+	if err != nil {
+		return "", CommitPage{}, err
+	}
+	var tag string
+	if len(w.tagged) > 0 {
+		tag = w.tagged[0].tags[0]
+	}
+	excluded, err := g.exclusions(ctx)
+	if err != nil {
+		return "", CommitPage{}, err
+	}
+	page, err := g.LogPage(ctx, tag, head, opts...)
+	if err != nil {
+		return "", CommitPage{}, err
+	}
+	commits, _ := exclude(page.Commits, excluded)
+	kept := commits[:0]
+	for _, c := range commits {
+		if !syntheticMergeRe.MatchString(c.Subject()) {
+			kept = append(kept, c)
+		}
+	}
+	page.Count -= len(page.Commits) - len(kept)
+	if page.Limited() && len(excluded) > 0 {
+		n, err := g.excludedOutside(ctx, tag, head, page.Commits, excluded, opts)
+		if err != nil {
+			return "", CommitPage{}, err
+		}
+		page.Count -= n
+	}
+	page.Commits = g.normalize(kept)
+	return tag, page, nil
+}
+
+// excludedOutside returns the number of the excluded commits of the range
+// that are not in the commits, e.g. the ones older than the Limit. Only the
+// hashes of the range are read.
+func (g *Git) excludedOutside(ctx context.Context, tag1, tag2 string, commits []Commit, excluded map[string]bool, opts []LogOption) (int, error) {
+	out, err := g.revList(ctx, tag1, tag2, opts)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		seen[c.SHA] = true
+	}
+	var n int
+	for _, sha := range strings.Fields(out) {
+		if excluded[sha] && !seen[sha] {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CIPullRequest returns the number of the pull request of the CI run from the
+// standard environment variables of GitHub Actions, CircleCI, Buildkite,
+// Travis CI, Drone and Jenkins. It returns zero if the run is not for a pull
+// request.
+func CIPullRequest(getenv func(string) string) int {
+	if m := pullRefRe.FindStringSubmatch(getenv("GITHUB_REF")); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	if m := pullURLRe.FindStringSubmatch(getenv("CIRCLE_PULL_REQUEST")); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	for _, key := range []string{"BUILDKITE_PULL_REQUEST", "TRAVIS_PULL_REQUEST", "DRONE_PULL_REQUEST", "CHANGE_ID"} {
+		// The runs of the branches have "false" in these.
+		if n, err := strconv.Atoi(getenv(key)); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// pendingHeader starts the comments of the pending notes.
+const pendingHeader = PendingMarker + "\n## Unreleased\n\n"
+
+// PendingComment returns the body of the comment with the notes of the
+// unreleased changes. If it's longer than a comment can be, the notes are
+// truncated at the section boundaries as TruncateNotes does.
+func PendingComment(notes string) string {
+	const note = "_The notes are truncated to fit in the comment._"
+	limit := MaxCommentLength - utf8.RuneCountInString(pendingHeader)
+	body, _ := TruncateNotes(notes, limit, note)
+	return pendingHeader + body
+}
+
+// PendingAction is what PostPendingNotes has done.
+type PendingAction int
+
+const (
+	// PendingCreated means the comment is created.
+	PendingCreated PendingAction = iota
+	// PendingUpdated means the comment of a previous run is updated.
+	PendingUpdated
+	// PendingUnchanged means the comment of a previous run has the same
+	// body.
+	PendingUnchanged
+)
+
+// String returns the name of the action.
+func (a PendingAction) String() string {
+	switch a {
+	case PendingUpdated:
+		return "updated"
+	case PendingUnchanged:
+		return "unchanged"
+	}
+	return "created"
+}
+
+// issueComment is a comment in the responses of the API.
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// PostPendingNotes posts the body on the pull request n, or updates the
+// comment with the PendingMarker if there is one. The body should have the
+// marker, e.g. the one of PendingComment. It returns ErrNoWriteAccess if the
+// token can't comment on the pull request.
+func (g *Git) PostPendingNotes(ctx context.Context, token, user, repo string, n int, body string) (PendingAction, error) {
+	c, err := g.pendingComment(ctx, token, user, repo, n)
+	if err != nil {
+		return PendingCreated, err
+	}
+	payload := map[string]string{"body": body}
+	if c == nil {
+		uri := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", user, repo, n)
+		err := g.api(ctx, token, http.MethodPost, uri, payload, nil)
+		return PendingCreated, commentError(n, err)
+	}
+	if c.Body == body {
+		return PendingUnchanged, nil
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", user, repo, c.ID)
+	err = g.api(ctx, token, http.MethodPatch, uri, payload, nil)
+	return PendingUpdated, commentError(n, err)
+}
+
+// pendingComment returns the comment of the pull request with the
+// PendingMarker, or nil if there is none.
+func (g *Git) pendingComment(ctx context.Context, token, user, repo string, n int) (*issueComment, error) {
+	for page := 1; ; page++ {
+		var batch []issueComment
+		uri := fmt.Sprintf("/repos/%s/%s/issues/%d/comments?per_page=%d&page=%d", user, repo, n, listPageSize, page)
+		if err := g.api(ctx, token, http.MethodGet, uri, nil, &batch); err != nil {
+			return nil, fmt.Errorf("listing the comments of #%d: %w", n, err)
+		}
+		for i := range batch {
+			if strings.HasPrefix(batch[i].Body, PendingMarker) {
+				return &batch[i], nil
+			}
+		}
+		if len(batch) < listPageSize {
+			return nil, nil
+		}
+	}
+}
+
+// commentError wraps the err of commenting on the pull request n. The
+// responses of the tokens without write access are ErrNoWriteAccess.
+func commentError(n int, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case hasStatus(err, http.StatusForbidden):
+		return fmt.Errorf("commenting on #%d: %w: %v", n, ErrNoWriteAccess, err)
+	}
+	return fmt.Errorf("commenting on #%d: %w", n, err)
+}
@@ -0,0 +1,198 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitPendingCommits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	commitChanges(t, dir, "feat: add the thing")
+	commitChanges(t, dir, "Merge "+strings.Repeat("a", 40)+" into "+strings.Repeat("b", 40))
+
+	g := &commit.Git{Dir: dir}
+	tag, commits, err := g.PendingCommits(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "feat: add the thing", commits[0].Subject())
+
+	tag, commits, err = g.PendingCommits(ctx, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+	assert.Empty(t, commits)
+	commitChanges(t, dir, "fix: the leak")
+	tag, page, err := g.PendingPage(ctx, "", commit.Limit(2))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+	require.Len(t, page.Commits, 1)
+	assert.Equal(t, "fix: the leak", page.Commits[0].Subject())
+	assert.Equal(t, 2, page.Count, "the merge commit of CI is not counted")
+	assert.True(t, page.Limited())
+}
+
+func TestGitPendingPageExcluded(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	old := r.Commit("chore: the old one")
+	r.Commit("feat: add the thing")
+	latest := r.Commit("fix: the leak")
+
+	g := commit.New(commit.WithDir(r.Dir), commit.WithExclude(old))
+	_, page, err := g.PendingPage(ctx, "", commit.Limit(1))
+	require.NoError(t, err)
+	require.Len(t, page.Commits, 1)
+	assert.Equal(t, 2, page.Count, "the excluded commit older than the limit is not counted")
+
+	g = commit.New(commit.WithDir(r.Dir), commit.WithExclude(latest))
+	_, page, err = g.PendingPage(ctx, "", commit.Limit(1))
+	require.NoError(t, err)
+	assert.Empty(t, page.Commits)
+	assert.Equal(t, 2, page.Count)
+}
+
+func TestCIPullRequest(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		env  map[string]string
+		want int
+	}{
+		"none":          {},
+		"github merge":  {env: map[string]string{"GITHUB_REF": "refs/pull/42/merge"}, want: 42},
+		"github head":   {env: map[string]string{"GITHUB_REF": "refs/pull/7/head"}, want: 7},
+		"github branch": {env: map[string]string{"GITHUB_REF": "refs/heads/master"}},
+		"circle": {
+			env:  map[string]string{"CIRCLE_PULL_REQUEST": "https://github.com/a/b/pull/12"},
+			want: 12,
+		},
+		"buildkite":       {env: map[string]string{"BUILDKITE_PULL_REQUEST": "13"}, want: 13},
+		"buildkite false": {env: map[string]string{"BUILDKITE_PULL_REQUEST": "false"}},
+		"travis":          {env: map[string]string{"TRAVIS_PULL_REQUEST": "14"}, want: 14},
+		"jenkins":         {env: map[string]string{"CHANGE_ID": "15"}, want: 15},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.CIPullRequest(func(key string) string { return tc.env[key] })
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPendingComment(t *testing.T) {
+	t.Parallel()
+	body := commit.PendingComment("### Feature\n\n- Add the thing")
+	assert.True(t, strings.HasPrefix(body, commit.PendingMarker))
+	assert.Contains(t, body, "- Add the thing")
+
+	section := "### Fix\n\n" + strings.Repeat("- Fix the thing\n", 3000)
+	body = commit.PendingComment("### Feature\n\n- Add the thing\n\n" + section + "\n" + section)
+	assert.LessOrEqual(t, utf8.RuneCountInString(body), commit.MaxCommentLength)
+	assert.Contains(t, body, "- Add the thing")
+	assert.Contains(t, body, "_The notes are truncated to fit in the comment._")
+	assert.Equal(t, 1, strings.Count(body, "### Fix"), "cut at the section")
+}
+
+// fakeComments serves the comments of the pull request 1.
+type fakeComments struct {
+	*httptest.Server
+	mu       sync.Mutex
+	comments []map[string]any
+	status   int
+	writes   []string
+}
+
+func newFakeComments(t *testing.T) *fakeComments {
+	t.Helper()
+	f := &fakeComments{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Method == http.MethodGet {
+			assert.Equal(t, "/repos/user/repo/issues/1/comments", r.URL.Path)
+			assert.NoError(t, json.NewEncoder(w).Encode(f.comments))
+			return
+		}
+		f.writes = append(f.writes, r.Method+" "+r.URL.Path)
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+			fmt.Fprint(w, `{"message":"Resource not accessible by integration"}`)
+			return
+		}
+		var payload struct {
+			Body string `json:"body"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if r.Method == http.MethodPost {
+			f.comments = append(f.comments, map[string]any{"id": len(f.comments) + 1, "body": payload.Body})
+			return
+		}
+		for _, c := range f.comments {
+			if r.URL.Path == fmt.Sprintf("/repos/user/repo/issues/comments/%d", c["id"]) {
+				c["body"] = payload.Body
+			}
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestGitPostPendingNotes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	f := newFakeComments(t)
+	f.comments = []map[string]any{{"id": 1, "body": "LGTM"}}
+	g := &commit.Git{BaseURL: f.URL}
+
+	body := commit.PendingComment("### Feature\n\n- Add the thing")
+	action, err := g.PostPendingNotes(ctx, "token", "user", "repo", 1, body)
+	require.NoError(t, err)
+	assert.Equal(t, commit.PendingCreated, action)
+
+	action, err = g.PostPendingNotes(ctx, "token", "user", "repo", 1, body)
+	require.NoError(t, err)
+	assert.Equal(t, commit.PendingUnchanged, action)
+
+	body = commit.PendingComment("### Fix\n\n- Fix the thing")
+	action, err = g.PostPendingNotes(ctx, "token", "user", "repo", 1, body)
+	require.NoError(t, err)
+	assert.Equal(t, commit.PendingUpdated, action)
+
+	assert.Equal(t, []string{
+		"POST /repos/user/repo/issues/1/comments",
+		"PATCH /repos/user/repo/issues/comments/2",
+	}, f.writes)
+	require.Len(t, f.comments, 2)
+	assert.Equal(t, "LGTM", f.comments[0]["body"])
+	assert.Equal(t, body, f.comments[1]["body"])
+}
+
+func TestGitPostPendingNotesFork(t *testing.T) {
+	t.Parallel()
+	f := newFakeComments(t)
+	f.status = http.StatusForbidden
+	g := &commit.Git{BaseURL: f.URL}
+	_, err := g.PostPendingNotes(context.Background(), "token", "user", "repo", 1, commit.PendingComment("notes"))
+	assert.True(t, errors.Is(err, commit.ErrNoWriteAccess), err)
+}
@@ -0,0 +1,207 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrMissingPermission is returned by CheckPermissions when the token lacks a
+// permission, or a scope, that the release needs.
+var ErrMissingPermission = errors.New("missing permission")
+
+// TokenKind is the kind of a GitHub token, which decides how its permissions
+// are checked.
+type TokenKind string
+
+const (
+	// TokenClassic is a personal access token with scopes, or an OAuth
+	// token.
+	TokenClassic TokenKind = "classic"
+	// TokenFineGrained is a personal access token with permissions on the
+	// chosen repositories, or the token of a GitHub App acting for a user.
+	TokenFineGrained TokenKind = "fine-grained"
+	// TokenApp is the installation token of a GitHub App, e.g. the
+	// GITHUB_TOKEN of the Actions.
+	TokenApp TokenKind = "app installation"
+	// TokenUnknown is a token with none of the known prefixes.
+	TokenUnknown TokenKind = "unknown"
+)
+
+// TokenKindOf returns the kind of the token from its prefix. The classic
+// tokens of before 2021 have no prefix, but 40 hex digits.
+func TokenKindOf(token string) TokenKind {
+	switch {
+	case strings.HasPrefix(token, "ghp_"), strings.HasPrefix(token, "gho_"):
+		return TokenClassic
+	case strings.HasPrefix(token, "github_pat_"), strings.HasPrefix(token, "ghu_"):
+		return TokenFineGrained
+	case strings.HasPrefix(token, "ghs_"):
+		return TokenApp
+	case len(token) == 40 && strings.Trim(token, "0123456789abcdef") == "":
+		return TokenClassic
+	}
+	return TokenUnknown
+}
+
+// Permission is a permission of the fine-grained tokens and the GitHub Apps,
+// e.g. "contents: write".
+type Permission struct {
+	// Name is the name of the permission in the API, e.g. "pull_requests".
+	Name string
+	// Level is "read", "write" or "admin".
+	Level string
+}
+
+var (
+	// PermContentsWrite creates the tags and the releases, and uploads their
+	// assets.
+	PermContentsWrite = Permission{Name: "contents", Level: "write"}
+	// PermIssuesWrite comments on the issues.
+	PermIssuesWrite = Permission{Name: "issues", Level: "write"}
+	// PermPullsWrite labels and comments on the pull requests.
+	PermPullsWrite = Permission{Name: "pull_requests", Level: "write"}
+)
+
+func (p Permission) String() string {
+	return p.Name + ": " + p.Level
+}
+
+// levels ranks the levels of the permissions, a higher level includes the
+// lower ones.
+var levels = map[string]int{"read": 1, "write": 2, "admin": 3}
+
+// role returns the key of the repository's permissions field that grants the
+// p.
+func (p Permission) role() string {
+	switch {
+	case p.Level == "admin":
+		return "admin"
+	case p.Level == "read":
+		return "pull"
+	case p.Name == "issues", p.Name == "pull_requests":
+		return "triage"
+	}
+	return "push"
+}
+
+// repoAccess is the part of a repository of the API that tells what the
+// token can do.
+type repoAccess struct {
+	Private     bool            `json:"private"`
+	Permissions map[string]bool `json:"permissions"`
+}
+
+// CheckPermissions checks that the token has the permissions on the
+// repository of the owner, before anything is changed. It only reads the
+// repository, therefore it doesn't count against the limits of the
+// mutations. The classic tokens are checked by their scopes, the
+// installation tokens of the TokenSource by the permissions they are issued
+// with, and the other ones by the permissions GitHub reports on the
+// repository. It returns the kind of the token, and an error wrapping
+// ErrMissingPermission that lists the missing permissions. The permissions
+// GitHub doesn't report are not checked.
+//
+// The permissions GitHub reports on the repository are the ones of the role
+// of the user, which limit the ones of a fine-grained token but are not them,
+// and GitHub can't be asked for the latter without a write. Therefore the
+// check of a fine-grained token is inconclusive if the role of its user has
+// all of the perms, and a WarnAuthInconclusive is added to the Warnings.
+func (g *Git) CheckPermissions(ctx context.Context, token, owner, repo string, perms ...Permission) (TokenKind, error) {
+	kind := TokenKindOf(token)
+	src, isApp := g.TokenSource.(*AppTokenSource)
+	if token == "" && isApp {
+		kind = TokenApp
+	}
+	target := owner + "/" + repo
+	resp, err := g.apiDo(ctx, token, http.MethodGet, fmt.Sprintf("/repos/%s/%s", owner, repo), nil, "")
+	switch {
+	case hasStatus(err, http.StatusUnauthorized):
+		return kind, fmt.Errorf("GitHub rejected the %s token: %w", kind, err)
+	case hasStatus(err, http.StatusNotFound), hasStatus(err, http.StatusForbidden):
+		// The fine-grained tokens can't see the repositories they are not
+		// given.
+		return kind, fmt.Errorf("the %s token can't read %s: %w", kind, target, ErrMissingPermission)
+	case err != nil:
+		return kind, fmt.Errorf("checking the permissions of the token: %w", err)
+	}
+	scopes, hasScopes := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]
+	var access repoAccess
+	if err := decodeResponse(resp, &access); err != nil {
+		return kind, err
+	}
+
+	var missing []string
+	checked := access.Permissions != nil
+	if kind == TokenClassic && hasScopes {
+		missing, checked = missingScopes(strings.Join(scopes, ","), access.Private), true
+	}
+	if token == "" && isApp {
+		if granted := src.Permissions(); granted != nil {
+			missing, checked = missingPermissions(granted, perms), true
+		}
+	}
+	if len(missing) == 0 {
+		missing = missingRoles(access.Permissions, perms)
+	}
+	if !checked {
+		g.debugf("GitHub doesn't report the permissions of the %s token on %s", kind, target)
+	}
+	if len(missing) > 0 {
+		return kind, fmt.Errorf("the %s token lacks %s on %s: %w", kind, strings.Join(missing, ", "), target, ErrMissingPermission)
+	}
+	if kind == TokenFineGrained && len(perms) > 0 {
+		names := make([]string, len(perms))
+		for i, p := range perms {
+			names[i] = fmt.Sprintf("%q", p)
+		}
+		g.Warnings.Add(WarnAuthInconclusive, "the permissions of the %s token on %s can't be read, make sure it has %s",
+			kind, target, strings.Join(names, ", "))
+	}
+	return kind, nil
+}
+
+// missingScopes returns the scope a classic token needs to release, if it
+// doesn't have it: the repo scope, or public_repo for the public
+// repositories.
+func missingScopes(header string, private bool) []string {
+	for _, s := range strings.Split(header, ",") {
+		s = strings.TrimSpace(s)
+		if s == "repo" || (s == "public_repo" && !private) {
+			return nil
+		}
+	}
+	if private {
+		return []string{`the "repo" scope`}
+	}
+	return []string{`the "public_repo" scope`}
+}
+
+// missingPermissions returns the perms the granted permissions of an
+// installation token don't include.
+func missingPermissions(granted map[string]string, perms []Permission) []string {
+	var missing []string
+	for _, p := range perms {
+		if levels[granted[p.Name]] < levels[p.Level] {
+			missing = append(missing, fmt.Sprintf("the %q permission", p))
+		}
+	}
+	return missing
+}
+
+// missingRoles returns the perms the permissions field of the repository
+// doesn't grant. It returns nil if the field is missing.
+func missingRoles(roles map[string]bool, perms []Permission) []string {
+	if roles == nil {
+		return nil
+	}
+	var missing []string
+	for _, p := range perms {
+		if !roles[p.role()] {
+			missing = append(missing, fmt.Sprintf("the %q permission", p))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,201 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenKindOf(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		token string
+		want  commit.TokenKind
+	}{
+		"classic":     {"ghp_abc", commit.TokenClassic},
+		"oauth":       {"gho_abc", commit.TokenClassic},
+		"legacy":      {"0123456789abcdef0123456789abcdef01234567", commit.TokenClassic},
+		"fine":        {"github_pat_abc", commit.TokenFineGrained},
+		"user to app": {"ghu_abc", commit.TokenFineGrained},
+		"app":         {"ghs_abc", commit.TokenApp},
+		"unknown":     {"secret", commit.TokenUnknown},
+		"empty":       {"", commit.TokenUnknown},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.TokenKindOf(tc.token))
+		})
+	}
+}
+
+func TestGitCheckPermissions(t *testing.T) {
+	t.Parallel()
+	t.Run("Token", testGitCheckPermissionsToken)
+	t.Run("App", testGitCheckPermissionsApp)
+}
+
+func testGitCheckPermissionsToken(t *testing.T) {
+	t.Parallel()
+	perms := []commit.Permission{commit.PermContentsWrite, commit.PermIssuesWrite}
+	tcs := map[string]struct {
+		token   string
+		scopes  string
+		status  int
+		body    string
+		kind    commit.TokenKind
+		wantErr string
+		// inconclusive is true if the check can't tell whether the token
+		// has the permissions.
+		inconclusive bool
+	}{
+		"classic": {
+			token:  "ghp_abc",
+			scopes: "repo, read:org",
+			body:   `{"private":true,"permissions":{"push":true,"triage":true,"pull":true}}`,
+			kind:   commit.TokenClassic,
+		},
+		"classic public": {
+			token:  "ghp_abc",
+			scopes: "public_repo",
+			body:   `{"private":false,"permissions":{"push":true,"triage":true,"pull":true}}`,
+			kind:   commit.TokenClassic,
+		},
+		"classic private": {
+			token:   "ghp_abc",
+			scopes:  "public_repo",
+			body:    `{"private":true,"permissions":{"push":true,"triage":true,"pull":true}}`,
+			kind:    commit.TokenClassic,
+			wantErr: `the classic token lacks the "repo" scope on user/repo`,
+		},
+		"classic no scopes": {
+			token:   "ghp_abc",
+			scopes:  "",
+			body:    `{"private":false,"permissions":{"push":true,"triage":true,"pull":true}}`,
+			kind:    commit.TokenClassic,
+			wantErr: `the "public_repo" scope`,
+		},
+		"classic read only user": {
+			token:   "ghp_abc",
+			scopes:  "repo",
+			body:    `{"private":false,"permissions":{"pull":true}}`,
+			kind:    commit.TokenClassic,
+			wantErr: `lacks the "contents: write" permission, the "issues: write" permission on user/repo`,
+		},
+		"fine": {
+			token:        "github_pat_abc",
+			body:         `{"permissions":{"admin":false,"push":true,"triage":true,"pull":true}}`,
+			kind:         commit.TokenFineGrained,
+			inconclusive: true,
+		},
+		"fine read only": {
+			token:   "github_pat_abc",
+			body:    `{"permissions":{"push":false,"triage":true,"pull":true}}`,
+			kind:    commit.TokenFineGrained,
+			wantErr: `the fine-grained token lacks the "contents: write" permission on user/repo`,
+		},
+		"fine not given": {
+			token:   "github_pat_abc",
+			status:  http.StatusNotFound,
+			kind:    commit.TokenFineGrained,
+			wantErr: "the fine-grained token can't read user/repo",
+		},
+		"not reported": {
+			token: "ghs_abc",
+			body:  `{"private":true}`,
+			kind:  commit.TokenApp,
+		},
+		"rejected": {
+			token:   "ghp_abc",
+			status:  http.StatusUnauthorized,
+			kind:    commit.TokenClassic,
+			wantErr: "GitHub rejected the classic token",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/repos/user/repo", r.URL.Path)
+				if tc.scopes != "" || tc.kind == commit.TokenClassic {
+					w.Header().Set("X-OAuth-Scopes", tc.scopes)
+				}
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					return
+				}
+				fmt.Fprint(w, tc.body)
+			}))
+			t.Cleanup(srv.Close)
+			w := &commit.Warnings{}
+			g := &commit.Git{BaseURL: srv.URL, Warnings: w}
+			kind, err := g.CheckPermissions(context.Background(), tc.token, "user", "repo", perms...)
+			assert.Equal(t, tc.kind, kind)
+			if tc.inconclusive {
+				require.Len(t, w.List(), 1)
+				assert.Equal(t, commit.WarnAuthInconclusive, w.List()[0].Code)
+				assert.Contains(t, w.List()[0].Message, `"contents: write", "issues: write"`)
+			} else {
+				assert.Zero(t, w.Len())
+			}
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+			if tc.status != http.StatusUnauthorized {
+				assert.ErrorIs(t, err, commit.ErrMissingPermission)
+			}
+		})
+	}
+}
+
+func testGitCheckPermissionsApp(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key := testAppKey(t)
+	tcs := map[string]struct {
+		perms   map[string]string
+		wantErr string
+	}{
+		"write":     {perms: map[string]string{"contents": "write", "issues": "write"}},
+		"admin":     {perms: map[string]string{"contents": "admin", "issues": "write"}},
+		"read":      {perms: map[string]string{"contents": "read", "issues": "write"}, wantErr: `lacks the "contents: write" permission on`},
+		"not given": {perms: map[string]string{"metadata": "read"}, wantErr: `the "contents: write" permission, the "issues: write" permission`},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			app := newFakeApp(t, &key.PublicKey)
+			app.perms = tc.perms
+			g := &commit.Git{
+				BaseURL: app.URL,
+				TokenSource: &commit.AppTokenSource{
+					AppID:   "123",
+					Owner:   "user",
+					Repo:    "repo",
+					Key:     key,
+					BaseURL: app.URL,
+				},
+			}
+			kind, err := g.CheckPermissions(ctx, "", "user", "repo", commit.PermContentsWrite, commit.PermIssuesWrite)
+			assert.Equal(t, commit.TokenApp, kind)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+			assert.ErrorIs(t, err, commit.ErrMissingPermission)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package commit
+
+import (
+	"io"
+)
+
+// Progress receives the progress of the long running operations, e.g. to show
+// it in a terminal. The Git methods report the transfers, and the callers can
+// report the steps of their own flows.
+type Progress interface {
+	// Start reports that the step has started. The returned function is
+	// called with the result of the step when it ends.
+	Start(step string) func(err error)
+	// Transfer reports that done bytes of the total are sent for the name,
+	// e.g. of an asset that is being uploaded.
+	Transfer(name string, done, total int64)
+}
+
+// noProgress discards the progress.
+type noProgress struct{}
+
+func (noProgress) Start(string) func(error)      { return func(error) {} }
+func (noProgress) Transfer(string, int64, int64) {}
+
+// progress returns the Progress, or one that discards the progress if it's
+// not set.
+func (g *Git) progress() Progress {
+	if g.Progress != nil {
+		return g.Progress
+	}
+	return noProgress{}
+}
+
+// countingReader reports the bytes that are read from r.
+type countingReader struct {
+	r        io.Reader
+	name     string
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.done += int64(n)
+		c.progress.Transfer(c.name, c.done, c.total)
+	}
+	return n, err
+}
@@ -0,0 +1,138 @@
+package commit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Provenance records how a release is produced.
+type Provenance struct {
+	// Version is the version of gitrelease.
+	Version    string `json:"version"`
+	GitVersion string `json:"git_version"`
+	// Commit is the SHA of the released commit.
+	Commit string `json:"commit"`
+	// RunURL is the address of the CI run, if it's known.
+	RunURL string `json:"run_url,omitempty"`
+	// Flags are the flags that are set, with the sensitive values redacted.
+	Flags map[string]string `json:"flags,omitempty"`
+}
+
+// redacted replaces the sensitive values.
+const redacted = "[REDACTED]"
+
+// sensitiveRe matches the names of the flags whose values are never recorded.
+var sensitiveRe = regexp.MustCompile(`(?i)token|secret|password|passwd|key|auth|credential`)
+
+// secretEnvs are the environment variables whose values are never recorded,
+// even if they are passed in other values.
+var secretEnvs = []string{
+	"GITHUB_TOKEN",
+	"GITHUB_APP_PRIVATE_KEY",
+	"SMTP_PASSWORD",
+}
+
+// Provenance returns the provenance of releasing the tag by the version of
+// gitrelease with the flags. The CI run is detected from the environment.
+func (g *Git) Provenance(ctx context.Context, version, tag string, flags map[string]string) (Provenance, error) {
+	p := Provenance{
+		Version: version,
+		RunURL:  CIRunURL(os.Getenv),
+		Flags:   redactFlags(flags, os.Getenv),
+	}
+	out, err := g.run(ctx, newCommand("version"))
+	if err != nil {
+		return p, fmt.Errorf("getting git version: %w", err)
+	}
+	p.GitVersion = strings.TrimPrefix(strings.TrimSpace(out), "git version ")
+	p.Commit, err = g.resolve(ctx, tag)
+	if err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// CIRunURL returns the address of the CI run from the standard environment
+// variables of GitHub Actions, GitLab CI, CircleCI and Jenkins. It returns an
+// empty string if none is set.
+func CIRunURL(getenv func(string) string) string {
+	if id := getenv("GITHUB_RUN_ID"); id != "" {
+		server := getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		u := fmt.Sprintf("%s/%s/actions/runs/%s", server, getenv("GITHUB_REPOSITORY"), id)
+		if attempt := getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+			u += "/attempts/" + attempt
+		}
+		return u
+	}
+	for _, key := range []string{"CI_JOB_URL", "CIRCLE_BUILD_URL", "BUILD_URL"} {
+		if u := getenv(key); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// redactFlags returns a copy of the flags with the values of the sensitive
+// flags, and the secrets of the environment, redacted.
+func redactFlags(flags map[string]string, getenv func(string) string) map[string]string {
+	if len(flags) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(flags))
+	for name, value := range flags {
+		if sensitiveRe.MatchString(name) {
+			out[name] = redacted
+			continue
+		}
+		for _, key := range secretEnvs {
+			if secret := getenv(key); secret != "" {
+				value = strings.ReplaceAll(value, secret, redacted)
+			}
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// provenanceMarker starts the HTML comment of the provenance in the notes.
+const provenanceMarker = "<!-- gitrelease-provenance\n"
+
+// provenanceRe matches the provenance footer.
+var provenanceRe = regexp.MustCompile(`(?s)\n*<!-- gitrelease-provenance\n(.*?)\n-->\s*$`)
+
+// Footer returns the provenance as an HTML comment, which is not rendered on
+// the release page but can be recovered with ParseProvenance.
+func (p Provenance) Footer() (string, error) {
+	// The encoder escapes ">", therefore the JSON can't close the comment.
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding the provenance: %w", err)
+	}
+	return provenanceMarker + string(b) + "\n-->", nil
+}
+
+// ParseProvenance returns the provenance in the footer of the body. It returns
+// false if the body doesn't have one.
+func ParseProvenance(body string) (Provenance, bool) {
+	var p Provenance
+	m := provenanceRe.FindStringSubmatch(body)
+	if m == nil {
+		return p, false
+	}
+	if err := json.Unmarshal([]byte(m[1]), &p); err != nil {
+		return p, false
+	}
+	return p, true
+}
+
+// StripProvenance returns the body without its provenance footer.
+func StripProvenance(body string) string {
+	return provenanceRe.ReplaceAllString(body, "")
+}
@@ -0,0 +1,133 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/blokur/testament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nolint:paralleltest // it sets the environment.
+func TestGitProvenance(t *testing.T) {
+	ctx := context.Background()
+	secret := "ghs_" + testament.RandomString(30)
+	t.Setenv("GITHUB_TOKEN", secret)
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "arsham/gitrelease")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_RUN_ATTEMPT", "")
+
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "feat: thing")
+	runGit(t, dir, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+	sha := strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+
+	g := commit.Git{Dir: dir}
+	flags := map[string]string{
+		"tag":          "v1.0.0",
+		"github-token": secret,
+		"asset-var":    "[Auth=" + secret + "]",
+	}
+	p, err := g.Provenance(ctx, "v9.9.9", "v1.0.0", flags)
+	require.NoError(t, err)
+	assert.Equal(t, "v9.9.9", p.Version)
+	assert.NotEmpty(t, p.GitVersion)
+	assert.NotContains(t, p.GitVersion, "git version")
+	assert.Equal(t, sha, p.Commit)
+	assert.Equal(t, "https://github.com/arsham/gitrelease/actions/runs/42", p.RunURL)
+	assert.Equal(t, "v1.0.0", p.Flags["tag"])
+	assert.Equal(t, "[REDACTED]", p.Flags["github-token"])
+	assert.Equal(t, "[Auth=[REDACTED]]", p.Flags["asset-var"])
+	assert.Equal(t, "v1.0.0", flags["tag"], "the flags should not be changed")
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"commit":"`+sha+`"`, "the annotated tag should be peeled to its commit")
+	footer, err := p.Footer()
+	require.NoError(t, err)
+	for _, out := range []string{string(b), footer} {
+		assert.NotContains(t, out, secret)
+	}
+
+	notes := "### Features\n\n- Thing"
+	body := notes + "\n\n" + footer
+	got, ok := commit.ParseProvenance(body)
+	require.True(t, ok)
+	assert.Equal(t, p, got)
+	assert.Equal(t, notes, commit.StripProvenance(body))
+}
+
+func TestProvenanceFooter(t *testing.T) {
+	t.Parallel()
+	p := commit.Provenance{
+		Version: "1.0.0",
+		Flags:   map[string]string{"issue-comment": "--> <b>"},
+	}
+	footer, err := p.Footer()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(footer, "<!--"))
+	assert.True(t, strings.HasSuffix(footer, "-->"))
+	assert.Equal(t, 1, strings.Count(footer, "-->"), "the values should not close the comment")
+
+	got, ok := commit.ParseProvenance("notes\n\n" + footer + "\n")
+	require.True(t, ok)
+	assert.Equal(t, p, got)
+
+	_, ok = commit.ParseProvenance("notes")
+	assert.False(t, ok)
+	_, ok = commit.ParseProvenance("notes\n\n<!-- gitrelease-provenance\nnot json\n-->")
+	assert.False(t, ok)
+	assert.Equal(t, "notes", commit.StripProvenance("notes"))
+}
+
+func TestCIRunURL(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		env  map[string]string
+		want string
+	}{
+		"none": {},
+		"github": {
+			env: map[string]string{
+				"GITHUB_SERVER_URL":  "https://ghe.example.com",
+				"GITHUB_REPOSITORY":  "arsham/gitrelease",
+				"GITHUB_RUN_ID":      "42",
+				"GITHUB_RUN_ATTEMPT": "2",
+			},
+			want: "https://ghe.example.com/arsham/gitrelease/actions/runs/42/attempts/2",
+		},
+		"github default server": {
+			env: map[string]string{
+				"GITHUB_REPOSITORY": "arsham/gitrelease",
+				"GITHUB_RUN_ID":     "42",
+			},
+			want: "https://github.com/arsham/gitrelease/actions/runs/42",
+		},
+		"gitlab": {
+			env:  map[string]string{"CI_JOB_URL": "https://gitlab.com/a/b/-/jobs/1"},
+			want: "https://gitlab.com/a/b/-/jobs/1",
+		},
+		"circle": {
+			env:  map[string]string{"CIRCLE_BUILD_URL": "https://circleci.com/gh/a/b/1"},
+			want: "https://circleci.com/gh/a/b/1",
+		},
+		"jenkins": {
+			env:  map[string]string{"BUILD_URL": "https://jenkins.example.com/job/b/1/"},
+			want: "https://jenkins.example.com/job/b/1/",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := commit.CIRunURL(func(key string) string { return tc.env[key] })
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// PullOptions configures AssociatePulls.
+type PullOptions struct {
+	// Offline disables the lookups from the API. Only the numbers of the
+	// messages are used.
+	Offline bool
+	// Concurrency is the maximum number of concurrent lookups. It defaults
+	// to 4.
+	Concurrency int
+	// Labels sets the Labels of the commits, e.g. for a LabelClassifier. The
+	// labels of the pull requests whose numbers are read from the messages
+	// are looked up too.
+	Labels bool
+}
+
+// AssociatePulls sets the PRNumber and the PRURL of the commits. The numbers
+// are read from the messages by Log, which misses the pull requests that
+// are merged with rebase. For the commits without a number, the pull
+// requests are looked up from the API, unless the opts is Offline. The
+// responses are cached like other GET requests, and the pull requests of all
+// commits are kept in the Ranges if it is set. A failed lookup doesn't stop
+// the others.
+func (g *Git) AssociatePulls(ctx context.Context, token string, remote RemoteInfo, commits []Commit, opts PullOptions) error {
+	key := g.pullsKey(ctx, token, remote, commits, opts)
+	if g.cachedPulls(key, commits) {
+		return nil
+	}
+	var (
+		mu       sync.Mutex
+		failures []string
+	)
+	eg, ctx := errgroup.WithContext(ctx)
+	if opts.Concurrency > 0 {
+		eg.SetLimit(opts.Concurrency)
+	} else {
+		eg.SetLimit(4)
+	}
+	for i := range commits {
+		c := &commits[i]
+		if opts.Offline || (c.PRNumber > 0 && !opts.Labels) {
+			continue
+		}
+		eg.Go(func() error {
+			var (
+				n      = c.PRNumber
+				link   = c.PRURL
+				labels []string
+				err    error
+			)
+			if n > 0 {
+				labels, err = g.pullLabels(ctx, token, remote, n)
+			} else {
+				n, link, labels, err = g.commitPull(ctx, token, remote, c.SHA)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", c.SHA, err))
+				return nil
+			}
+			c.PRNumber, c.PRURL = n, link
+			if opts.Labels {
+				c.Labels = labels
+			}
+			return nil
+		})
+	}
+	// nolint:errcheck // the goroutines don't return errors.
+	eg.Wait()
+	for i := range commits {
+		if c := &commits[i]; c.PRNumber > 0 && c.PRURL == "" {
+			c.PRURL = remote.PullURL(c.PRNumber)
+		}
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("failed to look up the pull requests of %d commit(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	// The failed lookups are retried in the next run.
+	g.storePulls(key, commits)
+	return nil
+}
+
+// pullLabel is a label in the responses of the API.
+type pullLabel struct {
+	Name string `json:"name"`
+}
+
+func labelNames(labels []pullLabel) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// commitPull returns the number, the address and the labels of the merged
+// pull request the commit belongs to. It returns zero if there is none.
+func (g *Git) commitPull(ctx context.Context, token string, remote RemoteInfo, sha string) (int, string, []string, error) {
+	var pulls []struct {
+		Number   int         `json:"number"`
+		HTMLURL  string      `json:"html_url"`
+		MergedAt string      `json:"merged_at"`
+		Labels   []pullLabel `json:"labels"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/commits/%s/pulls", remote.Owner, remote.Name, sha)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &pulls); err != nil {
+		return 0, "", nil, err
+	}
+	for _, p := range pulls {
+		// The open pull requests that contain the commit are listed too.
+		if p.MergedAt != "" {
+			return p.Number, p.HTMLURL, labelNames(p.Labels), nil
+		}
+	}
+	return 0, "", nil, nil
+}
+
+// pullLabels returns the labels of the pull request with the number.
+func (g *Git) pullLabels(ctx context.Context, token string, remote RemoteInfo, n int) ([]string, error) {
+	var issue struct {
+		Labels []pullLabel `json:"labels"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/issues/%d", remote.Owner, remote.Name, n)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &issue); err != nil {
+		return nil, err
+	}
+	return labelNames(issue.Labels), nil
+}
+
+// PullLogs returns the messages of the commits, with the number of their pull
+// requests at the end of the subjects, as GitHub does for the squashed pull
+// requests. The subject of a merge commit is replaced with the title of its
+// pull request. The notes of the commits are preferred over their subjects,
+// see Commit.Annotated.
+func PullLogs(commits []Commit) []string {
+	logs := make([]string, 0, len(commits))
+	for _, c := range commits {
+		msg := c.Annotated()
+		if c.PRNumber > 0 {
+			msg = withPullNumber(msg, c.PRNumber)
+		}
+		logs = append(logs, msg)
+	}
+	return logs
+}
+
+func withPullNumber(msg string, n int) string {
+	subject, body, _ := strings.Cut(msg, "\n")
+	if mergePullRe.MatchString(subject) {
+		if title, rest, _ := strings.Cut(strings.TrimLeft(body, "\n"), "\n"); title != "" {
+			subject, body = title, rest
+		}
+	}
+	if squashPullRe.MatchString(subject) {
+		return subject + "\n" + body
+	}
+	return fmt.Sprintf("%s (#%d)\n%s", strings.TrimRight(subject, " "), n, body)
+}
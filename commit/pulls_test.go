@@ -0,0 +1,194 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePulls serves the pull requests associated with the commits.
+type fakePulls struct {
+	*httptest.Server
+	mu       sync.Mutex
+	pulls    map[string][]map[string]any
+	issues   map[string]map[string]any
+	requests []string
+}
+
+func newFakePulls(t *testing.T) *fakePulls {
+	t.Helper()
+	f := &fakePulls{
+		pulls:  make(map[string][]map[string]any),
+		issues: make(map[string]map[string]any),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/issues/"); n != r.URL.Path {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.requests = append(f.requests, "#"+n)
+			issue, ok := f.issues[n]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(issue))
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/commits/")
+		sha := strings.TrimSuffix(rest, "/pulls")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, sha)
+		pulls, ok := f.pulls[sha]
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(pulls))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestGitAssociatePulls(t *testing.T) {
+	t.Parallel()
+	t.Run("Lookup", testGitAssociatePullsLookup)
+	t.Run("Offline", testGitAssociatePullsOffline)
+	t.Run("Failure", testGitAssociatePullsFailure)
+	t.Run("Labels", testGitAssociatePullsLabels)
+}
+
+var pullsRemote = commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"}
+
+func testGitAssociatePullsLookup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakePulls(t)
+	gh.pulls["bbb"] = []map[string]any{
+		{"number": 7, "html_url": "https://example.com/pull/7"},
+		{"number": 8, "html_url": "https://example.com/pull/8", "merged_at": "2024-06-01T10:00:00Z"},
+	}
+	gh.pulls["ccc"] = []map[string]any{}
+	commits := []commit.Commit{
+		{SHA: "aaa", Message: "feat: add the thing (#3)\n", PRNumber: 3},
+		{SHA: "bbb", Message: "fix: rebased\n"},
+		{SHA: "ccc", Message: "chore: pushed directly\n"},
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	err := g.AssociatePulls(ctx, "token", pullsRemote, commits, commit.PullOptions{Concurrency: 1})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bbb", "ccc"}, gh.requests)
+
+	assert.Equal(t, "https://github.com/user/repo/pull/3", commits[0].PRURL)
+	assert.Equal(t, 8, commits[1].PRNumber)
+	assert.Equal(t, "https://example.com/pull/8", commits[1].PRURL)
+	assert.Zero(t, commits[2].PRNumber)
+	assert.Empty(t, commits[2].PRURL)
+
+	want := []string{
+		"feat: add the thing (#3)\n",
+		"fix: rebased (#8)\n",
+		"chore: pushed directly\n",
+	}
+	if diff := cmp.Diff(want, commit.PullLogs(commits)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGitAssociatePullsLabels(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakePulls(t)
+	gh.pulls["bbb"] = []map[string]any{{
+		"number":    8,
+		"html_url":  "https://example.com/pull/8",
+		"merged_at": "2024-06-01T10:00:00Z",
+		"labels":    []map[string]any{{"name": "bug"}, {"name": "backend"}},
+	}}
+	gh.issues["3"] = map[string]any{"labels": []map[string]any{{"name": "enhancement"}}}
+	commits := []commit.Commit{
+		{SHA: "aaa", Message: "add the thing (#3)\n", PRNumber: 3},
+		{SHA: "bbb", Message: "rebased\n"},
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	err := g.AssociatePulls(ctx, "token", pullsRemote, commits, commit.PullOptions{Labels: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"#3", "bbb"}, gh.requests)
+	assert.Equal(t, []string{"enhancement"}, commits[0].Labels)
+	assert.Equal(t, "https://github.com/user/repo/pull/3", commits[0].PRURL)
+	assert.Equal(t, []string{"bug", "backend"}, commits[1].Labels)
+	assert.Equal(t, 8, commits[1].PRNumber)
+}
+
+func testGitAssociatePullsOffline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakePulls(t)
+	commits := []commit.Commit{
+		{SHA: "aaa", Message: "Merge pull request #4 from user/branch\n\nfix: the title\n", PRNumber: 4},
+		{SHA: "bbb", Message: "fix: rebased\n"},
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	err := g.AssociatePulls(ctx, "token", pullsRemote, commits, commit.PullOptions{Offline: true})
+	require.NoError(t, err)
+	assert.Empty(t, gh.requests)
+	assert.Equal(t, "https://github.com/user/repo/pull/4", commits[0].PRURL)
+	assert.Zero(t, commits[1].PRNumber)
+
+	want := []string{"fix: the title (#4)\n", "fix: rebased\n"}
+	if diff := cmp.Diff(want, commit.PullLogs(commits)); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func testGitAssociatePullsFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakePulls(t)
+	gh.pulls["bbb"] = []map[string]any{
+		{"number": 9, "html_url": "https://example.com/pull/9", "merged_at": "2024-06-01T10:00:00Z"},
+	}
+	commits := []commit.Commit{
+		{SHA: "aaa", Message: "fix: one\n"},
+		{SHA: "bbb", Message: "fix: two\n"},
+	}
+	g := &commit.Git{BaseURL: gh.URL}
+	err := g.AssociatePulls(ctx, "token", pullsRemote, commits, commit.PullOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aaa")
+	assert.Zero(t, commits[0].PRNumber)
+	assert.Equal(t, 9, commits[1].PRNumber)
+}
+
+func TestGitLogPullNumber(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v0.1.0")
+	r.Commit("feat: squashed (#12)")
+	r.Commit("fix: rebased")
+	r.Tag("v0.2.0")
+
+	g := commit.New(commit.WithDir(r.Dir))
+	info, err := g.Prepare(ctx, "v0.2.0")
+	require.NoError(t, err)
+	require.Len(t, info.Commits, 2)
+	numbers := map[string]int{}
+	for _, c := range info.Commits {
+		numbers[strings.TrimSpace(c.Message)] = c.PRNumber
+	}
+	assert.Equal(t, map[string]int{"feat: squashed (#12)": 12, "fix: rebased": 0}, numbers)
+	assert.Equal(t, commit.Messages(info.Commits), info.Logs)
+}
@@ -0,0 +1,197 @@
+package commit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RangeCache keeps the commits of the ranges of Prepare, and the pull
+// requests AssociatePulls finds for them, between the runs. It's meant for the
+// pipelines that generate the same notes in several jobs.
+//
+// The commits are keyed by the repository, the SHAs the tags resolve to and
+// the settings of the Git that change the commits, therefore a moved tag or a
+// new setting misses the cache. The pull requests are kept separately and are
+// keyed by the token too, therefore the pull requests of one token are never
+// served to another.
+type RangeCache struct {
+	// Dir is where the ranges are kept.
+	Dir string
+	// Options is added to the keys, e.g. the version of the program or the
+	// settings of the caller that change the commits.
+	Options string
+	// Refresh ignores the cached ranges. The new ones are stored.
+	Refresh bool
+}
+
+// cachedRange is the commits of a range before their pull requests are
+// associated.
+type cachedRange struct {
+	Commits  []Commit `json:"commits"`
+	Excluded int      `json:"excluded"`
+}
+
+// cachedPull is the pull request of a commit.
+type cachedPull struct {
+	Number int      `json:"number"`
+	URL    string   `json:"url,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// hashKey returns the key of the parts.
+func hashKey(parts ...string) string {
+	h := sha256.New()
+	for _, s := range parts {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// rangeKey returns the key of the commits between the tag and its previous
+// tag. The SHAs are read from the walk of PreviousTag, therefore it doesn't
+// spawn any other git processes.
+func (g *Git) rangeKey(ctx context.Context, tag string) (string, error) {
+	w, err := g.walkChannel(ctx, tag, g.previousChannel())
+	if err != nil {
+		return "", err
+	}
+	prev, _ := g.previousTagged(w, tag)
+	remotes, err := g.loadRemotes(ctx)
+	if err != nil {
+		return "", err
+	}
+	ranges, err := g.rangesKey(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+	return hashKey(
+		remotes[g.remote()],
+		prev,
+		w.head,
+		g.RangeMode.String(),
+		strings.Join(g.Paths, "\x01"),
+		strings.Join(g.Exclude, "\x01"),
+		strconv.Itoa(g.maxSubject()),
+		g.Order.String(),
+		strconv.FormatBool(g.NoMerges),
+		strconv.FormatBool(g.FirstParent),
+		strconv.FormatBool(g.ReplaceRefs),
+		g.NotesRef,
+		g.notesHead(ctx),
+		ranges,
+		g.Ranges.Options,
+	), nil
+}
+
+// cachedRange returns the key of the range of the tag, and its commits if
+// they are cached. The key is empty if the ranges are not cached.
+func (g *Git) cachedRange(ctx context.Context, tag string) (string, *cachedRange) {
+	if g.Ranges == nil {
+		return "", nil
+	}
+	key, err := g.rangeKey(ctx, tag)
+	if err != nil {
+		g.debugf("finding the key of the range of %s: %v", tag, err)
+		return "", nil
+	}
+	if g.Ranges.Refresh {
+		return key, nil
+	}
+	r := &cachedRange{}
+	if !g.readRangeCache("ranges", key, r) {
+		return key, nil
+	}
+	g.debugf("using the cached commits of %s", tag)
+	return key, r
+}
+
+// storeRange keeps the commits of the range of the key.
+func (g *Git) storeRange(key string, r *cachedRange) {
+	g.writeRangeCache("ranges", key, r)
+}
+
+// pullsKey returns the key of the pull requests of the commits, or an empty
+// string if they are not cached.
+func (g *Git) pullsKey(ctx context.Context, token string, remote RemoteInfo, commits []Commit, opts PullOptions) string {
+	if g.Ranges == nil || opts.Offline || len(commits) == 0 {
+		return ""
+	}
+	token, err := g.token(ctx, token)
+	if err != nil {
+		return ""
+	}
+	parts := []string{token, g.baseURL(), remote.Owner, remote.Name, strconv.FormatBool(opts.Labels), g.Ranges.Options}
+	for _, c := range commits {
+		parts = append(parts, c.SHA, strconv.Itoa(c.PRNumber))
+	}
+	return hashKey(parts...)
+}
+
+// cachedPulls sets the pull requests of the commits from the cache of the
+// key, and returns true if they are cached.
+func (g *Git) cachedPulls(key string, commits []Commit) bool {
+	if key == "" || g.Ranges.Refresh {
+		return false
+	}
+	var pulls map[string]cachedPull
+	if !g.readRangeCache("pulls", key, &pulls) {
+		return false
+	}
+	for _, c := range commits {
+		if _, ok := pulls[c.SHA]; !ok {
+			g.debugf("ignoring the cached pull requests %s: %s is missing", key, c.SHA)
+			return false
+		}
+	}
+	for i := range commits {
+		p := pulls[commits[i].SHA]
+		commits[i].PRNumber, commits[i].PRURL, commits[i].Labels = p.Number, p.URL, p.Labels
+	}
+	g.debugf("using the cached pull requests of %d commit(s)", len(commits))
+	return true
+}
+
+// storePulls keeps the pull requests of the commits with the key.
+func (g *Git) storePulls(key string, commits []Commit) {
+	if key == "" {
+		return
+	}
+	pulls := make(map[string]cachedPull, len(commits))
+	for _, c := range commits {
+		pulls[c.SHA] = cachedPull{Number: c.PRNumber, URL: c.PRURL, Labels: c.Labels}
+	}
+	g.writeRangeCache("pulls", key, pulls)
+}
+
+// readRangeCache decodes the entry of the key in the sub-directory of the Dir
+// of the Ranges into v. It returns false if there is no such entry.
+func (g *Git) readRangeCache(sub, key string, v any) bool {
+	b, err := os.ReadFile(filepath.Join(g.Ranges.Dir, sub, key+".json"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			g.debugf("reading the cache: %v", err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		g.debugf("ignoring the cached %s %s: %v", sub, key, err)
+		return false
+	}
+	return true
+}
+
+func (g *Git) writeRangeCache(sub, key string, v any) {
+	if key == "" {
+		return
+	}
+	if err := writeCache(filepath.Join(g.Ranges.Dir, sub), key, v); err != nil {
+		g.debugf("writing the cache: %v", err)
+	}
+}
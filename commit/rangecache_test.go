@@ -0,0 +1,114 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitRangeCache(t *testing.T) {
+	t.Parallel()
+	t.Run("Commits", testGitRangeCacheCommits)
+	t.Run("Pulls", testGitRangeCachePulls)
+}
+
+func testGitRangeCacheCommits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	addRemote(t, dir, "origin", "git@github.com:user/repo.git")
+	commitChanges(t, dir, "chore: initial")
+	createGitTag(t, dir, "v1.0.0")
+	commitChanges(t, dir, "feat: add the thing")
+	createGitTag(t, dir, "v1.1.0")
+	cache := t.TempDir()
+
+	prepare := func(refresh, replaceRefs bool) (*commit.ReleaseInfo, bool) {
+		t.Helper()
+		l := &logRecorder{}
+		g := &commit.Git{
+			Dir:         dir,
+			Logger:      l,
+			Ranges:      &commit.RangeCache{Dir: cache, Options: "v1", Refresh: refresh},
+			ReplaceRefs: replaceRefs,
+		}
+		info, err := g.Prepare(ctx, "v1.1.0")
+		require.NoError(t, err)
+		for _, line := range l.Lines() {
+			if line == "using the cached commits of v1.1.0" {
+				return info, true
+			}
+		}
+		return info, false
+	}
+
+	want, cached := prepare(false, false)
+	assert.False(t, cached)
+	got, cached := prepare(false, false)
+	assert.True(t, cached)
+	if diff := cmp.Diff(want.Commits, got.Commits); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Equal(t, want.Logs, got.Logs)
+
+	_, cached = prepare(true, false)
+	assert.False(t, cached, "refreshed")
+
+	_, cached = prepare(false, true)
+	assert.False(t, cached, "the replace refs are looked up")
+
+	commitChanges(t, dir, "fix: the late fix")
+	runGit(t, dir, "tag", "-f", "v1.1.0")
+	got, cached = prepare(false, false)
+	assert.False(t, cached, "the tag has moved")
+	assert.Contains(t, got.Logs, "fix: the late fix\n\n")
+	assert.Len(t, got.Logs, 2)
+}
+
+func testGitRangeCachePulls(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakePulls(t)
+	gh.pulls["bbb"] = []map[string]any{
+		{"number": 8, "html_url": "https://example.com/pull/8", "merged_at": "2024-06-01T10:00:00Z"},
+	}
+	gh.pulls["ccc"] = []map[string]any{}
+	cache := t.TempDir()
+
+	associate := func(token string) []commit.Commit {
+		t.Helper()
+		commits := []commit.Commit{
+			{SHA: "aaa", Message: "feat: add the thing (#3)\n", PRNumber: 3},
+			{SHA: "bbb", Message: "fix: rebased\n"},
+			{SHA: "ccc", Message: "chore: pushed directly\n"},
+		}
+		g := &commit.Git{BaseURL: gh.URL, Ranges: &commit.RangeCache{Dir: cache}}
+		err := g.AssociatePulls(ctx, token, pullsRemote, commits, commit.PullOptions{})
+		require.NoError(t, err)
+		return commits
+	}
+	requests := func() []string {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		r := gh.requests
+		gh.requests = nil
+		return r
+	}
+
+	want := associate("token")
+	assert.ElementsMatch(t, []string{"bbb", "ccc"}, requests())
+
+	got := associate("token")
+	assert.Empty(t, requests())
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	assert.Equal(t, 8, got[1].PRNumber)
+
+	associate("other")
+	assert.ElementsMatch(t, []string{"bbb", "ccc"}, requests(), "another token")
+}
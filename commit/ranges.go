@@ -0,0 +1,182 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommitRange is a range of commits that is added to a release, e.g. of a
+// branch that is merged late. Its commits are the ones reachable from To but
+// not from From, with the RangeMode of the Git.
+type CommitRange struct {
+	From string
+	// To is the tag of the release if it's empty.
+	To string
+}
+
+// ParseCommitRange parses "from..to", or "from" and "from.." for the range
+// from the revision to the tag of the release.
+func ParseCommitRange(s string) (CommitRange, error) {
+	if strings.Contains(s, "...") {
+		return CommitRange{}, fmt.Errorf("range %q: use two dots, the RangeMode applies to all ranges", s)
+	}
+	from, to, _ := strings.Cut(s, "..")
+	r := CommitRange{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+	if r.From == "" {
+		return CommitRange{}, fmt.Errorf("range %q has no start", s)
+	}
+	if err := checkRevs(r.From, r.To); err != nil {
+		return CommitRange{}, fmt.Errorf("range %q: %w", s, err)
+	}
+	return r, nil
+}
+
+// String returns the range in the notation of git, e.g. "v1.3.0..freeze".
+func (r CommitRange) String() string {
+	return r.From + ".." + r.To
+}
+
+// WithExtraRanges adds the commits of the ranges to the release. See the
+// ExtraRanges of the Git.
+func WithExtraRanges(ranges ...CommitRange) Option {
+	return func(g *Git) { g.ExtraRanges = append(g.ExtraRanges, ranges...) }
+}
+
+// extraRanges returns the ExtraRanges of the release of the tag, with their
+// empty To set to the tag.
+func (g *Git) extraRanges(tag string) []CommitRange {
+	if len(g.ExtraRanges) == 0 {
+		return nil
+	}
+	ranges := make([]CommitRange, 0, len(g.ExtraRanges))
+	for _, r := range g.ExtraRanges {
+		if r.To == "" {
+			r.To = tag
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// mergeRanges adds the commits of the ranges that are not already in the
+// commits, which are the primary range. The union is sorted in the Order of
+// the Git. With the OrderLog, the commits of the primary range come first,
+// followed by the new ones of each range in turn.
+func (g *Git) mergeRanges(ctx context.Context, commits []Commit, ranges []CommitRange) ([]Commit, error) {
+	if len(ranges) == 0 {
+		return commits, nil
+	}
+	seen := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		seen[c.SHA] = true
+	}
+	for _, r := range ranges {
+		extra, err := g.Log(ctx, r.From, r.To)
+		if err != nil {
+			return nil, fmt.Errorf("reading the commits of range %s: %w", r, err)
+		}
+		for _, c := range extra {
+			if !seen[c.SHA] {
+				seen[c.SHA] = true
+				commits = append(commits, c)
+			}
+		}
+	}
+	sortCommits(commits, g.Order)
+	return commits, nil
+}
+
+// checkUpperBounds adds a WarnAheadOfTag for each upper bound of the ranges
+// of the release that is a descendant of the commit of its tag. The upper
+// bound of the primary range is the upper, which is the HEAD for "@". The
+// ranges that end on another line, e.g. a branch that is merged late, are
+// intended. It's skipped if the warnings are dropped.
+func (g *Git) checkUpperBounds(ctx context.Context, info *ReleaseInfo, upper string) error {
+	if g.Warnings == nil || info.Untagged {
+		return nil
+	}
+	if upper == "@" {
+		upper = "HEAD"
+	}
+	bounds := []string{upper}
+	for _, r := range info.ExtraRanges {
+		bounds = append(bounds, r.To)
+	}
+	var tagSHA string
+	checked := make(map[string]bool, len(bounds))
+	for _, bound := range bounds {
+		if bound == info.Tag || checked[bound] {
+			continue
+		}
+		checked[bound] = true
+		if tagSHA == "" {
+			sha, err := g.resolve(ctx, info.Tag)
+			if err != nil {
+				return err
+			}
+			tagSHA = sha
+		}
+		sha, err := g.resolve(ctx, bound)
+		if err != nil {
+			return err
+		}
+		if sha == tagSHA {
+			continue
+		}
+		ahead, err := g.IsAncestor(ctx, tagSHA, sha)
+		if err != nil {
+			return err
+		}
+		if !ahead {
+			continue
+		}
+		n, err := g.CountCommits(ctx, tagSHA, sha)
+		if err != nil {
+			return err
+		}
+		g.Warnings.Add(WarnAheadOfTag, "the range ends at %s (%s), %d commit(s) ahead of tag %s (%s), therefore the notes include unreleased commits",
+			bound, shortSHA(sha), n, info.Tag, shortSHA(tagSHA))
+	}
+	return nil
+}
+
+// rangesKey returns the part of the key of the cached range of the tag that
+// identifies the commits of the ExtraRanges.
+func (g *Git) rangesKey(ctx context.Context, tag string) (string, error) {
+	buf := &strings.Builder{}
+	for _, r := range g.extraRanges(tag) {
+		from, err := g.resolve(ctx, r.From)
+		if err != nil {
+			return "", err
+		}
+		to, err := g.resolve(ctx, r.To)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(buf, "%s..%s\x01", from, to)
+	}
+	return buf.String(), nil
+}
+
+// WithCompareRanges notes the ranges after the compare link of
+// WithCompareLink, which only has the primary range, e.g. "(also includes
+// v1.3.0..freeze)". See the ExtraRanges of the ReleaseInfo.
+func WithCompareRanges(ranges []CommitRange) RenderOption {
+	return func(o *renderOptions) {
+		o.extraRanges = ranges
+	}
+}
+
+// extraRangesNote returns the note of the extra ranges after the compare
+// link, or an empty string if there are none.
+func (o *renderOptions) extraRangesNote() string {
+	if len(o.extraRanges) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(o.extraRanges))
+	for _, r := range o.extraRanges {
+		names = append(names, "`"+r.String()+"`")
+	}
+	return " (" + o.locale.extraRanges(strings.Join(names, ", ")) + ")"
+}
@@ -0,0 +1,231 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommitRange(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		input   string
+		want    commit.CommitRange
+		wantErr bool
+	}{
+		"range":      {input: "v1.3.0..feature-freeze", want: commit.CommitRange{From: "v1.3.0", To: "feature-freeze"}},
+		"to the tag": {input: "v1.3.0..", want: commit.CommitRange{From: "v1.3.0"}},
+		"revision":   {input: " v1.3.0 ", want: commit.CommitRange{From: "v1.3.0"}},
+		"no start":   {input: "..main", wantErr: true},
+		"empty":      {input: "", wantErr: true},
+		"three dots": {input: "v1.3.0...main", wantErr: true},
+		"option":     {input: "--all..main", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseCommitRange(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// freezeRepo returns a repository whose v1.1.0 misses the commits of the
+// feature-freeze branch, which starts at v1.0.0.
+func freezeRepo(t *testing.T) *committest.Repo {
+	t.Helper()
+	r := committest.NewRepo(t, identity)
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	main := r.CurrentBranch()
+	r.Branch("feature-freeze")
+	r.Commit("fix: the freeze")
+	r.Checkout(main)
+	r.Commit("feat: the main thing")
+	r.Commit("fix: the main leak")
+	r.Tag("v1.1.0")
+	return r
+}
+
+func TestGitPrepareExtraRanges(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := freezeRepo(t)
+	tcs := map[string]struct {
+		ranges []commit.CommitRange
+		want   []string
+		extra  []commit.CommitRange
+	}{
+		"none": {
+			want: []string{"fix: the main leak", "feat: the main thing"},
+		},
+		"branch": {
+			ranges: []commit.CommitRange{{From: "v1.0.0", To: "feature-freeze"}},
+			want:   []string{"fix: the main leak", "feat: the main thing", "fix: the freeze"},
+			extra:  []commit.CommitRange{{From: "v1.0.0", To: "feature-freeze"}},
+		},
+		"overlapping": {
+			ranges: []commit.CommitRange{
+				{From: "v1.0.0"},
+				{From: "v1.0.0", To: "feature-freeze"},
+				{From: "v1.0.0", To: "feature-freeze"},
+			},
+			want: []string{"fix: the main leak", "feat: the main thing", "fix: the freeze"},
+			extra: []commit.CommitRange{
+				{From: "v1.0.0", To: "v1.1.0"},
+				{From: "v1.0.0", To: "feature-freeze"},
+				{From: "v1.0.0", To: "feature-freeze"},
+			},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.New(commit.WithDir(r.Dir), commit.WithExtraRanges(tc.ranges...), commit.WithCommitOrder(commit.OrderLog))
+			info, err := g.Prepare(ctx, "v1.1.0")
+			require.NoError(t, err)
+			got := make([]string, 0, len(info.Logs))
+			for _, l := range info.Logs {
+				got = append(got, strings.TrimSpace(l))
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.extra, info.ExtraRanges)
+			assert.Equal(t, "https://github.com/user/repo/compare/v1.0.0...v1.1.0", info.CompareURL)
+		})
+	}
+
+	t.Run("Cache", func(t *testing.T) {
+		t.Parallel()
+		ranges := &commit.RangeCache{Dir: t.TempDir()}
+		g := commit.New(commit.WithDir(r.Dir), commit.WithCommitOrder(commit.OrderLog))
+		g.Ranges = ranges
+		info, err := g.Prepare(ctx, "v1.1.0")
+		require.NoError(t, err)
+		assert.Len(t, info.Commits, 2)
+
+		g = commit.New(commit.WithDir(r.Dir), commit.WithCommitOrder(commit.OrderLog),
+			commit.WithExtraRanges(commit.CommitRange{From: "v1.0.0", To: "feature-freeze"}))
+		g.Ranges = ranges
+		info, err = g.Prepare(ctx, "v1.1.0")
+		require.NoError(t, err)
+		assert.Len(t, info.Commits, 3)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		t.Parallel()
+		g := commit.New(commit.WithDir(r.Dir), commit.WithExtraRanges(commit.CommitRange{From: "v1.0.0", To: "nope"}))
+		_, err := g.Prepare(ctx, "v1.1.0")
+		assert.ErrorContains(t, err, "v1.0.0..nope")
+	})
+}
+
+func TestParseGroupsCompareRanges(t *testing.T) {
+	t.Parallel()
+	logs := []string{"feat: the thing"}
+	url := "https://github.com/user/repo/compare/v1.0.0...v1.1.0"
+	ranges := []commit.CommitRange{{From: "v1.0.0", To: "feature-freeze"}, {From: "v0.9.0", To: "v1.1.0"}}
+	tcs := map[string]struct {
+		opts []commit.RenderOption
+		want string
+	}{
+		"ranges": {
+			opts: []commit.RenderOption{commit.WithCompareLink(url), commit.WithCompareRanges(ranges)},
+			want: "### Feature\n\n- The thing\n\n**Full Changelog**: " + url +
+				" (also includes `v1.0.0..feature-freeze`, `v0.9.0..v1.1.0`)",
+		},
+		"no ranges": {
+			opts: []commit.RenderOption{commit.WithCompareLink(url), commit.WithCompareRanges(nil)},
+			want: "### Feature\n\n- The thing\n\n**Full Changelog**: " + url,
+		},
+		"no link": {
+			opts: []commit.RenderOption{commit.WithCompareRanges(ranges)},
+			want: "### Feature\n\n- The thing",
+		},
+		"locale": {
+			opts: []commit.RenderOption{
+				commit.WithCompareLink(url),
+				commit.WithCompareRanges(ranges[:1]),
+				commit.WithLocale(commit.Locale{ExtraRanges: "inklusive %s"}),
+			},
+			want: "### Feature\n\n- The thing\n\n**Full Changelog**: " + url + " (inklusive `v1.0.0..feature-freeze`)",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseGroups(logs, tc.opts...))
+		})
+	}
+}
+
+func TestGitPrepareAheadOfTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := freezeRepo(t)
+	tagged := r.Head()
+	r.Commit("feat: not released")
+	r.Commit("fix: not released either")
+	head := r.Head()
+	tcs := map[string]struct {
+		tag    string
+		ranges []commit.CommitRange
+		want   []string
+	}{
+		"tag is head": {
+			tag: "v1.1.0",
+		},
+		"tag behind head": {
+			tag: "@",
+			want: []string{
+				"the range ends at HEAD (" + head[:7] + "), 2 commit(s) ahead of tag v1.1.0 (" + tagged[:7] + ")",
+			},
+		},
+		"sha of the tag": {
+			tag:    "v1.1.0",
+			ranges: []commit.CommitRange{{From: "v1.0.0", To: tagged}},
+		},
+		"sha after the tag": {
+			tag:    "v1.1.0",
+			ranges: []commit.CommitRange{{From: "v1.0.0", To: head}},
+			want: []string{
+				"the range ends at " + head + " (" + head[:7] + "), 2 commit(s) ahead of tag v1.1.0 (" + tagged[:7] + ")",
+			},
+		},
+		"other branch": {
+			tag:    "v1.1.0",
+			ranges: []commit.CommitRange{{From: "v1.0.0", To: "feature-freeze"}},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			warnings := &commit.Warnings{}
+			g := commit.New(commit.WithDir(r.Dir), commit.WithExtraRanges(tc.ranges...))
+			g.Warnings = warnings
+			info, err := g.Prepare(ctx, tc.tag)
+			require.NoError(t, err)
+			assert.Equal(t, "v1.1.0", info.Tag)
+			var got []string
+			for _, w := range warnings.List() {
+				assert.Equal(t, commit.WarnAheadOfTag, w.Code)
+				got = append(got, strings.TrimSuffix(w.Message, ", therefore the notes include unreleased commits"))
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PullNotice selects how NotifyPulls tells the pull requests they are
+// released. The notices can be combined.
+type PullNotice int
+
+// These are the possible notices.
+const (
+	// NoticeComment posts a comment, which notifies the author.
+	NoticeComment PullNotice = 1 << iota
+	// NoticeLabel adds a label.
+	NoticeLabel
+)
+
+// ParsePullNotice parses "comment", "label" or "both" into a PullNotice.
+func ParsePullNotice(s string) (PullNotice, error) {
+	switch strings.ToLower(s) {
+	case "comment":
+		return NoticeComment, nil
+	case "label":
+		return NoticeLabel, nil
+	case "both":
+		return NoticeComment | NoticeLabel, nil
+	}
+	return 0, fmt.Errorf("unknown pull request notice %q, expected comment, label or both", s)
+}
+
+const (
+	// DefaultPullComment is the template of the comments posted on the
+	// released pull requests.
+	DefaultPullComment = "Included in [{{.Tag}}]({{.URL}})."
+	// DefaultReleasedLabel is the template of the label added to the
+	// released pull requests.
+	DefaultReleasedLabel = "released/{{.Tag}}"
+)
+
+// PullNoticeOptions configures NotifyPulls.
+type PullNoticeOptions struct {
+	// Tag and URL are the released tag and the address of its release. They
+	// are available in the Template and the Label along with the Pull number.
+	Tag string
+	URL string
+	// Notice defaults to NoticeComment.
+	Notice PullNotice
+	// Template is a text/template for the comment. It defaults to
+	// DefaultPullComment.
+	Template string
+	// Label is a text/template for the label. It defaults to
+	// DefaultReleasedLabel. The pull requests that already have it are
+	// skipped, whichever the Notice is, therefore they are not notified
+	// twice.
+	Label string
+	// Limit is the maximum number of pull requests to notify in a run. Zero
+	// means no limit. The pull requests over the Limit are not read.
+	Limit int
+	// Interval is the minimum time between two pull requests, because GitHub
+	// limits the mutations more strictly than the reads. It defaults to one
+	// second.
+	Interval time.Duration
+}
+
+// PullNoticeSummary is the outcome of notifying the pull requests.
+type PullNoticeSummary struct {
+	// Notified are the numbers of the pull requests that are commented on or
+	// labelled.
+	Notified []int
+	// Forks is the number of the pull requests from forks, which are skipped.
+	Forks int
+	// Labelled is the number of the pull requests that already have the
+	// label.
+	Labelled int
+	// Commented is the number of the pull requests that already have the
+	// comment of the Tag, e.g. of a previous run.
+	Commented int
+	// OverLimit is the number of the pull requests skipped after the Limit is
+	// reached.
+	OverLimit int
+	Failed    int
+}
+
+// NotifyPulls comments on, or labels, the merged pull requests of the commits
+// that they are included in the release. The pull requests from forks and the
+// ones that already have the label are skipped, and so are the ones over the
+// Limit. The comments have a hidden marker of the Tag, therefore the pull
+// requests that already have the comment are skipped too. The pull requests
+// are notified one at a time, and a failed one doesn't stop the others.
+func (g *Git) NotifyPulls(ctx context.Context, token, user, repo string, commits []Commit, opts PullNoticeOptions) (PullNoticeSummary, error) {
+	var summary PullNoticeSummary
+	notice := opts.Notice
+	if notice == 0 {
+		notice = NoticeComment
+	}
+	interval := opts.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	text, labelText := opts.Template, opts.Label
+	if text == "" {
+		text = DefaultPullComment
+	}
+	if labelText == "" {
+		labelText = DefaultReleasedLabel
+	}
+	tmpl, err := template.New("comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return summary, fmt.Errorf("parsing the comment template: %w", err)
+	}
+	labelTmpl, err := template.New("label").Option("missingkey=error").Parse(labelText)
+	if err != nil {
+		return summary, fmt.Errorf("parsing the label template: %w", err)
+	}
+
+	numbers := make([]int, 0, len(commits))
+	for _, c := range commits {
+		if c.PRNumber > 0 {
+			numbers = append(numbers, c.PRNumber)
+		}
+	}
+	sort.Ints(numbers)
+	numbers = uniqueInts(numbers)
+
+	var (
+		failures []string
+		last     time.Time
+	)
+	for _, n := range numbers {
+		if opts.Limit > 0 && len(summary.Notified) >= opts.Limit {
+			summary.OverLimit++
+			continue
+		}
+		data := map[string]any{"Tag": opts.Tag, "URL": opts.URL, "Pull": n}
+		label, err := execute(labelTmpl, data)
+		if err != nil {
+			return summary, fmt.Errorf("rendering the label: %w", err)
+		}
+		fork, labelled, err := g.releasedPull(ctx, token, user, repo, n, label)
+		switch {
+		case err != nil:
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("#%d: %v", n, err))
+			continue
+		case fork:
+			g.debugf("skipped #%d, it is from a fork", n)
+			summary.Forks++
+			continue
+		case labelled:
+			g.debugf("skipped #%d, it already has the %s label", n, label)
+			summary.Labelled++
+			continue
+		}
+		var comment string
+		pullNotice := notice
+		if notice&NoticeComment != 0 {
+			body, err := execute(tmpl, data)
+			if err != nil {
+				return summary, fmt.Errorf("rendering the comment: %w", err)
+			}
+			comment = releasedMarker(opts.Tag) + "\n" + body
+			commented, err := g.releasedComment(ctx, token, user, repo, n, opts.Tag)
+			switch {
+			case err != nil:
+				summary.Failed++
+				failures = append(failures, fmt.Sprintf("#%d: %v", n, err))
+				continue
+			case commented && notice&NoticeLabel == 0:
+				g.debugf("skipped #%d, it already has the comment of %s", n, opts.Tag)
+				summary.Commented++
+				continue
+			case commented:
+				// Only the label of a previous run is missing.
+				pullNotice = NoticeLabel
+			}
+		}
+		if wait := interval - time.Since(last); !last.IsZero() && wait > 0 {
+			select {
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		last = time.Now()
+		if err := g.noticePull(ctx, token, user, repo, n, pullNotice, comment, label); err != nil {
+			summary.Failed++
+			failures = append(failures, fmt.Sprintf("#%d: %v", n, err))
+			continue
+		}
+		g.debugf("notified #%d of %s", n, opts.Tag)
+		summary.Notified = append(summary.Notified, n)
+	}
+	if len(failures) > 0 {
+		return summary, fmt.Errorf("failed to notify %d pull request(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return summary, nil
+}
+
+// releasedPull returns true if the pull request is from a fork, and if it
+// already has the label.
+func (g *Git) releasedPull(ctx context.Context, token, user, repo string, n int, label string) (fork, labelled bool, err error) {
+	type pullRepo struct {
+		FullName string `json:"full_name"`
+	}
+	var pull struct {
+		Head struct {
+			// Repo is null if the fork is deleted.
+			Repo *pullRepo `json:"repo"`
+		} `json:"head"`
+		Base struct {
+			Repo pullRepo `json:"repo"`
+		} `json:"base"`
+		Labels []pullLabel `json:"labels"`
+	}
+	uri := fmt.Sprintf("/repos/%s/%s/pulls/%d", user, repo, n)
+	if err := g.api(ctx, token, http.MethodGet, uri, nil, &pull); err != nil {
+		return false, false, fmt.Errorf("getting the pull request: %w", err)
+	}
+	if pull.Head.Repo == nil || !strings.EqualFold(pull.Head.Repo.FullName, pull.Base.Repo.FullName) {
+		return true, false, nil
+	}
+	for _, l := range pull.Labels {
+		if strings.EqualFold(l.Name, label) {
+			return false, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+// releasedMarker returns the hidden marker of the comments of NotifyPulls for
+// the tag.
+func releasedMarker(tag string) string {
+	return fmt.Sprintf("<!-- gitrelease:released %s -->", tag)
+}
+
+// releasedComment returns true if the pull request has a comment with the
+// marker of the tag.
+func (g *Git) releasedComment(ctx context.Context, token, user, repo string, n int, tag string) (bool, error) {
+	marker := releasedMarker(tag)
+	for page := 1; ; page++ {
+		var batch []issueComment
+		uri := fmt.Sprintf("/repos/%s/%s/issues/%d/comments?per_page=%d&page=%d", user, repo, n, listPageSize, page)
+		if err := g.api(ctx, token, http.MethodGet, uri, nil, &batch); err != nil {
+			return false, fmt.Errorf("listing the comments: %w", err)
+		}
+		for _, c := range batch {
+			if strings.HasPrefix(c.Body, marker) {
+				return true, nil
+			}
+		}
+		if len(batch) < listPageSize {
+			return false, nil
+		}
+	}
+}
+
+// noticePull posts the comment on the pull request, and adds the label to it,
+// as the notice selects.
+func (g *Git) noticePull(ctx context.Context, token, user, repo string, n int, notice PullNotice, comment, label string) error {
+	uri := fmt.Sprintf("/repos/%s/%s/issues/%d", user, repo, n)
+	if notice&NoticeComment != 0 {
+		payload := map[string]string{"body": comment}
+		if err := g.api(ctx, token, http.MethodPost, uri+"/comments", payload, nil); err != nil {
+			return fmt.Errorf("posting the comment: %w", err)
+		}
+	}
+	if notice&NoticeLabel != 0 {
+		payload := map[string][]string{"labels": {label}}
+		if err := g.api(ctx, token, http.MethodPost, uri+"/labels", payload, nil); err != nil {
+			return fmt.Errorf("adding the label: %w", err)
+		}
+	}
+	return nil
+}
+
+// execute returns the output of the tmpl with the data.
+func execute(tmpl *template.Template, data any) (string, error) {
+	buf := &strings.Builder{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
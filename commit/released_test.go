@@ -0,0 +1,296 @@
+package commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePullNotice(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		in      string
+		want    commit.PullNotice
+		wantErr bool
+	}{
+		"comment": {in: "comment", want: commit.NoticeComment},
+		"label":   {in: "Label", want: commit.NoticeLabel},
+		"both":    {in: "both", want: commit.NoticeComment | commit.NoticeLabel},
+		"unknown": {in: "email", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParsePullNotice(tc.in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// fakeReleasedPull is a pull request of fakeReleased.
+type fakeReleasedPull struct {
+	// head is the repository of the branch, empty if the fork is deleted.
+	head   string
+	labels []string
+}
+
+// fakeReleased serves the pull requests of user/repo and their comments, and
+// records the comments and the labels added to them, and the pull requests
+// that are read.
+type fakeReleased struct {
+	*httptest.Server
+	mu       sync.Mutex
+	pulls    map[int]*fakeReleasedPull
+	comments map[int][]string
+	labels   map[int][]string
+	read     []int
+}
+
+func newFakeReleased(t *testing.T) *fakeReleased {
+	t.Helper()
+	f := &fakeReleased{
+		pulls:    make(map[int]*fakeReleasedPull),
+		comments: make(map[int][]string),
+		labels:   make(map[int][]string),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/repos/user/repo/pulls/") {
+			n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/repos/user/repo/pulls/"))
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.read = append(f.read, n)
+			p, ok := f.pulls[n]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			pull := map[string]any{
+				"number": n,
+				"base":   map[string]any{"repo": map[string]string{"full_name": "user/repo"}},
+				"head":   map[string]any{"repo": nil},
+			}
+			if p.head != "" {
+				pull["head"] = map[string]any{"repo": map[string]string{"full_name": p.head}}
+			}
+			labels := make([]map[string]string, 0, len(p.labels))
+			for _, l := range p.labels {
+				labels = append(labels, map[string]string{"name": l})
+			}
+			pull["labels"] = labels
+			assert.NoError(t, json.NewEncoder(w).Encode(pull))
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/repos/user/repo/issues/")
+		num, kind, _ := strings.Cut(rest, "/")
+		n, err := strconv.Atoi(num)
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodGet && kind == "comments" {
+			comments := make([]map[string]any, 0, len(f.comments[n]))
+			for i, body := range f.comments[n] {
+				comments = append(comments, map[string]any{"id": i + 1, "body": body})
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(comments))
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		switch kind {
+		case "comments":
+			var req struct {
+				Body string `json:"body"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			f.comments[n] = append(f.comments[n], req.Body)
+		case "labels":
+			var req struct {
+				Labels []string `json:"labels"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			f.labels[n] = append(f.labels[n], req.Labels...)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// releasedCommits returns the commits of the pull requests of the numbers.
+func releasedCommits(numbers ...int) []commit.Commit {
+	commits := make([]commit.Commit, 0, len(numbers)+1)
+	for _, n := range numbers {
+		commits = append(commits, commit.Commit{SHA: fmt.Sprintf("sha%d", n), PRNumber: n})
+	}
+	// A commit without a pull request.
+	return append(commits, commit.Commit{SHA: "direct"})
+}
+
+func TestGitNotifyPulls(t *testing.T) {
+	t.Parallel()
+	t.Run("Notices", testGitNotifyPullsNotices)
+	t.Run("Skipped", testGitNotifyPullsSkipped)
+	t.Run("Limit", testGitNotifyPullsLimit)
+	t.Run("Rerun", testGitNotifyPullsRerun)
+	t.Run("Failure", testGitNotifyPullsFailure)
+}
+
+func testGitNotifyPullsNotices(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		notice       commit.PullNotice
+		wantComments map[int][]string
+		wantLabels   map[int][]string
+	}{
+		"default": {
+			wantComments: map[int][]string{
+				1: {"<!-- gitrelease:released v1.4.0 -->\nIncluded in [v1.4.0](https://example.com/v1.4.0)."},
+				2: {"<!-- gitrelease:released v1.4.0 -->\nIncluded in [v1.4.0](https://example.com/v1.4.0)."},
+			},
+			wantLabels: map[int][]string{},
+		},
+		"label": {
+			notice:       commit.NoticeLabel,
+			wantComments: map[int][]string{},
+			wantLabels:   map[int][]string{1: {"released/v1.4.0"}, 2: {"released/v1.4.0"}},
+		},
+		"both": {
+			notice: commit.NoticeComment | commit.NoticeLabel,
+			wantComments: map[int][]string{
+				1: {"<!-- gitrelease:released v1.4.0 -->\nIncluded in [v1.4.0](https://example.com/v1.4.0)."},
+				2: {"<!-- gitrelease:released v1.4.0 -->\nIncluded in [v1.4.0](https://example.com/v1.4.0)."},
+			},
+			wantLabels: map[int][]string{1: {"released/v1.4.0"}, 2: {"released/v1.4.0"}},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			gh := newFakeReleased(t)
+			gh.pulls[1] = &fakeReleasedPull{head: "user/repo"}
+			gh.pulls[2] = &fakeReleasedPull{head: "User/Repo", labels: []string{"bug"}}
+			g := &commit.Git{BaseURL: gh.URL}
+			summary, err := g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(2, 1, 2), commit.PullNoticeOptions{
+				Tag:      "v1.4.0",
+				URL:      "https://example.com/v1.4.0",
+				Notice:   tc.notice,
+				Interval: time.Millisecond,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, commit.PullNoticeSummary{Notified: []int{1, 2}}, summary)
+			assert.Equal(t, tc.wantComments, gh.comments)
+			assert.Equal(t, tc.wantLabels, gh.labels)
+		})
+	}
+}
+
+func testGitNotifyPullsSkipped(t *testing.T) {
+	t.Parallel()
+	gh := newFakeReleased(t)
+	gh.pulls[1] = &fakeReleasedPull{head: "user/repo"}
+	gh.pulls[2] = &fakeReleasedPull{head: "someone/repo"}
+	gh.pulls[3] = &fakeReleasedPull{}
+	gh.pulls[4] = &fakeReleasedPull{head: "user/repo", labels: []string{"Shipped-v1.4.0"}}
+	g := &commit.Git{BaseURL: gh.URL}
+	summary, err := g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(1, 2, 3, 4), commit.PullNoticeOptions{
+		Tag:      "v1.4.0",
+		Template: "Thanks for #{{.Pull}}, it's in {{.Tag}}",
+		Label:    "shipped-{{.Tag}}",
+		Interval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commit.PullNoticeSummary{Notified: []int{1}, Forks: 2, Labelled: 1}, summary)
+	assert.Equal(t, map[int][]string{1: {"<!-- gitrelease:released v1.4.0 -->\nThanks for #1, it's in v1.4.0"}}, gh.comments)
+}
+
+func testGitNotifyPullsLimit(t *testing.T) {
+	t.Parallel()
+	gh := newFakeReleased(t)
+	for n := 1; n <= 4; n++ {
+		gh.pulls[n] = &fakeReleasedPull{head: "user/repo"}
+	}
+	gh.pulls[2].labels = []string{"released/v1.4.0"}
+	g := &commit.Git{BaseURL: gh.URL}
+	summary, err := g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(1, 2, 3, 4), commit.PullNoticeOptions{
+		Tag:      "v1.4.0",
+		Notice:   commit.NoticeLabel,
+		Limit:    2,
+		Interval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commit.PullNoticeSummary{Notified: []int{1, 3}, Labelled: 1, OverLimit: 1}, summary,
+		"the skipped pull requests don't count towards the limit")
+	assert.Equal(t, []int{1, 2, 3}, gh.read, "the pull requests over the limit are not read")
+}
+
+func testGitNotifyPullsRerun(t *testing.T) {
+	t.Parallel()
+	const body = "<!-- gitrelease:released v1.4.0 -->\nIncluded in [v1.4.0](https://example.com/v1.4.0)."
+	gh := newFakeReleased(t)
+	gh.pulls[1] = &fakeReleasedPull{head: "user/repo"}
+	gh.pulls[2] = &fakeReleasedPull{head: "user/repo"}
+	gh.comments[1] = []string{"LGTM", body}
+	gh.comments[2] = []string{"<!-- gitrelease:released v1.3.0 -->\nIncluded in v1.3.0."}
+	g := &commit.Git{BaseURL: gh.URL}
+	opts := commit.PullNoticeOptions{
+		Tag:      "v1.4.0",
+		URL:      "https://example.com/v1.4.0",
+		Interval: time.Millisecond,
+	}
+	summary, err := g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(1, 2), opts)
+	require.NoError(t, err)
+	assert.Equal(t, commit.PullNoticeSummary{Notified: []int{2}, Commented: 1}, summary)
+	assert.Equal(t, []string{"LGTM", body}, gh.comments[1])
+	assert.Len(t, gh.comments[2], 2)
+
+	// The label of a previous run that only commented is still added.
+	opts.Notice = commit.NoticeComment | commit.NoticeLabel
+	summary, err = g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(1, 2), opts)
+	require.NoError(t, err)
+	assert.Equal(t, commit.PullNoticeSummary{Notified: []int{1, 2}}, summary)
+	assert.Len(t, gh.comments[1], 2)
+	assert.Len(t, gh.comments[2], 2)
+	assert.Equal(t, map[int][]string{1: {"released/v1.4.0"}, 2: {"released/v1.4.0"}}, gh.labels)
+}
+
+func testGitNotifyPullsFailure(t *testing.T) {
+	t.Parallel()
+	gh := newFakeReleased(t)
+	gh.pulls[2] = &fakeReleasedPull{head: "user/repo"}
+	g := &commit.Git{BaseURL: gh.URL}
+	summary, err := g.NotifyPulls(context.Background(), "token", "user", "repo", releasedCommits(1, 2), commit.PullNoticeOptions{
+		Tag:      "v1.4.0",
+		Interval: time.Millisecond,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "#1: getting the pull request")
+	assert.Equal(t, commit.PullNoticeSummary{Notified: []int{2}, Failed: 1}, summary)
+	assert.Len(t, gh.comments[2], 1)
+
+	_, err = g.NotifyPulls(context.Background(), "token", "user", "repo", nil, commit.PullNoticeOptions{Label: "{{.Nope"})
+	assert.Error(t, err)
+}
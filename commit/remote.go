@@ -0,0 +1,183 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// githubHost is the host of the public GitHub.
+	githubHost = "github.com"
+	// bitbucketHost is the host of Bitbucket Cloud.
+	bitbucketHost = "bitbucket.org"
+	// azureHost is the host of Azure DevOps.
+	azureHost = "dev.azure.com"
+)
+
+// RemoteInfo describes the repository a remote points to.
+type RemoteInfo struct {
+	// Host is the name of the host without the port, e.g. "github.com".
+	Host string
+	// Owner is the organisation on Azure DevOps, and the workspace on
+	// Bitbucket.
+	Owner string
+	// Project is the project of the repository on Azure DevOps.
+	Project string
+	Name    string
+	// Scheme is the protocol of the URL: "ssh", "https", "http" or "git".
+	Scheme string
+	// URL is the url of the remote as it is configured.
+	URL string
+	// URLs overrides the addresses of the pages of the host.
+	URLs URLPatterns
+}
+
+// HTMLURL returns the address of the repository's web page, e.g.
+// "https://github.com/arsham/gitrelease", or
+// "https://dev.azure.com/org/project/_git/repo" on Azure DevOps.
+func (r RemoteInfo) HTMLURL() string {
+	if r.IsAzure() {
+		return fmt.Sprintf("https://%s/%s/%s/_git/%s", azureHost,
+			url.PathEscape(r.Owner), url.PathEscape(r.Project), url.PathEscape(r.Name))
+	}
+	scheme := "https"
+	if r.Scheme == "http" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", scheme, r.Host, r.Owner, r.Name)
+}
+
+// IsAzure returns true if the repository is on Azure DevOps, including the
+// legacy visualstudio.com hosts.
+func (r RemoteInfo) IsAzure() bool {
+	host := strings.ToLower(r.Host)
+	return host == azureHost || host == "ssh."+azureHost || strings.HasSuffix(host, ".visualstudio.com")
+}
+
+// IsBitbucket returns true if the repository is on Bitbucket Cloud.
+func (r RemoteInfo) IsBitbucket() bool {
+	return strings.EqualFold(r.Host, bitbucketHost)
+}
+
+// APIBaseURL returns the address of the API of the host: api.github.com for
+// GitHub, and the /api/v3 endpoint for the GitHub Enterprise servers.
+func (r RemoteInfo) APIBaseURL() string {
+	if strings.EqualFold(r.Host, githubHost) {
+		return baseURL
+	}
+	scheme := "https"
+	if r.Scheme == "http" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/api/v3", scheme, r.Host)
+}
+
+// RemoteInfo returns the information of the repository of the Remote.
+func (g *Git) RemoteInfo(ctx context.Context) (RemoteInfo, error) {
+	remote := g.remote()
+	remotes, err := g.loadRemotes(ctx)
+	if err != nil {
+		return RemoteInfo{}, err
+	}
+	u, ok := remotes[remote]
+	if !ok {
+		return RemoteInfo{}, fmt.Errorf("remote %q is not configured", remote)
+	}
+	info, err := parseRemote(u)
+	if err != nil {
+		return info, err
+	}
+	info.URLs = g.HostURLs[strings.ToLower(info.Host)]
+	return info, nil
+}
+
+// parseRemote parses the url of a remote, in any of the forms git accepts:
+//
+//	https://github.com/owner/name.git
+//	ssh://git@github.com:22/owner/name.git
+//	git@github.com:owner/name.git
+//	git://github.com/owner/name.git
+//
+// A url without a scheme, e.g. "github.com/owner/name", is taken as https.
+// The Azure DevOps urls have the project too:
+//
+//	https://dev.azure.com/org/project/_git/name
+//	git@ssh.dev.azure.com:v3/org/project/name
+//	https://org.visualstudio.com/project/_git/name
+func parseRemote(raw string) (RemoteInfo, error) {
+	info := RemoteInfo{URL: raw}
+	s := strings.TrimSpace(raw)
+	var path string
+	colon, slash := strings.IndexByte(s, ':'), strings.IndexByte(s, '/')
+	switch {
+	case strings.Contains(s, "://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return info, fmt.Errorf("could not parse repository info: %s", raw)
+		}
+		info.Scheme = strings.ToLower(u.Scheme)
+		if info.Scheme == "git+ssh" || info.Scheme == "ssh+git" {
+			info.Scheme = "ssh"
+		}
+		info.Host, path = u.Hostname(), u.Path
+	case colon > 0 && (slash < 0 || colon < slash):
+		// The scp-like syntax of ssh, e.g. "git@github.com:owner/name".
+		host := s[:colon]
+		if i := strings.LastIndexByte(host, '@'); i >= 0 {
+			host = host[i+1:]
+		}
+		info.Scheme, info.Host, path = "ssh", host, s[colon+1:]
+	default:
+		info.Scheme = "https"
+		info.Host, path, _ = strings.Cut(s, "/")
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if info.IsAzure() {
+		return parseAzurePath(info, path)
+	}
+	owner, name, _ := strings.Cut(path, "/")
+	if info.Host == "" || owner == "" || name == "" {
+		return info, fmt.Errorf("could not parse repository info: %s", raw)
+	}
+	info.Owner, info.Name = owner, name
+	return info, nil
+}
+
+// parseAzurePath sets the organisation, the project and the name of the
+// repository from the path of an Azure DevOps url.
+func parseAzurePath(info RemoteInfo, path string) (RemoteInfo, error) {
+	parts := strings.Split(path, "/")
+	host := strings.ToLower(info.Host)
+	var org, project, name string
+	switch {
+	case len(parts) == 4 && parts[0] == "v3":
+		// The ssh urls: v3/org/project/name. The scp-like urls are not
+		// decoded, e.g. the spaces in the project names are still escaped.
+		for i, p := range parts {
+			if unescaped, err := url.PathUnescape(p); err == nil && !strings.Contains(unescaped, "/") {
+				parts[i] = unescaped
+			}
+		}
+		org, project, name = parts[1], parts[2], parts[3]
+	case host == azureHost && len(parts) == 4 && parts[2] == "_git":
+		// org/project/_git/name
+		org, project, name = parts[0], parts[1], parts[3]
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		// The organisation is in the host, and the collection is optional:
+		// [DefaultCollection/]project/_git/name
+		if len(parts) == 4 {
+			parts = parts[1:]
+		}
+		if len(parts) == 3 && parts[1] == "_git" {
+			org, project, name = strings.TrimSuffix(host, ".visualstudio.com"), parts[0], parts[2]
+		}
+	}
+	if org == "" || project == "" || name == "" {
+		return info, fmt.Errorf("could not parse repository info: %s", info.URL)
+	}
+	info.Owner, info.Project, info.Name = org, project, name
+	return info, nil
+}
@@ -0,0 +1,102 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrTagPushed is returned when a tag that is on the remote would be
+	// replaced without forcing the push.
+	ErrTagPushed = errors.New("tag is already pushed")
+	// ErrTagMove is returned when a tag would be created again on another
+	// commit without allowing it to move.
+	ErrTagMove = errors.New("tag would move to another commit")
+)
+
+// RetagOptions configures how a tag is created again.
+type RetagOptions struct {
+	// Message is the message of the new annotated tag, e.g. the fixed notes.
+	// It's kept verbatim.
+	Message string
+	// Target is the revision of the new tag. It defaults to the commit of
+	// the tag.
+	Target string
+	// Move allows the Target to be another commit than the one of the tag.
+	Move bool
+	// ForcePush replaces the tag on the Remote if it's pushed. The push is
+	// rejected if the tag on the Remote has changed since it's planned.
+	ForcePush bool
+}
+
+// PlanRetag returns the steps to create the tag again as an annotated tag
+// with the Message of the opts, in the order they should run: the local tag
+// is replaced, and then the one on the Remote if the tag is pushed. Nothing
+// is changed until the steps run. It returns ErrTagPushed if the tag is on
+// the Remote, unless ForcePush is set, and ErrTagMove if the Target is another
+// commit, unless Move is set.
+func (g *Git) PlanRetag(ctx context.Context, tag string, opts RetagOptions) ([]*Step, error) {
+	if strings.TrimSpace(opts.Message) == "" {
+		return nil, fmt.Errorf("the new message of tag %s is empty", tag)
+	}
+	old, err := g.resolveTag(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("tag %s is not in the repository: %w", tag, err)
+	}
+	target := old
+	if opts.Target != "" {
+		if target, err = g.resolve(ctx, opts.Target); err != nil {
+			return nil, err
+		}
+	}
+	if target != old && !opts.Move {
+		return nil, fmt.Errorf("%w: %s is on %s, not on %s", ErrTagMove, tag, shortSHA(old), shortSHA(target))
+	}
+	object, remoteCommit, err := g.remoteTag(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	if object != "" && !opts.ForcePush {
+		return nil, fmt.Errorf("%w: %s is on %s at %s, force the push to replace it", ErrTagPushed, tag, g.remote(), shortSHA(remoteCommit))
+	}
+
+	// The tag is replaced in one update, therefore it's never lost if the
+	// new one can't be created.
+	steps := []*Step{{
+		Description: fmt.Sprintf("replace local tag %s on %s with an annotated tag on %s with the new message", tag, shortSHA(old), shortSHA(target)),
+		run: func(ctx context.Context) error {
+			c := newCommand("tag", "--force", "--annotate", "--cleanup=verbatim", "--message="+opts.Message)
+			_, err := g.run(ctx, c.rev(tag, target))
+			g.Refresh()
+			if err != nil {
+				return fmt.Errorf("creating tag %s: %w", tag, err)
+			}
+			return nil
+		},
+	}}
+	if object == "" {
+		return steps, nil
+	}
+	steps = append(steps, &Step{
+		Description: fmt.Sprintf("force-push tag %s to %s, replacing the one on %s", tag, g.remote(), shortSHA(remoteCommit)),
+		run: func(ctx context.Context) error {
+			ref := "refs/tags/" + tag
+			lease := fmt.Sprintf("--force-with-lease=%s:%s", ref, object)
+			c := newCommand("push", lease).rev(g.remote()).revFrom(ref, tag)
+			if _, err := g.run(ctx, c); err != nil {
+				return fmt.Errorf("pushing tag %s to %s: %w", tag, g.remote(), err)
+			}
+			return nil
+		},
+	})
+	return steps, nil
+}
+
+// Retag runs the steps in order. It stops at the first failure, and the
+// returned error lists which steps are done, which one failed and which ones
+// are skipped.
+func (g *Git) Retag(ctx context.Context, steps []*Step) error {
+	return g.runSteps(ctx, "retag", steps)
+}
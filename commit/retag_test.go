@@ -0,0 +1,155 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const retagMessage = "v1.0.0\n\n### Feature\n\n- The thing, without the typo\n"
+
+func retagDescriptions(steps []*commit.Step) []string {
+	got := make([]string, 0, len(steps))
+	for _, s := range steps {
+		got = append(got, s.Description)
+	}
+	return got
+}
+
+// tagMessage returns the message of the annotated tag in the dir.
+func tagMessage(t *testing.T, dir, tag string) string {
+	t.Helper()
+	out := runGit(t, dir, "for-each-ref", "--format=%(contents)", "refs/tags/"+tag)
+	// for-each-ref ends each ref with a new line.
+	return strings.TrimSuffix(out, "\n")
+}
+
+func TestGitRetag(t *testing.T) {
+	t.Parallel()
+	t.Run("Unpushed", testGitRetagUnpushed)
+	t.Run("Pushed", testGitRetagPushed)
+	t.Run("Lease", testGitRetagLease)
+	t.Run("Move", testGitRetagMove)
+	t.Run("Invalid", testGitRetagInvalid)
+}
+
+func testGitRetagUnpushed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, remote := createReleasedRepo(t)
+	commitChanges(t, dir, "fix: the other thing")
+	createGitTag(t, dir, "v1.1.0")
+	head := strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+	g := &commit.Git{Dir: dir}
+
+	steps, err := g.PlanRetag(ctx, "v1.1.0", commit.RetagOptions{Message: retagMessage})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"replace local tag v1.1.0 on " + head[:7] + " with an annotated tag on " + head[:7] + " with the new message",
+	}, retagDescriptions(steps))
+	// Planning doesn't change the tag.
+	assert.Equal(t, "commit", strings.TrimSpace(runGit(t, dir, "cat-file", "-t", "v1.1.0")))
+
+	require.NoError(t, g.Retag(ctx, steps))
+	assert.True(t, steps[0].Done)
+	assert.Equal(t, "tag", strings.TrimSpace(runGit(t, dir, "cat-file", "-t", "v1.1.0")))
+	assert.Equal(t, retagMessage, tagMessage(t, dir, "v1.1.0"))
+	assert.Equal(t, head, strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.1.0^{commit}")))
+	assert.Empty(t, runGit(t, remote, "tag", "--list", "v1.1.0"))
+}
+
+func testGitRetagPushed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, remote := createReleasedRepo(t)
+	sha := strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.0.0^{commit}"))
+	g := &commit.Git{Dir: dir}
+
+	_, err := g.PlanRetag(ctx, "v1.0.0", commit.RetagOptions{Message: retagMessage})
+	require.ErrorIs(t, err, commit.ErrTagPushed)
+	assert.Equal(t, "commit", strings.TrimSpace(runGit(t, dir, "cat-file", "-t", "v1.0.0")))
+
+	steps, err := g.PlanRetag(ctx, "v1.0.0", commit.RetagOptions{Message: retagMessage, ForcePush: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"replace local tag v1.0.0 on " + sha[:7] + " with an annotated tag on " + sha[:7] + " with the new message",
+		"force-push tag v1.0.0 to origin, replacing the one on " + sha[:7],
+	}, retagDescriptions(steps))
+
+	require.NoError(t, g.Retag(ctx, steps))
+	assert.Equal(t, retagMessage, tagMessage(t, remote, "v1.0.0"))
+	assert.Equal(t, sha, strings.TrimSpace(runGit(t, remote, "rev-parse", "v1.0.0^{commit}")))
+	assert.Equal(t,
+		strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.0.0")),
+		strings.TrimSpace(runGit(t, remote, "rev-parse", "v1.0.0")),
+	)
+}
+
+func testGitRetagLease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, remote := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir}
+	steps, err := g.PlanRetag(ctx, "v1.0.0", commit.RetagOptions{Message: retagMessage, ForcePush: true})
+	require.NoError(t, err)
+
+	// Someone else replaces the tag after it's planned.
+	runGit(t, remote, "tag", "--force", "--annotate", "--message", "theirs", "v1.0.0", "v1.0.0^{commit}")
+	err = g.Retag(ctx, steps)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "done: replace local tag v1.0.0")
+	assert.Contains(t, err.Error(), "failed: force-push tag v1.0.0")
+	assert.Equal(t, "theirs\n", tagMessage(t, remote, "v1.0.0"))
+}
+
+func testGitRetagMove(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, _ := createReleasedRepo(t)
+	commitChanges(t, dir, "fix: the other thing")
+	createGitTag(t, dir, "v1.1.0")
+	runGit(t, dir, "tag", "v1.0.1", "v1.0.0")
+	g := &commit.Git{Dir: dir}
+
+	_, err := g.PlanRetag(ctx, "v1.0.1", commit.RetagOptions{Message: retagMessage, Target: "HEAD"})
+	require.ErrorIs(t, err, commit.ErrTagMove)
+
+	steps, err := g.PlanRetag(ctx, "v1.0.1", commit.RetagOptions{Message: retagMessage, Target: "v1.0.0"})
+	require.NoError(t, err)
+	assert.Len(t, steps, 1)
+
+	steps, err = g.PlanRetag(ctx, "v1.0.1", commit.RetagOptions{Message: retagMessage, Target: "HEAD", Move: true})
+	require.NoError(t, err)
+	require.NoError(t, g.Retag(ctx, steps))
+	assert.Equal(t,
+		strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD")),
+		strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.0.1^{commit}")),
+	)
+}
+
+func testGitRetagInvalid(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, _ := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir}
+	tcs := map[string]struct {
+		tag  string
+		opts commit.RetagOptions
+	}{
+		"no message":  {tag: "v1.0.0", opts: commit.RetagOptions{Message: " \n"}},
+		"unknown tag": {tag: "v9.9.9", opts: commit.RetagOptions{Message: retagMessage}},
+		"target":      {tag: "v1.0.0", opts: commit.RetagOptions{Message: retagMessage, Target: "--all"}},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := g.PlanRetag(ctx, tc.tag, tc.opts)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidRevision is returned when a tag, a revision or a remote could be
+// taken by git as an option, or contains control characters.
+var ErrInvalidRevision = errors.New("invalid revision")
+
+// checkRevs returns ErrInvalidRevision if any of the revs could change the
+// behaviour of git. The tags and the revisions are passed to git as separate
+// arguments, therefore a value like "--upload-pack=cmd" would be read as an
+// option if it came from an untrusted source, e.g. the payload of a webhook.
+// Not every git command accepts the "--" separator before the revisions, so
+// they are rejected instead. The empty revs are accepted, the callers decide
+// what they mean. The operands of every git command are checked by exec, see
+// command.
+func checkRevs(revs ...string) error {
+	for _, rev := range revs {
+		if strings.HasPrefix(rev, "-") {
+			return fmt.Errorf("%q starts with a dash: %w", rev, ErrInvalidRevision)
+		}
+		if strings.IndexFunc(rev, unicode.IsControl) >= 0 {
+			return fmt.Errorf("%q contains control characters: %w", rev, ErrInvalidRevision)
+		}
+	}
+	return nil
+}
+
+// command is the args of a git process. The args are the subcommand and its
+// options, which are written by the package. The revs are the operands, e.g.
+// the tags, the revisions, the ranges and the remotes, which are added with
+// rev or revFrom and are checked with checkRevs, therefore the values of an
+// untrusted source can't be taken as options. The paths follow a "--".
+type command struct {
+	args []string
+	revs []string
+	// stdin has the revs that are written into the standard input of git,
+	// with the --stdin option.
+	stdin []string
+	paths []string
+	// dashdash is true if the "--" is added, even if there are no paths,
+	// to tell git the revs are not paths.
+	dashdash bool
+	err      error
+}
+
+// newCommand returns the command of the subcommand and its options.
+func newCommand(args ...string) command {
+	return command{args: args}
+}
+
+// opt adds the options.
+func (c command) opt(args ...string) command {
+	c.args = append(c.args[:len(c.args):len(c.args)], args...)
+	return c
+}
+
+// rev adds the revs as the operands of git.
+func (c command) rev(revs ...string) command {
+	for _, rev := range revs {
+		c = c.revFrom(rev)
+	}
+	return c
+}
+
+// revFrom adds the arg, which is made of the revs, e.g. "refs/tags/"+tag.
+// Both the arg and the revs are checked, since the prefix of the arg would
+// hide a dash in the revs.
+func (c command) revFrom(arg string, revs ...string) command {
+	if err := checkRevs(append([]string{arg}, revs...)...); err != nil && c.err == nil {
+		c.err = err
+	}
+	c.revs = append(c.revs[:len(c.revs):len(c.revs)], arg)
+	return c
+}
+
+// path adds the "--" and the paths after it.
+func (c command) path(paths ...string) command {
+	c.dashdash = true
+	c.paths = append(c.paths[:len(c.paths):len(c.paths)], paths...)
+	return c
+}
+
+// check returns the error of the revs. It returns an error if any of the args
+// after the subcommand is not an option, since the operands should be added
+// as the revs to be checked.
+func (c command) check() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.args) == 0 {
+		return errors.New("empty git command")
+	}
+	for _, arg := range c.args[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("git %s: %q is not an option", c.args[0], arg)
+		}
+	}
+	return checkRevs(append(c.revs[:len(c.revs):len(c.revs)], c.stdin...)...)
+}
+
+// argv returns the args of git.
+func (c command) argv() []string {
+	args := make([]string, 0, len(c.args)+len(c.revs)+len(c.paths)+2)
+	args = append(append(args, c.args...), c.revs...)
+	if c.stdin != nil {
+		args = append(args, "--stdin")
+	}
+	if c.dashdash || len(c.paths) > 0 {
+		args = append(append(args, "--"), c.paths...)
+	}
+	return args
+}
+
+// MissingRevisionError is returned when an end of a range is not a commit of
+// the repository, e.g. the tag of a commit that is purged by a history
+// rewrite.
+type MissingRevisionError struct {
+	Rev string
+	// Start is true if the Rev is the start of the range, otherwise it's
+	// the end.
+	Start bool
+	// ReplaceRefs is true if the replacements of git replace are looked up.
+	ReplaceRefs bool
+	Err         error
+}
+
+func (e *MissingRevisionError) Error() string {
+	end := "end"
+	if e.Start {
+		end = "start"
+	}
+	msg := fmt.Sprintf("the %s of the range, %s, is not a commit of the repository", end, e.Rev)
+	if e.ReplaceRefs {
+		return msg + ", and it has no replacement in refs/replace. If the history is rewritten, map the old commit to the new one with git replace, or use the tags of the new history"
+	}
+	return msg + ". If the history is rewritten, e.g. with git filter-repo, the old commits are gone: map them to the new ones with git replace and look up the replace refs, or use the tags of the new history"
+}
+
+func (e *MissingRevisionError) Unwrap() error { return e.Err }
+
+// checkRange returns a *MissingRevisionError if an end of the range of tag1
+// and tag2 is not a commit. The ends are checked with one git process before
+// the range is read, since the errors of git are cryptic then. Only if that
+// fails, they are resolved one by one to find the missing one. The empty tag1
+// is the start of the history.
+func (g *Git) checkRange(ctx context.Context, tag1, tag2 string) error {
+	c := newCommand("rev-parse")
+	for _, rev := range []string{tag1, tag2} {
+		if rev != "" {
+			c = c.revFrom(rev+"^{commit}", rev)
+		}
+	}
+	_, err := g.run(ctx, c.path())
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	for i, rev := range []string{tag1, tag2} {
+		if rev == "" {
+			continue
+		}
+		_, resolveErr := g.resolve(ctx, rev)
+		if resolveErr != nil && missingRevision(resolveErr) {
+			return &MissingRevisionError{Rev: rev, Start: i == 0, ReplaceRefs: g.ReplaceRefs, Err: err}
+		}
+	}
+	return err
+}
+
+// missingRevision returns true if the err of resolving a revision is because
+// it's unknown, or its object is missing.
+func missingRevision(err error) bool {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		return false
+	}
+	// rev-parse --quiet only exits with 1 if the revision is unknown.
+	if gitErr.ExitCode == 1 {
+		return true
+	}
+	return strings.Contains(gitErr.Stderr, "bad object") || strings.Contains(gitErr.Stderr, "unable to read")
+}
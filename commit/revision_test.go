@@ -0,0 +1,266 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/blokur/testament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitInvalidRevision(t *testing.T) {
+	t.Parallel()
+	t.Run("NotRun", testGitInvalidRevisionNotRun)
+	t.Run("Repo", testGitInvalidRevisionRepo)
+}
+
+// testGitInvalidRevisionNotRun checks that git is never started with a hostile
+// tag or remote.
+func testGitInvalidRevisionNotRun(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	hostile := []string{
+		"--upload-pack=touch pwned",
+		"-O/tmp/pwned",
+		"-d",
+		"v1.0.0\nv2.0.0",
+		"v1.0.0\x00",
+	}
+	calls := map[string]func(g *commit.Git, rev string) error{
+		"PreviousTag": func(g *commit.Git, rev string) error {
+			_, err := g.PreviousTag(ctx, rev)
+			return err
+		},
+		"Commits": func(g *commit.Git, rev string) error {
+			_, err := g.Commits(ctx, "v0.0.1", rev)
+			return err
+		},
+		"CommitsFrom": func(g *commit.Git, rev string) error {
+			_, err := g.Commits(ctx, rev, "HEAD")
+			return err
+		},
+		"Log": func(g *commit.Git, rev string) error {
+			_, err := g.Log(ctx, rev, "HEAD")
+			return err
+		},
+		"IsAncestor": func(g *commit.Git, rev string) error {
+			_, err := g.IsAncestor(ctx, rev, "main")
+			return err
+		},
+		"CheckReachable": func(g *commit.Git, rev string) error {
+			return g.CheckReachable(ctx, "v0.0.1", rev)
+		},
+		"CreateTag": func(g *commit.Git, rev string) error {
+			return g.CreateTag(ctx, rev, "HEAD")
+		},
+		"CreateTagRev": func(g *commit.Git, rev string) error {
+			return g.CreateTag(ctx, "v0.0.1", rev)
+		},
+		"CreateAnnotatedTag": func(g *commit.Git, rev string) error {
+			return g.CreateAnnotatedTag(ctx, rev, "HEAD", "the notes")
+		},
+		"DeleteTag": func(g *commit.Git, rev string) error {
+			return g.DeleteTag(ctx, rev)
+		},
+		"PushTag": func(g *commit.Git, rev string) error {
+			return g.PushTag(ctx, rev)
+		},
+		"PushTagRemote": func(g *commit.Git, rev string) error {
+			g.Remote = rev
+			return g.PushTag(ctx, "v0.0.1")
+		},
+		"DeleteRemoteTag": func(g *commit.Git, rev string) error {
+			return g.DeleteRemoteTag(ctx, rev)
+		},
+		"CheckRemoteTag": func(g *commit.Git, rev string) error {
+			return g.CheckRemoteTag(ctx, rev)
+		},
+		"FetchTag": func(g *commit.Git, rev string) error {
+			return g.FetchTag(ctx, rev)
+		},
+		"FetchTagRemote": func(g *commit.Git, rev string) error {
+			g.Remote = rev
+			return g.FetchTag(ctx, "v0.0.1")
+		},
+		"GenerateForTag": func(g *commit.Git, rev string) error {
+			_, err := g.GenerateForTag(ctx, rev)
+			return err
+		},
+		"ReleasesBetween": func(g *commit.Git, rev string) error {
+			_, err := g.ReleasesBetween(ctx, rev, "HEAD")
+			return err
+		},
+		"DependencyChanges": func(g *commit.Git, rev string) error {
+			_, err := g.DependencyChanges(ctx, "v0.0.1", rev)
+			return err
+		},
+		"DiffStats": func(g *commit.Git, rev string) error {
+			return g.DiffStats(ctx, []commit.Commit{{SHA: "abc123"}, {SHA: rev}})
+		},
+		"ChangedPaths": func(g *commit.Git, rev string) error {
+			return g.ChangedPaths(ctx, []commit.Commit{{SHA: rev}})
+		},
+		"CreateReleaseBranch": func(g *commit.Git, rev string) error {
+			_, err := g.CreateReleaseBranch(ctx, rev, "v0.0.1")
+			return err
+		},
+		"PlanRetag": func(g *commit.Git, rev string) error {
+			_, err := g.PlanRetag(ctx, rev, commit.RetagOptions{Message: "the notes"})
+			return err
+		},
+		"BumpVersion": func(g *commit.Git, rev string) error {
+			_, _, err := g.BumpVersion(ctx, nil, rev)
+			return err
+		},
+	}
+	for name, call := range calls {
+		name, call := name, call
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for _, rev := range hostile {
+				check := func(args []string, stdin string) (string, error) {
+					for _, arg := range append(args, stdin) {
+						if strings.Contains(arg, rev) {
+							t.Errorf("git is run with %q: %q", rev, args)
+						}
+					}
+					// ReleasesBetween lists the tags first.
+					return "", nil
+				}
+				// All git processes are checked the same way, whether the
+				// revisions are passed as the args or into the stdin.
+				runners := []commit.Runner{
+					fakeRunner(func(args []string) (string, error) { return check(args, "") }),
+					fakeStdinRunner(check),
+				}
+				for _, runner := range runners {
+					g := &commit.Git{Runner: runner}
+					err := call(g, rev)
+					assert.ErrorIs(t, err, commit.ErrInvalidRevision, rev)
+				}
+			}
+		})
+	}
+}
+
+// testGitInvalidRevisionRepo checks that the hostile tags don't change the
+// repository or write any files.
+func testGitInvalidRevisionRepo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "msg1")
+	createGitTag(t, dir, "v0.0.1")
+	createFile(t, dir, "file.txt", testament.RandomString(20))
+	commitChanges(t, dir, "msg2")
+	g := commit.New(commit.WithDir(dir))
+
+	out := filepath.Join(t.TempDir(), "out")
+	_, err := g.Commits(ctx, "v0.0.1", "--output="+out)
+	require.ErrorIs(t, err, commit.ErrInvalidRevision)
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err), "git wrote to %s", out)
+
+	// "git tag -d v0.0.1" would delete the tag.
+	err = g.CreateTag(ctx, "-d", "v0.0.1")
+	require.ErrorIs(t, err, commit.ErrInvalidRevision)
+	assert.Equal(t, "v0.0.1\n", runGit(t, dir, "tag", "--list"))
+
+	_, err = g.PreviousTag(ctx, "--all")
+	require.ErrorIs(t, err, commit.ErrInvalidRevision)
+
+	// The valid revisions still work.
+	logs, err := g.Commits(ctx, "v0.0.1", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "msg2", strings.TrimSpace(logs[0]))
+}
+
+// purgedSHA is a commit that is not in the repositories of the tests, e.g.
+// the one of a tag from before a history rewrite.
+const purgedSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func TestGitMissingRevision(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	r.Commit("feat: after the rewrite")
+	r.Tag("v1.1.0")
+
+	tcs := map[string]struct {
+		tag1, tag2 string
+		start      bool
+	}{
+		"start": {tag1: purgedSHA, tag2: "v1.1.0", start: true},
+		"end":   {tag1: "v1.0.0", tag2: "v9.9.9"},
+		"both":  {tag1: "v0.0.1", tag2: "v9.9.9", start: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			trace := &commit.Trace{Environ: func() []string { return nil }}
+			g := &commit.Git{Dir: r.Dir, Trace: trace}
+			_, err := g.Commits(ctx, tc.tag1, tc.tag2)
+			var missing *commit.MissingRevisionError
+			require.ErrorAs(t, err, &missing)
+			// The ends are checked before the range is read.
+			for _, e := range trace.Entries() {
+				assert.NotEqual(t, "log", e.Args[0], e.Args)
+			}
+			assert.Equal(t, tc.start, missing.Start)
+			if tc.start {
+				assert.Equal(t, tc.tag1, missing.Rev)
+			} else {
+				assert.Equal(t, tc.tag2, missing.Rev)
+			}
+			assert.Contains(t, err.Error(), "git replace")
+			var gitErr *commit.GitError
+			assert.ErrorAs(t, err, &gitErr)
+
+			_, err = g.CountCommits(ctx, tc.tag1, tc.tag2)
+			assert.ErrorAs(t, err, &missing)
+		})
+	}
+}
+
+func TestGitReplaceRefs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial")
+	rewritten := r.Head()
+	r.Commit("feat: after the rewrite")
+	r.Tag("v1.1.0")
+	// The repository maps the old commit to the new one, but doesn't read
+	// the replacements by default.
+	r.Run("update-ref", "refs/replace/"+purgedSHA, rewritten)
+	r.Run("config", "core.useReplaceRefs", "false")
+
+	g := &commit.Git{Dir: r.Dir}
+	_, err := g.Commits(ctx, purgedSHA, "v1.1.0")
+	var missing *commit.MissingRevisionError
+	require.ErrorAs(t, err, &missing)
+	assert.False(t, missing.ReplaceRefs)
+
+	g = &commit.Git{Dir: r.Dir, ReplaceRefs: true}
+	got, err := g.Commits(ctx, purgedSHA, "v1.1.0")
+	require.NoError(t, err)
+	// The old commit is read as the new one, but keeps its id, therefore
+	// the new one is not left out of the range.
+	assert.Equal(t, []string{"feat: after the rewrite", "chore: initial"}, got)
+
+	_, err = g.Commits(ctx, "0000000000000000000000000000000000000001", "v1.1.0")
+	require.ErrorAs(t, err, &missing)
+	assert.True(t, missing.ReplaceRefs)
+	assert.Contains(t, err.Error(), "no replacement")
+}
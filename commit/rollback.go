@@ -0,0 +1,90 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RollbackOptions configures which parts of a release are removed.
+type RollbackOptions struct {
+	// KeepTag only removes the GitHub release.
+	KeepTag bool
+	// DeleteLocalTag also deletes the tag from the local repository. It is
+	// ignored when KeepTag is set.
+	DeleteLocalTag bool
+}
+
+// PlanRollback returns the steps to remove the release of the tag, in the
+// order they should run: the GitHub release, the remote tag, and the local
+// tag. The parts that don't exist are left out.
+func (g *Git) PlanRollback(ctx context.Context, token, user, repo, tag string, opts RollbackOptions) ([]*Step, error) {
+	var steps []*Step
+	r, err := g.GetReleaseByTag(ctx, token, user, repo, tag)
+	switch {
+	case hasStatus(err, http.StatusNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		steps = append(steps, &Step{
+			Description: fmt.Sprintf("GitHub release of %s on %s/%s (id %d, %d assets)", tag, user, repo, r.ID, len(r.Assets)),
+			run: func(ctx context.Context) error {
+				uri := fmt.Sprintf("/repos/%s/%s/releases/%d", user, repo, r.ID)
+				return g.api(ctx, token, http.MethodDelete, uri, nil, nil)
+			},
+		})
+	}
+	if opts.KeepTag {
+		return steps, nil
+	}
+
+	ok, err := g.hasRemoteTag(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		steps = append(steps, &Step{
+			Description: fmt.Sprintf("tag %s on %s", tag, g.remote()),
+			run: func(ctx context.Context) error {
+				return g.DeleteRemoteTag(ctx, tag)
+			},
+		})
+	}
+	if !opts.DeleteLocalTag {
+		return steps, nil
+	}
+	if _, err := g.resolveTag(ctx, tag); err == nil {
+		steps = append(steps, &Step{
+			Description: fmt.Sprintf("local tag %s", tag),
+			run: func(ctx context.Context) error {
+				return g.DeleteTag(ctx, tag)
+			},
+		})
+	}
+	return steps, nil
+}
+
+// Rollback runs the steps in order. It stops at the first failure, since the
+// later steps would leave the release without its tag. The returned error
+// lists which steps are done, which one failed and which ones are skipped.
+func (g *Git) Rollback(ctx context.Context, steps []*Step) error {
+	return g.runSteps(ctx, "rollback", steps)
+}
+
+// DeleteRemoteTag deletes the tag from the Remote.
+func (g *Git) DeleteRemoteTag(ctx context.Context, tag string) error {
+	c := newCommand("push").rev(g.remote()).revFrom(":refs/tags/"+tag, tag)
+	if _, err := g.run(ctx, c); err != nil {
+		return fmt.Errorf("deleting tag %s from %s: %w", tag, g.remote(), err)
+	}
+	return nil
+}
+
+func (g *Git) hasRemoteTag(ctx context.Context, tag string) (bool, error) {
+	out, err := g.run(ctx, newCommand("ls-remote", "--tags").rev(g.remote()).revFrom("refs/tags/"+tag, tag))
+	if err != nil {
+		return false, fmt.Errorf("listing tags of %s: %w", g.remote(), err)
+	}
+	return strings.TrimSpace(out) != "", nil
+}
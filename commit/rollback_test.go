@@ -0,0 +1,127 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitRollback(t *testing.T) {
+	t.Parallel()
+	t.Run("All", testGitRollbackAll)
+	t.Run("KeepTag", testGitRollbackKeepTag)
+	t.Run("NoRelease", testGitRollbackNoRelease)
+	t.Run("Partial", testGitRollbackPartial)
+}
+
+// createReleasedRepo creates a repository with the v1.0.0 tag pushed to a bare
+// origin, and returns the directories of both.
+func createReleasedRepo(t *testing.T) (dir, remote string) {
+	t.Helper()
+	dir = createGitRepo(t)
+	createFile(t, dir, "file.txt", "content")
+	commitChanges(t, dir, "feat: thing")
+	createGitTag(t, dir, "v1.0.0")
+	remote = t.TempDir()
+	runGit(t, remote, "init", "--bare", "-q")
+	addRemote(t, dir, "origin", remote)
+	runGit(t, dir, "push", "-q", "origin", "refs/tags/v1.0.0")
+	return dir, remote
+}
+
+func descriptions(steps []*commit.Step) []string {
+	got := make([]string, 0, len(steps))
+	for _, s := range steps {
+		got = append(got, s.Description)
+	}
+	return got
+}
+
+func testGitRollbackAll(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir, remote := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir, BaseURL: gh.URL}
+
+	opts := commit.RollbackOptions{DeleteLocalTag: true}
+	steps, err := g.PlanRollback(ctx, "token", "user", "repo", "v1.0.0", opts)
+	require.NoError(t, err)
+	want := []string{
+		"GitHub release of v1.0.0 on user/repo (id 1, 0 assets)",
+		"tag v1.0.0 on origin",
+		"local tag v1.0.0",
+	}
+	assert.Equal(t, want, descriptions(steps))
+
+	require.NoError(t, g.Rollback(ctx, steps))
+	for _, s := range steps {
+		assert.True(t, s.Done, s.Description)
+	}
+	assert.True(t, gh.releaseDeleted)
+	assert.Empty(t, strings.TrimSpace(runGit(t, remote, "tag", "--list")))
+	assert.Empty(t, strings.TrimSpace(runGit(t, dir, "tag", "--list")))
+}
+
+func testGitRollbackKeepTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir, remote := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir, BaseURL: gh.URL}
+
+	opts := commit.RollbackOptions{KeepTag: true, DeleteLocalTag: true}
+	steps, err := g.PlanRollback(ctx, "token", "user", "repo", "v1.0.0", opts)
+	require.NoError(t, err)
+	assert.Len(t, steps, 1)
+
+	require.NoError(t, g.Rollback(ctx, steps))
+	assert.True(t, gh.releaseDeleted)
+	assert.Contains(t, runGit(t, remote, "tag", "--list"), "v1.0.0")
+	assert.Contains(t, runGit(t, dir, "tag", "--list"), "v1.0.0")
+}
+
+func testGitRollbackNoRelease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v2.0.0")
+	dir, _ := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir, BaseURL: gh.URL}
+
+	steps, err := g.PlanRollback(ctx, "token", "user", "repo", "v1.0.0", commit.RollbackOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag v1.0.0 on origin"}, descriptions(steps))
+}
+
+func testGitRollbackPartial(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	gh := newFakeGitHub(t, "v1.0.0")
+	dir, remote := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir, BaseURL: gh.URL}
+
+	opts := commit.RollbackOptions{DeleteLocalTag: true}
+	steps, err := g.PlanRollback(ctx, "token", "user", "repo", "v1.0.0", opts)
+	require.NoError(t, err)
+	require.Len(t, steps, 3)
+
+	// The remote disappears between planning and running.
+	require.NoError(t, os.RemoveAll(remote))
+	err = g.Rollback(ctx, steps)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "done: GitHub release of v1.0.0")
+	assert.Contains(t, err.Error(), "failed: tag v1.0.0 on origin")
+	assert.Contains(t, err.Error(), "skipped: local tag v1.0.0")
+	assert.True(t, steps[0].Done)
+	var gitErr *commit.GitError
+	require.ErrorAs(t, steps[1].Err, &gitErr)
+	assert.Equal(t, "push", gitErr.Args[0])
+	assert.NotEmpty(t, gitErr.Stderr)
+	assert.False(t, steps[2].Done)
+	assert.Contains(t, runGit(t, dir, "tag", "--list"), "v1.0.0")
+}
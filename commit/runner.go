@@ -0,0 +1,184 @@
+package commit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// Runner runs git with the args in the dir, and writes the standard output of
+// the process into stdout. If git fails, the returned error should be a
+// *GitError with what the process has written to its standard error. Other
+// errors are wrapped in a GitError by the Git.
+type Runner interface {
+	Run(ctx context.Context, dir string, stdout io.Writer, args ...string) error
+}
+
+// StdinRunner is a Runner that can also write the stdin into the standard
+// input of git. The long lists of the commits are passed to git this way,
+// since the arguments of a process are limited by the system. If the Runner
+// of the Git doesn't implement it, the commits are passed as the arguments of
+// several processes instead.
+type StdinRunner interface {
+	Runner
+	RunStdin(ctx context.Context, dir string, stdin io.Reader, stdout io.Writer, args ...string) error
+}
+
+// GitError is returned when a git command fails. All methods of Git return it
+// wrapped, therefore the details can be extracted with errors.As.
+type GitError struct {
+	Args []string
+	// ExitCode is the exit code of git, or -1 if it hasn't exited, e.g. it
+	// couldn't be started.
+	ExitCode int
+	// Stdout is empty if the output is consumed while git is running.
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// Error returns the message of the error with the standard error of git. The
+// terminal escape sequences and the control characters of the output are
+// removed, and only its last maxErrorOutput bytes are kept, therefore the
+// message is safe to print in the logs and the JSON results.
+func (e *GitError) Error() string {
+	msg := fmt.Sprintf("git %s: %v", stripControl(strings.Join(e.Args, " ")), e.Err)
+	if stderr := errorOutput(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// maxErrorOutput is the number of the bytes of the output of git that are kept
+// in the message of a GitError.
+const maxErrorOutput = 4 << 10
+
+// errorOutput returns the output of git as it's included in the errors.
+func errorOutput(out string) string {
+	out = strings.TrimSpace(stripControl(strings.ToValidUTF8(out, "\uFFFD")))
+	if len(out) <= maxErrorOutput {
+		return out
+	}
+	cut := len(out) - maxErrorOutput
+	for cut < len(out) && !utf8.RuneStart(out[cut]) {
+		cut++
+	}
+	return fmt.Sprintf("[%d bytes truncated] …%s", cut, out[cut:])
+}
+
+func (e *GitError) Unwrap() error { return e.Err }
+
+// gitError returns the err as a GitError of the args, with the stdout if the
+// runner hasn't set it.
+func gitError(err error, args []string, stdout string) error {
+	if err == nil {
+		return nil
+	}
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		gitErr = &GitError{Args: args, ExitCode: exitCode(err), Err: err}
+		err = gitErr
+	}
+	if gitErr.Stdout == "" {
+		gitErr.Stdout = stdout
+	}
+	return err
+}
+
+// execRunner runs the git binary. The colours are turned off regardless of the
+// config of the user, since the output is parsed and included in the errors.
+type execRunner struct{}
+
+func (r execRunner) Run(ctx context.Context, dir string, stdout io.Writer, args ...string) error {
+	return r.RunStdin(ctx, dir, nil, stdout, args...)
+}
+
+func (execRunner) RunStdin(ctx context.Context, dir string, stdin io.Reader, stdout io.Writer, args ...string) error {
+	stderr := &bytes.Buffer{}
+	// nolint:gosec // the arguments are controlled by the caller.
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "color.ui=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return &GitError{
+			Args:     args,
+			ExitCode: exitCode(err),
+			Stderr:   stderr.String(),
+			Err:      err,
+		}
+	}
+	return nil
+}
+
+// exitCode returns the exit code of the process that caused the err, or -1 if
+// it's not caused by a process exiting.
+func exitCode(err error) int {
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return gitErr.ExitCode
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// hasExitCode returns true if the err is caused by the process exiting with the
+// code.
+func hasExitCode(err error, code int) bool {
+	return exitCode(err) == code
+}
+
+// errStopWriting is returned by the lineWriter when it doesn't need any more
+// lines.
+var errStopWriting = errors.New("stop writing")
+
+// lineWriter calls fn with every line written into it, without the line
+// endings. It returns errStopWriting as soon as fn returns false.
+type lineWriter struct {
+	fn      func(line string) bool
+	buf     []byte
+	stopped bool
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.stopped {
+		return 0, errStopWriting
+	}
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSuffix(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		if !w.fn(line) {
+			w.stopped = true
+			return len(p), errStopWriting
+		}
+	}
+}
+
+// Flush passes the remaining of the input that doesn't end with a new line to
+// the fn.
+func (w *lineWriter) Flush() {
+	if w.stopped || len(w.buf) == 0 {
+		return
+	}
+	w.fn(strings.TrimSuffix(string(w.buf), "\r"))
+	w.buf = nil
+}
+
+// splitLines splits the s into lines, handling both LF and CRLF line endings.
+func splitLines(s string) []string {
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
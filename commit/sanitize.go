@@ -0,0 +1,149 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Sanitize defines how much of the commit messages is escaped before they are
+// rendered.
+type Sanitize int
+
+const (
+	// SanitizeSafe escapes the HTML and wraps the mentions in backticks, so
+	// they don't notify anyone. This is the default.
+	SanitizeSafe Sanitize = iota
+	// SanitizeStrict also escapes the markdown control characters, e.g. the
+	// emphasis and links. Issue references are kept.
+	SanitizeStrict
+	// SanitizeNone renders the messages as they are.
+	SanitizeNone
+)
+
+// ParseSanitize returns the Sanitize for "safe", "strict" or "none".
+func ParseSanitize(s string) (Sanitize, error) {
+	switch s {
+	case "safe", "":
+		return SanitizeSafe, nil
+	case "strict":
+		return SanitizeStrict, nil
+	case "none":
+		return SanitizeNone, nil
+	}
+	return 0, fmt.Errorf("unknown sanitize level %q: use safe, strict or none", s)
+}
+
+func (s Sanitize) String() string {
+	switch s {
+	case SanitizeStrict:
+		return "strict"
+	case SanitizeNone:
+		return "none"
+	}
+	return "safe"
+}
+
+// WithSanitize sets how the commit messages are sanitised. The mentions of
+// the allowed logins, e.g. the authors of the pull requests, are kept.
+func WithSanitize(level Sanitize, allowed ...string) RenderOption {
+	return func(o *renderOptions) {
+		o.sanitize = level
+		o.mentions = make(map[string]bool, len(allowed))
+		for _, login := range allowed {
+			o.mentions[strings.ToLower(strings.TrimPrefix(login, "@"))] = true
+		}
+	}
+}
+
+var (
+	// mentionRe matches the user and team mentions. The ones preceded by a
+	// word, e.g. in email addresses, are not mentions.
+	mentionRe  = regexp.MustCompile(`(^|[^\w@/.\x60-])@([[:alnum:]](?:[[:alnum:]-]*[[:alnum:]])?(?:/[\w.-]+)?)`)
+	htmlEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	mdEscape   = strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+		"~", `\~`, "|", `\|`, "!", `\!`,
+	)
+)
+
+// sanitizeTitle sanitises the description of the title, leaving the verb and
+// the subject of a conventional commit intact so they can still be parsed.
+func (o *renderOptions) sanitizeTitle(title string) string {
+	m := descRe.FindStringSubmatchIndex(title)
+	if m == nil || m[8] < 0 {
+		return o.sanitizeText(title)
+	}
+	return title[:m[8]] + o.sanitizeText(title[m[8]:])
+}
+
+// sanitizeText returns the text escaped for the level. The code spans are left
+// as they are, since GitHub renders their content literally.
+func (o *renderOptions) sanitizeText(s string) string {
+	if o.sanitize == SanitizeNone {
+		return s
+	}
+	buf := &strings.Builder{}
+	for s != "" {
+		start, end := codeSpan(s)
+		if start < 0 {
+			buf.WriteString(o.escape(s))
+			break
+		}
+		buf.WriteString(o.escape(s[:start]))
+		buf.WriteString(s[start:end])
+		s = s[end:]
+	}
+	return buf.String()
+}
+
+func (o *renderOptions) escape(s string) string {
+	if o.sanitize == SanitizeStrict {
+		s = mdEscape.Replace(s)
+		// The backticks are only left here if they don't close a code span.
+		s = strings.ReplaceAll(s, "`", "\\`")
+	}
+	s = htmlEscape.Replace(s)
+	return mentionRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mentionRe.FindStringSubmatch(m)
+		login := sub[2]
+		if o.mentions[strings.ToLower(login)] {
+			return m
+		}
+		return sub[1] + "`@" + login + "`"
+	})
+}
+
+// codeSpan returns the position of the first code span in s, or -1 if there
+// is none. A code span starts with a run of backticks and ends with a run of
+// the same length.
+func codeSpan(s string) (start, end int) {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		n := backticks(s[i:])
+		for j := i + n; j < len(s); {
+			if s[j] != '`' {
+				j++
+				continue
+			}
+			m := backticks(s[j:])
+			if m == n {
+				return i, j + m
+			}
+			j += m
+		}
+		i += n
+	}
+	return -1, -1
+}
+
+func backticks(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSanitize(t *testing.T) {
+	t.Parallel()
+	for _, want := range []commit.Sanitize{commit.SanitizeSafe, commit.SanitizeStrict, commit.SanitizeNone} {
+		got, err := commit.ParseSanitize(want.String())
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	got, err := commit.ParseSanitize("")
+	require.NoError(t, err)
+	assert.Equal(t, commit.SanitizeSafe, got)
+	_, err = commit.ParseSanitize("paranoid")
+	assert.Error(t, err)
+}
+
+func TestParseGroupsSanitize(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		msg    string
+		safe   string
+		strict string
+	}{
+		"script": {
+			msg:    "fix: <script>alert(1)</script>",
+			safe:   "- &lt;script&gt;alert(1)&lt;/script&gt;",
+			strict: "- &lt;script&gt;alert(1)&lt;/script&gt;",
+		},
+		"html": {
+			msg:    `fix: show <img src=x onerror="alert(1)"> & more`,
+			safe:   `- Show &lt;img src=x onerror="alert(1)"&gt; &amp; more`,
+			strict: `- Show &lt;img src=x onerror="alert(1)"&gt; &amp; more`,
+		},
+		"everyone": {
+			msg:    "fix: ping @everyone and @org/team",
+			safe:   "- Ping `@everyone` and `@org/team`",
+			strict: "- Ping `@everyone` and `@org/team`",
+		},
+		"allowed mention": {
+			msg:    "fix: thanks @Alice and @bob-1",
+			safe:   "- Thanks @Alice and `@bob-1`",
+			strict: "- Thanks @Alice and `@bob-1`",
+		},
+		"email": {
+			msg:    "fix: mail me@example.com",
+			safe:   "- Mail me@example.com",
+			strict: "- Mail me@example.com",
+		},
+		"code span": {
+			msg:    "fix: handle `<T>` and `` @x ` `` for *all*",
+			safe:   "- Handle `<T>` and `` @x ` `` for *all*",
+			strict: "- Handle `<T>` and `` @x ` `` for \\*all\\*",
+		},
+		"unclosed code span": {
+			msg:    "fix: stray ` and <b>",
+			safe:   "- Stray ` and &lt;b&gt;",
+			strict: "- Stray \\` and &lt;b&gt;",
+		},
+		"markdown": {
+			msg:    "fix: [click](https://evil.example) __now__ ~~x~~ | ![img](x)",
+			safe:   "- [click](https://evil.example) __now__ ~~x~~ | ![img](x)",
+			strict: "- \\[click\\](https://evil.example) \\_\\_now\\_\\_ \\~\\~x\\~\\~ \\| \\!\\[img\\](x)",
+		},
+		"scope": {
+			msg:    "fix(my_api): use *pointers*",
+			safe:   "- **My_api:** Use *pointers*",
+			strict: "- **My_api:** Use \\*pointers\\*",
+		},
+		"references": {
+			msg:    "fix: one\n\nFixes #12 for @bob",
+			safe:   "- One (Fixes #12 for `@bob`)",
+			strict: "- One (Fixes #12 for `@bob`)",
+		},
+		"sub items": {
+			msg:    "fix: one\n\n- <b>bold</b> for @bob",
+			safe:   "- One\n  - &lt;b&gt;bold&lt;/b&gt; for `@bob`",
+			strict: "- One\n  - &lt;b&gt;bold&lt;/b&gt; for `@bob`",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			render := func(opts ...commit.RenderOption) string {
+				opts = append(opts, commit.WithSubItems(0))
+				return commit.ParseGroups([]string{tc.msg}, opts...)
+			}
+			assert.Equal(t, "### Fix\n\n"+tc.safe, render(commit.WithSanitize(commit.SanitizeSafe, "alice")))
+			assert.Equal(t, "### Fix\n\n"+tc.strict, render(commit.WithSanitize(commit.SanitizeStrict, "@alice")))
+		})
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+		got := commit.ParseGroups([]string{"fix: <b>@bob</b>"})
+		assert.Equal(t, "### Fix\n\n- &lt;b&gt;`@bob`&lt;/b&gt;", got)
+	})
+	t.Run("None", func(t *testing.T) {
+		t.Parallel()
+		got := commit.ParseGroups([]string{"fix: <b>@bob</b>"}, commit.WithSanitize(commit.SanitizeNone))
+		assert.Equal(t, "### Fix\n\n- <b>@bob</b>", got)
+	})
+}
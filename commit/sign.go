@@ -0,0 +1,108 @@
+package commit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSignatureExt is the extension of the signatures if the Signer has
+// none.
+const DefaultSignatureExt = ".sig"
+
+// Signer creates the detached signatures of the assets by running a command,
+// e.g. cosign or gpg, for each of them.
+type Signer struct {
+	// Command is the program and its arguments. "{file}" is replaced with the
+	// path of the asset, and "{signature}" with the path the signature should
+	// be written to. Without "{signature}", the command should write it next
+	// to the asset, e.g. "gpg --detach-sign {file}" writes "{file}.sig".
+	Command []string
+	// Ext is the extension of the signatures. It defaults to
+	// DefaultSignatureExt.
+	Ext string
+	// Dir is the folder of the signatures of the commands with
+	// "{signature}".
+	Dir string
+}
+
+// SignedAsset is an asset and its signature.
+type SignedAsset struct {
+	Asset     string `json:"asset"`
+	Signature string `json:"signature"`
+}
+
+// ParseSignCommand splits the command on the spaces. It doesn't support
+// quoting, therefore the paths are always passed as single arguments.
+func ParseSignCommand(s string) ([]string, error) {
+	args := strings.Fields(s)
+	if len(args) == 0 {
+		return nil, errors.New("empty sign command")
+	}
+	if !strings.Contains(s, "{file}") {
+		return nil, fmt.Errorf("sign command %q has no {file}", s)
+	}
+	return args, nil
+}
+
+// Sign runs the Command for each asset, and returns the assets of the
+// signatures, named after the assets with the Ext. The assets that are
+// already signatures are skipped. It stops at the first failure, or if a
+// command doesn't produce the signature, therefore the release can be aborted
+// before it is published.
+func (s Signer) Sign(ctx context.Context, assets []Asset) ([]Asset, []SignedAsset, error) {
+	ext := s.Ext
+	if ext == "" {
+		ext = DefaultSignatureExt
+	}
+	var (
+		sigs   []Asset
+		signed []SignedAsset
+	)
+	for _, a := range assets {
+		if strings.HasSuffix(a.Name, ext) {
+			continue
+		}
+		sig := Asset{Path: a.Path + ext, Name: a.Name + ext}
+		if s.Dir != "" && s.hasSignature() {
+			sig.Path = filepath.Join(s.Dir, sig.Name)
+		}
+		args := make([]string, len(s.Command))
+		for i, arg := range s.Command {
+			args[i] = strings.NewReplacer("{file}", a.Path, "{signature}", sig.Path).Replace(arg)
+		}
+		// A stale signature would hide a command that doesn't write one.
+		if err := os.Remove(sig.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("signing %s: %w", a.Name, err)
+		}
+		out := &bytes.Buffer{}
+		// nolint:gosec // the command is configured by the user.
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			return nil, nil, fmt.Errorf("signing %s: %w: %s", a.Name, err, strings.TrimSpace(out.String()))
+		}
+		if info, err := os.Stat(sig.Path); err != nil || info.Size() == 0 {
+			return nil, nil, fmt.Errorf("signing %s: no signature in %s", a.Name, sig.Path)
+		}
+		sigs = append(sigs, sig)
+		signed = append(signed, SignedAsset{Asset: a.Name, Signature: sig.Name})
+	}
+	return sigs, signed, nil
+}
+
+// hasSignature returns true if the Command has "{signature}".
+func (s Signer) hasSignature() bool {
+	for _, arg := range s.Command {
+		if strings.Contains(arg, "{signature}") {
+			return true
+		}
+	}
+	return false
+}
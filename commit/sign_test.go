@@ -0,0 +1,102 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSignCommand(t *testing.T) {
+	t.Parallel()
+	got, err := commit.ParseSignCommand("  gpg --detach-sign  {file} ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpg", "--detach-sign", "{file}"}, got)
+
+	for _, s := range []string{"", "   ", "gpg --detach-sign"} {
+		_, err := commit.ParseSignCommand(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSignerSign(t *testing.T) {
+	t.Parallel()
+	t.Run("Signature", testSignerSignSignature)
+	t.Run("NextToFile", testSignerSignNextToFile)
+	t.Run("Failure", testSignerSignFailure)
+	t.Run("NoSignature", testSignerSignNoSignature)
+}
+
+// signAssets writes the files of the assets into a temporary folder.
+func signAssets(t *testing.T, names ...string) []commit.Asset {
+	t.Helper()
+	dir := t.TempDir()
+	assets := make([]commit.Asset, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, "file-"+name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o600))
+		assets = append(assets, commit.Asset{Path: path, Name: name})
+	}
+	return assets
+}
+
+func testSignerSignSignature(t *testing.T) {
+	t.Parallel()
+	assets := signAssets(t, "app.tar.gz", "checksums.txt", "old.tar.gz.sig")
+	dir := t.TempDir()
+	s := commit.Signer{
+		Command: []string{"sh", "-c", `printf "signed %s" "$(cat "$1")" > "$2"`, "sh", "{file}", "{signature}"},
+		Dir:     dir,
+	}
+	sigs, signed, err := s.Sign(context.Background(), assets)
+	require.NoError(t, err)
+	assert.Equal(t, []commit.Asset{
+		{Path: filepath.Join(dir, "app.tar.gz.sig"), Name: "app.tar.gz.sig"},
+		{Path: filepath.Join(dir, "checksums.txt.sig"), Name: "checksums.txt.sig"},
+	}, sigs)
+	assert.Equal(t, []commit.SignedAsset{
+		{Asset: "app.tar.gz", Signature: "app.tar.gz.sig"},
+		{Asset: "checksums.txt", Signature: "checksums.txt.sig"},
+	}, signed)
+	b, err := os.ReadFile(sigs[1].Path)
+	require.NoError(t, err)
+	assert.Equal(t, "signed checksums.txt", string(b))
+}
+
+func testSignerSignNextToFile(t *testing.T) {
+	t.Parallel()
+	assets := signAssets(t, "app.tar.gz")
+	s := commit.Signer{
+		Command: []string{"sh", "-c", `echo sig > "$1.asc"`, "sh", "{file}"},
+		Ext:     ".asc",
+		Dir:     t.TempDir(),
+	}
+	sigs, _, err := s.Sign(context.Background(), assets)
+	require.NoError(t, err)
+	assert.Equal(t, []commit.Asset{{Path: assets[0].Path + ".asc", Name: "app.tar.gz.asc"}}, sigs)
+}
+
+func testSignerSignFailure(t *testing.T) {
+	t.Parallel()
+	assets := signAssets(t, "app.tar.gz")
+	s := commit.Signer{Command: []string{"sh", "-c", "echo no key >&2; exit 3", "sh", "{file}"}}
+	_, _, err := s.Sign(context.Background(), assets)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "app.tar.gz")
+	assert.Contains(t, err.Error(), "no key")
+}
+
+func testSignerSignNoSignature(t *testing.T) {
+	t.Parallel()
+	assets := signAssets(t, "app.tar.gz")
+	// A signature of a previous run doesn't count.
+	require.NoError(t, os.WriteFile(assets[0].Path+".sig", []byte("stale"), 0o600))
+	s := commit.Signer{Command: []string{"true", "{file}"}}
+	_, _, err := s.Sign(context.Background(), assets)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no signature")
+}
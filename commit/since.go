@@ -0,0 +1,68 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sinceLayouts are the layouts of the dates ParseSince accepts. Dates without
+// a zone are in UTC.
+var sinceLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseSince returns the time of s, which is either a duration before now,
+// e.g. "24h" or "7d", or a date, e.g. "2024-06-01" or "2024-06-01T10:00:00Z".
+// Dates without a zone are in UTC. The returned time is in UTC.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, ok := parseDays(s); ok {
+		return now.Add(-d).UTC(), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("negative duration %q", s)
+		}
+		return now.Add(-d).UTC(), nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration nor a date", s)
+}
+
+// parseDays parses the number of days, e.g. "7d", which time.ParseDuration
+// doesn't support.
+func parseDays(s string) (time.Duration, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+	if !strings.HasSuffix(s, "d") || err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * 24 * time.Hour, true
+}
+
+// CommitsSince returns the commits reachable from the HEAD that are committed
+// since the time, and before the until if it's not nil, regardless of the
+// tags. The times are compared with the committer dates, and are passed to git
+// in UTC.
+func (g *Git) CommitsSince(ctx context.Context, since time.Time, until *time.Time) ([]Commit, error) {
+	format, flags := g.notesArgs()
+	flags = append(flags, "--since="+since.UTC().Format(time.RFC3339))
+	if until != nil {
+		flags = append(flags, "--until="+until.UTC().Format(time.RFC3339))
+	}
+	c := g.logCommand(g.logOptions(nil), format, flags...)
+	parts, err := g.logRevs(ctx, c.rev("HEAD"))
+	if err != nil {
+		return nil, fmt.Errorf("listing commits since %s: %w", since.UTC().Format(time.RFC3339), err)
+	}
+	return g.parseCommits(parts), nil
+}
@@ -0,0 +1,96 @@
+package commit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/blokur/testament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitCommitsSince(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := createGitRepo(t)
+	g := commit.Git{Dir: dir}
+
+	// The local dates of the commits are in different zones. The one of
+	// "fix: boundary" is on June 1st, but it's May 31st in UTC.
+	dates := []struct{ msg, date string }{
+		{"feat: old", "2024-05-31T23:00:00+02:00"},
+		{"fix: boundary", "2024-06-01T01:30:00+02:00"},
+		{"feat: new", "2024-06-01T03:00:00+02:00"},
+		{"feat: later", "2024-06-02T12:00:00-05:00"},
+	}
+	for _, d := range dates {
+		createFile(t, dir, "file.txt", testament.RandomString(20))
+		commitAt(t, dir, d.msg, d.date)
+	}
+
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	got, err := g.CommitsSince(ctx, since, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "feat: later", got[0].Subject())
+	assert.Equal(t, time.Date(2024, 6, 2, 17, 0, 0, 0, time.UTC), got[0].Date)
+	assert.Equal(t, "feat: new", got[1].Subject())
+	assert.Equal(t, time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC), got[1].Date)
+	assert.Equal(t, "arsham <arsham@github.com>", got[1].Author)
+	assert.NotEmpty(t, got[1].SHA)
+
+	// The zone of the argument doesn't matter.
+	tehran := time.FixedZone("IRST", 3*60*60+30*60)
+	got, err = g.CommitsSince(ctx, since.In(tehran), nil)
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	until := time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)
+	got, err = g.CommitsSince(ctx, since, &until)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "feat: new", got[0].Subject())
+
+	got, err = g.CommitsSince(ctx, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.FixedZone("", 2*60*60))
+	tcs := map[string]struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		"hours":         {in: "24h", want: time.Date(2024, 6, 9, 10, 0, 0, 0, time.UTC)},
+		"minutes":       {in: "90m", want: time.Date(2024, 6, 10, 8, 30, 0, 0, time.UTC)},
+		"days":          {in: "7d", want: time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)},
+		"date":          {in: "2024-06-01", want: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		"date time":     {in: "2024-06-01T10:30:00", want: time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)},
+		"with zone":     {in: "2024-06-01T10:30:00+02:00", want: time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)},
+		"spaces":        {in: " 2024-06-01 ", want: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		"negative":      {in: "-24h", wantErr: true},
+		"negative days": {in: "-1d", wantErr: true},
+		"garbage":       {in: "yesterday", wantErr: true},
+		"invalid date":  {in: "2024-13-01", wantErr: true},
+		"empty":         {in: "", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseSince(tc.in, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
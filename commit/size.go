@@ -0,0 +1,128 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiffStats sets the Additions, the Deletions and the FilesChanged of the
+// commits in place. They are read with one git log process, which reads the
+// commits from its standard input and only counts the changes in the Paths of
+// the Git. The binary files are counted as changed files without any lines,
+// and the merge commits have no stats.
+func (g *Git) DiffStats(ctx context.Context, commits []Commit) error {
+	if len(commits) == 0 {
+		return nil
+	}
+	c := newCommand(
+		"log", "--no-walk=unsorted", "--numstat", "--no-ext-diff",
+		"--pretty="+commitSeparator+"%H",
+	)
+	out, err := g.runRevs(ctx, c.rev(commitSHAs(commits)...).path(g.Paths...))
+	if err != nil {
+		return fmt.Errorf("reading the stats of the commits: %w", err)
+	}
+	stats := make(map[string]Commit, len(commits))
+	for _, part := range strings.Split(out, commitSeparator)[1:] {
+		lines := splitLines(part)
+		if len(lines) == 0 {
+			continue
+		}
+		var s Commit
+		for _, line := range lines[1:] {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			s.FilesChanged++
+			// The binary files have "-" instead of the numbers.
+			if n, err := strconv.Atoi(fields[0]); err == nil {
+				s.Additions += n
+			}
+			if n, err := strconv.Atoi(fields[1]); err == nil {
+				s.Deletions += n
+			}
+		}
+		stats[strings.TrimSpace(lines[0])] = s
+	}
+	for i, c := range commits {
+		s, ok := stats[c.SHA]
+		if !ok {
+			continue
+		}
+		commits[i].Additions = s.Additions
+		commits[i].Deletions = s.Deletions
+		commits[i].FilesChanged = s.FilesChanged
+	}
+	return nil
+}
+
+// SizeThresholds are the smallest numbers of the changed lines of the M, L
+// and XL commits. The smaller commits are S.
+type SizeThresholds struct {
+	M  int
+	L  int
+	XL int
+}
+
+// DefaultSizeThresholds are the SizeThresholds of WithSizeHints if none is
+// given.
+var DefaultSizeThresholds = SizeThresholds{M: 50, L: 250, XL: 1000}
+
+// ParseSizeThresholds returns the SizeThresholds of the M, L and XL numbers,
+// e.g. "50,250,1000". They should be increasing.
+func ParseSizeThresholds(s string) (SizeThresholds, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return SizeThresholds{}, fmt.Errorf("size thresholds %q: want the M, L and XL numbers, e.g. 50,250,1000", s)
+	}
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 1 {
+			return SizeThresholds{}, fmt.Errorf("size thresholds %q: %q is not a positive number", s, p)
+		}
+		nums[i] = n
+	}
+	if nums[0] >= nums[1] || nums[1] >= nums[2] {
+		return SizeThresholds{}, fmt.Errorf("size thresholds %q: the numbers should be increasing", s)
+	}
+	return SizeThresholds{M: nums[0], L: nums[1], XL: nums[2]}, nil
+}
+
+// Size returns the size of a commit with the changed lines, "S", "M", "L" or
+// "XL".
+func (t SizeThresholds) Size(lines int) string {
+	switch {
+	case lines >= t.XL:
+		return "XL"
+	case lines >= t.L:
+		return "L"
+	case lines >= t.M:
+		return "M"
+	}
+	return "S"
+}
+
+// WithSizeHints appends the size of the commits to their entries, e.g.
+// "- Add the thing `L`". The lines are the numbers of the changed lines of
+// the commits, e.g. their Additions and Deletions, in the order of the logs
+// of ParseGroups. The logs with a negative number, or without one, have no
+// hint.
+func WithSizeHints(lines []int, t SizeThresholds) RenderOption {
+	return func(o *renderOptions) {
+		o.sizes = lines
+		o.thresholds = t
+	}
+}
+
+// sizeHint returns the suffix of the entry of the log at i with its size, or
+// an empty string if it's not known.
+func (o *renderOptions) sizeHint(i int) string {
+	if i >= len(o.sizes) || o.sizes[i] < 0 {
+		return ""
+	}
+	return " `" + o.thresholds.Size(o.sizes[i]) + "`"
+}
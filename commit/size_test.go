@@ -0,0 +1,152 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitDiffStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial", committest.File{Path: "a.txt", Content: "one\ntwo\nthree\n"})
+	r.Tag("v1.0.0")
+	r.Commit("feat: the thing",
+		committest.File{Path: "a.txt", Content: "one\n2\nthree\nfour\n"},
+		committest.File{Path: "b.txt", Content: "b\n"},
+	)
+	r.Commit("chore: the logo", committest.File{Path: "logo.bin", Content: "\x00\x01\x02\x00"})
+	r.Commit("fix: the docs", committest.File{Path: "docs/c.md", Content: "c\n"})
+
+	g := &commit.Git{Dir: r.Dir}
+	commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+	require.NoError(t, g.DiffStats(ctx, commits))
+	got := make(map[string][3]int, len(commits))
+	for _, c := range commits {
+		got[strings.TrimSpace(c.Message)] = [3]int{c.Additions, c.Deletions, c.FilesChanged}
+	}
+	assert.Equal(t, map[string][3]int{
+		"feat: the thing": {3, 1, 2},
+		"chore: the logo": {0, 0, 1},
+		"fix: the docs":   {1, 0, 1},
+	}, got)
+
+	t.Run("Paths", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir, Paths: []string{"docs"}}
+		commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		require.Len(t, commits, 1)
+		require.NoError(t, g.DiffStats(ctx, commits))
+		assert.Equal(t, 1, commits[0].Additions)
+		assert.Equal(t, 1, commits[0].FilesChanged)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+		g := &commit.Git{Dir: r.Dir}
+		assert.NoError(t, g.DiffStats(ctx, nil))
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		t.Parallel()
+		trace := &commit.Trace{Environ: func() []string { return nil }}
+		g := &commit.Git{Dir: r.Dir, Trace: trace}
+		commits, err := g.Log(ctx, "v1.0.0", "HEAD")
+		require.NoError(t, err)
+		require.NoError(t, g.DiffStats(ctx, commits))
+		entries := trace.Entries()
+		args := entries[len(entries)-1].Args
+		assert.Contains(t, args, "--stdin")
+		for _, c := range commits {
+			assert.NotContains(t, args, c.SHA)
+		}
+		assert.NotZero(t, commits[0].FilesChanged)
+	})
+}
+
+// TestGitDiffStatsBatches passes the commits as the arguments of several git
+// processes to a runner that can't write into the standard input.
+func TestGitDiffStatsBatches(t *testing.T) {
+	t.Parallel()
+	commits := make([]commit.Commit, 2500)
+	for i := range commits {
+		commits[i].SHA = strings.Repeat("ab", 17) + fmt.Sprintf("%06d", i)
+	}
+	var batches []int
+	g := &commit.Git{Runner: fakeRunner(func(args []string) (string, error) {
+		out := &strings.Builder{}
+		n := 0
+		for _, arg := range args {
+			if len(arg) == 40 && !strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(out, "00000000000000000000000000000000000%s\n\n1\t2\ta.txt\n", arg)
+				n++
+			}
+		}
+		batches = append(batches, n)
+		return out.String(), nil
+	})}
+	require.NoError(t, g.DiffStats(context.Background(), commits))
+	assert.Equal(t, []int{1000, 1000, 500}, batches)
+	for _, c := range commits {
+		assert.Equal(t, 3, c.Additions+c.Deletions, c.SHA)
+	}
+}
+
+func TestParseSizeThresholds(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		in      string
+		want    commit.SizeThresholds
+		wantErr bool
+	}{
+		"default":    {in: "50,250,1000", want: commit.DefaultSizeThresholds},
+		"spaces":     {in: "10, 20, 30", want: commit.SizeThresholds{M: 10, L: 20, XL: 30}},
+		"two":        {in: "10,20", wantErr: true},
+		"not number": {in: "10,x,30", wantErr: true},
+		"zero":       {in: "0,20,30", wantErr: true},
+		"decreasing": {in: "30,20,10", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseSizeThresholds(tc.in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseGroupsSizeHints(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing",
+		"feat!: the other thing",
+		"fix: the leak",
+		"chore: merge",
+	}
+	got := commit.ParseGroups(logs,
+		commit.WithSizeHints([]int{1200, 300, 49, -1}, commit.DefaultSizeThresholds),
+		commit.WithAttribution([]string{"@jsmith"}),
+	)
+	want := "### Feature\n\n" +
+		"- The thing by @jsmith `XL`\n" +
+		"- The other thing `L` [**BREAKING CHANGE**]\n\n\n" +
+		"### Fix\n\n- The leak `S`\n\n\n" +
+		"### Chore\n\n- Merge"
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,135 @@
+package commit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReleaseStats are the timing metrics of the commits of a release.
+type ReleaseStats struct {
+	// Commits is the number of the commits with a date.
+	Commits int
+	// FirstCommit is the date of the oldest commit.
+	FirstCommit time.Time
+	// Published is the date the release is published.
+	Published time.Time
+	// LeadTime is the duration from the FirstCommit to the publication.
+	LeadTime time.Duration
+	// MedianAge is the median of the durations from the commits to the
+	// publication.
+	MedianAge time.Duration
+	// ActiveDays is the number of the distinct days, in UTC, with commits.
+	ActiveDays int
+	// Clamped is the number of the commits that are dated after the
+	// publication, e.g. because of the wrong clocks of their authors. Their
+	// dates are taken as the publication date.
+	Clamped int
+}
+
+// Stats returns the ReleaseStats of the commits of a release published at the
+// published date. The commits without a date are left out.
+func Stats(commits []Commit, published time.Time) ReleaseStats {
+	s := ReleaseStats{Published: published.UTC()}
+	ages := make([]time.Duration, 0, len(commits))
+	days := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		if c.Date.IsZero() {
+			continue
+		}
+		date := c.Date.UTC()
+		if date.After(s.Published) {
+			date = s.Published
+			s.Clamped++
+		}
+		if s.FirstCommit.IsZero() || date.Before(s.FirstCommit) {
+			s.FirstCommit = date
+		}
+		ages = append(ages, s.Published.Sub(date))
+		days[date.Format("2006-01-02")] = true
+	}
+	s.Commits = len(ages)
+	s.ActiveDays = len(days)
+	if len(ages) == 0 {
+		return s
+	}
+	s.LeadTime = s.Published.Sub(s.FirstCommit)
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	mid := len(ages) / 2
+	s.MedianAge = ages[mid]
+	if len(ages)%2 == 0 {
+		s.MedianAge = (ages[mid-1] + ages[mid]) / 2
+	}
+	return s
+}
+
+// MarshalJSON returns the stats with the durations in seconds.
+func (s ReleaseStats) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Commits          int        `json:"commits"`
+		FirstCommit      *time.Time `json:"first_commit,omitempty"`
+		Published        time.Time  `json:"published"`
+		LeadTimeSeconds  int64      `json:"lead_time_seconds"`
+		MedianAgeSeconds int64      `json:"median_commit_age_seconds"`
+		ActiveDays       int        `json:"active_days"`
+		Clamped          int        `json:"clamped_commits"`
+	}{
+		Commits:          s.Commits,
+		Published:        s.Published,
+		LeadTimeSeconds:  int64(s.LeadTime / time.Second),
+		MedianAgeSeconds: int64(s.MedianAge / time.Second),
+		ActiveDays:       s.ActiveDays,
+		Clamped:          s.Clamped,
+	}
+	if !s.FirstCommit.IsZero() {
+		v.FirstCommit = &s.FirstCommit
+	}
+	return json.Marshal(v)
+}
+
+// Footer returns the "Release stats" section of the stats, or an empty string
+// if there are no commits. The heading is translated with the Headings of the
+// locale, and the labels with its LeadTime, MedianAge and ActiveDays.
+func (s ReleaseStats) Footer(l Locale) string {
+	if s.Commits == 0 {
+		return ""
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "### %s\n\n", l.Heading("Release stats"))
+	fmt.Fprintf(buf, "- %s: %s\n", l.leadTime(), formatDuration(s.LeadTime))
+	fmt.Fprintf(buf, "- %s: %s\n", l.medianAge(), formatDuration(s.MedianAge))
+	fmt.Fprintf(buf, "- %s: %d", l.activeDays(), s.ActiveDays)
+	return buf.String()
+}
+
+// WithStats adds the Footer of the stats to the end of the notes.
+func WithStats(s ReleaseStats) RenderOption {
+	return func(o *renderOptions) {
+		o.stats = &s
+	}
+}
+
+// formatDuration returns the d in days and hours, or in hours and minutes if
+// it's less than a day, e.g. "3d 4h" or "5h 10m".
+func formatDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// statsFooter returns the Footer of the stats of WithStats.
+func (o *renderOptions) statsFooter() string {
+	if o.stats == nil {
+		return ""
+	}
+	return o.stats.Footer(o.locale)
+}
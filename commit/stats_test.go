@@ -0,0 +1,160 @@
+package commit_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	published := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	berlin := time.FixedZone("CEST", 2*60*60)
+	tcs := map[string]struct {
+		dates []time.Time
+		want  commit.ReleaseStats
+	}{
+		"none": {
+			want: commit.ReleaseStats{Published: published},
+		},
+		"one": {
+			dates: []time.Time{published.Add(-90 * time.Minute)},
+			want: commit.ReleaseStats{
+				Commits:     1,
+				FirstCommit: published.Add(-90 * time.Minute),
+				Published:   published,
+				LeadTime:    90 * time.Minute,
+				MedianAge:   90 * time.Minute,
+				ActiveDays:  1,
+			},
+		},
+		"even": {
+			dates: []time.Time{
+				published.Add(-4 * 24 * time.Hour),
+				published.Add(-2 * time.Hour),
+				published.Add(-4 * time.Hour),
+				published.Add(-3 * 24 * time.Hour),
+			},
+			want: commit.ReleaseStats{
+				Commits:     4,
+				FirstCommit: published.Add(-4 * 24 * time.Hour),
+				Published:   published,
+				LeadTime:    4 * 24 * time.Hour,
+				MedianAge:   (3*24*time.Hour + 4*time.Hour) / 2,
+				ActiveDays:  3,
+			},
+		},
+		"days in utc": {
+			// 01:00 in Berlin is still the previous day in UTC.
+			dates: []time.Time{
+				time.Date(2022, 5, 10, 1, 0, 0, 0, berlin),
+				time.Date(2022, 5, 9, 22, 0, 0, 0, time.UTC),
+			},
+			want: commit.ReleaseStats{
+				Commits:     2,
+				FirstCommit: time.Date(2022, 5, 9, 22, 0, 0, 0, time.UTC),
+				Published:   published,
+				LeadTime:    14 * time.Hour,
+				MedianAge:   (14*time.Hour + 13*time.Hour) / 2,
+				ActiveDays:  1,
+			},
+		},
+		"future": {
+			dates: []time.Time{
+				published.Add(48 * time.Hour),
+				published.Add(-time.Hour),
+				{},
+			},
+			want: commit.ReleaseStats{
+				Commits:     2,
+				FirstCommit: published.Add(-time.Hour),
+				Published:   published,
+				LeadTime:    time.Hour,
+				MedianAge:   30 * time.Minute,
+				ActiveDays:  1,
+				Clamped:     1,
+			},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			commits := make([]commit.Commit, 0, len(tc.dates))
+			for _, d := range tc.dates {
+				commits = append(commits, commit.Commit{Date: d})
+			}
+			got := commit.Stats(commits, published)
+			assert.Equal(t, tc.want.Commits, got.Commits)
+			assert.True(t, tc.want.FirstCommit.Equal(got.FirstCommit), got.FirstCommit)
+			assert.Equal(t, tc.want.Published, got.Published)
+			assert.Equal(t, tc.want.LeadTime, got.LeadTime)
+			assert.Equal(t, tc.want.MedianAge, got.MedianAge)
+			assert.Equal(t, tc.want.ActiveDays, got.ActiveDays)
+			assert.Equal(t, tc.want.Clamped, got.Clamped)
+			assert.GreaterOrEqual(t, got.LeadTime, time.Duration(0))
+		})
+	}
+}
+
+func TestReleaseStatsJSON(t *testing.T) {
+	t.Parallel()
+	published := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	s := commit.Stats([]commit.Commit{{Date: published.Add(-time.Hour)}}, published)
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"commits": 1,
+		"first_commit": "2022-05-10T11:00:00Z",
+		"published": "2022-05-10T12:00:00Z",
+		"lead_time_seconds": 3600,
+		"median_commit_age_seconds": 3600,
+		"active_days": 1,
+		"clamped_commits": 0
+	}`, string(b))
+
+	b, err = json.Marshal(commit.Stats(nil, published))
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "first_commit")
+}
+
+func TestParseGroupsStats(t *testing.T) {
+	t.Parallel()
+	published := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	commits := []commit.Commit{
+		{Message: "feat: add the thing", Date: published.Add(-(3*24 + 4) * time.Hour)},
+		{Message: "fix: the thing", Date: published.Add(-(5*time.Hour + 10*time.Minute))},
+	}
+	got := commit.ParseGroups(commit.Messages(commits),
+		commit.WithStats(commit.Stats(commits, published)),
+		commit.WithLocale(commit.Locale{Headings: map[string]string{"release stats": "Statistik"}}),
+		commit.WithCompareLink("https://example.com/compare"),
+	)
+	assert.Contains(t, got, "### Fix\n\n- The thing\n\n"+
+		"### Statistik\n\n"+
+		"- Lead time: 3d 4h\n"+
+		"- Median commit age: 1d 16h\n"+
+		"- Active days: 2\n\n"+
+		"**Full Changelog**: https://example.com/compare")
+
+	got = commit.ParseGroups(commit.Messages(commits),
+		commit.WithStats(commit.Stats(commits, published)),
+		commit.WithLocale(commit.Locale{
+			Headings:   map[string]string{"release stats": "Statistik"},
+			LeadTime:   "Durchlaufzeit",
+			MedianAge:  "Medianes Alter der Commits",
+			ActiveDays: "Aktive Tage",
+		}),
+	)
+	assert.Contains(t, got, "### Statistik\n\n"+
+		"- Durchlaufzeit: 3d 4h\n"+
+		"- Medianes Alter der Commits: 1d 16h\n"+
+		"- Aktive Tage: 2")
+
+	got = commit.ParseGroups(nil, commit.WithStats(commit.Stats(nil, published)))
+	assert.Empty(t, got)
+}
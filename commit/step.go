@@ -0,0 +1,48 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Step is one action of a plan, e.g. of a rollback or a retag.
+type Step struct {
+	// Description explains what the step changes.
+	Description string
+	// Done is set when the step has run successfully.
+	Done bool
+	// Err is set when the step has failed.
+	Err error
+	run func(ctx context.Context) error
+}
+
+// runSteps runs the steps of the op in order. It stops at the first failure,
+// and the returned error lists which steps are done, which one failed and
+// which ones are skipped.
+func (g *Git) runSteps(ctx context.Context, op string, steps []*Step) error {
+	for i, s := range steps {
+		if err := s.run(ctx); err != nil {
+			s.Err = err
+			return stepsError(op, steps, i)
+		}
+		s.Done = true
+		g.debugf("%s done: %s", op, s.Description)
+	}
+	return nil
+}
+
+func stepsError(op string, steps []*Step, failed int) error {
+	lines := make([]string, 0, len(steps))
+	for i, s := range steps {
+		switch {
+		case i < failed:
+			lines = append(lines, "done: "+s.Description)
+		case i == failed:
+			lines = append(lines, fmt.Sprintf("failed: %s: %v", s.Description, s.Err))
+		default:
+			lines = append(lines, "skipped: "+s.Description)
+		}
+	}
+	return fmt.Errorf("%s is incomplete:\n%s", op, strings.Join(lines, "\n"))
+}
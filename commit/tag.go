@@ -0,0 +1,303 @@
+package commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tag is a tag of the repository.
+type Tag struct {
+	Name string
+	// Commit is the commit the tag points to.
+	Commit string
+	// Annotated is true for the tags that are objects of their own, e.g. the
+	// ones created with git tag -a. Lightweight tags are only references to
+	// commits.
+	Annotated bool
+	// Date is the tagger date of an annotated tag, and the committer date of
+	// a lightweight one.
+	Date time.Time
+	// CommitDate is the committer date of the Commit.
+	CommitDate time.Time
+}
+
+type tagsResult struct {
+	tags []Tag
+	err  error
+	once sync.Once
+}
+
+// Tags returns the tags of the repository that match the TagPrefix, the
+// Scheme, the Channel and the AnnotatedOnly options, the most recently created
+// first.
+func (g *Git) Tags(ctx context.Context) ([]Tag, error) {
+	all, err := g.loadTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tags []Tag
+	for _, t := range all {
+		if g.matchTag(t.Name) && (!g.AnnotatedOnly || t.Annotated) {
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+// matchTag returns true if the name of the tag matches the TagPrefix, the
+// Scheme and the Channel.
+func (g *Git) matchTag(name string) bool {
+	return g.matchChannelTag(name, g.Channel)
+}
+
+// matchChannelTag is like matchTag, but matches the tags of the ch instead of
+// the Channel.
+func (g *Git) matchChannelTag(name string, ch *Channel) bool {
+	if !strings.HasPrefix(name, g.TagPrefix) {
+		return false
+	}
+	version := strings.TrimPrefix(name, g.TagPrefix)
+	return (g.Scheme == nil || g.Scheme.Match(version)) && ch.Match(version)
+}
+
+// tagPatterns returns the patterns of for-each-ref for the tags that can
+// match the TagPrefix. The "*" of the patterns doesn't match the slashes,
+// therefore the second one matches the tags in the folders of the prefix. A
+// prefix with the wildcard characters is not turned into patterns.
+func (g *Git) tagPatterns() []string {
+	if g.TagPrefix == "" || strings.ContainsAny(g.TagPrefix, "*?[\\") {
+		return []string{"refs/tags/"}
+	}
+	return []string{"refs/tags/" + g.TagPrefix + "*", "refs/tags/" + g.TagPrefix + "*/**"}
+}
+
+// decorateRefs returns the pattern of the tags for the decorations of git log.
+// Unlike for-each-ref, its "*" matches the slashes too.
+func (g *Git) decorateRefs() string {
+	if g.TagPrefix == "" || strings.ContainsAny(g.TagPrefix, "*?[\\") {
+		return "refs/tags/"
+	}
+	return "refs/tags/" + g.TagPrefix + "*"
+}
+
+// annotatedTags returns the names of the annotated tags.
+func (g *Git) annotatedTags(ctx context.Context) (map[string]bool, error) {
+	tags, err := g.loadTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	annotated := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if t.Annotated {
+			annotated[t.Name] = true
+		}
+	}
+	return annotated, nil
+}
+
+// loadTags reads the tags of the repository that can match the TagPrefix
+// once. Git only reads the tags of the prefix, which matters in the
+// repositories with many tags of other prefixes.
+func (g *Git) loadTags(ctx context.Context) ([]Tag, error) {
+	g.mu.Lock()
+	if g.tags == nil {
+		g.tags = make(map[string]*tagsResult)
+	}
+	t, ok := g.tags[g.TagPrefix]
+	if !ok {
+		t = &tagsResult{}
+		g.tags[g.TagPrefix] = t
+	}
+	g.mu.Unlock()
+
+	t.once.Do(func() {
+		c := newCommand(
+			"for-each-ref", "--sort=-creatordate",
+			"--format=%(refname:strip=2)%00%(objecttype)%00%(objectname)%00%(*objectname)"+
+				"%00%(creatordate:unix)%00%(committerdate:unix)%00%(*committerdate:unix)",
+		)
+		var out string
+		out, t.err = g.run(ctx, c.rev(g.tagPatterns()...))
+		if t.err != nil {
+			return
+		}
+		for _, line := range splitLines(strings.TrimSpace(out)) {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 7 {
+				continue
+			}
+			tag := Tag{Name: fields[0], Commit: fields[2]}
+			tag.Date, _ = parseUnix(fields[4])
+			tag.CommitDate, _ = parseUnix(fields[5])
+			// The object of an annotated tag is the tag itself, and the
+			// commit is the object it is peeled to.
+			if fields[1] == "tag" {
+				tag.Annotated = true
+				tag.Commit = fields[3]
+				tag.CommitDate, _ = parseUnix(fields[6])
+			}
+			t.tags = append(t.tags, tag)
+		}
+	})
+	if contextError(t.err) {
+		g.mu.Lock()
+		if g.tags[g.TagPrefix] == t {
+			delete(g.tags, g.TagPrefix)
+		}
+		g.mu.Unlock()
+	}
+	return t.tags, t.err
+}
+
+// TagMismatchError is returned when a local tag points to a different commit
+// than the tag with the same name on the remote, e.g. when the tag is moved
+// with force on either side.
+type TagMismatchError struct {
+	Tag    string
+	Remote string
+	// Local and RemoteCommit are the commits the tag points to.
+	Local        string
+	RemoteCommit string
+}
+
+func (e *TagMismatchError) Error() string {
+	return fmt.Sprintf("tag %s points to %s locally but to %s on %s", e.Tag, e.Local, e.RemoteCommit, e.Remote)
+}
+
+// CheckRemoteTag returns a *TagMismatchError if the tag points to a different
+// commit on the Remote. A tag that is not pushed yet is accepted.
+func (g *Git) CheckRemoteTag(ctx context.Context, tag string) error {
+	local, err := g.resolve(ctx, tag)
+	if err != nil {
+		return err
+	}
+	remote, err := g.remoteTagCommit(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if remote == "" || remote == local {
+		return nil
+	}
+	return &TagMismatchError{
+		Tag:          tag,
+		Remote:       g.remote(),
+		Local:        local,
+		RemoteCommit: remote,
+	}
+}
+
+// StaleTagError is returned when the local tag is behind the tag with the
+// same name on the remote, e.g. when the tag is moved to a newer commit after
+// the CI has checked out the repository, or it's only on the remote.
+type StaleTagError struct {
+	Tag    string
+	Remote string
+	// Local is empty if the tag is not fetched.
+	Local        string
+	RemoteCommit string
+}
+
+func (e *StaleTagError) Error() string {
+	if e.Local == "" {
+		return fmt.Sprintf("tag %s is not fetched from %s, where it points to %s", e.Tag, e.Remote, e.RemoteCommit)
+	}
+	return fmt.Sprintf("tag %s points to %s locally, which is behind %s on %s", e.Tag, e.Local, e.RemoteCommit, e.Remote)
+}
+
+// SyncTag compares the tag with the one on the Remote. If the local tag is
+// missing, or is behind the remote one, the remote tag is fetched if fetch is
+// set, and true is returned to prepare the release again. Otherwise a
+// *StaleTagError is returned. A tag that has diverged, i.e. the local commit is
+// not an ancestor of the remote one, is never replaced and returns a
+// *TagMismatchError. A tag that is not pushed yet is accepted.
+func (g *Git) SyncTag(ctx context.Context, tag string, fetch bool) (bool, error) {
+	remote, err := g.remoteTagCommit(ctx, tag)
+	if err != nil {
+		return false, err
+	}
+	local, err := g.resolve(ctx, tag)
+	if err != nil && !hasExitCode(err, 1) {
+		return false, err
+	}
+	if remote == "" || local == remote {
+		return false, nil
+	}
+	if local != "" {
+		if fetch {
+			// The commits of the remote tag are needed for comparing them,
+			// without replacing the local tag yet.
+			c := newCommand("fetch", "--no-tags").rev(g.remote()).revFrom("refs/tags/"+tag, tag)
+			if _, err := g.run(ctx, c); err != nil {
+				return false, fmt.Errorf("fetching tag %s from %s: %w", tag, g.remote(), err)
+			}
+		}
+		// Without fetching, the remote commit might not be known, which means
+		// the checkout is behind too.
+		behind, err := g.IsAncestor(ctx, local, remote)
+		switch {
+		case err != nil && fetch:
+			return false, err
+		case err == nil && !behind:
+			return false, &TagMismatchError{Tag: tag, Remote: g.remote(), Local: local, RemoteCommit: remote}
+		}
+	}
+	if !fetch {
+		return false, &StaleTagError{Tag: tag, Remote: g.remote(), Local: local, RemoteCommit: remote}
+	}
+	g.debugf("fetching tag %s from %s, where it points to %s", tag, g.remote(), remote)
+	if err := g.FetchTag(ctx, tag); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// remoteTagCommit returns the commit the tag points to on the Remote, or an
+// empty string if the Remote doesn't have the tag.
+func (g *Git) remoteTagCommit(ctx context.Context, tag string) (string, error) {
+	_, commit, err := g.remoteTag(ctx, tag)
+	return commit, err
+}
+
+// remoteTag returns the object of the ref of the tag on the Remote, which is
+// the tag object of an annotated tag, and the commit the tag points to. They
+// are empty if the Remote doesn't have the tag.
+func (g *Git) remoteTag(ctx context.Context, tag string) (object, commit string, err error) {
+	ref := "refs/tags/" + tag
+	// The peeled ref of an annotated tag is the commit, and the ref itself is
+	// the tag object.
+	c := newCommand("ls-remote").rev(g.remote()).revFrom(ref, tag).revFrom(ref+"^{}", tag)
+	out, err := g.run(ctx, c)
+	if err != nil {
+		return "", "", fmt.Errorf("listing tag %s of %s: %w", tag, g.remote(), err)
+	}
+	for _, line := range splitLines(out) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[1] {
+		case ref + "^{}":
+			commit = fields[0]
+		case ref:
+			object = fields[0]
+		}
+	}
+	if commit == "" {
+		commit = object
+	}
+	return object, commit, nil
+}
+
+// FetchTag replaces the local tag with the one on the Remote.
+func (g *Git) FetchTag(ctx context.Context, tag string) error {
+	ref := "refs/tags/" + tag
+	c := newCommand("fetch", "--no-tags", "--force").rev(g.remote()).revFrom(ref+":"+ref, tag)
+	if _, err := g.run(ctx, c); err != nil {
+		return fmt.Errorf("fetching tag %s from %s: %w", tag, g.remote(), err)
+	}
+	g.Refresh()
+	return nil
+}
@@ -0,0 +1,452 @@
+package commit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/blokur/testament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createMixedTagsRepo creates a repository whose release tags are interleaved
+// with lightweight build tags:
+//
+//	v1.0.0(a) -- build-1 -- v1.1.0 -- v1.2.0(a) build-2 -- rc-1(a) build-3 -- v1.3.0 build-4
+//
+// The annotated tags are marked with (a). It returns the commits in order.
+func createMixedTagsRepo(t *testing.T) (string, []string) {
+	t.Helper()
+	dir := createGitRepo(t)
+	commits := [][]string{
+		{"+v1.0.0"},
+		{"build-1"},
+		{"v1.1.0"},
+		{"+v1.2.0", "build-2"},
+		{"+rc-1", "build-3"},
+		{"v1.3.0", "build-4"},
+	}
+	var shas []string
+	for i, tags := range commits {
+		createFile(t, dir, "file.txt", testament.RandomString(20))
+		commitChanges(t, dir, "feat: commit "+string(rune('a'+i)))
+		shas = append(shas, strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD")))
+		for _, tag := range tags {
+			if name := strings.TrimPrefix(tag, "+"); name != tag {
+				runGit(t, dir, "tag", "-a", name, "-m", name)
+				continue
+			}
+			createGitTag(t, dir, tag)
+		}
+	}
+	return dir, shas
+}
+
+func TestGitAnnotatedOnly(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, _ := createMixedTagsRepo(t)
+
+	tcs := map[string]struct {
+		prefix    string
+		annotated bool
+		latest    string
+		previous  map[string]string
+	}{
+		"prefix": {
+			prefix: "v",
+			latest: "v1.3.0",
+			previous: map[string]string{
+				"v1.3.0": "v1.2.0",
+				"v1.2.0": "v1.1.0",
+			},
+		},
+		"annotated": {
+			annotated: true,
+			latest:    "rc-1",
+			previous: map[string]string{
+				"rc-1":   "v1.2.0",
+				"v1.2.0": "v1.0.0",
+			},
+		},
+		"annotated with prefix": {
+			prefix:    "v",
+			annotated: true,
+			latest:    "v1.2.0",
+			previous: map[string]string{
+				"v1.3.0": "v1.2.0",
+				"v1.2.0": "v1.0.0",
+			},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.Git{
+				Dir:           dir,
+				TagPrefix:     tc.prefix,
+				AnnotatedOnly: tc.annotated,
+			}
+			latest, err := g.LatestTag(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.latest, latest)
+			for tag, want := range tc.previous {
+				got, err := g.PreviousTag(ctx, tag)
+				require.NoError(t, err)
+				assert.Equal(t, want, got, tag)
+			}
+		})
+	}
+
+	t.Run("NoAnnotatedTag", func(t *testing.T) {
+		t.Parallel()
+		g := commit.Git{Dir: dir, TagPrefix: "build-", AnnotatedOnly: true}
+		_, err := g.LatestTag(ctx)
+		assert.ErrorIs(t, err, commit.ErrNoTag)
+	})
+}
+
+func TestGitLatestTagDecorations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial")
+	r.Tag("svc/v1.0.0")
+	r.Commit("feat: other")
+	r.Tag("v2.0.0")
+
+	trace := &commit.Trace{Environ: func() []string { return nil }}
+	g := &commit.Git{Dir: r.Dir, TagPrefix: "svc/", Trace: trace}
+	latest, err := g.LatestTag(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "svc/v1.0.0", latest)
+
+	// Only the tags of the prefix decorate the walk, the others are not read.
+	var walk []string
+	for _, e := range trace.Entries() {
+		if len(e.Args) > 0 && e.Args[0] == "log" {
+			walk = e.Args
+		}
+	}
+	require.NotEmpty(t, walk)
+	assert.Contains(t, walk, "--decorate-refs=refs/tags/svc/*")
+	assert.NotContains(t, walk, "--decorate-refs=refs/tags/")
+}
+
+func TestGitTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, shas := createMixedTagsRepo(t)
+
+	g := commit.Git{Dir: dir}
+	tags, err := g.Tags(ctx)
+	require.NoError(t, err)
+	byName := make(map[string]commit.Tag, len(tags))
+	for _, tag := range tags {
+		// The dates are checked with a retro-created tag.
+		assert.False(t, tag.Date.IsZero(), tag.Name)
+		assert.False(t, tag.CommitDate.IsZero(), tag.Name)
+		tag.Date, tag.CommitDate = time.Time{}, time.Time{}
+		byName[tag.Name] = tag
+	}
+	assert.Len(t, byName, 9)
+	assert.Equal(t, commit.Tag{Name: "v1.0.0", Commit: shas[0], Annotated: true}, byName["v1.0.0"])
+	assert.Equal(t, commit.Tag{Name: "build-1", Commit: shas[1]}, byName["build-1"])
+	assert.Equal(t, commit.Tag{Name: "v1.2.0", Commit: shas[3], Annotated: true}, byName["v1.2.0"])
+	assert.Equal(t, commit.Tag{Name: "build-2", Commit: shas[3]}, byName["build-2"])
+
+	names := func(tags []commit.Tag) []string {
+		out := make([]string, len(tags))
+		for i, tag := range tags {
+			out[i] = tag.Name
+		}
+		return out
+	}
+	g = commit.Git{Dir: dir, AnnotatedOnly: true}
+	tags, err = g.Tags(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1.0.0", "v1.2.0", "rc-1"}, names(tags))
+
+	g = commit.Git{Dir: dir, TagPrefix: "v", AnnotatedOnly: true}
+	tags, err = g.Tags(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1.0.0", "v1.2.0"}, names(tags))
+
+	g = commit.Git{Dir: dir, TagPrefix: "v", Scheme: commit.SemVer{}}
+	tags, err = g.Tags(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"}, names(tags))
+
+	// New tags are only seen after a Refresh.
+	createGitTag(t, dir, "v1.4.0")
+	tags, err = g.Tags(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tags, 4)
+	g.Refresh()
+	tags, err = g.Tags(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tags, 5)
+}
+
+func TestGitCheckRemoteTag(t *testing.T) {
+	t.Parallel()
+	t.Run("Same", testGitCheckRemoteTagSame)
+	t.Run("NotPushed", testGitCheckRemoteTagNotPushed)
+	t.Run("Annotated", testGitCheckRemoteTagAnnotated)
+	t.Run("Diverged", testGitCheckRemoteTagDiverged)
+	t.Run("NoRemote", testGitCheckRemoteTagNoRemote)
+}
+
+func testGitCheckRemoteTagSame(t *testing.T) {
+	t.Parallel()
+	dir, _ := createReleasedRepo(t)
+	g := &commit.Git{Dir: dir}
+	assert.NoError(t, g.CheckRemoteTag(context.Background(), "v1.0.0"))
+}
+
+func testGitCheckRemoteTagNotPushed(t *testing.T) {
+	t.Parallel()
+	dir, _ := createReleasedRepo(t)
+	createFile(t, dir, "file.txt", "other")
+	commitChanges(t, dir, "fix: thing")
+	createGitTag(t, dir, "v1.0.1")
+	g := &commit.Git{Dir: dir}
+	assert.NoError(t, g.CheckRemoteTag(context.Background(), "v1.0.1"))
+}
+
+func testGitCheckRemoteTagAnnotated(t *testing.T) {
+	t.Parallel()
+	dir, _ := createReleasedRepo(t)
+	runGit(t, dir, "tag", "-a", "-m", "release", "v1.1.0")
+	runGit(t, dir, "push", "-q", "origin", "refs/tags/v1.1.0")
+	g := &commit.Git{Dir: dir}
+	assert.NoError(t, g.CheckRemoteTag(context.Background(), "v1.1.0"))
+}
+
+// testGitCheckRemoteTagDiverged moves the tag on the remote from another
+// clone.
+func testGitCheckRemoteTagDiverged(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir, remote := createReleasedRepo(t)
+	other := t.TempDir()
+	runGit(t, other, "clone", "-q", remote, ".")
+	gitConfig(t, other, "user.email", "arsham@github.com")
+	gitConfig(t, other, "user.name", "arsham")
+	runGit(t, other, "checkout", "-q", "-b", "other", "v1.0.0")
+	createFile(t, other, "file.txt", "moved")
+	commitChanges(t, other, "fix: moved")
+	runGit(t, other, "tag", "-f", "-a", "-m", "moved", "v1.0.0")
+	runGit(t, other, "push", "-q", "-f", "origin", "refs/tags/v1.0.0")
+
+	local := strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.0.0^{commit}"))
+	moved := strings.TrimSpace(runGit(t, other, "rev-parse", "v1.0.0^{commit}"))
+	require.NotEqual(t, local, moved)
+
+	g := &commit.Git{Dir: dir}
+	err := g.CheckRemoteTag(ctx, "v1.0.0")
+	var mismatch *commit.TagMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	want := &commit.TagMismatchError{Tag: "v1.0.0", Remote: "origin", Local: local, RemoteCommit: moved}
+	assert.Equal(t, want, mismatch)
+	assert.Contains(t, err.Error(), local)
+	assert.Contains(t, err.Error(), moved)
+
+	require.NoError(t, g.FetchTag(ctx, "v1.0.0"))
+	assert.NoError(t, g.CheckRemoteTag(ctx, "v1.0.0"))
+	got := strings.TrimSpace(runGit(t, dir, "rev-parse", "v1.0.0^{commit}"))
+	assert.Equal(t, moved, got)
+}
+
+func testGitCheckRemoteTagNoRemote(t *testing.T) {
+	t.Parallel()
+	dir := createGitRepo(t)
+	createFile(t, dir, "file.txt", "content")
+	commitChanges(t, dir, "feat: thing")
+	createGitTag(t, dir, "v1.0.0")
+	g := &commit.Git{Dir: dir, Remote: testament.RandomString(10)}
+	err := g.CheckRemoteTag(context.Background(), "v1.0.0")
+	var gitErr *commit.GitError
+	assert.ErrorAs(t, err, &gitErr)
+}
+
+func TestGitSyncTag(t *testing.T) {
+	t.Parallel()
+	t.Run("Same", testGitSyncTagSame)
+	t.Run("Behind", testGitSyncTagBehind)
+	t.Run("BehindNoFetch", testGitSyncTagBehindNoFetch)
+	t.Run("Missing", testGitSyncTagMissing)
+	t.Run("Diverged", testGitSyncTagDiverged)
+}
+
+// staleClones returns two clones of a remote with the v1.0.0 and v1.1.0 tags.
+// The v1.1.0 is moved to a newer commit from the other clone after the stale
+// one is cloned, as when the tag is pushed again while the CI is running.
+func staleClones(t *testing.T) (stale, other string) {
+	t.Helper()
+	dir, remote := createReleasedRepo(t)
+	createFile(t, dir, "file.txt", "fix")
+	commitChanges(t, dir, "fix: first")
+	createGitTag(t, dir, "v1.1.0")
+	runGit(t, dir, "push", "-q", "origin", "refs/tags/v1.1.0")
+
+	stale = t.TempDir()
+	runGit(t, stale, "clone", "-q", remote, ".")
+	other = t.TempDir()
+	runGit(t, other, "clone", "-q", remote, ".")
+	gitConfig(t, other, "user.email", "arsham@github.com")
+	gitConfig(t, other, "user.name", "arsham")
+	runGit(t, other, "checkout", "-q", "-b", "release", "v1.1.0")
+	createFile(t, other, "file.txt", "late")
+	commitChanges(t, other, "fix: late")
+	runGit(t, other, "tag", "-f", "v1.1.0")
+	runGit(t, other, "push", "-q", "-f", "origin", "refs/tags/v1.1.0")
+	return stale, other
+}
+
+func testGitSyncTagSame(t *testing.T) {
+	t.Parallel()
+	stale, other := staleClones(t)
+	g := &commit.Git{Dir: other}
+	fetched, err := g.SyncTag(context.Background(), "v1.1.0", true)
+	require.NoError(t, err)
+	assert.False(t, fetched)
+
+	g = &commit.Git{Dir: stale}
+	fetched, err = g.SyncTag(context.Background(), "v1.0.0", true)
+	require.NoError(t, err)
+	assert.False(t, fetched)
+}
+
+func testGitSyncTagBehind(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	stale, other := staleClones(t)
+	g := &commit.Git{Dir: stale}
+	logs, err := g.Commits(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	fetched, err := g.SyncTag(ctx, "v1.1.0", true)
+	require.NoError(t, err)
+	assert.True(t, fetched)
+	want := strings.TrimSpace(runGit(t, other, "rev-parse", "v1.1.0^{commit}"))
+	got := strings.TrimSpace(runGit(t, stale, "rev-parse", "v1.1.0^{commit}"))
+	assert.Equal(t, want, got)
+
+	logs, err = g.Commits(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+}
+
+func testGitSyncTagBehindNoFetch(t *testing.T) {
+	t.Parallel()
+	stale, other := staleClones(t)
+	local := strings.TrimSpace(runGit(t, stale, "rev-parse", "v1.1.0^{commit}"))
+	moved := strings.TrimSpace(runGit(t, other, "rev-parse", "v1.1.0^{commit}"))
+
+	g := &commit.Git{Dir: stale}
+	fetched, err := g.SyncTag(context.Background(), "v1.1.0", false)
+	assert.False(t, fetched)
+	var staleErr *commit.StaleTagError
+	require.ErrorAs(t, err, &staleErr)
+	want := &commit.StaleTagError{Tag: "v1.1.0", Remote: "origin", Local: local, RemoteCommit: moved}
+	assert.Equal(t, want, staleErr)
+	got := strings.TrimSpace(runGit(t, stale, "rev-parse", "v1.1.0^{commit}"))
+	assert.Equal(t, local, got, "the tag should not be fetched")
+}
+
+func testGitSyncTagMissing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	stale, _ := staleClones(t)
+	runGit(t, stale, "tag", "-d", "v1.1.0")
+	g := &commit.Git{Dir: stale}
+
+	_, err := g.SyncTag(ctx, "v1.1.0", false)
+	var staleErr *commit.StaleTagError
+	require.ErrorAs(t, err, &staleErr)
+	assert.Empty(t, staleErr.Local)
+	assert.Contains(t, err.Error(), "not fetched")
+
+	fetched, err := g.SyncTag(ctx, "v1.1.0", true)
+	require.NoError(t, err)
+	assert.True(t, fetched)
+	logs, err := g.Commits(ctx, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+}
+
+// testGitSyncTagDiverged moves the local tag to a commit the remote doesn't
+// have, which is never replaced automatically.
+func testGitSyncTagDiverged(t *testing.T) {
+	t.Parallel()
+	stale, _ := staleClones(t)
+	gitConfig(t, stale, "user.email", "arsham@github.com")
+	gitConfig(t, stale, "user.name", "arsham")
+	runGit(t, stale, "checkout", "-q", "-b", "local", "v1.1.0")
+	createFile(t, stale, "file.txt", "local")
+	commitChanges(t, stale, "fix: local")
+	runGit(t, stale, "tag", "-f", "v1.1.0")
+	local := strings.TrimSpace(runGit(t, stale, "rev-parse", "v1.1.0^{commit}"))
+
+	for _, fetch := range []bool{true, false} {
+		g := &commit.Git{Dir: stale}
+		_, err := g.SyncTag(context.Background(), "v1.1.0", fetch)
+		var mismatch *commit.TagMismatchError
+		require.ErrorAs(t, err, &mismatch, "fetch: %v", fetch)
+		assert.Equal(t, local, mismatch.Local)
+		got := strings.TrimSpace(runGit(t, stale, "rev-parse", "v1.1.0^{commit}"))
+		assert.Equal(t, local, got)
+	}
+}
+
+func TestGitTagsPrefix(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := committest.NewRepo(t, identity)
+	r.Commit("feat: one")
+	for _, tag := range []string{"v1.0.0", "mod/v1.0.0", "mod/sub/v1.0.0", "modx/v1.0.0", "mo"} {
+		r.Tag(tag)
+	}
+	r.Commit("fix: two")
+
+	tcs := map[string]struct {
+		prefix string
+		want   []string
+	}{
+		"none":     {"", []string{"mo", "mod/sub/v1.0.0", "mod/v1.0.0", "modx/v1.0.0", "v1.0.0"}},
+		"folder":   {"mod/", []string{"mod/sub/v1.0.0", "mod/v1.0.0"}},
+		"partial":  {"mod", []string{"mod/sub/v1.0.0", "mod/v1.0.0", "modx/v1.0.0"}},
+		"wildcard": {"m*", nil},
+		"missing":  {"other/", nil},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := commit.New(commit.WithDir(r.Dir), commit.WithTagPrefix(tc.prefix))
+			tags, err := g.Tags(ctx)
+			require.NoError(t, err)
+			var names []string
+			for _, tag := range tags {
+				names = append(names, tag.Name)
+			}
+			assert.ElementsMatch(t, tc.want, names)
+
+			if len(tc.want) == 0 {
+				_, err := g.LatestTag(ctx)
+				assert.ErrorIs(t, err, commit.ErrNoTag)
+				return
+			}
+			latest, err := g.LatestTag(ctx)
+			require.NoError(t, err)
+			assert.Contains(t, tc.want, latest)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Term is a forbidden pattern of the notes, e.g. a common misspelling or the
+// old codename of the product, with its suggested Replacement. The
+// Replacement can refer to the groups of the Pattern, e.g. "$1". A Term
+// without a Replacement is only reported.
+type Term struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// NewTerm compiles the pattern of a Term.
+func NewTerm(pattern, replacement string) (Term, error) {
+	if pattern == "" {
+		return Term{}, fmt.Errorf("the term of replacement %q has no pattern", replacement)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Term{}, fmt.Errorf("compiling term %q: %w", pattern, err)
+	}
+	return Term{Pattern: re, Replacement: replacement}, nil
+}
+
+// TermMatch is an occurrence of a Term in the notes.
+type TermMatch struct {
+	Term Term
+	// Text is the matched text, and Line is its line in the notes, starting
+	// from 1.
+	Text string
+	Line int
+	// Suggestion is the Replacement of the Text, or empty if the Term has
+	// none.
+	Suggestion string
+}
+
+// String returns the description of the match, with the suggested
+// replacement if the Term has one.
+func (m TermMatch) String() string {
+	s := fmt.Sprintf("line %d: %q matches the forbidden term %s", m.Line, m.Text, m.Term.Pattern)
+	if m.Term.Replacement == "" {
+		return s
+	}
+	return s + fmt.Sprintf(", use %q", m.Suggestion)
+}
+
+// Terms are the forbidden terms of the notes.
+type Terms []Term
+
+// Check returns the occurrences of the terms in the notes, in the order of
+// the terms and then of their positions.
+func (t Terms) Check(notes string) []TermMatch {
+	var matches []TermMatch
+	for _, term := range t {
+		for _, loc := range term.Pattern.FindAllStringSubmatchIndex(notes, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			m := TermMatch{
+				Term: term,
+				Text: notes[loc[0]:loc[1]],
+				Line: strings.Count(notes[:loc[0]], "\n") + 1,
+			}
+			if term.Replacement != "" {
+				m.Suggestion = string(term.Pattern.ExpandString(nil, term.Replacement, notes, loc))
+			}
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Fix replaces the occurrences of the terms that have a Replacement, and
+// returns the notes with the number of the replacements. The terms are
+// applied in order, therefore a replacement can be matched by a later term.
+func (t Terms) Fix(notes string) (string, int) {
+	var n int
+	for _, term := range t {
+		if term.Replacement == "" {
+			continue
+		}
+		var (
+			b    []byte
+			last int
+		)
+		for _, loc := range term.Pattern.FindAllStringSubmatchIndex(notes, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			b = append(b, notes[last:loc[0]]...)
+			b = term.Pattern.ExpandString(b, term.Replacement, notes, loc)
+			last = loc[1]
+			n++
+		}
+		if last > 0 {
+			notes = string(append(b, notes[last:]...))
+		}
+	}
+	return notes, n
+}
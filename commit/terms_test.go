@@ -0,0 +1,87 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forbiddenTerms returns the terms of a misspelling, a codename and a word
+// that is only reported.
+func forbiddenTerms(t *testing.T) commit.Terms {
+	t.Helper()
+	spelling, err := commit.NewTerm(`(?i)\b(r)ecieve`, "${1}eceive")
+	require.NoError(t, err)
+	codename, err := commit.NewTerm(`\bProject Falcon\b`, "Acme Cloud")
+	require.NoError(t, err)
+	internal, err := commit.NewTerm(`(?i)\bjira-\d+\b`, "")
+	require.NoError(t, err)
+	return commit.Terms{spelling, codename, internal}
+}
+
+func TestNewTerm(t *testing.T) {
+	t.Parallel()
+	_, err := commit.NewTerm("", "x")
+	assert.ErrorContains(t, err, "no pattern")
+	_, err = commit.NewTerm("[", "x")
+	assert.ErrorContains(t, err, "compiling term")
+}
+
+func TestTermsCheck(t *testing.T) {
+	t.Parallel()
+	notes := "### Feature\n\n- Recieve the events of Project Falcon\n- Fix JIRA-12 and recieved\n"
+	got := forbiddenTerms(t).Check(notes)
+	require.Len(t, got, 4)
+	want := []struct {
+		text, suggestion string
+		line             int
+	}{
+		{"Recieve", "Receive", 3},
+		{"recieve", "receive", 4},
+		{"Project Falcon", "Acme Cloud", 3},
+		{"JIRA-12", "", 4},
+	}
+	for i, w := range want {
+		assert.Equal(t, w.text, got[i].Text)
+		assert.Equal(t, w.suggestion, got[i].Suggestion)
+		assert.Equal(t, w.line, got[i].Line)
+	}
+	assert.Equal(t, `line 3: "Recieve" matches the forbidden term (?i)\b(r)ecieve, use "Receive"`, got[0].String())
+	assert.Equal(t, `line 4: "JIRA-12" matches the forbidden term (?i)\bjira-\d+\b`, got[3].String())
+
+	assert.Empty(t, forbiddenTerms(t).Check("- Receive the events"))
+}
+
+func TestTermsFix(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		notes string
+		want  string
+		n     int
+	}{
+		"replaced": {
+			notes: "- Recieve the events of Project Falcon, recieved once\n",
+			want:  "- Receive the events of Acme Cloud, received once\n",
+			n:     3,
+		},
+		"reported only": {
+			notes: "- Fix JIRA-12\n",
+			want:  "- Fix JIRA-12\n",
+		},
+		"none": {
+			notes: "- Receive the events\n",
+			want:  "- Receive the events\n",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, n := forbiddenTerms(t).Fix(tc.notes)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.n, n)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package commit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DefaultTOCMinSections is the number of the headings from which WithTOC adds
+// the table of contents.
+const DefaultTOCMinSections = 4
+
+// WithTOC adds a table of contents before the first heading of the notes,
+// which links to all the headings with the anchors of GitHub. It is left out
+// if the notes have fewer than minSections headings. If minSections is less
+// than one, DefaultTOCMinSections is used.
+func WithTOC(minSections int) RenderOption {
+	return func(o *renderOptions) {
+		if minSections < 1 {
+			minSections = DefaultTOCMinSections
+		}
+		o.tocMin = minSections
+	}
+}
+
+var (
+	// tocHeadingRe matches an ATX heading, without its optional closing
+	// sequence.
+	tocHeadingRe = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	// inlineLinkRe matches the links and the images, whose texts are kept.
+	inlineLinkRe = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	htmlTagRe    = regexp.MustCompile(`<[^>]+>`)
+)
+
+// tocHeading is a heading of the notes.
+type tocHeading struct {
+	level  int
+	text   string
+	anchor string
+}
+
+// addTOC returns the notes with the table of contents before their first
+// heading, or the notes as they are if they have fewer than the tocMin
+// headings.
+func (o *renderOptions) addTOC(notes string) string {
+	lines := strings.Split(notes, "\n")
+	var (
+		headings []tocHeading
+		first    = -1
+		fence    string
+		anchors  = &slugger{}
+	)
+	for i, line := range lines {
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch fence {
+			case "":
+				fence = m[1]
+			case m[1]:
+				fence = ""
+			}
+			continue
+		}
+		m := tocHeadingRe.FindStringSubmatch(line)
+		if fence != "" || m == nil {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		text := headingText(m[2])
+		headings = append(headings, tocHeading{
+			level:  len(m[1]),
+			text:   text,
+			anchor: anchors.slug(text),
+		})
+	}
+	if len(headings) == 0 || len(headings) < o.tocMin {
+		return notes
+	}
+	top := headings[0].level
+	for _, h := range headings {
+		if h.level < top {
+			top = h.level
+		}
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "**%s**\n\n", o.locale.contents())
+	for _, h := range headings {
+		indent := strings.Repeat("  ", h.level-top)
+		fmt.Fprintf(buf, "%s- [%s](#%s)\n", indent, h.text, h.anchor)
+	}
+	buf.WriteString("\n")
+	head := strings.Join(lines[:first], "\n")
+	if first > 0 {
+		head += "\n"
+	}
+	return head + buf.String() + strings.Join(lines[first:], "\n")
+}
+
+// headingText returns the text of the heading as GitHub renders it, without
+// the emphasis, the code spans, the links and the HTML tags.
+func headingText(heading string) string {
+	text := inlineLinkRe.ReplaceAllString(heading, "$1")
+	text = htmlTagRe.ReplaceAllString(text, "")
+	text = strings.NewReplacer("**", "", "`", "", "*", "").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// slugger makes the anchors of the headings as GitHub does. The anchors of
+// the duplicate headings are suffixed with their number, e.g. "fix-1".
+type slugger struct {
+	seen map[string]int
+}
+
+// slug returns the anchor of the text of a heading. The text is lowercased,
+// its spaces are replaced by hyphens, and anything but the letters, the
+// numbers, the hyphens and the underscores is removed, e.g. the emoji and
+// the punctuation.
+func (s *slugger) slug(text string) string {
+	buf := &strings.Builder{}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			buf.WriteRune('-')
+		case r == '-', unicode.IsLetter(r), unicode.IsMark(r), unicode.IsNumber(r), unicode.Is(unicode.Pc, r):
+			buf.WriteRune(r)
+		}
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]int)
+	}
+	base := buf.String()
+	anchor := base
+	for {
+		if _, ok := s.seen[anchor]; !ok {
+			break
+		}
+		s.seen[base]++
+		anchor = fmt.Sprintf("%s-%d", base, s.seen[base])
+	}
+	s.seen[anchor] = 0
+	return anchor
+}
@@ -0,0 +1,105 @@
+package commit_test
+
+import (
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGroupsTOC(t *testing.T) {
+	t.Parallel()
+	logs := []string{
+		"feat: the thing",
+		"fix: the leak",
+		"docs: the readme",
+		"chore: the linter",
+	}
+	tcs := map[string]struct {
+		logs []string
+		opts []commit.RenderOption
+		want string
+	}{
+		"sections": {
+			logs: logs,
+			opts: []commit.RenderOption{commit.WithTOC(0)},
+			want: "**Contents**\n\n" +
+				"- [Feature](#feature)\n" +
+				"- [Fix](#fix)\n" +
+				"- [Docs](#docs)\n" +
+				"- [Chore](#chore)\n\n" +
+				"### Feature\n\n- The thing\n\n\n" +
+				"### Fix\n\n- The leak\n\n\n" +
+				"### Docs\n\n- The readme\n\n\n" +
+				"### Chore\n\n- The linter",
+		},
+		"fewer sections": {
+			logs: logs[:3],
+			opts: []commit.RenderOption{commit.WithTOC(4)},
+			want: "### Feature\n\n- The thing\n\n\n" +
+				"### Fix\n\n- The leak\n\n\n" +
+				"### Docs\n\n- The readme",
+		},
+		"min sections": {
+			logs: logs[:2],
+			opts: []commit.RenderOption{commit.WithTOC(2)},
+			want: "**Contents**\n\n" +
+				"- [Feature](#feature)\n" +
+				"- [Fix](#fix)\n\n" +
+				"### Feature\n\n- The thing\n\n\n" +
+				"### Fix\n\n- The leak",
+		},
+		"duplicates and emoji": {
+			logs: logs[:3],
+			opts: []commit.RenderOption{
+				commit.WithTOC(2),
+				commit.WithLocale(commit.Locale{
+					Contents: "Inhalt",
+					Headings: map[string]string{
+						"feature": "✨ New: `Features`!",
+						"fix":     "Änderungen & Fixes",
+						"docs":    "Änderungen & Fixes",
+					},
+				}),
+			},
+			want: "**Inhalt**\n\n" +
+				"- [✨ New: Features!](#-new-features)\n" +
+				"- [Änderungen & Fixes](#änderungen--fixes)\n" +
+				"- [Änderungen & Fixes](#änderungen--fixes-1)\n\n" +
+				"### ✨ New: `Features`!\n\n- The thing\n\n\n" +
+				"### Änderungen & Fixes\n\n- The leak\n\n\n" +
+				"### Änderungen & Fixes\n\n- The readme",
+		},
+		"first release": {
+			logs: logs[:2],
+			opts: []commit.RenderOption{commit.WithTOC(2), commit.WithInitialRelease()},
+			want: "This is the first release.\n\n" +
+				"**Contents**\n\n" +
+				"- [Feature](#feature)\n" +
+				"- [Fix](#fix)\n\n" +
+				"### Feature\n\n- The thing\n\n\n" +
+				"### Fix\n\n- The leak",
+		},
+		"code blocks": {
+			logs: logs[:2],
+			opts: []commit.RenderOption{
+				commit.WithTOC(3),
+				commit.WithDependencies("### Dependencies\n\n```\n# Not a heading\n```", false),
+			},
+			want: "**Contents**\n\n" +
+				"- [Feature](#feature)\n" +
+				"- [Fix](#fix)\n" +
+				"- [Dependencies](#dependencies)\n\n" +
+				"### Feature\n\n- The thing\n\n\n" +
+				"### Fix\n\n- The leak\n\n" +
+				"### Dependencies\n\n```\n# Not a heading\n```",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.ParseGroups(tc.logs, tc.opts...))
+		})
+	}
+}
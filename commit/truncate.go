@@ -0,0 +1,78 @@
+package commit
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBodyLength is the maximum number of characters GitHub accepts in the
+// body of a release.
+const MaxBodyLength = 125000
+
+// ErrBodyTooLong is returned when the notes of a release are longer than
+// MaxBodyLength.
+var ErrBodyTooLong = errors.New("release body is too long")
+
+var (
+	headingRe  = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	listItemRe = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+	fenceRe    = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+)
+
+// ChangelogAssetName returns the name of the asset that holds the full notes
+// of the tag, e.g. "CHANGELOG-v1.4.0.md".
+func ChangelogAssetName(tag string) string {
+	return "CHANGELOG-" + strings.ReplaceAll(tag, "/", "-") + ".md"
+}
+
+// TruncateNotes returns the notes unchanged and false if they are no longer
+// than the limit of characters. Otherwise the notes are cut before the last
+// heading that leaves room for the note, which is appended to them. If no
+// heading fits, they are cut before the last list item, code fence or empty
+// line. The notes are never cut inside a code fence or a paragraph, so
+// the markdown constructs such as links are left intact.
+func TruncateNotes(notes string, limit int, note string) (string, bool) {
+	if utf8.RuneCountInString(notes) <= limit {
+		return notes, false
+	}
+	budget := limit - utf8.RuneCountInString(note) - 2
+	lines := strings.Split(notes, "\n")
+	var (
+		section, item int // the number of lines before the best cuts.
+		fence         string
+		length        = -1 // the length of the lines so far, joined.
+	)
+	for i, line := range lines {
+		if length > budget {
+			break
+		}
+		if fence == "" && i > 0 {
+			switch {
+			case headingRe.MatchString(line):
+				section, item = i, i
+			case strings.TrimSpace(line) == "", listItemRe.MatchString(line), fenceRe.MatchString(line):
+				item = i
+			}
+		}
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case strings.HasPrefix(m[1], fence) && strings.TrimSpace(line) == m[1]:
+				fence = ""
+			}
+		}
+		length += utf8.RuneCountInString(line) + 1
+	}
+	cut := section
+	if cut == 0 {
+		cut = item
+	}
+	kept := strings.TrimRight(strings.Join(lines[:cut], "\n"), " \t\n")
+	if kept == "" {
+		return note, true
+	}
+	return kept + "\n\n" + note, true
+}
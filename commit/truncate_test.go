@@ -0,0 +1,97 @@
+package commit_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangelogAssetName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "CHANGELOG-v1.4.0.md", commit.ChangelogAssetName("v1.4.0"))
+	assert.Equal(t, "CHANGELOG-mod-sub-v1.4.0.md", commit.ChangelogAssetName("mod/sub/v1.4.0"))
+}
+
+func TestTruncateNotes(t *testing.T) {
+	t.Parallel()
+	const note = "_truncated_"
+	items := func(n int) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = "- Item number " + strings.Repeat("x", 5)
+		}
+		return strings.Join(lines, "\n")
+	}
+	tcs := map[string]struct {
+		notes string
+		limit int
+		want  string
+		cut   bool
+	}{
+		"short": {
+			notes: "### Fix\n\n- One",
+			limit: 100,
+			want:  "### Fix\n\n- One",
+		},
+		"exact": {
+			notes: "### Fix\n\n- One",
+			limit: 14,
+			want:  "### Fix\n\n- One",
+		},
+		"section": {
+			notes: "### Fix\n\n" + items(2) + "\n\n\n### Feature\n\n" + items(10),
+			limit: 100,
+			want:  "### Fix\n\n" + items(2) + "\n\n" + note,
+		},
+		"last section that fits": {
+			notes: "### Fix\n\n- One\n\n### Feature\n\n- Two\n\n### Misc\n\n" + items(10),
+			limit: 60,
+			want:  "### Fix\n\n- One\n\n### Feature\n\n- Two\n\n" + note,
+		},
+		"item": {
+			notes: "### Fix\n\n" + items(10),
+			limit: 80,
+			want:  "### Fix\n\n" + items(2) + "\n\n" + note,
+		},
+		"code fence": {
+			notes: "### Fix\n\n- One\n  ```go\n  - not an item\n\n  - nor this\n  ```\n- Two\n" + items(5),
+			limit: 60,
+			want:  "### Fix\n\n- One\n\n" + note,
+		},
+		"tilde fence": {
+			notes: "- One\n~~~\n- Two\n```\n- Three\n~~~~\n- Four\n" + items(5),
+			limit: 60,
+			want:  "- One\n~~~\n- Two\n```\n- Three\n~~~~\n- Four\n\n" + note,
+		},
+		"link": {
+			notes: "- One\n- See the [long\ndescription](https://example.com) of it\n- Two",
+			limit: 50,
+			want:  "- One\n\n" + note,
+		},
+		"nothing fits": {
+			notes: "- " + strings.Repeat("x", 30),
+			limit: 20,
+			want:  note,
+		},
+		"only the heading fits": {
+			notes: "### Fix\n\n" + items(3),
+			limit: 20,
+			want:  "### Fix\n\n" + note,
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, cut := commit.TruncateNotes(tc.notes, tc.limit, note)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.want != tc.notes, cut)
+			if cut && utf8.RuneCountInString(got) > tc.limit {
+				t.Errorf("got %d characters, want at most %d", utf8.RuneCountInString(got), tc.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arsham/gitrelease/version"
+)
+
+// Bump is the part of a semantic version that should be incremented.
+type Bump int
+
+// These are the possible bumps, ordered by their significance.
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// BumpFromLogs returns the bump the logs require: a breaking change requires a
+// major bump, a feature a minor bump, and anything else a patch bump. If there
+// are no logs, BumpNone is returned.
+func BumpFromLogs(logs []string) Bump {
+	bump := BumpNone
+	for _, l := range logs {
+		g := GroupFromCommit(l)
+		switch {
+		case g.Breaking:
+			return BumpMajor
+		case g.Verb == "Feature":
+			bump = BumpMinor
+		case bump < BumpPatch:
+			bump = BumpPatch
+		}
+	}
+	return bump
+}
+
+// NextVersion returns the current version incremented by the bump. The current
+// version should be a semantic version with an optional "v" prefix. The
+// pre-release and build parts are dropped. If current is empty, the
+// DefaultInitialVersion is returned. Versions before v1.0.0 are not bumped to
+// v1: a major bump increments the minor part instead.
+func NextVersion(current string, bump Bump) (string, error) {
+	if current == "" {
+		return DefaultInitialVersion, nil
+	}
+	if !semverRe.MatchString(current) {
+		return "", fmt.Errorf("%q is not a semantic version", current)
+	}
+	v, err := version.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	if bump == BumpMajor && v.Major == 0 {
+		bump = BumpMinor
+	}
+	return version.Bump(v, version.Part(bump)).String(), nil
+}
+
+// A VersionScheme defines the format of the versions and how the next version
+// is calculated. The versions don't contain the tag prefix of the modules.
+type VersionScheme interface {
+	// Match returns true if the version belongs to the scheme.
+	Match(version string) bool
+	// Next returns the version after the current one. The current version is
+	// empty if there are no releases yet.
+	Next(current string, bump Bump, now time.Time) (string, error)
+}
+
+// ParseScheme returns the VersionScheme with the name, which is either
+// "semver" or "calver". The pattern is only used by calver.
+func ParseScheme(name, pattern string) (VersionScheme, error) {
+	switch name {
+	case "", "semver":
+		return SemVer{}, nil
+	case "calver":
+		return NewCalVer(pattern)
+	}
+	return nil, fmt.Errorf("unknown version scheme: %q", name)
+}
+
+var semverRe = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// SemVer is the semantic versioning scheme, with an optional "v" prefix.
+type SemVer struct{}
+
+// Match returns true if the version is a semantic version.
+func (SemVer) Match(version string) bool {
+	return semverRe.MatchString(version)
+}
+
+// Next returns the current version incremented by the bump.
+func (SemVer) Next(current string, bump Bump, _ time.Time) (string, error) {
+	return NextVersion(current, bump)
+}
+
+func (SemVer) String() string { return "semver" }
+
+// calverTokens are the supported segments of CalVer patterns, and the regexp
+// each of them matches.
+var calverTokens = map[string]string{
+	"YYYY":  `\d{4}`,
+	"YY":    `[1-9]\d{0,2}|0`,
+	"0Y":    `\d{2,3}`,
+	"MM":    `1[0-2]|[1-9]`,
+	"0M":    `1[0-2]|0[1-9]`,
+	"WW":    `5[0-3]|[1-4]\d|\d`,
+	"0W":    `5[0-3]|[0-4]\d`,
+	"DD":    `3[01]|[12]\d|[1-9]`,
+	"0D":    `3[01]|[12]\d|0[1-9]`,
+	"MICRO": `\d+`,
+}
+
+// CalVer is the calendar versioning scheme. The Pattern consists of segments
+// separated by dots, e.g. "YYYY.0M.MICRO". The date segments are rolled when
+// the date changes, otherwise the MICRO segment is incremented.
+type CalVer struct {
+	Pattern  string
+	segments []string
+	re       *regexp.Regexp
+}
+
+// NewCalVer returns a CalVer for the pattern. The optional MICRO segment can
+// only be the last segment.
+func NewCalVer(pattern string) (*CalVer, error) {
+	if pattern == "" {
+		return nil, errors.New("empty calver pattern")
+	}
+	segments := strings.Split(pattern, ".")
+	exprs := make([]string, len(segments))
+	for i, seg := range segments {
+		expr, ok := calverTokens[seg]
+		if !ok {
+			return nil, fmt.Errorf("unknown segment %q in calver pattern %q", seg, pattern)
+		}
+		if seg == "MICRO" && i != len(segments)-1 {
+			return nil, fmt.Errorf("MICRO should be the last segment of calver pattern %q", pattern)
+		}
+		exprs[i] = "(" + expr + ")"
+	}
+	if segments[0] == "MICRO" {
+		return nil, fmt.Errorf("calver pattern %q has no date segments", pattern)
+	}
+	return &CalVer{
+		Pattern:  pattern,
+		segments: segments,
+		re:       regexp.MustCompile(`^` + strings.Join(exprs, `\.`) + `$`),
+	}, nil
+}
+
+// Match returns true if the version matches the pattern.
+func (c *CalVer) Match(version string) bool {
+	return c.re.MatchString(version)
+}
+
+// Next returns the version for the now date. If the current version is of
+// the same date, its MICRO segment is incremented. The bump is ignored.
+func (c *CalVer) Next(current string, _ Bump, now time.Time) (string, error) {
+	date := c.date(now)
+	hasMicro := c.segments[len(c.segments)-1] == "MICRO"
+	if !hasMicro {
+		if current == date {
+			return "", fmt.Errorf("%s is already released, add MICRO to the calver pattern", current)
+		}
+		return date, nil
+	}
+	if current == "" {
+		return date + ".0", nil
+	}
+	if !c.Match(current) {
+		return "", fmt.Errorf("%q does not match calver pattern %q", current, c.Pattern)
+	}
+	i := strings.LastIndexByte(current, '.')
+	if current[:i] != date {
+		return date + ".0", nil
+	}
+	micro, err := strconv.Atoi(current[i+1:])
+	if err != nil {
+		return "", fmt.Errorf("%q does not match calver pattern %q", current, c.Pattern)
+	}
+	return fmt.Sprintf("%s.%d", date, micro+1), nil
+}
+
+func (c *CalVer) String() string { return "calver:" + c.Pattern }
+
+// date returns the date segments of the pattern for t.
+func (c *CalVer) date(t time.Time) string {
+	_, week := t.ISOWeek()
+	parts := make([]string, 0, len(c.segments))
+	for _, seg := range c.segments {
+		switch seg {
+		case "YYYY":
+			parts = append(parts, strconv.Itoa(t.Year()))
+		case "YY":
+			parts = append(parts, strconv.Itoa(t.Year()-2000))
+		case "0Y":
+			parts = append(parts, fmt.Sprintf("%02d", t.Year()-2000))
+		case "MM":
+			parts = append(parts, strconv.Itoa(int(t.Month())))
+		case "0M":
+			parts = append(parts, fmt.Sprintf("%02d", int(t.Month())))
+		case "WW":
+			parts = append(parts, strconv.Itoa(week))
+		case "0W":
+			parts = append(parts, fmt.Sprintf("%02d", week))
+		case "DD":
+			parts = append(parts, strconv.Itoa(t.Day()))
+		case "0D":
+			parts = append(parts, fmt.Sprintf("%02d", t.Day()))
+		}
+	}
+	return strings.Join(parts, ".")
+}
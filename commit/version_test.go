@@ -0,0 +1,153 @@
+package commit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpFromLogs(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		logs []string
+		want commit.Bump
+	}{
+		"empty":    {nil, commit.BumpNone},
+		"fix":      {[]string{"fix: something"}, commit.BumpPatch},
+		"misc":     {[]string{"something"}, commit.BumpPatch},
+		"feature":  {[]string{"fix: something", "feat: new thing"}, commit.BumpMinor},
+		"breaking": {[]string{"feat: new thing", "ref!: remove old thing"}, commit.BumpMajor},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, commit.BumpFromLogs(tc.logs))
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		current string
+		bump    commit.Bump
+		want    string
+		wantErr bool
+	}{
+		"empty":         {"", commit.BumpPatch, "v0.1.0", false},
+		"none":          {"v1.2.3", commit.BumpNone, "v1.2.3", false},
+		"patch":         {"v1.2.3", commit.BumpPatch, "v1.2.4", false},
+		"minor":         {"v1.2.3", commit.BumpMinor, "v1.3.0", false},
+		"major":         {"v1.2.3", commit.BumpMajor, "v2.0.0", false},
+		"major v0":      {"v0.2.3", commit.BumpMajor, "v0.3.0", false},
+		"no prefix":     {"1.2.3", commit.BumpPatch, "1.2.4", false},
+		"pre-release":   {"v1.2.3-rc.1+build", commit.BumpPatch, "v1.2.4", false},
+		"two parts":     {"v1.2", commit.BumpPatch, "", true},
+		"not a number":  {"v1.x.3", commit.BumpPatch, "", true},
+		"negative part": {"v1.-2.3", commit.BumpPatch, "", true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.NextVersion(tc.current, tc.bump)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+	t.Parallel()
+	s, err := commit.ParseScheme("", "")
+	require.NoError(t, err)
+	assert.Equal(t, commit.SemVer{}, s)
+
+	s, err = commit.ParseScheme("calver", "YYYY.0M.MICRO")
+	require.NoError(t, err)
+	assert.True(t, s.Match("2024.06.1"))
+
+	_, err = commit.ParseScheme("calver", "")
+	assert.Error(t, err)
+	_, err = commit.ParseScheme("romver", "")
+	assert.Error(t, err)
+}
+
+func TestSemVerMatch(t *testing.T) {
+	t.Parallel()
+	s := commit.SemVer{}
+	for _, v := range []string{"v1.2.3", "1.2.3", "v1.2.3-rc.1", "v1.2.3+build.5"} {
+		assert.True(t, s.Match(v), v)
+	}
+	for _, v := range []string{"", "v1.2", "2024.06", "mod/v1.2.3", "latest"} {
+		assert.False(t, s.Match(v), v)
+	}
+}
+
+func TestNewCalVer(t *testing.T) {
+	t.Parallel()
+	for _, p := range []string{"YYYY.0M.MICRO", "YY.MM.DD", "YYYY.0W.MICRO", "0Y.0D"} {
+		_, err := commit.NewCalVer(p)
+		assert.NoError(t, err, p)
+	}
+	for _, p := range []string{"", "YYYY.Q", "MICRO.YYYY", "MICRO", "YYYY-MM"} {
+		_, err := commit.NewCalVer(p)
+		assert.Error(t, err, p)
+	}
+}
+
+func TestCalVerMatch(t *testing.T) {
+	t.Parallel()
+	c, err := commit.NewCalVer("YYYY.0M.MICRO")
+	require.NoError(t, err)
+	for _, v := range []string{"2024.06.1", "2024.12.0", "1999.01.15"} {
+		assert.True(t, c.Match(v), v)
+	}
+	for _, v := range []string{"v1.2.3", "2024.6.1", "2024.13.1", "24.06.1", "2024.06"} {
+		assert.False(t, c.Match(v), v)
+	}
+}
+
+func TestCalVerNext(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+	tcs := map[string]struct {
+		pattern string
+		current string
+		want    string
+		wantErr bool
+	}{
+		"first":          {"YYYY.0M.MICRO", "", "2024.06.0", false},
+		"same month":     {"YYYY.0M.MICRO", "2024.06.1", "2024.06.2", false},
+		"new month":      {"YYYY.0M.MICRO", "2024.05.7", "2024.06.0", false},
+		"short year":     {"YY.MM.MICRO", "24.5.3", "24.6.0", false},
+		"padded year":    {"0Y.0M.0D", "24.06.02", "24.06.03", false},
+		"week":           {"YYYY.0W.MICRO", "2024.23.0", "2024.23.1", false},
+		"no micro":       {"YYYY.0M.0D", "2024.05.31", "2024.06.03", false},
+		"no micro again": {"YYYY.0M.0D", "2024.06.03", "", true},
+		"mismatch":       {"YYYY.0M.MICRO", "v1.2.3", "", true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, err := commit.NewCalVer(tc.pattern)
+			require.NoError(t, err)
+			got, err := c.Next(tc.current, commit.BumpMajor, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package commit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VersionFormat is how the version is found in a VersionFile.
+type VersionFormat string
+
+// These are the formats of the VersionFiles.
+const (
+	// VersionPlain is a file that only has the version, e.g. VERSION.
+	VersionPlain VersionFormat = "plain"
+	// VersionPackageJSON is the version field of a package.json.
+	VersionPackageJSON VersionFormat = "package.json"
+	// VersionCargo is the version of the package section of a Cargo.toml.
+	VersionCargo VersionFormat = "cargo"
+	// VersionRegexp is the first capture group of the Pattern.
+	VersionRegexp VersionFormat = "regexp"
+)
+
+// VersionFile is a file that has the version of the project, which is
+// updated to the version of the release before it's tagged.
+type VersionFile struct {
+	// Path is relative to the Dir of the Git.
+	Path string
+	// Format defaults to the one of the name of the Path: VERSION is
+	// VersionPlain, package.json is VersionPackageJSON and Cargo.toml is
+	// VersionCargo. It's VersionRegexp if the Pattern is set.
+	Format VersionFormat
+	// Pattern is the regexp of VersionRegexp, whose first capture group is
+	// the version, e.g. `const Version = "(.+)"`. Only its first match is
+	// replaced.
+	Pattern string
+}
+
+var (
+	// packageVersionRe matches the version field of a package.json. The
+	// first one is the field of the package, which is checked by decoding
+	// the file.
+	packageVersionRe = regexp.MustCompile(`"version"\s*:\s*"([^"]*)"`)
+	// cargoVersionRe matches the version of the package section of a
+	// Cargo.toml.
+	cargoVersionRe = regexp.MustCompile(`(?m)^\[package\][^\[]*?^version\s*=\s*"([^"]*)"`)
+)
+
+// ParseVersionFile parses "path", whose format is known from its name, or
+// "path=pattern" for a VersionRegexp.
+func ParseVersionFile(s string) (VersionFile, error) {
+	p, pattern, _ := strings.Cut(s, "=")
+	f := VersionFile{Path: strings.TrimSpace(p), Pattern: pattern}
+	if f.Path == "" {
+		return VersionFile{}, fmt.Errorf("version file %q has no path", s)
+	}
+	if _, err := f.format(); err != nil {
+		return VersionFile{}, err
+	}
+	return f, nil
+}
+
+// format returns the Format of the file, or the one of its name.
+func (f VersionFile) format() (VersionFormat, error) {
+	switch {
+	case f.Format != "":
+		return f.Format, nil
+	case f.Pattern != "":
+		return VersionRegexp, nil
+	}
+	switch name := path.Base(filepath.ToSlash(f.Path)); {
+	case name == "package.json":
+		return VersionPackageJSON, nil
+	case name == "Cargo.toml":
+		return VersionCargo, nil
+	case strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), "VERSION"):
+		return VersionPlain, nil
+	}
+	return "", fmt.Errorf("version file %s has an unknown format, set its pattern, e.g. %s=version: (.+)", f.Path, f.Path)
+}
+
+// Update returns the content with the version replaced. The version is
+// written without the "v" prefix, unless the old one has it.
+func (f VersionFile) Update(content, version string) (string, error) {
+	format, err := f.format()
+	if err != nil {
+		return "", err
+	}
+	version = strings.TrimPrefix(version, "v")
+	switch format {
+	case VersionPlain:
+		old := strings.TrimSpace(content)
+		if old == "" {
+			return "", fmt.Errorf("version file %s is empty", f.Path)
+		}
+		return strings.Replace(content, old, withPrefix(old, version), 1), nil
+	case VersionPackageJSON:
+		var pkg struct {
+			Version *string `json:"version"`
+		}
+		if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+			return "", fmt.Errorf("version file %s: %w", f.Path, err)
+		}
+		if pkg.Version == nil {
+			return "", fmt.Errorf("version file %s has no version field", f.Path)
+		}
+		updated, err := replaceGroup(f.Path, packageVersionRe, content, version)
+		if err != nil {
+			return "", err
+		}
+		// The first version field might belong to something else.
+		if err := json.Unmarshal([]byte(updated), &pkg); err != nil || strings.TrimPrefix(*pkg.Version, "v") != version {
+			return "", fmt.Errorf("version file %s: the version field of the package is not the first one", f.Path)
+		}
+		return updated, nil
+	case VersionCargo:
+		return replaceGroup(f.Path, cargoVersionRe, content, version)
+	case VersionRegexp:
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("version file %s: %w", f.Path, err)
+		}
+		if re.NumSubexp() < 1 {
+			return "", fmt.Errorf("version file %s: pattern %q has no capture group", f.Path, f.Pattern)
+		}
+		return replaceGroup(f.Path, re, content, version)
+	}
+	return "", fmt.Errorf("version file %s has unknown format %q", f.Path, format)
+}
+
+// replaceGroup replaces the first capture group of the first match of the re
+// with the version.
+func replaceGroup(name string, re *regexp.Regexp, content, version string) (string, error) {
+	loc := re.FindStringSubmatchIndex(content)
+	if loc == nil || loc[2] < 0 {
+		return "", fmt.Errorf("version file %s has no version that matches %q", name, re)
+	}
+	old := content[loc[2]:loc[3]]
+	return content[:loc[2]] + withPrefix(old, version) + content[loc[3]:], nil
+}
+
+// withPrefix returns the version with the "v" prefix if the old one has it.
+func withPrefix(old, version string) string {
+	if strings.HasPrefix(old, "v") {
+		return "v" + version
+	}
+	return version
+}
+
+// VersionChange is the change of a VersionFile.
+type VersionChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// Diff returns the unified diff of the change.
+func (c VersionChange) Diff() string {
+	return DiffNotes("a/"+c.Path, "b/"+c.Path, c.Old, c.New)
+}
+
+// VersionChanges returns the changes of the files for the version of the
+// tag, without writing them. The files that already have the version are left
+// out.
+func (g *Git) VersionChanges(files []VersionFile, tag string) ([]VersionChange, error) {
+	version := strings.TrimPrefix(tag, g.TagPrefix)
+	changes := make([]VersionChange, 0, len(files))
+	for _, f := range files {
+		b, err := os.ReadFile(g.path(f.Path))
+		if err != nil {
+			return nil, fmt.Errorf("reading version file: %w", err)
+		}
+		updated, err := f.Update(string(b), version)
+		if err != nil {
+			return nil, err
+		}
+		if updated != string(b) {
+			changes = append(changes, VersionChange{Path: f.Path, Old: string(b), New: updated})
+		}
+	}
+	return changes, nil
+}
+
+// BumpVersion writes the version of the tag into the files, and commits them
+// on the HEAD as "chore(release): <tag>". The files should be tracked, and
+// only their changes are committed. It returns the changes and the commit,
+// which is empty if the files already have the version. If a file can't be
+// written, or the commit fails, the files are restored.
+func (g *Git) BumpVersion(ctx context.Context, files []VersionFile, tag string) ([]VersionChange, string, error) {
+	if err := checkRevs(tag); err != nil {
+		return nil, "", err
+	}
+	changes, err := g.VersionChanges(files, tag)
+	if err != nil || len(changes) == 0 {
+		return changes, "", err
+	}
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	written := make([]VersionChange, 0, len(changes))
+	restore := func(err error) error {
+		for _, c := range written {
+			if e := writeFileMode(g.path(c.Path), c.Old); e != nil {
+				err = fmt.Errorf("%w\nrestoring %s: %v", err, c.Path, e)
+			}
+		}
+		return err
+	}
+	for _, c := range changes {
+		if err := writeFileMode(g.path(c.Path), c.New); err != nil {
+			return nil, "", restore(fmt.Errorf("writing version file: %w", err))
+		}
+		written = append(written, c)
+	}
+
+	c := newCommand("commit", "--quiet", "--only", "--message=chore(release): "+tag).path(paths...)
+	if _, err := g.run(ctx, c); err != nil {
+		return nil, "", restore(fmt.Errorf("committing the version files: %w", err))
+	}
+	g.Refresh()
+	sha, err := g.resolve(ctx, "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	return changes, sha, nil
+}
+
+// path returns the path of the name in the working tree.
+func (g *Git) path(name string) string {
+	dir := g.workTree()
+	if filepath.IsAbs(name) || dir == "" {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// workTree returns the directory of the working tree, which is the WorkTree
+// relative to the Dir if it is set, or the Dir.
+func (g *Git) workTree() string {
+	switch {
+	case g.WorkTree == "":
+		return g.Dir
+	case filepath.IsAbs(g.WorkTree) || g.Dir == "":
+		return g.WorkTree
+	}
+	return filepath.Join(g.Dir, g.WorkTree)
+}
+
+// writeFileMode replaces the content of the file, keeping its mode.
+func writeFileMode(name, content string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, []byte(content), info.Mode().Perm())
+}
@@ -0,0 +1,208 @@
+package commit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionFile(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		input   string
+		want    commit.VersionFile
+		wantErr bool
+	}{
+		"plain":        {input: "VERSION", want: commit.VersionFile{Path: "VERSION"}},
+		"package.json": {input: "web/package.json", want: commit.VersionFile{Path: "web/package.json"}},
+		"cargo":        {input: "Cargo.toml", want: commit.VersionFile{Path: "Cargo.toml"}},
+		"pattern":      {input: `main.go=Version = "(.+)"`, want: commit.VersionFile{Path: "main.go", Pattern: `Version = "(.+)"`}},
+		"unknown":      {input: "main.go", wantErr: true},
+		"no path":      {input: "=(.+)", wantErr: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := commit.ParseVersionFile(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestVersionFileUpdate(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		file    commit.VersionFile
+		content string
+		want    string
+		wantErr bool
+	}{
+		"plain": {
+			file:    commit.VersionFile{Path: "VERSION"},
+			content: "1.0.0\n",
+			want:    "1.2.0\n",
+		},
+		"plain prefix": {
+			file:    commit.VersionFile{Path: "VERSION.txt"},
+			content: "v1.0.0",
+			want:    "v1.2.0",
+		},
+		"plain empty": {
+			file:    commit.VersionFile{Path: "VERSION"},
+			content: "\n",
+			wantErr: true,
+		},
+		"package.json": {
+			file:    commit.VersionFile{Path: "package.json"},
+			content: "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\",\n  \"dependencies\": {\"version\": \"^2.0.0\"}\n}\n",
+			want:    "{\n  \"name\": \"app\",\n  \"version\": \"1.2.0\",\n  \"dependencies\": {\"version\": \"^2.0.0\"}\n}\n",
+		},
+		"package.json nested first": {
+			file:    commit.VersionFile{Path: "package.json"},
+			content: `{"engines": {"version": "18"}, "version": "1.0.0"}`,
+			wantErr: true,
+		},
+		"package.json no version": {
+			file:    commit.VersionFile{Path: "package.json"},
+			content: `{"name": "app"}`,
+			wantErr: true,
+		},
+		"cargo": {
+			file:    commit.VersionFile{Path: "Cargo.toml"},
+			content: "[package]\nname = \"app\"\nversion = \"1.0.0\"\n\n[dependencies]\nserde = { version = \"1.0\" }\n",
+			want:    "[package]\nname = \"app\"\nversion = \"1.2.0\"\n\n[dependencies]\nserde = { version = \"1.0\" }\n",
+		},
+		"cargo no package": {
+			file:    commit.VersionFile{Path: "Cargo.toml"},
+			content: "[dependencies]\nversion = \"1.0\"\n",
+			wantErr: true,
+		},
+		"regexp": {
+			file:    commit.VersionFile{Path: "main.go", Pattern: `Version = "(.+)"`},
+			content: "package main\n\nconst Version = \"1.0.0\"\n",
+			want:    "package main\n\nconst Version = \"1.2.0\"\n",
+		},
+		"regexp no group": {
+			file:    commit.VersionFile{Path: "main.go", Pattern: `Version = ".+"`},
+			content: "const Version = \"1.0.0\"\n",
+			wantErr: true,
+		},
+		"regexp no match": {
+			file:    commit.VersionFile{Path: "main.go", Pattern: `Version = "(.+)"`},
+			content: "package main\n",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tc.file.Update(tc.content, "v1.2.0")
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// versionRepo returns a repository with a VERSION and a package.json at
+// 1.0.0.
+func versionRepo(t *testing.T) *committest.Repo {
+	t.Helper()
+	r := committest.NewRepo(t, identity)
+	r.Commit("chore: initial",
+		committest.File{Path: "VERSION", Content: "1.0.0\n"},
+		committest.File{Path: "package.json", Content: "{\n  \"version\": \"1.0.0\"\n}\n"},
+	)
+	r.Tag("v1.0.0")
+	r.Commit("feat: the thing")
+	return r
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestGitBumpVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	files := []commit.VersionFile{{Path: "VERSION"}, {Path: "package.json"}}
+
+	t.Run("Commit", func(t *testing.T) {
+		t.Parallel()
+		r := versionRepo(t)
+		r.WriteFile("README.md", "not committed")
+		g := &commit.Git{Dir: r.Dir}
+		changes, sha, err := g.BumpVersion(ctx, files, "v1.1.0")
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, strings.TrimSpace(r.Head()), sha)
+		assert.Equal(t, "chore(release): v1.1.0", strings.TrimSpace(r.Run("log", "-1", "--format=%s")))
+		assert.Equal(t, "VERSION\npackage.json", strings.TrimSpace(r.Run("show", "--name-only", "--format=", "HEAD")))
+		assert.Equal(t, "1.1.0\n", readFile(t, filepath.Join(r.Dir, "VERSION")))
+		assert.Contains(t, r.Run("status", "--porcelain"), "README.md")
+
+		// The files that have the version are not committed again.
+		changes, sha, err = g.BumpVersion(ctx, files, "v1.1.0")
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		assert.Empty(t, sha)
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		t.Parallel()
+		r := versionRepo(t)
+		head := r.Head()
+		g := &commit.Git{Dir: r.Dir}
+		changes, err := g.VersionChanges(files[:1], "v1.1.0")
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "--- a/VERSION\n+++ b/VERSION\n@@ -1 +1 @@\n-1.0.0\n+1.1.0\n", changes[0].Diff())
+		assert.Equal(t, "1.0.0\n", readFile(t, filepath.Join(r.Dir, "VERSION")))
+		assert.Equal(t, head, r.Head())
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		t.Parallel()
+		r := versionRepo(t)
+		head := r.Head()
+		r.WriteFile("VERSION.txt", "1.0.0\n")
+		g := &commit.Git{Dir: r.Dir}
+		// The untracked file can't be committed.
+		_, _, err := g.BumpVersion(ctx, append(files, commit.VersionFile{Path: "VERSION.txt"}), "v1.1.0")
+		require.Error(t, err)
+		assert.Equal(t, "1.0.0\n", readFile(t, filepath.Join(r.Dir, "VERSION")))
+		assert.Equal(t, "{\n  \"version\": \"1.0.0\"\n}\n", readFile(t, filepath.Join(r.Dir, "package.json")))
+		assert.Equal(t, "1.0.0\n", readFile(t, filepath.Join(r.Dir, "VERSION.txt")))
+		assert.Equal(t, head, r.Head())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		r := versionRepo(t)
+		r.WriteFile("Cargo.toml", "[dependencies]\n")
+		g := &commit.Git{Dir: r.Dir}
+		_, _, err := g.BumpVersion(ctx, append(files, commit.VersionFile{Path: "Cargo.toml"}), "v1.1.0")
+		require.Error(t, err)
+		assert.Equal(t, "1.0.0\n", readFile(t, filepath.Join(r.Dir, "VERSION")))
+	})
+}
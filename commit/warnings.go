@@ -0,0 +1,107 @@
+package commit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// The codes of the warnings. They don't change between the versions,
+// therefore the CI scripts can look for them.
+const (
+	// WarnNonConventional is a commit whose message doesn't follow the
+	// conventional commits, which is listed under Misc.
+	WarnNonConventional = "non-conventional-commit"
+	// WarnInvalidUTF8 is a commit message with invalid UTF-8 sequences,
+	// which are replaced.
+	WarnInvalidUTF8 = "invalid-utf8"
+	// WarnSlowGit is a git process that takes longer than the SlowThreshold.
+	WarnSlowGit = "slow-git"
+	// WarnPullLookup is a commit whose pull request couldn't be looked up.
+	WarnPullLookup = "pull-lookup-failed"
+	// WarnTagMismatch is a tag that points to different commits locally and
+	// on the remote, and one of them is trusted.
+	WarnTagMismatch = "tag-mismatch"
+	// WarnNotify is an announcement that couldn't be sent.
+	WarnNotify = "notify-failed"
+	// WarnLoginLookup is an author whose login couldn't be looked up.
+	WarnLoginLookup = "login-lookup-failed"
+	// WarnBranchExists is a release branch that is not created, because it
+	// is already on the remote at the commit of the tag.
+	WarnBranchExists = "branch-exists"
+	// WarnAheadOfTag is a range of the release that ends after the commit of
+	// the tag, e.g. at a HEAD that has moved on, therefore the notes include
+	// the commits that are not released.
+	WarnAheadOfTag = "range-ahead-of-tag"
+	// WarnForbiddenTerm is an occurrence of a forbidden term in the rendered
+	// notes.
+	WarnForbiddenTerm = "forbidden-term"
+	// WarnFeatureSkipped is a feature of the notes, e.g. the attribution,
+	// that is not rendered because the logs are not the ones of the commits.
+	WarnFeatureSkipped = "feature-skipped"
+	// WarnAuthInconclusive is a token whose permissions can't be checked
+	// before the release, e.g. a fine-grained token.
+	WarnAuthInconclusive = "auth-inconclusive"
+)
+
+// Warning is an issue that doesn't stop the release.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("[%s] %s", w.Code, w.Message)
+}
+
+// Warnings collects the warnings of a run. It is safe for concurrent use,
+// and a nil *Warnings drops them.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+// Add adds a warning with the code and the formatted message.
+func (w *Warnings) Add(code, format string, v ...any) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, Warning{Code: code, Message: fmt.Sprintf(format, v...)})
+}
+
+// List returns the warnings in the order they are added.
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.list...)
+}
+
+// Len returns the number of the warnings.
+func (w *Warnings) Len() int {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.list)
+}
+
+// Summary returns the warnings as a block of text, one warning on each line.
+// It returns an empty string if there are none.
+func (w *Warnings) Summary() string {
+	list := w.List()
+	if len(list) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(list)+1)
+	lines = append(lines, fmt.Sprintf("%d warning(s):", len(list)))
+	for _, warning := range list {
+		lines = append(lines, "  "+warning.String())
+	}
+	return strings.Join(lines, "\n")
+}
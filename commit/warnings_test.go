@@ -0,0 +1,69 @@
+package commit_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+	var nilWarnings *commit.Warnings
+	nilWarnings.Add(commit.WarnNotify, "dropped")
+	assert.Zero(t, nilWarnings.Len())
+	assert.Empty(t, nilWarnings.Summary())
+
+	w := &commit.Warnings{}
+	assert.Empty(t, w.Summary())
+	w.Add(commit.WarnNotify, "sending to %s", "slack")
+	w.Add(commit.WarnPullLookup, "no pull request")
+	want := []commit.Warning{
+		{Code: "notify-failed", Message: "sending to slack"},
+		{Code: "pull-lookup-failed", Message: "no pull request"},
+	}
+	assert.Equal(t, want, w.List())
+	assert.Equal(t, "2 warning(s):\n  [notify-failed] sending to slack\n  [pull-lookup-failed] no pull request", w.Summary())
+
+	w = &commit.Warnings{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.Add(commit.WarnSlowGit, "%d", i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 20, w.Len())
+}
+
+func TestGitWarnings(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	separator := "00000000000000000000000000000000000"
+	out := fmt.Sprintf("%saaa\x00arsham <arsham@github.com>\x0010\x00fix: bad \xff byte\n", separator)
+	runner := fakeRunner(func(args []string) (string, error) {
+		if args[0] == "rev-parse" {
+			return "", nil
+		}
+		time.Sleep(5 * time.Millisecond)
+		return out, nil
+	})
+	w := &commit.Warnings{}
+	g := commit.New(commit.WithRunner(runner), commit.WithWarnings(w))
+	g.SlowThreshold = time.Millisecond
+	_, err := g.Commits(ctx, "v0.1.0", "v0.2.0")
+	require.NoError(t, err)
+
+	codes := make(map[string]int)
+	for _, warning := range w.List() {
+		codes[warning.Code]++
+	}
+	assert.Equal(t, map[string]int{commit.WarnSlowGit: 1, commit.WarnInvalidUTF8: 1}, codes)
+}
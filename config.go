@@ -0,0 +1,550 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// forbiddenTerms returns the terms of the config file that the notes
+// shouldn't have, e.g.:
+//
+//	terms:
+//	  - pattern: (?i)\brecieve
+//	    replacement: receive
+//	  - pattern: \bProject Falcon\b
+//	    replacement: Acme Cloud
+//	  - pattern: (?i)\bjira-\d+
+//
+// The terms without a replacement are only reported.
+func forbiddenTerms(v *viper.Viper) (commit.Terms, error) {
+	var cfg []struct {
+		Pattern     string
+		Replacement string
+	}
+	if err := v.UnmarshalKey("terms", &cfg); err != nil {
+		return nil, fmt.Errorf("reading the terms of the config file: %w", err)
+	}
+	terms := make(commit.Terms, 0, len(cfg))
+	for _, c := range cfg {
+		t, err := commit.NewTerm(c.Pattern, c.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// footersConfig is the footers of the commits that are summarised in a
+// section of the notes, e.g.:
+//
+//	footers:
+//	  heading: Environments
+//	  keys: [Deploy-To]
+//
+// The heading defaults to "Footers".
+type footersConfig struct {
+	Heading string
+	Keys    []string
+}
+
+// footerSettings returns the footers of the config file.
+func footerSettings(v *viper.Viper) (footersConfig, error) {
+	var footers footersConfig
+	if err := v.UnmarshalKey("footers", &footers); err != nil {
+		return footersConfig{}, fmt.Errorf("reading the footers of the config file: %w", err)
+	}
+	return footers, nil
+}
+
+// groupConfig sets how the sections of the groups are rendered, e.g.:
+//
+//	groups:
+//	  chore:
+//	    collapse: true
+//	  dependencies:
+//	    collapse: true
+//
+// The names of the groups are case insensitive.
+type groupConfig struct {
+	Collapse bool
+}
+
+// collapsedGroups returns the names of the groups that are folded in a
+// details block by the groups of the config file. The groups of the classify
+// config are folded with their own collapse.
+func collapsedGroups(v *viper.Viper) ([]string, error) {
+	var groups map[string]groupConfig
+	// The unknown keys are errors, since a misspelled collapse would silently
+	// be ignored.
+	if v.IsSet("groups") {
+		sub := v.Sub("groups")
+		if sub == nil {
+			return nil, errors.New("reading the groups of the config file: expected the settings of each group")
+		}
+		if err := sub.UnmarshalExact(&groups); err != nil {
+			return nil, fmt.Errorf("reading the groups of the config file: %w", err)
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for name, cfg := range groups {
+		if cfg.Collapse {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// excludedCommits returns the commits of the exclude-sha flags, or the ones of
+// the config file if the flag is not set.
+func excludedCommits() []string {
+	return viper.GetStringSlice("exclude-sha")
+}
+
+// extraRanges returns the ranges of the extra-range flags, or the ones of the
+// extra-ranges of the config file if the flag is not set.
+func extraRanges() ([]commit.CommitRange, error) {
+	specs := viper.GetStringSlice("extra-ranges")
+	ranges := make([]commit.CommitRange, 0, len(specs))
+	for _, s := range specs {
+		r, err := commit.ParseCommitRange(s)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// versionFiles returns the version files of the version-file flags, or the
+// ones of the version-files of the config file if the flag is not set, e.g.:
+//
+//	version-files:
+//	  - package.json
+//	  - main.go=const Version = "(.+)"
+func versionFiles() ([]commit.VersionFile, error) {
+	specs := viper.GetStringSlice("version-files")
+	files := make([]commit.VersionFile, 0, len(specs))
+	for _, s := range specs {
+		f, err := commit.ParseVersionFile(s)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// authorLogins returns the GitHub logins of the authors, keyed by their emails,
+// from the logins-file and the logins of the config file, e.g.:
+//
+//	logins:
+//	  jsmith: [j.smith@example.com, john@old.example.com]
+//
+// The config file wins if both have an email. A missing logins-file is
+// ignored unless the flag is set.
+func authorLogins(cmd *cobra.Command) (commit.Logins, error) {
+	logins := make(commit.Logins)
+	f, err := os.Open(loginsFile)
+	switch {
+	case os.IsNotExist(err) && !cmd.Flags().Changed("logins-file"):
+	case err != nil:
+		return nil, err
+	default:
+		// nolint:errcheck // it's only read.
+		defer f.Close()
+		if logins, err = commit.ParseLogins(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", loginsFile, err)
+		}
+	}
+	for login, emails := range viper.GetStringMapStringSlice("logins") {
+		logins.Add(login, emails...)
+	}
+	return logins, nil
+}
+
+// rangeCache returns the cache of the commits and their pull requests in the
+// cache-dir, or nil if it's not set. The version is in the keys, therefore an
+// upgrade doesn't reuse the commits read by an older one.
+func rangeCache() *commit.RangeCache {
+	if cacheDir == "" {
+		return nil
+	}
+	return &commit.RangeCache{
+		Dir:     filepath.Join(cacheDir, "changelogs"),
+		Options: version + " " + currentSha,
+		Refresh: noCache,
+	}
+}
+
+// maxSubject returns the MaxSubject of the max-subject flag.
+func maxSubject() int {
+	if maxSubj <= 0 {
+		return -1
+	}
+	return maxSubj
+}
+
+// hostURLs returns the addresses of the pages of the self-hosted instances
+// from the hosts of the config file, e.g.:
+//
+//	hosts:
+//	  - host: git.example.com
+//	    commit: "{repo}/-/commit/{sha}"
+//	    compare: "{repo}/-/compare/{from}...{to}"
+//	    issue: "{repo}/-/issues/{number}"
+//	    pull: "{repo}/-/merge_requests/{number}"
+func hostURLs(v *viper.Viper) (map[string]commit.URLPatterns, error) {
+	var hosts []struct {
+		Host               string
+		commit.URLPatterns `mapstructure:",squash"`
+	}
+	if err := v.UnmarshalKey("hosts", &hosts); err != nil {
+		return nil, fmt.Errorf("reading the hosts of the config file: %w", err)
+	}
+	urls := make(map[string]commit.URLPatterns, len(hosts))
+	for _, h := range hosts {
+		if h.Host == "" {
+			return nil, errors.New("a host of the config file has no name")
+		}
+		urls[strings.ToLower(h.Host)] = h.URLPatterns
+	}
+	return urls, nil
+}
+
+// channelConfig is a release channel of the config file, e.g.:
+//
+//	channels:
+//	  stable:
+//	    pattern: '^v\d+\.\d+\.\d+$'
+//	  beta:
+//	    pattern: '^v\d+\.\d+\.\d+-beta\.\d+$'
+//	    prerelease: true
+//	    since: stable
+//	    template: "{{.Notes}}\n\n> This is a beta of the next release."
+//	  nightly:
+//	    pattern: '^nightly-\d{8}$'
+//	    prerelease: true
+//
+// The since channel makes the notes cumulative since its last tag.
+type channelConfig struct {
+	Pattern    string
+	Prerelease bool
+	Since      string
+	Template   string
+}
+
+// releaseChannel returns the channel of the channel flag from the config
+// file, or nil if the flag is not set.
+func releaseChannel() (*commit.Channel, error) {
+	if channel == "" {
+		return nil, nil
+	}
+	return loadChannel(viper.GetViper(), channel)
+}
+
+// loadChannel returns the channel of the name from the channels of the config
+// file.
+func loadChannel(v *viper.Viper, name string) (*commit.Channel, error) {
+	var channels map[string]channelConfig
+	if err := v.UnmarshalKey("channels", &channels); err != nil {
+		return nil, fmt.Errorf("reading the channels of the config file: %w", err)
+	}
+	key := strings.ToLower(name)
+	cfg, ok := channels[key]
+	if !ok {
+		return nil, fmt.Errorf("channel %q is not in the channels of the config file", name)
+	}
+	ch, err := commit.NewChannel(key, cfg.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	ch.Prerelease, ch.Template = cfg.Prerelease, cfg.Template
+	if cfg.Since == "" {
+		return ch, nil
+	}
+	since, ok := channels[strings.ToLower(cfg.Since)]
+	if !ok {
+		return nil, fmt.Errorf("channel %q of the since of channel %s is not in the config file", cfg.Since, key)
+	}
+	ch.Since, err = commit.NewChannel(strings.ToLower(cfg.Since), since.Pattern)
+	return ch, err
+}
+
+// checksConfig is the checks of the config file that should pass on the
+// commit of the tag before the release, e.g.:
+//
+//	checks:
+//	  required: [build, lint, "ci/circleci: test"]
+//	  wait: 10m
+//	  interval: 30s
+//
+// Without the required checks, all the checks of the commit should pass.
+type checksConfig struct {
+	Required []string
+	Wait     time.Duration
+	Interval time.Duration
+}
+
+// checksOptions returns the checks that should pass before the release, or
+// nil if they are not asked for with the checks of the config file or the
+// require-checks flag, or are skipped with the skip-checks flag.
+func checksOptions(cmd *cobra.Command) (*commit.ChecksOptions, error) {
+	if skipChecks || (!reqChecks && !viper.IsSet("checks")) {
+		return nil, nil
+	}
+	var cfg checksConfig
+	if err := viper.UnmarshalKey("checks", &cfg); err != nil {
+		return nil, fmt.Errorf("reading the checks of the config file: %w", err)
+	}
+	if cmd.Flags().Changed("checks-wait") {
+		cfg.Wait = checksWait
+	}
+	return &commit.ChecksOptions{
+		Required: cfg.Required,
+		Wait:     cfg.Wait,
+		Interval: cfg.Interval,
+	}, nil
+}
+
+// classifyConfig selects the classifiers of the commits, e.g.:
+//
+//	classify:
+//	  use: [patterns, labels, conventional]
+//	  patterns:
+//	    - pattern: "^\\[FEATURE\\]"
+//	      group: Feature
+//	  labels:
+//	    bug: Fix
+//	  repos:
+//	    - repo: owner/name
+//	      use: [labels]
+//
+// The first classifier that matches a commit wins. The settings of the repos
+// replace the others for the repositories they name. The groups define the
+// sections instead of the conventional ones, e.g.:
+//
+//	classify:
+//	  groups:
+//	    - title: Security
+//	      scopes: [security, auth]
+//	    - title: Platform
+//	      types: [infra]
+//	      order: 1
+//	    - title: Chores
+//	      types: [chore]
+//	      collapse: true
+//	  default: Other
+//
+// The commits that no classifier matches are in the default group, Misc if it
+// is empty, or left out with hide-unmatched. The dirs classifier groups the
+// commits by the directories they change, e.g.:
+//
+//	classify:
+//	  use: [conventional, dirs]
+//	  dirs:
+//	    depth: 2
+//	    multiple: Cross-cutting
+type classifyConfig struct {
+	Use      []string
+	Patterns []struct {
+		Pattern string
+		Group   string
+	}
+	Labels        map[string]string
+	Groups        []groupDefinition
+	Dirs          dirsConfig
+	Default       string
+	HideUnmatched bool `mapstructure:"hide-unmatched"`
+}
+
+// groupDefinition is a group of the classify config. The commits that match
+// all of its types, scopes and subjects are in its section. The sections are
+// sorted by their order, and then by their position. The section is folded in
+// a details block with the collapse, as with the groups of the config file.
+type groupDefinition struct {
+	Title    string
+	Types    []string
+	Scopes   []string
+	Subjects []string
+	Order    int
+	Collapse bool
+}
+
+// dirsConfig sets the directories of the dirs classifier. The depth is the
+// number of the leading directories of the groups, 1 if it's zero, and the
+// multiple is the group of the commits that change more than one directory.
+type dirsConfig struct {
+	Depth    int
+	Multiple string
+}
+
+// classification returns the classifyConfig of the repository of the remote
+// from the config file.
+func classification(v *viper.Viper, remote commit.RemoteInfo) (classifyConfig, error) {
+	var cfg struct {
+		classifyConfig `mapstructure:",squash"`
+		Repos          []struct {
+			Repo           string
+			classifyConfig `mapstructure:",squash"`
+		}
+	}
+	// The unknown keys are errors, since a misspelled rule would silently
+	// match all the commits.
+	if sub := v.Sub("classify"); sub != nil {
+		if err := sub.UnmarshalExact(&cfg); err != nil {
+			return classifyConfig{}, fmt.Errorf("reading the classifiers of the config file: %w", err)
+		}
+	}
+	slug := remote.Owner + "/" + remote.Name
+	for _, r := range cfg.Repos {
+		if strings.EqualFold(r.Repo, slug) {
+			return r.classifyConfig, nil
+		}
+	}
+	return cfg.classifyConfig, nil
+}
+
+// checkClassify returns an error if the classify config of any repository is
+// invalid, therefore it is reported when the config file is loaded rather than
+// when the notes are rendered.
+func checkClassify(v *viper.Viper) error {
+	var repos []struct{ Repo string }
+	if err := v.UnmarshalKey("classify.repos", &repos); err != nil {
+		return fmt.Errorf("reading the classifiers of the config file: %w", err)
+	}
+	remotes := []commit.RemoteInfo{{}}
+	for _, r := range repos {
+		owner, name, _ := strings.Cut(r.Repo, "/")
+		remotes = append(remotes, commit.RemoteInfo{Owner: owner, Name: name})
+	}
+	for _, remote := range remotes {
+		cfg, err := classification(v, remote)
+		if err != nil {
+			return err
+		}
+		if _, err := cfg.classifier(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uses returns true if the classifier of the name is selected.
+func (c classifyConfig) uses(name string) bool {
+	for _, u := range c.Use {
+		if u == name {
+			return true
+		}
+	}
+	return false
+}
+
+// groupRules returns the rules of the groups in order.
+func (c classifyConfig) groupRules() (commit.GroupRules, error) {
+	rules := make(commit.GroupRules, 0, len(c.Groups))
+	for i, g := range c.Groups {
+		r, err := commit.NewGroupRule(g.Title, g.Types, g.Scopes, g.Subjects, g.Order)
+		if err != nil {
+			return nil, fmt.Errorf("group %d of the config file: %w", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// classifier returns the classifiers in the order of their use. The groups are
+// used if none is selected but there are groups. The labels of the pull
+// requests are read from the commits. It returns nil if none is selected,
+// therefore the commits are grouped by their conventional types.
+func (c classifyConfig) classifier(commits []commit.Commit) (commit.Classifier, error) {
+	use := c.Use
+	if len(use) == 0 && len(c.Groups) > 0 {
+		use = []string{"groups"}
+	}
+	if len(use) == 0 {
+		return nil, nil
+	}
+	cs := make(commit.Classifiers, 0, len(use))
+	for _, name := range use {
+		switch name {
+		case "conventional":
+			cs = append(cs, commit.Conventional{})
+		case "patterns":
+			rules := make(commit.PatternClassifier, 0, len(c.Patterns))
+			for _, p := range c.Patterns {
+				r, err := commit.ParsePatternRule(p.Pattern, p.Group)
+				if err != nil {
+					return nil, err
+				}
+				rules = append(rules, r)
+			}
+			cs = append(cs, rules)
+		case "labels":
+			cs = append(cs, commit.NewLabelClassifier(c.Labels, commits))
+		case "groups":
+			if len(c.Groups) == 0 {
+				return nil, errors.New("the groups classifier is used without any groups")
+			}
+			rules, err := c.groupRules()
+			if err != nil {
+				return nil, err
+			}
+			cs = append(cs, rules)
+		case "dirs":
+			if c.Dirs.Depth < 0 {
+				return nil, fmt.Errorf("the depth of the dirs classifier is negative: %d", c.Dirs.Depth)
+			}
+			cs = append(cs, commit.DirClassifier{Depth: c.Dirs.Depth, Multiple: c.Dirs.Multiple})
+		default:
+			return nil, fmt.Errorf("unknown classifier %q, use conventional, patterns, labels, groups or dirs", name)
+		}
+	}
+	return cs, nil
+}
+
+// classifyOptions returns the options of the classifiers, the order of the
+// groups and the section of the unmatched commits.
+func (c classifyConfig) classifyOptions(commits []commit.Commit) ([]commit.RenderOption, error) {
+	cls, err := c.classifier(commits)
+	if err != nil || cls == nil {
+		return nil, err
+	}
+	opts := []commit.RenderOption{commit.WithClassifier(cls)}
+	if c.Default != "" {
+		opts = append(opts, commit.WithUnmatched(c.Default))
+	}
+	if c.HideUnmatched {
+		opts = append(opts, commit.WithHiddenUnmatched())
+	}
+	var collapsed []string
+	for _, g := range c.Groups {
+		if g.Collapse {
+			collapsed = append(collapsed, g.Title)
+		}
+	}
+	if len(collapsed) > 0 {
+		opts = append(opts, commit.WithCollapsed(collapsed...))
+	}
+	// The group-order flag wins over the order of the groups.
+	if len(c.Groups) > 0 && len(groupOrder) == 0 {
+		rules, err := c.groupRules()
+		if err != nil {
+			return nil, err
+		}
+		order := rules.Order()
+		if c.Default != "" {
+			order = append(order, c.Default)
+		}
+		opts = append(opts, commit.WithGroupOrder(order...))
+	}
+	return opts, nil
+}
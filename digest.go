@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// slugRe matches the owner/repo of a GitHub repository.
+var slugRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+var digestCmd = &cobra.Command{
+	Use:   "digest REPO...",
+	Short: "Print the changes of several repositories in one document",
+	Long: `Print the changes of several repositories in one document, with a section for
+each repository. A repository is a local path, an owner/repo on GitHub or the
+url of a remote. The remote ones are cloned into a temporary folder, which is
+removed afterwards.
+
+The changes are the commits since the latest tag of each repository, or the
+commits since --since, e.g. 7d or 2024-06-01. The repositories without changes
+are listed under "No changes".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		order, err := commit.ParseCommitOrder(commitOrder)
+		if err != nil {
+			return err
+		}
+		var from time.Time
+		if since != "" {
+			from, err = commit.ParseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		tmp, err := os.MkdirTemp("", "gitrelease-digest-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+
+		sections := make([]commit.DigestSection, len(args))
+		eg, ctx := errgroup.WithContext(cmd.Context())
+		eg.SetLimit(4)
+		for i, arg := range args {
+			i, arg := i, arg
+			eg.Go(func() error {
+				g := &commit.Git{
+					AnnotatedOnly: annotated,
+					MaxSubject:    maxSubject(),
+					Order:         order,
+					Offline:       offline,
+					ReplaceRefs:   replaceRefs,
+				}
+				if debug {
+					g.Logger = log.New(os.Stderr, "debug: ", 0)
+				}
+				reportWarnings(g)
+				var err error
+				sections[i], err = digestSection(ctx, g, arg, filepath.Join(tmp, strconv.Itoa(i)), from)
+				return err
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return err
+		}
+		opts, err := renderOptions()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(commit.RenderDigest(sections, opts...))
+		return err
+	},
+}
+
+// digestSection returns the changes of the repository of the arg since the
+// date, or since its latest tag if the date is zero. The remote repositories
+// are cloned into the dir.
+func digestSection(ctx context.Context, g *commit.Git, arg, dir string, from time.Time) (commit.DigestSection, error) {
+	s := commit.DigestSection{Name: arg}
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		if abs, err := filepath.Abs(arg); err == nil {
+			s.Name = filepath.Base(abs)
+		}
+		g.Dir = arg
+	} else {
+		url := arg
+		if slugRe.MatchString(arg) {
+			url = "https://github.com/" + arg + ".git"
+		} else {
+			s.Name = strings.TrimSuffix(path.Base(strings.TrimRight(arg, "/")), ".git")
+		}
+		err := g.Clone(ctx, url, dir, from)
+		if errors.Is(err, commit.ErrNoCommitsSince) {
+			return s, nil
+		}
+		if err != nil {
+			return s, err
+		}
+		g.Dir = dir
+	}
+
+	if from.IsZero() {
+		var err error
+		s.Since, s.Logs, err = g.Unreleased(ctx)
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		return s, nil
+	}
+	commits, err := g.CommitsSince(ctx, from, nil)
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", s.Name, err)
+	}
+	s.Since = from.Format(time.RFC3339)
+	for _, c := range commits {
+		s.Logs = append(s.Logs, c.Normalize(maxSubject()).Message)
+	}
+	return s, nil
+}
+
+func init() {
+	rootCmd.AddCommand(digestCmd)
+}
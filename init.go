@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// initConfigFile and initTemplateFile are the files init writes.
+	initConfigFile   = ".gitrelease.yaml"
+	initTemplateFile = ".gitrelease.tmpl"
+)
+
+var (
+	withTemplate bool
+	forceInit    bool
+
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Write a commented config file with the settings detected in the repository",
+		Long: `Write a commented .gitrelease.yaml with the settings detected in the
+repository: the host of the remote, whether the commits follow the conventional
+commits, and the style of the existing CHANGELOG.md. With --with-template, the
+built-in template of the notes is written to .gitrelease.tmpl and used by the
+config. The existing files are only replaced with --force. Use --print to only
+print the config.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			g, err := newGit()
+			if err != nil {
+				return err
+			}
+			p, err := g.Inspect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			cfg := scaffoldConfig(p, withTemplate)
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(cfg)); err != nil {
+				return fmt.Errorf("the generated config is invalid: %w", err)
+			}
+			if err := validateConfig(v); err != nil {
+				return fmt.Errorf("the generated config is invalid: %w", err)
+			}
+			if printMode {
+				fmt.Print(cfg)
+				return nil
+			}
+			files := map[string]string{initConfigFile: cfg}
+			if withTemplate {
+				files[initTemplateFile] = commit.DefaultTemplate
+			}
+			for name := range files {
+				if _, err := os.Stat(name); err == nil && !forceInit {
+					return fmt.Errorf("%s already exists, use --force to replace it", name)
+				} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+			for _, name := range []string{initConfigFile, initTemplateFile} {
+				content, ok := files[name]
+				if !ok {
+					continue
+				}
+				if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+					return err
+				}
+				fmt.Printf("wrote %s\n", name)
+			}
+			return nil
+		},
+	}
+)
+
+// scaffoldConfig returns the content of the config file of the repository of
+// the profile. The settings that are not detected are commented out.
+func scaffoldConfig(p *commit.Profile, template bool) string {
+	buf := &strings.Builder{}
+	buf.WriteString(`# The settings of gitrelease, written by "gitrelease init". The flags of the
+# command line take precedence over them.
+`)
+
+	buf.WriteString("\n")
+	switch {
+	case p.Commits == 0:
+		buf.WriteString(`# The repository has no commits yet. The commits are grouped by their
+# conventional commit types, e.g. "feat: add x" is a Feature.
+classify:
+  use: [conventional]
+`)
+	case p.IsConventional():
+		fmt.Fprintf(buf, `# %d of the latest %d commits follow the conventional commits, therefore
+# they are grouped by their types, e.g. "feat: add x" is a Feature.
+classify:
+  use: [conventional]
+`, p.Conventional, p.Commits)
+	default:
+		fmt.Fprintf(buf, `# Only %d of the latest %d commits follow the conventional commits, therefore
+# the others are grouped by the patterns of their subjects, and the rest are
+# in Misc.
+classify:
+  use: [conventional, patterns]
+  patterns:
+    - pattern: "(?i)^(add|implement|support)\\b"
+      group: Feature
+    - pattern: "(?i)^(fix|resolve)"
+      group: Fix
+    - pattern: "(?i)^(update|bump|upgrade)\\b"
+      group: Upgrades
+`, p.Conventional, p.Commits)
+	}
+
+	buf.WriteString("\n")
+	if p.KnownHost() {
+		known := "The repository has no remote yet."
+		if p.Remote.Host != "" {
+			known = fmt.Sprintf("The pages of %s are known.", p.Remote.Host)
+		}
+		fmt.Fprintf(buf, `# %s The self-hosted instances of the other hosts
+# need the addresses of their pages, e.g.:
+# hosts:
+#   - host: git.example.com
+#     commit: "{repo}/commit/{sha}"
+#     compare: "{repo}/compare/{from}...{to}"
+#     issue: "{repo}/issues/{number}"
+#     pull: "{repo}/pulls/{number}"
+`, known)
+	} else {
+		fmt.Fprintf(buf, `# %s is neither GitHub nor GitLab, therefore the notes link to the
+# pages of its commits, comparisons, issues and pull requests with these
+# patterns. Check them against your instance.
+hosts:
+  - host: %s
+    commit: "{repo}/commit/{sha}"
+    compare: "{repo}/compare/{from}...{to}"
+    issue: "{repo}/issues/{number}"
+    pull: "{repo}/pulls/{number}"
+`, p.Remote.Host, p.Remote.Host)
+	}
+
+	buf.WriteString("\n")
+	buf.WriteString(`# The sections that are folded in a details block.
+groups:
+  chore:
+    collapse: true
+  dependencies:
+    collapse: true
+`)
+
+	if headings := changelogHeadings(p.Changelog); headings != "" {
+		buf.WriteString("\n")
+		fmt.Fprintf(buf, `# The CHANGELOG.md is in the %s style, therefore these headings keep
+# the notes in the same style with --lang en.
+locales:
+  en:
+    headings:
+%s`, p.Changelog, headings)
+	}
+
+	buf.WriteString("\n")
+	if template {
+		fmt.Fprintf(buf, `# The notes are rendered with this template, see the file for its values.
+template: %s
+`, initTemplateFile)
+	} else {
+		fmt.Fprintf(buf, `# The notes can be rendered with a template, "gitrelease init --with-template"
+# writes the built-in one:
+# template: %s
+`, initTemplateFile)
+	}
+	return buf.String()
+}
+
+// changelogHeadings returns the headings of the locale of the style, or an
+// empty string if the style has none.
+func changelogHeadings(style commit.ChangelogStyle) string {
+	switch style {
+	case commit.ChangelogKeepAChangelog:
+		return `      feature: Added
+      enhancements: Changed
+      fix: Fixed
+`
+	case commit.ChangelogConventional:
+		return `      feature: Features
+      fix: Bug Fixes
+`
+	}
+	return ""
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&withTemplate, "with-template", false, "also write the built-in template of the notes to "+initTemplateFile)
+	initCmd.Flags().BoolVar(&forceInit, "force", false, "replace the existing files")
+	rootCmd.AddCommand(initCmd)
+}
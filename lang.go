@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/viper"
+)
+
+// initConfig reads the config file of the config flag, or the .gitrelease
+// file of the current folder if it exists. The flags that are bound to viper
+// can be set in it, as well as the locales of the notes.
+func initConfig() {
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName(".gitrelease")
+		viper.AddConfigPath(".")
+	}
+	err := viper.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && (configFile != "" || !errors.As(err, &notFound)) {
+		fmt.Fprintf(os.Stderr, "reading the config file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkClassify(viper.GetViper()); err != nil {
+		fmt.Fprintf(os.Stderr, "reading the config file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// language is the name and the locale of a language of the notes.
+type language struct {
+	code   string
+	name   string
+	locale commit.Locale
+}
+
+// loadLanguage returns the language from the locales of the config file. The
+// English is built in, therefore "en" doesn't need to be configured.
+//
+//	locales:
+//	  de:
+//	    name: Deutsch
+//	    date-format: 02.01.2006
+//	    breaking: BREAKING CHANGE
+//	    full-changelog: Alle Änderungen
+//	    no-changes: Keine Änderungen seit %s.
+//	    internal-changes: "%d interne Änderungen."
+//	    more-changes: "…und %d weitere Änderungen"
+//	    headings:
+//	      feature: Neue Funktionen
+//	      fix: Fehlerbehebungen
+func loadLanguage(v *viper.Viper, code string) (language, error) {
+	l := language{code: code, name: code}
+	sub := v.Sub("locales." + strings.ToLower(code))
+	if sub == nil {
+		if strings.EqualFold(code, "en") {
+			l.name = "English"
+			return l, nil
+		}
+		return l, fmt.Errorf("language %q is not in the locales of the config file", code)
+	}
+	if name := sub.GetString("name"); name != "" {
+		l.name = name
+	}
+	l.locale = commit.Locale{
+		Headings:        sub.GetStringMapString("headings"),
+		DateFormat:      sub.GetString("date-format"),
+		Breaking:        sub.GetString("breaking"),
+		FullChangelog:   sub.GetString("full-changelog"),
+		ExtraRanges:     sub.GetString("extra-ranges"),
+		NoChanges:       sub.GetString("no-changes"),
+		InternalChanges: sub.GetString("internal-changes"),
+		MoreChanges:     sub.GetString("more-changes"),
+		FirstRelease:    sub.GetString("first-release"),
+		ShowingLatest:   sub.GetString("showing-latest"),
+		Contents:        sub.GetString("contents"),
+		By:              sub.GetString("by"),
+		Details:         sub.GetString("details"),
+		ReadMore:        sub.GetString("read-more"),
+		LeadTime:        sub.GetString("lead-time"),
+		MedianAge:       sub.GetString("median-age"),
+		ActiveDays:      sub.GetString("active-days"),
+		Backports:       sub.GetString("backports"),
+	}
+	if err := l.locale.Validate(); err != nil {
+		return l, fmt.Errorf("locale %q: %w", code, err)
+	}
+	return l, nil
+}
+
+// languages returns the languages of the lang flags, the primary first. It
+// returns nil if the flag is not set.
+func languages() ([]language, error) {
+	ret := make([]language, 0, len(langs))
+	for _, code := range langs {
+		l, err := loadLanguage(viper.GetViper(), code)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, l)
+	}
+	return ret, nil
+}
+
+// localeOption returns the option of the locale of the primary language, or
+// nil if the lang flag is not set.
+func localeOption() (commit.RenderOption, error) {
+	if len(langs) == 0 {
+		return nil, nil
+	}
+	l, err := loadLanguage(viper.GetViper(), langs[0])
+	if err != nil {
+		return nil, err
+	}
+	return commit.WithLocale(l.locale), nil
+}
+
+// translateNotes renders the notes in the other languages than the primary
+// one. With the sections lang-output, they are added to the desc under a
+// heading for each language. Otherwise they are written into the lang-dir and
+// returned as assets to be uploaded if lang-assets is set. The files are only
+// written when the release is published, not when its notes are printed or
+// compared.
+func translateNotes(ctx context.Context, g *commit.Git, info *commit.ReleaseInfo, desc string) (string, []commit.Asset, error) {
+	all, err := languages()
+	if err != nil || len(all) < 2 {
+		return desc, nil, err
+	}
+	switch langOutput {
+	case "files", "sections":
+	default:
+		return "", nil, fmt.Errorf("unknown lang output %q, use files or sections", langOutput)
+	}
+	if langOutput == "files" && (printMode || diffMode || updateDiff) {
+		return desc, nil, nil
+	}
+	sections := []string{fmt.Sprintf("## %s\n\n%s", all[0].name, desc)}
+	var files []commit.Asset
+	for _, l := range all[1:] {
+		notes, err := releaseNotes(ctx, g, info, commit.WithLocale(l.locale))
+		if err != nil {
+			return "", nil, err
+		}
+		if langOutput == "sections" {
+			sections = append(sections, fmt.Sprintf("## %s\n\n%s", l.name, notes))
+			continue
+		}
+		name := fmt.Sprintf("RELEASE_NOTES.%s.md", l.code)
+		path := filepath.Join(langDir, name)
+		// The sections are checked with the rest of the notes.
+		if notes, err = checkTerms(path, notes); err != nil {
+			return "", nil, err
+		}
+		if err := os.WriteFile(path, []byte(notes+"\n"), 0o600); err != nil {
+			return "", nil, err
+		}
+		if langAssets {
+			files = append(files, commit.Asset{Path: path, Name: name})
+		}
+	}
+	if langOutput == "sections" {
+		return strings.Join(sections, "\n\n"), nil, nil
+	}
+	return desc, files, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintAllow     string
+	lintThreshold int
+
+	lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Report the commits of the release that don't follow conventional commits",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var allow *regexp.Regexp
+			if lintAllow != "" {
+				var err error
+				allow, err = regexp.Compile(lintAllow)
+				if err != nil {
+					return fmt.Errorf("parsing the allow regexp: %w", err)
+				}
+			}
+			g, err := newGit()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			previous, err := g.PreviousTag(ctx, tag)
+			if err != nil && !errors.Is(err, commit.ErrNoTag) {
+				return err
+			}
+			issues, err := g.Lint(ctx, previous, tag, allow)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, c := range issues {
+				sha := c.SHA
+				if len(sha) > 7 {
+					sha = sha[:7]
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", sha, c.Author, c.Subject())
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if len(issues) > lintThreshold {
+				return fmt.Errorf("%d commit(s) don't follow conventional commits, the threshold is %d", len(issues), lintThreshold)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	lintCmd.Flags().StringVar(&lintAllow, "allow", "^Merge ", "ignore the commits whose subject matches this regexp")
+	lintCmd.Flags().IntVar(&lintThreshold, "threshold", 0, "number of non-conforming commits to tolerate before failing")
+	rootCmd.AddCommand(lintCmd)
+}
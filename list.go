@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listLimit     int
+	includeDrafts bool
+
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the releases of the repository",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			g, token, user, repo, err := githubRepo(ctx)
+			if err != nil {
+				return err
+			}
+			opts := commit.ListOptions{
+				Limit:         listLimit,
+				IncludeDrafts: includeDrafts,
+			}
+			releases, err := g.ListReleases(ctx, token, user, repo, opts)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(releases)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TAG\tNAME\tPUBLISHED\tFLAGS\tASSETS")
+			for _, r := range releases {
+				published := "-"
+				if r.PublishedAt != nil {
+					published = r.PublishedAt.Format("2006-01-02")
+				}
+				var flags []string
+				if r.Draft {
+					flags = append(flags, "draft")
+				}
+				if r.Prerelease {
+					flags = append(flags, "prerelease")
+				}
+				if len(flags) == 0 {
+					flags = append(flags, "-")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", r.TagName, r.Name, published, strings.Join(flags, ","), len(r.Assets))
+			}
+			return w.Flush()
+		},
+	}
+
+	showCmd = &cobra.Command{
+		Use:   "show <tag>",
+		Short: "Print the notes and the assets of a release",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			g, token, user, repo, err := githubRepo(ctx)
+			if err != nil {
+				return err
+			}
+			r, err := g.GetReleaseByTag(ctx, token, user, repo, args[0])
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(r)
+			}
+			fmt.Println(r.Body)
+			if len(r.Assets) > 0 {
+				fmt.Println()
+				for _, a := range r.Assets {
+					fmt.Println(a.BrowserDownloadURL)
+				}
+			}
+			return nil
+		},
+	}
+)
+
+// githubRepo returns a Git for the current directory and the GitHub
+// credentials and repository it is released on.
+func githubRepo(ctx context.Context) (g *commit.Git, token, user, repo string, err error) {
+	if g, err = newGit(); err != nil {
+		return nil, "", "", "", err
+	}
+	token, err = resolveAuth(g)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	r, err := g.RemoteInfo(ctx)
+	if err != nil {
+		return nil, "", "", "", fmt.Errorf("can't get repo name: %w", err)
+	}
+	useRepo(g, r)
+	return g, token, r.Owner, r.Name, nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func init() {
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "maximum number of releases to list, 0 for all")
+	listCmd.Flags().BoolVar(&includeDrafts, "include-drafts", false, "include the draft releases, the token needs push access")
+	listCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the releases as JSON")
+	showCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the release as JSON")
+	rootCmd.AddCommand(listCmd, showCmd)
+}
@@ -1,84 +1,331 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/arsham/gitrelease/commit"
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	tag        string
-	printMode  bool
-	remote     string
-	version    = "development"
-	currentSha = "N/A"
+	tag         string
+	printMode   bool
+	debug       bool
+	rangeMode   string
+	subItems    bool
+	maxItems    int
+	toc         bool
+	tocMin      int
+	bodies      string
+	maxBody     int
+	reupload    bool
+	noPullAPI   bool
+	offline     bool
+	slowGit     time.Duration
+	strict      bool
+	langs       []string
+	configFile  string
+	commitOrder string
+	groupOrder  []string
+	dateSource  string
+	breaking    string
+	failLong    bool
+	direct      bool
+	cacheDir    string
+	sanitize    string
+	mentions    []string
+	since       string
+	annotated   bool
+	sinceStable bool
+	metricsFile string
+	traceGit    bool
+	traceFile   string
+	replaceRefs bool
+	gitDir      string
+	workTree    string
+	compare     bool
+	budget      int
+	stats       bool
+	notesRef    string
+	backports   bool
+	attribution bool
+	skipAuth    bool
+	secBudget   int
+	depsMode    string
+	depsAll     bool
+	maxSubj     int
+	remote      string
+	sizeHints   bool
+	sizeLimits  string
+	version     = "development"
+	currentSha  = "N/A"
+
+	noteExcluded bool
+	contributors bool
 
 	rootCmd = &cobra.Command{
 		Use:   "gitrelease",
 		Short: "Release commit information of a tag to github",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) > 0 && args[0] == "version" {
-				fmt.Printf("gitrelease version %s (%s)\n", version, currentSha)
-				return nil
-			}
-
 			ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 			defer cancel()
-			token := os.Getenv("GITHUB_TOKEN")
-			if token == "" {
-				return errors.New("please export GITHUB_TOKEN")
+			if offline {
+				if diffMode || updateDiff {
+					return errors.New("--diff and --update-if-changed need the published release, and can't be used with --offline")
+				}
+				// Nothing is published, the notes are only printed.
+				printMode = true
 			}
-			g := &commit.Git{
-				Remote: remote,
+			notice, err := pullNotices()
+			if err != nil {
+				return err
 			}
-
-			user, repo, err := g.RepoInfo(ctx)
+			g, err := releaseGit()
 			if err != nil {
-				return errors.Wrap(err, "can't get repo name")
+				return err
 			}
-
-			tag1, err := g.PreviousTag(ctx, tag)
+			if since != "" {
+				return printSince(ctx, g)
+			}
+			token, err := resolveAuth(g)
 			if err != nil {
-				return errors.Wrap(err, "getting previous tag")
+				return err
 			}
+			g.Progress = newProgress(os.Stderr, quiet)
 
-			logs, err := g.Commits(ctx, tag1, tag)
+			notes := &changelog{g: g, token: token, cmd: cmd, cleanup: func() {}}
+			defer func() { notes.cleanup() }()
+			cfg, removeSigs, err := releaseConfig(cmd, g, token, notes)
 			if err != nil {
 				return err
 			}
-			desc := commit.ParseGroups(logs)
-			if tag == "@" {
-				tag, err = g.LatestTag(ctx)
-				if err != nil {
-					return err
-				}
-			}
-
-			if printMode {
-				_, err := fmt.Println(desc)
+			defer removeSigs()
+			res, err := runRelease(ctx, cfg)
+			if err = releaseError(res, err); err != nil {
 				return err
 			}
+			return finishRelease(ctx, g, token, res, notes, notice)
+		},
+	}
 
-			return g.Release(ctx, token, user, repo, tag, desc)
+	versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print binary version information",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			fmt.Printf("gitrelease version %s (%s)\n", version, currentSha)
 		},
 	}
 )
 
+// newGit returns the Git of the shared flags of all the commands, with the
+// logger of the debug flag and the warnings of the run.
+func newGit() (*commit.Git, error) {
+	mode, err := commit.ParseRangeMode(rangeMode)
+	if err != nil {
+		return nil, err
+	}
+	order, err := commit.ParseCommitOrder(commitOrder)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := hostURLs(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+	g := &commit.Git{
+		Remote:          remote,
+		RangeMode:       mode,
+		CacheDir:        cacheDir,
+		AnnotatedOnly:   annotated,
+		SinceLastStable: sinceStable,
+		MaxSubject:      maxSubject(),
+		Order:           order,
+		HostURLs:        urls,
+		Exclude:         excludedCommits(),
+		NotesRef:        notesRef,
+		Offline:         offline,
+		ReplaceRefs:     replaceRefs,
+		GitDir:          gitDir,
+		WorkTree:        workTree,
+	}
+	if debug {
+		g.Logger = log.New(os.Stderr, "debug: ", 0)
+	}
+	reportWarnings(g)
+	return g, nil
+}
+
+// exitError makes the program exit with the code.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
+// emptyCode is the exit code when the repository has no commits.
+const emptyCode = 4
+
+// warnings collects the warnings of the run, which are printed when it ends.
+var warnings = &commit.Warnings{}
+
+// metrics collects the durations of the run, which are written into the file
+// of the metrics-file flag when it ends.
+var metrics = &commit.Metrics{}
+
+// writeMetrics writes the metrics of the run in the OpenMetrics text format
+// into the file of the metrics-file flag, if it's set.
+func writeMetrics() error {
+	if metricsFile == "" {
+		return nil
+	}
+	if err := os.WriteFile(metricsFile, []byte(metrics.Report().OpenMetrics()), 0o600); err != nil {
+		return fmt.Errorf("writing the metrics: %w", err)
+	}
+	return nil
+}
+
+// trace records the git processes of the run with the trace or the trace-file
+// flags. It's nil otherwise.
+var trace *commit.Trace
+
+// writeTrace writes the git processes of the run as JSON into the file of the
+// trace-file flag, if it's set.
+func writeTrace() error {
+	if traceFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(trace.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(traceFile, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing the trace: %w", err)
+	}
+	return nil
+}
+
+// reportWarnings collects the warnings of the g, prints the git processes that
+// take longer than the slow-git flag as they happen, and records them with the
+// trace flags.
+func reportWarnings(g *commit.Git) {
+	g.Warnings = warnings
+	g.SlowThreshold = slowGit
+	g.SlowLogger = log.New(os.Stderr, "warning: slow ", 0)
+	if traceGit || traceFile != "" {
+		if trace == nil {
+			trace = &commit.Trace{}
+		}
+		g.Trace = trace
+	}
+}
+
+// checkStrict returns an error if there are warnings and the strict flag is
+// set.
+func checkStrict() error {
+	if !strict || warnings.Len() == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d warning(s) with --strict", warnings.Len())
+}
+
 func main() {
-	cobra.CheckErr(rootCmd.Execute())
+	err := rootCmd.Execute()
+	// The metrics and the trace of the failed runs are written too, to see
+	// where they stopped.
+	if mErr := writeMetrics(); mErr != nil && err == nil {
+		err = mErr
+	}
+	if tErr := writeTrace(); tErr != nil && err == nil {
+		err = tErr
+	}
+	if summary := warnings.Summary(); summary != "" {
+		fmt.Fprintln(os.Stderr, summary)
+	}
+	if err == nil {
+		err = checkStrict()
+	}
+	if errors.Is(err, commit.ErrEmptyRepository) {
+		fmt.Fprintln(os.Stderr, "There is nothing to release: the repository has no commits yet. Commit and tag the changes first.")
+		os.Exit(emptyCode)
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+	cobra.CheckErr(err)
 }
 
 func init() {
-	cobra.OnInitialize(viper.AutomaticEnv)
+	rootCmd.AddCommand(versionCmd)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	cobra.OnInitialize(viper.AutomaticEnv, initConfig)
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "read the settings and the locales from this file, defaults to .gitrelease.yaml if it exists")
 	rootCmd.PersistentFlags().StringVarP(&tag, "tag", "t", "@", "tag to produce the logs for. Leave empty for current tag.")
 	rootCmd.PersistentFlags().BoolVarP(&printMode, "print", "p", false, "only print, do not release!")
 	rootCmd.PersistentFlags().StringVarP(&remote, "remote", "r", "origin", "use a different remote")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "print debug information")
+	rootCmd.PersistentFlags().StringVar(&rangeMode, "range", "two-dot", "how to select the commits between the tags: two-dot or three-dot")
+	rootCmd.PersistentFlags().BoolVar(&compare, "compare-link", false, "add a link to the compare page of the tags, with the same range as the notes")
+	rootCmd.PersistentFlags().StringArray("exclude-sha", nil, "leave the commit out of the notes: a full or an abbreviated hash, or a range such as v1.0.0..abc123. Repeat for more, or set exclude-sha in the config file")
+	rootCmd.PersistentFlags().BoolVar(&noteExcluded, "note-excluded", false, "note the number of the excluded commits as internal changes")
+	rootCmd.PersistentFlags().IntVar(&budget, "budget", 0, "render at most this many entries, the breaking changes and the features first. 0 is no limit")
+	rootCmd.PersistentFlags().IntVar(&secBudget, "section-budget", 0, "render at most this many entries in each section. 0 is no limit")
+	rootCmd.PersistentFlags().StringVar(&depsMode, "deps", "", "add a Dependencies section from the changes of go.mod: add keeps the dependency commits, replace drops them")
+	rootCmd.PersistentFlags().BoolVar(&depsAll, "deps-indirect", false, "include the indirect dependencies in the Dependencies section")
+	rootCmd.PersistentFlags().IntVar(&maxSubj, "max-subject", commit.DefaultMaxSubject, "truncate the subjects of the commits longer than this many characters, 0 for no limit")
+	rootCmd.PersistentFlags().StringVar(&commitOrder, "commit-order", "time", "order of the commits in the sections: time sorts them by the committer dates and the SHAs, log keeps the order of git log")
+	rootCmd.PersistentFlags().StringVar(&breaking, "breaking", "inline", "how to render the breaking changes: inline only marks them, reference adds a Breaking Changes section that refers to them, duplicate repeats them there, breaking-only moves them there")
+	rootCmd.PersistentFlags().StringVar(&dateSource, "date-source", "tag", "date of the release headings: tag, commit or now. The tag date of a lightweight tag or an unreleased revision is its commit date")
+	rootCmd.PersistentFlags().StringSliceVar(&groupOrder, "group-order", nil, "order of the sections, e.g. Feature,Fix. The other sections come after them")
+	rootCmd.PersistentFlags().BoolVar(&subItems, "sub-items", false, "render the bullet lists of commit bodies as sub-items")
+	rootCmd.PersistentFlags().IntVar(&maxItems, "max-sub-items", 10, "maximum number of sub-items of each commit, 0 for no limit")
+	rootCmd.PersistentFlags().BoolVar(&toc, "toc", false, "start the notes with a table of contents that links to their sections")
+	rootCmd.PersistentFlags().IntVar(&tocMin, "toc-min-sections", commit.DefaultTOCMinSections, "leave out the table of contents of the notes with fewer sections than this")
+	rootCmd.PersistentFlags().StringVar(&bodies, "bodies", "none", "render the commit bodies: none, full, first for the first paragraph, or details to collapse them")
+	rootCmd.PersistentFlags().IntVar(&maxBody, "max-body", commit.DefaultMaxBody, "maximum characters of each body before it's truncated, -1 for no limit")
+	rootCmd.PersistentFlags().BoolVar(&reupload, "reupload", false, "replace the assets that are already uploaded")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "fail if there are warnings, before the release is published if possible")
+	rootCmd.PersistentFlags().DurationVar(&slowGit, "slow-git", 10*time.Second, "warn about the git processes that take longer than this, 0 disables the warnings")
+	rootCmd.PersistentFlags().BoolVar(&noPullAPI, "no-pull-lookup", false, "only use the pull request numbers of the commit messages, without looking up the others from the API")
+	rootCmd.PersistentFlags().StringVar(&gitDir, "git-dir", "", "git directory of the repository, e.g. of a clone with --separate-git-dir, like GIT_DIR")
+	rootCmd.PersistentFlags().StringVar(&workTree, "work-tree", "", "working tree of the repository, like GIT_WORK_TREE")
+	rootCmd.PersistentFlags().BoolVar(&replaceRefs, "replace-refs", false, "read the commits that are replaced with git replace as their replacements, e.g. to start the range at a tag from before a history rewrite")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "generate the notes without any network access: nothing is fetched, looked up from the API, published or announced, and the notes are printed")
+	rootCmd.PersistentFlags().StringArrayVar(&langs, "lang", nil, "render the notes in the language from the locales of the config file, the first one is published. Repeat for more languages")
+	rootCmd.PersistentFlags().StringVar(&sanitize, "sanitize", "safe", "how to escape the commit messages: safe escapes HTML and mentions, strict also escapes markdown, none")
+	rootCmd.PersistentFlags().StringArrayVar(&mentions, "allow-mention", nil, "keep the mentions of this login, e.g. the authors of the pull requests")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "keep the responses of the GitHub API and the commits of the releases in this directory between the runs")
+	rootCmd.PersistentFlags().BoolVar(&direct, "direct", false, "publish the release right away, instead of publishing a draft after its assets are verified")
+	rootCmd.PersistentFlags().BoolVar(&failLong, "fail-on-long-notes", false, "fail instead of truncating the notes that are longer than GitHub accepts")
+	rootCmd.PersistentFlags().StringVar(&since, "since", "", "only print the notes of the commits since a duration ago (24h, 7d) or a date in UTC (2024-06-01), regardless of the tags")
+	rootCmd.PersistentFlags().BoolVar(&annotated, "annotated-only", false, "only consider the annotated tags, ignoring the lightweight ones")
+	rootCmd.PersistentFlags().BoolVar(&sinceStable, "since-last-stable", false, "skip the pre-release tags when looking for the previous tag of a stable release, therefore its notes include the changes of its release candidates")
+	rootCmd.PersistentFlags().StringVar(&notesRef, "notes-ref", "", "git notes ref, e.g. release, whose notes replace the subjects of the commits in the notes")
+	rootCmd.PersistentFlags().BoolVar(&attribution, "attribution", false, "add the GitHub logins, or the names, of the authors to the entries of their commits")
+	rootCmd.PersistentFlags().BoolVar(&sizeHints, "size-hints", false, "add the size of the commits to their entries, S, M, L or XL by their changed lines")
+	rootCmd.PersistentFlags().StringVar(&sizeLimits, "size-thresholds", "50,250,1000", "the smallest numbers of the changed lines of the M, L and XL commits of --size-hints")
+	rootCmd.PersistentFlags().BoolVar(&contributors, "contributors", false, "end the notes with a Contributors section of the GitHub logins, or the names, of the authors")
+	rootCmd.PersistentFlags().BoolVar(&skipAuth, "skip-auth-check", false, "release without checking the permissions of the token on the repository first")
+	rootCmd.PersistentFlags().BoolVar(&backports, "backports", false, "list the cherry-picked commits in a Backported fixes section, with their original commits")
+	rootCmd.PersistentFlags().BoolVar(&stats, "stats", false, "end the notes with the lead time, the median commit age and the active days of the release")
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "write the durations of the stages, the git processes and the API calls, and the uploaded bytes into the file in the OpenMetrics text format")
+	rootCmd.PersistentFlags().BoolVar(&traceGit, "trace", false, "add the git processes with their arguments, durations, exit codes and the first KB of their outputs to the json-result, with the secrets redacted")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "write the git processes of the trace flag as JSON into the file, even if the run fails")
+
+	rootCmd.Flags().AddFlagSet(releaseFlags)
+
+	cobra.CheckErr(viper.BindPFlag("exclude-sha", rootCmd.PersistentFlags().Lookup("exclude-sha")))
 
 	rootCmd.SetUsageTemplate(`Usage:{{if .Runnable}}
   {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
@@ -93,10 +340,6 @@ Examples:
 Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
   {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
 
-Available Commands:
-  help        Help about any command
-  version     Print binary version information
-
 Flags:
 {{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
 
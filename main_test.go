@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/release"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setFlag sets the flag variable to the value for the test, and restores it
+// afterwards. The tests that set the flags can't run in parallel.
+func setFlag[T any](t *testing.T, flag *T, value T) {
+	t.Helper()
+	old := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = old })
+}
+
+// writeNotes writes the content into a file of the test, and returns its
+// path.
+func writeNotes(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "notes.md")
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	return name
+}
+
+func TestReadNotes(t *testing.T) {
+	t.Run("File", testReadNotesFile)
+	t.Run("Stdin", testReadNotesStdin)
+}
+
+func testReadNotesFile(t *testing.T) {
+	tcs := map[string]struct {
+		content string
+		want    string
+		wantErr string
+	}{
+		"plain":       {content: "- The fix", want: "- The fix"},
+		"blank lines": {content: "\n\n- The fix\n\n- The feature\r\n\n", want: "- The fix\n\n- The feature"},
+		"indented":    {content: "\n    code\n", want: "    code"},
+		"empty":       {content: "", wantErr: "are empty"},
+		"blank":       {content: "\n \n\t\n", wantErr: "are empty"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := readNotes(writeNotes(t, tc.content))
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Parallel()
+		_, err := readNotes(filepath.Join(t.TempDir(), "notes.md"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func testReadNotesStdin(t *testing.T) {
+	setFlag[io.Reader](t, &stdin, strings.NewReader("\n- From the pipe\n"))
+	got, err := readNotes("-")
+	require.NoError(t, err)
+	assert.Equal(t, "- From the pipe", got)
+
+	setFlag[io.Reader](t, &stdin, strings.NewReader("\n"))
+	_, err = readNotes("-")
+	assert.ErrorContains(t, err, "the notes of stdin are empty")
+}
+
+func TestReleaseBody(t *testing.T) {
+	ctx := context.Background()
+	tcs := map[string]struct {
+		notesFile  string
+		appendFile string
+		stdin      string
+		langs      []string
+		want       string
+		wantErr    string
+	}{
+		"notes file": {
+			notesFile: "- The notes",
+			want:      "- The notes",
+		},
+		"appended": {
+			notesFile:  "- The notes\n",
+			appendFile: "\nSee the docs.\n",
+			want:       "- The notes\n\nSee the docs.",
+		},
+		"notes from stdin": {
+			notesFile:  "-",
+			appendFile: "See the docs.",
+			stdin:      "- The piped notes\n",
+			want:       "- The piped notes\n\nSee the docs.",
+		},
+		"appended from stdin": {
+			notesFile:  "- The notes",
+			appendFile: "-",
+			stdin:      "See the docs.\n",
+			want:       "- The notes\n\nSee the docs.",
+		},
+		"both from stdin": {
+			notesFile:  "-",
+			appendFile: "-",
+			wantErr:    "can't both read the stdin",
+		},
+		"empty appended": {
+			notesFile:  "- The notes",
+			appendFile: "\n",
+			wantErr:    "are empty",
+		},
+		"translated": {
+			notesFile: "- The notes",
+			langs:     []string{"en", "de"},
+			wantErr:   "use a single --lang",
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			// The file flags are named by their contents, the stdin is "-".
+			file := func(content string) string {
+				if content == "" || content == "-" {
+					return content
+				}
+				return writeNotes(t, content)
+			}
+			setFlag(t, &notesFile, file(tc.notesFile))
+			setFlag(t, &appendFile, file(tc.appendFile))
+			setFlag(t, &langs, tc.langs)
+			setFlag(t, &fullNotes, "")
+			setFlag[io.Reader](t, &stdin, strings.NewReader(tc.stdin))
+
+			// The notes of the files don't need the repository.
+			got, assets, err := releaseBody(ctx, nil, nil)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Empty(t, assets)
+		})
+	}
+}
+
+func TestReleaseError(t *testing.T) {
+	t.Parallel()
+	untagged := release.Result{Info: &commit.ReleaseInfo{Untagged: true}}
+	tcs := map[string]struct {
+		res  release.Result
+		err  error
+		want string
+	}{
+		"none":       {},
+		"no changes": {err: release.ErrNoChanges, want: "--allow-empty"},
+		"checks":     {err: commit.ErrChecksFailed, want: "--skip-checks"},
+		"head":       {err: commit.ErrHeadTagged, want: "--no-tag"},
+		"untagged":   {res: untagged, err: commit.ErrNoTag, want: "--print"},
+		"no tag":     {err: commit.ErrNoTag, want: commit.ErrNoTag.Error()},
+		"other":      {err: errors.New("boom"), want: "boom"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := releaseError(tc.res, tc.err)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestLogsMatch(t *testing.T) {
+	setFlag(t, &warnings, &commit.Warnings{})
+	info := &commit.ReleaseInfo{
+		Commits: []commit.Commit{{Message: "fix: the leak"}},
+		Logs:    []string{"fix: the leak"},
+	}
+	assert.True(t, logsMatch(info, "--attribution"))
+	assert.Zero(t, warnings.Len())
+
+	info.Logs = append(info.Logs, "feat: the extra entry")
+	assert.False(t, logsMatch(info, "--attribution"))
+	got := warnings.List()
+	require.Len(t, got, 1)
+	assert.Equal(t, commit.WarnFeatureSkipped, got[0].Code)
+	assert.Contains(t, got[0].Message, "--attribution is skipped")
+}
+
+func TestVersionFiles(t *testing.T) {
+	t.Cleanup(func() { viper.Set("version-files", nil) })
+	viper.Set("version-files", []string{"package.json", `main.go=const Version = "(.+)"`})
+	got, err := versionFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []commit.VersionFile{
+		{Path: "package.json"},
+		{Path: "main.go", Pattern: `const Version = "(.+)"`},
+	}, got)
+
+	viper.Set("version-files", []string{"main.go"})
+	_, err = versionFiles()
+	assert.Error(t, err)
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		config  string
+		wantErr string
+	}{
+		"empty": {config: ""},
+		"valid": {config: `
+classify:
+  use: [conventional]
+  groups:
+    - title: Chores
+      types: [chore]
+      collapse: true
+groups:
+  chore:
+    collapse: true
+terms:
+  - pattern: (?i)\brecieve
+    replacement: receive
+channels:
+  stable:
+    pattern: '^v\d+\.\d+\.\d+$'
+  beta:
+    pattern: '^v\d+\.\d+\.\d+-beta\.\d+$'
+    since: stable
+locales:
+  de:
+    no-changes: Keine Änderungen seit %s.
+`},
+		"classifier":  {config: "classify:\n  use: [magic]\n", wantErr: `unknown classifier "magic"`},
+		"term":        {config: "terms:\n  - pattern: '('\n", wantErr: "missing closing )"},
+		"host":        {config: "hosts:\n  - commit: x\n", wantErr: "has no name"},
+		"channel":     {config: "channels:\n  beta:\n    pattern: '('\n", wantErr: "missing closing )"},
+		"since":       {config: "channels:\n  beta:\n    pattern: beta\n    since: stable\n", wantErr: `channel "stable" of the since`},
+		"highlights":  {config: "highlights:\n  file: HIGHLIGHTS.md\n  text: x\n", wantErr: "not both"},
+		"locale":      {config: "locales:\n  de:\n    no-changes: Keine Änderungen seit %d.\n", wantErr: `locale "de"`},
+		"groups":      {config: "groups: [chore]\n", wantErr: "reading the groups"},
+		"collapse":    {config: "groups:\n  chore:\n    colapse: true\n", wantErr: "reading the groups"},
+		"group def":   {config: "classify:\n  groups:\n    - title: Chores\n      types: [chore]\n      colapse: true\n", wantErr: "reading the classifiers"},
+		"footers":     {config: "footers: [Deploy-To]\n", wantErr: "reading the footers"},
+		"unknown key": {config: "classify:\n  patern: x\n", wantErr: "reading the classifiers"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			v := viper.New()
+			v.SetConfigType("yaml")
+			require.NoError(t, v.ReadConfig(strings.NewReader(tc.config)))
+			err := validateConfig(v)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestWithHighlights(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("highlights.text", nil)
+		viper.Set("highlights.required", nil)
+	})
+	viper.Set("highlights.text", "## v1.5.0\n\n- Dark mode.\n")
+	g := &commit.Git{}
+	info := &commit.ReleaseInfo{Tag: "v1.5.0"}
+	got, err := withHighlights(g, info)
+	require.NoError(t, err)
+	assert.Equal(t, "- Dark mode.", got.Highlights)
+	assert.Empty(t, info.Highlights, "the info of the translations has no highlights")
+
+	viper.Set("highlights.required", true)
+	_, err = withHighlights(g, &commit.ReleaseInfo{Tag: "v1.6.0"})
+	assert.ErrorContains(t, err, "v1.6.0 has no highlights")
+}
+
+func TestClassifyOptionsCollapse(t *testing.T) {
+	t.Parallel()
+	cfg := classifyConfig{Groups: []groupDefinition{
+		{Title: "Platform", Types: []string{"feat"}},
+		{Title: "Chores", Types: []string{"chore"}, Collapse: true},
+	}}
+	opts, err := cfg.classifyOptions(nil)
+	require.NoError(t, err)
+	got := commit.ParseGroups([]string{"feat: the api", "chore: tidy up"}, opts...)
+	assert.Contains(t, got, "### Platform")
+	assert.Contains(t, got, "<details>")
+	assert.NotContains(t, got, "### Chores")
+}
+
+func TestCheckTerms(t *testing.T) {
+	setFlag(t, &warnings, &commit.Warnings{})
+	setFlag(t, &strictTerms, false)
+	setFlag(t, &fixTerms, false)
+	t.Cleanup(func() { viper.Set("terms", nil) })
+	viper.Set("terms", []map[string]any{{"pattern": `(?i)\brecieve`, "replacement": "receive"}})
+
+	got, err := checkTerms("RELEASE_NOTES.de.md", "- Recieve the files")
+	require.NoError(t, err)
+	assert.Equal(t, "- Recieve the files", got)
+	list := warnings.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, commit.WarnForbiddenTerm, list[0].Code)
+	assert.Contains(t, list[0].Message, "RELEASE_NOTES.de.md: line 1")
+
+	setFlag(t, &fixTerms, true)
+	got, err = checkTerms("FULL.md", "- Recieve the files")
+	require.NoError(t, err)
+	assert.Equal(t, "- receive the files", got)
+
+	setFlag(t, &fixTerms, false)
+	setFlag(t, &strictTerms, true)
+	_, err = checkTerms("FULL.md", "- Recieve the files")
+	assert.ErrorContains(t, err, "1 forbidden term(s) in FULL.md")
+}
+
+func TestNewGit(t *testing.T) {
+	setFlag(t, &warnings, &commit.Warnings{})
+	setFlag(t, &sinceStable, true)
+	setFlag(t, &rangeMode, "three-dot")
+	setFlag(t, &annotated, true)
+	setFlag(t, &notesRef, "release")
+	g, err := newGit()
+	require.NoError(t, err)
+	assert.True(t, g.SinceLastStable)
+	assert.Equal(t, commit.RangeSymmetric, g.RangeMode)
+	assert.True(t, g.AnnotatedOnly)
+	assert.Equal(t, "release", g.NotesRef)
+	assert.Same(t, warnings, g.Warnings)
+
+	setFlag(t, &rangeMode, "four-dot")
+	_, err = newGit()
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/release"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	jsonOutput     bool
+	releaseModules bool
+	combined       bool
+	scheme         string
+	calverPattern  string
+
+	modulesCmd = &cobra.Command{
+		Use:   "modules",
+		Short: "List the Go modules of the repository and the ones that need a release",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			vs, err := commit.ParseScheme(viper.GetString("scheme"), viper.GetString("calver-pattern"))
+			if err != nil {
+				return err
+			}
+			g, err := newGit()
+			if err != nil {
+				return err
+			}
+			g.Scheme = vs
+			if releaseModules {
+				return releaseAll(cmd.Context(), g)
+			}
+
+			modules, err := g.Modules(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(modules)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MODULE\tLATEST\tCOMMITS\tNEXT")
+			for _, m := range modules {
+				latest := m.LatestTag
+				if latest == "" {
+					latest = "-"
+				}
+				next := m.NextTag
+				if !m.NeedsRelease() {
+					next = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", m.Dir, latest, m.Commits, next)
+			}
+			return w.Flush()
+		},
+	}
+)
+
+// releaseAll tags and releases all modules that need a release, and prints a
+// summary of the outcome of each module.
+func releaseAll(ctx context.Context, g *commit.Git) error {
+	token, err := resolveAuth(g)
+	if err != nil {
+		return err
+	}
+	r, err := g.RemoteInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get repo name: %w", err)
+	}
+	useRepo(g, r)
+	user, repo := r.Owner, r.Name
+	publish := func(ctx context.Context, tag, desc string) error {
+		body, changelog, cleanup, err := fitNotes(tag, desc, "")
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		info := &commit.ReleaseInfo{Tag: tag, User: user, Repo: repo, Remote: r}
+		_, err = runRelease(ctx, release.Config{
+			Git:        g,
+			Token:      token,
+			Tag:        tag,
+			AllowEmpty: true,
+			Resolver: release.ResolverFunc(func(context.Context, string) (*commit.ReleaseInfo, error) {
+				return info, nil
+			}),
+			Changelog: release.ChangelogFunc(func(context.Context, *commit.ReleaseInfo) (string, []commit.Asset, error) {
+				return body, changelog, nil
+			}),
+		})
+		return err
+	}
+	render, err := renderOptions()
+	if err != nil {
+		return err
+	}
+	opts := commit.BatchOptions{Combined: combined, Render: render}
+	results, err := g.ReleaseModules(ctx, publish, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tTAG\tSTATUS\tERROR")
+	for _, res := range results {
+		tag, msg := res.Module.NextTag, ""
+		if tag == "" {
+			tag = "-"
+		}
+		if res.Err != nil {
+			failed++
+			msg = res.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.Module.Dir, tag, res.Status, msg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d module(s) failed to release", failed)
+	}
+	return nil
+}
+
+func init() {
+	modulesCmd.Flags().BoolVar(&jsonOutput, "json", false, "print the modules as JSON")
+	modulesCmd.Flags().BoolVar(&releaseModules, "release", false, "tag and release all modules that need a release")
+	modulesCmd.Flags().BoolVar(&combined, "combined", false, "with --release, publish one release with the notes of all modules")
+	modulesCmd.Flags().StringVar(&scheme, "scheme", "semver", "version scheme of the tags: semver or calver. Can be set with SCHEME")
+	modulesCmd.Flags().StringVar(&calverPattern, "calver-pattern", "YYYY.0M.MICRO", "pattern of the calver tags. Can be set with CALVER_PATTERN")
+	cobra.CheckErr(viper.BindPFlag("scheme", modulesCmd.Flags().Lookup("scheme")))
+	cobra.CheckErr(viper.BindPFlag("calver-pattern", modulesCmd.Flags().Lookup("calver-pattern")))
+	rootCmd.AddCommand(modulesCmd)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var notesCmd = &cobra.Command{
+	Use:   "notes <tag>",
+	Short: "Print the notes of an existing tag as they are generated at release time",
+	Long: `Print the notes of an existing tag as they are generated at release time. The
+previous tag is found relative to the tag, and the commits end at the tag
+instead of the HEAD. Use --update-if-changed with --tag to publish them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		g, err := newGit()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		info, err := g.PrepareTag(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		// The notes are rendered without a token.
+		err = g.AssociatePulls(ctx, "", info.Remote, info.Commits, commit.PullOptions{Offline: true})
+		if err != nil {
+			return err
+		}
+		info.Logs = commit.PullLogs(info.Commits)
+		desc, err := releaseNotes(ctx, g, info)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(desc)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(notesCmd)
+}
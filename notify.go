@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var notifyRequired bool
+
+// notifiers returns the notifiers that are configured with the flags or the
+// environment.
+func notifiers() ([]commit.Notifier, error) {
+	var ns []commit.Notifier
+	if to := recipients(); len(to) > 0 {
+		security, err := commit.ParseSMTPSecurity(viper.GetString("smtp-security"))
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, &commit.EmailNotifier{
+			Host:     viper.GetString("smtp-host"),
+			Port:     viper.GetInt("smtp-port"),
+			Security: security,
+			Username: viper.GetString("smtp-user"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     viper.GetString("email-from"),
+			To:       to,
+			Subject:  viper.GetString("email-subject"),
+			DryRun:   viper.GetString("email-dry-run"),
+		})
+	}
+	return ns, nil
+}
+
+// recipients returns the addresses of the email-to flag. The environment
+// variable is a comma separated list.
+func recipients() []string {
+	var to []string
+	for _, v := range viper.GetStringSlice("email-to") {
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+	}
+	return to
+}
+
+// emailFlags adds the flags of the announcements to the flags of the release.
+func emailFlags(flags *pflag.FlagSet) {
+	flags.StringSlice("email-to", nil, "announce the release to these addresses. Can be set with EMAIL_TO")
+	flags.String("email-from", "", "sender of the announcements. Can be set with EMAIL_FROM")
+	flags.String("email-subject", commit.DefaultEmailSubject, "template of the subject of the announcements, with .Project, .Tag and .URL")
+	flags.String("email-dry-run", "", "write the announcement into this file instead of sending it")
+	flags.String("smtp-host", "", "SMTP server of the announcements. Can be set with SMTP_HOST")
+	flags.Int("smtp-port", 0, "port of the SMTP server, defaults to the port of the security. Can be set with SMTP_PORT")
+	flags.String("smtp-user", "", "user of the SMTP server, its password is read from SMTP_PASSWORD. Can be set with SMTP_USER")
+	flags.String("smtp-security", "starttls", "encryption of the SMTP connection: starttls, tls or none. Can be set with SMTP_SECURITY")
+	flags.BoolVar(&notifyRequired, "notify-required", false, "fail if an announcement can't be sent")
+	for _, name := range []string{
+		"email-to", "email-from", "email-subject", "email-dry-run",
+		"smtp-host", "smtp-port", "smtp-user", "smtp-security",
+	} {
+		cobra.CheckErr(viper.BindPFlag(name, flags.Lookup(name)))
+	}
+}
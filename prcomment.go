@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pullNumber int
+	pullHead   string
+	pullLimit  int
+
+	prCommentCmd = &cobra.Command{
+		Use:   "pr-comment",
+		Short: "Comment the notes of the unreleased changes on a pull request",
+		Long: `Comment the notes of the unreleased changes, including the commits of the pull
+request, on the pull request. The comment of a previous run is updated instead
+of adding another one. The number of the pull request is read from the
+environment of the CI if --pr is not set. The pull requests from forks, whose
+tokens can't comment, are skipped. With --limit, only the latest commits are
+in the notes, which keeps the previews of large ranges fast. Use --print to
+only print the comment.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			g, err := newGit()
+			if err != nil {
+				return err
+			}
+			n := pullNumber
+			if n == 0 {
+				n = commit.CIPullRequest(os.Getenv)
+			}
+			if n == 0 && !printMode {
+				return errors.New("the pull request is unknown, set it with --pr")
+			}
+
+			ctx := cmd.Context()
+			r, err := g.RemoteInfo(ctx)
+			if err != nil {
+				return err
+			}
+			useRepo(g, r)
+			var token string
+			if !printMode {
+				if token, err = resolveAuth(g); err != nil {
+					return err
+				}
+			}
+			tag, page, err := g.PendingPage(ctx, pullHead, commit.Limit(pullLimit))
+			if err != nil {
+				return err
+			}
+			info := &commit.ReleaseInfo{
+				User:        r.Owner,
+				Repo:        r.Name,
+				Remote:      r,
+				Tag:         pullHead,
+				PreviousTag: tag,
+				Commits:     page.Commits,
+			}
+			if printMode {
+				// The notes are printed without a token.
+				err = g.AssociatePulls(ctx, "", r, info.Commits, commit.PullOptions{Offline: true})
+				info.Logs = commit.PullLogs(info.Commits)
+			} else {
+				err = associatePulls(ctx, g, token, info)
+			}
+			if err != nil {
+				return err
+			}
+			notes, err := releaseNotes(ctx, g, info, commit.WithLatest(len(page.Commits), page.Count))
+			if err != nil {
+				return err
+			}
+			body := commit.PendingComment(notes)
+			if printMode {
+				_, err := fmt.Println(body)
+				return err
+			}
+
+			action, err := g.PostPendingNotes(ctx, token, r.Owner, r.Name, n, body)
+			if errors.Is(err, commit.ErrNoWriteAccess) {
+				fmt.Fprintf(os.Stderr, "skipping the comment on #%d, the token can't write to it, e.g. the pull request is from a fork: %v\n", n, err)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("comment on #%d: %s\n", n, action)
+			return nil
+		},
+	}
+)
+
+func init() {
+	prCommentCmd.Flags().IntVar(&pullNumber, "pr", 0, "number of the pull request, defaults to the one of the CI run")
+	prCommentCmd.Flags().StringVar(&pullHead, "head", "HEAD", "head of the pull request, the merge commit CI checks out for it is left out of the notes")
+	prCommentCmd.Flags().IntVar(&pullLimit, "limit", 0, "only render the latest commits of the range, with the number of all of them. 0 is no limit")
+	rootCmd.AddCommand(prCommentCmd)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+)
+
+// newProgress returns the progress display of the release flow on the file. It
+// returns nil if quiet is set. If the file is not a terminal, the progress is
+// printed as plain lines.
+func newProgress(f *os.File, quiet bool) commit.Progress {
+	if quiet {
+		return nil
+	}
+	if isTerminal(f) {
+		return &termProgress{w: f}
+	}
+	return &lineProgress{w: f}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// lineProgress prints a line for each step, and one for each finished
+// transfer.
+type lineProgress struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (p *lineProgress) Start(name string) func(err error) {
+	p.printf("%s...\n", name)
+	return func(err error) {
+		if err != nil {
+			p.printf("%s: failed: %v\n", name, err)
+			return
+		}
+		p.printf("%s: done\n", name)
+	}
+}
+
+func (p *lineProgress) Transfer(name string, done, total int64) {
+	if done >= total {
+		p.printf("uploaded %s (%s)\n", name, byteSize(total))
+	}
+}
+
+func (p *lineProgress) printf(format string, v ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, format, v...)
+}
+
+// spinnerFrames are the frames of the spinner of the running step.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// barWidth is the number of the characters of the progress bars.
+const barWidth = 30
+
+// termProgress redraws the status line of the running step with a spinner,
+// and the progress bar of the current transfer.
+type termProgress struct {
+	w      io.Writer
+	mu     sync.Mutex
+	name   string
+	detail string
+	frame  int
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func (p *termProgress) Start(name string) func(err error) {
+	p.mu.Lock()
+	p.name = name
+	p.detail = ""
+	p.render()
+	stop, done := make(chan struct{}), make(chan struct{})
+	p.stop, p.done = stop, done
+	p.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame = (p.frame + 1) % len(spinnerFrames)
+				p.render()
+				p.mu.Unlock()
+			}
+		}
+	}()
+
+	return func(err error) {
+		close(stop)
+		<-done
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err != nil {
+			fmt.Fprintf(p.w, "\r\x1b[K✗ %s: %v\n", name, err)
+		} else {
+			fmt.Fprintf(p.w, "\r\x1b[K✓ %s\n", name)
+		}
+		p.name, p.detail = "", ""
+	}
+}
+
+func (p *termProgress) Transfer(name string, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if done >= total {
+		fmt.Fprintf(p.w, "\r\x1b[K  ✓ %s (%s)\n", name, byteSize(total))
+		p.detail = ""
+		p.render()
+		return
+	}
+	filled := int(done * barWidth / total)
+	p.detail = fmt.Sprintf("%s [%s%s] %3d%% %s/%s", name,
+		strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled),
+		done*100/total, byteSize(done), byteSize(total))
+	p.render()
+}
+
+// render redraws the status line. It should be called with the lock held.
+func (p *termProgress) render() {
+	if p.name == "" {
+		return
+	}
+	line := spinnerFrames[p.frame] + " " + p.name
+	if p.detail != "" {
+		line += ": " + p.detail
+	}
+	fmt.Fprint(p.w, "\r\x1b[K"+line)
+}
+
+// byteSize returns the size in a human readable form.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
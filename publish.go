@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/release"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	nextMode  bool
+	noTag     bool
+	noPush    bool
+	noPublish bool
+
+	publishCmd = &cobra.Command{
+		Use:   "publish",
+		Short: "Tag the HEAD with the next version and release it",
+		Long: `Release the HEAD in one step: the next version is computed from the commits
+since the previous tag, the HEAD is tagged with it as an annotated tag with the
+notes as its message, the tag is pushed, and the release is published. The
+flags of the release apply, except for --tag.
+
+Use --dry-run to print the plan and the notes without changing anything. If a
+step fails, the steps that are already done are reported. A release whose tag
+is created can be resumed with --no-tag, which releases the tag of the HEAD.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			nextMode = true
+			return rootCmd.RunE(cmd, nil)
+		},
+	}
+)
+
+// unpublishedCode is the exit code when the draft release is left unpublished
+// because it couldn't be verified.
+const unpublishedCode = 3
+
+// runRelease runs the release of the cfg with the direct and reupload flags,
+// and prints the summary of the uploads. Unless direct is set, the release is
+// created as a draft, and is only published after the files are uploaded and
+// verified. The permissions of the token are checked before anything is
+// changed, unless the skip-auth-check flag is set.
+func runRelease(ctx context.Context, cfg release.Config) (release.Result, error) {
+	cfg.Direct = direct
+	if !cfg.DryRun && !cfg.Offline && !skipAuth && !(cfg.NoPush && cfg.NoPublish) {
+		if err := checkAuth(ctx, cfg.Git, cfg.Token); err != nil {
+			return release.Result{}, err
+		}
+	}
+	if cfg.Publisher == nil {
+		cfg.Publisher = release.GitHub{Git: cfg.Git, Token: cfg.Token, Reupload: reupload}
+	}
+	res, err := release.Run(ctx, cfg)
+	if res.Existed {
+		fmt.Fprintf(os.Stderr, "release of %s already exists, only the assets are uploaded\n", res.Info.Tag)
+	}
+	if res.Uploads != (commit.UploadSummary{}) {
+		printUploadSummary(res.Uploads)
+	}
+	var unpublished *release.UnpublishedError
+	if errors.As(err, &unpublished) {
+		return res, &exitError{code: unpublishedCode, msg: err.Error()}
+	}
+	return res, err
+}
+
+func printUploadSummary(s commit.UploadSummary) {
+	fmt.Printf("assets: %d uploaded, %d replaced, %d skipped, %d failed\n",
+		s.Uploaded, s.Replaced, s.Skipped, s.Failed)
+}
+
+func init() {
+	publishCmd.Flags().AddFlagSet(releaseFlags)
+	publishCmd.Flags().BoolVar(&noTag, "no-tag", false, "release the existing tag of the HEAD instead of creating one")
+	publishCmd.Flags().BoolVar(&noPush, "no-push", false, "don't push the tag, requires --no-publish")
+	publishCmd.Flags().BoolVar(&noPublish, "no-publish", false, "stop after the tag is pushed, without publishing the release")
+	publishCmd.Flags().BoolVar(&printMode, "dry-run", false, "only print the plan and the notes, do not change anything")
+	publishCmd.Flags().StringArray("version-file", nil, "update the version in the file and commit it before the tag: a VERSION, package.json or Cargo.toml, or path=regexp whose first group is the version. Repeat for more, or set version-files in the config file")
+	cobra.CheckErr(viper.BindPFlag("version-files", publishCmd.Flags().Lookup("version-file")))
+	rootCmd.AddCommand(publishCmd)
+}
@@ -0,0 +1,692 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/release"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	allowEmpty  bool
+	branch      string
+	onBranch    string
+	assets      []string
+	signCmd     string
+	signExt     string
+	assetVars   map[string]string
+	diffMode    bool
+	updateDiff  bool
+	comment     bool
+	commentTpl  string
+	maxComment  int
+	pullNotice  string
+	pullComment string
+	pullLabel   string
+	maxNotices  int
+	labelPulls  bool
+	strictTerms bool
+	fixTerms    bool
+	labelMap    []string
+	langOutput  string
+	langDir     string
+	langAssets  bool
+	notesFile   string
+	appendFile  string
+	noCache     bool
+	provenance  bool
+	jsonResult  string
+	noActions   bool
+	channel     string
+	reqChecks   bool
+	loginsFile  string
+	newBranch   string
+	skipChecks  bool
+	checksWait  time.Duration
+	fullNotes   string
+	fullAsset   bool
+	trustLocal  bool
+	trustRemote bool
+	noFetch     bool
+	quiet       bool
+
+	// releaseFlags are the flags of the release, which are shared by the
+	// root command and the publish command.
+	releaseFlags = newReleaseFlags()
+)
+
+// releaseGit returns the Git of the release from the flags.
+func releaseGit() (*commit.Git, error) {
+	ch, err := releaseChannel()
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := extraRanges()
+	if err != nil {
+		return nil, err
+	}
+	g, err := newGit()
+	if err != nil {
+		return nil, err
+	}
+	g.Ranges = rangeCache()
+	g.InitialVersion = viper.GetString("initial_version")
+	g.Channel = ch
+	g.ExtraRanges = ranges
+	g.Metrics = metrics
+	return g, nil
+}
+
+// releaseConfig returns the config of the release from the flags. The
+// returned function removes the signatures of the assets, and should be
+// called after the release.
+func releaseConfig(cmd *cobra.Command, g *commit.Git, token string, notes *changelog) (release.Config, func(), error) {
+	mappings := make([]commit.AssetMapping, 0, len(assets))
+	for _, a := range assets {
+		m, err := commit.ParseAssetMapping(a)
+		if err != nil {
+			return release.Config{}, nil, err
+		}
+		mappings = append(mappings, m)
+	}
+	ns, err := notifiers()
+	if err != nil {
+		return release.Config{}, nil, err
+	}
+	checks, err := checksOptions(cmd)
+	if err != nil {
+		return release.Config{}, nil, err
+	}
+	files, err := versionFiles()
+	if err != nil {
+		return release.Config{}, nil, err
+	}
+	cfg := release.Config{
+		Git:            g,
+		Token:          token,
+		Tag:            tag,
+		AllowEmpty:     allowEmpty,
+		DryRun:         printMode || diffMode || updateDiff,
+		Assets:         mappings,
+		AssetVars:      assetVars,
+		Notifiers:      ns,
+		NotifyRequired: notifyRequired,
+		Checks:         checks,
+		Branch:         newBranch,
+		Offline:        offline,
+		Next:           nextMode,
+		NoTag:          noTag,
+		NoPush:         noPush,
+		NoPublish:      noPublish,
+		VersionFiles:   files,
+		Resolver: release.ResolverFunc(func(ctx context.Context, t string) (*commit.ReleaseInfo, error) {
+			return resolveRelease(ctx, g, t)
+		}),
+		Changelog: notes,
+	}
+	if signCmd == "" {
+		return cfg, func() {}, nil
+	}
+	signer, removeSigs, err := signer()
+	if err != nil {
+		return release.Config{}, nil, err
+	}
+	cfg.Signer = signer
+	return cfg, removeSigs, nil
+}
+
+// releaseError returns the error of the release with the flag that gets past
+// it, if there is one.
+func releaseError(res release.Result, err error) error {
+	switch {
+	case errors.Is(err, release.ErrNoChanges):
+		return fmt.Errorf("%w, use --allow-empty to release anyway", err)
+	case errors.Is(err, commit.ErrChecksFailed):
+		return fmt.Errorf("%w\nuse --skip-checks to release anyway", err)
+	case errors.Is(err, commit.ErrHeadTagged):
+		return fmt.Errorf("%w, release it with --no-tag", err)
+	case res.Info != nil && res.Info.Untagged && errors.Is(err, commit.ErrNoTag):
+		return fmt.Errorf("%w, create and push it first, or preview the notes with --print", err)
+	}
+	return err
+}
+
+// finishRelease prints the notes of the release, or compares them with the
+// published ones, or notifies the pull requests and the issues of the
+// published release and writes its result.
+func finishRelease(ctx context.Context, g *commit.Git, token string, res release.Result, notes *changelog, notice commit.PullNotice) error {
+	if !diffMode && !updateDiff && !noActions {
+		if err := writeActions(res); err != nil {
+			return err
+		}
+	}
+	if printMode && !diffMode && !updateDiff {
+		for _, step := range res.Plan {
+			fmt.Fprintf(os.Stderr, "would %s\n", step)
+		}
+		for _, c := range res.VersionChanges {
+			fmt.Fprint(os.Stderr, c.Diff())
+		}
+		_, err := fmt.Println(res.Notes)
+		return err
+	}
+	if diffMode || updateDiff {
+		return diffRelease(ctx, g, token, res.Info, res.Notes, notes.changelog)
+	}
+	// The pull requests are notified before the result is written, which
+	// lists them, but a failure is reported after it.
+	var notified []int
+	var noticeErr error
+	if res.Created && notice != 0 {
+		notified, noticeErr = notifyPulls(ctx, g, token, res.Info, notice)
+	}
+	if jsonResult != "" {
+		if err := writeResult(res.Info, res.Published, notes.prov, res.Signatures, notified); err != nil {
+			return err
+		}
+	}
+	if noticeErr != nil {
+		return noticeErr
+	}
+	if !res.Created || !comment {
+		return nil
+	}
+	return commentIssues(ctx, g, token, res.Info)
+}
+
+// diffCode is the exit code when the notes of a release differ from the
+// generated ones.
+const diffCode = 2
+
+// resolveLogins sets the logins of the authors of the commits if they are
+// attributed. A failed lookup only leaves the author with the name, therefore
+// it is printed as a warning.
+func resolveLogins(ctx context.Context, g *commit.Git, token string, cmd *cobra.Command, info *commit.ReleaseInfo) error {
+	if !attribution && !contributors {
+		return nil
+	}
+	logins, err := authorLogins(cmd)
+	if err != nil {
+		return err
+	}
+	err = g.ResolveLogins(ctx, token, info.Remote, info.Commits, logins, noPullAPI || offline)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		warnings.Add(commit.WarnLoginLookup, "%v", err)
+	}
+	return nil
+}
+
+// resolveRelease prepares the release of the tag, and checks the branch
+// flags. The commit of an untagged release is checked instead of its tag.
+func resolveRelease(ctx context.Context, g *commit.Git, t string) (*commit.ReleaseInfo, error) {
+	info, err := prepare(ctx, g, t)
+	if err != nil {
+		return nil, err
+	}
+	useRepo(g, info.Remote)
+	if branch != "" {
+		if err := g.CheckBranch(ctx, branch, info.Tag); err != nil {
+			return nil, err
+		}
+	}
+	if onBranch != "" {
+		rev := info.Tag
+		if info.Target != "" {
+			rev = info.Target
+		}
+		if err := g.CheckReachable(ctx, rev, onBranch); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// changelog builds the notes of the release from the flags. Unless the notes
+// are only printed, they are truncated to fit in the release, and the prov
+// and the changelog are kept for the result and the diff. The cleanup removes
+// the file of the changelog.
+type changelog struct {
+	g         *commit.Git
+	token     string
+	cmd       *cobra.Command
+	prov      commit.Provenance
+	changelog []commit.Asset
+	cleanup   func()
+}
+
+func (c *changelog) Notes(ctx context.Context, info *commit.ReleaseInfo) (string, []commit.Asset, error) {
+	if err := associatePulls(ctx, c.g, c.token, info); err != nil {
+		return "", nil, err
+	}
+	nonConventional(info)
+	if err := resolveLogins(ctx, c.g, c.token, c.cmd, info); err != nil {
+		return "", nil, err
+	}
+	// Labelling writes to the pull requests, therefore it's skipped when
+	// nothing is published.
+	if labelPulls && !printMode && !diffMode {
+		if err := labelPullRequests(ctx, c.g, c.token, info); err != nil {
+			return "", nil, err
+		}
+	}
+	desc, translated, err := releaseBody(ctx, c.g, info)
+	if err != nil {
+		return "", nil, err
+	}
+	if desc, err = checkTerms("the notes", desc); err != nil {
+		return "", nil, err
+	}
+	if printMode && !diffMode && !updateDiff {
+		return desc, nil, nil
+	}
+
+	var footer string
+	if provenance || jsonResult != "" {
+		if c.prov, err = c.g.Provenance(ctx, version, info.Tag, usedFlags(c.cmd)); err != nil {
+			return "", nil, err
+		}
+	}
+	if provenance {
+		if footer, err = c.prov.Footer(); err != nil {
+			return "", nil, err
+		}
+	}
+	body, files, cleanup, err := fitNotes(info.Tag, desc, footer)
+	if err != nil {
+		return "", nil, err
+	}
+	c.changelog, c.cleanup = files, cleanup
+	if diffMode || updateDiff {
+		return body, nil, nil
+	}
+	if err := checkStrict(); err != nil {
+		return "", nil, err
+	}
+	return body, append(files, translated...), nil
+}
+
+// prepare prepares the release of the tag. Unless the notes are only
+// printed, the tag is compared with the remote first, therefore the notes
+// have all commits of the tag on the remote. The release.NextTag is the next
+// release of the HEAD, which has no tag to compare yet.
+func prepare(ctx context.Context, g *commit.Git, tag string) (*commit.ReleaseInfo, error) {
+	if tag == release.NextTag {
+		return g.PrepareNext(ctx)
+	}
+	// Printing the notes doesn't need the remote.
+	if printMode && !diffMode && !updateDiff {
+		return g.Prepare(ctx, tag)
+	}
+	name := tag
+	if name == "@" {
+		latest, err := g.LatestTag(ctx)
+		if errors.Is(err, commit.ErrNoTag) {
+			// The first release has no tag to compare with the remote yet.
+			return g.Prepare(ctx, tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		name = latest
+	}
+	if err := checkRemoteTag(ctx, g, name); err != nil {
+		return nil, err
+	}
+	return g.Prepare(ctx, tag)
+}
+
+// checkRemoteTag fetches the tag if the local one is missing or behind the
+// remote, unless the no-fetch flag is set. If the tags have diverged, it
+// returns an error unless one of the trust flags picks a side. The local tag
+// is replaced with the remote one with --trust-remote.
+func checkRemoteTag(ctx context.Context, g *commit.Git, name string) error {
+	if trustLocal && trustRemote {
+		return errors.New("--trust-local and --trust-remote can't be used together")
+	}
+	// The local tag is trusted even if it's behind.
+	_, err := g.SyncTag(ctx, name, !noFetch && !trustLocal)
+	var (
+		mismatch *commit.TagMismatchError
+		stale    *commit.StaleTagError
+	)
+	isStale, isMismatch := errors.As(err, &stale), errors.As(err, &mismatch)
+	switch {
+	case (isStale || isMismatch) && trustLocal:
+		warnings.Add(commit.WarnTagMismatch, "%v, using the local tag", err)
+		return nil
+	case isStale:
+		return fmt.Errorf("%w: fetch it, or release without --no-fetch", err)
+	case !isMismatch:
+		return err
+	case trustRemote:
+		warnings.Add(commit.WarnTagMismatch, "%v, using the tag of %s", err, mismatch.Remote)
+		return g.FetchTag(ctx, name)
+	}
+	return fmt.Errorf("%w: use --trust-local or --trust-remote to pick one", err)
+}
+
+// printSince prints the notes of the commits since the time in the since
+// flag, regardless of the tags.
+func printSince(ctx context.Context, g *commit.Git) error {
+	from, err := commit.ParseSince(since, time.Now())
+	if err != nil {
+		return err
+	}
+	commits, err := g.CommitsSince(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Printf("No changes since %s.\n", from.Format(time.RFC3339))
+		return nil
+	}
+	opts, err := renderOptions()
+	if err != nil {
+		return err
+	}
+	logs := make([]string, len(commits))
+	for i, c := range commits {
+		logs[i] = c.Normalize(maxSubject()).Message
+	}
+	_, err = fmt.Println(commit.ParseGroups(logs, opts...))
+	return err
+}
+
+// diffRelease prints the diff of the published notes of the release and the
+// desc. If updateDiff is set, the release is updated with the desc and the
+// changelog is uploaded, otherwise an exitError is returned when they differ.
+func diffRelease(ctx context.Context, g *commit.Git, token string, info *commit.ReleaseInfo, desc string, changelog []commit.Asset) error {
+	r, err := g.GetReleaseByTag(ctx, token, info.User, info.Repo, info.Tag)
+	if err != nil {
+		return err
+	}
+	// The provenance differs in every run.
+	published, generated := commit.StripProvenance(r.Body), commit.StripProvenance(desc)
+	diff := commit.DiffNotes("published/"+info.Tag, "generated/"+info.Tag, published, generated)
+	if diff == "" {
+		fmt.Println("no changes")
+		return nil
+	}
+	fmt.Print(diff)
+	if updateDiff {
+		if err := g.UpdateReleaseBody(ctx, token, info.User, info.Repo, r.ID, desc); err != nil {
+			return err
+		}
+		_, err := g.UploadAssets(ctx, token, info.User, info.Repo, info.Tag, changelog, false)
+		return err
+	}
+	return &exitError{code: diffCode, msg: "the release notes differ"}
+}
+
+// signer returns the signer of the command of the sign-cmd flag, which
+// writes the signatures into a temporary folder. The cleanup function removes
+// the folder.
+func signer() (commit.Signer, func(), error) {
+	command, err := commit.ParseSignCommand(signCmd)
+	if err != nil {
+		return commit.Signer{}, nil, err
+	}
+	dir, err := os.MkdirTemp("", "gitrelease-signatures-")
+	if err != nil {
+		return commit.Signer{}, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	return commit.Signer{Command: command, Ext: signExt, Dir: dir}, cleanup, nil
+}
+
+// releaseResult is written into the file of the json-result flag.
+type releaseResult struct {
+	Tag         string            `json:"tag"`
+	PreviousTag string            `json:"previous_tag,omitempty"`
+	Initial     bool              `json:"initial,omitempty"`
+	URL         string            `json:"url"`
+	Created     bool              `json:"created"`
+	Provenance  commit.Provenance `json:"provenance"`
+	Warnings    []commit.Warning  `json:"warnings"`
+	// Signatures are the assets of the signatures, listed separately from
+	// the assets they sign.
+	Signatures []commit.SignedAsset `json:"signatures"`
+	// Stats are the timing metrics of the commits of the release.
+	Stats commit.ReleaseStats `json:"stats"`
+	// NotifiedPulls are the numbers of the pull requests that are commented
+	// on or labelled with the notify-pulls flag.
+	NotifiedPulls []int `json:"notified_pulls,omitempty"`
+	// Metrics are the durations of the stages of the run so far.
+	Metrics commit.MetricsReport `json:"metrics"`
+	// Trace are the git processes of the run so far with the trace flag.
+	Trace []commit.TraceEntry `json:"trace,omitempty"`
+}
+
+// writeResult writes the result of releasing into the file of the json-result
+// flag, or into the stdout if it's "-". The published time is zero if the
+// release is not created by the run, and the stats are measured to now.
+func writeResult(info *commit.ReleaseInfo, published time.Time, prov commit.Provenance, signed []commit.SignedAsset, notified []int) error {
+	created := !published.IsZero()
+	if !created {
+		published = time.Now()
+	}
+	b, err := json.MarshalIndent(releaseResult{
+		Tag:           info.Tag,
+		PreviousTag:   info.PreviousTag,
+		Initial:       info.Initial,
+		URL:           release.URL(info),
+		Created:       created,
+		Provenance:    prov,
+		Stats:         commit.Stats(info.Commits, published),
+		Warnings:      warnings.List(),
+		Signatures:    signed,
+		Metrics:       metrics.Report(),
+		NotifiedPulls: notified,
+		Trace:         trace.Entries(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if jsonResult == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(jsonResult, b, 0o600)
+}
+
+// writeActions writes the notes of the release into the job summary, and its
+// version into the step outputs, when it runs in GitHub Actions.
+func writeActions(res release.Result) error {
+	a := commit.ActionsFromEnv(os.Getenv)
+	if !a.Enabled() {
+		return nil
+	}
+	if err := a.AppendSummary("## " + res.Info.Tag + "\n\n" + res.Notes); err != nil {
+		return fmt.Errorf("writing the job summary: %w", err)
+	}
+	err := a.SetOutputs(
+		commit.ActionsOutput{Name: "version", Value: res.Info.Tag},
+		commit.ActionsOutput{Name: "previous", Value: res.Info.PreviousTag},
+		commit.ActionsOutput{Name: "release_url", Value: release.URL(res.Info)},
+		commit.ActionsOutput{Name: "body", Value: res.Notes},
+	)
+	if err != nil {
+		return fmt.Errorf("writing the step outputs: %w", err)
+	}
+	return nil
+}
+
+// usedFlags returns the values of the flags that are set on the command line.
+func usedFlags(cmd *cobra.Command) map[string]string {
+	flags := make(map[string]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
+
+// commentIssues comments on the issues referenced in the logs of the release.
+func commentIssues(ctx context.Context, g *commit.Git, token string, info *commit.ReleaseInfo) error {
+	r, err := g.GetReleaseByTag(ctx, token, info.User, info.Repo, info.Tag)
+	if err != nil {
+		return err
+	}
+	summary, err := g.CommentIssues(ctx, token, info.User, info.Repo, info.Logs, commit.IssueCommentOptions{
+		Tag:      info.Tag,
+		URL:      r.HTMLURL,
+		Template: commentTpl,
+		Limit:    maxComment,
+	})
+	fmt.Printf("issues: %d commented, %d closed, %d in other repos, %d over the limit, %d failed\n",
+		summary.Commented, summary.Closed, summary.CrossRepo, summary.OverLimit, summary.Failed)
+	return err
+}
+
+// pullNotices returns the notices of the notify-pulls flag, or zero if it's
+// not set.
+func pullNotices() (commit.PullNotice, error) {
+	if pullNotice == "" {
+		return 0, nil
+	}
+	return commit.ParsePullNotice(pullNotice)
+}
+
+// notifyPulls comments on, or labels, the pull requests of the release that
+// they are included in it, and returns the numbers of the notified ones.
+func notifyPulls(ctx context.Context, g *commit.Git, token string, info *commit.ReleaseInfo, notice commit.PullNotice) ([]int, error) {
+	r, err := g.GetReleaseByTag(ctx, token, info.User, info.Repo, info.Tag)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := g.NotifyPulls(ctx, token, info.User, info.Repo, info.Commits, commit.PullNoticeOptions{
+		Tag:      info.Tag,
+		URL:      r.HTMLURL,
+		Notice:   notice,
+		Template: pullComment,
+		Label:    pullLabel,
+		Limit:    maxNotices,
+	})
+	fmt.Printf("pull requests: %d notified, %d from forks, %d already labelled, %d already commented, %d over the limit, %d failed\n",
+		len(summary.Notified), summary.Forks, summary.Labelled, summary.Commented, summary.OverLimit, summary.Failed)
+	return summary.Notified, err
+}
+
+// labelPullRequests adds the labels of the commit types and scopes to the pull
+// requests of the release.
+func labelPullRequests(ctx context.Context, g *commit.Git, token string, info *commit.ReleaseInfo) error {
+	m, err := commit.ParseLabelMap(labelMap)
+	if err != nil {
+		return err
+	}
+	summary, err := g.LabelPulls(ctx, token, info.User, info.Repo, info.Logs, commit.LabelOptions{Map: m})
+	fmt.Printf("pull requests: %d labelled, %d unchanged, %d not pull requests, %d failed\n",
+		summary.Labelled, summary.Unchanged, summary.NotPull, summary.Failed)
+	return err
+}
+
+// nonConventional adds a warning for each commit of the release that doesn't
+// follow the conventional commits. The merge commits of the pull requests are
+// checked with the titles in their Logs.
+func nonConventional(info *commit.ReleaseInfo) {
+	commits := make([]commit.Commit, 0, len(info.Commits))
+	for i, c := range info.Commits {
+		if i < len(info.Logs) {
+			c.Message = info.Logs[i]
+		}
+		commits = append(commits, c)
+	}
+	for _, c := range commit.Lint(commits, nil) {
+		sha := c.SHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		warnings.Add(commit.WarnNonConventional, "%s %q is listed under Misc", sha, c.Subject())
+	}
+}
+
+// associatePulls finds the pull requests of the commits, and adds their
+// numbers to the logs. Their labels are looked up too if the labels
+// classifier is used. The lookups from the API are skipped with the
+// no-pull-lookup flag. A failed lookup only leaves the commit without a
+// number, therefore it is printed as a warning.
+func associatePulls(ctx context.Context, g *commit.Git, token string, info *commit.ReleaseInfo) error {
+	cfg, err := classification(viper.GetViper(), info.Remote)
+	if err != nil {
+		return err
+	}
+	opts := commit.PullOptions{Offline: noPullAPI || offline, Labels: cfg.uses("labels")}
+	err = g.AssociatePulls(ctx, token, info.Remote, info.Commits, opts)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		warnings.Add(commit.WarnPullLookup, "%v", err)
+	}
+	info.Logs = commit.PullLogs(info.Commits)
+	return nil
+}
+
+// newReleaseFlags returns the flags that only apply to the release, and not
+// to the other commands.
+func newReleaseFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("release", pflag.ContinueOnError)
+	flags.BoolVarP(&quiet, "quiet", "q", false, "don't show the progress of the release")
+	flags.StringVarP(&branch, "branch", "b", "", "only release from this branch. A detached HEAD at the tag is accepted.")
+	flags.StringVar(&onBranch, "require-on-branch", "", "only release if the tag is reachable from this branch")
+	flags.BoolVar(&trustLocal, "trust-local", false, "release the local tag even if it points to a different commit on the remote")
+	flags.BoolVar(&noFetch, "no-fetch", false, "fail instead of fetching the tag if the local one is missing or behind the remote")
+	flags.BoolVar(&trustRemote, "trust-remote", false, "replace the local tag with the remote one if they point to different commits")
+	flags.StringArray("extra-range", nil, "add the commits of another range to the release, e.g. v1.3.0..feature-freeze, or a revision for the range from it to the tag. The commits are counted once. Repeat for more, or set extra-ranges in the config file")
+	flags.StringVar(&fullNotes, "full-notes", "", "write the notes without the budget to this file")
+	flags.BoolVar(&fullAsset, "full-notes-asset", false, "upload the file of --full-notes as an asset")
+	flags.StringArrayVar(&assets, "asset", nil, "upload the files matching the glob, optionally renamed with a template: glob[=template]")
+	flags.StringToStringVar(&assetVars, "asset-var", nil, "variables for the asset name templates: key=value")
+	flags.StringVar(&signCmd, "sign-cmd", "", "sign each asset with this command before the release is published, e.g. \"cosign sign-blob --yes --output-signature {signature} {file}\"")
+	flags.StringVar(&signExt, "sign-ext", commit.DefaultSignatureExt, "extension of the signatures of the sign-cmd, which are uploaded next to the assets")
+	flags.BoolVar(&diffMode, "diff", false, "print the diff of the published notes and the generated ones, exits with 2 if they differ")
+	flags.BoolVar(&updateDiff, "update-if-changed", false, "update the notes of the published release if they differ")
+	flags.BoolVar(&comment, "comment-issues", false, "comment on the issues referenced in the commits after the release is published")
+	flags.StringVar(&commentTpl, "issue-comment", commit.DefaultIssueComment, "template of the issue comments, with .Tag, .URL and .Issue")
+	flags.IntVar(&maxComment, "max-issue-comments", 20, "maximum number of issues to comment on, 0 for no limit")
+	flags.StringVar(&pullNotice, "notify-pulls", "", "tell the merged pull requests of the release that they are included in it after it's published: comment, label or both")
+	flags.StringVar(&pullComment, "pull-comment", commit.DefaultPullComment, "template of the pull request comments, with .Tag, .URL and .Pull")
+	flags.StringVar(&pullLabel, "released-label", commit.DefaultReleasedLabel, "template of the label of the released pull requests, with .Tag, .URL and .Pull; the pull requests that have it are skipped")
+	flags.IntVar(&maxNotices, "max-pull-notices", 50, "maximum number of pull requests to notify, 0 for no limit")
+	flags.BoolVar(&strictTerms, "strict-terms", false, "fail before the release is published if the notes have any of the terms of the config file")
+	flags.BoolVar(&fixTerms, "fix-terms", false, "replace the terms of the config file in the notes with their replacements")
+	flags.BoolVar(&labelPulls, "label-pulls", false, "add the labels of the commit types and scopes to their pull requests before the notes are generated")
+	flags.StringArrayVar(&labelMap, "pr-label", nil, "map a commit type, type(scope) or (scope) to pull request labels: key=label[,label]. Defaults to feat=enhancement, fix=bug and docs=documentation")
+	flags.StringVar(&langOutput, "lang-output", "files", "how to output the other languages: files writes them to the lang-dir, sections adds them to the notes")
+	flags.StringVar(&langDir, "lang-dir", ".", "folder of the notes of the other languages")
+	flags.BoolVar(&langAssets, "lang-assets", false, "upload the notes of the other languages as assets")
+	flags.BoolVar(&noCache, "no-cache", false, "read the commits and their pull requests again instead of the ones in the cache-dir, and replace them")
+	flags.BoolVar(&provenance, "provenance", false, "add how the release is produced as a hidden comment at the end of the notes")
+	flags.StringVar(&channel, "channel", "", "only consider the tags of this release channel of the config file, e.g. beta, and use its prerelease, since and template settings")
+	flags.StringVar(&newBranch, "create-branch", "", "create and push a branch from the commit of the tag after the release, e.g. release/{major}.{minor}, with {tag}, {version}, {major}, {minor}, {patch} and {prerelease}")
+	flags.StringVar(&loginsFile, "logins-file", ".gitrelease-logins", "file of the GitHub logins of the authors in the .mailmap style: a login and its emails on each line, e.g. jsmith <j.smith@example.com>")
+	flags.BoolVar(&reqChecks, "require-checks", false, "refuse to release unless the checks of the commit of the tag have passed, or the required ones of the checks in the config file")
+	flags.BoolVar(&skipChecks, "skip-checks", false, "release without verifying the checks of the commit of the tag")
+	flags.DurationVar(&checksWait, "checks-wait", 0, "wait this long for the pending checks of the commit of the tag before refusing to release")
+	flags.BoolVar(&noActions, "no-actions-output", false, "don't write the notes into the job summary and the version into the step outputs of GitHub Actions")
+	flags.StringVar(&jsonResult, "json-result", "", "write the result of the release and its provenance as JSON into the file, - for stdout")
+	flags.String("highlights-file", "", "start the notes with the highlights of the version from this file, e.g. HIGHLIGHTS.md, or set highlights.file in the config file")
+	flags.Bool("require-highlights", false, "fail if the version has no highlights, or set highlights.required in the config file")
+	flags.StringVar(&notesFile, "notes-file", "", "use the content of this file as the notes instead of generating them, - reads the stdin")
+	flags.StringVar(&appendFile, "notes-append-file", "", "append the content of this file to the notes, - reads the stdin")
+	flags.BoolVar(&allowEmpty, "allow-empty", false, "release even if there are no changes since the previous tag")
+	flags.String("initial-version", commit.DefaultInitialVersion, "suggested tag of the first release when the repository has no tags, or set initial_version in the config file")
+	emailFlags(flags)
+
+	cobra.CheckErr(viper.BindPFlag("extra-ranges", flags.Lookup("extra-range")))
+	cobra.CheckErr(viper.BindPFlag("initial_version", flags.Lookup("initial-version")))
+	cobra.CheckErr(viper.BindPFlag("highlights.file", flags.Lookup("highlights-file")))
+	cobra.CheckErr(viper.BindPFlag("highlights.required", flags.Lookup("require-highlights")))
+	return flags
+}
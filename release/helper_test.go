@@ -0,0 +1,132 @@
+package release_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/arsham/gitrelease/commit"
+)
+
+// fakeRunner returns the output of the function instead of running git.
+type fakeRunner func(args []string) (string, error)
+
+func (f fakeRunner) Run(_ context.Context, _ string, stdout io.Writer, args ...string) error {
+	out, err := f(args)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, out)
+	return err
+}
+
+// offlineRunner runs git, except for the commands that need the remote, which
+// are answered by the remote function.
+type offlineRunner struct {
+	remote func(args []string) (string, error)
+}
+
+func (r offlineRunner) Run(ctx context.Context, dir string, stdout io.Writer, args ...string) error {
+	switch args[0] {
+	case "ls-remote", "fetch", "push":
+		return fakeRunner(r.remote).Run(ctx, dir, stdout, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	out := &strings.Builder{}
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		code := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		return &commit.GitError{Args: args, ExitCode: code, Stderr: out.String(), Err: err}
+	}
+	return nil
+}
+
+// recorder records the calls of the fake stages in order.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// fakePublisher is a Publisher that records its calls, and fails with the
+// errors that are set.
+type fakePublisher struct {
+	*recorder
+	createErr error
+	uploadErr error
+	verifyErr error
+}
+
+var draft = &commit.ReleaseDetails{ID: 42, HTMLURL: "https://github.com/user/repo/releases/tag/untagged-1"}
+
+func (p *fakePublisher) Create(_ context.Context, info *commit.ReleaseInfo, notes string, isDraft bool) (*commit.ReleaseDetails, error) {
+	if isDraft {
+		p.add("draft " + info.Tag + ": " + notes)
+	} else {
+		p.add("release " + info.Tag + ": " + notes)
+	}
+	if p.createErr != nil {
+		return nil, p.createErr
+	}
+	if !isDraft {
+		return nil, nil
+	}
+	return draft, nil
+}
+
+func (p *fakePublisher) Upload(_ context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, assets []commit.Asset) (commit.UploadSummary, error) {
+	names := make([]string, 0, len(assets))
+	for _, a := range assets {
+		names = append(names, a.Name)
+	}
+	target := info.Tag
+	if r != nil {
+		target = "draft"
+	}
+	p.add("upload " + target + ": " + strings.Join(names, ","))
+	if p.uploadErr != nil {
+		return commit.UploadSummary{Failed: len(assets)}, p.uploadErr
+	}
+	return commit.UploadSummary{Uploaded: len(assets)}, nil
+}
+
+func (p *fakePublisher) Verify(context.Context, *commit.ReleaseInfo, *commit.ReleaseDetails, []commit.Asset) error {
+	p.add("verify")
+	return p.verifyErr
+}
+
+func (p *fakePublisher) Publish(context.Context, *commit.ReleaseInfo, *commit.ReleaseDetails) error {
+	p.add("publish")
+	return nil
+}
+
+// fakeNotifier records the announcements.
+type fakeNotifier struct {
+	*recorder
+	err error
+}
+
+func (n fakeNotifier) Notify(_ context.Context, a commit.Announcement) error {
+	n.add("notify " + a.Tag + " " + a.URL)
+	return n.err
+}
@@ -0,0 +1,579 @@
+// Package release runs the whole release of a tag: it resolves the tag and its
+// commits since the previous tag, builds the notes, publishes the release with
+// its assets and announces it. Each stage can be replaced, e.g. to embed the
+// releases in a bot instead of running the gitrelease binary.
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+)
+
+// ErrNoChanges is returned by Run when the tag has no changes since the
+// previous tag, unless the AllowEmpty of the Config is set.
+var ErrNoChanges = errors.New("no changes")
+
+// Config is the release of a tag. Only the Git is required.
+type Config struct {
+	// Git runs the git commands and the requests of the GitHub API of the
+	// default stages. Its Progress receives the stages, its Metrics their
+	// durations, and its Warnings the failures that don't stop the release.
+	Git *commit.Git
+	// Token authenticates the requests of the GitHub API. If it's empty, the
+	// TokenSource of the Git is used.
+	Token string
+	// Tag is the tag to release. It defaults to the latest tag.
+	Tag string
+	// CreateTag creates the Tag on the Target and pushes it before the
+	// release. Without a Tag, the InitialTag of the Git is created if the
+	// repository has no tags.
+	CreateTag bool
+	// Target is the revision of the created tag. It defaults to HEAD.
+	Target string
+	// Next releases the untagged HEAD with the next version of the previous
+	// tag, see NextTag. After the notes are built, the tag is created on the
+	// HEAD as an annotated tag with the notes as its message, and pushed
+	// before the release is published. It can't be used with CreateTag. In
+	// a DryRun, the steps are only returned in the Plan of the Result.
+	Next bool
+	// NoTag releases the tag of the HEAD instead of creating one, e.g. to
+	// resume a Next release that has failed after the tag is created.
+	NoTag bool
+	// NoPush leaves the tag of a Next release local. It requires NoPublish,
+	// since a release of a tag that is not on the remote would tag the
+	// default branch instead.
+	NoPush bool
+	// NoPublish stops a Next release after its tag is pushed.
+	NoPublish bool
+	// VersionFiles are updated to the version of the created tag, and
+	// committed on the HEAD as "chore(release): <tag>", which is then
+	// tagged instead of the Target. The Target can only be the HEAD. The
+	// commit is not pushed, only the tag that points to it. In a DryRun, the
+	// changes are only returned in the Result.
+	VersionFiles []commit.VersionFile
+	// AllowEmpty releases the tag even if it has no changes since the
+	// previous tag.
+	AllowEmpty bool
+	// DryRun stops after the notes are built, without signing or publishing
+	// anything.
+	DryRun bool
+	// Direct creates the release as published and uploads the assets
+	// afterwards. Otherwise the release is a draft until its assets are
+	// uploaded and verified.
+	Direct bool
+	// Assets are uploaded with the release. The templates of their names
+	// have the Tag, the Version and the AssetVars.
+	Assets    []commit.AssetMapping
+	AssetVars map[string]string
+	// Branch is the template of the release branch that is created on the
+	// commit of the tag and pushed after the release is published, e.g.
+	// "release/{major}.{minor}". See commit.Git.BranchName for the
+	// placeholders. No branch is created if it's empty, or in a DryRun.
+	Branch string
+	// Checks are the checks of the commit of the tag that should pass before
+	// anything is published. The commit is the Target if the tag is created.
+	// The checks are not verified if it's nil, or in a DryRun.
+	Checks *commit.ChecksOptions
+	// Offline runs without the network: the Git is set Offline, the run is
+	// a DryRun, and the tag is not fetched. The pull requests are only the
+	// ones of the commit messages. A stage that still reaches the network
+	// fails with commit.ErrOffline.
+	Offline bool
+	// Notifiers announce the release if it's published by the run.
+	Notifiers []commit.Notifier
+	// NotifyRequired fails the release if an announcement can't be sent.
+	// Otherwise the failures are added to the Warnings of the Git.
+	NotifyRequired bool
+
+	// Tagger creates the tag. It defaults to a GitTagger.
+	Tagger Tagger
+	// Resolver finds the tag and its commits. It defaults to a GitResolver.
+	Resolver Resolver
+	// Changelog builds the notes. It defaults to a Notes without options.
+	// The Notes and the GitHub without a Git use the Git and the Token of
+	// the Config.
+	Changelog Changelog
+	// Signer signs the assets if it's set.
+	Signer Signer
+	// Publisher publishes the release. It defaults to a GitHub publisher.
+	Publisher Publisher
+}
+
+// Result is what Run has done. It is returned with the errors too, with the
+// stages that have finished.
+type Result struct {
+	Info  *commit.ReleaseInfo
+	Notes string
+	// Assets are the assets of the release, including the ones of the
+	// Changelog and the signatures.
+	Assets     []commit.Asset
+	Signatures []commit.SignedAsset
+	// Created is true if the release is published by the run. It's false if
+	// the release already existed, and only the assets are uploaded.
+	Created bool
+	// Existed is true if the release already existed.
+	Existed bool
+	// Published is the time the release is published by the run. It's zero
+	// if the release is not Created.
+	Published time.Time
+	Uploads   commit.UploadSummary
+	// Branch is the release branch created by the run. It's empty if the
+	// Branch of the Config is not set, or the branch already existed.
+	Branch string
+	// VersionChanges are the changes of the VersionFiles, which are not
+	// written in a DryRun. VersionCommit is the commit of the changes.
+	VersionChanges []commit.VersionChange
+	VersionCommit  string
+	// Plan is what a Next release would do, in a DryRun.
+	Plan []string
+	// Changes are what the run has changed in the repository and on the
+	// remote so far, in order, e.g. the created tag.
+	Changes []string
+}
+
+// UnpublishedError is returned by Run when the draft release is left
+// unpublished because its assets couldn't be uploaded or verified.
+type UnpublishedError struct {
+	Release *commit.ReleaseDetails
+	Err     error
+}
+
+func (e *UnpublishedError) Error() string {
+	return fmt.Sprintf("%v\nthe release is not published, the draft is left at %s", e.Err, e.Release.HTMLURL)
+}
+
+func (e *UnpublishedError) Unwrap() error { return e.Err }
+
+// IncompleteError is returned by Run when it fails after it has changed the
+// repository or the remote, e.g. when the tag is pushed but the release can't
+// be created. The Changes are what has already been done.
+type IncompleteError struct {
+	Changes []string
+	Err     error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%v\nalready done:\n  - %s", e.Err, strings.Join(e.Changes, "\n  - "))
+}
+
+func (e *IncompleteError) Unwrap() error { return e.Err }
+
+// URL returns the address of the release page of the tag.
+func URL(info *commit.ReleaseInfo) string {
+	return info.Remote.HTMLURL() + "/releases/tag/" + url.PathEscape(info.Tag)
+}
+
+// Run releases the tag of the cfg. The stages are run in order: updating the
+// VersionFiles and creating the tag if it's asked for, resolving the tag and
+// its commits, verifying the Checks, building the notes, signing the assets,
+// publishing the release with its assets, creating the release Branch, and
+// announcing it. A Next release creates and pushes its tag after the notes
+// are built. It returns commit.ErrEmptyRepository if the repository of the
+// Git has no commits. If the repository has no tags, the latest tag is the
+// first release with the InitialTag of the Git, which is only published if
+// CreateTag is set. An error after the repository or the remote has changed
+// is an IncompleteError.
+func Run(ctx context.Context, cfg Config) (res Result, err error) {
+	if cfg.Git == nil {
+		return res, errors.New("the release has no Git")
+	}
+	if cfg.Next {
+		if cfg.CreateTag {
+			return res, errors.New("the next release creates its own tag, it can't be used with CreateTag")
+		}
+		if cfg.NoPush && !cfg.NoPublish {
+			return res, errors.New("the release can't be published without pushing its tag")
+		}
+	}
+	defer func() {
+		if err != nil && len(res.Changes) > 0 {
+			err = &IncompleteError{Changes: res.Changes, Err: err}
+		}
+	}()
+	if cfg.Offline {
+		if cfg.CreateTag {
+			return res, fmt.Errorf("%w: the created tag can't be pushed", commit.ErrOffline)
+		}
+		cfg.Git.Offline, cfg.DryRun = true, true
+	}
+	cfg.defaults()
+	tag := cfg.Tag
+	if cfg.CreateTag {
+		if tag == "" || tag == "@" {
+			// The first release is tagged with the suggested version.
+			_, err := cfg.Git.LatestTag(ctx)
+			switch {
+			case errors.Is(err, commit.ErrNoTag):
+				tag = cfg.Git.InitialTag()
+			case err != nil:
+				return res, err
+			default:
+				return res, errors.New("the created tag has no name")
+			}
+		}
+		target := cfg.Target
+		if target == "" {
+			target = "HEAD"
+		}
+		if err := cfg.verifyChecks(ctx, target); err != nil {
+			return res, err
+		}
+		target, err := cfg.bumpVersion(ctx, tag, target, &res)
+		if err != nil {
+			return res, err
+		}
+		done := cfg.step("Creating the tag")
+		err = cfg.Tagger.Tag(ctx, tag, target)
+		done(err)
+		if err != nil {
+			return res, cfg.emptyError(ctx, err)
+		}
+	}
+
+	if cfg.Next {
+		if tag, err = cfg.nextTag(ctx); err != nil {
+			return res, cfg.emptyError(ctx, err)
+		}
+	}
+	done := cfg.step("Discovering tags")
+	info, err := cfg.Resolver.Resolve(ctx, tag)
+	done(err)
+	if err != nil {
+		return res, cfg.emptyError(ctx, err)
+	}
+	res.Info = info
+	if info.Untagged && !cfg.DryRun && !cfg.Next {
+		return res, fmt.Errorf("%w: the first release %s is not tagged yet", commit.ErrNoTag, info.Tag)
+	}
+	// The excluded commits are changes too, even if they are not in the
+	// notes.
+	if len(info.Logs) == 0 && info.Excluded == 0 && !cfg.AllowEmpty {
+		return res, fmt.Errorf("%w since %s", ErrNoChanges, info.PreviousTag)
+	}
+	if !cfg.CreateTag {
+		rev := info.Tag
+		if info.Untagged && info.Target != "" {
+			rev = info.Target
+		}
+		if err := cfg.verifyChecks(ctx, rev); err != nil {
+			return res, err
+		}
+	}
+	vars := map[string]string{
+		"Tag":     info.Tag,
+		"Version": strings.TrimPrefix(info.Tag, "v"),
+	}
+	for k, v := range cfg.AssetVars {
+		vars[k] = v
+	}
+	// Collisions should be found before anything is published.
+	res.Assets, err = commit.ResolveAssets(cfg.Assets, vars)
+	if err != nil {
+		return res, err
+	}
+
+	done = cfg.step("Generating notes")
+	notes, extra, err := cfg.Changelog.Notes(ctx, info)
+	done(err)
+	if err != nil {
+		return res, err
+	}
+	res.Notes = notes
+	res.Assets = append(res.Assets, extra...)
+	if cfg.Next {
+		if err := cfg.tagNext(ctx, info, &res); err != nil {
+			return res, err
+		}
+		if cfg.NoPublish {
+			return res, nil
+		}
+	}
+	if cfg.DryRun {
+		return res, nil
+	}
+
+	if cfg.Signer != nil {
+		done = cfg.step("Signing assets")
+		sigs, signed, err := cfg.Signer.Sign(ctx, res.Assets)
+		done(err)
+		if err != nil {
+			return res, err
+		}
+		res.Assets = append(res.Assets, sigs...)
+		res.Signatures = signed
+	}
+
+	if err := cfg.publish(ctx, info, &res); err != nil {
+		return res, err
+	}
+	if res.Created {
+		res.Changes = append(res.Changes, "published the release "+info.Tag)
+	}
+	if err := cfg.createBranch(ctx, info, &res); err != nil {
+		return res, err
+	}
+	if res.Branch != "" {
+		res.Changes = append(res.Changes, "created the release branch "+res.Branch)
+	}
+	if !res.Created || len(cfg.Notifiers) == 0 {
+		return res, nil
+	}
+	err = commit.NotifyAll(ctx, commit.Announcement{
+		Project: info.Repo,
+		Tag:     info.Tag,
+		URL:     URL(info),
+		Notes:   res.Notes,
+	}, cfg.Notifiers...)
+	if err != nil && !cfg.NotifyRequired {
+		cfg.Git.Warnings.Add(commit.WarnNotify, "%v", err)
+		return res, nil
+	}
+	return res, err
+}
+
+// nextTag returns the tag to resolve for the Next release: the NextTag, or
+// the tag of the HEAD with NoTag.
+func (c *Config) nextTag(ctx context.Context) (string, error) {
+	if !c.NoTag {
+		return NextTag, nil
+	}
+	head, err := c.Git.Head(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(head.Tags) == 0 {
+		return "", fmt.Errorf("%w: HEAD has no tag to release", commit.ErrNoTag)
+	}
+	return head.Tags[0], nil
+}
+
+// tagNext commits the VersionFiles, creates the tag of the Next release on
+// the Target of the info, or on the commit of the files, with the notes as
+// its message, and pushes it. The tag is not created with NoTag, and not
+// pushed with NoPush. In a DryRun, the steps are only added to the Plan.
+func (c *Config) tagNext(ctx context.Context, info *commit.ReleaseInfo, res *Result) error {
+	remote := c.Git.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	if !c.NoTag {
+		target := info.Target
+		if len(c.VersionFiles) > 0 {
+			bumped, err := c.bumpVersion(ctx, info.Tag, "HEAD", res)
+			if err != nil {
+				return err
+			}
+			switch {
+			case c.DryRun:
+				target = "the commit of the version files"
+				res.Plan = append(res.Plan, fmt.Sprintf("commit the changes of %d version files on HEAD", len(res.VersionChanges)))
+			case res.VersionCommit != "":
+				target = bumped
+				res.Changes = append(res.Changes, "committed the version files as "+res.VersionCommit)
+			}
+		}
+		if c.DryRun {
+			res.Plan = append(res.Plan, fmt.Sprintf("create the annotated tag %s on %s", info.Tag, target))
+		} else {
+			done := c.step("Creating the tag")
+			err := c.Git.CreateAnnotatedTag(ctx, info.Tag, target, res.Notes)
+			done(err)
+			if err != nil {
+				return err
+			}
+			res.Changes = append(res.Changes, fmt.Sprintf("created the annotated tag %s on %s", info.Tag, target))
+			info.Untagged = false
+			c.Git.Refresh()
+		}
+	}
+	if !c.NoPush {
+		if c.DryRun {
+			res.Plan = append(res.Plan, fmt.Sprintf("push the tag %s to %s", info.Tag, remote))
+		} else {
+			done := c.step("Pushing the tag")
+			err := c.Git.PushTag(ctx, info.Tag)
+			done(err)
+			if err != nil {
+				return err
+			}
+			res.Changes = append(res.Changes, fmt.Sprintf("pushed the tag %s to %s", info.Tag, remote))
+		}
+	}
+	if c.DryRun && !c.NoPublish {
+		res.Plan = append(res.Plan, "publish the release "+info.Tag)
+	}
+	return nil
+}
+
+// bumpVersion updates the VersionFiles to the version of the tag and commits
+// them, and returns the commit to tag. In a DryRun, the changes are only
+// added to the res, and the target is tagged.
+func (c *Config) bumpVersion(ctx context.Context, tag, target string, res *Result) (string, error) {
+	if len(c.VersionFiles) == 0 {
+		return target, nil
+	}
+	if target != "HEAD" {
+		return "", fmt.Errorf("the version files are committed on the HEAD, they can't be updated for target %s", target)
+	}
+	var err error
+	if c.DryRun {
+		res.VersionChanges, err = c.Git.VersionChanges(c.VersionFiles, tag)
+		return target, err
+	}
+	done := c.step("Updating the version files")
+	res.VersionChanges, res.VersionCommit, err = c.Git.BumpVersion(ctx, c.VersionFiles, tag)
+	done(err)
+	if err != nil || res.VersionCommit == "" {
+		return target, err
+	}
+	return res.VersionCommit, nil
+}
+
+// emptyError returns commit.ErrEmptyRepository instead of the err if the
+// repository of the Git has no commits, e.g. when a custom Resolver fails on
+// it with the error of git.
+func (c *Config) emptyError(ctx context.Context, err error) error {
+	if c.Git == nil || errors.Is(err, commit.ErrEmptyRepository) {
+		return err
+	}
+	if empty, _ := c.Git.IsEmpty(ctx); empty {
+		return commit.ErrEmptyRepository
+	}
+	return err
+}
+
+// createBranch creates the release branch of the Branch template on the
+// commit of the tag.
+func (c *Config) createBranch(ctx context.Context, info *commit.ReleaseInfo, res *Result) error {
+	if c.Branch == "" {
+		return nil
+	}
+	name, err := c.Git.BranchName(c.Branch, info.Tag)
+	if err != nil {
+		return err
+	}
+	done := c.step("Creating the release branch")
+	created, err := c.Git.CreateReleaseBranch(ctx, name, info.Tag)
+	done(err)
+	if created {
+		res.Branch = name
+	}
+	return err
+}
+
+// verifyChecks returns a commit.ChecksError if the Checks of the commit of
+// the rev haven't passed.
+func (c *Config) verifyChecks(ctx context.Context, rev string) error {
+	if c.Checks == nil || c.DryRun {
+		return nil
+	}
+	remote, err := c.Git.RemoteInfo(ctx)
+	if err != nil {
+		return err
+	}
+	done := c.step("Verifying the checks")
+	_, err = c.Git.VerifyChecks(ctx, c.Token, remote.Owner, remote.Name, rev, *c.Checks)
+	done(err)
+	return err
+}
+
+// publish publishes the release with the Publisher. Unless Direct is set, the
+// release is created as a draft, and is only published after the assets are
+// uploaded and verified. If the release exists, only the assets are uploaded.
+func (c *Config) publish(ctx context.Context, info *commit.ReleaseInfo, res *Result) error {
+	title := "Creating the draft release"
+	if c.Direct {
+		title = "Creating the release"
+	}
+	done := c.step(title)
+	r, err := c.Publisher.Create(ctx, info, res.Notes, !c.Direct)
+	done(err)
+	if errors.Is(err, commit.ErrReleaseExists) && len(res.Assets) > 0 {
+		// The assets of a previous run might have failed to upload.
+		res.Existed = true
+		return c.upload(ctx, info, nil, res)
+	}
+	if err != nil {
+		return err
+	}
+	if c.Direct {
+		res.Created, res.Published = true, time.Now().UTC()
+		return c.upload(ctx, info, nil, res)
+	}
+
+	if err := c.upload(ctx, info, r, res); err != nil {
+		return &UnpublishedError{Release: r, Err: err}
+	}
+	done = c.step("Verifying the release")
+	err = c.Publisher.Verify(ctx, info, r, res.Assets)
+	done(err)
+	if err != nil {
+		return &UnpublishedError{Release: r, Err: err}
+	}
+	done = c.step("Publishing the release")
+	err = c.Publisher.Publish(ctx, info, r)
+	done(err)
+	if err != nil {
+		return &UnpublishedError{Release: r, Err: err}
+	}
+	res.Created, res.Published = true, time.Now().UTC()
+	return nil
+}
+
+// upload uploads the assets of the res to the r, or to the release of the tag
+// if r is nil.
+func (c *Config) upload(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, res *Result) error {
+	if len(res.Assets) == 0 {
+		return nil
+	}
+	done := c.step("Uploading assets")
+	var err error
+	res.Uploads, err = c.Publisher.Upload(ctx, info, r, res.Assets)
+	done(err)
+	return err
+}
+
+// defaults sets the stages that are not set, and the Git and the Token of the
+// default stages that don't have them.
+func (c *Config) defaults() {
+	if n, ok := c.Changelog.(Notes); ok && n.Git == nil {
+		n.Git, n.Token = c.Git, c.Token
+		c.Changelog = n
+	}
+	if p, ok := c.Publisher.(GitHub); ok && p.Git == nil {
+		p.Git, p.Token = c.Git, c.Token
+		c.Publisher = p
+	}
+	if c.Tagger == nil {
+		c.Tagger = GitTagger{Git: c.Git}
+	}
+	if c.Resolver == nil {
+		c.Resolver = GitResolver{Git: c.Git}
+	}
+	if c.Changelog == nil {
+		c.Changelog = Notes{Git: c.Git, Token: c.Token}
+	}
+	if c.Publisher == nil {
+		c.Publisher = GitHub{Git: c.Git, Token: c.Token}
+	}
+}
+
+// step reports the start of the step to the Progress and the Metrics of the
+// Git, and returns the function that reports its result.
+func (c *Config) step(name string) func(err error) {
+	record := c.Git.Metrics.Start(name)
+	if c.Git.Progress == nil {
+		return record
+	}
+	done := c.Git.Progress.Start(name)
+	return func(err error) {
+		record(err)
+		done(err)
+	}
+}
@@ -0,0 +1,792 @@
+package release_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/arsham/gitrelease/commit/committest"
+	"github.com/arsham/gitrelease/release"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfig returns the config of the release of v1.1.0 with fake stages,
+// which record their calls in the rec.
+func newConfig(rec *recorder) (release.Config, *fakePublisher) {
+	p := &fakePublisher{recorder: rec}
+	return release.Config{
+		Git: &commit.Git{Warnings: &commit.Warnings{}},
+		Tag: "@",
+		Resolver: release.ResolverFunc(func(_ context.Context, tag string) (*commit.ReleaseInfo, error) {
+			rec.add("resolve " + tag)
+			return &commit.ReleaseInfo{
+				Tag:         "v1.1.0",
+				PreviousTag: "v1.0.0",
+				User:        "user",
+				Repo:        "repo",
+				Remote:      commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"},
+				Logs:        []string{"feat: add the thing"},
+			}, nil
+		}),
+		Changelog: release.ChangelogFunc(func(context.Context, *commit.ReleaseInfo) (string, []commit.Asset, error) {
+			rec.add("notes")
+			return "the notes", []commit.Asset{{Path: "CHANGELOG.md", Name: "CHANGELOG.md"}}, nil
+		}),
+		Publisher: p,
+		Notifiers: []commit.Notifier{fakeNotifier{recorder: rec}},
+	}, p
+}
+
+func assertCalls(t *testing.T, want []string, rec *recorder) {
+	t.Helper()
+	if diff := cmp.Diff(want, rec.list()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+const releasePage = "https://github.com/user/repo/releases/tag/v1.1.0"
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	t.Run("Draft", testRunDraft)
+	t.Run("Direct", testRunDirect)
+	t.Run("Exists", testRunExists)
+	t.Run("Unpublished", testRunUnpublished)
+	t.Run("DryRun", testRunDryRun)
+	t.Run("NoChanges", testRunNoChanges)
+	t.Run("CreateTag", testRunCreateTag)
+	t.Run("VersionFiles", testRunVersionFiles)
+	t.Run("Assets", testRunAssets)
+	t.Run("Notify", testRunNotify)
+	t.Run("NoGit", testRunNoGit)
+	t.Run("Checks", testRunChecks)
+	t.Run("Branch", testRunBranch)
+	t.Run("Next", testRunNext)
+}
+
+func testRunDraft(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Git.Metrics = &commit.Metrics{}
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.True(t, res.Created)
+	assert.False(t, res.Existed)
+	assert.Equal(t, "the notes", res.Notes)
+	assert.Equal(t, "v1.1.0", res.Info.Tag)
+	assert.Equal(t, commit.UploadSummary{Uploaded: 1}, res.Uploads)
+	assertCalls(t, []string{
+		"resolve @",
+		"notes",
+		"draft v1.1.0: the notes",
+		"upload draft: CHANGELOG.md",
+		"verify",
+		"publish",
+		"notify v1.1.0 " + releasePage,
+	}, rec)
+	var stages []string
+	for _, s := range cfg.Git.Metrics.Report().Stages {
+		stages = append(stages, s.Name)
+	}
+	assert.Equal(t, []string{
+		"Discovering tags",
+		"Generating notes",
+		"Creating the draft release",
+		"Uploading assets",
+		"Verifying the release",
+		"Publishing the release",
+	}, stages)
+}
+
+func testRunDirect(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Direct = true
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.True(t, res.Created)
+	assertCalls(t, []string{
+		"resolve @",
+		"notes",
+		"release v1.1.0: the notes",
+		"upload v1.1.0: CHANGELOG.md",
+		"notify v1.1.0 " + releasePage,
+	}, rec)
+}
+
+func testRunExists(t *testing.T) {
+	t.Parallel()
+	t.Run("Assets", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{}
+		cfg, p := newConfig(rec)
+		p.createErr = commit.ErrReleaseExists
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.False(t, res.Created)
+		assert.True(t, res.Existed)
+		assertCalls(t, []string{
+			"resolve @",
+			"notes",
+			"draft v1.1.0: the notes",
+			"upload v1.1.0: CHANGELOG.md",
+		}, rec)
+	})
+	t.Run("NoAssets", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{}
+		cfg, p := newConfig(rec)
+		p.createErr = commit.ErrReleaseExists
+		cfg.Changelog = release.ChangelogFunc(func(context.Context, *commit.ReleaseInfo) (string, []commit.Asset, error) {
+			return "the notes", nil, nil
+		})
+		_, err := release.Run(context.Background(), cfg)
+		assert.ErrorIs(t, err, commit.ErrReleaseExists)
+	})
+}
+
+func testRunUnpublished(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		upload error
+		verify error
+		want   []string
+	}{
+		"upload": {
+			upload: errors.New("upload failed"),
+			want:   []string{"resolve @", "notes", "draft v1.1.0: the notes", "upload draft: CHANGELOG.md"},
+		},
+		"verify": {
+			verify: errors.New("verify failed"),
+			want:   []string{"resolve @", "notes", "draft v1.1.0: the notes", "upload draft: CHANGELOG.md", "verify"},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := &recorder{}
+			cfg, p := newConfig(rec)
+			p.uploadErr, p.verifyErr = tc.upload, tc.verify
+			res, err := release.Run(context.Background(), cfg)
+			var unpublished *release.UnpublishedError
+			require.ErrorAs(t, err, &unpublished)
+			assert.Equal(t, draft, unpublished.Release)
+			assert.Contains(t, err.Error(), "the draft is left at "+draft.HTMLURL)
+			assert.False(t, res.Created)
+			assertCalls(t, tc.want, rec)
+		})
+	}
+}
+
+func testRunDryRun(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.DryRun = true
+	cfg.Signer = fakeSigner{rec}
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "the notes", res.Notes)
+	assert.Len(t, res.Assets, 1)
+	assert.False(t, res.Created)
+	assertCalls(t, []string{"resolve @", "notes"}, rec)
+
+	// The Notes uses the Git of the config.
+	cfg.Changelog = release.Notes{Offline: true}
+	res, err = release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "No changes since v1.0.0.", res.Notes)
+}
+
+func testRunNoChanges(t *testing.T) {
+	t.Parallel()
+	empty := release.ResolverFunc(func(context.Context, string) (*commit.ReleaseInfo, error) {
+		return &commit.ReleaseInfo{Tag: "v1.1.0", PreviousTag: "v1.0.0"}, nil
+	})
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Resolver = empty
+	_, err := release.Run(context.Background(), cfg)
+	require.ErrorIs(t, err, release.ErrNoChanges)
+	assert.EqualError(t, err, "no changes since v1.0.0")
+	assert.Empty(t, rec.list())
+
+	cfg.AllowEmpty = true
+	cfg.DryRun = true
+	_, err = release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assertCalls(t, []string{"notes"}, rec)
+}
+
+func testRunCreateTag(t *testing.T) {
+	t.Parallel()
+	var args []string
+	g := &commit.Git{
+		Remote: "upstream",
+		Runner: fakeRunner(func(a []string) (string, error) {
+			args = append(args, strings.Join(a, " "))
+			return "", nil
+		}),
+	}
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Git = g
+	cfg.Tag = "v1.1.0"
+	cfg.CreateTag = true
+	cfg.DryRun = true
+	_, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tag v1.1.0 HEAD", "push upstream refs/tags/v1.1.0"}, args)
+	assertCalls(t, []string{"resolve v1.1.0", "notes"}, rec)
+
+	cfg.Tag = "v1.2.0"
+	cfg.Git.Runner = fakeRunner(func(a []string) (string, error) {
+		return "", &commit.GitError{Args: a, ExitCode: 1, Stderr: "rejected"}
+	})
+	_, err = release.Run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "rejected")
+}
+
+func testRunVersionFiles(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial", committest.File{Path: "VERSION", Content: "1.0.0\n"})
+	r.Tag("v1.0.0")
+	r.Commit("feat: add the thing")
+	head := r.Head()
+	var pushed []string
+	runner := offlineRunner{remote: func(args []string) (string, error) {
+		pushed = append(pushed, strings.Join(args, " "))
+		return "", nil
+	}}
+	newVersionConfig := func() (release.Config, *recorder) {
+		rec := &recorder{}
+		cfg, _ := newConfig(rec)
+		cfg.Git = &commit.Git{Dir: r.Dir, Runner: runner}
+		cfg.Resolver = nil
+		cfg.Tag = "v1.1.0"
+		cfg.CreateTag = true
+		cfg.VersionFiles = []commit.VersionFile{{Path: "VERSION"}}
+		return cfg, rec
+	}
+
+	t.Run("Target", func(t *testing.T) {
+		cfg, _ := newVersionConfig()
+		cfg.Target = head
+		_, err := release.Run(context.Background(), cfg)
+		assert.ErrorContains(t, err, "committed on the HEAD")
+		assert.Equal(t, head, r.Head())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		cfg, _ := newVersionConfig()
+		cfg.Tag = "v1.0.1"
+		cfg.DryRun = true
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		require.Len(t, res.VersionChanges, 1)
+		assert.Equal(t, "1.0.1\n", res.VersionChanges[0].New)
+		assert.Empty(t, res.VersionCommit)
+		assert.Equal(t, head, r.Head())
+		assert.Equal(t, "1.0.0", strings.TrimSpace(r.Run("show", "HEAD:VERSION")))
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		cfg, rec := newVersionConfig()
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		require.Len(t, res.VersionChanges, 1)
+		assert.Equal(t, r.Head(), res.VersionCommit)
+		assert.Equal(t, res.VersionCommit, strings.TrimSpace(r.Run("rev-parse", "v1.1.0^{commit}")))
+		assert.Equal(t, "1.1.0", strings.TrimSpace(r.Run("show", "v1.1.0:VERSION")))
+		assert.Contains(t, pushed, "push origin refs/tags/v1.1.0")
+		assert.Contains(t, rec.list(), "draft v1.1.0: the notes")
+	})
+}
+
+// fakeSigner signs the assets with the ".sig" files.
+type fakeSigner struct{ *recorder }
+
+func (s fakeSigner) Sign(_ context.Context, assets []commit.Asset) ([]commit.Asset, []commit.SignedAsset, error) {
+	var (
+		sigs   []commit.Asset
+		signed []commit.SignedAsset
+	)
+	for _, a := range assets {
+		s.add("sign " + a.Name)
+		sigs = append(sigs, commit.Asset{Path: a.Path + ".sig", Name: a.Name + ".sig"})
+		signed = append(signed, commit.SignedAsset{Asset: a.Name, Signature: a.Name + ".sig"})
+	}
+	return sigs, signed, nil
+}
+
+func testRunAssets(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app_linux.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("app"), 0o600))
+
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Assets = []commit.AssetMapping{{Glob: path, Name: "app-{{.Version}}-{{.Arch}}.tar.gz"}}
+	cfg.AssetVars = map[string]string{"Arch": "amd64"}
+	cfg.Signer = fakeSigner{rec}
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []commit.SignedAsset{
+		{Asset: "app-1.1.0-amd64.tar.gz", Signature: "app-1.1.0-amd64.tar.gz.sig"},
+		{Asset: "CHANGELOG.md", Signature: "CHANGELOG.md.sig"},
+	}, res.Signatures)
+	assertCalls(t, []string{
+		"resolve @",
+		"notes",
+		"sign app-1.1.0-amd64.tar.gz",
+		"sign CHANGELOG.md",
+		"draft v1.1.0: the notes",
+		"upload draft: app-1.1.0-amd64.tar.gz,CHANGELOG.md,app-1.1.0-amd64.tar.gz.sig,CHANGELOG.md.sig",
+		"verify",
+		"publish",
+		"notify v1.1.0 " + releasePage,
+	}, rec)
+
+	cfg.Assets = []commit.AssetMapping{{Glob: filepath.Join(dir, "*.zip")}}
+	_, err = release.Run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "no files match")
+}
+
+func testRunNotify(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Notifiers = []commit.Notifier{fakeNotifier{recorder: rec, err: errors.New("smtp is down")}}
+	_, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	warnings := cfg.Git.Warnings.List()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, commit.WarnNotify, warnings[0].Code)
+
+	cfg.NotifyRequired = true
+	_, err = release.Run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "smtp is down")
+}
+
+func testRunNoGit(t *testing.T) {
+	t.Parallel()
+	_, err := release.Run(context.Background(), release.Config{})
+	assert.Error(t, err)
+}
+
+func testRunChecks(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.Commit("feat: add the thing")
+	r.Tag("v1.1.0")
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	var passed int32
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/check-runs") {
+			conclusion := "failure"
+			if atomic.LoadInt32(&passed) == 1 {
+				conclusion = "success"
+			}
+			_, err := w.Write([]byte(`{"check_runs": [{"name": "build", "status": "completed", "conclusion": "` + conclusion + `"}]}`))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte(`{"statuses": []}`))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(gh.Close)
+
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Git = &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+	cfg.Token = "token"
+	cfg.Checks = &commit.ChecksOptions{Required: []string{"build"}}
+	_, err := release.Run(context.Background(), cfg)
+	require.ErrorIs(t, err, commit.ErrChecksFailed)
+	assert.ErrorContains(t, err, "failed: build")
+	assertCalls(t, []string{"resolve @"}, rec)
+
+	// The checks are not verified without publishing.
+	rec = &recorder{}
+	cfg, _ = newConfig(rec)
+	cfg.Git = &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+	cfg.Checks = &commit.ChecksOptions{Required: []string{"build"}}
+	cfg.DryRun = true
+	_, err = release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assertCalls(t, []string{"resolve @", "notes"}, rec)
+
+	atomic.StoreInt32(&passed, 1)
+	rec = &recorder{}
+	cfg, _ = newConfig(rec)
+	cfg.Git = &commit.Git{Dir: r.Dir, BaseURL: gh.URL}
+	cfg.Token = "token"
+	cfg.Checks = &commit.ChecksOptions{Required: []string{"build"}}
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.True(t, res.Created)
+}
+
+func TestGitResolver(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v0.1.0")
+	r.Commit("docs: the readme")
+	r.Tag("v1.0.0")
+	r.Commit("feat: add the thing")
+	r.Commit("fix: the leak")
+	r.Tag("v1.1.0")
+	head := r.Head()
+
+	tcs := map[string]struct {
+		tag    string
+		remote string
+		want   string
+		err    bool
+	}{
+		"latest":     {tag: "@", want: "v1.1.0"},
+		"empty":      {tag: "", want: "v1.1.0"},
+		"named":      {tag: "v1.0.0", want: "v1.0.0"},
+		"pushed":     {tag: "v1.1.0", remote: head + "\trefs/tags/v1.1.0\n", want: "v1.1.0"},
+		"diverged":   {tag: "v1.1.0", remote: strings.Repeat("a", 40) + "\trefs/tags/v1.1.0\n", err: true},
+		"not exists": {tag: "v2.0.0", err: true},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var calls []string
+			g := &commit.Git{
+				Dir: r.Dir,
+				Runner: offlineRunner{remote: func(args []string) (string, error) {
+					calls = append(calls, args[0])
+					if args[0] == "fetch" {
+						return "", &commit.GitError{Args: args, ExitCode: 128, Stderr: "couldn't find remote ref"}
+					}
+					return tc.remote, nil
+				}},
+			}
+			info, err := release.GitResolver{Git: g}.Resolve(context.Background(), tc.tag)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, info.Tag)
+			assert.Equal(t, []string{"ls-remote"}, calls)
+			assert.Equal(t, "https://api.github.com", g.BaseURL)
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	t.Parallel()
+	info := &commit.ReleaseInfo{
+		Tag:         "v1.1.0",
+		PreviousTag: "v1.0.0",
+		CompareURL:  "https://github.com/user/repo/compare/v1.0.0...v1.1.0",
+		Remote:      commit.RemoteInfo{Host: "github.com", Owner: "user", Name: "repo"},
+		Commits: []commit.Commit{
+			{SHA: "aaa", Message: "feat: add the thing (#3)\n", PRNumber: 3},
+		},
+	}
+	n := release.Notes{Git: &commit.Git{}, Offline: true, CompareLink: true}
+	notes, assets, err := n.Notes(context.Background(), info)
+	require.NoError(t, err)
+	assert.Empty(t, assets)
+	assert.Equal(t, "### Feature\n\n- Add the thing (#3)\n\n**Full Changelog**: "+info.CompareURL, notes)
+
+	info.Commits = nil
+	notes, _, err = n.Notes(context.Background(), info)
+	require.NoError(t, err)
+	assert.Equal(t, "No changes since v1.0.0.", notes)
+}
+
+func TestRunEmptyRepository(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t)
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Git = &commit.Git{Dir: r.Dir}
+	cfg.Resolver = release.ResolverFunc(func(context.Context, string) (*commit.ReleaseInfo, error) {
+		return nil, &commit.GitError{Args: []string{"ls-remote"}, ExitCode: 128, Stderr: "fatal: bad revision 'HEAD'"}
+	})
+	_, err := release.Run(context.Background(), cfg)
+	assert.True(t, errors.Is(err, commit.ErrEmptyRepository), err)
+}
+
+func TestRunInitialRelease(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Commit("feat: add the thing")
+	var pushed []string
+	runner := offlineRunner{remote: func(args []string) (string, error) {
+		pushed = append(pushed, strings.Join(args, " "))
+		return "", nil
+	}}
+	newInitialConfig := func() (release.Config, *recorder) {
+		rec := &recorder{}
+		cfg, _ := newConfig(rec)
+		cfg.Git = &commit.Git{Dir: r.Dir, Runner: runner, InitialVersion: "v0.2.0"}
+		cfg.Resolver = nil
+		cfg.Changelog = release.Notes{Offline: true, CompareLink: true}
+		return cfg, rec
+	}
+
+	// The subtests are in order, since the last one creates the tag.
+	t.Run("DryRun", func(t *testing.T) {
+		cfg, _ := newInitialConfig()
+		cfg.DryRun = true
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "v0.2.0", res.Info.Tag)
+		assert.True(t, res.Info.Initial)
+		assert.True(t, res.Info.Untagged)
+		assert.Equal(t, "This is the first release.\n\n"+
+			"### Feature\n\n- Add the thing\n\n\n"+
+			"### Chore\n\n- Initial\n\n"+
+			"**Full Changelog**: https://github.com/user/repo/commits/v0.2.0", res.Notes)
+	})
+
+	t.Run("Untagged", func(t *testing.T) {
+		cfg, rec := newInitialConfig()
+		_, err := release.Run(context.Background(), cfg)
+		assert.ErrorIs(t, err, commit.ErrNoTag)
+		assert.Empty(t, rec.list())
+	})
+
+	t.Run("CreateTag", func(t *testing.T) {
+		cfg, rec := newInitialConfig()
+		cfg.CreateTag = true
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "v0.2.0", res.Info.Tag)
+		assert.True(t, res.Info.Initial)
+		assert.False(t, res.Info.Untagged)
+		assert.Contains(t, pushed, "push origin refs/tags/v0.2.0")
+		assert.Contains(t, rec.list(), "draft v0.2.0: "+res.Notes)
+
+		// The next release without a name can't guess it.
+		cfg, _ = newInitialConfig()
+		cfg.CreateTag = true
+		_, err = release.Run(context.Background(), cfg)
+		assert.ErrorContains(t, err, "the created tag has no name")
+	})
+}
+
+func testRunBranch(t *testing.T) {
+	t.Parallel()
+	remote := committest.NewRepo(t)
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.Commit("feat: add the thing")
+	r.Tag("v1.1.0")
+	r.Commit("fix: after the release")
+	r.AddRemote("origin", remote.Dir)
+
+	rec := &recorder{}
+	cfg, _ := newConfig(rec)
+	cfg.Git = &commit.Git{Dir: r.Dir, Warnings: &commit.Warnings{}}
+	cfg.Branch = "release/{major}.{minor}"
+	res, err := release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "release/1.1", res.Branch)
+	assert.Equal(t, r.Run("rev-parse", "v1.1.0^{commit}"), remote.Run("rev-parse", "refs/heads/release/1.1"))
+
+	// The branch is already there.
+	res, err = release.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Empty(t, res.Branch)
+	assert.Equal(t, 1, cfg.Git.Warnings.Len())
+
+	cfg.Branch = "release/{major}"
+	r.Run("push", "-q", "origin", "HEAD:refs/heads/release/1")
+	_, err = release.Run(context.Background(), cfg)
+	require.ErrorIs(t, err, commit.ErrBranchExists)
+}
+
+func TestRunOffline(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	r.Commit("feat: add the thing")
+	r.Commit("fix: the leak (#12)")
+	r.Tag("v1.1.0")
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	t.Cleanup(srv.Close)
+	newOfflineConfig := func() (release.Config, *recorder) {
+		rec := &recorder{}
+		cfg, _ := newConfig(rec)
+		cfg.Git = &commit.Git{Dir: r.Dir, BaseURL: srv.URL, Warnings: &commit.Warnings{}}
+		cfg.Token = "token"
+		cfg.Offline = true
+		cfg.Resolver = nil
+		cfg.Changelog = release.Notes{CompareLink: true}
+		cfg.Checks = &commit.ChecksOptions{}
+		cfg.Branch = "release/{major}.{minor}"
+		return cfg, rec
+	}
+
+	t.Run("Notes", func(t *testing.T) {
+		t.Parallel()
+		cfg, rec := newOfflineConfig()
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "### Feature\n\n- Add the thing\n\n\n"+
+			"### Fix\n\n- The leak (#12)\n\n"+
+			"**Full Changelog**: https://github.com/user/repo/compare/v1.0.0...v1.1.0", res.Notes)
+		assert.False(t, res.Created)
+		assert.Empty(t, res.Branch)
+		assert.Empty(t, rec.list())
+		assert.True(t, cfg.Git.Offline)
+		assert.Empty(t, cfg.Git.OfflineViolations())
+		assert.Empty(t, cfg.Git.Warnings.List())
+		assert.Zero(t, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("CreateTag", func(t *testing.T) {
+		t.Parallel()
+		cfg, rec := newOfflineConfig()
+		cfg.CreateTag = true
+		cfg.Tag = "v1.2.0"
+		_, err := release.Run(context.Background(), cfg)
+		require.ErrorIs(t, err, commit.ErrOffline)
+		assert.Empty(t, rec.list())
+		assert.Empty(t, r.Run("tag", "--list", "v1.2.0"))
+	})
+}
+
+func testRunNext(t *testing.T) {
+	t.Parallel()
+	r := committest.NewRepo(t, committest.WithIdentity("arsham", "arsham@github.com"))
+	r.AddRemote("origin", "git@github.com:user/repo.git")
+	r.Commit("chore: initial")
+	r.Tag("v1.0.0")
+	r.Commit("feat: add the thing")
+	head := r.Head()
+	var pushErr error
+	var pushed []string
+	runner := offlineRunner{remote: func(args []string) (string, error) {
+		if args[0] == "push" {
+			pushed = append(pushed, strings.Join(args, " "))
+			return "", pushErr
+		}
+		return "", nil
+	}}
+	newNextConfig := func() (release.Config, *recorder) {
+		rec := &recorder{}
+		cfg, _ := newConfig(rec)
+		cfg.Git = &commit.Git{Dir: r.Dir, Runner: runner}
+		cfg.Resolver = nil
+		cfg.Next = true
+		return cfg, rec
+	}
+
+	// The subtests are in order, since the later ones create the tag.
+	t.Run("Invalid", func(t *testing.T) {
+		cfg, rec := newNextConfig()
+		cfg.NoPush = true
+		_, err := release.Run(context.Background(), cfg)
+		assert.ErrorContains(t, err, "without pushing its tag")
+
+		cfg, _ = newNextConfig()
+		cfg.CreateTag = true
+		_, err = release.Run(context.Background(), cfg)
+		assert.Error(t, err)
+		assert.Empty(t, rec.list())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		cfg, rec := newNextConfig()
+		cfg.DryRun = true
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.0", res.Info.Tag)
+		assert.Equal(t, []string{
+			"create the annotated tag v1.1.0 on " + head,
+			"push the tag v1.1.0 to origin",
+			"publish the release v1.1.0",
+		}, res.Plan)
+		assert.Empty(t, res.Changes)
+		assertCalls(t, []string{"notes"}, rec)
+		assert.Empty(t, r.Run("tag", "--list", "v1.1.0"))
+		assert.Empty(t, pushed)
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		pushErr = errors.New("rejected")
+		defer func() { pushErr = nil }()
+		cfg, rec := newNextConfig()
+		res, err := release.Run(context.Background(), cfg)
+		var incomplete *release.IncompleteError
+		require.ErrorAs(t, err, &incomplete)
+		assert.Equal(t, []string{"created the annotated tag v1.1.0 on " + head}, res.Changes)
+		assert.ErrorContains(t, err, "rejected\nalready done:\n  - created the annotated tag v1.1.0")
+		assert.Equal(t, "tag", strings.TrimSpace(r.Run("cat-file", "-t", "v1.1.0")))
+		assert.Equal(t, []string{"notes"}, rec.list())
+
+		// The tag is there already.
+		cfg, _ = newNextConfig()
+		_, err = release.Run(context.Background(), cfg)
+		assert.ErrorIs(t, err, commit.ErrHeadTagged)
+	})
+
+	t.Run("NoTag", func(t *testing.T) {
+		cfg, rec := newNextConfig()
+		cfg.NoTag = true
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"pushed the tag v1.1.0 to origin",
+			"published the release v1.1.0",
+		}, res.Changes)
+		assert.Contains(t, pushed, "push origin refs/tags/v1.1.0")
+		assert.Contains(t, rec.list(), "draft v1.1.0: the notes")
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		r.Commit("fix: the leak")
+		cfg, rec := newNextConfig()
+		res, err := release.Run(context.Background(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.1.1", res.Info.Tag)
+		assert.Equal(t, []string{
+			"created the annotated tag v1.1.1 on " + r.Head(),
+			"pushed the tag v1.1.1 to origin",
+			"published the release v1.1.1",
+		}, res.Changes)
+		assert.Equal(t, "the notes", strings.TrimSpace(r.Run("tag", "--list", "--format=%(contents)", "v1.1.1")))
+		assertCalls(t, []string{
+			"notes",
+			"draft v1.1.1: the notes",
+			"upload draft: CHANGELOG.md",
+			"verify",
+			"publish",
+			"notify v1.1.1 https://github.com/user/repo/releases/tag/v1.1.1",
+		}, rec)
+	})
+}
@@ -0,0 +1,213 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arsham/gitrelease/commit"
+)
+
+// Tagger creates the tag on the target before the release.
+type Tagger interface {
+	Tag(ctx context.Context, tag, target string) error
+}
+
+// Resolver finds the tag, the previous tag and the commits between them. The
+// tag is empty or "@" for the latest tag, or NextTag for the next release.
+type Resolver interface {
+	Resolve(ctx context.Context, tag string) (*commit.ReleaseInfo, error)
+}
+
+// ResolverFunc is a function that is a Resolver.
+type ResolverFunc func(ctx context.Context, tag string) (*commit.ReleaseInfo, error)
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(ctx context.Context, tag string) (*commit.ReleaseInfo, error) {
+	return f(ctx, tag)
+}
+
+// Changelog builds the notes of the release, and the assets that go with
+// them, e.g. their translations.
+type Changelog interface {
+	Notes(ctx context.Context, info *commit.ReleaseInfo) (string, []commit.Asset, error)
+}
+
+// ChangelogFunc is a function that is a Changelog.
+type ChangelogFunc func(ctx context.Context, info *commit.ReleaseInfo) (string, []commit.Asset, error)
+
+// Notes calls f.
+func (f ChangelogFunc) Notes(ctx context.Context, info *commit.ReleaseInfo) (string, []commit.Asset, error) {
+	return f(ctx, info)
+}
+
+// Signer returns the signatures of the assets, which are uploaded with them.
+// The commit.Signer is a Signer.
+type Signer interface {
+	Sign(ctx context.Context, assets []commit.Asset) ([]commit.Asset, []commit.SignedAsset, error)
+}
+
+// Publisher publishes the releases on a provider.
+type Publisher interface {
+	// Create creates the release of the tag with the notes, as a draft if
+	// draft is set. It returns commit.ErrReleaseExists if the release
+	// exists. The details can be nil if the release is not a draft.
+	Create(ctx context.Context, info *commit.ReleaseInfo, notes string, draft bool) (*commit.ReleaseDetails, error)
+	// Upload uploads the assets to the r, or to the release of the tag if r
+	// is nil.
+	Upload(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, assets []commit.Asset) (commit.UploadSummary, error)
+	// Verify checks that the assets of the draft are uploaded.
+	Verify(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, assets []commit.Asset) error
+	// Publish publishes the draft.
+	Publish(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails) error
+}
+
+// NextTag is the tag Run resolves for a Next release: the next version of the
+// untagged HEAD. See commit.Git.PrepareNext.
+const NextTag = "@next"
+
+// GitTagger creates the tags with git, and pushes them to the Remote of the
+// Git.
+type GitTagger struct {
+	Git *commit.Git
+}
+
+// Tag creates the tag on the target and pushes it.
+func (t GitTagger) Tag(ctx context.Context, tag, target string) error {
+	if err := t.Git.CreateTag(ctx, tag, target); err != nil {
+		return err
+	}
+	return t.Git.PushTag(ctx, tag)
+}
+
+// GitResolver resolves the tags of the repository of the Git. The tag is
+// fetched if it's missing or behind the one of the remote, unless the Git is
+// Offline. The BaseURL of the Git is set to the API of the remote if it's
+// empty.
+type GitResolver struct {
+	Git *commit.Git
+}
+
+// Resolve returns the information of the release of the tag. If the tag is
+// empty or "@" and the repository has no tags, the info is the Untagged first
+// release of the commits up to the HEAD. The NextTag is the next release of
+// the HEAD, see commit.Git.PrepareNext.
+func (r GitResolver) Resolve(ctx context.Context, tag string) (*commit.ReleaseInfo, error) {
+	if tag == NextTag {
+		info, err := r.Git.PrepareNext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if r.Git.BaseURL == "" {
+			r.Git.BaseURL = info.Remote.APIBaseURL()
+		}
+		return info, nil
+	}
+	if tag == "" || tag == "@" {
+		latest, err := r.Git.LatestTag(ctx)
+		switch {
+		case errors.Is(err, commit.ErrNoTag):
+			latest = "@"
+		case err != nil:
+			return nil, err
+		}
+		tag = latest
+	}
+	if tag != "@" && !r.Git.Offline {
+		if _, err := r.Git.SyncTag(ctx, tag, true); err != nil {
+			return nil, err
+		}
+	}
+	info, err := r.Git.Prepare(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	if r.Git.BaseURL == "" {
+		r.Git.BaseURL = info.Remote.APIBaseURL()
+	}
+	return info, nil
+}
+
+// Notes renders the notes of the commits, with the numbers of their pull
+// requests.
+type Notes struct {
+	Git   *commit.Git
+	Token string
+	// Offline only uses the pull request numbers of the commit messages. It's
+	// implied by an Offline Git.
+	Offline bool
+	// CompareLink ends the notes with the link to the compare page of the
+	// tags, or to the list of the commits of the first release.
+	CompareLink bool
+	// Stats ends the notes with the timing metrics of the commits, taking
+	// the time of the rendering as the publication.
+	Stats   bool
+	Options []commit.RenderOption
+}
+
+// Notes returns the notes of the release, rendered with the template of the
+// Channel of the Git. A failed lookup of the pull requests is added to the
+// Warnings of the Git.
+func (n Notes) Notes(ctx context.Context, info *commit.ReleaseInfo) (string, []commit.Asset, error) {
+	err := n.Git.AssociatePulls(ctx, n.Token, info.Remote, info.Commits, commit.PullOptions{Offline: n.Offline || n.Git.Offline})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "", nil, err
+	}
+	if err != nil {
+		n.Git.Warnings.Add(commit.WarnPullLookup, "%v", err)
+	}
+	info.Logs = commit.PullLogs(info.Commits)
+	if len(info.Logs) == 0 {
+		notes, err := n.Git.Channel.RenderNotes(info, commit.NoChangesNotes(info.PreviousTag, n.Options...))
+		return notes, nil, err
+	}
+	opts := n.Options
+	if info.Initial {
+		opts = append(opts[:len(opts):len(opts)], commit.WithInitialRelease())
+	}
+	// The first release links to the list of its commits.
+	if n.CompareLink && (info.PreviousTag != "" || info.Initial) {
+		opts = append(opts[:len(opts):len(opts)], commit.WithCompareLink(info.CompareURL), commit.WithCompareRanges(info.ExtraRanges))
+	}
+	if n.Stats {
+		opts = append(opts[:len(opts):len(opts)], commit.WithStats(commit.Stats(info.Commits, time.Now())))
+	}
+	notes, err := n.Git.Channel.RenderNotes(info, commit.ParseGroups(info.Logs, opts...))
+	return notes, nil, err
+}
+
+// GitHub publishes the releases on GitHub with the Git.
+type GitHub struct {
+	Git   *commit.Git
+	Token string
+	// Reupload replaces the assets that are already uploaded.
+	Reupload bool
+}
+
+// Create creates the release.
+func (p GitHub) Create(ctx context.Context, info *commit.ReleaseInfo, notes string, draft bool) (*commit.ReleaseDetails, error) {
+	if draft {
+		return p.Git.DraftRelease(ctx, p.Token, info.User, info.Repo, info.Tag, notes)
+	}
+	return nil, p.Git.Release(ctx, p.Token, info.User, info.Repo, info.Tag, notes)
+}
+
+// Upload uploads the assets.
+func (p GitHub) Upload(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, assets []commit.Asset) (commit.UploadSummary, error) {
+	if r == nil {
+		return p.Git.UploadAssets(ctx, p.Token, info.User, info.Repo, info.Tag, assets, p.Reupload)
+	}
+	return p.Git.UploadReleaseAssets(ctx, p.Token, info.User, info.Repo, r, assets, p.Reupload)
+}
+
+// Verify checks the assets of the draft.
+func (p GitHub) Verify(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails, assets []commit.Asset) error {
+	_, err := p.Git.VerifyRelease(ctx, p.Token, info.User, info.Repo, r.ID, assets)
+	return err
+}
+
+// Publish publishes the draft.
+func (p GitHub) Publish(ctx context.Context, info *commit.ReleaseInfo, r *commit.ReleaseDetails) error {
+	_, err := p.Git.PublishDraft(ctx, p.Token, info.User, info.Repo, r.ID)
+	return err
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/viper"
+)
+
+// renderOptions returns the options for rendering the notes from the flags.
+func renderOptions() ([]commit.RenderOption, error) {
+	level, err := commit.ParseSanitize(sanitize)
+	if err != nil {
+		return nil, err
+	}
+	opts := []commit.RenderOption{commit.WithSanitize(level, mentions...)}
+	if subItems {
+		opts = append(opts, commit.WithSubItems(maxItems))
+	}
+	mode, err := commit.ParseBodyMode(bodies)
+	if err != nil {
+		return nil, err
+	}
+	if mode != commit.BodyNone {
+		opts = append(opts, commit.WithBodies(mode, maxBody, nil))
+	}
+	if len(groupOrder) > 0 {
+		opts = append(opts, commit.WithGroupOrder(groupOrder...))
+	}
+	src, err := commit.ParseDateSource(dateSource)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, commit.WithDateSource(src))
+	policy, err := commit.ParseBreakingPolicy(breaking)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, commit.WithBreakingPolicy(policy))
+	localeOpt, err := localeOption()
+	if err != nil {
+		return nil, err
+	}
+	if localeOpt != nil {
+		opts = append(opts, localeOpt)
+	}
+	if budget > 0 || secBudget > 0 {
+		opts = append(opts, commit.WithBudget(budget, secBudget))
+	}
+	if toc {
+		opts = append(opts, commit.WithTOC(tocMin))
+	}
+	collapsed, err := collapsedGroups(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+	if len(collapsed) > 0 {
+		opts = append(opts, commit.WithCollapsed(collapsed...))
+	}
+	footers, err := footerSettings(viper.GetViper())
+	if err != nil {
+		return nil, err
+	}
+	if len(footers.Keys) > 0 {
+		opts = append(opts, commit.WithFooters(footers.Heading, footers.Keys...))
+	}
+	return opts, nil
+}
+
+// checkTerms reports the forbidden terms of the rendered notes as warnings,
+// after replacing the ones that have a replacement with the fix-terms flag.
+// It returns an error if any is left with the strict-terms flag. The name of
+// the notes, e.g. the file of a translation, starts the warnings.
+func checkTerms(name, notes string) (string, error) {
+	terms, err := forbiddenTerms(viper.GetViper())
+	if err != nil || len(terms) == 0 {
+		return notes, err
+	}
+	if fixTerms {
+		var n int
+		if notes, n = terms.Fix(notes); n > 0 {
+			fmt.Fprintf(os.Stderr, "replaced %d forbidden term(s) in %s\n", n, name)
+		}
+	}
+	matches := terms.Check(notes)
+	for _, m := range matches {
+		warnings.Add(commit.WarnForbiddenTerm, "%s: %s", name, m)
+	}
+	if strictTerms && len(matches) > 0 {
+		return "", fmt.Errorf("%d forbidden term(s) in %s with --strict-terms, see the warnings", len(matches), name)
+	}
+	return notes, nil
+}
+
+// sizeOption returns the option that adds the size hints of the commits to
+// their entries. The stats of the commits are only read when the hints are
+// asked for.
+func sizeOption(ctx context.Context, g *commit.Git, commits []commit.Commit) (commit.RenderOption, error) {
+	thresholds, err := commit.ParseSizeThresholds(sizeLimits)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.DiffStats(ctx, commits); err != nil {
+		return nil, err
+	}
+	lines := make([]int, len(commits))
+	for i, c := range commits {
+		lines[i] = c.Additions + c.Deletions
+		if c.FilesChanged == 0 {
+			// The merge commits have no stats.
+			lines[i] = -1
+		}
+	}
+	return commit.WithSizeHints(lines, thresholds), nil
+}
+
+// releaseNotes renders the notes of the release with the options of the flags,
+// followed by the extra options, and the template of the channel or of the
+// config file.
+func releaseNotes(ctx context.Context, g *commit.Git, info *commit.ReleaseInfo, extra ...commit.RenderOption) (string, error) {
+	opts, err := renderOptions()
+	if err != nil {
+		return "", err
+	}
+	opts = append(opts, extra...)
+	cfg, err := classification(viper.GetViper(), info.Remote)
+	if err != nil {
+		return "", err
+	}
+	clsOpts, err := cfg.classifyOptions(info.Commits)
+	if err != nil {
+		return "", err
+	}
+	opts = append(opts, clsOpts...)
+	// The changed files are only read for the dirs classifier.
+	if cfg.uses("dirs") && logsMatch(info, "the dirs classifier") {
+		if err := g.ChangedPaths(ctx, info.Commits); err != nil {
+			return "", err
+		}
+		paths := make([][]string, len(info.Commits))
+		for i, c := range info.Commits {
+			paths[i] = c.Paths
+		}
+		opts = append(opts, commit.WithPaths(paths))
+	}
+	internal := noteExcluded && info.Excluded > 0
+	if len(info.Logs) == 0 && !internal {
+		return renderTemplate(g, info, commit.NoChangesNotes(info.PreviousTag, opts...))
+	}
+	if info.Initial {
+		opts = append(opts, commit.WithInitialRelease())
+	}
+	// The first release links to the list of its commits.
+	if info.CompareURL != "" && (info.PreviousTag != "" || info.Initial) {
+		// The entries that are left out by the budget are on the page.
+		opts = append(opts, commit.WithMoreLink(info.CompareURL))
+		if compare {
+			opts = append(opts, commit.WithCompareLink(info.CompareURL), commit.WithCompareRanges(info.ExtraRanges))
+		}
+	}
+	if internal {
+		opts = append(opts, commit.WithInternalChanges(info.Excluded))
+	}
+	if backports {
+		originals, err := g.Backports(ctx, info.Remote, info.Commits)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, commit.WithBackports(originals))
+	}
+	if attribution || contributors {
+		authors := make([]string, len(info.Commits))
+		for i, c := range info.Commits {
+			authors[i] = c.Attribution()
+		}
+		if attribution && logsMatch(info, "--attribution") {
+			opts = append(opts, commit.WithAttribution(authors))
+		}
+		if contributors {
+			opts = append(opts, commit.WithContributors(authors))
+		}
+	}
+	if sizeHints && logsMatch(info, "--size-hints") {
+		opt, err := sizeOption(ctx, g, info.Commits)
+		if err != nil {
+			return "", err
+		}
+		opts = append(opts, opt)
+	}
+	if stats {
+		// The notes are rendered right before the release is published.
+		opts = append(opts, commit.WithStats(commit.Stats(info.Commits, time.Now())))
+	}
+	if links := commitLinks(info); links != nil {
+		// The bodies that are truncated are linked to their commits.
+		mode, _ := commit.ParseBodyMode(bodies)
+		opts = append(opts, commit.WithBodies(mode, maxBody, links))
+	}
+	depsOpt, err := dependencies(ctx, g, info)
+	if err != nil {
+		return "", err
+	}
+	if depsOpt != nil {
+		opts = append(opts, depsOpt)
+	}
+	return renderTemplate(g, info, commit.ParseGroups(info.Logs, opts...))
+}
+
+// renderTemplate returns the notes rendered with the template of the channel,
+// or with the template file of the config file if the channel has none, e.g.:
+//
+//	template: .gitrelease.tmpl
+//
+// The final new line of the file is not part of the notes.
+func renderTemplate(g *commit.Git, info *commit.ReleaseInfo, notes string) (string, error) {
+	path := viper.GetString("template")
+	if path == "" || (g.Channel != nil && g.Channel.Template != "") {
+		return g.Channel.RenderNotes(info, notes)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading the template: %w", err)
+	}
+	ch := &commit.Channel{}
+	if g.Channel != nil {
+		*ch = *g.Channel
+	}
+	ch.Template = strings.TrimSuffix(string(b), "\n")
+	return ch.RenderNotes(info, notes)
+}
+
+// withHighlights returns a copy of the info with the highlights of its tag,
+// which is the version being released.
+func withHighlights(g *commit.Git, info *commit.ReleaseInfo) (*commit.ReleaseInfo, error) {
+	hl, err := releaseHighlights(g, info.Tag)
+	if err != nil {
+		return nil, err
+	}
+	highlighted := *info
+	highlighted.Highlights = hl
+	return &highlighted, nil
+}
+
+// releaseHighlights returns the curated highlights of the tag from the file
+// of the highlights-file flag, or from the highlights of the config file:
+//
+//	highlights:
+//	  file: HIGHLIGHTS.md
+//	  required: true
+//
+// The text of the config file can have the highlights instead of the file:
+//
+//	highlights:
+//	  text: |
+//	    ## v1.5.0
+//
+//	    - The new dashboard.
+//
+// It returns an error if the tag has no highlights and they are required.
+func releaseHighlights(g *commit.Git, tag string) (string, error) {
+	text, err := highlightsText(viper.GetViper())
+	if err != nil {
+		return "", err
+	}
+	hl := commit.Highlights(text, strings.TrimPrefix(tag, g.TagPrefix))
+	if hl == "" && viper.GetBool("highlights.required") {
+		return "", fmt.Errorf("%s has no highlights, add them to the highlights file or drop --require-highlights", tag)
+	}
+	return hl, nil
+}
+
+// highlightsText returns the highlights of all the releases from the file or
+// the text of the highlights of the config file.
+func highlightsText(v *viper.Viper) (string, error) {
+	file, text := v.GetString("highlights.file"), v.GetString("highlights.text")
+	if file != "" && text != "" {
+		return "", errors.New("the highlights can be either in a file or in the text of the config file, not both")
+	}
+	if file == "" {
+		return text, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("reading the highlights: %w", err)
+	}
+	return string(b), nil
+}
+
+// logsMatch reports whether the logs of the info are the messages of its
+// commits, one for each, therefore the data of the commits can be passed to
+// the options that are in the order of the logs. Otherwise the feature is
+// skipped with a warning, instead of being attached to the wrong entries.
+func logsMatch(info *commit.ReleaseInfo, feature string) bool {
+	if len(info.Commits) == len(info.Logs) {
+		return true
+	}
+	warnings.Add(commit.WarnFeatureSkipped, "%s is skipped: the %d entries are not the %d commits",
+		feature, len(info.Logs), len(info.Commits))
+	return false
+}
+
+// commitLinks returns the addresses of the commits of the logs of the info,
+// or nil if the bodies are not rendered.
+func commitLinks(info *commit.ReleaseInfo) []string {
+	if bodies == "" || bodies == "none" || !logsMatch(info, "--bodies") {
+		return nil
+	}
+	links := make([]string, len(info.Commits))
+	for i, c := range info.Commits {
+		links[i] = info.Remote.CommitURL(c.SHA)
+	}
+	return links
+}
+
+// releaseBody returns the notes of the release, and the assets of their
+// translations. The content of the notes-file is used verbatim instead of the
+// generated notes, and the content of the notes-append-file is appended to
+// either of them.
+func releaseBody(ctx context.Context, g *commit.Git, info *commit.ReleaseInfo) (string, []commit.Asset, error) {
+	if notesFile == "-" && appendFile == "-" {
+		return "", nil, errors.New("--notes-file and --notes-append-file can't both read the stdin")
+	}
+	if fullAsset && fullNotes == "" {
+		return "", nil, errors.New("--full-notes-asset needs the file of --full-notes")
+	}
+	var (
+		desc       string
+		translated []commit.Asset
+		err        error
+	)
+	// The highlights are only in the notes of the primary language.
+	highlighted := info
+	if notesFile != "" {
+		if len(langs) > 1 {
+			return "", nil, errors.New("--notes-file can't be translated, use a single --lang")
+		}
+		desc, err = readNotes(notesFile)
+	} else {
+		highlighted, err = withHighlights(g, info)
+		if err == nil {
+			desc, err = releaseNotes(ctx, g, highlighted)
+		}
+		if err == nil {
+			desc, translated, err = translateNotes(ctx, g, info, desc)
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if fullNotes != "" && notesFile == "" {
+		full, err := writeFullNotes(ctx, g, highlighted)
+		if err != nil {
+			return "", nil, err
+		}
+		translated = append(translated, full...)
+	}
+	if appendFile != "" {
+		extra, err := readNotes(appendFile)
+		if err != nil {
+			return "", nil, err
+		}
+		desc += "\n\n" + extra
+	}
+	return desc, translated, nil
+}
+
+// writeFullNotes writes the notes without the budget to the full-notes file,
+// and returns its asset if the full-notes-asset flag is set.
+func writeFullNotes(ctx context.Context, g *commit.Git, info *commit.ReleaseInfo) ([]commit.Asset, error) {
+	full, err := releaseNotes(ctx, g, info, commit.WithBudget(0, 0))
+	if err != nil {
+		return nil, err
+	}
+	if full, err = checkTerms(fullNotes, full); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fullNotes, []byte(full+"\n"), 0o600); err != nil {
+		return nil, fmt.Errorf("writing the full notes: %w", err)
+	}
+	if !fullAsset {
+		return nil, nil
+	}
+	return []commit.Asset{{Path: fullNotes, Name: filepath.Base(fullNotes)}}, nil
+}
+
+// stdin is where the notes of "-" are read from.
+var stdin io.Reader = os.Stdin
+
+// readNotes returns the content of the file, or of the stdin if the name is
+// "-", without the surrounding blank lines. An empty content is an error,
+// therefore a blank release is never published by mistake.
+func readNotes(name string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if name == "-" {
+		b, err = io.ReadAll(stdin)
+		name = "stdin"
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the notes: %w", err)
+	}
+	notes := strings.Trim(string(b), "\r\n")
+	if strings.TrimSpace(notes) == "" {
+		return "", fmt.Errorf("the notes of %s are empty", name)
+	}
+	return notes, nil
+}
+
+// dependencies returns the option of the Dependencies section if the deps
+// flag is set. It returns nil on the first release, or if go.mod hasn't
+// changed.
+func dependencies(ctx context.Context, g *commit.Git, info *commit.ReleaseInfo) (commit.RenderOption, error) {
+	switch depsMode {
+	case "":
+		return nil, nil
+	case "add", "replace":
+	default:
+		return nil, fmt.Errorf("unknown deps mode %q, use add or replace", depsMode)
+	}
+	if info.PreviousTag == "" {
+		return nil, nil
+	}
+	changes, err := g.DependencyChanges(ctx, info.PreviousTag, info.Tag)
+	if err != nil {
+		return nil, err
+	}
+	section := commit.RenderDependencies(changes, depsAll)
+	if section == "" {
+		return nil, nil
+	}
+	return commit.WithDependencies(section, depsMode == "replace"), nil
+}
+
+// fitNotes returns the desc unchanged if GitHub accepts it as the body of the
+// release. Otherwise it returns the truncated desc, and the asset with the full
+// desc to be uploaded with the release. The footer, if not empty, is appended
+// to the body and is never truncated. The cleanup function removes the asset's
+// file.
+func fitNotes(tag, desc, footer string) (string, []commit.Asset, func(), error) {
+	name := commit.ChangelogAssetName(tag)
+	note := fmt.Sprintf("_The notes are truncated, the full changelog is attached as `%s`._", name)
+	limit := commit.MaxBodyLength
+	if footer != "" {
+		limit -= utf8.RuneCountInString(footer) + 2
+	}
+	body, truncated := commit.TruncateNotes(desc, limit, note)
+	if footer != "" {
+		body += "\n\n" + footer
+	}
+	if !truncated {
+		return body, nil, func() {}, nil
+	}
+	if failLong {
+		return "", nil, nil, fmt.Errorf("notes of %s have %d characters: %w", tag, utf8.RuneCountInString(desc), commit.ErrBodyTooLong)
+	}
+	dir, err := os.MkdirTemp("", "gitrelease")
+	if err != nil {
+		return "", nil, nil, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("removing %s: %v", dir, err)
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(desc+"\n"), 0o600); err != nil {
+		cleanup()
+		return "", nil, nil, err
+	}
+	fmt.Fprintf(os.Stderr, "the notes of %s are truncated, the full changelog is uploaded as %s\n", tag, name)
+	return body, []commit.Asset{{Path: path, Name: name}}, cleanup, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retagMessage string
+	retagFile    string
+	forcePush    bool
+	moveTo       string
+
+	retagCmd = &cobra.Command{
+		Use:   "retag <tag>",
+		Short: "Create the tag again as an annotated tag with a new message",
+		Long: `Create the tag again as an annotated tag with a new message, e.g. the fixed
+notes, on the same commit. A tag that is already pushed is only replaced with
+--force-push, and the push is rejected if the tag on the remote has changed in
+the meantime. The tag is only moved to another commit with --move-to. Use
+--print to only print the steps.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			msg, err := readRetagMessage(cmd)
+			if err != nil {
+				return err
+			}
+			g, err := newGit()
+			if err != nil {
+				return err
+			}
+			opts := commit.RetagOptions{
+				Message:   msg,
+				Target:    moveTo,
+				Move:      moveTo != "",
+				ForcePush: forcePush,
+			}
+			ctx := cmd.Context()
+			steps, err := g.PlanRetag(ctx, args[0], opts)
+			if err != nil {
+				return err
+			}
+			fmt.Println("The following will be done:")
+			for _, s := range steps {
+				fmt.Println("  " + s.Description)
+			}
+			if printMode {
+				return nil
+			}
+			if !assumeYes && !confirm(cmd, "Continue? [y/N] ") {
+				return errors.New("aborted")
+			}
+			if err := g.Retag(ctx, steps); err != nil {
+				return err
+			}
+			fmt.Printf("retagged %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+// readRetagMessage returns the message of the message flag, or the content of
+// the file of the file flag, which is read from the stdin if it's "-".
+func readRetagMessage(cmd *cobra.Command) (string, error) {
+	switch {
+	case retagMessage != "" && retagFile != "":
+		return "", errors.New("--message and --file can't be used together")
+	case retagMessage != "":
+		return retagMessage, nil
+	case retagFile == "":
+		return "", errors.New("the new message is not set, use --message or --file")
+	case retagFile == "-":
+		b, err := io.ReadAll(cmd.InOrStdin())
+		return string(b), err
+	}
+	b, err := os.ReadFile(retagFile)
+	return string(b), err
+}
+
+func init() {
+	retagCmd.Flags().StringVarP(&retagMessage, "message", "m", "", "the new message of the tag")
+	retagCmd.Flags().StringVarP(&retagFile, "file", "F", "", "read the new message of the tag from the file, - reads the stdin")
+	retagCmd.Flags().BoolVar(&forcePush, "force-push", false, "replace the tag on the remote if it's already pushed")
+	retagCmd.Flags().StringVar(&moveTo, "move-to", "", "create the tag on this revision, even if it's another commit")
+	retagCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "do not ask for confirmation")
+	rootCmd.AddCommand(retagCmd)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/arsham/gitrelease/commit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keepTag     bool
+	deleteLocal bool
+	assumeYes   bool
+
+	rollbackCmd = &cobra.Command{
+		Use:   "rollback <tag>",
+		Short: "Delete the release of a tag and the tag itself",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			g, token, user, repo, err := githubRepo(ctx)
+			if err != nil {
+				return err
+			}
+
+			opts := commit.RollbackOptions{
+				KeepTag:        keepTag,
+				DeleteLocalTag: deleteLocal,
+			}
+			steps, err := g.PlanRollback(ctx, token, user, repo, args[0], opts)
+			if err != nil {
+				return err
+			}
+			if len(steps) == 0 {
+				return fmt.Errorf("nothing to remove for %s", args[0])
+			}
+			fmt.Println("The following will be removed:")
+			for _, s := range steps {
+				fmt.Println("  " + s.Description)
+			}
+			if !assumeYes && !confirm(cmd, "Continue? [y/N] ") {
+				return errors.New("aborted")
+			}
+			if err := g.Rollback(ctx, steps); err != nil {
+				return err
+			}
+			fmt.Printf("removed %d item(s)\n", len(steps))
+			return nil
+		},
+	}
+)
+
+// confirm asks the question and returns true if the user answers yes.
+func confirm(cmd *cobra.Command, question string) bool {
+	fmt.Print(question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func init() {
+	rollbackCmd.Flags().BoolVar(&keepTag, "keep-tag", false, "only remove the GitHub release")
+	rollbackCmd.Flags().BoolVar(&deleteLocal, "delete-local", false, "also delete the local tag")
+	rollbackCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "do not ask for confirmation")
+	rootCmd.AddCommand(rollbackCmd)
+}
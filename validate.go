@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the config file with the same rules as a release",
+	Long: `Check the config file with the same rules as a release: the hosts, the
+classifiers, the groups, the forbidden terms, the footers, the channels, the
+highlights and the locales. The file defaults to the one of --config, or to
+.gitrelease.yaml.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		name := configFile
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			name = initConfigFile
+		}
+		v := viper.New()
+		v.SetConfigFile(name)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("reading the config file: %w", err)
+		}
+		if err := validateConfig(v); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("%s is valid\n", name)
+		return nil
+	},
+}
+
+// validateConfig checks the config of the v with the same rules as a release
+// does. All the channels and the locales are loaded, not only the ones of the
+// flags.
+func validateConfig(v *viper.Viper) error {
+	if _, err := hostURLs(v); err != nil {
+		return err
+	}
+	if err := checkClassify(v); err != nil {
+		return err
+	}
+	if _, err := collapsedGroups(v); err != nil {
+		return err
+	}
+	if _, err := forbiddenTerms(v); err != nil {
+		return err
+	}
+	if _, err := footerSettings(v); err != nil {
+		return err
+	}
+	for name := range v.GetStringMap("channels") {
+		if _, err := loadChannel(v, name); err != nil {
+			return err
+		}
+	}
+	if _, err := highlightsText(v); err != nil {
+		return err
+	}
+	if _, err := loadLanguage(v, "en"); err != nil {
+		return err
+	}
+	for code := range v.GetStringMap("locales") {
+		if _, err := loadLanguage(v, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,260 @@
+// Package version parses, compares and bumps the semantic versions of the
+// tags. The versions can have a "v" prefix, and the patch part can be
+// missing, e.g. "v1.2" is "v1.2.0". See https://semver.org for the precedence
+// of the versions.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version.
+type Version struct {
+	// Prefix is "v" if the version has one, otherwise it's empty.
+	Prefix string
+	Major  int
+	Minor  int
+	Patch  int
+	// Prerelease is the pre-release part after the "-", e.g. "rc.1".
+	Prerelease string
+	// Build is the build metadata after the "+". It doesn't change the
+	// precedence of the version.
+	Build string
+}
+
+// ParseError is returned by Parse when the input is not a semantic version.
+type ParseError struct {
+	Input  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%q is not a semantic version: %s", e.Input, e.Reason)
+}
+
+// Parse returns the Version of s. The "v" prefix is kept in the Prefix, and a
+// missing patch part is zero. The errors are ParseErrors with the reason.
+func Parse(s string) (Version, error) {
+	v := Version{}
+	fail := func(format string, a ...any) (Version, error) {
+		return Version{}, &ParseError{Input: s, Reason: fmt.Sprintf(format, a...)}
+	}
+	rest := s
+	if strings.HasPrefix(rest, "v") {
+		v.Prefix, rest = "v", rest[1:]
+	}
+	if rest == "" {
+		return fail("it's empty")
+	}
+	rest, build, hasBuild := strings.Cut(rest, "+")
+	if hasBuild {
+		if err := checkIdentifiers(build, false); err != nil {
+			return fail("build metadata %s", err)
+		}
+		v.Build = build
+	}
+	rest, pre, hasPre := strings.Cut(rest, "-")
+	if hasPre {
+		if err := checkIdentifiers(pre, true); err != nil {
+			return fail("pre-release %s", err)
+		}
+		v.Prerelease = pre
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fail("it should have a major, a minor and an optional patch part")
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	names := []string{"major", "minor", "patch"}
+	for i, p := range parts {
+		n, err := parseNumber(p)
+		if err != nil {
+			return fail("the %s part %s", names[i], err)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// MustParse is like Parse, but panics if s is not a semantic version. It's
+// meant for the constant versions.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parseNumber parses a numeric part, which can't have leading zeros.
+func parseNumber(s string) (int, error) {
+	switch {
+	case s == "":
+		return 0, fmt.Errorf("is empty")
+	case len(s) > 1 && s[0] == '0':
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%q is not a number", s)
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is too large", s)
+	}
+	return n, nil
+}
+
+// checkIdentifiers checks the dot separated identifiers of the pre-release
+// or the build metadata. The numeric identifiers of the pre-releases can't
+// have leading zeros.
+func checkIdentifiers(s string, pre bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("%q has an empty identifier", s)
+		}
+		numeric := true
+		for _, r := range id {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("%q has an invalid character %q", s, r)
+			}
+		}
+		if pre && numeric && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("%q has a leading zero in %q", s, id)
+		}
+	}
+	return nil
+}
+
+// String returns the version with its Prefix, e.g. "v1.2.0-rc.1+build.5".
+// The missing patch part of the parsed version is added.
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease returns true if the version has a pre-release part.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// IsPrerelease returns true if s is a semantic version with a pre-release
+// part.
+func IsPrerelease(s string) bool {
+	v, err := Parse(s)
+	return err == nil && v.IsPrerelease()
+}
+
+// Compare returns -1, 0 or +1 if the precedence of a is lower, equal or higher
+// than b's. The Prefix and the Build are ignored, and a pre-release has lower
+// precedence than its release.
+func Compare(a, b Version) int {
+	for _, c := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if n := compareInts(c[0], c[1]); n != 0 {
+			return n
+		}
+	}
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+	ida, idb := strings.Split(a.Prerelease, "."), strings.Split(b.Prerelease, ".")
+	for i := 0; i < len(ida) && i < len(idb); i++ {
+		if n := compareIdentifiers(ida[i], idb[i]); n != 0 {
+			return n
+		}
+	}
+	return compareInts(len(ida), len(idb))
+}
+
+// compareIdentifiers compares the identifiers of the pre-releases. The
+// numeric ones, which only have digits, are compared numerically, and have
+// lower precedence than the others. Therefore "-1" is alphanumeric, and the
+// numbers are not limited to the size of an int.
+func compareIdentifiers(a, b string) int {
+	numa, numb := isNumeric(a), isNumeric(b)
+	switch {
+	case numa && numb:
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if n := compareInts(len(a), len(b)); n != 0 {
+			return n
+		}
+	case numa:
+		return -1
+	case numb:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// isNumeric returns true if the identifier only has digits.
+func isNumeric(id string) bool {
+	return id != "" && strings.Trim(id, "0123456789") == ""
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Part is the part of a version that Bump increments. The values are the same
+// as the ones of commit.Bump.
+type Part int
+
+// These are the parts, ordered by their significance.
+const (
+	None Part = iota
+	Patch
+	Minor
+	Major
+)
+
+func (p Part) String() string {
+	switch p {
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	case Major:
+		return "major"
+	}
+	return "none"
+}
+
+// Bump returns the v with the part incremented, and the less significant
+// parts set to zero. The Prefix is kept, and the Prerelease and the Build are
+// dropped. Bumping None only drops them.
+func Bump(v Version, part Part) Version {
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	switch part {
+	case Major:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case Minor:
+		next.Minor, next.Patch = v.Minor+1, 0
+	case Patch:
+		next.Patch++
+	}
+	return next
+}
@@ -0,0 +1,273 @@
+package version_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/arsham/gitrelease/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		in   string
+		want version.Version
+	}{
+		"full":        {"v1.2.3", version.Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3}},
+		"no prefix":   {"1.2.3", version.Version{Major: 1, Minor: 2, Patch: 3}},
+		"no patch":    {"v1.2", version.Version{Prefix: "v", Major: 1, Minor: 2}},
+		"zeros":       {"0.0.0", version.Version{}},
+		"pre-release": {"v1.0.0-rc.1", version.Version{Prefix: "v", Major: 1, Prerelease: "rc.1"}},
+		"hyphens":     {"1.0.0-x-y-z.--", version.Version{Major: 1, Prerelease: "x-y-z.--"}},
+		"build":       {"1.0.0+20130313144700", version.Version{Major: 1, Build: "20130313144700"}},
+		"build zeros": {"1.0.0+001", version.Version{Major: 1, Build: "001"}},
+		"both": {
+			"v1.0.0-beta+exp.sha.5114f85",
+			version.Version{Prefix: "v", Major: 1, Prerelease: "beta", Build: "exp.sha.5114f85"},
+		},
+		"pseudo": {
+			"v0.0.0-20220503163025-988cb79eb6c6",
+			version.Version{Prefix: "v", Prerelease: "20220503163025-988cb79eb6c6"},
+		},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := version.Parse(tc.in)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		in     string
+		reason string
+	}{
+		"empty":            {"", "empty"},
+		"prefix only":      {"v", "empty"},
+		"one part":         {"v1", "a major, a minor and an optional patch"},
+		"four parts":       {"1.2.3.4", "a major, a minor and an optional patch"},
+		"empty part":       {"1..3", "minor part is empty"},
+		"not a number":     {"v1.x.3", `minor part "x" is not a number`},
+		"negative":         {"v1.-2.3", "minor part is empty"},
+		"leading zero":     {"01.2.3", `major part "01" has a leading zero`},
+		"too large":        {"1.2.99999999999999999999", "too large"},
+		"empty pre":        {"1.2.3-", "pre-release"},
+		"empty identifier": {"1.2.3-rc..1", "empty identifier"},
+		"zero identifier":  {"1.2.3-rc.01", "leading zero"},
+		"invalid pre":      {"1.2.3-rc_1", "invalid character"},
+		"invalid build":    {"1.2.3+b!", "build metadata"},
+		"garbage":          {"latest", "a major, a minor and an optional patch"},
+		"space":            {" 1.2.3", "not a number"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := version.Parse(tc.in)
+			var parseErr *version.ParseError
+			require.True(t, errors.As(err, &parseErr), err)
+			assert.Equal(t, tc.in, parseErr.Input)
+			assert.Contains(t, err.Error(), tc.reason)
+		})
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1.2.0", version.MustParse("v1.2").String())
+	assert.Panics(t, func() { version.MustParse("v1") })
+}
+
+func TestVersionString(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"v1.2.3", "1.2.3", "v1.0.0-rc.1+build.5", "0.0.0+001"} {
+		assert.Equal(t, s, version.MustParse(s).String())
+	}
+	assert.Equal(t, "v1.2.0", version.MustParse("v1.2").String())
+}
+
+func TestIsPrerelease(t *testing.T) {
+	t.Parallel()
+	assert.True(t, version.IsPrerelease("v1.0.0-rc.1"))
+	assert.True(t, version.IsPrerelease("v1.0-alpha"))
+	assert.False(t, version.IsPrerelease("v1.0.0"))
+	assert.False(t, version.IsPrerelease("v1.0.0+build-1"))
+	assert.False(t, version.IsPrerelease("not-a-version"))
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	// The example of the precedence in the specification.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"v1.0.1",
+		"1.1",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, b := version.MustParse(ordered[i]), version.MustParse(ordered[j])
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			assert.Equal(t, want, version.Compare(a, b), "%s and %s", a, b)
+		}
+	}
+
+	// Only the digits are numeric, "-1" is alphanumeric and comes after
+	// the numbers. The numbers can be longer than an int.
+	assert.Equal(t, 1, version.Compare(version.MustParse("1.0.0-alpha.-1"), version.MustParse("1.0.0-alpha.2")))
+	assert.Equal(t, -1, version.Compare(version.MustParse("1.0.0-alpha.-1"), version.MustParse("1.0.0-alpha.a")))
+	assert.Equal(t, 1, version.Compare(
+		version.MustParse("1.0.0-rc.100000000000000000000"),
+		version.MustParse("1.0.0-rc.99999999999999999999"),
+	))
+
+	assert.Zero(t, version.Compare(version.MustParse("v1.2.3+a"), version.MustParse("1.2.3+b")))
+	assert.Zero(t, version.Compare(version.MustParse("v1.2"), version.MustParse("1.2.0")))
+}
+
+func TestBump(t *testing.T) {
+	t.Parallel()
+	tcs := map[string]struct {
+		in   string
+		part version.Part
+		want string
+	}{
+		"none":        {"v1.2.3", version.None, "v1.2.3"},
+		"patch":       {"v1.2.3", version.Patch, "v1.2.4"},
+		"minor":       {"v1.2.3", version.Minor, "v1.3.0"},
+		"major":       {"v1.2.3", version.Major, "v2.0.0"},
+		"no prefix":   {"1.2", version.Patch, "1.2.1"},
+		"pre-release": {"v1.2.3-rc.1+build", version.Patch, "v1.2.4"},
+		"drop only":   {"v1.2.3-rc.1+build", version.None, "v1.2.3"},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := version.Bump(version.MustParse(tc.in), tc.part)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
+
+// randomVersion is a Version that testing/quick generates from a small set
+// of parts, therefore the generated versions are often equal in some parts.
+type randomVersion struct {
+	version.Version
+}
+
+var identifiers = []string{"0", "1", "2", "11", "alpha", "beta", "rc", "x-y"}
+
+// Generate returns a random version.
+func (randomVersion) Generate(r *rand.Rand, _ int) reflect.Value {
+	v := version.Version{
+		Major: r.Intn(3),
+		Minor: r.Intn(3),
+		Patch: r.Intn(3),
+	}
+	if r.Intn(2) == 0 {
+		v.Prefix = "v"
+	}
+	if r.Intn(2) == 0 {
+		ids := make([]string, 1+r.Intn(3))
+		for i := range ids {
+			ids[i] = identifiers[r.Intn(len(identifiers))]
+		}
+		v.Prerelease = strings.Join(ids, ".")
+	}
+	if r.Intn(4) == 0 {
+		v.Build = fmt.Sprintf("build.%d", r.Intn(3))
+	}
+	return reflect.ValueOf(randomVersion{v})
+}
+
+func TestCompareProperties(t *testing.T) {
+	t.Parallel()
+	cfg := &quick.Config{MaxCount: 5000}
+	t.Run("Reflexive", func(t *testing.T) {
+		t.Parallel()
+		f := func(a randomVersion) bool {
+			return version.Compare(a.Version, a.Version) == 0
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+	t.Run("Antisymmetric", func(t *testing.T) {
+		t.Parallel()
+		f := func(a, b randomVersion) bool {
+			return version.Compare(a.Version, b.Version) == -version.Compare(b.Version, a.Version)
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+	t.Run("Transitive", func(t *testing.T) {
+		t.Parallel()
+		f := func(a, b, c randomVersion) bool {
+			ab := version.Compare(a.Version, b.Version)
+			bc := version.Compare(b.Version, c.Version)
+			ac := version.Compare(a.Version, c.Version)
+			switch {
+			case ab == 0 && bc == 0:
+				return ac == 0
+			case ab <= 0 && bc <= 0:
+				return ac < 0
+			case ab >= 0 && bc >= 0:
+				return ac > 0
+			}
+			return true
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+	t.Run("PrereleaseBeforeRelease", func(t *testing.T) {
+		t.Parallel()
+		f := func(a randomVersion) bool {
+			release := a.Version
+			release.Prerelease = ""
+			if !a.IsPrerelease() {
+				return version.Compare(a.Version, release) == 0
+			}
+			return version.Compare(a.Version, release) < 0
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+	t.Run("BumpIsGreater", func(t *testing.T) {
+		t.Parallel()
+		f := func(a randomVersion, p uint8) bool {
+			part := version.Part(1 + p%3)
+			return version.Compare(version.Bump(a.Version, part), a.Version) > 0
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Parallel()
+		f := func(a randomVersion) bool {
+			got, err := version.Parse(a.String())
+			return err == nil && got == a.Version
+		}
+		assert.NoError(t, quick.Check(f, cfg))
+	})
+}